@@ -0,0 +1,63 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// runAnalytics downloads a report of clipboard activity from the daemon, as
+// JSON, CSV or an SVG heatmap, for feeding personal dashboards
+func runAnalytics(port int, args []string) error {
+	analyticsFlags := flag.NewFlagSet("analytics", flag.ExitOnError)
+	format := analyticsFlags.String("format", "json", "Report format: json, csv or svg (a heatmap by weekday and hour)")
+	from := analyticsFlags.String("from", "", "Start at this date, YYYY-MM-DD (default: 30 days ago)")
+	to := analyticsFlags.String("to", "", "End with this date, YYYY-MM-DD (default: now)")
+	output := analyticsFlags.String("o", "", "Write to this file instead of stdout")
+	analyticsFlags.Parse(args)
+
+	params := url.Values{}
+	if *from != "" {
+		params.Set("from", *from)
+	}
+	if *to != "" {
+		params.Set("to", *to)
+	}
+	endpoint := fmt.Sprintf("http://localhost:%d/api/analytics", port)
+	switch *format {
+	case "json", "csv":
+		params.Set("format", *format)
+	case "svg":
+		endpoint += "/heatmap.svg"
+	default:
+		return fmt.Errorf("unknown format %q, expected json, csv or svg", *format)
+	}
+
+	client := &http.Client{Timeout: time.Minute}
+	resp, err := client.Get(endpoint + "?" + params.Encode())
+	if err != nil {
+		return fmt.Errorf("daemon is not reachable on port %d: %w", port, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return daemonError(resp)
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %w", err)
+		}
+		defer f.Close()
+		w = f
+	}
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// appendStatus is append mode as the daemon reports it
+type appendStatus struct {
+	Active bool `json:"active"`
+	Clips  int  `json:"clips"`
+	Length int  `json:"length"`
+}
+
+// runAppend turns append mode on, optionally with a separator, or off, or
+// prints its state when given no arguments
+func runAppend(port int, args []string) error {
+	endpoint := fmt.Sprintf("http://localhost:%d/api/append", port)
+	method := http.MethodGet
+	switch {
+	case len(args) == 0:
+	case args[0] == "on" && len(args) <= 2:
+		method = http.MethodPost
+		if len(args) == 2 {
+			endpoint += "?separator=" + url.QueryEscape(args[1])
+		}
+	case args[0] == "off" && len(args) == 1:
+		method = http.MethodDelete
+	default:
+		return fmt.Errorf("usage: append [on [separator] | off]")
+	}
+	req, err := http.NewRequest(method, endpoint, nil)
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("daemon is not reachable on port %d: %w", port, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return daemonError(resp)
+	}
+
+	var status appendStatus
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	switch {
+	case status.Active:
+		fmt.Printf("Appending copies: %d so far, %d bytes\n", status.Clips, status.Length)
+	case method == http.MethodDelete && status.Clips > 0:
+		fmt.Printf("Saved %d copies as one clip of %d bytes\n", status.Clips, status.Length)
+	default:
+		fmt.Println("Append mode is off")
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"clipboard-manager/internal/audit"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+// runAudit prints the daemon's audit log, newest first
+func runAudit(port int, args []string) error {
+	auditFlags := flag.NewFlagSet("audit", flag.ExitOnError)
+	action := auditFlags.String("action", "", "Only show this action: read, add, paste, modify, delete, export or denied")
+	user := auditFlags.String("user", "", "Only show requests by this user")
+	since := auditFlags.String("since", "", "Only show entries this recent, e.g. 24h, or since an RFC 3339 time")
+	limit := auditFlags.Int("limit", 50, "Number of entries to show")
+	asJSON := auditFlags.Bool("json", false, "Print the entries as JSON")
+	auditFlags.Parse(args)
+
+	query := url.Values{"limit": {strconv.Itoa(*limit)}}
+	if *action != "" {
+		query.Set("action", *action)
+	}
+	if *user != "" {
+		query.Set("user", *user)
+	}
+	if *since != "" {
+		query.Set("since", *since)
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	var entries []audit.Entry
+	if err := getJSON(client, fmt.Sprintf("http://localhost:%d/api/audit?%s", port, query.Encode()), &entries); err != nil {
+		return err
+	}
+	if *asJSON {
+		return json.NewEncoder(os.Stdout).Encode(entries)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "TIME\tACTION\tUSER\tCLIENT\tREMOTE\tREQUEST\tSTATUS\n")
+	for _, e := range entries {
+		user := e.User
+		if user == "" {
+			user = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s %s\t%d\n",
+			e.Time.Local().Format("2006-01-02 15:04:05"), e.Action, user, e.Client, e.Remote, e.Method, e.Path, e.Status)
+	}
+	return w.Flush()
+}
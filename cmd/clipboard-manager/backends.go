@@ -0,0 +1,7 @@
+package main
+
+// Storage backends that build without CGO
+import (
+	_ "clipboard-manager/internal/storage/bolt"
+	_ "clipboard-manager/internal/storage/postgres"
+)
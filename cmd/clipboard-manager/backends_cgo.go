@@ -0,0 +1,6 @@
+//go:build cgo
+
+package main
+
+// The sqlite backend requires CGO; CGO_ENABLED=0 builds fall back to bolt or postgres
+import _ "clipboard-manager/internal/storage/sqlite"
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"clipboard-manager/internal/bench"
+	"clipboard-manager/internal/storage"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"gorm.io/gorm/logger"
+)
+
+// runBench fills a throwaway store and reports how fast the backend is
+func runBench(driver string, args []string) error {
+	benchFlags := flag.NewFlagSet("bench", flag.ExitOnError)
+	backend := benchFlags.String("storage", driver, "Storage backend to measure")
+	dsn := benchFlags.String("dsn", "", "Connection string of a throwaway database, for backends without a file (postgres)")
+	clips := benchFlags.Int("clips", bench.DefaultClips, "Number of clips to store")
+	size := benchFlags.String("size", "2kb", "Size of each clip, such as 512b, 2kb or 1mb")
+	searches := benchFlags.Int("searches", bench.DefaultSearches, "Number of searches to run")
+	lists := benchFlags.Int("lists", bench.DefaultLists, "Number of pages of history to list")
+	asJSON := benchFlags.Bool("json", false, "Print the report as JSON")
+	benchFlags.Parse(args)
+
+	clipSize, err := bench.ParseSize(*size)
+	if err != nil {
+		return err
+	}
+
+	// File backends get a directory that's removed afterwards; nothing is
+	// written to the real history
+	dir, err := os.MkdirTemp("", "clipboard-manager-bench-")
+	if err != nil {
+		return fmt.Errorf("failed to create a directory for the store: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	if *dsn == "" {
+		switch *backend {
+		case "sqlite", "sqlite-direct":
+			*dsn = filepath.Join(dir, "clipboard.db")
+		case "bolt":
+			*dsn = filepath.Join(dir, "clipboard.bolt")
+		default:
+			return fmt.Errorf("%s storage needs -dsn pointing at a throwaway database; the benchmark leaves its clips there", *backend)
+		}
+	}
+
+	// GORM warns about every lookup of a clip that isn't stored yet, on stdout
+	logger.Default = logger.Default.LogMode(logger.Silent)
+
+	store, err := storage.Open(*backend, *dsn, storage.Config{FSPath: filepath.Join(dir, "files")})
+	if err != nil {
+		return fmt.Errorf("failed to open %s storage: %w", *backend, err)
+	}
+	if closer, ok := store.(interface{ Close() error }); ok {
+		defer closer.Close()
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	fmt.Fprintf(os.Stderr, "Storing %d clips of %s in %s storage...\n", *clips, formatBytes(int64(clipSize)), *backend)
+	report, err := bench.Run(ctx, store, bench.Options{
+		Clips:    *clips,
+		Size:     clipSize,
+		Searches: *searches,
+		Lists:    *lists,
+		Progress: func(stored, total int) {
+			fmt.Fprintf(os.Stderr, "\r%d/%d", stored, total)
+			if stored == total {
+				fmt.Fprintln(os.Stderr)
+			}
+		},
+	})
+	if errors.Is(err, context.Canceled) {
+		return errors.New("interrupted")
+	}
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		return json.NewEncoder(os.Stdout).Encode(struct {
+			Storage string `json:"storage"`
+			*bench.Report
+		}{*backend, report})
+	}
+
+	fmt.Printf("%s storage, %d clips of %s, %s in total\n\n", *backend, report.Clips, formatBytes(int64(report.Size)), report.Elapsed.Round(time.Millisecond))
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(w, "\tCOUNT\tMEAN\tP50\tP95\tP99\tMAX\t\n")
+	for _, row := range []struct {
+		name    string
+		latency bench.Latency
+	}{{"store", report.Store}, {"search", report.Search}, {"list", report.List}} {
+		l := row.latency
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%s\t%s\t\n", row.name, l.Count,
+			formatLatency(l.Mean), formatLatency(l.P50), formatLatency(l.P95), formatLatency(l.P99), formatLatency(l.Max))
+	}
+	w.Flush()
+
+	if report.Usage != nil {
+		fmt.Printf("\nStorage: %s database, %s in files\n", formatBytes(report.Usage.DatabaseBytes), formatBytes(report.Usage.ExternalBytes))
+	}
+	return nil
+}
+
+// formatLatency rounds a latency to three significant digits or so
+func formatLatency(d time.Duration) string {
+	switch {
+	case d >= time.Second:
+		return d.Round(time.Millisecond).String()
+	case d >= time.Millisecond:
+		return d.Round(10 * time.Microsecond).String()
+	default:
+		return d.Round(100 * time.Nanosecond).String()
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// command describes a subcommand for usage and shell completion
+type command struct {
+	name  string
+	usage string
+	help  string
+	flags []string // Flags accepted after the subcommand
+	args  []string // Fixed argument values, if any
+}
+
+// commands lists every subcommand. Keep it in sync with the switch in main.
+var commands = []command{
+	{name: "gc", usage: "gc [-dry-run]", help: "Remove orphaned files and dangling clips", flags: []string{"-dry-run"}},
+	{name: "migrate", usage: "migrate [-status]", help: "Apply pending database migrations, or list them", flags: []string{"-status"}},
+	{name: "import", usage: "import -from maccy|copyq|clipy|paste [-path p] [-dry-run]", help: "Import history from another clipboard manager", flags: []string{"-from", "-path", "-dry-run"}},
+	{name: "pause", usage: "pause [duration]", help: "Pause recording, until resumed or for a duration"},
+	{name: "resume", usage: "resume", help: "Resume recording"},
+	{name: "stop", usage: "stop", help: "Stop the running daemon"},
+	{name: "reload", usage: "reload", help: "Reload the running daemon's settings file"},
+	{name: "status", usage: "status", help: "Show whether the daemon is running and recording"},
+	{name: "search", usage: "search [-format f] [-limit n] [query]", help: "Search history, for scripts and launchers", flags: []string{"-format", "-limit"}},
+	{name: "pick", usage: "pick [-limit n] [query]", help: "Print history as id, preview, type and age separated by tabs, for fzf", flags: []string{"-limit"}},
+	{name: "related", usage: "related [id]", help: "Print the clips copied together with a clip, or the latest one, like pick"},
+	{name: "paste", usage: "paste [-id id | -from-launcher [id] | -next | index]", help: "Copy a clip back to the clipboard", flags: []string{"-id", "-from-launcher", "-next"}},
+	{name: "stack", usage: "stack [id... | clear]", help: "Paste clips in order, one per paste -next or ⌘V in menu bar mode"},
+	{name: "user", usage: "user list | add [-admin] [-scope s] name | token [-scope s] name | remove name", help: "Manage the users and API tokens the daemon accepts"},
+	{name: "profile", usage: "profile [list | use name]", help: "Show or switch the active profile, each with its own history"},
+	{name: "append", usage: "append [on [separator] | off]", help: "Collect copies into one clip until turned off"},
+	{name: "cat", usage: "cat [id]", help: "Write the raw content of a clip, or the latest one, to stdout"},
+	{name: "export", usage: "export -template t [-o file] [-images dir] [-limit n] [query]", help: "Render history through a template: html, org, csv, markdown or a file", flags: []string{"-template", "-o", "-images", "-limit"}},
+	{name: "publish", usage: "publish [-to gist|paste] id", help: "Upload a text clip and copy its link", flags: []string{"-to"}},
+	{name: "translate", usage: "translate [-to lang] [-store] id", help: "Translate a text clip and copy, or store, the translation", flags: []string{"-to", "-store"}},
+	{name: "stats", usage: "stats [-json] [-top n]", help: "Show counts by app, type, day and hour", flags: []string{"-json", "-top"}},
+	{name: "timeline", usage: "timeline [-hour] [-from date] [-to date] [-json] | timeline [-limit n] day|hour", help: "Print clip counts by day or hour, or the clips copied in one, for fzf", flags: []string{"-hour", "-from", "-to", "-limit", "-json"}},
+	{name: "analytics", usage: "analytics [-format json|csv|svg] [-from date] [-to date] [-o file]", help: "Export copy activity by hour, app and type, or a heatmap", flags: []string{"-format", "-from", "-to", "-o"}},
+	{name: "audit", usage: "audit [-action a] [-user u] [-since d] [-limit n] [-json]", help: "Show who read, pasted, changed, deleted or exported clips", flags: []string{"-action", "-user", "-since", "-limit", "-json"}},
+	{name: "doctor", usage: "doctor [-open] [-json]", help: "Check the permissions the daemon needs and where to grant them", flags: []string{"-open", "-json"}},
+	{name: "bench", usage: "bench [-storage s] [-dsn d] [-clips n] [-size s] [-searches n] [-lists n] [-json]", help: "Measure store, search and list latency of a backend on a throwaway store", flags: []string{"-storage", "-dsn", "-clips", "-size", "-searches", "-lists", "-json"}},
+	{name: "service", usage: "service install|uninstall|start|stop|status", help: "Run the daemon at login", args: []string{"install", "uninstall", "start", "stop", "status"}},
+	{name: "update", usage: "update [-check] [-force]", help: "Install the latest release and restart the service", flags: []string{"-check", "-force"}},
+	{name: "completion", usage: "completion bash|zsh|fish", help: "Print a shell completion script", args: []string{"bash", "zsh", "fish"}},
+}
+
+// formatValues completes the value of search -format
+var formatValues = []string{"text", "json", "alfred", "raycast"}
+
+// printUsage lists the subcommands and global flags
+func printUsage(w io.Writer, program string) {
+	fmt.Fprintf(w, "Usage: %s [flags] [command]\n\nCommands:\n", program)
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-45s %s\n", cmd.usage, cmd.help)
+	}
+	fmt.Fprintf(w, "\nWith no command the daemon starts.\n\nFlags:\n")
+	flag.PrintDefaults()
+}
+
+// runCompletion prints a completion script for shell
+func runCompletion(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("expected a shell: bash, zsh or fish")
+	}
+
+	var globalFlags []string
+	flag.VisitAll(func(f *flag.Flag) {
+		globalFlags = append(globalFlags, "-"+f.Name)
+	})
+	sort.Strings(globalFlags)
+
+	switch args[0] {
+	case "bash":
+		fmt.Print(bashCompletion(globalFlags))
+	case "zsh":
+		// zsh runs bash completions through bashcompinit
+		fmt.Print("autoload -U +X bashcompinit && bashcompinit\n" + bashCompletion(globalFlags))
+	case "fish":
+		fmt.Print(fishCompletion(globalFlags))
+	default:
+		return fmt.Errorf("unknown shell %q: expected bash, zsh or fish", args[0])
+	}
+	return nil
+}
+
+func commandNames() []string {
+	names := make([]string, len(commands))
+	for i, cmd := range commands {
+		names[i] = cmd.name
+	}
+	return names
+}
+
+func bashCompletion(globalFlags []string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, `# bash completion for %[1]s
+_%[2]s() {
+    local cur prev cmd word
+    cur="${COMP_WORDS[COMP_CWORD]}"
+    prev="${COMP_WORDS[COMP_CWORD-1]}"
+
+    for word in "${COMP_WORDS[@]:1:COMP_CWORD-1}"; do
+        case "$word" in
+            %[3]s) cmd="$word"; break ;;
+        esac
+    done
+
+    if [[ "$prev" == "-format" ]]; then
+        COMPREPLY=($(compgen -W "%[4]s" -- "$cur"))
+        return
+    fi
+
+    case "$cmd" in
+        "")
+            COMPREPLY=($(compgen -W "%[5]s %[6]s" -- "$cur")) ;;
+`, serviceName, strings.ReplaceAll(serviceName, "-", "_"), strings.Join(commandNames(), "|"),
+		strings.Join(formatValues, " "), strings.Join(commandNames(), " "), strings.Join(globalFlags, " "))
+
+	for _, cmd := range commands {
+		words := append(append([]string{}, cmd.flags...), cmd.args...)
+		if len(words) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "        %s)\n            COMPREPLY=($(compgen -W \"%s\" -- \"$cur\")) ;;\n", cmd.name, strings.Join(words, " "))
+	}
+
+	fmt.Fprintf(&b, `    esac
+}
+complete -o default -F _%[2]s %[1]s
+`, serviceName, strings.ReplaceAll(serviceName, "-", "_"))
+	return b.String()
+}
+
+func fishCompletion(globalFlags []string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# fish completion for %s\n", serviceName)
+	fmt.Fprintf(&b, "complete -c %s -f\n", serviceName)
+
+	noCommand := "not __fish_seen_subcommand_from " + strings.Join(commandNames(), " ")
+	for _, name := range globalFlags {
+		fmt.Fprintf(&b, "complete -c %s -n '%s' -o %s\n", serviceName, noCommand, strings.TrimPrefix(name, "-"))
+	}
+	for _, cmd := range commands {
+		fmt.Fprintf(&b, "complete -c %s -n '%s' -a %s -d %q\n", serviceName, noCommand, cmd.name, cmd.help)
+
+		seen := "__fish_seen_subcommand_from " + cmd.name
+		for _, name := range cmd.flags {
+			fmt.Fprintf(&b, "complete -c %s -n '%s' -o %s\n", serviceName, seen, strings.TrimPrefix(name, "-"))
+		}
+		if len(cmd.args) > 0 {
+			fmt.Fprintf(&b, "complete -c %s -n '%s' -a '%s'\n", serviceName, seen, strings.Join(cmd.args, " "))
+		}
+	}
+	fmt.Fprintf(&b, "complete -c %s -n '__fish_seen_subcommand_from search' -o format -x -a '%s'\n",
+		serviceName, strings.Join(formatValues, " "))
+	return b.String()
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"clipboard-manager/internal/server"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"syscall"
+	"time"
+)
+
+// stopTimeout is how long stop waits for the daemon to exit
+const stopTimeout = 10 * time.Second
+
+// runControl sends a pause or resume request to the running daemon and prints
+// the resulting state
+func runControl(port int, command string, args []string) error {
+	endpoint := fmt.Sprintf("http://localhost:%d/api/%s", port, command)
+
+	if command == "pause" && len(args) > 0 {
+		if _, err := time.ParseDuration(args[0]); err != nil {
+			return fmt.Errorf("invalid duration %q: %w", args[0], err)
+		}
+		endpoint += "?duration=" + url.QueryEscape(args[0])
+	}
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Post(endpoint, "application/json", nil)
+	if err != nil {
+		return fmt.Errorf("daemon is not reachable on port %d: %w", port, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return daemonError(resp)
+	}
+
+	var status struct {
+		Paused      bool   `json:"paused"`
+		PausedUntil string `json:"paused_until"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	switch {
+	case !status.Paused:
+		fmt.Println("Clipboard history is recording")
+	case status.PausedUntil != "":
+		fmt.Printf("Clipboard history paused until %s\n", status.PausedUntil)
+	default:
+		fmt.Println("Clipboard history paused until resumed")
+	}
+	return nil
+}
+
+// runDaemon stops, reloads or reports on the running daemon found through the
+// PID file
+func runDaemon(port int, command string) error {
+	pid, err := server.RunningPID()
+	if err != nil {
+		return err
+	}
+	if pid == 0 {
+		if command == "status" {
+			return fmt.Errorf("clipboard manager is not running")
+		}
+		fmt.Println("Clipboard manager is not running")
+		return nil
+	}
+
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return fmt.Errorf("failed to find process %d: %w", pid, err)
+	}
+
+	switch command {
+	case "stop":
+		if err := process.Signal(syscall.SIGTERM); err != nil {
+			return fmt.Errorf("failed to stop process %d: %w", pid, err)
+		}
+		deadline := time.Now().Add(stopTimeout)
+		for time.Now().Before(deadline) {
+			// Signal 0 fails once the process has exited
+			if process.Signal(syscall.Signal(0)) != nil {
+				fmt.Printf("Stopped clipboard manager (pid %d)\n", pid)
+				return nil
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+		return fmt.Errorf("process %d did not exit within %v", pid, stopTimeout)
+	case "reload":
+		if err := process.Signal(syscall.SIGHUP); err != nil {
+			return fmt.Errorf("failed to signal process %d: %w", pid, err)
+		}
+		fmt.Printf("Asked clipboard manager (pid %d) to reload its settings\n", pid)
+		return nil
+	}
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Get(fmt.Sprintf("http://localhost:%d/status", port))
+	if err != nil {
+		return fmt.Errorf("clipboard manager (pid %d) is not answering on port %d: %w", pid, port, err)
+	}
+	defer resp.Body.Close()
+
+	var status struct {
+		Addr        string `json:"addr"`
+		PID         int    `json:"pid"`
+		Paused      bool   `json:"paused"`
+		PausedUntil string `json:"paused_until"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	if status.PID != pid {
+		return fmt.Errorf("clipboard manager (pid %d) is running, but port %d is answered by pid %d", pid, port, status.PID)
+	}
+
+	fmt.Printf("Clipboard manager is running (pid %d) on %s\n", pid, status.Addr)
+	switch {
+	case !status.Paused:
+		fmt.Println("Clipboard history is recording")
+	case status.PausedUntil != "":
+		fmt.Printf("Clipboard history paused until %s\n", status.PausedUntil)
+	default:
+		fmt.Println("Clipboard history paused until resumed")
+	}
+	return nil
+}
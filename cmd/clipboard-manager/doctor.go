@@ -0,0 +1,70 @@
+package main
+
+import (
+	"clipboard-manager/internal/config"
+	"clipboard-manager/internal/doctor"
+	"clipboard-manager/internal/paste"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+)
+
+// runDoctor checks the permissions the clipboard manager needs. macOS grants
+// them to the app that asks, so when the daemon is running its own checks
+// are shown; otherwise they are run here with settings.
+func runDoctor(port int, settings config.Config, args []string) error {
+	doctorFlags := flag.NewFlagSet("doctor", flag.ExitOnError)
+	open := doctorFlags.Bool("open", false, "Open the System Settings pane of each missing permission")
+	asJSON := doctorFlags.Bool("json", false, "Print the checks as JSON")
+	doctorFlags.Parse(args)
+
+	checks, source := daemonPermissions(port), "the running daemon"
+	if checks == nil {
+		checks = doctor.Run(doctor.Options{Paster: paste.New(), Folders: doctor.SyncFolders(settings)})
+		source = "this process; start the daemon to check its own permissions"
+	}
+	if *asJSON {
+		return json.NewEncoder(os.Stdout).Encode(checks)
+	}
+
+	fmt.Printf("Checked by %s\n\n", source)
+	failed := 0
+	for _, check := range checks {
+		if check.OK {
+			fmt.Printf("✓ %s\n", check.Name)
+			continue
+		}
+		failed++
+		fmt.Printf("✗ %s: %s\n", check.Name, check.Message)
+		if check.Settings == "" {
+			continue
+		}
+		fmt.Printf("  Open: %s\n", check.Settings)
+		if *open {
+			if err := doctor.OpenSettings(check.Settings); err != nil {
+				log.Printf("Warning: failed to open System Settings: %v", err)
+			}
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d checks failed", failed, len(checks))
+	}
+	return nil
+}
+
+// daemonPermissions returns the checks the daemon reports in its status, or
+// nil if it isn't running
+func daemonPermissions(port int) []doctor.Check {
+	client := &http.Client{Timeout: 5 * time.Second}
+	var status struct {
+		Permissions []doctor.Check `json:"permissions"`
+	}
+	if err := getJSON(client, fmt.Sprintf("http://localhost:%d/status", port), &status); err != nil {
+		return nil
+	}
+	return status.Permissions
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"clipboard-manager/internal/export"
+	"clipboard-manager/internal/storage"
+	"clipboard-manager/pkg/types"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// exportPageSize is how many clips each request to the daemon fetches
+const exportPageSize = 100
+
+// runExport renders history, or the clips matching a search query, through a
+// template
+func runExport(port int, args []string) error {
+	exportFlags := flag.NewFlagSet("export", flag.ExitOnError)
+	templatePath := exportFlags.String("template", "", "Template file, or a builtin template: "+strings.Join(export.Builtins, ", "))
+	output := exportFlags.String("o", "", "Output file (default: stdout)")
+	imageDir := exportFlags.String("images", "", "Folder images are saved in (default: images next to the output file)")
+	limit := exportFlags.Int("limit", 0, "Most clips to export, newest first (0 exports all of them)")
+	exportFlags.Parse(args)
+	query := strings.Join(exportFlags.Args(), " ")
+
+	if *templatePath == "" {
+		return fmt.Errorf("-template is required")
+	}
+	tmpl, err := export.Load(*templatePath)
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	clips, err := fetchAllClips(client, port, query, *limit)
+	if err != nil {
+		return err
+	}
+
+	opts := export.Options{
+		ImageDir: *imageDir,
+		Content: func(clip *types.Clip) ([]byte, error) {
+			return fetchContent(client, port, clip.ID)
+		},
+	}
+	data := export.Data{Clips: clips, Query: query}
+	if *output == "" {
+		return tmpl.Execute(os.Stdout, data, opts)
+	}
+
+	opts.OutputDir = filepath.Dir(*output)
+	if opts.ImageDir == "" {
+		opts.ImageDir = filepath.Join(opts.OutputDir, "images")
+	}
+	if err := os.MkdirAll(opts.OutputDir, 0755); err != nil {
+		return fmt.Errorf("failed to create output folder: %w", err)
+	}
+	f, err := os.Create(*output)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	if err := tmpl.Execute(f, data, opts); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// fetchAllClips pages through history, or the results of a search, up to
+// limit clips (0 for all)
+func fetchAllClips(client *http.Client, port int, query string, limit int) ([]*types.Clip, error) {
+	base := fmt.Sprintf("http://localhost:%d/api", port)
+	var clips []*types.Clip
+	cursor := ""
+	for {
+		var page []*types.Clip
+		var next string
+		if query == "" {
+			var clipPage struct {
+				Clips      []*types.Clip `json:"clips"`
+				NextCursor string        `json:"next_cursor"`
+			}
+			endpoint := fmt.Sprintf("%s/clips?limit=%d&cursor=%s", base, exportPageSize, url.QueryEscape(cursor))
+			if err := getJSON(client, endpoint, &clipPage); err != nil {
+				return nil, err
+			}
+			page, next = clipPage.Clips, clipPage.NextCursor
+		} else {
+			var searchPage struct {
+				Results    []storage.SearchResult `json:"results"`
+				NextCursor string                 `json:"next_cursor"`
+			}
+			endpoint := fmt.Sprintf("%s/search?q=%s&limit=%d&cursor=%s", base, url.QueryEscape(query), exportPageSize, url.QueryEscape(cursor))
+			if err := getJSON(client, endpoint, &searchPage); err != nil {
+				return nil, err
+			}
+			for _, result := range searchPage.Results {
+				page = append(page, result.Clip)
+			}
+			next = searchPage.NextCursor
+		}
+
+		for _, clip := range page {
+			if limit > 0 && len(clips) == limit {
+				return clips, nil
+			}
+			clips = append(clips, clip)
+		}
+		if next == "" || len(page) == 0 {
+			return clips, nil
+		}
+		cursor = next
+	}
+}
+
+// fetchContent returns all of a clip's content from the daemon
+func fetchContent(client *http.Client, port int, id string) ([]byte, error) {
+	resp, err := client.Get(fmt.Sprintf("http://localhost:%d/api/clips/id/%s/content", port, url.PathEscape(id)))
+	if err != nil {
+		return nil, fmt.Errorf("daemon is not reachable on port %d: %w", port, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, daemonError(resp)
+	}
+	return io.ReadAll(resp.Body)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"clipboard-manager/internal/importer"
+	"clipboard-manager/internal/storage"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// runImport reads the history of another clipboard manager into store,
+// keeping when clips were copied. Content already in history is left alone.
+func runImport(store storage.Storage, args []string) error {
+	importFlags := flag.NewFlagSet("import", flag.ExitOnError)
+	from := importFlags.String("from", "", "Clipboard manager to import from: "+strings.Join(importer.Sources, ", "))
+	path := importFlags.String("path", "", "Its history file or folder, if not where it keeps it by default")
+	dryRun := importFlags.Bool("dry-run", false, "Report the clips that would be imported without storing them")
+	importFlags.Parse(args)
+
+	if *from == "" {
+		return fmt.Errorf("-from is required: %s", strings.Join(importer.Sources, ", "))
+	}
+	importService, ok := store.(storage.ImportService)
+	if !ok {
+		return fmt.Errorf("this storage backend doesn't support importing history")
+	}
+
+	clips, err := importer.Read(*from, *path)
+	if err != nil {
+		return err
+	}
+	if *dryRun {
+		fmt.Printf("Would import %d clips from %s\n", len(clips), *from)
+		return nil
+	}
+
+	ctx := context.Background()
+	var imported, existing, skipped int
+	for _, clip := range clips {
+		_, added, err := importService.Import(ctx, clip)
+		switch {
+		case errors.Is(err, storage.ErrFileTooLarge):
+			skipped++
+		case err != nil:
+			return fmt.Errorf("imported %d clips before failing: %w", imported, err)
+		case added:
+			imported++
+		default:
+			existing++
+		}
+	}
+	fmt.Printf("Imported %d clips, %d already in history, %d skipped\n", imported, existing, skipped)
+	return nil
+}
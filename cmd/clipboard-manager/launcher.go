@@ -0,0 +1,375 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"clipboard-manager/internal/clipboard"
+	"clipboard-manager/internal/storage"
+	"clipboard-manager/pkg/types"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// launcherTitleLength fits a title on one line in Alfred and Raycast
+const launcherTitleLength = 80
+
+// alfredItem is an item in Alfred's Script Filter JSON format
+type alfredItem struct {
+	UID      string      `json:"uid"`
+	Title    string      `json:"title"`
+	Subtitle string      `json:"subtitle"`
+	Arg      string      `json:"arg"`
+	Icon     *alfredIcon `json:"icon,omitempty"`
+	Text     *alfredText `json:"text,omitempty"`
+}
+
+type alfredIcon struct {
+	Path string `json:"path"`
+}
+
+// alfredText is what ⌘C and Large Type show for an item
+type alfredText struct {
+	Copy      string `json:"copy"`
+	LargeType string `json:"largetype"`
+}
+
+// raycastItem is a list item for a Raycast script or extension
+type raycastItem struct {
+	ID          string              `json:"id"`
+	Title       string              `json:"title"`
+	Subtitle    string              `json:"subtitle"`
+	Arg         string              `json:"arg"`
+	Icon        string              `json:"icon,omitempty"`
+	Accessories []map[string]string `json:"accessories,omitempty"`
+}
+
+// runSearch prints clips matching a query from the running daemon, as plain
+// text or in the JSON formats launchers read. With no query it lists the most
+// recent clips.
+func runSearch(port int, args []string) error {
+	searchFlags := flag.NewFlagSet("search", flag.ExitOnError)
+	format := searchFlags.String("format", "text", "Output format (text, json, alfred, raycast)")
+	limit := searchFlags.Int("limit", 20, "Maximum number of clips to list")
+	searchFlags.Parse(args)
+	query := strings.Join(searchFlags.Args(), " ")
+
+	switch *format {
+	case "text", "json", "alfred", "raycast":
+	default:
+		return fmt.Errorf("unknown format %q: expected text, json, alfred or raycast", *format)
+	}
+
+	clips, err := fetchClips(port, query, *limit)
+	if err != nil {
+		return err
+	}
+
+	switch *format {
+	case "json":
+		return json.NewEncoder(os.Stdout).Encode(clips)
+	case "alfred":
+		items := make([]alfredItem, 0, len(clips))
+		for _, clip := range clips {
+			item := alfredItem{
+				UID:      clip.ID,
+				Title:    clipboard.Title(clip, launcherTitleLength),
+				Subtitle: clipSubtitle(clip),
+				Arg:      clip.ID,
+			}
+			if path := appIconPath(port, clip.Metadata.SourceBundleID); path != "" {
+				item.Icon = &alfredIcon{Path: path}
+			}
+			if strings.HasPrefix(clip.Type, "text/") {
+				item.Text = &alfredText{Copy: string(clip.Content), LargeType: string(clip.Content)}
+			}
+			items = append(items, item)
+		}
+		return json.NewEncoder(os.Stdout).Encode(map[string]interface{}{"items": items})
+	case "raycast":
+		items := make([]raycastItem, 0, len(clips))
+		for _, clip := range clips {
+			items = append(items, raycastItem{
+				ID:          clip.ID,
+				Title:       clipboard.Title(clip, launcherTitleLength),
+				Subtitle:    clip.Metadata.SourceApp,
+				Arg:         clip.ID,
+				Icon:        appIconPath(port, clip.Metadata.SourceBundleID),
+				Accessories: []map[string]string{{"text": timeAgo(clip.CreatedAt)}},
+			})
+		}
+		return json.NewEncoder(os.Stdout).Encode(map[string]interface{}{"items": items})
+	}
+
+	for _, clip := range clips {
+		fmt.Printf("%s\t%s\n", clip.ID, clipboard.Title(clip, launcherTitleLength))
+	}
+	return nil
+}
+
+// runPick prints clips one per line as ID, preview, type and age separated by
+// tabs. The format is stable so it can be piped through fzf and cut:
+//
+//	clipboard-manager paste -id "$(clipboard-manager pick | fzf -d '\t' --with-nth 2.. | cut -f1)"
+func runPick(port int, args []string) error {
+	pickFlags := flag.NewFlagSet("pick", flag.ExitOnError)
+	limit := pickFlags.Int("limit", 100, "Maximum number of clips to list")
+	pickFlags.Parse(args)
+
+	clips, err := fetchClips(port, strings.Join(pickFlags.Args(), " "), *limit)
+	if err != nil {
+		return err
+	}
+
+	return writePickLines(clips)
+}
+
+// runRelated prints the clips copied in the same session as a clip, or as
+// the latest one, in the format of pick. It fits fzf's preview window, so
+// picking a clip shows what was copied along with it:
+//
+//	clipboard-manager pick | fzf -d '\t' --with-nth 2.. --preview 'clipboard-manager related {1}'
+func runRelated(port int, args []string) error {
+	if len(args) > 1 {
+		return fmt.Errorf("expected at most one clip ID")
+	}
+
+	var id string
+	if len(args) == 1 {
+		id = args[0]
+	} else {
+		clips, err := fetchClips(port, "", 1)
+		if err != nil {
+			return err
+		}
+		if len(clips) == 0 {
+			return fmt.Errorf("history is empty")
+		}
+		id = clips[0].ID
+	}
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	var clips []*types.Clip
+	if err := getJSON(client, fmt.Sprintf("http://localhost:%d/api/clips/%s/related", port, url.PathEscape(id)), &clips); err != nil {
+		return err
+	}
+	return writePickLines(clips)
+}
+
+// writePickLines prints clips one per line as ID, preview, type and age
+// separated by tabs
+func writePickLines(clips []*types.Clip) error {
+	w := bufio.NewWriter(os.Stdout)
+	for _, clip := range clips {
+		// Title collapses whitespace, so the preview never contains a tab
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", clip.ID, clipboard.Title(clip, launcherTitleLength), clip.Type, timeAgo(clip.CreatedAt))
+	}
+	return w.Flush()
+}
+
+// runPaste copies a clip back to the clipboard through the running daemon.
+// Clips are picked by index, by ID with -id, or by the ID a launcher passes
+// on with -from-launcher, either as an argument or on stdin.
+func runPaste(port int, args []string) error {
+	pasteFlags := flag.NewFlagSet("paste", flag.ExitOnError)
+	fromLauncher := pasteFlags.Bool("from-launcher", false, "Take the clip ID a launcher passes as the argument or on stdin")
+	clipID := pasteFlags.String("id", "", "ID of the clip to paste")
+	next := pasteFlags.Bool("next", false, "Copy the next clip on the paste stack")
+	pasteFlags.Parse(args)
+
+	var path string
+	if *next {
+		path = "/api/stack/next"
+	} else if *clipID != "" {
+		path = "/api/clips/id/" + url.PathEscape(strings.TrimSpace(*clipID)) + "/paste"
+	} else if *fromLauncher {
+		id := strings.TrimSpace(strings.Join(pasteFlags.Args(), " "))
+		if id == "" {
+			line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+			if err != nil && err != io.EOF {
+				return fmt.Errorf("failed to read clip ID: %w", err)
+			}
+			id = strings.TrimSpace(line)
+		}
+		if id == "" {
+			return fmt.Errorf("missing clip ID")
+		}
+		path = "/api/clips/id/" + url.PathEscape(id) + "/paste"
+	} else {
+		if pasteFlags.NArg() != 1 {
+			return fmt.Errorf("expected a clip index")
+		}
+		path = "/api/clips/" + url.PathEscape(pasteFlags.Arg(0)) + "/paste"
+	}
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Post(fmt.Sprintf("http://localhost:%d%s", port, path), "application/json", nil)
+	if err != nil {
+		return fmt.Errorf("daemon is not reachable on port %d: %w", port, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return daemonError(resp)
+	}
+	return nil
+}
+
+// runCat writes the raw content of a clip, or of the latest clip if no ID is
+// given, to stdout
+func runCat(port int, args []string) error {
+	if len(args) > 1 {
+		return fmt.Errorf("expected at most one clip ID")
+	}
+
+	var id string
+	if len(args) == 1 {
+		id = args[0]
+	} else {
+		clips, err := fetchClips(port, "", 1)
+		if err != nil {
+			return err
+		}
+		if len(clips) == 0 {
+			return fmt.Errorf("history is empty")
+		}
+		id = clips[0].ID
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Get(fmt.Sprintf("http://localhost:%d/api/clips/id/%s/content", port, url.PathEscape(id)))
+	if err != nil {
+		return fmt.Errorf("daemon is not reachable on port %d: %w", port, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return daemonError(resp)
+	}
+	_, err = io.Copy(os.Stdout, resp.Body)
+	return err
+}
+
+// fetchClips searches the daemon for query, or lists recent clips if it is empty
+func fetchClips(port int, query string, limit int) ([]*types.Clip, error) {
+	client := &http.Client{Timeout: 5 * time.Second}
+	base := fmt.Sprintf("http://localhost:%d/api", port)
+
+	if query == "" {
+		var clips []*types.Clip
+		err := getJSON(client, fmt.Sprintf("%s/clips?limit=%d", base, limit), &clips)
+		return clips, err
+	}
+
+	var results []storage.SearchResult
+	if err := getJSON(client, base+"/search?q="+url.QueryEscape(query), &results); err != nil {
+		return nil, err
+	}
+	clips := make([]*types.Clip, 0, len(results))
+	for _, result := range results {
+		if len(clips) == limit {
+			break
+		}
+		clips = append(clips, result.Clip)
+	}
+	return clips, nil
+}
+
+func getJSON(client *http.Client, endpoint string, v interface{}) error {
+	resp, err := client.Get(endpoint)
+	if err != nil {
+		return fmt.Errorf("daemon is not reachable: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return daemonError(resp)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// daemonError describes an error response from the daemon, using the
+// message of its JSON error when there is one
+func daemonError(resp *http.Response) error {
+	body, _ := io.ReadAll(resp.Body)
+	var e struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(body, &e) == nil && e.Error.Message != "" {
+		return fmt.Errorf("daemon returned %s: %s", resp.Status, e.Error.Message)
+	}
+	return fmt.Errorf("daemon returned %s: %s", resp.Status, bytes.TrimSpace(body))
+}
+
+// appIconPath returns a cached PNG of the source app's icon, fetching it from
+// the daemon the first time. Launchers want icons as files.
+func appIconPath(port int, bundleID string) string {
+	if bundleID == "" || strings.ContainsAny(bundleID, `/\`) {
+		return ""
+	}
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	path := filepath.Join(cacheDir, serviceName, "icons", bundleID+".png")
+	if _, err := os.Stat(path); err == nil {
+		return path
+	}
+
+	client := &http.Client{Timeout: 2 * time.Second}
+	resp, err := client.Get(fmt.Sprintf("http://localhost:%d/api/apps/%s/icon", port, url.PathEscape(bundleID)))
+	if err != nil {
+		return ""
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return ""
+	}
+	icon, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return ""
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return ""
+	}
+	if err := os.WriteFile(path, icon, 0644); err != nil {
+		return ""
+	}
+	return path
+}
+
+// clipSubtitle describes where and when a clip was copied
+func clipSubtitle(clip *types.Clip) string {
+	if clip.Metadata.SourceApp == "" {
+		return timeAgo(clip.CreatedAt)
+	}
+	return clip.Metadata.SourceApp + " · " + timeAgo(clip.CreatedAt)
+}
+
+// timeAgo formats t relative to now, e.g. "5m ago"
+func timeAgo(t time.Time) string {
+	d := time.Since(t)
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd ago", int(d.Hours()/24))
+	}
+}
@@ -1,17 +1,31 @@
 package main
 
 import (
+	"clipboard-manager/internal/audit"
+	"clipboard-manager/internal/auth"
 	"clipboard-manager/internal/clipboard"
+	"clipboard-manager/internal/config"
+	"clipboard-manager/internal/digest"
+	"clipboard-manager/internal/menubar"
+	"clipboard-manager/internal/paste"
+	"clipboard-manager/internal/profile"
 	"clipboard-manager/internal/server"
 	"clipboard-manager/internal/service"
+	"clipboard-manager/internal/share"
+	"clipboard-manager/internal/speech"
 	"clipboard-manager/internal/storage"
-	"clipboard-manager/internal/storage/sqlite"
+	"clipboard-manager/internal/trace"
+	"context"
 	"flag"
+	"fmt"
+	"io"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
 	"syscall"
+	"time"
 )
 
 func main() {
@@ -19,14 +33,165 @@ func main() {
 	
 	// Configuration flags
 	var (
+		driver  = flag.String("storage", envOrDefault("CLIPBOARD_STORAGE", "sqlite"), "Storage backend (sqlite, sqlite-direct, postgres, bolt)")
+		dsn     = flag.String("dsn", os.Getenv("CLIPBOARD_DSN"), "Storage connection string (defaults to the database path for sqlite)")
 		dbPath  = flag.String("db", "", "Database path (default: ~/.clipboard-manager/clipboard.db)")
 		fsPath  = flag.String("fs", "", "File storage path (default: ~/.clipboard-manager/files)")
+		configPath = flag.String("config", "", "Settings file, reloaded when it changes or on SIGHUP (default: ~/.clipboard-manager/config.json)")
 		port    = flag.Int("port", 54321, "HTTP server port")
+		pollMin = flag.Duration("poll-min", clipboard.DefaultMinPollInterval, "Clipboard poll interval right after activity")
+		pollMax = flag.Duration("poll-max", clipboard.DefaultMaxPollInterval, "Clipboard poll interval when idle")
+		concealedExpiry = flag.Duration("concealed-expiry", clipboard.DefaultConcealedExpiry, "Delete clips password managers mark as concealed after this long (negative keeps them)")
+		replace = flag.Bool("replace", false, "Stop a clipboard manager that is already running instead of exiting")
+		headless = flag.Bool("headless", false, "Use an in-memory clipboard instead of the system clipboard")
+		menubarMode = flag.Bool("menubar", false, "Show recent clips in the macOS menu bar")
+		menubarItems = flag.Int("menubar-items", menubar.DefaultItems, "Number of recent clips listed in the menu bar")
+		picker = flag.Bool("picker", false, "In menu bar mode, open a quick picker with Shift-Cmd-V (conflicts with the app's hotkey)")
+		digestPeriod = flag.String("digest", "", "Write a Markdown digest of each day's or week's clips (day, week)")
+		digestDir = flag.String("digest-dir", "", "Digest directory (default: Clipboard/Digests in the Obsidian vault, or ~/.clipboard-manager/digests)")
+		coalesce = flag.Duration("coalesce", service.DefaultCaptureLimits.Coalesce, "Keep only the last clipboard change within this window (0 keeps every change)")
+		appRate = flag.Int("app-rate", service.DefaultCaptureLimits.PerAppPerMinute, "Most clips recorded from one app per minute (0 is unlimited)")
+		restoreLast = flag.Bool("restore-last", false, "Put the latest clip back on an empty clipboard at startup, skipping sensitive clips")
+		profileName = flag.String("profile", "", "Profile to start in (default: the profile last used)")
+		auditEnabled = flag.Bool("audit", true, "Record who reads, pastes, changes, deletes or exports clips through the API in audit.log")
+		auditSize = flag.Int64("audit-size", audit.DefaultMaxSize, "Rotate the audit log at this size in bytes")
+		auditKeep = flag.Int("audit-keep", audit.DefaultKeep, "Number of rotated audit logs to keep")
+		slowThreshold = flag.Duration("slow-threshold", trace.DefaultSlowThreshold, "Log stores, searches and pastes that take at least this long, with their request ID (0 turns it off)")
+		trashDays = flag.Int("trash-days", int(storage.DefaultTrashRetention/(24*time.Hour)), "Days to keep deleted clips in the trash (0 keeps them forever)")
 	)
 
+	flag.Usage = func() {
+		printUsage(flag.CommandLine.Output(), os.Args[0])
+	}
 	flag.Parse()
-	
-	log.Printf("Starting clipboard manager...")
+
+	// Commands that talk to the daemon identify themselves and send the
+	// token once it requires one
+	http.DefaultTransport = cliTransport{token: os.Getenv("CLIPBOARD_TOKEN"), next: http.DefaultTransport}
+
+	command := flag.Arg(0)
+	switch command {
+	case "", "gc", "user", "migrate", "import", "doctor":
+	case "pause", "resume":
+		// Control commands talk to the running daemon
+		if err := runControl(*port, command, flag.Args()[1:]); err != nil {
+			log.Fatalf("Failed to %s clipboard history: %v", command, err)
+		}
+		return
+	case "stop", "reload", "status":
+		if err := runDaemon(*port, command); err != nil {
+			log.Fatalf("Daemon %s failed: %v", command, err)
+		}
+		return
+	case "search":
+		if err := runSearch(*port, flag.Args()[1:]); err != nil {
+			log.Fatalf("Search failed: %v", err)
+		}
+		return
+	case "pick":
+		if err := runPick(*port, flag.Args()[1:]); err != nil {
+			log.Fatalf("Pick failed: %v", err)
+		}
+		return
+	case "stats":
+		if err := runStats(*port, flag.Args()[1:]); err != nil {
+			log.Fatalf("Stats failed: %v", err)
+		}
+		return
+	case "timeline":
+		if err := runTimeline(*port, flag.Args()[1:]); err != nil {
+			log.Fatalf("Timeline failed: %v", err)
+		}
+		return
+	case "analytics":
+		if err := runAnalytics(*port, flag.Args()[1:]); err != nil {
+			log.Fatalf("Analytics failed: %v", err)
+		}
+		return
+	case "audit":
+		if err := runAudit(*port, flag.Args()[1:]); err != nil {
+			log.Fatalf("Audit failed: %v", err)
+		}
+		return
+	case "related":
+		if err := runRelated(*port, flag.Args()[1:]); err != nil {
+			log.Fatalf("Related failed: %v", err)
+		}
+		return
+	case "cat":
+		if err := runCat(*port, flag.Args()[1:]); err != nil {
+			log.Fatalf("Cat failed: %v", err)
+		}
+		return
+	case "paste":
+		if err := runPaste(*port, flag.Args()[1:]); err != nil {
+			log.Fatalf("Paste failed: %v", err)
+		}
+		return
+	case "stack":
+		if err := runStack(*port, flag.Args()[1:]); err != nil {
+			log.Fatalf("Stack failed: %v", err)
+		}
+		return
+	case "append":
+		if err := runAppend(*port, flag.Args()[1:]); err != nil {
+			log.Fatalf("Append failed: %v", err)
+		}
+		return
+	case "profile":
+		if err := runProfile(*port, flag.Args()[1:]); err != nil {
+			log.Fatalf("Profile failed: %v", err)
+		}
+		return
+	case "export":
+		if err := runExport(*port, flag.Args()[1:]); err != nil {
+			log.Fatalf("Export failed: %v", err)
+		}
+		return
+	case "publish":
+		if err := runPublish(*port, flag.Args()[1:]); err != nil {
+			log.Fatalf("Publish failed: %v", err)
+		}
+		return
+	case "translate":
+		if err := runTranslate(*port, flag.Args()[1:]); err != nil {
+			log.Fatalf("Translate failed: %v", err)
+		}
+		return
+	case "completion":
+		if err := runCompletion(flag.Args()[1:]); err != nil {
+			log.Fatalf("Completion failed: %v", err)
+		}
+		return
+	case "bench":
+		if err := runBench(*driver, flag.Args()[1:]); err != nil {
+			log.Fatalf("Bench failed: %v", err)
+		}
+		return
+	case "update":
+		if err := runUpdate(flag.Args()[1:]); err != nil {
+			log.Fatalf("Update failed: %v", err)
+		}
+		return
+	case "service":
+		// Flags given alongside install are passed to the daemon by the service
+		if err := runService(flag.Args()[1:], daemonFlags()); err != nil {
+			log.Fatalf("Service command failed: %v", err)
+		}
+		return
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if command == "" {
+		log.Printf("Starting clipboard manager...")
+
+		// Refuse to start before touching the clipboard or the database
+		if err := server.CheckExisting(*port, *replace); err != nil {
+			log.Fatalf("Failed to start: %v", err)
+		}
+	}
 
 	// Set up storage paths
 	homeDir, err := os.UserHomeDir()
@@ -46,33 +211,233 @@ func main() {
 	if *fsPath == "" {
 		*fsPath = filepath.Join(baseDir, "files")
 	}
+	if *configPath == "" {
+		*configPath = filepath.Join(baseDir, config.FileName)
+	}
 
-	// Initialize storage
-	store, err := sqlite.New(storage.Config{
-		DBPath: *dbPath,
-		FSPath: *fsPath,
+	if command == "user" {
+		if err := runUser(filepath.Join(baseDir, auth.FileName), flag.Args()[1:]); err != nil {
+			log.Fatalf("User failed: %v", err)
+		}
+		return
+	}
+
+	// sqlite-direct is the sqlite backend with hand-written SQL, on the same
+	// database
+	isSQLite := *driver == "sqlite" || *driver == "sqlite-direct"
+	if *dsn == "" && isSQLite {
+		*dsn = *dbPath
+	}
+	if *dsn == "" && *driver == "bolt" {
+		*dsn = filepath.Join(baseDir, "clipboard.bolt")
+	}
+
+	// Settings in the config file override the environment and flags
+	baseSettings := config.FromEnv()
+	baseSettings.Poll = config.Poll{Min: config.Duration(*pollMin), Max: config.Duration(*pollMax)}
+	baseSettings.TrashDays = trashDays
+	baseSettings.Scripts.Dir = filepath.Join(baseDir, "scripts")
+	settings, err := config.Load(*configPath, baseSettings)
+	if err != nil {
+		log.Fatalf("Failed to load settings: %v", err)
+	}
+	settingsBus := config.NewBus(settings)
+
+	if command == "doctor" {
+		if err := runDoctor(*port, settings, flag.Args()[1:]); err != nil {
+			log.Fatalf("Doctor failed: %v", err)
+		}
+		return
+	}
+
+	// The default profile uses the paths above, other profiles a directory
+	// of their own
+	// openIn opens a database in dir, a profile's or user's directory,
+	// unless dsn points elsewhere
+	openIn := func(dir, dsn string) (storage.Storage, error) {
+		switch {
+		case dsn != "":
+		case isSQLite:
+			dsn = filepath.Join(dir, "clipboard.db")
+		case *driver == "bolt":
+			dsn = filepath.Join(dir, "clipboard.bolt")
+		default:
+			return nil, fmt.Errorf("%s storage needs a dsn for each history", *driver)
+		}
+		return storage.Open(*driver, dsn, storage.Config{FSPath: filepath.Join(dir, "files"), NoMigrate: command == "migrate"})
+	}
+	profiles := profile.NewManager(baseDir, func(name, dir string) (storage.Storage, error) {
+		if name == profile.Default {
+			return storage.Open(*driver, *dsn, storage.Config{FSPath: *fsPath, NoMigrate: command == "migrate"})
+		}
+		return openIn(dir, settingsBus.Current().Profiles[name].DSN)
 	})
+	if *profileName == "" {
+		*profileName = profiles.Active()
+	}
+
+	// Initialize storage
+	store, err := profiles.Open(*profileName)
 	if err != nil {
 		log.Fatalf("Failed to initialize storage: %v", err)
 	}
 
+	if command == "gc" {
+		gcFlags := flag.NewFlagSet("gc", flag.ExitOnError)
+		dryRun := gcFlags.Bool("dry-run", false, "Report orphaned files and dangling clips without repairing them")
+		gcFlags.Parse(flag.Args()[1:])
+
+		err := runGC(store, *dryRun)
+		if closer, ok := store.(io.Closer); ok {
+			closer.Close()
+		}
+		if err != nil {
+			log.Fatalf("Garbage collection failed: %v", err)
+		}
+		return
+	}
+
+	if command == "migrate" {
+		err := runMigrate(store, flag.Args()[1:])
+		if closer, ok := store.(io.Closer); ok {
+			closer.Close()
+		}
+		if err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		return
+	}
+
+	if command == "import" {
+		err := runImport(store, flag.Args()[1:])
+		if closer, ok := store.(io.Closer); ok {
+			closer.Close()
+		}
+		if err != nil {
+			log.Fatalf("Import failed: %v", err)
+		}
+		return
+	}
+
+	// Repair anything left behind by a crash before we start capturing
+	if err := runGC(store, false); err != nil {
+		log.Printf("Warning: garbage collection failed: %v", err)
+	}
+
 	// Initialize monitor
-	monitor := clipboard.NewMonitor()
+	var monitor clipboard.Monitor
+	if *headless {
+		log.Printf("Running headless with in-memory clipboard")
+		monitor = clipboard.NewMemoryMonitor()
+	} else {
+		monitor = clipboard.NewMonitorWithConfig(clipboard.Config{
+			MinPollInterval: *pollMin,
+			MaxPollInterval: *pollMax,
+			ConcealedExpiry: *concealedExpiry,
+		})
+	}
 
 	// Create and start clipboard service
 	clipService := service.New(monitor, store)
+	if *headless {
+		clipService.SetPaster(paste.Disabled("the daemon is running headless"))
+		clipService.SetSpeaker(speech.Disabled("the daemon is running headless"))
+	}
+
+	clipService.SetProfiles(profiles, *profileName)
+	settingsBus.Subscribe(clipService.ApplyConfig)
+	clipService.SetCaptureLimits(service.CaptureLimits{Coalesce: *coalesce, PerAppPerMinute: *appRate})
+	clipService.SetRestoreLast(*restoreLast)
+	if *digestPeriod != "" {
+		period, err := digest.ParsePeriod(*digestPeriod)
+		if err != nil {
+			log.Fatalf("Invalid -digest: %v", err)
+		}
+		if *digestDir == "" {
+			*digestDir = filepath.Join(baseDir, "digests")
+			if vault := os.Getenv("OBSIDIAN_VAULT_PATH"); os.Getenv("OBSIDIAN_ENABLED") == "true" && vault != "" {
+				*digestDir = filepath.Join(vault, "Clipboard", "Digests")
+			}
+		}
+		clipService.SetDigest(digest.Config{Period: period, Dir: *digestDir})
+	}
 	if err := clipService.Start(); err != nil {
 		log.Fatalf("Failed to start clipboard service: %v", err)
 	}
 
+	// Point out permissions that are missing, and where to grant them
+	for _, check := range clipService.Permissions() {
+		if !check.OK && check.Settings != "" {
+			log.Printf("Warning: %s: %s (open %s, or run doctor -open)", check.Name, check.Message, check.Settings)
+		}
+	}
+
+	watchCtx, stopWatching := context.WithCancel(context.Background())
+	go func() {
+		if err := config.Watch(watchCtx, *configPath, baseSettings, settingsBus); err != nil {
+			log.Printf("Warning: settings won't reload: %v", err)
+		}
+	}()
+
 	log.Printf("Using configuration:")
-	log.Printf("- Database: %s", *dbPath)
-	log.Printf("- File storage: %s", *fsPath)
+	log.Printf("- Storage: %s", *driver)
+	log.Printf("- Profile: %s", *profileName)
+	if *profileName == profile.Default && (isSQLite || *driver == "bolt") {
+		log.Printf("- Database: %s", *dsn)
+	}
+	if *profileName == profile.Default {
+		log.Printf("- File storage: %s", *fsPath)
+	} else {
+		log.Printf("- Profile directory: %s", profiles.Dir(*profileName))
+	}
 	log.Printf("- HTTP server port: %d", *port)
+	log.Printf("- Poll interval: %v - %v", time.Duration(settings.Poll.Min), time.Duration(settings.Poll.Max))
+	log.Printf("- Settings: %s", *configPath)
+	log.Printf("- Trash retention: %d days", *settings.TrashDays)
+	if !settings.Scripts.Disabled {
+		log.Printf("- Scripts: %s", settings.Scripts.Dir)
+	}
+	log.Printf("- Capture limits: coalesce %v, %d clips per app per minute", *coalesce, *appRate)
+	if *digestPeriod != "" {
+		log.Printf("- Digests: every %s in %s", *digestPeriod, *digestDir)
+	}
+
+	shares, err := share.Open(filepath.Join(baseDir, share.FileName))
+	if err != nil {
+		log.Fatalf("Failed to load shares: %v", err)
+	}
+
+	users, err := auth.Open(filepath.Join(baseDir, auth.FileName))
+	if err != nil {
+		log.Fatalf("Failed to load users: %v", err)
+	}
+
+	trace.SetSlowThreshold(*slowThreshold)
+
+	var auditLog *audit.Log
+	if *auditEnabled {
+		auditLog, err = audit.Open(filepath.Join(baseDir, audit.FileName), *auditSize, *auditKeep)
+		if err != nil {
+			log.Fatalf("Failed to open the audit log: %v", err)
+		}
+	}
 
 	// Initialize HTTP server
 	httpServer, err := server.New(clipService, server.Config{
-		Port: *port,
+		Port:         *port,
+		Replace:      *replace,
+		Settings:     settingsBus,
+		SettingsPath: *configPath,
+		Shares:       shares,
+		Users:        users,
+		Audit:        auditLog,
+		OpenUserStore: func(name string) (storage.Storage, error) {
+			dir := filepath.Join(baseDir, "users", name)
+			if err := os.MkdirAll(dir, 0700); err != nil {
+				return nil, err
+			}
+			return openIn(dir, "")
+		},
 	})
 	if err != nil {
 		log.Fatalf("Failed to initialize HTTP server: %v", err)
@@ -84,21 +449,79 @@ func main() {
 		log.Fatalf("Failed to start HTTP server: %v", err)
 	}
 
+	// Clean shutdown
+	shutdown := func() {
+		log.Println("Shutting down...")
+		stopWatching()
+
+		// Stop HTTP server first
+		if err := httpServer.Stop(); err != nil {
+			log.Printf("Error stopping HTTP server: %v", err)
+		}
+
+		// Stop clipboard service
+		if err := clipService.Stop(); err != nil {
+			log.Printf("Error stopping service: %v", err)
+		}
+
+		if auditLog != nil {
+			auditLog.Close()
+		}
+	}
+
+	if *menubarMode {
+		// The menu bar runs the AppKit event loop on the main thread until quit
+		err := menubar.Run(clipService, menubar.Config{Items: *menubarItems, Picker: *picker}, shutdown)
+		if err != nil {
+			shutdown()
+			log.Fatalf("Failed to start menu bar: %v", err)
+		}
+		return
+	}
+
 	// Wait for interrupt signal
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 	<-sigChan
 
-	// Clean shutdown
-	log.Println("Shutting down...")
+	shutdown()
+}
 
-	// Stop HTTP server first
-	if err := httpServer.Stop(); err != nil {
-		log.Printf("Error stopping HTTP server: %v", err)
+// runGC removes orphaned files and dangling clips from the store and logs the result
+func runGC(store storage.Storage, dryRun bool) error {
+	collector, ok := store.(storage.GarbageCollector)
+	if !ok {
+		log.Printf("Storage backend does not support garbage collection")
+		return nil
 	}
 
-	// Stop clipboard service
-	if err := clipService.Stop(); err != nil {
-		log.Printf("Error stopping service: %v", err)
+	report, err := collector.GC(context.Background(), dryRun)
+	if err != nil {
+		return err
+	}
+
+	action := "Removed"
+	if dryRun {
+		action = "Would remove"
+	}
+	for _, name := range report.OrphanedFiles {
+		log.Printf("%s orphaned file: %s", action, name)
+	}
+	for _, id := range report.DanglingClips {
+		log.Printf("%s clip %s: external file is missing", action, id)
+	}
+	for _, id := range report.LostCopies {
+		log.Printf("%s copy of the file of clip %s: it is missing", action, id)
+	}
+	log.Printf("Garbage collection: %d orphaned files, %d stale temp files, %d dangling clips, %d bytes freed",
+		len(report.OrphanedFiles), len(report.StaleSpools), len(report.DanglingClips), report.FreedBytes)
+	return nil
+}
+
+// envOrDefault returns the value of an environment variable or a default
+func envOrDefault(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
 	}
+	return fallback
 }
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"clipboard-manager/internal/storage"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+// runMigrate applies the pending schema migrations of store, which was opened
+// without applying them, or lists every migration with -status
+func runMigrate(store storage.Storage, args []string) error {
+	migrateFlags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	status := migrateFlags.Bool("status", false, "List migrations and when they were applied without applying any")
+	migrateFlags.Parse(args)
+
+	migrator, ok := store.(storage.MigrationService)
+	if !ok {
+		fmt.Println("This storage backend updates its schema when it opens; there is nothing to migrate")
+		return nil
+	}
+
+	ctx := context.Background()
+	if *status {
+		migrations, err := migrator.Migrations(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED")
+		for _, m := range migrations {
+			applied := "pending"
+			if !m.AppliedAt.IsZero() {
+				applied = m.AppliedAt.Local().Format(time.DateTime)
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\n", m.Version, m.Name, applied)
+		}
+		return w.Flush()
+	}
+
+	applied, err := migrator.Migrate(ctx)
+	for _, m := range applied {
+		fmt.Printf("Applied %04d_%s\n", m.Version, m.Name)
+	}
+	if err != nil {
+		return err
+	}
+	if len(applied) == 0 {
+		fmt.Println("The schema is up to date")
+	}
+	return nil
+}
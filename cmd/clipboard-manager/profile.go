@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// runProfile prints the active profile and the others, or switches to
+// another one with "use name"
+func runProfile(port int, args []string) error {
+	endpoint := fmt.Sprintf("http://localhost:%d/api/profile", port)
+	var req *http.Request
+	var err error
+	switch {
+	case len(args) == 0 || (len(args) == 1 && args[0] == "list"):
+		req, err = http.NewRequest(http.MethodGet, endpoint, nil)
+	case len(args) == 2 && args[0] == "use":
+		body, _ := json.Marshal(map[string]string{"name": args[1]})
+		req, err = http.NewRequest(http.MethodPut, endpoint, bytes.NewReader(body))
+	default:
+		return fmt.Errorf("usage: profile [list | use name]")
+	}
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("daemon is not reachable on port %d: %w", port, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return daemonError(resp)
+	}
+
+	var status struct {
+		Active   string   `json:"active"`
+		Profiles []string `json:"profiles"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	if req.Method == http.MethodPut {
+		fmt.Printf("Switched to profile %s\n", status.Active)
+		return nil
+	}
+	for _, name := range status.Profiles {
+		marker := "  "
+		if name == status.Active {
+			marker = "* "
+		}
+		fmt.Println(marker + name)
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// runPublish uploads a text clip to a gist or paste service through the
+// daemon and prints the link, which the daemon also copies
+func runPublish(port int, args []string) error {
+	publishFlags := flag.NewFlagSet("publish", flag.ExitOnError)
+	to := publishFlags.String("to", "paste", "Where to upload the clip: gist or paste")
+	publishFlags.Parse(args)
+	// Flags may also follow the clip ID
+	if publishFlags.NArg() > 1 {
+		id := publishFlags.Arg(0)
+		publishFlags.Parse(publishFlags.Args()[1:])
+		args = append([]string{id}, publishFlags.Args()...)
+	} else {
+		args = publishFlags.Args()
+	}
+	if len(args) != 1 {
+		return fmt.Errorf("expected a clip ID")
+	}
+
+	endpoint := fmt.Sprintf("http://localhost:%d/api/clips/%s/publish?to=%s", port, url.PathEscape(args[0]), url.QueryEscape(*to))
+	client := &http.Client{Timeout: 15 * time.Second}
+	resp, err := client.Post(endpoint, "application/json", nil)
+	if err != nil {
+		return fmt.Errorf("daemon is not reachable on port %d: %w", port, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return daemonError(resp)
+	}
+	var published struct {
+		URL string `json:"url"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&published); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	fmt.Println(published.URL)
+	return nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// serviceName identifies the daemon to launchd and systemd
+const serviceName = "clipboard-manager"
+
+var (
+	errServiceNotInstalled = errors.New("service is not installed")
+	errServiceUnsupported  = errors.New("service management is only supported on macOS and Linux")
+)
+
+// runService manages the daemon as a service that starts at login. daemonArgs
+// are the flags the service passes to the daemon when it starts it.
+func runService(args []string, daemonArgs []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing command: expected install, uninstall, start, stop or status")
+	}
+
+	switch args[0] {
+	case "install":
+		exe, err := os.Executable()
+		if err != nil {
+			return fmt.Errorf("failed to locate executable: %w", err)
+		}
+		// Point the service at the real binary, not a symlink that may move
+		if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+			exe = resolved
+		}
+		return installService(exe, daemonArgs)
+	case "uninstall":
+		return uninstallService()
+	case "start":
+		return startService()
+	case "stop":
+		return stopService()
+	case "status":
+		return serviceStatus()
+	default:
+		return fmt.Errorf("unknown command %q: expected install, uninstall, start, stop or status", args[0])
+	}
+}
+
+// daemonFlags returns the flags set on the command line, plus the storage
+// settings taken from the environment, so the installed service runs the
+// daemon the same way
+func daemonFlags() []string {
+	set := make(map[string]bool)
+	var args []string
+	flag.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+		args = append(args, fmt.Sprintf("-%s=%s", f.Name, f.Value.String()))
+	})
+
+	// The service won't inherit this shell's environment
+	for name, env := range map[string]string{"storage": "CLIPBOARD_STORAGE", "dsn": "CLIPBOARD_DSN"} {
+		if !set[name] && os.Getenv(env) != "" {
+			args = append(args, fmt.Sprintf("-%s=%s", name, os.Getenv(env)))
+		}
+	}
+	return args
+}
+
+// runCommand runs a service manager command, including its output in the
+// error if it fails
+func runCommand(name string, args ...string) error {
+	output, err := exec.Command(name, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s %v failed: %w: %s", name, args, err, output)
+	}
+	return nil
+}
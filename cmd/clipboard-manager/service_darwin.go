@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+)
+
+// launchdLabel names the launch agent
+const launchdLabel = "com.hp77." + serviceName
+
+var launchdPIDPattern = regexp.MustCompile(`"PID" = (\d+);`)
+
+// plistPath returns where the launch agent is installed
+func plistPath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, "Library", "LaunchAgents", launchdLabel+".plist"), nil
+}
+
+// launchdDomain is the launchctl domain of the logged in user
+func launchdDomain() string {
+	return fmt.Sprintf("gui/%d", os.Getuid())
+}
+
+// launchdPlist renders a launch agent that runs exe with args at login and
+// restarts it if it crashes
+func launchdPlist(exe string, args []string, logPath string) string {
+	var buf bytes.Buffer
+	str := func(s string) {
+		buf.WriteString("\t\t<string>")
+		xml.EscapeText(&buf, []byte(s))
+		buf.WriteString("</string>\n")
+	}
+
+	buf.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Label</key>
+	<string>` + launchdLabel + `</string>
+	<key>ProgramArguments</key>
+	<array>
+`)
+	str(exe)
+	for _, arg := range args {
+		str(arg)
+	}
+	buf.WriteString(`	</array>
+	<key>RunAtLoad</key>
+	<true/>
+	<key>KeepAlive</key>
+	<dict>
+		<key>SuccessfulExit</key>
+		<false/>
+	</dict>
+	<key>StandardOutPath</key>
+	<string>`)
+	xml.EscapeText(&buf, []byte(logPath))
+	buf.WriteString(`</string>
+	<key>StandardErrorPath</key>
+	<string>`)
+	xml.EscapeText(&buf, []byte(logPath))
+	buf.WriteString(`</string>
+</dict>
+</plist>
+`)
+	return buf.String()
+}
+
+func installService(exe string, args []string) error {
+	path, err := plistPath()
+	if err != nil {
+		return err
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return fmt.Errorf("failed to get home directory: %w", err)
+	}
+	logPath := filepath.Join(homeDir, ".clipboard-manager", serviceName+".log")
+	if err := os.MkdirAll(filepath.Dir(logPath), 0755); err != nil {
+		return fmt.Errorf("failed to create log directory: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create LaunchAgents directory: %w", err)
+	}
+	// Unload an earlier install so the new plist takes effect
+	exec.Command("launchctl", "bootout", launchdDomain(), path).Run()
+	if err := os.WriteFile(path, []byte(launchdPlist(exe, args, logPath)), 0644); err != nil {
+		return fmt.Errorf("failed to write plist: %w", err)
+	}
+
+	if err := runCommand("launchctl", "bootstrap", launchdDomain(), path); err != nil {
+		return err
+	}
+	fmt.Printf("Installed %s\n", path)
+	return nil
+}
+
+func uninstallService() error {
+	path, err := plistPath()
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return errServiceNotInstalled
+	}
+
+	// Not loaded if it was stopped
+	exec.Command("launchctl", "bootout", launchdDomain(), path).Run()
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("failed to remove plist: %w", err)
+	}
+	fmt.Printf("Removed %s\n", path)
+	return nil
+}
+
+func startService() error {
+	path, err := plistPath()
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return errServiceNotInstalled
+	}
+	return runCommand("launchctl", "bootstrap", launchdDomain(), path)
+}
+
+func stopService() error {
+	path, err := plistPath()
+	if err != nil {
+		return err
+	}
+	// Unloading keeps launchd from restarting it; it loads again at next login
+	return runCommand("launchctl", "bootout", launchdDomain(), path)
+}
+
+func serviceStatus() error {
+	path, err := plistPath()
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		fmt.Println("Service is not installed")
+		return nil
+	}
+
+	output, err := exec.Command("launchctl", "list", launchdLabel).Output()
+	switch {
+	case err != nil:
+		fmt.Printf("Service is installed at %s and stopped\n", path)
+	case launchdPIDPattern.Match(output):
+		pid := launchdPIDPattern.FindSubmatch(output)[1]
+		fmt.Printf("Service is installed at %s and running (pid %s)\n", path, pid)
+	default:
+		fmt.Printf("Service is installed at %s and loaded but not running\n", path)
+	}
+	return nil
+}
+
+func restartService() error {
+	path, err := plistPath()
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return errServiceNotInstalled
+	}
+	// -k stops the running daemon first; launchd then starts it from the plist
+	return runCommand("launchctl", "kickstart", "-k", launchdDomain()+"/"+launchdLabel)
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// unitPath returns where the systemd user unit is installed
+func unitPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate config directory: %w", err)
+	}
+	return filepath.Join(configDir, "systemd", "user", serviceName+".service"), nil
+}
+
+// systemdUnit renders a user unit that runs exe with args
+func systemdUnit(exe string, args []string) string {
+	command := []string{systemdQuote(exe)}
+	for _, arg := range args {
+		command = append(command, systemdQuote(arg))
+	}
+
+	return fmt.Sprintf(`[Unit]
+Description=Rockstar clipboard manager
+After=graphical-session.target
+
+[Service]
+ExecStart=%s
+Restart=on-failure
+RestartSec=5
+
+[Install]
+WantedBy=default.target
+`, strings.Join(command, " "))
+}
+
+// systemdQuote quotes a word for ExecStart, escaping specifiers and quotes
+func systemdQuote(word string) string {
+	word = strings.ReplaceAll(word, "%", "%%")
+	if word != "" && !strings.ContainsAny(word, " \t\"'\\;$") {
+		return word
+	}
+	word = strings.ReplaceAll(word, `\`, `\\`)
+	word = strings.ReplaceAll(word, `"`, `\"`)
+	word = strings.ReplaceAll(word, "$", "$$")
+	return `"` + word + `"`
+}
+
+func installService(exe string, args []string) error {
+	path, err := unitPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create unit directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(systemdUnit(exe, args)), 0644); err != nil {
+		return fmt.Errorf("failed to write unit: %w", err)
+	}
+
+	if err := runCommand("systemctl", "--user", "daemon-reload"); err != nil {
+		return err
+	}
+	if err := runCommand("systemctl", "--user", "enable", "--now", serviceName+".service"); err != nil {
+		return err
+	}
+	fmt.Printf("Installed %s\n", path)
+	return nil
+}
+
+func uninstallService() error {
+	path, err := unitPath()
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return errServiceNotInstalled
+	}
+
+	// Keep going if it was never enabled
+	if err := runCommand("systemctl", "--user", "disable", "--now", serviceName+".service"); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("failed to remove unit: %w", err)
+	}
+	if err := runCommand("systemctl", "--user", "daemon-reload"); err != nil {
+		return err
+	}
+	fmt.Printf("Removed %s\n", path)
+	return nil
+}
+
+func startService() error {
+	return runCommand("systemctl", "--user", "start", serviceName+".service")
+}
+
+func stopService() error {
+	return runCommand("systemctl", "--user", "stop", serviceName+".service")
+}
+
+func serviceStatus() error {
+	path, err := unitPath()
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		fmt.Println("Service is not installed")
+		return nil
+	}
+
+	// is-active exits non-zero when the service is stopped, which isn't an error here
+	output, _ := exec.Command("systemctl", "--user", "is-active", serviceName+".service").Output()
+	fmt.Printf("Service is installed at %s and %s\n", path, strings.TrimSpace(string(output)))
+	return nil
+}
+
+func restartService() error {
+	path, err := unitPath()
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return errServiceNotInstalled
+	}
+	return runCommand("systemctl", "--user", "restart", serviceName+".service")
+}
@@ -0,0 +1,15 @@
+//go:build !darwin && !linux
+
+package main
+
+func installService(exe string, args []string) error { return errServiceUnsupported }
+
+func uninstallService() error { return errServiceUnsupported }
+
+func startService() error { return errServiceUnsupported }
+
+func stopService() error { return errServiceUnsupported }
+
+func serviceStatus() error { return errServiceUnsupported }
+
+func restartService() error { return errServiceUnsupported }
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// stackStatus is the paste stack as the daemon reports it
+type stackStatus struct {
+	Active    bool     `json:"active"`
+	IDs       []string `json:"ids"`
+	Next      int      `json:"next"`
+	Remaining int      `json:"remaining"`
+}
+
+// runStack starts a paste stack of the given clip IDs, clears it, or prints
+// its state when given no arguments
+func runStack(port int, args []string) error {
+	endpoint := fmt.Sprintf("http://localhost:%d/api/stack", port)
+	var req *http.Request
+	var err error
+	switch {
+	case len(args) == 0:
+		req, err = http.NewRequest(http.MethodGet, endpoint, nil)
+	case len(args) == 1 && args[0] == "clear":
+		req, err = http.NewRequest(http.MethodDelete, endpoint, nil)
+	default:
+		body, _ := json.Marshal(map[string][]string{"ids": args})
+		req, err = http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+	}
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("daemon is not reachable on port %d: %w", port, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNoContent:
+		fmt.Println("Paste stack cleared")
+		return nil
+	default:
+		return daemonError(resp)
+	}
+
+	var status stackStatus
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	if !status.Active {
+		fmt.Println("No paste stack")
+		return nil
+	}
+	fmt.Printf("Paste stack: %d of %d clips copied, %d left\n", status.Next, len(status.IDs), status.Remaining)
+	return nil
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"clipboard-manager/internal/storage"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// statsBarWidth is the length of the longest histogram bar
+const statsBarWidth = 40
+
+// runStats prints clipboard history statistics from the running daemon
+func runStats(port int, args []string) error {
+	statsFlags := flag.NewFlagSet("stats", flag.ExitOnError)
+	asJSON := statsFlags.Bool("json", false, "Print the statistics as JSON")
+	top := statsFlags.Int("top", 10, "Number of apps and types to list")
+	statsFlags.Parse(args)
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	var stats storage.Stats
+	if err := getJSON(client, fmt.Sprintf("http://localhost:%d/api/stats", port), &stats); err != nil {
+		return err
+	}
+	if *asJSON {
+		return json.NewEncoder(os.Stdout).Encode(stats)
+	}
+
+	fmt.Printf("%d clips, %s in total, %s on average\n", stats.Clips, formatBytes(stats.Bytes), formatBytes(stats.AverageBytes))
+	if stats.Usage.DatabaseBytes > 0 || stats.Usage.ExternalBytes > 0 {
+		fmt.Printf("Storage: %s database, %s in files\n", formatBytes(stats.Usage.DatabaseBytes), formatBytes(stats.Usage.ExternalBytes))
+	}
+	if m := stats.Maintenance; m != nil {
+		last := "never run"
+		if m.LastRun != nil {
+			kind := "checkpoint"
+			if m.LastRun.Full {
+				kind = "full"
+			}
+			last = fmt.Sprintf("last %s run %s ago", kind, time.Since(m.LastRun.Started).Round(time.Second))
+		}
+		fmt.Printf("Maintenance: %s, %d runs; %s WAL, %s free\n", last, m.Runs, formatBytes(m.WALBytes), formatBytes(m.FreeBytes))
+	}
+
+	fmt.Println()
+	printCounts("APP", stats.ByApp, *top)
+	fmt.Println()
+	printCounts("TYPE", stats.ByType, *top)
+
+	fmt.Printf("\nLast %d days\n", len(stats.ByDay))
+	var days []int64
+	for _, day := range stats.ByDay {
+		days = append(days, day.Clips)
+	}
+	for i, day := range stats.ByDay {
+		// Keys are YYYY-MM-DD; the month and day are enough here
+		label := day.Key
+		if len(label) == len("2006-01-02") {
+			label = label[5:]
+		}
+		fmt.Printf("%s %s\n", label, bar(days, i))
+	}
+
+	fmt.Println("\nBy hour")
+	for hour := range stats.ByHour {
+		fmt.Printf("%02d    %s\n", hour, bar(stats.ByHour[:], hour))
+	}
+	return nil
+}
+
+// printCounts prints the first n counts as a table
+func printCounts(heading string, counts []storage.Count, n int) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "%s\tCLIPS\tSIZE\n", heading)
+	for i, count := range counts {
+		if i == n {
+			fmt.Fprintf(w, "… %d more\t\t\n", len(counts)-n)
+			break
+		}
+		key := count.Key
+		if key == "" {
+			key = "(unknown)"
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\n", key, count.Clips, formatBytes(count.Bytes))
+	}
+	w.Flush()
+}
+
+// bar draws values[i] as a bar scaled to the largest value, followed by it
+func bar(values []int64, i int) string {
+	var max int64
+	for _, v := range values {
+		if v > max {
+			max = v
+		}
+	}
+	if max == 0 || values[i] == 0 {
+		return "0"
+	}
+	width := int(values[i] * statsBarWidth / max)
+	if width == 0 {
+		width = 1
+	}
+	return fmt.Sprintf("%s %d", strings.Repeat("█", width), values[i])
+}
+
+// formatBytes formats a size with a binary unit, e.g. "1.5 MB"
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
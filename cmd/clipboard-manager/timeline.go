@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bufio"
+	"clipboard-manager/internal/storage"
+	"clipboard-manager/pkg/types"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// Layouts of the bucket keys timeline prints and takes back
+const (
+	timelineDayLayout  = "2006-01-02"
+	timelineHourLayout = "2006-01-02 15:04"
+)
+
+// runTimeline browses the history by time. Without an argument it prints
+// each day, or hour with -hour, that has clips as its key, weekday and a bar
+// of its clip count separated by tabs. Given one of those keys it prints the
+// clips copied then, like pick, so fzf makes a navigator of the two:
+//
+//	clipboard-manager timeline -hour | fzf -d '\t' --tac --preview 'clipboard-manager timeline {1}'
+func runTimeline(port int, args []string) error {
+	timelineFlags := flag.NewFlagSet("timeline", flag.ExitOnError)
+	hourly := timelineFlags.Bool("hour", false, "Count clips by hour instead of by day")
+	from := timelineFlags.String("from", "", "Start at this date, YYYY-MM-DD")
+	to := timelineFlags.String("to", "", "End with this date, YYYY-MM-DD")
+	limit := timelineFlags.Int("limit", 200, "Most clips to print for a day or hour")
+	asJSON := timelineFlags.Bool("json", false, "Print the buckets as JSON")
+	timelineFlags.Parse(args)
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	base := fmt.Sprintf("http://localhost:%d/api", port)
+	if timelineFlags.NArg() > 0 {
+		start, end, err := parseTimelineKey(timelineFlags.Arg(0))
+		if err != nil {
+			return err
+		}
+		// The search range is inclusive, the bucket's isn't
+		params := url.Values{
+			"from":  {start.Format(time.RFC3339)},
+			"to":    {end.Add(-time.Nanosecond).Format(time.RFC3339Nano)},
+			"limit": {fmt.Sprint(*limit)},
+		}
+		var results []storage.SearchResult
+		if err := getJSON(client, base+"/search?"+params.Encode(), &results); err != nil {
+			return err
+		}
+		clips := make([]*types.Clip, 0, len(results))
+		for _, result := range results {
+			clips = append(clips, result.Clip)
+		}
+		return writePickLines(clips)
+	}
+
+	params := url.Values{"granularity": {storage.GranularityDay}}
+	layout := timelineDayLayout
+	if *hourly {
+		params.Set("granularity", storage.GranularityHour)
+		layout = timelineHourLayout
+	}
+	if *from != "" {
+		params.Set("from", *from)
+	}
+	if *to != "" {
+		params.Set("to", *to)
+	}
+	var buckets []storage.TimelineBucket
+	if err := getJSON(client, base+"/timeline?"+params.Encode(), &buckets); err != nil {
+		return err
+	}
+	if *asJSON {
+		return json.NewEncoder(os.Stdout).Encode(buckets)
+	}
+
+	counts := make([]int64, len(buckets))
+	for i, bucket := range buckets {
+		counts[i] = bucket.Clips
+	}
+	w := bufio.NewWriter(os.Stdout)
+	for i, bucket := range buckets {
+		start := bucket.Start.Local()
+		fmt.Fprintf(w, "%s\t%s\t%s\n", start.Format(layout), start.Format("Mon"), bar(counts, i))
+	}
+	return w.Flush()
+}
+
+// parseTimelineKey returns the day or hour a key printed by timeline stands
+// for, in local time
+func parseTimelineKey(key string) (start, end time.Time, err error) {
+	if start, err = time.ParseInLocation(timelineHourLayout, key, time.Local); err == nil {
+		return start, start.Add(time.Hour), nil
+	}
+	if start, err = time.ParseInLocation(timelineDayLayout, key, time.Local); err == nil {
+		return start, start.AddDate(0, 0, 1), nil
+	}
+	return time.Time{}, time.Time{}, fmt.Errorf("invalid day or hour %q, expected YYYY-MM-DD or YYYY-MM-DD HH:MM", key)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// runTranslate translates a text clip through the daemon and prints the
+// translation, which the daemon copies or, with -store, keeps as a clip
+func runTranslate(port int, args []string) error {
+	translateFlags := flag.NewFlagSet("translate", flag.ExitOnError)
+	to := translateFlags.String("to", "", "Language to translate into, such as de (default: translate.target in the settings, or en)")
+	store := translateFlags.Bool("store", false, "Keep the translation as a clip related to the original instead of copying it")
+	translateFlags.Parse(args)
+	// Flags may also follow the clip ID
+	if translateFlags.NArg() > 1 {
+		id := translateFlags.Arg(0)
+		translateFlags.Parse(translateFlags.Args()[1:])
+		args = append([]string{id}, translateFlags.Args()...)
+	} else {
+		args = translateFlags.Args()
+	}
+	if len(args) != 1 {
+		return fmt.Errorf("expected a clip ID")
+	}
+
+	query := url.Values{"to": {*to}, "store": {fmt.Sprint(*store)}}
+	endpoint := fmt.Sprintf("http://localhost:%d/api/clips/%s/translate?%s", port, url.PathEscape(args[0]), query.Encode())
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Post(endpoint, "application/json", nil)
+	if err != nil {
+		return fmt.Errorf("daemon is not reachable on port %d: %w", port, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return daemonError(resp)
+	}
+	var translation struct {
+		Text string `json:"text"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&translation); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	fmt.Println(translation.Text)
+	return nil
+}
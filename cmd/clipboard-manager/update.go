@@ -0,0 +1,76 @@
+package main
+
+import (
+	"clipboard-manager/internal/server"
+	"clipboard-manager/internal/update"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// version is the release this binary was built from, set with
+// -ldflags "-X main.version=v1.2.3"
+var version = "dev"
+
+// updateTimeout bounds checking for and downloading a release
+const updateTimeout = 5 * time.Minute
+
+// runUpdate replaces this binary with the latest release and restarts the
+// daemon through the service manager
+func runUpdate(args []string) error {
+	updateFlags := flag.NewFlagSet("update", flag.ExitOnError)
+	checkOnly := updateFlags.Bool("check", false, "Only report whether a newer release is out")
+	force := updateFlags.Bool("force", false, "Install the latest release even if it isn't newer")
+	updateFlags.Parse(args)
+
+	ctx, cancel := context.WithTimeout(context.Background(), updateTimeout)
+	defer cancel()
+
+	updater := update.Updater{Client: &http.Client{}}
+	release, err := updater.Latest(ctx)
+	if err != nil {
+		return err
+	}
+	if !update.Newer(release.Version, version) && !*force {
+		fmt.Printf("Clipboard manager %s is up to date\n", version)
+		return nil
+	}
+	if *checkOnly {
+		fmt.Printf("Clipboard manager %s is out, this is %s: %s\n", release.Version, version, release.URL)
+		return nil
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate executable: %w", err)
+	}
+	// Replace the real binary, which the service runs, not a symlink to it
+	if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+		exe = resolved
+	}
+	if err := updater.Install(ctx, release, exe); err != nil {
+		return err
+	}
+	fmt.Printf("Updated %s from %s to %s\n", exe, version, release.Version)
+
+	err = restartService()
+	switch {
+	case err == nil:
+		fmt.Println("Restarted the daemon")
+		return nil
+	case errors.Is(err, errServiceNotInstalled) || errors.Is(err, errServiceUnsupported):
+	default:
+		return fmt.Errorf("failed to restart the daemon: %w", err)
+	}
+
+	// Not run by a service manager; a running daemon has to be replaced by hand
+	if pid, err := server.RunningPID(); err == nil && pid != 0 {
+		fmt.Printf("The daemon (pid %d) still runs the old version; start it again with -replace\n", pid)
+	}
+	return nil
+}
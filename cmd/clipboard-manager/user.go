@@ -0,0 +1,107 @@
+package main
+
+import (
+	"clipboard-manager/internal/audit"
+	"clipboard-manager/internal/auth"
+	"flag"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// cliTransport marks requests to the daemon as coming from the command line,
+// for the audit log, and adds the API token if there is one
+type cliTransport struct {
+	token string
+	next  http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t cliTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Hostname() == "localhost" || req.URL.Hostname() == "127.0.0.1" {
+		req = req.Clone(req.Context())
+		req.Header.Set("User-Agent", audit.CLIUserAgent)
+		if t.token != "" {
+			req.Header.Set("Authorization", "Bearer "+t.token)
+		}
+	}
+	return t.next.RoundTrip(req)
+}
+
+// runUser manages the users allowed to call the API. It edits the users
+// file directly, which the daemon reads again when it changes.
+func runUser(path string, args []string) error {
+	usage := fmt.Errorf("usage: user list | add [-admin] [-scope read|paste|full] name | token [-scope read|paste|full] name | remove name")
+	if len(args) == 0 {
+		return usage
+	}
+	users, err := auth.Open(path)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "list":
+		for _, user := range users.List() {
+			role := "user"
+			if user.Admin {
+				role = "admin"
+			}
+			scopes := make([]string, len(user.Tokens))
+			for i, t := range user.Tokens {
+				scopes[i] = t.ID + ":" + t.Scope
+				if t.Scope == "" {
+					scopes[i] = t.ID + ":" + auth.ScopeFull
+				}
+			}
+			fmt.Printf("%-20s %-5s added %s, tokens %s\n", user.Name, role, user.CreatedAt.Format("2006-01-02"), strings.Join(scopes, " "))
+		}
+		return nil
+	case "add":
+		addFlags := flag.NewFlagSet("user add", flag.ContinueOnError)
+		admin := addFlags.Bool("admin", false, "Let the user manage the daemon and see its own history")
+		scope := addFlags.String("scope", auth.ScopeFull, "What the token can do: read, paste or full")
+		if err := addFlags.Parse(args[1:]); err != nil {
+			return err
+		}
+		if addFlags.NArg() != 1 {
+			return usage
+		}
+		first := !users.Enabled()
+		user, token, err := users.Add(addFlags.Arg(0), *admin, *scope)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Added %s. Their API token, shown only once:\n%s\n", user.Name, token)
+		if first && !user.Admin {
+			fmt.Println("The API now requires a token; add an admin with \"user add -admin\" to manage the daemon.")
+		}
+		return nil
+	case "token":
+		tokenFlags := flag.NewFlagSet("user token", flag.ContinueOnError)
+		scope := tokenFlags.String("scope", auth.ScopeFull, "What the token can do: read, paste or full")
+		if err := tokenFlags.Parse(args[1:]); err != nil {
+			return err
+		}
+		if tokenFlags.NArg() != 1 {
+			return usage
+		}
+		name := tokenFlags.Arg(0)
+		token, err := users.NewToken(name, *scope)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("New %s API token for %s, shown only once:\n%s\n", *scope, name, token)
+		return nil
+	case "remove":
+		if len(args) != 2 {
+			return usage
+		}
+		if err := users.Remove(args[1]); err != nil {
+			return err
+		}
+		fmt.Printf("Removed %s; their history is kept on disk\n", args[1])
+		return nil
+	}
+	return usage
+}
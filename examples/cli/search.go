@@ -1,12 +1,12 @@
 package cmd
 
 import (
-	"clipboard-manager/internal/storage"
+	"clipboard-manager/internal/paste"
+	"clipboard-manager/pkg/clipman"
 	"clipboard-manager/pkg/types"
+	"context"
 	"fmt"
 	"os"
-	"os/exec"
-	"runtime"
 	"strings"
 	"text/tabwriter"
 	"time"
@@ -16,24 +16,24 @@ import (
 
 // SearchCommand handles searching and pasting clipboard history
 type SearchCommand struct {
-	store storage.SearchService
+	store clipman.SearchService
 }
 
 // NewSearchCommand creates a new search command
-func NewSearchCommand(store storage.SearchService) *SearchCommand {
+func NewSearchCommand(store clipman.SearchService) *SearchCommand {
 	return &SearchCommand{store: store}
 }
 
 // Search searches clipboard history and displays results
 func (c *SearchCommand) Search(query string, limit int) error {
-	opts := storage.SearchOptions{
+	opts := clipman.SearchOptions{
 		Query:     query,
 		Limit:     limit,
 		SortBy:    "last_used",
 		SortOrder: "desc",
 	}
 
-	results, err := c.store.Search(opts)
+	results, err := c.store.Search(context.Background(), opts)
 	if err != nil {
 		return fmt.Errorf("search failed: %w", err)
 	}
@@ -67,7 +67,7 @@ func (c *SearchCommand) Search(query string, limit int) error {
 // Paste copies the content with given ID to clipboard and simulates Command+V
 func (c *SearchCommand) Paste(id string) error {
 	// Get the clip
-	results, err := c.store.Search(storage.SearchOptions{
+	results, err := c.store.Search(context.Background(), clipman.SearchOptions{
 		Query: id,
 		Limit: 1,
 	})
@@ -86,28 +86,21 @@ func (c *SearchCommand) Paste(id string) error {
 
 	// Set content based on type
 	switch clip.Type {
-	case "text":
+	case types.TypeText:
 		pb.SetStringForType(string(clip.Content), appkit.PasteboardType("public.utf8-plain-text"))
-	case "image/png":
+	case types.TypePNG:
 		pb.SetDataForType(clip.Content, appkit.PasteboardType("public.png"))
-	case "image/tiff":
+	case types.TypeTIFF:
 		pb.SetDataForType(clip.Content, appkit.PasteboardType("public.tiff"))
-	case "file":
+	case types.TypeFile:
 		pb.SetStringForType(string(clip.Content), appkit.PasteboardType("public.file-url"))
 	default:
 		return fmt.Errorf("unsupported content type: %s", clip.Type)
 	}
 
-	// Simulate Command+V using osascript
-	if runtime.GOOS == "darwin" {
-		cmd := exec.Command("osascript", "-e", `
-			tell application "System Events"
-				keystroke "v" using command down
-			end tell
-		`)
-		if err := cmd.Run(); err != nil {
-			return fmt.Errorf("failed to simulate paste: %w", err)
-		}
+	// Press the paste shortcut in the frontmost app
+	if err := paste.New().Paste(context.Background()); err != nil {
+		return fmt.Errorf("failed to simulate paste: %w", err)
 	}
 
 	return nil
@@ -118,16 +111,16 @@ func getPreview(clip *types.Clip) string {
 	const maxPreviewLength = 50
 
 	switch clip.Type {
-	case "text":
+	case types.TypeText:
 		text := string(clip.Content)
 		text = strings.ReplaceAll(text, "\n", " ")
 		if len(text) > maxPreviewLength {
 			text = text[:maxPreviewLength] + "..."
 		}
 		return text
-	case "image/png", "image/tiff":
+	case types.TypePNG, types.TypeTIFF:
 		return fmt.Sprintf("[Image %d bytes]", len(clip.Content))
-	case "file":
+	case types.TypeFile:
 		return fmt.Sprintf("[File URL: %s]", string(clip.Content))
 	default:
 		return fmt.Sprintf("[%s %d bytes]", clip.Type, len(clip.Content))
@@ -1,10 +1,7 @@
 package examples
 
 import (
-	"clipboard-manager/internal/clipboard"
-	"clipboard-manager/internal/service"
-	"clipboard-manager/internal/storage"
-	"clipboard-manager/internal/storage/sqlite"
+	"clipboard-manager/pkg/clipman"
 	"context"
 	"fmt"
 	"log"
@@ -21,8 +18,7 @@ func RunClipboardHistoryTest() {
 	}
 	defer os.RemoveAll(tempDir)
 
-	store, err := sqlite.New(storage.Config{
-		DBPath: filepath.Join(tempDir, "clipboard.db"),
+	store, err := clipman.OpenStorage("sqlite", filepath.Join(tempDir, "clipboard.db"), clipman.StorageConfig{
 		FSPath: filepath.Join(tempDir, "files"),
 	})
 	if err != nil {
@@ -30,8 +26,8 @@ func RunClipboardHistoryTest() {
 	}
 
 	// 2. Create clipboard service
-	monitor := clipboard.NewMonitor()
-	clipService := service.New(monitor, store)
+	monitor := clipman.NewMonitor()
+	clipService := clipman.New(monitor, store)
 
 	// 3. Start monitoring
 	if err := clipService.Start(); err != nil {
@@ -47,7 +43,7 @@ func RunClipboardHistoryTest() {
 	time.Sleep(3 * time.Second)
 
 	// Debug: List clips after first copy
-	clips, err := store.List(ctx, storage.ListFilter{Limit: 10})
+	clips, err := store.List(ctx, clipman.ListFilter{Limit: 10})
 	if err != nil {
 		log.Printf("Error listing clips: %v", err)
 	} else {
@@ -61,7 +57,7 @@ func RunClipboardHistoryTest() {
 	time.Sleep(3 * time.Second)
 
 	// Debug: List clips after second copy
-	clips, err = store.List(ctx, storage.ListFilter{Limit: 10})
+	clips, err = store.List(ctx, clipman.ListFilter{Limit: 10})
 	if err != nil {
 		log.Printf("Error listing clips: %v", err)
 	} else {
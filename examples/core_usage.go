@@ -1,13 +1,11 @@
 package examples
 
 import (
-	"clipboard-manager/internal/clipboard"
-	"clipboard-manager/internal/service"
-	"clipboard-manager/internal/storage"
-	"clipboard-manager/internal/storage/sqlite"
+	"clipboard-manager/pkg/clipman"
 	"clipboard-manager/pkg/types"
 	"context"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
@@ -22,8 +20,7 @@ func Example() {
 	}
 
 	baseDir := filepath.Join(homeDir, ".clipboard-manager")
-	store, err := sqlite.New(storage.Config{
-		DBPath: filepath.Join(baseDir, "clipboard.db"),
+	store, err := clipman.OpenStorage("sqlite", filepath.Join(baseDir, "clipboard.db"), clipman.StorageConfig{
 		FSPath: filepath.Join(baseDir, "files"),
 	})
 	if err != nil {
@@ -31,10 +28,10 @@ func Example() {
 	}
 
 	// 2. Create clipboard monitor
-	monitor := clipboard.NewMonitor()
+	monitor := clipman.NewMonitor()
 
 	// 3. Create clipboard service
-	clipService := service.New(monitor, store)
+	clipService := clipman.New(monitor, store)
 
 	// 4. Start monitoring clipboard
 	if err := clipService.Start(); err != nil {
@@ -42,13 +39,15 @@ func Example() {
 	}
 	defer clipService.Stop()
 
-	// 5. Search functionality example
-	results, err := store.Search(storage.SearchOptions{
-		Query:     "example",           // Search for specific content
-		Type:      storage.TypeText,    // Filter by type
-		SortBy:    "last_used",        // Sort by timestamp
-		SortOrder: "desc",             // Most recent first
-		Limit:     10,                 // Limit results
+	// 5. Search functionality example. The built-in backends all implement
+	// SearchService.
+	searcher := store.(clipman.SearchService)
+	results, err := searcher.Search(context.Background(), clipman.SearchOptions{
+		Query:     "example",      // Search for specific content
+		Type:      types.TypeText, // Filter by type
+		SortBy:    "last_used",    // Sort by timestamp
+		SortOrder: "desc",         // Most recent first
+		Limit:     10,             // Limit results
 	})
 	if err != nil {
 		log.Fatal(err)
@@ -56,21 +55,21 @@ func Example() {
 
 	// 6. Process search results
 	for _, result := range results {
-		fmt.Printf("Found clip: %s (type: %s)\n", 
+		fmt.Printf("Found clip: %s (type: %s)\n",
 			string(result.Clip.Content),
 			result.Clip.Type,
 		)
 	}
 
-	// 7. Get recent clips
-	recent, err := store.GetRecent(5)
+	// 7. Get recent clips, which takes the same filters as Search
+	recent, err := searcher.GetRecent(context.Background(), clipman.SearchOptions{Limit: 5})
 	if err != nil {
 		log.Fatal(err)
 	}
 	fmt.Printf("Recent clips: %d\n", len(recent))
 
 	// 8. Get clips by type
-	images, err := store.GetByType(storage.TypeImage, 5)
+	images, err := searcher.GetByType(context.Background(), types.TypePNG, clipman.SearchOptions{Limit: 5})
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -86,7 +85,7 @@ func Example() {
 	}
 
 	// Store new content
-	clip, err := store.Store(ctx, content, storage.TypeText, metadata)
+	clip, err := store.Store(ctx, content, types.TypeText, metadata)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -124,22 +123,44 @@ type CustomStorage struct {
 	// Your storage fields
 }
 
+var _ clipman.Storage = (*CustomStorage)(nil)
+
 func (s *CustomStorage) Store(ctx context.Context, content []byte, clipType string, metadata types.Metadata) (*types.Clip, error) {
 	// Your implementation
 	return nil, nil
 }
 
+func (s *CustomStorage) StoreStream(ctx context.Context, r io.Reader, clipType string, metadata types.Metadata) (*types.Clip, error) {
+	// Your implementation
+	return nil, nil
+}
+
 func (s *CustomStorage) Get(ctx context.Context, id string) (*types.Clip, error) {
 	// Your implementation
 	return nil, nil
 }
 
+func (s *CustomStorage) GetStream(ctx context.Context, id string) (io.ReadCloser, *types.Clip, error) {
+	// Your implementation
+	return nil, nil, nil
+}
+
 func (s *CustomStorage) Delete(ctx context.Context, id string) error {
 	// Your implementation
 	return nil
 }
 
-func (s *CustomStorage) List(ctx context.Context, filter storage.ListFilter) ([]*types.Clip, error) {
+func (s *CustomStorage) List(ctx context.Context, filter clipman.ListFilter) ([]*types.Clip, error) {
+	// Your implementation
+	return nil, nil
+}
+
+func (s *CustomStorage) MarkAsSynced(ctx context.Context, id string) error {
+	// Your implementation
+	return nil
+}
+
+func (s *CustomStorage) ListUnsynced(ctx context.Context, limit int) ([]*types.Clip, error) {
 	// Your implementation
 	return nil, nil
 }
@@ -149,6 +170,8 @@ type CustomMonitor struct {
 	// Your monitor fields
 }
 
+var _ clipman.Monitor = (*CustomMonitor)(nil)
+
 func (m *CustomMonitor) Start() error {
 	// Your implementation
 	return nil
@@ -175,7 +198,7 @@ func ExampleCustomImplementation() {
 	monitor := &CustomMonitor{}
 
 	// Create service with custom components
-	clipService := service.New(monitor, store)
+	clipService := clipman.New(monitor, store)
 
 	// Use the service as normal
 	if err := clipService.Start(); err != nil {
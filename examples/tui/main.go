@@ -1,23 +1,184 @@
 package cmd
 
 import (
-	"clipboard-manager/internal/storage"
+	"bytes"
+	"clipboard-manager/pkg/clipman"
+	"clipboard-manager/pkg/types"
+	"context"
+	"encoding/json"
 	"fmt"
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
 	"github.com/gdamore/tcell/v2"
+	qrcode "github.com/skip2/go-qrcode"
+	"io"
+	"net/http"
 	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
+// DefaultDaemonURL is where the daemon listens unless started with -port
+const DefaultDaemonURL = "http://localhost:54321"
+
+// Daemon is the running clipboard manager the TUI asks to paste, translate
+// and read clips aloud, since only it owns the clipboard and the settings,
+// and whether it is paused
+type Daemon struct {
+	URL   string // DefaultDaemonURL when empty
+	Token string // API token, needed once the daemon has users, as in CLIPBOARD_TOKEN
+}
+
+// do sends a request to path with body encoded as JSON, or without a body if
+// it is nil, failing with the daemon's message unless it answers want. The
+// caller closes the response's body.
+func (d Daemon) do(method, path string, body interface{}, want int) (*http.Response, error) {
+	url := d.URL
+	if url == "" {
+		url = DefaultDaemonURL
+	}
+	var content io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		content = bytes.NewReader(encoded)
+	}
+	req, err := http.NewRequest(method, url+path, content)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if d.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+d.Token)
+	}
+	client := http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("daemon not reachable at %s", url)
+	}
+	if resp.StatusCode != want {
+		defer resp.Body.Close()
+		var failure struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&failure) == nil && failure.Error.Message != "" {
+			return nil, fmt.Errorf("%s", failure.Error.Message)
+		}
+		return nil, fmt.Errorf("daemon answered %s", resp.Status)
+	}
+	return resp, nil
+}
+
+// post sends an empty POST to path, failing unless the daemon answers want
+func (d Daemon) post(path string, want int) error {
+	return d.postJSON(path, nil, nil, want)
+}
+
+// postJSON POSTs body to path as JSON and decodes the answer into result
+// unless it is nil, failing unless the daemon answers want
+func (d Daemon) postJSON(path string, body, result interface{}, want int) error {
+	resp, err := d.do(http.MethodPost, path, body, want)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if result != nil {
+		return json.NewDecoder(resp.Body).Decode(result)
+	}
+	return nil
+}
+
+// idsRequest is the body of POST /api/stack
+type idsRequest struct {
+	IDs []string `json:"ids"`
+}
+
+// concatRequest is the body of POST /api/clips/merge that joins clips into
+// a new one, in the order of IDs and a line each
+type concatRequest struct {
+	Action string   `json:"action"` // "concat"
+	IDs    []string `json:"ids"`
+}
+
+// bulkRequest is the body of POST /api/clips/bulk
+type bulkRequest struct {
+	Action string   `json:"action"`
+	IDs    []string `json:"ids"`
+}
+
+// getJSON GETs path and decodes the daemon's answer into result
+func (d Daemon) getJSON(path string, result interface{}) error {
+	resp, err := d.do(http.MethodGet, path, nil, http.StatusOK)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+// timelineBucket is a day or hour with clips, from GET /api/timeline
+type timelineBucket struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	Clips int64     `json:"clips"`
+}
+
+// pauseState is whether the daemon is recording history, from its /status
+type pauseState struct {
+	Paused bool      `json:"paused"`
+	Until  time.Time `json:"paused_until"` // Zero until resumed by hand
+}
+
+// paused asks the daemon whether it is paused
+func (d Daemon) paused() (pauseState, error) {
+	var state pauseState
+	err := d.getJSON("/status", &state)
+	return state, err
+}
+
+// pausePoll is how often the TUI asks the daemon whether it is paused
+const pausePoll = 5 * time.Second
+
 type InteractiveMode struct {
-	store      storage.SearchService
+	store      clipman.SearchService
+	daemon     Daemon
 	screen     tcell.Screen
-	results    []storage.SearchResult
+	results    []clipman.SearchResult
+	cursor     string // Where the page after results starts, "" after the last page
 	selected   int
 	offset     int
 	searchMode bool
 	searchText string
+	sendMode   bool   // The next key picks where the selected clip is sent
+	status     string // What the last action did, or why it failed
+
+	marked []string // IDs of the clips marked for bulk actions, in the order they were marked
+
+	qr [][]bool // QR code of the selected clip, shown over the list until a key is pressed
+
+	screenshots bool          // Only screenshots are listed
+	related     *clipman.Clip // Only the clips copied together with this one are listed
+	detail      bool          // The selected clip is shown beside the list, on wide enough screens
+
+	timeline []timelineBucket // Days or hours with clips, newest first, listed instead of clips while browsing the timeline
+	hourly   bool             // The timeline is by the hour rather than the day
+	period   *timelineBucket  // Only the clips copied in this day or hour are listed
+	browsed  int              // The selected day or hour, to return to from its clips
+
+	pause pauseState // Last known, so copies aren't expected to show up while paused
 }
 
-func NewInteractiveMode(store storage.SearchService) (*InteractiveMode, error) {
+// NewInteractiveMode browses the clips in store. Pasting, translating and
+// reading aloud go through daemon.
+func NewInteractiveMode(store clipman.SearchService, daemon Daemon) (*InteractiveMode, error) {
 	screen, err := tcell.NewScreen()
 	if err != nil {
 		return nil, fmt.Errorf("failed to create screen: %w", err)
@@ -34,18 +195,22 @@ func NewInteractiveMode(store storage.SearchService) (*InteractiveMode, error) {
 
 	return &InteractiveMode{
 		store:    store,
+		daemon:   daemon,
 		screen:   screen,
 		selected: 0,
 		offset:   0,
+		detail:   true,
 	}, nil
 }
 
 func (im *InteractiveMode) Run() error {
 	defer im.screen.Fini()
 
-	if err := im.loadResults(""); err != nil {
-		return err
-	}
+	im.loadResults("")
+
+	done := make(chan struct{})
+	defer close(done)
+	go im.watchPause(done)
 
 	for {
 		im.draw()
@@ -53,20 +218,25 @@ func (im *InteractiveMode) Run() error {
 		switch ev := im.screen.PollEvent().(type) {
 		case *tcell.EventResize:
 			im.screen.Sync()
+		case *tcell.EventInterrupt:
+			if state, ok := ev.Data().(pauseState); ok {
+				im.pause = state
+			}
 		case *tcell.EventKey:
+			im.status = ""
+			if im.qr != nil {
+				im.qr = nil
+				continue
+			}
 			if im.searchMode {
 				switch ev.Key() {
 				case tcell.KeyEscape:
 					im.searchMode = false
 					im.searchText = ""
-					if err := im.loadResults(""); err != nil {
-						return err
-					}
+					im.loadResults("")
 				case tcell.KeyEnter:
-					im.searchMode = false
-					if err := im.loadResults(im.searchText); err != nil {
-						return err
-					}
+					// A query that doesn't parse stays open to be fixed
+					im.searchMode = !im.loadResults(im.searchText)
 				case tcell.KeyBackspace, tcell.KeyBackspace2:
 					if len(im.searchText) > 0 {
 						im.searchText = im.searchText[:len(im.searchText)-1]
@@ -76,9 +246,33 @@ func (im *InteractiveMode) Run() error {
 				}
 				continue
 			}
+			if im.sendMode {
+				im.sendMode = false
+				if target, ok := sendTargets[ev.Rune()]; ok && ev.Key() == tcell.KeyRune {
+					im.sendSelected(target)
+				}
+				continue
+			}
+			if im.timeline != nil {
+				if im.timelineKey(ev) {
+					return nil
+				}
+				continue
+			}
 
 			switch ev.Key() {
-			case tcell.KeyEscape, tcell.KeyCtrlC:
+			case tcell.KeyEscape:
+				// Leave the clips copied together, or in a day or hour of
+				// the timeline, for the whole history
+				switch {
+				case im.related != nil:
+					im.loadResults(im.searchText)
+				case im.period != nil:
+					im.showTimeline()
+				default:
+					return nil
+				}
+			case tcell.KeyCtrlC:
 				return nil
 			case tcell.KeyUp, tcell.KeyCtrlP:
 				im.moveSelection(-1)
@@ -87,17 +281,42 @@ func (im *InteractiveMode) Run() error {
 			case tcell.KeyHome, tcell.KeyCtrlA:
 				im.selected = 0
 			case tcell.KeyEnd, tcell.KeyCtrlE:
-				im.selected = len(im.results) - 1
+				im.moveSelection(len(im.results))
 			case tcell.KeyPgUp:
 				im.moveSelection(-10)
 			case tcell.KeyPgDn:
 				im.moveSelection(10)
 			case tcell.KeyEnter, tcell.KeyCtrlV:
-				if len(im.results) > 0 {
-					return im.pasteSelected()
+				if len(im.results) > 0 && im.pasteSelected() {
+					return nil
 				}
 			case tcell.KeyRune:
 				switch ev.Rune() {
+				case ' ':
+					if len(im.results) > 0 {
+						im.toggleMark()
+						im.moveSelection(1)
+					}
+				case 'x':
+					if len(im.results) > 0 {
+						im.deleteMarked()
+					}
+				case 'p':
+					if len(im.results) > 0 {
+						im.stackMarked()
+					}
+				case 'M':
+					im.concatMarked()
+				case 'n':
+					if len(im.results) > 0 {
+						im.exportMarked()
+					}
+				case 'r':
+					if im.related != nil {
+						im.loadResults(im.searchText)
+					} else if len(im.results) > 0 {
+						im.showRelated()
+					}
 				case 'j':
 					im.moveSelection(1)
 				case 'k':
@@ -105,10 +324,34 @@ func (im *InteractiveMode) Run() error {
 				case 'g':
 					im.selected = 0
 				case 'G':
-					im.selected = len(im.results) - 1
+					im.moveSelection(len(im.results))
 				case '/':
+					// The current query is edited rather than retyped
 					im.searchMode = true
-					im.searchText = ""
+				case 't':
+					if len(im.results) > 0 {
+						im.translateSelected()
+					}
+				case 'd':
+					im.detail = !im.detail
+				case 's':
+					im.screenshots = !im.screenshots
+					im.loadResults(im.searchText)
+				case 'v':
+					if len(im.results) > 0 {
+						im.speakSelected()
+					}
+				case 'Q':
+					if len(im.results) > 0 {
+						im.showQR()
+					}
+				case 'S':
+					if len(im.results) > 0 {
+						im.sendMode = true
+						im.status = "Send to  s:Slack  d:Discord  Esc:Cancel"
+					}
+				case 'T':
+					im.showTimeline()
 				case 'q':
 					return nil
 				}
@@ -117,26 +360,391 @@ func (im *InteractiveMode) Run() error {
 	}
 }
 
-func (im *InteractiveMode) loadResults(query string) error {
-	results, err := im.store.Search(storage.SearchOptions{
-		Query:     query,
-		SortBy:    "last_used",
-		SortOrder: "desc",
-	})
+// queryHelp sums up the search query language, see storage.ParseQuery
+const queryHelp = `type: app: tag: format: url: category: device: window: before: after:  "phrase"  OR  Esc:Clear`
+
+// pageSize is how many clips are loaded at a time; the next page is loaded
+// when the selection reaches the last one
+const pageSize = 100
+
+// loadResults lists the clips matching query, in the search query language
+// of the API and CLI. It keeps the current ones and reports why in the
+// status line if they can't be read, such as for a malformed date.
+func (im *InteractiveMode) loadResults(query string) bool {
+	results, err := im.fetch(im.searchOptions(query, ""))
 	if err != nil {
-		return fmt.Errorf("failed to load clips: %w", err)
+		im.status = fmt.Sprintf("Failed to load clips: %v", err)
+		return false
 	}
 	im.results = results
+	im.cursor = clipman.NextCursor(results, pageSize)
+	im.related = nil
 	im.selected = 0
 	im.offset = 0
-	return nil
+	return true
+}
+
+// showRelated lists the other clips copied in the same session as the
+// selected one, such as the other cells copied from a spreadsheet
+func (im *InteractiveMode) showRelated() {
+	selected := im.results[im.selected].Clip
+	var clips []*clipman.Clip
+	if err := im.daemon.getJSON("/api/clips/"+selected.ID+"/related", &clips); err != nil {
+		im.status = fmt.Sprintf("Failed to load related clips: %v", err)
+		return
+	}
+	if len(clips) == 0 {
+		im.status = "No other clips were copied together with this one"
+		return
+	}
+
+	results := make([]clipman.SearchResult, len(clips))
+	for i, clip := range clips {
+		results[i] = clipman.SearchResult{Clip: clip}
+	}
+	im.results = results
+	im.cursor = ""
+	im.related = selected
+	im.selected = 0
+	im.offset = 0
+}
+
+// loadMore appends the page after the results, if there is one. A cursor
+// rather than an offset is used, so clips copied in the meantime don't shift
+// the page.
+func (im *InteractiveMode) loadMore() {
+	if im.cursor == "" {
+		return
+	}
+	results, err := im.fetch(im.searchOptions(im.searchText, im.cursor))
+	if err != nil {
+		im.status = fmt.Sprintf("Failed to load more clips: %v", err)
+		return
+	}
+	im.results = append(im.results, results...)
+	im.cursor = clipman.NextCursor(results, pageSize)
+}
+
+// searchOptions selects a page of the clips matching query from cursor, or
+// from the first one if it is empty, in the listed period
+func (im *InteractiveMode) searchOptions(query, cursor string) clipman.SearchOptions {
+	opts := clipman.SearchOptions{Query: query, Limit: pageSize, Cursor: cursor}
+	if im.period != nil {
+		// The end of a bucket is where the next one starts
+		opts.From, opts.To = im.period.Start, im.period.End.Add(-time.Nanosecond)
+	}
+	return opts
+}
+
+// fetch reads the clips opts select, most recently used first
+func (im *InteractiveMode) fetch(opts clipman.SearchOptions) ([]clipman.SearchResult, error) {
+	if im.screenshots {
+		return im.store.GetByType(context.Background(), types.TypeScreenshot, opts)
+	}
+	return im.store.GetRecent(context.Background(), opts)
+}
+
+// toggleMark marks the selected clip, or unmarks it if it is marked
+func (im *InteractiveMode) toggleMark() {
+	id := im.results[im.selected].Clip.ID
+	for i, marked := range im.marked {
+		if marked == id {
+			im.marked = append(im.marked[:i], im.marked[i+1:]...)
+			return
+		}
+	}
+	im.marked = append(im.marked, id)
+}
+
+// isMarked reports whether the clip with id is marked
+func (im *InteractiveMode) isMarked(id string) bool {
+	for _, marked := range im.marked {
+		if marked == id {
+			return true
+		}
+	}
+	return false
+}
+
+// markedOrSelected returns the IDs of the marked clips, or of the selected
+// one if none are marked
+func (im *InteractiveMode) markedOrSelected() []string {
+	if len(im.marked) > 0 {
+		return im.marked
+	}
+	return []string{im.results[im.selected].Clip.ID}
+}
+
+// deleteMarked has the daemon move the marked clips, or the selected one, to
+// the trash in one request and reloads the results
+func (im *InteractiveMode) deleteMarked() {
+	var result struct {
+		Affected int64 `json:"affected"`
+	}
+	req := bulkRequest{Action: "delete", IDs: im.markedOrSelected()}
+	if err := im.daemon.postJSON("/api/clips/bulk", req, &result, http.StatusOK); err != nil {
+		im.status = fmt.Sprintf("Failed to delete clips: %v", err)
+		return
+	}
+	im.marked = nil
+	im.status = fmt.Sprintf("Deleted %d clips, GET /api/trash lists them", result.Affected)
+	im.loadResults(im.searchText)
+}
+
+// stackMarked has the daemon start a paste stack of the marked clips, in the
+// order they were marked, which copies the first one. Each paste -next, or
+// ⌘V in menu bar mode, copies the next.
+func (im *InteractiveMode) stackMarked() {
+	var stack struct {
+		Remaining int `json:"remaining"`
+	}
+	if err := im.daemon.postJSON("/api/stack", idsRequest{IDs: im.markedOrSelected()}, &stack, http.StatusOK); err != nil {
+		im.status = fmt.Sprintf("Failed to start a paste stack: %v", err)
+		return
+	}
+	im.marked = nil
+	im.status = fmt.Sprintf("Paste stack started, the first clip is copied and %d follow", stack.Remaining)
+}
+
+// concatMarked has the daemon join the text of the marked clips, in the order
+// they were marked, into a new clip and reloads the results, so it is listed
+// first
+func (im *InteractiveMode) concatMarked() {
+	if len(im.marked) < 2 {
+		im.status = "Mark at least two clips with Space to merge them"
+		return
+	}
+	var clip clipman.Clip
+	req := concatRequest{Action: "concat", IDs: im.marked}
+	if err := im.daemon.postJSON("/api/clips/merge", req, &clip, http.StatusCreated); err != nil {
+		im.status = fmt.Sprintf("Failed to merge clips: %v", err)
+		return
+	}
+	im.status = fmt.Sprintf("Merged %d clips into clip %s", len(im.marked), clip.ID)
+	im.marked = nil
+	im.loadResults(im.searchText)
+}
+
+// exportMarked has the daemon add the marked clips, or the selected one, to
+// Apple Notes, which only admins may do
+func (im *InteractiveMode) exportMarked() {
+	var err error
+	exported := int64(1)
+	if len(im.marked) > 0 {
+		var result struct {
+			Affected int64 `json:"affected"`
+		}
+		err = im.daemon.postJSON("/api/clips/bulk", bulkRequest{Action: "apple-notes", IDs: im.marked}, &result, http.StatusOK)
+		exported = result.Affected
+	} else {
+		err = im.daemon.post("/api/clips/"+im.results[im.selected].Clip.ID+"/apple-notes", http.StatusNoContent)
+	}
+	if err != nil {
+		im.status = fmt.Sprintf("Failed to export to Apple Notes: %v", err)
+		return
+	}
+	im.marked = nil
+	im.status = fmt.Sprintf("Exported %d clips to Apple Notes", exported)
+}
+
+// pasteSelected has the daemon copy the selected clip to the clipboard,
+// reporting whether it did
+func (im *InteractiveMode) pasteSelected() bool {
+	selected := im.results[im.selected]
+	if err := im.daemon.post("/api/clips/id/"+selected.Clip.ID+"/paste", http.StatusOK); err != nil {
+		im.status = fmt.Sprintf("Failed to paste clip: %v", err)
+		return false
+	}
+	return true
+}
+
+// translateSelected has the daemon store a translation of the selected clip
+// and reloads the results, so the translation is listed
+func (im *InteractiveMode) translateSelected() {
+	selected := im.results[im.selected]
+	if err := im.daemon.post("/api/clips/"+selected.Clip.ID+"/translate?store=true", http.StatusOK); err != nil {
+		im.status = fmt.Sprintf("Failed to translate clip: %v", err)
+		return
+	}
+	im.status = "Translated, the translation is the newest clip"
+	im.loadResults(im.searchText)
 }
 
-func (im *InteractiveMode) pasteSelected() error {
+// speakSelected has the daemon read the selected clip aloud, which stops
+// whatever it was reading
+func (im *InteractiveMode) speakSelected() {
 	selected := im.results[im.selected]
-	searchCmd := NewSearchCommand(im.store)
-	im.screen.Fini()
-	return searchCmd.Paste(selected.Clip.ID)
+	if err := im.daemon.post("/api/clips/"+selected.Clip.ID+"/speak", http.StatusAccepted); err != nil {
+		im.status = fmt.Sprintf("Failed to read clip aloud: %v", err)
+	}
+}
+
+// sendTargets are the chat targets the daemon sends clips to, by the key
+// that picks them
+var sendTargets = map[rune]string{'s': "slack", 'd': "discord"}
+
+// sendSelected has the daemon post the selected clip to target, with the
+// webhook or bot configured in its settings
+func (im *InteractiveMode) sendSelected(target string) {
+	selected := im.results[im.selected]
+	if err := im.daemon.post("/api/clips/"+selected.Clip.ID+"/send?target="+target, http.StatusNoContent); err != nil {
+		im.status = fmt.Sprintf("Failed to send clip: %v", err)
+		return
+	}
+	im.status = "Sent to " + target
+}
+
+// qrMargin is how many light modules surround a QR code, half the quiet
+// zone the standard asks for, which phones scan well enough and which leaves
+// more codes room on the screen
+const qrMargin = 2
+
+// showQR shows the text of the selected clip as a QR code, to be scanned with
+// a phone
+func (im *InteractiveMode) showQR() {
+	clip := im.results[im.selected].Clip
+	if !types.IsText(clip.Type) {
+		im.status = fmt.Sprintf("Clip %s is %s, QR codes need text", clip.ID, clip.Type)
+		return
+	}
+	code, err := qrcode.New(strings.TrimSpace(string(clip.Content)), qrcode.Medium)
+	if err != nil {
+		im.status = fmt.Sprintf("Failed to make a QR code: %v", err)
+		return
+	}
+	code.DisableBorder = true
+	bitmap := code.Bitmap()
+
+	// Two modules share a row of the screen
+	width, height := im.screen.Size()
+	if size := len(bitmap) + 2*qrMargin; size > width || (size+1)/2 > height {
+		im.status = fmt.Sprintf("The QR code needs a %dx%d terminal", size, (size+1)/2)
+		return
+	}
+	im.qr = bitmap
+}
+
+// drawQR draws the QR code centred on the screen in half blocks, dark
+// modules black on white whatever the terminal's colours
+func (im *InteractiveMode) drawQR() {
+	width, height := im.screen.Size()
+	size := len(im.qr) + 2*qrMargin
+	x0, y0 := (width-size)/2, (height-(size+1)/2)/2
+	dark := func(row, col int) bool {
+		row, col = row-qrMargin, col-qrMargin
+		return row >= 0 && row < len(im.qr) && col >= 0 && col < len(im.qr) && im.qr[row][col]
+	}
+	colour := func(dark bool) tcell.Color {
+		if dark {
+			return tcell.ColorBlack
+		}
+		return tcell.ColorWhite
+	}
+	for row := 0; row < size; row += 2 {
+		for col := 0; col < size; col++ {
+			style := tcell.StyleDefault.Foreground(colour(dark(row, col))).Background(colour(dark(row+1, col)))
+			im.screen.SetContent(x0+col, y0+row/2, '▀', nil, style)
+		}
+	}
+}
+
+// showTimeline lists the days, or hours, with clips in place of the clips
+func (im *InteractiveMode) showTimeline() {
+	granularity := "day"
+	if im.hourly {
+		granularity = "hour"
+	}
+	var buckets []timelineBucket
+	if err := im.daemon.getJSON("/api/timeline?granularity="+granularity, &buckets); err != nil {
+		im.status = fmt.Sprintf("Failed to load the timeline: %v", err)
+		return
+	}
+	if len(buckets) == 0 {
+		im.status = "No clips to browse"
+		return
+	}
+	for i, j := 0, len(buckets)-1; i < j; i, j = i+1, j-1 {
+		buckets[i], buckets[j] = buckets[j], buckets[i]
+	}
+
+	// Coming back from the clips of a day or hour selects it again
+	selected := 0
+	if im.period != nil {
+		selected = im.browsed
+	}
+	im.timeline = buckets
+	im.period = nil
+	im.selected, im.offset = 0, 0
+	im.moveSelection(selected)
+}
+
+// timelineKey handles a key pressed while browsing the timeline, reporting
+// whether the TUI should quit
+func (im *InteractiveMode) timelineKey(ev *tcell.EventKey) bool {
+	switch ev.Key() {
+	case tcell.KeyCtrlC:
+		return true
+	case tcell.KeyEscape:
+		im.leaveTimeline(nil)
+	case tcell.KeyUp, tcell.KeyCtrlP:
+		im.moveSelection(-1)
+	case tcell.KeyDown, tcell.KeyCtrlN:
+		im.moveSelection(1)
+	case tcell.KeyHome, tcell.KeyCtrlA:
+		im.moveSelection(-len(im.timeline))
+	case tcell.KeyEnd, tcell.KeyCtrlE:
+		im.moveSelection(len(im.timeline))
+	case tcell.KeyPgUp:
+		im.moveSelection(-10)
+	case tcell.KeyPgDn:
+		im.moveSelection(10)
+	case tcell.KeyEnter:
+		im.browsed = im.selected
+		bucket := im.timeline[im.selected]
+		im.leaveTimeline(&bucket)
+	case tcell.KeyRune:
+		switch ev.Rune() {
+		case 'j':
+			im.moveSelection(1)
+		case 'k':
+			im.moveSelection(-1)
+		case 'g':
+			im.moveSelection(-len(im.timeline))
+		case 'G':
+			im.moveSelection(len(im.timeline))
+		case 'h':
+			im.hourly = !im.hourly
+			im.showTimeline()
+		case 'T':
+			im.leaveTimeline(nil)
+		case 'q':
+			return true
+		}
+	}
+	return false
+}
+
+// leaveTimeline lists the clips copied in period, or all of them if it is nil
+func (im *InteractiveMode) leaveTimeline(period *timelineBucket) {
+	im.timeline = nil
+	im.period = period
+	im.loadResults(im.searchText)
+}
+
+// bucketName names the day or hour of a timeline bucket
+func (im *InteractiveMode) bucketName(bucket timelineBucket) string {
+	if im.hourly {
+		return bucket.Start.Local().Format("Mon 2006-01-02 15:04")
+	}
+	return bucket.Start.Local().Format("Mon 2006-01-02")
+}
+
+// listed returns how many lines the list has, of clips or of the timeline
+func (im *InteractiveMode) listed() int {
+	if im.timeline != nil {
+		return len(im.timeline)
+	}
+	return len(im.results)
 }
 
 func (im *InteractiveMode) moveSelection(delta int) {
@@ -144,8 +752,11 @@ func (im *InteractiveMode) moveSelection(delta int) {
 	if im.selected < 0 {
 		im.selected = 0
 	}
-	if im.selected >= len(im.results) {
-		im.selected = len(im.results) - 1
+	if im.timeline == nil && im.selected >= len(im.results)-1 {
+		im.loadMore()
+	}
+	if im.selected >= im.listed() {
+		im.selected = im.listed() - 1
 	}
 
 	// Adjust offset for scrolling
@@ -166,24 +777,64 @@ func (im *InteractiveMode) draw() {
 	// Draw header
 	headerStyle := tcell.StyleDefault.Reverse(true)
 	header := " Clipboard History "
+	if im.screenshots {
+		header = " Screenshots "
+	}
+	if im.related != nil {
+		header = fmt.Sprintf(" Copied Together with Clip %s ", im.related.ID)
+	}
+	switch {
+	case im.timeline != nil && im.hourly:
+		header = " Timeline by Hour "
+	case im.timeline != nil:
+		header = " Timeline by Day "
+	case im.period != nil:
+		header = " Copied " + im.bucketName(*im.period) + " "
+	}
+	if im.pause.Paused {
+		header += "- PAUSED "
+		if !im.pause.Until.IsZero() {
+			header += "until " + im.pause.Until.Local().Format("15:04") + " "
+		}
+		headerStyle = headerStyle.Foreground(tcell.ColorRed)
+	}
 	drawStringCenter(im.screen, 0, header, headerStyle)
 
 	// Draw help text
 	helpStyle := tcell.StyleDefault.Foreground(tcell.ColorYellow)
-	help := "↑/k:Up  ↓/j:Down  Enter:Paste  g/G:Top/Bottom  /:Search  Esc/q:Quit"
+	help := "↑/k:Up  ↓/j:Down  Enter:Paste  g/G:Top/Bottom  Space:Mark  x:Delete  p:Stack  M:Merge  n:Notes  r:Related  T:Timeline  d:Detail  s:Screenshots  t:Translate  v:Speak  S:Send  Q:QR  /:Search  Esc/q:Quit"
+	if im.timeline != nil {
+		help = "↑/k:Up  ↓/j:Down  Enter:Clips  g/G:Top/Bottom  h:Days/Hours  Esc/T:Back  q:Quit"
+	}
 	drawStringCenter(im.screen, 1, help, helpStyle)
 
-	// Draw search bar if in search mode
+	// Draw search bar if in search mode, with the query language's fields
+	// in place of the help
 	if im.searchMode {
+		drawString(im.screen, 0, 1, strings.Repeat(" ", width), tcell.StyleDefault)
+		drawStringCenter(im.screen, 1, queryHelp, helpStyle)
 		searchStyle := tcell.StyleDefault.Reverse(true)
 		searchPrompt := fmt.Sprintf(" Search: %s█", im.searchText)
-		drawString(im.screen, 0, 2, searchPrompt, searchStyle)
+		drawString(im.screen, 0, 2, searchPrompt+strings.Repeat(" ", max(width-utf8.RuneCountInString(searchPrompt), 0)), searchStyle)
 	} else {
-		// Draw separator
+		// Draw separator, naming the query the results match
 		drawString(im.screen, 0, 2, strings.Repeat("─", width), tcell.StyleDefault)
+		if im.searchText != "" {
+			drawString(im.screen, 2, 2, " "+im.searchText+" ", tcell.StyleDefault.Bold(true))
+		}
+	}
+
+	if im.timeline != nil {
+		im.drawTimeline(width, height)
+		im.screen.Show()
+		return
 	}
 
-	// Draw results
+	// Draw results, leaving the right half to the detail pane
+	listWidth := width
+	if im.detail && width >= minDetailWidth {
+		listWidth = width / 2
+	}
 	visibleHeight := height - 5
 	endIdx := im.offset + visibleHeight
 	if endIdx > len(im.results) {
@@ -198,37 +849,278 @@ func (im *InteractiveMode) draw() {
 			style = style.Reverse(true)
 		}
 
+		// A search shows the text around the first match, which may be far
+		// into the clip
 		preview := getPreview(result.Clip)
-		if len(preview) > width-20 {
-			preview = preview[:width-23] + "..."
+		if result.Snippet != "" {
+			preview = showMatch(result.Snippet, result.Matches, listWidth-23)
+		}
+		if len(preview) > listWidth-20 {
+			preview = preview[:max(listWidth-23, 0)] + "..."
 		}
 
-		line := fmt.Sprintf(" %-3s  %-10s  %s",
+		mark := " "
+		if im.isMarked(result.Clip.ID) {
+			mark = "*"
+		}
+		line := fmt.Sprintf("%s%-3s  %-10s  %s",
+			mark,
 			result.Clip.ID,
 			truncate(result.Clip.Type, 10),
 			preview,
 		)
-		drawString(im.screen, 0, y, line, style)
+		if runes := []rune(line); len(runes) > listWidth {
+			line = string(runes[:listWidth])
+		}
+		drawMatches(im.screen, 0, y, line, result.Matches, style)
+	}
+
+	if listWidth < width && len(im.results) > 0 {
+		for y := 3; y < 3+visibleHeight; y++ {
+			im.screen.SetContent(listWidth, y, '│', nil, tcell.StyleDefault)
+		}
+		selected := im.results[im.selected]
+		y, detailHeight := 3, visibleHeight
+		if len(selected.Matches) > 0 {
+			drawString(im.screen, listWidth+2, y, "Matches: "+strings.Join(selected.Matches, ", "), tcell.StyleDefault.Bold(true))
+			y, detailHeight = y+2, detailHeight-2
+		}
+		im.drawDetail(listWidth+2, y, width-listWidth-2, detailHeight, selected.Clip)
+	}
+
+	if im.qr != nil {
+		im.drawQR()
 	}
 
 	// Draw footer
+	if im.status != "" {
+		drawString(im.screen, 0, height-1, " "+im.status, tcell.StyleDefault.Bold(true))
+	}
 	if len(im.results) > 0 {
 		status := fmt.Sprintf(" %d/%d ", im.selected+1, len(im.results))
+		if im.cursor != "" {
+			status = fmt.Sprintf(" %d/%d+ ", im.selected+1, len(im.results))
+		}
+		if len(im.marked) > 0 {
+			status = fmt.Sprintf(" %d marked ", len(im.marked)) + status
+		}
 		drawString(im.screen, width-len(status), height-1, status, tcell.StyleDefault)
 	}
 
 	im.screen.Show()
 }
 
+// drawTimeline lists the visible days or hours of the timeline below the
+// header, each with a bar as long as its share of the busiest one, and the
+// footer
+func (im *InteractiveMode) drawTimeline(width, height int) {
+	visibleHeight := height - 5
+	var most int64
+	for _, bucket := range im.timeline {
+		most = max(most, bucket.Clips)
+	}
+	endIdx := min(im.offset+visibleHeight, len(im.timeline))
+	for i, bucket := range im.timeline[im.offset:endIdx] {
+		style := tcell.StyleDefault
+		if i+im.offset == im.selected {
+			style = style.Reverse(true)
+		}
+		line := fmt.Sprintf(" %-20s %6d  ", im.bucketName(bucket), bucket.Clips)
+		bar := max(width-utf8.RuneCountInString(line)-1, 0)
+		line += strings.Repeat("█", int(int64(bar)*bucket.Clips/max(most, 1)))
+		drawString(im.screen, 0, i+3, line, style)
+	}
+
+	if im.status != "" {
+		drawString(im.screen, 0, height-1, " "+im.status, tcell.StyleDefault.Bold(true))
+	}
+	status := fmt.Sprintf(" %d/%d ", im.selected+1, len(im.timeline))
+	drawString(im.screen, width-len(status), height-1, status, tcell.StyleDefault)
+}
+
+// watchPause reports the daemon's pause state to Run until done is closed.
+// A daemon that can't be reached is taken to be recording, since there is
+// nothing to tell about one that isn't running.
+func (im *InteractiveMode) watchPause(done <-chan struct{}) {
+	ticker := time.NewTicker(pausePoll)
+	defer ticker.Stop()
+	for {
+		state, _ := im.daemon.paused()
+		im.screen.PostEvent(tcell.NewEventInterrupt(state))
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// minDetailWidth is the narrowest screen the detail pane is shown on
+const minDetailWidth = 100
+
+// detailStyle is the chroma style code is highlighted in. It is a dark one,
+// as most terminals are.
+const detailStyle = "monokai"
+
+// drawDetail draws as much of clip as fits in the box at x, y. Code snippets
+// are highlighted in their language; text that isn't code, and clips that
+// aren't text, are drawn plain.
+func (im *InteractiveMode) drawDetail(x, y, width, height int, clip *clipman.Clip) {
+	text := getPreview(clip)
+	if types.IsText(clip.Type) {
+		text = strings.ReplaceAll(string(clip.Content), "\t", "    ")
+	}
+
+	row, col := 0, 0
+	for _, span := range highlight(text, clip.Metadata.Language) {
+		for _, r := range span.text {
+			if r == '\n' {
+				row, col = row+1, 0
+				continue
+			}
+			if row >= height {
+				return
+			}
+			if col < width {
+				im.screen.SetContent(x+col, y+row, r, nil, span.style)
+			}
+			col++
+		}
+	}
+}
+
+// span is a run of text drawn in one style
+type span struct {
+	text  string
+	style tcell.Style
+}
+
+// highlight splits text into spans coloured as language in detailStyle.
+// Without a language chroma knows, text is a single plain span.
+func highlight(text, language string) []span {
+	plain := []span{{text: text, style: tcell.StyleDefault}}
+	lexer := lexers.Get(language)
+	if language == "" || lexer == nil {
+		return plain
+	}
+	iterator, err := chroma.Coalesce(lexer).Tokenise(nil, text)
+	if err != nil {
+		return plain
+	}
+
+	style := styles.Get(detailStyle)
+	var spans []span
+	for _, token := range iterator.Tokens() {
+		entry := style.Get(token.Type)
+		s := tcell.StyleDefault
+		if entry.Colour.IsSet() {
+			s = s.Foreground(tcell.NewRGBColor(int32(entry.Colour.Red()), int32(entry.Colour.Green()), int32(entry.Colour.Blue())))
+		}
+		s = s.Bold(entry.Bold == chroma.Yes).Italic(entry.Italic == chroma.Yes)
+		spans = append(spans, span{text: token.Value, style: s})
+	}
+	return spans
+}
+
+// getPreview returns a line describing clip for the list
+func getPreview(clip *clipman.Clip) string {
+	switch {
+	case clip.Type == types.TypeScreenshot:
+		return screenshotPreview(clip)
+	case types.IsText(clip.Type):
+		return strings.Join(strings.Fields(string(clip.Content)), " ")
+	case types.IsImage(clip.Type):
+		return fmt.Sprintf("[Image %d bytes]", len(clip.Content))
+	case clip.Type == types.TypeFile:
+		return fmt.Sprintf("[File URL: %s]", string(clip.Content))
+	default:
+		return fmt.Sprintf("[%s %d bytes]", clip.Type, len(clip.Content))
+	}
+}
+
+// screenshotPreview describes a screenshot by the window it was taken of, as
+// the terminal can't show the image itself
+func screenshotPreview(clip *clipman.Clip) string {
+	shot := clip.Metadata.Screenshot
+	if shot == nil {
+		return fmt.Sprintf("[Screenshot %d bytes]", len(clip.Content))
+	}
+	title := shot.WindowTitle
+	if title == "" {
+		title = shot.App
+	}
+	if title == "" {
+		title = "Full screen"
+	}
+	if shot.Width > 0 && shot.Height > 0 {
+		return fmt.Sprintf("[%dx%d] %s", shot.Width, shot.Height, title)
+	}
+	return title
+}
+
+// drawString draws str from x, a column per character
 func drawString(s tcell.Screen, x, y int, str string, style tcell.Style) {
-	for i, r := range str {
-		s.SetContent(x+i, y, r, nil, style)
+	for _, r := range str {
+		s.SetContent(x, y, r, nil, style)
+		x++
+	}
+}
+
+// showMatchContext is how many runes before a match showMatch keeps
+const showMatchContext = 10
+
+// showMatch drops the start of snippet if the first of matches would
+// otherwise be past width runes
+func showMatch(snippet string, matches []string, width int) string {
+	runes := []rune(snippet)
+	lower := strings.Map(unicode.ToLower, snippet)
+	first, firstLen := -1, 0
+	for _, match := range matches {
+		if i := strings.Index(lower, match); i >= 0 && (first < 0 || i < first) {
+			first, firstLen = i, utf8.RuneCountInString(match)
+		}
+	}
+	if first < 0 {
+		return snippet
+	}
+	start := utf8.RuneCountInString(lower[:first])
+	if start+firstLen <= width || start <= showMatchContext {
+		return snippet
+	}
+	return "…" + string(runes[start-showMatchContext:])
+}
+
+// drawMatches draws str like drawString, underlining where the terms a
+// search matched appear in it, whatever their case
+func drawMatches(s tcell.Screen, x, y int, str string, matches []string, style tcell.Style) {
+	runes := []rune(str)
+	lower := []rune(strings.Map(unicode.ToLower, str))
+	matched := make([]bool, len(runes))
+	for _, match := range matches {
+		term := []rune(match)
+		if len(term) == 0 {
+			continue
+		}
+		for i := 0; i+len(term) <= len(lower); i++ {
+			if string(lower[i:i+len(term)]) == match {
+				for j := i; j < i+len(term); j++ {
+					matched[j] = true
+				}
+			}
+		}
+	}
+	for i, r := range runes {
+		runeStyle := style
+		if matched[i] {
+			runeStyle = style.Underline(true).Bold(true)
+		}
+		s.SetContent(x+i, y, r, nil, runeStyle)
 	}
 }
 
 func drawStringCenter(s tcell.Screen, y int, str string, style tcell.Style) {
 	w, _ := s.Size()
-	x := (w - len(str)) / 2
+	x := (w - utf8.RuneCountInString(str)) / 2
 	if x < 0 {
 		x = 0
 	}
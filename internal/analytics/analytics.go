@@ -0,0 +1,227 @@
+// Package analytics summarizes clipboard activity over a range of time for
+// personal dashboards: clips per hour, by weekday and hour of day, by source
+// app and by type per day. Reports are exported as JSON or CSV, and the
+// weekday and hour counts render as an SVG heatmap.
+package analytics
+
+import (
+	"clipboard-manager/internal/storage"
+	"clipboard-manager/pkg/types"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// DefaultDays is how far back a report goes when its start isn't given
+const DefaultDays = 30
+
+// pageSize is how many clips are read at a time
+const pageSize = 500
+
+// ErrInvalidRange is returned for a range that ends before it starts
+var ErrInvalidRange = errors.New("the range ends before it starts")
+
+// SearchFunc finds clips, such as the service's Search
+type SearchFunc func(ctx context.Context, opts storage.SearchOptions) ([]storage.SearchResult, error)
+
+// Report is clipboard activity between From and To, in local time
+type Report struct {
+	From  time.Time `json:"from"`
+	To    time.Time `json:"to"`
+	Clips int64     `json:"clips"`
+	Bytes int64     `json:"bytes"`
+
+	Hours   []Hour       `json:"hours"`   // Each hour with clips, oldest first
+	Heatmap [7][24]int64 `json:"heatmap"` // Clips by weekday, Monday first, and hour of day
+	Apps    []App        `json:"apps"`    // Most clips first
+	Types   []TypeDay    `json:"types"`   // By day, oldest first, then most clips first
+
+	// Clips counted by hour, app and type, the rows of the CSV export
+	Rows []Row `json:"-"`
+}
+
+// Hour is the clips copied in an hour
+type Hour struct {
+	Start time.Time `json:"start"`
+	Clips int64     `json:"clips"`
+	Bytes int64     `json:"bytes"`
+}
+
+// App is the clips copied from an app
+type App struct {
+	Name     string `json:"name"` // Empty for clips with no source app
+	BundleID string `json:"bundle_id,omitempty"`
+	Clips    int64  `json:"clips"`
+	Bytes    int64  `json:"bytes"`
+}
+
+// TypeDay is the clips of a type copied in a day
+type TypeDay struct {
+	Day   string `json:"day"` // YYYY-MM-DD
+	Type  string `json:"type"`
+	Clips int64  `json:"clips"`
+}
+
+// Row is the clips of a type copied from an app in an hour
+type Row struct {
+	Hour     time.Time
+	App      string
+	BundleID string
+	Type     string
+	Clips    int64
+	Bytes    int64
+}
+
+// Range returns the range a report covers given the requested from and to,
+// either of which may be zero: to defaults to now, and from to the start of
+// the day DefaultDays-1 days before to
+func Range(from, to, now time.Time) (time.Time, time.Time, error) {
+	if to.IsZero() {
+		to = now
+	}
+	if from.IsZero() {
+		day := to.Local().AddDate(0, 0, -(DefaultDays - 1))
+		from = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.Local)
+	}
+	if to.Before(from) {
+		return time.Time{}, time.Time{}, ErrInvalidRange
+	}
+	return from, to, nil
+}
+
+// Build reports the clips copied between from and to, inclusive
+func Build(ctx context.Context, search SearchFunc, from, to time.Time) (*Report, error) {
+	b := NewBuilder(from, to)
+	for offset := 0; ; offset += pageSize {
+		results, err := search(ctx, storage.SearchOptions{
+			From:      from,
+			To:        to,
+			SortBy:    "created_at",
+			SortOrder: "asc",
+			Limit:     pageSize,
+			Offset:    offset,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to find clips: %w", err)
+		}
+		for _, result := range results {
+			b.Add(result.Clip)
+		}
+		if len(results) < pageSize {
+			return b.Report(), nil
+		}
+	}
+}
+
+type rowKey struct {
+	hour     time.Time
+	app      string
+	bundleID string
+	clipType string
+}
+
+// Builder accumulates a Report one clip at a time
+type Builder struct {
+	from, to time.Time
+	rows     map[rowKey]*Row
+}
+
+// NewBuilder starts a report of the clips copied between from and to
+func NewBuilder(from, to time.Time) *Builder {
+	return &Builder{from: from, to: to, rows: make(map[rowKey]*Row)}
+}
+
+// Add counts clip
+func (b *Builder) Add(clip *types.Clip) {
+	created := clip.CreatedAt.Local()
+	key := rowKey{
+		hour:     time.Date(created.Year(), created.Month(), created.Day(), created.Hour(), 0, 0, 0, time.Local),
+		app:      clip.Metadata.SourceApp,
+		bundleID: clip.Metadata.SourceBundleID,
+		clipType: clip.Type,
+	}
+	row := b.rows[key]
+	if row == nil {
+		row = &Row{Hour: key.hour, App: key.app, BundleID: key.bundleID, Type: key.clipType}
+		b.rows[key] = row
+	}
+	row.Clips++
+	row.Bytes += int64(len(clip.Content))
+}
+
+// Report summarizes the clips added so far
+func (b *Builder) Report() *Report {
+	report := &Report{From: b.from, To: b.to, Hours: []Hour{}, Apps: []App{}, Types: []TypeDay{}, Rows: []Row{}}
+	for _, row := range b.rows {
+		report.Rows = append(report.Rows, *row)
+	}
+	sort.Slice(report.Rows, func(i, j int) bool {
+		a, b := report.Rows[i], report.Rows[j]
+		switch {
+		case !a.Hour.Equal(b.Hour):
+			return a.Hour.Before(b.Hour)
+		case a.App != b.App:
+			return a.App < b.App
+		case a.BundleID != b.BundleID:
+			return a.BundleID < b.BundleID
+		}
+		return a.Type < b.Type
+	})
+
+	apps := make(map[string]*App)
+	types := make(map[[2]string]*TypeDay)
+	for _, row := range report.Rows {
+		report.Clips += row.Clips
+		report.Bytes += row.Bytes
+
+		if n := len(report.Hours); n == 0 || !report.Hours[n-1].Start.Equal(row.Hour) {
+			report.Hours = append(report.Hours, Hour{Start: row.Hour})
+		}
+		hour := &report.Hours[len(report.Hours)-1]
+		hour.Clips += row.Clips
+		hour.Bytes += row.Bytes
+
+		// Go weeks start on Sunday
+		report.Heatmap[(int(row.Hour.Weekday())+6)%7][row.Hour.Hour()] += row.Clips
+
+		app := apps[row.App]
+		if app == nil {
+			app = &App{Name: row.App, BundleID: row.BundleID}
+			apps[row.App] = app
+		}
+		app.Clips += row.Clips
+		app.Bytes += row.Bytes
+
+		day := [2]string{row.Hour.Format("2006-01-02"), row.Type}
+		if types[day] == nil {
+			types[day] = &TypeDay{Day: day[0], Type: day[1]}
+		}
+		types[day].Clips += row.Clips
+	}
+
+	for _, app := range apps {
+		report.Apps = append(report.Apps, *app)
+	}
+	sort.Slice(report.Apps, func(i, j int) bool {
+		if report.Apps[i].Clips != report.Apps[j].Clips {
+			return report.Apps[i].Clips > report.Apps[j].Clips
+		}
+		return report.Apps[i].Name < report.Apps[j].Name
+	})
+	for _, day := range types {
+		report.Types = append(report.Types, *day)
+	}
+	sort.Slice(report.Types, func(i, j int) bool {
+		a, b := report.Types[i], report.Types[j]
+		switch {
+		case a.Day != b.Day:
+			return a.Day < b.Day
+		case a.Clips != b.Clips:
+			return a.Clips > b.Clips
+		}
+		return a.Type < b.Type
+	})
+	return report
+}
@@ -0,0 +1,101 @@
+package analytics
+
+import (
+	"bytes"
+	"clipboard-manager/internal/storage"
+	"clipboard-manager/pkg/types"
+	"context"
+	"encoding/xml"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuilder(t *testing.T) {
+	// Monday 3 June 2024
+	monday := time.Date(2024, 6, 3, 9, 0, 0, 0, time.Local)
+	clip := func(at time.Time, app, clipType, content string) *types.Clip {
+		return &types.Clip{Type: clipType, Content: []byte(content), CreatedAt: at, Metadata: types.Metadata{SourceApp: app}}
+	}
+
+	b := NewBuilder(monday, monday.AddDate(0, 0, 7))
+	b.Add(clip(monday.Add(5*time.Minute), "Safari", types.TypeText, "abc"))
+	b.Add(clip(monday.Add(10*time.Minute), "Safari", types.TypeText, "de"))
+	b.Add(clip(monday.Add(20*time.Minute), "Terminal", types.TypeText, "f"))
+	b.Add(clip(monday.AddDate(0, 0, 6).Add(14*time.Hour), "Safari", types.TypePNG, "png"))
+	report := b.Report()
+
+	if report.Clips != 4 || report.Bytes != 9 {
+		t.Errorf("report counts %d clips, %d bytes", report.Clips, report.Bytes)
+	}
+	if len(report.Hours) != 2 || report.Hours[0].Clips != 3 || report.Hours[0].Bytes != 6 || !report.Hours[0].Start.Equal(monday) {
+		t.Errorf("unexpected hours %+v", report.Hours)
+	}
+	if report.Heatmap[0][9] != 3 || report.Heatmap[6][23] != 1 {
+		t.Errorf("unexpected heatmap %v", report.Heatmap)
+	}
+	if len(report.Apps) != 2 || report.Apps[0].Name != "Safari" || report.Apps[0].Clips != 3 {
+		t.Errorf("unexpected apps %+v", report.Apps)
+	}
+	want := []TypeDay{{"2024-06-03", types.TypeText, 3}, {"2024-06-09", types.TypePNG, 1}}
+	if len(report.Types) != len(want) || report.Types[0] != want[0] || report.Types[1] != want[1] {
+		t.Errorf("types = %+v, want %+v", report.Types, want)
+	}
+
+	var csv bytes.Buffer
+	if err := report.WriteCSV(&csv); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(csv.String()), "\n")
+	if len(lines) != 4 || lines[0] != "hour,app,bundle_id,type,clips,bytes" || !strings.HasSuffix(lines[1], ",Safari,,text/plain,2,5") {
+		t.Errorf("unexpected CSV:\n%s", csv.String())
+	}
+
+	var svg bytes.Buffer
+	if err := report.WriteSVG(&svg); err != nil {
+		t.Fatal(err)
+	}
+	if err := xml.Unmarshal(svg.Bytes(), new(struct{})); err != nil {
+		t.Errorf("heatmap isn't valid XML: %v", err)
+	}
+	if !strings.Contains(svg.String(), "<title>Mon 09:00: 3 clips</title>") {
+		t.Errorf("expected Monday 9:00 in the heatmap:\n%s", svg.String())
+	}
+}
+
+func TestBuild_Pages(t *testing.T) {
+	start := time.Date(2024, 6, 3, 0, 0, 0, 0, time.Local)
+	var offsets []int
+	search := func(ctx context.Context, opts storage.SearchOptions) ([]storage.SearchResult, error) {
+		offsets = append(offsets, opts.Offset)
+		n := pageSize
+		if opts.Offset > 0 {
+			n = 1
+		}
+		results := make([]storage.SearchResult, n)
+		for i := range results {
+			results[i].Clip = &types.Clip{Type: types.TypeText, CreatedAt: start}
+		}
+		return results, nil
+	}
+
+	report, err := Build(context.Background(), search, start, start.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Clips != pageSize+1 || len(offsets) != 2 || offsets[1] != pageSize {
+		t.Errorf("counted %d clips in searches at %v", report.Clips, offsets)
+	}
+}
+
+func TestRange(t *testing.T) {
+	now := time.Date(2024, 6, 30, 15, 4, 0, 0, time.Local)
+	from, to, err := Range(time.Time{}, time.Time{}, now)
+	if err != nil || !to.Equal(now) || !from.Equal(time.Date(2024, 6, 1, 0, 0, 0, 0, time.Local)) {
+		t.Errorf("Range() = %v, %v, %v", from, to, err)
+	}
+	if _, _, err := Range(now, now.Add(-time.Hour), now); !errors.Is(err, ErrInvalidRange) {
+		t.Error("expected a backwards range to be rejected")
+	}
+}
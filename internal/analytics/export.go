@@ -0,0 +1,101 @@
+package analytics
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// WriteCSV writes the report's rows as CSV with a header: the hour in RFC
+// 3339, the app, its bundle ID, the type and the clips and bytes copied
+func (r *Report) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"hour", "app", "bundle_id", "type", "clips", "bytes"})
+	for _, row := range r.Rows {
+		cw.Write([]string{
+			row.Hour.Format(time.RFC3339),
+			row.App,
+			row.BundleID,
+			row.Type,
+			fmt.Sprint(row.Clips),
+			fmt.Sprint(row.Bytes),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// Layout of the heatmap, in pixels
+const (
+	cellSize    = 14
+	cellGap     = 2
+	labelWidth  = 36
+	titleHeight = 24
+	hourHeight  = 16
+	legendGap   = 12
+)
+
+// shades are the colors of cells with no clips up to the most, as on
+// GitHub's contribution graph
+var shades = []string{"#ebedf0", "#9be9a8", "#40c463", "#30a14e", "#216e39"}
+
+var weekdays = []string{"Mon", "Tue", "Wed", "Thu", "Fri", "Sat", "Sun"}
+
+// WriteSVG draws the report's clips by weekday and hour of day as an SVG
+// heatmap, each cell titled with its count
+func (r *Report) WriteSVG(w io.Writer) error {
+	var max int64
+	for _, day := range r.Heatmap {
+		for _, clips := range day {
+			if clips > max {
+				max = clips
+			}
+		}
+	}
+	step := cellSize + cellGap
+	gridTop := titleHeight + hourHeight
+	width := labelWidth + 24*step
+	height := gridTop + 7*step + legendGap + cellSize
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="-apple-system, sans-serif" font-size="10" fill="#57606a">`+"\n",
+		width, height, width, height)
+	fmt.Fprintf(&b, `<text x="0" y="14" font-size="12" fill="#24292f">Clipboard activity, %s – %s: %d clips</text>`+"\n",
+		r.From.Local().Format("2 Jan 2006"), r.To.Local().Format("2 Jan 2006"), r.Clips)
+	for hour := 0; hour < 24; hour += 3 {
+		fmt.Fprintf(&b, `<text x="%d" y="%d">%02d</text>`+"\n", labelWidth+hour*step, gridTop-4, hour)
+	}
+	for day, counts := range r.Heatmap {
+		y := gridTop + day*step
+		fmt.Fprintf(&b, `<text x="0" y="%d">%s</text>`+"\n", y+cellSize-3, weekdays[day])
+		for hour, clips := range counts {
+			fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%d" height="%d" rx="2" fill="%s"><title>%s %02d:00: %d clips</title></rect>`+"\n",
+				labelWidth+hour*step, y, cellSize, cellSize, shade(clips, max), weekdays[day], hour, clips)
+		}
+	}
+
+	// Legend, from fewest clips to most
+	y := gridTop + 7*step + legendGap
+	x := width - len(shades)*step - 36
+	fmt.Fprintf(&b, `<text x="%d" y="%d" text-anchor="end">Less</text>`+"\n", x-4, y+cellSize-3)
+	for i, color := range shades {
+		fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%d" height="%d" rx="2" fill="%s"/>`+"\n", x+i*step, y, cellSize, cellSize, color)
+	}
+	fmt.Fprintf(&b, `<text x="%d" y="%d">More</text>`+"\n", x+len(shades)*step+2, y+cellSize-3)
+	b.WriteString("</svg>\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// shade is the color of a cell with clips, in quarters of the most clips
+// in a cell
+func shade(clips, max int64) string {
+	if clips <= 0 || max <= 0 {
+		return shades[0]
+	}
+	levels := int64(len(shades) - 1)
+	return shades[(clips*levels+max-1)/max]
+}
@@ -0,0 +1,219 @@
+// Package audit keeps an append-only trail of who read, pasted, changed,
+// deleted or exported clips through the API, for histories that hold
+// sensitive data. Entries are JSON lines; the file is rotated by size.
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FileName is the name of the audit log in the data directory
+const FileName = "audit.log"
+
+const (
+	// DefaultMaxSize is the size at which the log is rotated
+	DefaultMaxSize = 5 << 20
+	// DefaultKeep is how many rotated logs are kept next to the current one
+	DefaultKeep = 5
+)
+
+// CLIUserAgent is the User-Agent of the command line, recorded as "cli"
+const CLIUserAgent = "clipboard-manager-cli"
+
+// Actions recorded in the log
+const (
+	ActionRead   = "read"   // Clips were listed, searched or read
+	ActionAdd    = "add"    // A clip was added
+	ActionPaste  = "paste"  // A clip was put on the clipboard
+	ActionModify = "modify" // Clips or settings were changed
+	ActionDelete = "delete" // Clips were deleted
+	ActionExport = "export" // A clip left the daemon: published, sent, shared or shown as a QR code
+	ActionDenied = "denied" // A request was refused for a missing token or scope
+)
+
+// Entry is one request in the log
+type Entry struct {
+	Time    time.Time `json:"time"`
+	Action  string    `json:"action"`
+	User    string    `json:"user,omitempty"`  // Empty without users
+	Token   string    `json:"token,omitempty"` // ID of the token used
+	Remote  string    `json:"remote"`
+	Client  string    `json:"client,omitempty"` // "cli" for the command line, otherwise the User-Agent
+	Method  string    `json:"method"`
+	Path    string    `json:"path"`
+	Clip    string    `json:"clip,omitempty"` // ID or index of the clip, if the route names one
+	Status  int       `json:"status"`
+	Request string    `json:"request,omitempty"` // ID of the request, as in the daemon's log and X-Request-Id
+}
+
+// Filter selects entries from the log
+type Filter struct {
+	Since  time.Time // Zero for all
+	Action string    // Empty for all
+	User   string    // Empty for all
+	Limit  int       // Newest entries to return, 0 for all
+}
+
+func (f Filter) match(e Entry) bool {
+	return !e.Time.Before(f.Since) &&
+		(f.Action == "" || e.Action == f.Action) &&
+		(f.User == "" || e.User == f.User)
+}
+
+// Log appends entries to a file, rotating it to path.1, path.2 and so on
+// once it grows past maxSize
+type Log struct {
+	path    string
+	maxSize int64
+	keep    int
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// Open opens the log at path for appending, creating it if needed. A
+// maxSize or keep of 0 uses the default.
+func Open(path string, maxSize int64, keep int) (*Log, error) {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxSize
+	}
+	if keep <= 0 {
+		keep = DefaultKeep
+	}
+	l := &Log{path: path, maxSize: maxSize, keep: keep}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Record appends an entry, stamping it with the current time if it has none
+func (l *Log) Record(e Entry) error {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit entry: %w", err)
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return fmt.Errorf("audit log %s is closed", l.path)
+	}
+	if l.size > 0 && l.size+int64(len(line)) > l.maxSize {
+		if err := l.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := l.file.Write(line)
+	l.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", l.path, err)
+	}
+	return nil
+}
+
+// Query returns the entries matching filter, newest first. Rotated logs are
+// searched too.
+func (l *Log) Query(filter Filter) ([]Entry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var entries []Entry
+	// Oldest file first, so entries end up in the order they were written
+	for i := l.keep; i >= 0; i-- {
+		path := l.rotated(i)
+		f, err := os.Open(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 64*1024), 1<<20)
+		for scanner.Scan() {
+			var e Entry
+			// Skip a line cut short by a crash rather than lose the rest
+			if json.Unmarshal(scanner.Bytes(), &e) == nil && filter.match(e) {
+				entries = append(entries, e)
+			}
+		}
+		err = scanner.Err()
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+	}
+
+	if filter.Limit > 0 && len(entries) > filter.Limit {
+		entries = entries[len(entries)-filter.Limit:]
+	}
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	return entries, nil
+}
+
+// Close closes the log. Entries recorded afterwards are refused.
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+func (l *Log) open() error {
+	f, err := os.OpenFile(l.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", l.path, err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to stat %s: %w", l.path, err)
+	}
+	l.file, l.size = f, info.Size()
+	return nil
+}
+
+// rotate shifts the rotated logs up by one, dropping the oldest, and starts
+// a new log
+func (l *Log) rotate() error {
+	if err := l.file.Close(); err != nil {
+		return fmt.Errorf("failed to close %s: %w", l.path, err)
+	}
+	l.file = nil
+	os.Remove(l.rotated(l.keep))
+	for i := l.keep - 1; i >= 0; i-- {
+		if err := os.Rename(l.rotated(i), l.rotated(i+1)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to rotate %s: %w", l.rotated(i), err)
+		}
+	}
+	return l.open()
+}
+
+// rotated returns the path of the i-th rotated log, the log itself for 0
+func (l *Log) rotated(i int) string {
+	if i == 0 {
+		return l.path
+	}
+	return fmt.Sprintf("%s.%d", l.path, i)
+}
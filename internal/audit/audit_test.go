@@ -0,0 +1,103 @@
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	log, err := Open(path, 0, 0)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	for i, action := range []string{ActionRead, ActionPaste, ActionDelete, ActionRead} {
+		user := "alice"
+		if i%2 == 1 {
+			user = "bob"
+		}
+		if err := log.Record(Entry{Time: start.Add(time.Duration(i) * time.Minute), Action: action, User: user, Status: 200}); err != nil {
+			t.Fatalf("Record() error = %v", err)
+		}
+	}
+	if err := log.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	// Entries survive reopening
+	log, err = Open(path, 0, 0)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer log.Close()
+
+	entries, err := log.Query(Filter{})
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if len(entries) != 4 || !entries[0].Time.Equal(start.Add(3*time.Minute)) {
+		t.Fatalf("Query() = %+v, want 4 entries newest first", entries)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		filter Filter
+		want   int
+	}{
+		{"action", Filter{Action: ActionRead}, 2},
+		{"user", Filter{User: "bob"}, 2},
+		{"since", Filter{Since: start.Add(2 * time.Minute)}, 2},
+		{"limit", Filter{Limit: 3}, 3},
+		{"combined", Filter{Action: ActionRead, User: "bob", Since: start.Add(time.Minute)}, 1},
+	} {
+		entries, err := log.Query(tc.filter)
+		if err != nil {
+			t.Fatalf("%s: Query() error = %v", tc.name, err)
+		}
+		if len(entries) != tc.want {
+			t.Errorf("%s: Query() returned %d entries, want %d", tc.name, len(entries), tc.want)
+		}
+	}
+}
+
+func TestLog_Rotate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	// Small enough that every entry starts a new file
+	log, err := Open(path, 10, 2)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer log.Close()
+
+	for i := 0; i < 5; i++ {
+		if err := log.Record(Entry{Action: ActionRead, Clip: string(rune('a' + i))}); err != nil {
+			t.Fatalf("Record() error = %v", err)
+		}
+	}
+
+	for _, name := range []string{FileName, FileName + ".1", FileName + ".2"} {
+		if _, err := os.Stat(filepath.Join(filepath.Dir(path), name)); err != nil {
+			t.Errorf("%s is missing: %v", name, err)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("more rotated logs than kept: %v", err)
+	}
+
+	// The two oldest entries were rotated away
+	entries, err := log.Query(Filter{})
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	var clips string
+	for _, e := range entries {
+		clips += e.Clip
+	}
+	if clips != "edc" {
+		t.Errorf("Query() clips = %q, want %q", clips, "edc")
+	}
+}
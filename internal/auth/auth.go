@@ -0,0 +1,289 @@
+// Package auth maps API tokens to users, for running the daemon on a shared
+// machine or home server. Without users the API is open to anyone who can
+// reach it, as it always was.
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileName is the name of the users file in the data directory
+const FileName = "users.json"
+
+// tokenPrefix makes tokens easy to recognize, e.g. by secret scanners
+const tokenPrefix = "cm_"
+
+// Scopes limit what a token can do
+const (
+	ScopeRead  = "read"  // List, search and read clips
+	ScopePaste = "paste" // Add clips and copy them to the clipboard, without reading history
+	ScopeFull  = "full"  // Everything the user can do
+)
+
+var (
+	ErrUnauthorized = errors.New("missing or invalid API token")
+	ErrInvalidScope = errors.New("invalid scope, expected read, paste or full")
+	ErrNotFound     = errors.New("user not found")
+	ErrExists       = errors.New("user already exists")
+	ErrInvalidName  = errors.New("invalid user name, use up to 32 lower case letters, digits, - and _")
+)
+
+var userName = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+
+// User is someone who can call the API. Admins see the daemon's own
+// history and manage it; everyone else has a history of their own.
+type User struct {
+	Name      string    `json:"name"`
+	Admin     bool      `json:"admin,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	Tokens    []Token   `json:"tokens"`
+}
+
+// Token is an API token of a user. Only its hash is kept.
+type Token struct {
+	ID        string    `json:"id"`
+	Hash      string    `json:"hash"`            // SHA-256 of the token, hex encoded
+	Scope     string    `json:"scope,omitempty"` // "" is full, for tokens from before scopes
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Allows reports whether the token grants scope
+func (t Token) Allows(scope string) bool {
+	return t.Scope == "" || t.Scope == ScopeFull || t.Scope == scope
+}
+
+// ValidScope reports whether scope names a scope
+func ValidScope(scope string) bool {
+	switch scope {
+	case ScopeRead, ScopePaste, ScopeFull:
+		return true
+	}
+	return false
+}
+
+// Users holds the users and their tokens, saved to a file. The file is read
+// again when it changes, so users added from the command line work without
+// restarting the daemon.
+type Users struct {
+	path string
+
+	mu      sync.Mutex
+	users   []User
+	modTime time.Time
+	now     func() time.Time
+}
+
+// Open loads the users file at path. A missing file means no users.
+func Open(path string) (*Users, error) {
+	u := &Users{path: path, now: time.Now}
+	if err := u.reload(); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// Enabled reports whether there are users, and so whether requests need a
+// token
+func (u *Users) Enabled() bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.reloadIfChanged()
+	return len(u.users) > 0
+}
+
+// Authenticate returns the user a token belongs to and the token's record
+func (u *Users) Authenticate(token string) (User, Token, error) {
+	if !strings.HasPrefix(token, tokenPrefix) {
+		return User{}, Token{}, ErrUnauthorized
+	}
+	sum := sha256.Sum256([]byte(token))
+	hash := hex.EncodeToString(sum[:])
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.reloadIfChanged()
+	for _, user := range u.users {
+		for _, t := range user.Tokens {
+			if subtle.ConstantTimeCompare([]byte(t.Hash), []byte(hash)) == 1 {
+				return user, t, nil
+			}
+		}
+	}
+	return User{}, Token{}, ErrUnauthorized
+}
+
+// Add creates a user with a first token of the given scope, which is
+// returned since it can't be recovered later
+func (u *Users) Add(name string, admin bool, scope string) (User, string, error) {
+	if !userName.MatchString(name) {
+		return User{}, "", fmt.Errorf("%w: %q", ErrInvalidName, name)
+	}
+	if !ValidScope(scope) {
+		return User{}, "", fmt.Errorf("%w: %q", ErrInvalidScope, scope)
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if err := u.reload(); err != nil {
+		return User{}, "", err
+	}
+	if u.find(name) >= 0 {
+		return User{}, "", fmt.Errorf("%w: %s", ErrExists, name)
+	}
+
+	token, t, err := u.newToken(scope)
+	if err != nil {
+		return User{}, "", err
+	}
+	user := User{Name: name, Admin: admin, CreatedAt: u.now(), Tokens: []Token{t}}
+	u.users = append(u.users, user)
+	if err := u.save(); err != nil {
+		return User{}, "", err
+	}
+	return user, token, nil
+}
+
+// NewToken adds a token of the given scope to a user and returns it
+func (u *Users) NewToken(name, scope string) (string, error) {
+	if !ValidScope(scope) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidScope, scope)
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if err := u.reload(); err != nil {
+		return "", err
+	}
+	i := u.find(name)
+	if i < 0 {
+		return "", fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	token, t, err := u.newToken(scope)
+	if err != nil {
+		return "", err
+	}
+	u.users[i].Tokens = append(u.users[i].Tokens, t)
+	return token, u.save()
+}
+
+// Remove deletes a user and their tokens. Their history is left on disk.
+func (u *Users) Remove(name string) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if err := u.reload(); err != nil {
+		return err
+	}
+	i := u.find(name)
+	if i < 0 {
+		return fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	u.users = append(u.users[:i], u.users[i+1:]...)
+	return u.save()
+}
+
+// List returns the users sorted by name
+func (u *Users) List() []User {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.reloadIfChanged()
+	users := make([]User, len(u.users))
+	copy(users, u.users)
+	sort.Slice(users, func(i, j int) bool { return users[i].Name < users[j].Name })
+	return users
+}
+
+func (u *Users) find(name string) int {
+	for i, user := range u.users {
+		if user.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// newToken returns a random token and the record of it to keep
+func (u *Users) newToken(scope string) (string, Token, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", Token{}, fmt.Errorf("failed to generate token: %w", err)
+	}
+	token := tokenPrefix + base64.RawURLEncoding.EncodeToString(secret)
+	sum := sha256.Sum256([]byte(token))
+	return token, Token{
+		ID:        hex.EncodeToString(sum[:4]),
+		Hash:      hex.EncodeToString(sum[:]),
+		Scope:     scope,
+		CreatedAt: u.now(),
+	}, nil
+}
+
+// reloadIfChanged reads the file again if it changed since it was last
+// read. Errors keep the users already loaded.
+func (u *Users) reloadIfChanged() {
+	info, err := os.Stat(u.path)
+	switch {
+	case os.IsNotExist(err):
+		u.users, u.modTime = nil, time.Time{}
+	case err != nil || info.ModTime().Equal(u.modTime):
+	default:
+		if err := u.reload(); err != nil {
+			log.Printf("[WARN] Keeping the previous users: %v", err)
+		}
+	}
+}
+
+func (u *Users) reload() error {
+	data, err := os.ReadFile(u.path)
+	if os.IsNotExist(err) {
+		u.users, u.modTime = nil, time.Time{}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", u.path, err)
+	}
+	var users []User
+	if err := json.Unmarshal(data, &users); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", u.path, err)
+	}
+	u.users = users
+	if info, err := os.Stat(u.path); err == nil {
+		u.modTime = info.ModTime()
+	}
+	return nil
+}
+
+// save writes the users file in one step, readable only by its owner
+func (u *Users) save() error {
+	data, err := json.MarshalIndent(u.users, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode users: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(u.path), 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(u.path), err)
+	}
+	tmp := u.path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, u.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to replace %s: %w", u.path, err)
+	}
+	if info, err := os.Stat(u.path); err == nil {
+		u.modTime = info.ModTime()
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestUsers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	users, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if users.Enabled() {
+		t.Error("Enabled() = true without users")
+	}
+
+	if _, _, err := users.Add("Alice!", false, ScopeFull); !errors.Is(err, ErrInvalidName) {
+		t.Errorf("Add() with an invalid name error = %v, want ErrInvalidName", err)
+	}
+	_, token, err := users.Add("alice", false, ScopeFull)
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if _, _, err := users.Add("alice", true, ScopeFull); !errors.Is(err, ErrExists) {
+		t.Errorf("Add() of an existing user error = %v, want ErrExists", err)
+	}
+	if !users.Enabled() {
+		t.Error("Enabled() = false with a user")
+	}
+
+	// Another process sees the new user through the file
+	other, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	user, _, err := other.Authenticate(token)
+	if err != nil || user.Name != "alice" || user.Admin {
+		t.Errorf("Authenticate() = %+v, %v", user, err)
+	}
+	if _, _, err := other.Authenticate(token + "x"); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Authenticate() of a wrong token error = %v, want ErrUnauthorized", err)
+	}
+
+	if _, err := users.NewToken("alice", "admin"); !errors.Is(err, ErrInvalidScope) {
+		t.Errorf("NewToken() with an invalid scope error = %v, want ErrInvalidScope", err)
+	}
+	second, err := users.NewToken("alice", ScopeRead)
+	if err != nil {
+		t.Fatalf("NewToken() error = %v", err)
+	}
+	_, record, err := users.Authenticate(second)
+	if err != nil {
+		t.Errorf("Authenticate() of a second token error = %v", err)
+	}
+	if !record.Allows(ScopeRead) || record.Allows(ScopePaste) || record.Allows(ScopeFull) {
+		t.Errorf("a read token allows %+v", record)
+	}
+
+	if err := users.Remove("alice"); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if _, _, err := users.Authenticate(token); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Authenticate() of a removed user's token error = %v, want ErrUnauthorized", err)
+	}
+	if err := users.Remove("alice"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Remove() of a missing user error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestToken_Allows(t *testing.T) {
+	for _, tc := range []struct {
+		scope, need string
+		want        bool
+	}{
+		{"", ScopeFull, true},
+		{ScopeFull, ScopePaste, true},
+		{ScopePaste, ScopePaste, true},
+		{ScopePaste, ScopeRead, false},
+		{ScopeRead, ScopeFull, false},
+	} {
+		if got := (Token{Scope: tc.scope}).Allows(tc.need); got != tc.want {
+			t.Errorf("Token{Scope: %q}.Allows(%q) = %v, want %v", tc.scope, tc.need, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,241 @@
+// Package bench fills a storage backend with generated clips and measures how
+// long storing, searching and listing take, so backends can be compared on
+// the hardware they'll run on
+package bench
+
+import (
+	"clipboard-manager/internal/storage"
+	"clipboard-manager/pkg/types"
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Defaults of Options
+const (
+	DefaultClips    = 100000
+	DefaultSize     = 2 * 1024
+	DefaultSearches = 1000
+	DefaultLists    = 1000
+)
+
+// pageSize is the number of clips a search or list asks for, as the app does
+const pageSize = 50
+
+// apps is the number of source apps generated clips are spread over
+const apps = 20
+
+// words make up the text of generated clips
+var words = strings.Fields(`lorem ipsum dolor sit amet consectetur adipiscing elit sed do
+	eiusmod tempor incididunt ut labore et dolore magna aliqua enim ad minim veniam quis
+	nostrud exercitation ullamco laboris nisi aliquip ex ea commodo consequat duis aute irure
+	in reprehenderit voluptate velit esse cillum fugiat nulla pariatur excepteur sint occaecat
+	cupidatat non proident sunt culpa qui officia deserunt mollit anim id est laborum`)
+
+// Options configures a run
+type Options struct {
+	Clips    int // Clips to store, 0 uses DefaultClips
+	Size     int // Bytes of text in each clip, 0 uses DefaultSize
+	Searches int // Searches to run, 0 uses DefaultSearches
+	Lists    int // Pages to list, 0 uses DefaultLists
+
+	// Progress, if set, is called as clips are stored
+	Progress func(stored, total int)
+}
+
+func (o Options) withDefaults() Options {
+	if o.Clips <= 0 {
+		o.Clips = DefaultClips
+	}
+	if o.Size <= 0 {
+		o.Size = DefaultSize
+	}
+	if o.Searches <= 0 {
+		o.Searches = DefaultSearches
+	}
+	if o.Lists <= 0 {
+		o.Lists = DefaultLists
+	}
+	return o
+}
+
+// Latency summarizes how long the operations of one kind took
+type Latency struct {
+	Count int           `json:"count"`
+	Mean  time.Duration `json:"mean"`
+	P50   time.Duration `json:"p50"`
+	P95   time.Duration `json:"p95"`
+	P99   time.Duration `json:"p99"`
+	Max   time.Duration `json:"max"`
+}
+
+// Report is the outcome of a run
+type Report struct {
+	Clips   int           `json:"clips"`
+	Size    int           `json:"size"`
+	Elapsed time.Duration `json:"elapsed"`
+	Store   Latency       `json:"store"`
+	Search  Latency       `json:"search"`
+	List    Latency       `json:"list"`
+	// Usage is the space the clips take up, if the backend reports it
+	Usage *storage.Usage `json:"usage,omitempty"`
+}
+
+// Run stores opts.Clips generated text clips in store, then runs searches and
+// lists against them. The store should be a throwaway one: the clips are
+// left in it.
+func Run(ctx context.Context, store storage.Storage, opts Options) (*Report, error) {
+	opts = opts.withDefaults()
+	searcher, ok := store.(storage.SearchService)
+	if !ok {
+		return nil, errors.New("the storage backend doesn't support search")
+	}
+
+	report := &Report{Clips: opts.Clips, Size: opts.Size}
+	started := time.Now()
+	rng := rand.New(rand.NewSource(1)) // The same clips and queries every run
+
+	stores := make([]time.Duration, 0, opts.Clips)
+	for i := 0; i < opts.Clips; i++ {
+		content := clipText(rng, i, opts.Size)
+		metadata := types.Metadata{SourceApp: fmt.Sprintf("App %d", i%apps)}
+
+		start := time.Now()
+		if _, err := store.Store(ctx, content, "text/plain", metadata); err != nil {
+			return nil, fmt.Errorf("failed to store clip %d: %w", i, err)
+		}
+		stores = append(stores, time.Since(start))
+
+		if opts.Progress != nil && ((i+1)%1000 == 0 || i+1 == opts.Clips) {
+			opts.Progress(i+1, opts.Clips)
+		}
+	}
+	report.Store = summarize(stores)
+
+	searches := make([]time.Duration, 0, opts.Searches)
+	for i := 0; i < opts.Searches; i++ {
+		search := searchOptions(rng, i, opts.Clips)
+
+		start := time.Now()
+		if _, err := searcher.Search(ctx, search); err != nil {
+			return nil, fmt.Errorf("search %+v failed: %w", search, err)
+		}
+		searches = append(searches, time.Since(start))
+	}
+	report.Search = summarize(searches)
+
+	lists := make([]time.Duration, 0, opts.Lists)
+	for i := 0; i < opts.Lists; i++ {
+		// Mostly the first pages, as people scroll; now and then far back
+		offset := 0
+		if i%10 == 9 {
+			offset = rng.Intn(opts.Clips)
+		} else if opts.Clips > 10*pageSize {
+			offset = rng.Intn(10) * pageSize
+		}
+
+		start := time.Now()
+		if _, err := store.List(ctx, storage.ListFilter{Limit: pageSize, Offset: offset}); err != nil {
+			return nil, fmt.Errorf("list at offset %d failed: %w", offset, err)
+		}
+		lists = append(lists, time.Since(start))
+	}
+	report.List = summarize(lists)
+
+	if reporter, ok := store.(storage.UsageReporter); ok {
+		usage, err := reporter.Usage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to measure storage size: %w", err)
+		}
+		report.Usage = &usage
+	}
+
+	report.Elapsed = time.Since(started)
+	return report, nil
+}
+
+// clipText generates size bytes of text for clip i. Each starts with a
+// unique marker, so searches for one clip have exactly one hit.
+func clipText(rng *rand.Rand, i, size int) []byte {
+	var b strings.Builder
+	b.Grow(size + 16)
+	b.WriteString("clip" + strconv.Itoa(i))
+	for b.Len() < size {
+		b.WriteByte(' ')
+		b.WriteString(words[rng.Intn(len(words))])
+	}
+	return []byte(b.String()[:size])
+}
+
+// searchOptions returns the i-th search of a run, cycling through the kinds
+// of search the app makes
+func searchOptions(rng *rand.Rand, i, clips int) storage.SearchOptions {
+	switch i % 4 {
+	case 0: // A word most clips contain
+		return storage.SearchOptions{Query: words[rng.Intn(len(words))], Limit: pageSize}
+	case 1: // One clip
+		return storage.SearchOptions{Query: "clip" + strconv.Itoa(rng.Intn(clips)), Limit: pageSize}
+	case 2: // Clips from an app
+		return storage.SearchOptions{SourceApp: fmt.Sprintf("App %d", rng.Intn(apps)), Limit: pageSize}
+	default: // The most recent clips
+		return storage.SearchOptions{Limit: pageSize}
+	}
+}
+
+// summarize computes the latency percentiles of durations
+func summarize(durations []time.Duration) Latency {
+	if len(durations) == 0 {
+		return Latency{}
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+
+	var total time.Duration
+	for _, d := range durations {
+		total += d
+	}
+	return Latency{
+		Count: len(durations),
+		Mean:  total / time.Duration(len(durations)),
+		P50:   percentile(durations, 50),
+		P95:   percentile(durations, 95),
+		P99:   percentile(durations, 99),
+		Max:   durations[len(durations)-1],
+	}
+}
+
+// percentile returns the p-th percentile of sorted durations, by the
+// nearest-rank method
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// ParseSize parses a size such as 2kb, 512B or 1.5MB into bytes. Units are
+// binary and case-insensitive; a bare number is bytes.
+func ParseSize(input string) (int, error) {
+	s := strings.ToLower(strings.TrimSpace(input))
+	multiplier := 1.0
+	for _, unit := range []struct {
+		suffix string
+		bytes  float64
+	}{{"kb", 1 << 10}, {"mb", 1 << 20}, {"gb", 1 << 30}, {"k", 1 << 10}, {"m", 1 << 20}, {"g", 1 << 30}, {"b", 1}} {
+		if strings.HasSuffix(s, unit.suffix) {
+			s, multiplier = strings.TrimSpace(strings.TrimSuffix(s, unit.suffix)), unit.bytes
+			break
+		}
+	}
+
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || !(n > 0) || n*multiplier >= 1<<31 {
+		return 0, fmt.Errorf("invalid size %q, expected a size such as 2kb", input)
+	}
+	return int(n * multiplier), nil
+}
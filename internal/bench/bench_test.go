@@ -0,0 +1,104 @@
+package bench
+
+import (
+	"clipboard-manager/internal/storage"
+	"clipboard-manager/internal/storage/sqlite"
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		input string
+		want  int
+	}{
+		{"2kb", 2048},
+		{"2KB", 2048},
+		{"512b", 512},
+		{"1.5mb", 1536 * 1024},
+		{"4k", 4096},
+		{"100", 100},
+	}
+	for _, test := range tests {
+		got, err := ParseSize(test.input)
+		if err != nil || got != test.want {
+			t.Errorf("ParseSize(%q) = %d, %v, want %d", test.input, got, err, test.want)
+		}
+	}
+
+	for _, input := range []string{"", "kb", "-1kb", "0", "2tb", "NaN", "3gb"} {
+		if _, err := ParseSize(input); err == nil {
+			t.Errorf("ParseSize(%q) succeeded", input)
+		}
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	var durations []time.Duration
+	for i := 100; i >= 1; i-- {
+		durations = append(durations, time.Duration(i)*time.Millisecond)
+	}
+	got := summarize(durations)
+	want := Latency{
+		Count: 100,
+		Mean:  50500 * time.Microsecond,
+		P50:   50 * time.Millisecond,
+		P95:   95 * time.Millisecond,
+		P99:   99 * time.Millisecond,
+		Max:   100 * time.Millisecond,
+	}
+	if got != want {
+		t.Errorf("summarize = %+v, want %+v", got, want)
+	}
+
+	if got := summarize([]time.Duration{time.Second}); got.P50 != time.Second || got.P99 != time.Second {
+		t.Errorf("summarize of one duration = %+v", got)
+	}
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	store, err := sqlite.New(storage.Config{
+		DBPath: filepath.Join(dir, "bench.db"),
+		FSPath: filepath.Join(dir, "files"),
+	})
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	var progress int
+	report, err := Run(context.Background(), store, Options{
+		Clips:    200,
+		Size:     256,
+		Searches: 20,
+		Lists:    10,
+		Progress: func(stored, total int) { progress = stored },
+	})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if report.Store.Count != 200 || report.Search.Count != 20 || report.List.Count != 10 {
+		t.Errorf("ran %d stores, %d searches and %d lists", report.Store.Count, report.Search.Count, report.List.Count)
+	}
+	if progress != 200 {
+		t.Errorf("progress ended at %d", progress)
+	}
+	if report.Usage == nil || report.Usage.DatabaseBytes == 0 {
+		t.Errorf("usage = %+v, want the database size", report.Usage)
+	}
+
+	// Every clip is unique, so none were folded into another
+	clips, err := store.List(context.Background(), storage.ListFilter{Limit: 1000})
+	if err != nil || len(clips) != 200 {
+		t.Errorf("store holds %d clips (%v), want 200", len(clips), err)
+	}
+	for _, clip := range clips {
+		if len(clip.Content) != 256 {
+			t.Fatalf("clip %s is %d bytes, want 256", clip.ID, len(clip.Content))
+		}
+	}
+}
@@ -0,0 +1,253 @@
+// Package chat sends clips to Slack and Discord: text as a code block and
+// images as uploaded files
+package chat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Targets
+const (
+	Slack   = "slack"
+	Discord = "discord"
+)
+
+const (
+	// DefaultSlackAPI is the Slack Web API used for uploads
+	DefaultSlackAPI = "https://slack.com/api"
+
+	// Longer text is sent as a file instead of a code block
+	maxSlackText   = 39000
+	maxDiscordText = 2000
+
+	maxResponseSize = 64 * 1024
+)
+
+var (
+	ErrUnknownTarget = errors.New("unknown target, expected slack or discord")
+	ErrNotConfigured = errors.New("target is not configured")
+	ErrSensitive     = errors.New("sensitive clips are never sent")
+	ErrSend          = errors.New("send failed")
+)
+
+// Message is a clip to send. It holds either text or an image.
+type Message struct {
+	Text     string
+	Language string // Language of a code snippet, used to highlight it
+	Image    []byte
+	FileName string // Name of the uploaded image or long text
+}
+
+// Sender posts messages to the configured Slack and Discord channels. Slack
+// text goes through an incoming webhook if one is set, and everything else
+// through the bot token.
+type Sender struct {
+	Client         *http.Client
+	SlackWebhook   string
+	SlackToken     string // Bot token with chat:write and files:write
+	SlackChannel   string // Channel ID the bot posts to
+	SlackAPI       string // "" uses DefaultSlackAPI
+	DiscordWebhook string
+}
+
+// Send posts msg to target
+func (s Sender) Send(ctx context.Context, target string, msg Message) error {
+	switch target {
+	case Slack:
+		return s.slack(ctx, msg)
+	case Discord:
+		return s.discord(ctx, msg)
+	}
+	return fmt.Errorf("%w: %q", ErrUnknownTarget, target)
+}
+
+// CodeBlock fences text for Slack and Discord. Backtick fences inside the
+// text are broken up so they don't end the block early.
+func CodeBlock(text, language string) string {
+	text = strings.ReplaceAll(text, "```", "`\u200b``")
+	return "```" + language + "\n" + strings.TrimRight(text, "\n") + "\n```"
+}
+
+func (s Sender) slack(ctx context.Context, msg Message) error {
+	botReady := s.SlackToken != "" && s.SlackChannel != ""
+	if msg.Image == nil && len(msg.Text) <= maxSlackText {
+		// Slack doesn't highlight code blocks, so the language is left out
+		text := CodeBlock(msg.Text, "")
+		switch {
+		case s.SlackWebhook != "":
+			return s.postJSON(ctx, s.SlackWebhook, map[string]string{"text": text})
+		case botReady:
+			return s.slackCall(ctx, "chat.postMessage", map[string]string{"channel": s.SlackChannel, "text": text}, nil)
+		}
+		return fmt.Errorf("%w: set send.slack.webhook_url, or token and channel", ErrNotConfigured)
+	}
+
+	if !botReady {
+		return fmt.Errorf("%w: uploading to Slack needs send.slack.token and channel", ErrNotConfigured)
+	}
+	content := msg.Image
+	if content == nil {
+		content = []byte(msg.Text)
+	}
+	return s.slackUpload(ctx, msg.FileName, content)
+}
+
+// slackUpload uploads a file to the channel in the three steps of Slack's
+// external upload API
+func (s Sender) slackUpload(ctx context.Context, name string, content []byte) error {
+	var upload struct {
+		UploadURL string `json:"upload_url"`
+		FileID    string `json:"file_id"`
+	}
+	form := strings.NewReader(url.Values{"filename": {name}, "length": {strconv.Itoa(len(content))}}.Encode())
+	if err := s.slackRequest(ctx, "files.getUploadURLExternal", "application/x-www-form-urlencoded", form, &upload); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, upload.UploadURL, bytes.NewReader(content))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrSend, err)
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	if _, err := s.do(req); err != nil {
+		return err
+	}
+
+	return s.slackCall(ctx, "files.completeUploadExternal", map[string]any{
+		"files":      []map[string]string{{"id": upload.FileID, "title": name}},
+		"channel_id": s.SlackChannel,
+	}, nil)
+}
+
+// slackCall calls a Slack Web API method with a JSON body
+func (s Sender) slackCall(ctx context.Context, method string, body, result any) error {
+	encoded, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", method, err)
+	}
+	return s.slackRequest(ctx, method, "application/json; charset=utf-8", bytes.NewReader(encoded), result)
+}
+
+// slackRequest calls a Slack Web API method. Slack reports failures in the
+// body of a successful response.
+func (s Sender) slackRequest(ctx context.Context, method, contentType string, body io.Reader, result any) error {
+	api := s.SlackAPI
+	if api == "" {
+		api = DefaultSlackAPI
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(api, "/")+"/"+method, body)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrSend, err)
+	}
+	req.Header.Set("Authorization", "Bearer "+s.SlackToken)
+	req.Header.Set("Content-Type", contentType)
+	response, err := s.do(req)
+	if err != nil {
+		return err
+	}
+
+	var status struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(response, &status); err != nil {
+		return fmt.Errorf("%w: invalid response from Slack %s", ErrSend, method)
+	}
+	if !status.OK {
+		return fmt.Errorf("%w: Slack %s: %s", ErrSend, method, status.Error)
+	}
+	if result != nil {
+		return json.Unmarshal(response, result)
+	}
+	return nil
+}
+
+func (s Sender) discord(ctx context.Context, msg Message) error {
+	if s.DiscordWebhook == "" {
+		return fmt.Errorf("%w: set send.discord.webhook_url", ErrNotConfigured)
+	}
+	if msg.Image == nil {
+		text := CodeBlock(msg.Text, msg.Language)
+		if len(text) <= maxDiscordText {
+			return s.postJSON(ctx, s.DiscordWebhook, map[string]any{
+				"content":          text,
+				"allowed_mentions": map[string]any{"parse": []string{}},
+			})
+		}
+	}
+
+	content := msg.Image
+	if content == nil {
+		content = []byte(msg.Text)
+	}
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	form.WriteField("payload_json", `{"allowed_mentions":{"parse":[]}}`)
+	part, err := form.CreateFormFile("files[0]", msg.FileName)
+	if err != nil {
+		return fmt.Errorf("failed to encode upload: %w", err)
+	}
+	part.Write(content)
+	if err := form.Close(); err != nil {
+		return fmt.Errorf("failed to encode upload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.DiscordWebhook, &body)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrSend, err)
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	_, err = s.do(req)
+	return err
+}
+
+// postJSON posts body to a webhook
+func (s Sender) postJSON(ctx context.Context, endpoint string, body any) error {
+	encoded, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(encoded))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrSend, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	_, err = s.do(req)
+	return err
+}
+
+// do sends a request and returns the body of a successful response
+func (s Sender) do(req *http.Request) ([]byte, error) {
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSend, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSend, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		message := strings.TrimSpace(string(body))
+		if len(message) > 200 {
+			message = message[:200]
+		}
+		return nil, fmt.Errorf("%w: %s returned %s: %s", ErrSend, req.URL.Host, resp.Status, message)
+	}
+	return body, nil
+}
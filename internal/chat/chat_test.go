@@ -0,0 +1,124 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCodeBlock(t *testing.T) {
+	got := CodeBlock("a\n```\nb\n", "go")
+	if got != "```go\na\n`\u200b``\nb\n```" {
+		t.Errorf("CodeBlock() = %q", got)
+	}
+}
+
+func TestSend_Slack(t *testing.T) {
+	var posted []string
+	var uploaded []byte
+	mux := http.NewServeMux()
+	mux.HandleFunc("/webhook", func(w http.ResponseWriter, r *http.Request) {
+		var message struct{ Text string }
+		json.NewDecoder(r.Body).Decode(&message)
+		posted = append(posted, message.Text)
+		w.Write([]byte("ok"))
+	})
+	mux.HandleFunc("/api/files.getUploadURLExternal", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer xoxb" || r.FormValue("filename") != "shot.png" || r.FormValue("length") != "3" {
+			w.Write([]byte(`{"ok": false, "error": "invalid_arguments"}`))
+			return
+		}
+		w.Write([]byte(`{"ok": true, "upload_url": "http://` + r.Host + `/upload", "file_id": "F1"}`))
+	})
+	mux.HandleFunc("/upload", func(w http.ResponseWriter, r *http.Request) {
+		uploaded, _ = io.ReadAll(r.Body)
+	})
+	mux.HandleFunc("/api/files.completeUploadExternal", func(w http.ResponseWriter, r *http.Request) {
+		var complete struct {
+			Files     []struct{ ID string }
+			ChannelID string `json:"channel_id"`
+		}
+		json.NewDecoder(r.Body).Decode(&complete)
+		if len(complete.Files) != 1 || complete.Files[0].ID != "F1" || complete.ChannelID != "C1" {
+			w.Write([]byte(`{"ok": false, "error": "file_not_found"}`))
+			return
+		}
+		w.Write([]byte(`{"ok": true}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	sender := Sender{Client: server.Client(), SlackWebhook: server.URL + "/webhook"}
+	if err := sender.Send(context.Background(), Slack, Message{Text: "x := 1", Language: "go"}); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if len(posted) != 1 || posted[0] != "```\nx := 1\n```" {
+		t.Errorf("posted %q", posted)
+	}
+
+	image := Message{Image: []byte("png"), FileName: "shot.png"}
+	if err := sender.Send(context.Background(), Slack, image); !errors.Is(err, ErrNotConfigured) {
+		t.Errorf("Send() of an image without a token error = %v, want ErrNotConfigured", err)
+	}
+	sender.SlackToken, sender.SlackChannel, sender.SlackAPI = "xoxb", "C1", server.URL+"/api"
+	if err := sender.Send(context.Background(), Slack, image); err != nil {
+		t.Fatalf("Send() of an image error = %v", err)
+	}
+	if string(uploaded) != "png" {
+		t.Errorf("uploaded %q", uploaded)
+	}
+	sender.SlackChannel = "C2"
+	if err := sender.Send(context.Background(), Slack, image); !errors.Is(err, ErrSend) || !strings.Contains(err.Error(), "file_not_found") {
+		t.Errorf("Send() error = %v, want Slack's error", err)
+	}
+}
+
+func TestSend_Discord(t *testing.T) {
+	var contents, files []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+			var message struct{ Content string }
+			json.NewDecoder(r.Body).Decode(&message)
+			contents = append(contents, message.Content)
+		} else {
+			_, header, err := r.FormFile("files[0]")
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			files = append(files, header.Filename)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	sender := Sender{Client: server.Client()}
+	if err := sender.Send(context.Background(), Discord, Message{Text: "hi"}); !errors.Is(err, ErrNotConfigured) {
+		t.Errorf("Send() without a webhook error = %v, want ErrNotConfigured", err)
+	}
+	sender.DiscordWebhook = server.URL
+	ctx := context.Background()
+	if err := sender.Send(ctx, Discord, Message{Text: "print(1)", Language: "python"}); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if err := sender.Send(ctx, Discord, Message{Text: strings.Repeat("x", 3000), FileName: "clip.txt"}); err != nil {
+		t.Fatalf("Send() of long text error = %v", err)
+	}
+	if err := sender.Send(ctx, Discord, Message{Image: []byte("png"), FileName: "shot.png"}); err != nil {
+		t.Fatalf("Send() of an image error = %v", err)
+	}
+	if len(contents) != 1 || contents[0] != "```python\nprint(1)\n```" {
+		t.Errorf("posted %q", contents)
+	}
+	if strings.Join(files, ",") != "clip.txt,shot.png" {
+		t.Errorf("uploaded %q", files)
+	}
+	if err := sender.Send(ctx, "teams", Message{Text: "hi"}); !errors.Is(err, ErrUnknownTarget) {
+		t.Errorf("Send() to an unknown target error = %v, want ErrUnknownTarget", err)
+	}
+}
@@ -0,0 +1,128 @@
+package clipboard
+
+import "clipboard-manager/pkg/types"
+
+// contentPasteboard is the pasteboard access needed to tell what was copied.
+// Types are pasteboard types (UTIs) such as public.utf8-plain-text.
+type contentPasteboard interface {
+	String(pasteboardType string) string
+	Data(pasteboardType string) []byte
+	// FileURLs returns the file URL of every item, in pasteboard order
+	FileURLs() []string
+}
+
+// detectContent picks the richest representation on the pasteboard as the
+// content of a clip, keeping others it can paste back as alternate formats.
+// It reports false when there's nothing it captures. Whatever apps put on
+// the pasteboard, it must not panic.
+func detectContent(pb contentPasteboard) (types.Clip, bool) {
+	var clip types.Clip
+	handled := false
+
+	// Check for text content
+	if text := pb.String("public.utf8-plain-text"); text != "" {
+		clip.Content = []byte(text)
+		clip.Type = types.TypeText
+		handled = true
+
+		// Keep the rich text versions alongside the plain text
+		if rtf := pb.Data("public.rtf"); len(rtf) > 0 {
+			clip.Metadata.Formats = map[string][]byte{types.TypeRTF: rtf}
+			debugLog("Debug: Captured RTF representation, length: %d\n", len(rtf))
+		}
+		if rtfd := pb.Data("com.apple.flat-rtfd"); len(rtfd) > 0 {
+			if clip.Metadata.Formats == nil {
+				clip.Metadata.Formats = make(map[string][]byte)
+			}
+			clip.Metadata.Formats[types.TypeRTFD] = rtfd
+			debugLog("Debug: Captured RTFD representation, length: %d\n", len(rtfd))
+		}
+	}
+
+	// Prefer HTML when available, keeping a plain text shadow copy for search and preview
+	if htmlContent := pb.String("public.html"); htmlContent != "" {
+		if sanitized := SanitizeHTML(htmlContent); sanitized != "" {
+			plainText := string(clip.Content)
+			if !handled {
+				plainText = HTMLToText(sanitized)
+			}
+			if clip.Metadata.Formats == nil {
+				clip.Metadata.Formats = make(map[string][]byte)
+			}
+			clip.Metadata.Formats[types.TypeText] = []byte(plainText)
+			clip.Content = []byte(sanitized)
+			clip.Type = types.TypeHTML
+			handled = true
+			debugLog("Debug: Captured HTML content, length: %d\n", len(sanitized))
+		}
+	}
+
+	// Check for RTF-only content
+	if !handled {
+		if rtf := pb.Data("public.rtf"); len(rtf) > 0 {
+			clip.Content = rtf
+			clip.Type = types.TypeRTF
+			handled = true
+		}
+	}
+
+	// Check for RTFD-only content, such as an image copied out of a TextEdit
+	// document. The monitor extracts its text.
+	if !handled {
+		if rtfd := pb.Data("com.apple.flat-rtfd"); len(rtfd) > 0 {
+			clip.Content = rtfd
+			clip.Type = types.TypeRTFD
+			handled = true
+		}
+	}
+
+	// Check for PDF content, such as a selection copied in Preview. The image
+	// apps put next to it is kept for pasting into apps that don't take PDF;
+	// its text is extracted later.
+	if !handled {
+		if pdf := pb.Data("com.adobe.pdf"); len(pdf) > 0 {
+			clip.Content = pdf
+			clip.Type = types.TypePDF
+			clip.Metadata.Formats = make(map[string][]byte)
+			if tiff := pb.Data("public.tiff"); len(tiff) > 0 {
+				clip.Metadata.Formats[types.TypeTIFF] = tiff
+			} else if png := pb.Data("public.png"); len(png) > 0 {
+				clip.Metadata.Formats[types.TypePNG] = png
+			}
+			handled = true
+			debugLog("Debug: Captured PDF content, length: %d\n", len(pdf))
+		}
+	}
+
+	// Check for image content. The monitor tells screenshots apart.
+	if !handled {
+		if data := pb.Data("public.png"); len(data) > 0 {
+			clip.Content = data
+			clip.Type = types.TypePNG
+			handled = true
+		} else if data := pb.Data("public.tiff"); len(data) > 0 {
+			clip.Content = data
+			clip.Type = types.TypeTIFF
+			handled = true
+		}
+	}
+
+	// Check for file URLs
+	if !handled {
+		if urls := pb.FileURLs(); len(urls) > 1 {
+			if content, err := EncodeFileList(urls); err == nil {
+				clip.Content = content
+				clip.Type = types.TypeFileList
+				clip.Metadata.Formats = map[string][]byte{types.TypeText: []byte(FileListText(urls))}
+				handled = true
+				debugLog("Debug: Captured file list with %d files\n", len(urls))
+			}
+		} else if urls := pb.String("public.file-url"); urls != "" {
+			clip.Content = []byte(urls)
+			clip.Type = types.TypeFile
+			handled = true
+		}
+	}
+
+	return clip, handled
+}
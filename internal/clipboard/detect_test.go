@@ -0,0 +1,137 @@
+package clipboard
+
+import (
+	"bytes"
+	"clipboard-manager/pkg/types"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// contentFake is an in-memory pasteboard holding strings and data by type
+type contentFake struct {
+	values   map[string][]byte
+	fileURLs []string
+}
+
+func (p contentFake) String(pasteboardType string) string {
+	return string(p.values[pasteboardType])
+}
+
+func (p contentFake) Data(pasteboardType string) []byte {
+	return p.values[pasteboardType]
+}
+
+func (p contentFake) FileURLs() []string {
+	return p.fileURLs
+}
+
+func TestDetectContent(t *testing.T) {
+	tests := []struct {
+		name      string
+		pb        contentFake
+		wantType  string
+		want      string
+		wantPlain string
+	}{
+		{"empty", contentFake{}, "", "", ""},
+		{"text", contentFake{values: map[string][]byte{
+			"public.utf8-plain-text": []byte("hello"),
+			"public.rtf":             []byte(`{\rtf1 hello}`),
+		}}, "text/plain", "hello", ""},
+		{"html with text", contentFake{values: map[string][]byte{
+			"public.utf8-plain-text": []byte("hello"),
+			"public.html":            []byte("<b>hello</b><script>x</script>"),
+		}}, "text/html", "<b>hello</b>", "hello"},
+		{"html only", contentFake{values: map[string][]byte{
+			"public.html": []byte("<p>hi</p>"),
+		}}, "text/html", "<p>hi</p>", "hi"},
+		{"pdf", contentFake{values: map[string][]byte{
+			"com.adobe.pdf": []byte("%PDF-1.4"),
+			"public.png":    []byte("png"),
+		}}, types.TypePDF, "%PDF-1.4", ""},
+		{"tiff", contentFake{values: map[string][]byte{
+			"public.tiff": []byte("tiff"),
+		}}, "image/tiff", "tiff", ""},
+		{"files", contentFake{fileURLs: []string{"file:///a", "file:///b"}}, types.TypeFileList, `["file:///a","file:///b"]`, "/a\n/b"},
+		{"file", contentFake{
+			values:   map[string][]byte{"public.file-url": []byte("file:///a")},
+			fileURLs: []string{"file:///a"},
+		}, "file", "file:///a", ""},
+	}
+	for _, test := range tests {
+		clip, ok := detectContent(test.pb)
+		if ok != (test.wantType != "") || clip.Type != test.wantType || string(clip.Content) != test.want {
+			t.Errorf("%s: got %v %q %q, want %q %q", test.name, ok, clip.Type, clip.Content, test.wantType, test.want)
+		}
+		if plain := string(clip.Metadata.Formats["text/plain"]); plain != test.wantPlain {
+			t.Errorf("%s: plain text is %q, want %q", test.name, plain, test.wantPlain)
+		}
+	}
+}
+
+// FuzzDetectContent puts whatever apps might on the pasteboard and checks
+// the clip captured from it makes sense
+func FuzzDetectContent(f *testing.F) {
+	f.Add("hello", "", []byte(nil), []byte(nil), []byte(nil), "")
+	f.Add("", "<p>hi<script>alert(1)</script>", []byte(nil), []byte(nil), []byte(nil), "")
+	f.Add("", "<a href=\"javascript:x\"><img src=x onerror=y>", []byte(`{\rtf1}`), []byte(nil), []byte(nil), "")
+	f.Add("", "", []byte(nil), []byte("%PDF-1.4"), []byte("\x89PNG\r\n\x1a\n"), "")
+	f.Add("", "", []byte(nil), []byte(nil), []byte(nil), "file:///a%20b\nfile:///c")
+	f.Add("", "<", []byte(nil), []byte(nil), []byte(nil), "\x00\n%zz")
+
+	f.Fuzz(func(t *testing.T, text, html string, rtf, pdf, png []byte, files string) {
+		pb := contentFake{values: map[string][]byte{
+			"public.utf8-plain-text": []byte(text),
+			"public.html":            []byte(html),
+			"public.rtf":             rtf,
+			"com.adobe.pdf":          pdf,
+			"public.png":             png,
+		}}
+		if files != "" {
+			pb.fileURLs = strings.Split(files, "\n")
+			pb.values["public.file-url"] = []byte(pb.fileURLs[0])
+		}
+
+		clip, ok := detectContent(pb)
+		if !ok {
+			if clip.Type != "" || len(clip.Content) > 0 {
+				t.Fatalf("nothing captured, but got a %q clip", clip.Type)
+			}
+			if text != "" || len(rtf) > 0 || len(pdf) > 0 || len(png) > 0 || files != "" {
+				t.Fatal("content on the pasteboard wasn't captured")
+			}
+			return
+		}
+		if clip.Type == "" || len(clip.Content) == 0 {
+			t.Fatalf("captured a %q clip with %d bytes", clip.Type, len(clip.Content))
+		}
+
+		switch clip.Type {
+		case "text/html":
+			if sanitized := SanitizeHTML(string(clip.Content)); sanitized != string(clip.Content) {
+				t.Errorf("captured HTML isn't sanitized: %q sanitizes to %q", clip.Content, sanitized)
+			}
+			if _, ok := clip.Metadata.Formats["text/plain"]; !ok {
+				t.Error("HTML clip has no plain text")
+			}
+		case "text/plain":
+			if string(clip.Content) != text {
+				t.Errorf("text clip holds %q, want %q", clip.Content, text)
+			}
+		case types.TypeFileList:
+			urls, err := DecodeFileList(clip.Content)
+			if err != nil {
+				t.Fatalf("file list doesn't decode: %v", err)
+			}
+			// JSON replaces invalid UTF-8, which pasteboard strings never are
+			if utf8.ValidString(files) && strings.Join(urls, "\n") != files {
+				t.Errorf("file list holds %q, want %q", urls, files)
+			}
+		case types.TypePDF:
+			if !bytes.Equal(clip.Content, pdf) {
+				t.Error("PDF clip doesn't hold the PDF")
+			}
+		}
+	})
+}
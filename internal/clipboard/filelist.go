@@ -0,0 +1,36 @@
+package clipboard
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// EncodeFileList stores a list of file URLs as the content of a file-list clip
+func EncodeFileList(urls []string) ([]byte, error) {
+	return json.Marshal(urls)
+}
+
+// DecodeFileList returns the file URLs stored in a file-list clip
+func DecodeFileList(content []byte) ([]string, error) {
+	var urls []string
+	if err := json.Unmarshal(content, &urls); err != nil {
+		return nil, fmt.Errorf("invalid file list: %w", err)
+	}
+	return urls, nil
+}
+
+// FileListText returns a newline separated list of file paths, used as the
+// plain text representation of a file-list clip for search and preview
+func FileListText(urls []string) string {
+	paths := make([]string, 0, len(urls))
+	for _, u := range urls {
+		if parsed, err := url.Parse(u); err == nil && parsed.Scheme == "file" {
+			paths = append(paths, parsed.Path)
+			continue
+		}
+		paths = append(paths, u)
+	}
+	return strings.Join(paths, "\n")
+}
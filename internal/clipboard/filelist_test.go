@@ -0,0 +1,53 @@
+package clipboard
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFileList(t *testing.T) {
+	tests := []struct {
+		name string
+		urls []string
+		text string
+	}{
+		{"one file", []string{"file:///Users/me/report.pdf"}, "/Users/me/report.pdf"},
+		{
+			"several files",
+			[]string{"file:///tmp/a.txt", "file:///tmp/b.txt", "file:///tmp/c.txt"},
+			"/tmp/a.txt\n/tmp/b.txt\n/tmp/c.txt",
+		},
+		{"spaces", []string{"file:///Users/me/My%20Documents/tax%20return.pdf"}, "/Users/me/My Documents/tax return.pdf"},
+		{"unicode", []string{"file:///Users/me/R%C3%A9sum%C3%A9%20%E2%9C%93.pdf", "file:///tmp/日本語.txt"}, "/Users/me/Résumé ✓.pdf\n/tmp/日本語.txt"},
+		{"newline in a name", []string{"file:///tmp/first%0Asecond.txt"}, "/tmp/first\nsecond.txt"},
+		{"not a file URL", []string{"https://example.com/a.txt", "file:///tmp/b.txt"}, "https://example.com/a.txt\n/tmp/b.txt"},
+		{"empty", []string{}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content, err := EncodeFileList(tt.urls)
+			if err != nil {
+				t.Fatalf("EncodeFileList() error = %v", err)
+			}
+			got, err := DecodeFileList(content)
+			if err != nil {
+				t.Fatalf("DecodeFileList() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.urls) {
+				t.Errorf("round trip = %q, want %q", got, tt.urls)
+			}
+			if text := FileListText(got); text != tt.text {
+				t.Errorf("FileListText() = %q, want %q", text, tt.text)
+			}
+		})
+	}
+}
+
+func TestDecodeFileList_Invalid(t *testing.T) {
+	for _, content := range []string{"", "file:///tmp/a.txt", `{"urls": []}`} {
+		if urls, err := DecodeFileList([]byte(content)); err == nil {
+			t.Errorf("DecodeFileList(%q) = %q, want an error", content, urls)
+		}
+	}
+}
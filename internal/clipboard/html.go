@@ -0,0 +1,55 @@
+package clipboard
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	// Elements whose content must never be kept
+	scriptPattern = regexp.MustCompile(`(?is)<script\b[^>]*>.*?</script\s*>`)
+	stylePattern  = regexp.MustCompile(`(?is)<style\b[^>]*>.*?</style\s*>`)
+	// Unterminated or self-closing script/style tags
+	strayTagPattern = regexp.MustCompile(`(?is)</?(script|style)\b[^>]*>`)
+	// Inline event handlers such as onclick="..."
+	eventAttrPattern = regexp.MustCompile(`(?is)\s+on[a-z]+\s*=\s*("[^"]*"|'[^']*'|[^\s>]+)`)
+	// javascript: URLs in href/src attributes
+	jsURLPattern = regexp.MustCompile(`(?is)(href|src)\s*=\s*("\s*javascript:[^"]*"|'\s*javascript:[^']*'|javascript:[^\s>]+)`)
+
+	// Tags that start a new line when converted to plain text
+	blockTagPattern = regexp.MustCompile(`(?i)<\s*(br|/p|/div|/li|/tr|/h[1-6]|/blockquote|/pre)\b[^>]*>`)
+	tagPattern      = regexp.MustCompile(`(?s)<[^>]*>`)
+	commentPattern  = regexp.MustCompile(`(?s)<!--.*?-->`)
+	blankLines      = regexp.MustCompile(`\n{3,}`)
+)
+
+// SanitizeHTML removes scripts, styles, inline event handlers and javascript: URLs
+// from captured HTML so it is safe to store and render
+func SanitizeHTML(content string) string {
+	content = commentPattern.ReplaceAllString(content, "")
+	content = scriptPattern.ReplaceAllString(content, "")
+	content = stylePattern.ReplaceAllString(content, "")
+	content = strayTagPattern.ReplaceAllString(content, "")
+	content = eventAttrPattern.ReplaceAllString(content, "")
+	content = jsURLPattern.ReplaceAllString(content, `$1=""`)
+	return strings.TrimSpace(content)
+}
+
+// HTMLToText derives a plain text version of HTML content for search and preview
+func HTMLToText(content string) string {
+	content = SanitizeHTML(content)
+	content = blockTagPattern.ReplaceAllString(content, "\n")
+	content = tagPattern.ReplaceAllString(content, "")
+	content = html.UnescapeString(content)
+
+	// Normalize whitespace line by line
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		lines[i] = strings.Join(strings.Fields(line), " ")
+	}
+	content = strings.Join(lines, "\n")
+	content = blankLines.ReplaceAllString(content, "\n\n")
+
+	return strings.TrimSpace(content)
+}
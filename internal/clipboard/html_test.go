@@ -0,0 +1,33 @@
+package clipboard
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSanitizeHTML(t *testing.T) {
+	input := `<div onclick="steal()"><style>p { color: red; }</style>` +
+		`<p>Hello <a href="javascript:alert(1)">link</a></p>` +
+		`<script type="text/javascript">alert("x")</script></div>`
+
+	got := SanitizeHTML(input)
+
+	for _, forbidden := range []string{"<script", "alert(", "<style", "color: red", "onclick", "javascript:"} {
+		if strings.Contains(got, forbidden) {
+			t.Errorf("sanitized HTML still contains %q: %s", forbidden, got)
+		}
+	}
+	if !strings.Contains(got, "<p>Hello") {
+		t.Errorf("sanitized HTML lost content: %s", got)
+	}
+}
+
+func TestHTMLToText(t *testing.T) {
+	input := `<h1>Title</h1><p>First &amp; <b>bold</b></p><script>ignored()</script><ul><li>one</li><li>two</li></ul>`
+
+	got := HTMLToText(input)
+	want := "Title\nFirst & bold\none\ntwo"
+	if got != want {
+		t.Errorf("HTMLToText() = %q, want %q", got, want)
+	}
+}
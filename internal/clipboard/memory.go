@@ -0,0 +1,100 @@
+package clipboard
+
+import (
+	"clipboard-manager/pkg/types"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// MemoryMonitor is an in-memory Monitor that never touches the system clipboard.
+// It backs headless mode and lets tests drive the service without AppKit:
+// clips are injected programmatically and SetContent calls are recorded.
+type MemoryMonitor struct {
+	mu      sync.RWMutex
+	handler func(types.Clip)
+	running bool
+	current *types.Clip
+	written []types.Clip
+}
+
+// NewMemoryMonitor creates a new in-memory monitor
+func NewMemoryMonitor() *MemoryMonitor {
+	return &MemoryMonitor{}
+}
+
+func (m *MemoryMonitor) Start() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.running {
+		return fmt.Errorf("monitor already running")
+	}
+	m.running = true
+	return nil
+}
+
+func (m *MemoryMonitor) Stop() error {
+	m.mu.Lock()
+	m.running = false
+	m.mu.Unlock()
+	return nil
+}
+
+func (m *MemoryMonitor) OnChange(handler func(types.Clip)) {
+	m.mu.Lock()
+	m.handler = handler
+	m.mu.Unlock()
+}
+
+// SetContent records the clip as the current clipboard content
+func (m *MemoryMonitor) SetContent(clip types.Clip) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.current = &clip
+	m.written = append(m.written, clip)
+	return nil
+}
+
+// InjectClip simulates a user copy. The change handler is only notified
+// while the monitor is running, like a real pasteboard monitor.
+func (m *MemoryMonitor) InjectClip(clip types.Clip) {
+	if clip.CreatedAt.IsZero() {
+		clip.CreatedAt = time.Now()
+	}
+
+	m.mu.Lock()
+	m.current = &clip
+	handler := m.handler
+	running := m.running
+	m.mu.Unlock()
+
+	if running && handler != nil {
+		handler(clip)
+	}
+}
+
+// Current returns the current clipboard content, if any
+func (m *MemoryMonitor) Current() (types.Clip, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.current == nil {
+		return types.Clip{}, false
+	}
+	return *m.current, true
+}
+
+// HasContent implements ContentChecker
+func (m *MemoryMonitor) HasContent() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.current != nil
+}
+
+// Written returns every clip passed to SetContent, oldest first
+func (m *MemoryMonitor) Written() []types.Clip {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	written := make([]types.Clip, len(m.written))
+	copy(written, m.written)
+	return written
+}
@@ -1,6 +1,30 @@
 package clipboard
 
-import "clipboard-manager/pkg/types"
+import (
+	"clipboard-manager/pkg/types"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// debugMode turns on verbose monitor logging, from DEBUG=1 or SetDebug
+var debugMode atomic.Bool
+
+func init() {
+	debugMode.Store(os.Getenv("DEBUG") == "1")
+}
+
+func debugLog(format string, args ...interface{}) {
+	if debugMode.Load() {
+		fmt.Printf("[DEBUG] "+format, args...)
+	}
+}
+
+// SetDebug turns verbose monitor logging on or off
+func SetDebug(enabled bool) {
+	debugMode.Store(enabled)
+}
 
 type Monitor interface {
 	Start() error
@@ -9,3 +33,15 @@ type Monitor interface {
 	// SetContent sets the system clipboard content
 	SetContent(clip types.Clip) error
 }
+
+// ContentChecker is implemented by monitors that can tell whether the
+// clipboard holds anything
+type ContentChecker interface {
+	HasContent() bool
+}
+
+// PollIntervalSetter is implemented by monitors that poll the clipboard and
+// can change their polling intervals while running
+type PollIntervalSetter interface {
+	SetPollIntervals(min, max time.Duration)
+}
@@ -3,21 +3,19 @@ package clipboard
 import (
 	"clipboard-manager/pkg/types"
 	"fmt"
-	"os"
 	"runtime"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/progrium/darwinkit/macos/appkit"
+	"github.com/progrium/darwinkit/macos/foundation"
 )
 
-var debugMode = os.Getenv("DEBUG") == "1"
-
-func debugLog(format string, args ...interface{}) {
-	if debugMode {
-		fmt.Printf("[DEBUG] "+format, args...)
-	}
-}
+// concealedType is the nspasteboard.org marker password managers add to
+// secrets they put on the pasteboard
+const concealedType = "org.nspasteboard.ConcealedType"
 
 type pasteboardOp struct {
 	clip types.Clip
@@ -26,11 +24,45 @@ type pasteboardOp struct {
 
 type DarwinMonitor struct {
 	handler     func(types.Clip)
+	errHandler  func(error) // Told when polling panics and restarts
 	pasteboard  appkit.Pasteboard
 	changeCount int
 	mutex       sync.RWMutex
 	stopChan    chan struct{}
 	opChan      chan pasteboardOp
+	iconCache   map[string][]byte // PNG icons by bundle ID, only used by the polling goroutine
+	config      Config            // Polling intervals are guarded by mutex
+	pollChanged chan struct{}     // Signals new polling intervals to the polling goroutine
+	guard       writeGuard
+}
+
+// darwinPasteboard adapts an AppKit pasteboard for self-write and content
+// detection
+type darwinPasteboard struct {
+	pb appkit.Pasteboard
+}
+
+func (p darwinPasteboard) SetString(value string, pasteboardType string) bool {
+	return p.pb.SetStringForType(value, appkit.PasteboardType(pasteboardType))
+}
+
+func (p darwinPasteboard) String(pasteboardType string) string {
+	return p.pb.StringForType(appkit.PasteboardType(pasteboardType))
+}
+
+func (p darwinPasteboard) Data(pasteboardType string) []byte {
+	return p.pb.DataForType(appkit.PasteboardType(pasteboardType))
+}
+
+// FileURLs implements contentPasteboard
+func (p darwinPasteboard) FileURLs() []string {
+	var urls []string
+	for _, item := range p.pb.PasteboardItems() {
+		if u := item.StringForType(appkit.PasteboardType("public.file-url")); u != "" {
+			urls = append(urls, u)
+		}
+	}
+	return urls
 }
 
 func init() {
@@ -38,11 +70,20 @@ func init() {
 	runtime.LockOSThread()
 }
 
+// NewMonitor creates a monitor using the default polling configuration
 func NewMonitor() Monitor {
+	return NewMonitorWithConfig(Config{})
+}
+
+// NewMonitorWithConfig creates a monitor with custom polling intervals
+func NewMonitorWithConfig(config Config) Monitor {
 	m := &DarwinMonitor{
-		pasteboard: appkit.Pasteboard_GeneralPasteboard(),
-		stopChan:   make(chan struct{}),
-		opChan:     make(chan pasteboardOp),
+		config:      config.withDefaults(),
+		pasteboard:  appkit.Pasteboard_GeneralPasteboard(),
+		stopChan:    make(chan struct{}),
+		opChan:      make(chan pasteboardOp),
+		pollChanged: make(chan struct{}, 1),
+		iconCache:   make(map[string][]byte),
 	}
 
 	// Start a goroutine on the main thread to handle pasteboard operations
@@ -67,21 +108,70 @@ func (m *DarwinMonitor) Start() error {
 	m.changeCount = initialCount
 	m.mutex.Unlock()
 
-	go func() {
-		ticker := time.NewTicker(1 * time.Second)
-		defer ticker.Stop()
+	// A panic while reading the pasteboard restarts polling instead of
+	// ending it
+	go Supervise("clipboard monitor", m.stopChan, m.poll, func(err error) {
+		m.mutex.RLock()
+		onError := m.errHandler
+		m.mutex.RUnlock()
+		if onError != nil {
+			onError(err)
+		}
+	})
 
-		for {
-			select {
-			case <-ticker.C:
-				m.checkForChanges()
-			case <-m.stopChan:
-				return
+	return nil
+}
+
+// poll checks the pasteboard for changes until the monitor is stopped
+func (m *DarwinMonitor) poll() {
+	m.mutex.RLock()
+	config := m.config
+	m.mutex.RUnlock()
+
+	// Poll quickly right after activity and back off while idle
+	poller := newAdaptivePoller(config)
+	timer := time.NewTimer(config.MinPollInterval)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+			changed := m.checkForChanges()
+			timer.Reset(poller.next(changed))
+		case <-m.pollChanged:
+			m.mutex.RLock()
+			config = m.config
+			m.mutex.RUnlock()
+			poller = newAdaptivePoller(config)
+			if !timer.Stop() {
+				<-timer.C
 			}
+			timer.Reset(config.MinPollInterval)
+		case <-m.stopChan:
+			return
 		}
-	}()
+	}
+}
 
-	return nil
+// OnError implements ErrorReporter
+func (m *DarwinMonitor) OnError(handler func(error)) {
+	m.mutex.Lock()
+	m.errHandler = handler
+	m.mutex.Unlock()
+}
+
+// SetPollIntervals implements PollIntervalSetter
+func (m *DarwinMonitor) SetPollIntervals(min, max time.Duration) {
+	config := Config{MinPollInterval: min, MaxPollInterval: max}.withDefaults()
+	m.mutex.Lock()
+	m.config.MinPollInterval, m.config.MaxPollInterval = config.MinPollInterval, config.MaxPollInterval
+	m.mutex.Unlock()
+
+	select {
+	case m.pollChanged <- struct{}{}:
+	default:
+		// The polling goroutine hasn't picked up the last change yet
+	}
 }
 
 func (m *DarwinMonitor) Stop() error {
@@ -89,6 +179,14 @@ func (m *DarwinMonitor) Stop() error {
 	return nil
 }
 
+// HasContent implements ContentChecker. The pasteboard is empty after a
+// restart.
+func (m *DarwinMonitor) HasContent() bool {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return len(m.pasteboard.Types()) > 0
+}
+
 // GetPasteboardTypes returns all available types in the pasteboard
 func (m *DarwinMonitor) GetPasteboardTypes() []string {
 	m.mutex.RLock()
@@ -134,35 +232,27 @@ func (m *DarwinMonitor) setPasteboardContent(clip types.Clip) error {
 	
 	// Clear the pasteboard first
 	m.pasteboard.ClearContents()
+
+	// Mark the write before adding content so the poller never captures it
+	if err := m.guard.mark(darwinPasteboard{m.pasteboard}); err != nil {
+		debugLog("Debug: Failed to mark pasteboard write: %v\n", err)
+	}
 	
-	switch clip.Type {
-	case "text/plain":
-		m.pasteboard.SetStringForType(string(clip.Content), appkit.PasteboardType("public.utf8-plain-text"))
-	case "text":
-		m.pasteboard.SetStringForType(string(clip.Content), appkit.PasteboardType("public.utf8-plain-text"))
-	case "image/png":
-		m.pasteboard.SetDataForType(clip.Content, appkit.PasteboardType("public.png"))
-	case "image/tiff":
-		m.pasteboard.SetDataForType(clip.Content, appkit.PasteboardType("public.tiff"))
-	case "screenshot":
-		// For screenshots, try PNG first, then TIFF
-		m.pasteboard.SetDataForType(clip.Content, appkit.PasteboardType("public.png"))
-	case "file":
-		m.pasteboard.SetStringForType(string(clip.Content), appkit.PasteboardType("public.file-url"))
-	case "text/html":
-		// For HTML content, set both HTML and plain text
-		m.pasteboard.SetStringForType(string(clip.Content), appkit.PasteboardType("public.html"))
-		if plainText := string(clip.Content); plainText != "" {
-			m.pasteboard.SetStringForType(plainText, appkit.PasteboardType("public.utf8-plain-text"))
+	if clip.Type == types.TypeFileList {
+		items, err := PasteboardItems(clip)
+		if err != nil {
+			return err
 		}
-	default:
-		// Try as plain text for unknown types
-		if plainText := string(clip.Content); plainText != "" {
-			m.pasteboard.SetStringForType(plainText, appkit.PasteboardType("public.utf8-plain-text"))
-			debugLog("Debug: Set unknown type as plain text, length: %d\n", len(plainText))
-			return nil
+		// Write one pasteboard item per file so Finder pastes all of them
+		writers := make([]appkit.PPasteboardWriting, 0, len(items))
+		for _, item := range items {
+			writers = append(writers, appkit.PasteboardWritingObject{Object: item.Object})
+		}
+		if !m.pasteboard.WriteObjects(writers) {
+			return fmt.Errorf("failed to write %d file URLs to pasteboard", len(items))
 		}
-		return fmt.Errorf("unsupported content type: %s", clip.Type)
+	} else if err := writeClip(m.pasteboard, clip); err != nil {
+		return err
 	}
 
 	// Update change count to prevent re-triggering the monitor
@@ -172,6 +262,76 @@ func (m *DarwinMonitor) setPasteboardContent(clip types.Clip) error {
 	return nil
 }
 
+// setSourceApp fills in the source application name, bundle ID and icon of a clip
+func (m *DarwinMonitor) setSourceApp(clip *types.Clip, app appkit.RunningApplication) {
+	clip.Metadata.SourceApp = app.LocalizedName()
+	clip.Metadata.SourceBundleID = app.BundleIdentifier()
+	if clip.Metadata.SourceBundleID == "" {
+		return
+	}
+
+	icon, ok := m.iconCache[clip.Metadata.SourceBundleID]
+	if !ok {
+		icon = appIconPNG(app)
+		m.iconCache[clip.Metadata.SourceBundleID] = icon
+	}
+	clip.Metadata.SourceIcon = icon
+}
+
+// appIconPNG converts the icon of a running application to PNG
+func appIconPNG(app appkit.RunningApplication) []byte {
+	tiff := app.Icon().TIFFRepresentation()
+	if len(tiff) == 0 {
+		return nil
+	}
+	rep := appkit.BitmapImageRep_ImageRepWithData(tiff)
+	return rep.RepresentationUsingTypeProperties(appkit.BitmapImageFileTypePNG, nil)
+}
+
+// screenshot returns the capture details of a screenshot on the pasteboard,
+// or nil if the image on it isn't a screenshot. The captured size is only
+// known for PNG screenshots.
+func (m *DarwinMonitor) screenshot(data []byte) *types.Screenshot {
+	windowIDType := appkit.PasteboardType("com.apple.screencapture.window-id")
+	hasWindowID := false
+	for _, t := range m.pasteboard.Types() {
+		if t == windowIDType {
+			hasWindowID = true
+			break
+		}
+	}
+	if !hasWindowID {
+		return nil
+	}
+
+	screenshot := &types.Screenshot{
+		WindowTitle: m.pasteboard.StringForType(appkit.PasteboardType("com.apple.screencapture.window-name")),
+	}
+	if id, err := strconv.Atoi(strings.TrimSpace(m.pasteboard.StringForType(windowIDType))); err == nil {
+		screenshot.WindowID = id
+	}
+	if app := appkit.Workspace_SharedWorkspace().FrontmostApplication(); !app.IsNil() {
+		screenshot.App = app.LocalizedName()
+	}
+	if screen := appkit.Screen_MainScreen(); !screen.IsNil() {
+		frame, scale := screen.Frame(), screen.BackingScaleFactor()
+		screenshot.DisplayWidth = int(frame.Size.Width * scale)
+		screenshot.DisplayHeight = int(frame.Size.Height * scale)
+	}
+	screenshot.Width, screenshot.Height, _ = ImageSize(data)
+	return screenshot
+}
+
+// attributedText returns the text of rich text data, such as flat RTFD, using
+// AppKit's document readers
+func attributedText(data []byte) string {
+	text := foundation.NewAttributedStringWithDataOptionsDocumentAttributesError(data, foundation.NewDictionary(), nil, nil)
+	if text.IsNil() {
+		return ""
+	}
+	return text.String()
+}
+
 // SetContent sets the system clipboard content by sending the operation to the main thread
 func (m *DarwinMonitor) SetContent(clip types.Clip) error {
 	done := make(chan error, 1)
@@ -182,164 +342,165 @@ func (m *DarwinMonitor) SetContent(clip types.Clip) error {
 	return <-done
 }
 
-func (m *DarwinMonitor) checkForChanges() {
+// checkForChanges captures new pasteboard content and reports whether the pasteboard changed
+func (m *DarwinMonitor) checkForChanges() bool {
 	m.mutex.Lock()
 	currentCount := m.pasteboard.ChangeCount()
 	previousCount := m.changeCount
 	m.mutex.Unlock()
 
-	if currentCount != previousCount {
-		debugLog("Debug: Clipboard change detected (count: %d -> %d)\n", previousCount, currentCount)
+	if currentCount == previousCount {
+		return false
+	}
 
-		// Get clipboard content
-		var clip types.Clip
-		clip.CreatedAt = time.Now()
+	debugLog("Debug: Clipboard change detected (count: %d -> %d)\n", previousCount, currentCount)
 
-		m.mutex.Lock()
-		m.changeCount = currentCount
-		m.mutex.Unlock()
-
-		// Try different content types in order
-		handled := false
+	m.mutex.Lock()
+	m.changeCount = currentCount
+	selfWrite := m.guard.isSelfWrite(darwinPasteboard{m.pasteboard})
+	m.mutex.Unlock()
 
-		// Check for text content
-		if text := m.pasteboard.StringForType(appkit.PasteboardType("public.utf8-plain-text")); text != "" {
-			clip.Content = []byte(text)
-			clip.Type = "text/plain"
-			handled = true
-		}
+	// Skip content we put on the pasteboard ourselves
+	if selfWrite {
+		debugLog("Debug: Ignoring own pasteboard write (count: %d)\n", currentCount)
+		return true
+	}
 
-		// Check for screenshot or image content
-		if !handled {
-			// Try PNG
-			if data := m.pasteboard.DataForType(appkit.PasteboardType("public.png")); len(data) > 0 {
-				clip.Content = data
-				clip.Type = "image/png"
-
-				// Check if it's a screenshot by looking for screenshot-specific metadata
-				hasWindowID := false
-				for _, t := range m.pasteboard.Types() {
-					if t == appkit.PasteboardType("com.apple.screencapture.window-id") {
-						hasWindowID = true
-						break
-					}
-				}
-				if hasWindowID {
-					clip.Type = "screenshot"
-					if windowTitle := m.pasteboard.StringForType(appkit.PasteboardType("com.apple.screencapture.window-name")); windowTitle != "" {
-						clip.Metadata.SourceApp = windowTitle
-					}
-				}
+	clip, handled := detectContent(darwinPasteboard{m.pasteboard})
+	clip.CreatedAt = time.Now()
 
-				handled = true
+	switch clip.Type {
+	case types.TypeRTFD:
+		if text := attributedText(clip.Content); text != "" {
+			clip.Metadata.Formats = map[string][]byte{types.TypeText: []byte(text)}
+		}
+	case types.TypePNG, types.TypeTIFF:
+		// Check if it's a screenshot by looking for screenshot-specific metadata
+		if screenshot := m.screenshot(clip.Content); screenshot != nil {
+			clip.Type = types.TypeScreenshot
+			clip.Metadata.Screenshot = screenshot
+			if screenshot.WindowTitle != "" {
+				clip.Metadata.SourceApp = screenshot.WindowTitle
 			}
 		}
+	}
 
-		// Check for TIFF image
-		if !handled {
-			if data := m.pasteboard.DataForType(appkit.PasteboardType("public.tiff")); len(data) > 0 {
-				clip.Content = data
-				clip.Type = "image/tiff"
-
-				// Similar screenshot check for TIFF
-				hasWindowID := false
-				for _, t := range m.pasteboard.Types() {
-					if t == appkit.PasteboardType("com.apple.screencapture.window-id") {
-						hasWindowID = true
-						break
-					}
-				}
-				if hasWindowID {
-					clip.Type = "screenshot"
-					if windowTitle := m.pasteboard.StringForType(appkit.PasteboardType("com.apple.screencapture.window-name")); windowTitle != "" {
-						clip.Metadata.SourceApp = windowTitle
-					}
-				}
+	if handled {
+		m.mutex.Lock()
+		types := m.pasteboard.Types()
+		m.mutex.Unlock()
 
-				handled = true
+		// Print all pasteboard types in debug mode
+		if debugMode.Load() {
+			debugLog("Available pasteboard types:\n")
+			for _, t := range types {
+				m.mutex.Lock()
+				val := m.pasteboard.StringForType(t)
+				m.mutex.Unlock()
+
+				if val != "" {
+					debugLog("  %s = %s\n", t, val)
+				} else {
+					debugLog("  %s (no string value)\n", t)
+				}
 			}
 		}
 
-		// Check for file URLs
-		if !handled {
-			if urls := m.pasteboard.StringForType(appkit.PasteboardType("public.file-url")); urls != "" {
-				clip.Content = []byte(urls)
-				clip.Type = "file"
-				handled = true
+		// Try to determine source application using multiple methods
+		m.mutex.Lock()
+		sourceURL := m.pasteboard.StringForType(appkit.PasteboardType("org.chromium.source-url"))
+		m.mutex.Unlock()
+
+		remote := false
+		for _, t := range types {
+			if t == appkit.PasteboardType(RemoteClipboardType) {
+				remote = true
+				break
 			}
 		}
 
-		if handled {
+		if remote {
+			// The frontmost app had nothing to do with a clip that came over
+			// Universal Clipboard
+			clip.Metadata.SourceApp = RemoteDevice
+			clip.Metadata.Device = RemoteDevice
+			clip.Metadata.SourceURL = sourceURL
+			debugLog("Debug: Source is Universal Clipboard\n")
+		} else if sourceURL != "" {
+			clip.Metadata.SourceURL = sourceURL
 			m.mutex.Lock()
-			types := m.pasteboard.Types()
+			clip.Metadata.SourceTitle = m.pasteboard.StringForType(appkit.PasteboardType("public.url-name"))
 			m.mutex.Unlock()
 
-			// Print all pasteboard types in debug mode
-			if debugMode {
-				debugLog("Available pasteboard types:\n")
-				for _, t := range types {
-					m.mutex.Lock()
-					val := m.pasteboard.StringForType(t)
-					m.mutex.Unlock()
-
-					if val != "" {
-						debugLog("  %s = %s\n", t, val)
-					} else {
-						debugLog("  %s (no string value)\n", t)
-					}
-				}
+			// Content is from a web browser, which is normally the frontmost app
+			if app := appkit.Workspace_SharedWorkspace().FrontmostApplication(); app.BundleIdentifier() != "" {
+				m.setSourceApp(&clip, app)
+			} else {
+				clip.Metadata.SourceApp = "Chrome"
 			}
-
-			// Try to determine source application using multiple methods
+			debugLog("Debug: Source from browser URL: %s\n", sourceURL)
+		} else {
+			// Try other methods
 			m.mutex.Lock()
-			sourceURL := m.pasteboard.StringForType(appkit.PasteboardType("org.chromium.source-url"))
+			sourceApp := m.pasteboard.StringForType(appkit.PasteboardType("com.apple.pasteboard.app"))
 			m.mutex.Unlock()
 
-			if sourceURL != "" {
-				// Content is from a web browser
-				if sourceURL != "" {
-					clip.Metadata.SourceApp = "Chrome"
-					debugLog("Debug: Source from Chrome URL: %s\n", sourceURL)
-				}
+			if sourceApp != "" {
+				clip.Metadata.SourceApp = sourceApp
+				debugLog("Debug: Source from pasteboard metadata: %s\n", sourceApp)
 			} else {
-				// Try other methods
 				m.mutex.Lock()
-				sourceApp := m.pasteboard.StringForType(appkit.PasteboardType("com.apple.pasteboard.app"))
+				bundleID := m.pasteboard.StringForType(appkit.PasteboardType("com.apple.pasteboard.bundleid"))
 				m.mutex.Unlock()
 
-				if sourceApp != "" {
-					clip.Metadata.SourceApp = sourceApp
-					debugLog("Debug: Source from pasteboard metadata: %s\n", sourceApp)
-				} else {
-					m.mutex.Lock()
-					bundleID := m.pasteboard.StringForType(appkit.PasteboardType("com.apple.pasteboard.bundleid"))
-					m.mutex.Unlock()
-
-					if bundleID != "" {
-						if apps := appkit.RunningApplication_RunningApplicationsWithBundleIdentifier(bundleID); len(apps) > 0 {
-							clip.Metadata.SourceApp = apps[0].LocalizedName()
-							debugLog("Debug: Source from bundle ID: %s (%s)\n", apps[0].LocalizedName(), bundleID)
-						}
-					} else if app := appkit.Workspace_SharedWorkspace().FrontmostApplication(); app.LocalizedName() != "" {
-						// Only use frontmost app if it's not VS Code (which might just be our active editor)
-						if app.BundleIdentifier() != "com.microsoft.VSCode" {
-							clip.Metadata.SourceApp = app.LocalizedName()
-							debugLog("Debug: Source from frontmost app: %s (%s)\n",
-								app.LocalizedName(), app.BundleIdentifier())
-						} else {
-							debugLog("Debug: Ignoring VS Code as source\n")
-						}
+				if bundleID != "" {
+					clip.Metadata.SourceBundleID = bundleID
+					if apps := appkit.RunningApplication_RunningApplicationsWithBundleIdentifier(bundleID); len(apps) > 0 {
+						m.setSourceApp(&clip, apps[0])
+						debugLog("Debug: Source from bundle ID: %s (%s)\n", apps[0].LocalizedName(), bundleID)
+					}
+				} else if app := appkit.Workspace_SharedWorkspace().FrontmostApplication(); app.LocalizedName() != "" {
+					// Only use frontmost app if it's not VS Code (which might just be our active editor)
+					if app.BundleIdentifier() != "com.microsoft.VSCode" {
+						m.setSourceApp(&clip, app)
+						debugLog("Debug: Source from frontmost app: %s (%s)\n",
+							app.LocalizedName(), app.BundleIdentifier())
+					} else {
+						debugLog("Debug: Ignoring VS Code as source\n")
 					}
 				}
 			}
+		}
+
+		if clip.Metadata.SourceApp == "" {
+			debugLog("Debug: Could not determine source application\n")
+		}
 
-			if clip.Metadata.SourceApp == "" {
-				debugLog("Debug: Could not determine source application\n")
+		// The front window of the source app tells its browser tabs and
+		// documents apart. Only the frontmost app's windows are looked at.
+		if !remote && clip.Type != types.TypeScreenshot && clip.Metadata.SourceApp != "" {
+			if app := appkit.Workspace_SharedWorkspace().FrontmostApplication(); !app.IsNil() &&
+				(app.BundleIdentifier() == clip.Metadata.SourceBundleID || app.LocalizedName() == clip.Metadata.SourceApp) {
+				clip.Metadata.SourceWindow = windowTitle(int(app.ProcessIdentifier()))
+				debugLog("Debug: Source window: %q\n", clip.Metadata.SourceWindow)
 			}
+		}
 
-			if m.handler != nil {
-				m.handler(clip)
+		// Password managers mark secrets as concealed; don't keep them around
+		if m.config.ConcealedExpiry > 0 {
+			for _, t := range types {
+				if t == appkit.PasteboardType(concealedType) {
+					expiresAt := clip.CreatedAt.Add(m.config.ConcealedExpiry)
+					clip.Metadata.ExpiresAt = &expiresAt
+					debugLog("Debug: Concealed content expires at %v\n", expiresAt)
+					break
+				}
 			}
 		}
+
+		if m.handler != nil {
+			m.handler(clip)
+		}
 	}
+	return true
 }
@@ -0,0 +1,18 @@
+//go:build !darwin
+
+package clipboard
+
+import "log"
+
+// NewMonitor returns an in-memory monitor on platforms without a native
+// clipboard implementation
+func NewMonitor() Monitor {
+	return NewMonitorWithConfig(Config{})
+}
+
+// NewMonitorWithConfig returns an in-memory monitor on platforms without a
+// native clipboard implementation; polling configuration does not apply
+func NewMonitorWithConfig(config Config) Monitor {
+	log.Printf("[WARN] No native clipboard monitor for this platform, running headless")
+	return NewMemoryMonitor()
+}
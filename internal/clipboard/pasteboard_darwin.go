@@ -0,0 +1,118 @@
+package clipboard
+
+import (
+	"clipboard-manager/pkg/types"
+	"fmt"
+
+	"github.com/progrium/darwinkit/macos/appkit"
+)
+
+// pasteboardWriter is what a clip's representations are written to: the
+// pasteboard itself, or a pasteboard item for a drag
+type pasteboardWriter interface {
+	SetStringForType(value string, dataType appkit.PasteboardType) bool
+	SetDataForType(data []byte, dataType appkit.PasteboardType) bool
+}
+
+// PasteboardItems returns pasteboard items holding every representation of
+// a clip, for writing to a pasteboard or beginning a drag. File lists get an
+// item per file so Finder takes all of them, other clips a single item.
+func PasteboardItems(clip types.Clip) ([]appkit.PasteboardItem, error) {
+	if clip.Type == types.TypeFileList {
+		urls, err := DecodeFileList(clip.Content)
+		if err != nil {
+			return nil, err
+		}
+		items := make([]appkit.PasteboardItem, 0, len(urls))
+		for _, u := range urls {
+			item := appkit.NewPasteboardItem()
+			item.SetStringForType(u, appkit.PasteboardType("public.file-url"))
+			items = append(items, item)
+		}
+		return items, nil
+	}
+
+	item := appkit.NewPasteboardItem()
+	if err := writeClip(item, clip); err != nil {
+		return nil, err
+	}
+	return []appkit.PasteboardItem{item}, nil
+}
+
+// writeClip writes the representations of a clip other than a file list
+func writeClip(w pasteboardWriter, clip types.Clip) error {
+	switch clip.Type {
+	case types.TypeText:
+		w.SetStringForType(string(clip.Content), appkit.PasteboardType("public.utf8-plain-text"))
+		setRichText(w, clip)
+	case types.TypeRTF:
+		w.SetDataForType(clip.Content, appkit.PasteboardType("public.rtf"))
+		if plainText := clip.Metadata.Formats[types.TypeText]; len(plainText) > 0 {
+			w.SetStringForType(string(plainText), appkit.PasteboardType("public.utf8-plain-text"))
+		}
+	case types.TypePDF:
+		w.SetDataForType(clip.Content, appkit.PasteboardType("com.adobe.pdf"))
+		setAlternates(w, clip)
+	case types.TypeRTFD:
+		w.SetDataForType(clip.Content, appkit.PasteboardType("com.apple.flat-rtfd"))
+		setRichText(w, clip)
+		setAlternates(w, clip)
+	case types.TypePNG:
+		w.SetDataForType(clip.Content, appkit.PasteboardType("public.png"))
+	case types.TypeTIFF:
+		w.SetDataForType(clip.Content, appkit.PasteboardType("public.tiff"))
+	case types.TypeScreenshot:
+		// For screenshots, try PNG first, then TIFF
+		w.SetDataForType(clip.Content, appkit.PasteboardType("public.png"))
+	case types.TypeFile:
+		w.SetStringForType(string(clip.Content), appkit.PasteboardType("public.file-url"))
+	case types.TypeHTML:
+		// For HTML content, set both HTML and plain text
+		w.SetStringForType(string(clip.Content), appkit.PasteboardType("public.html"))
+		plainText := string(clip.Metadata.Formats[types.TypeText])
+		if plainText == "" {
+			plainText = HTMLToText(string(clip.Content))
+		}
+		if plainText != "" {
+			w.SetStringForType(plainText, appkit.PasteboardType("public.utf8-plain-text"))
+		}
+		setRichText(w, clip)
+	default:
+		// Try as plain text for unknown types
+		plainText := string(clip.Content)
+		if plainText == "" {
+			return fmt.Errorf("unsupported content type: %s", clip.Type)
+		}
+		w.SetStringForType(plainText, appkit.PasteboardType("public.utf8-plain-text"))
+		debugLog("Debug: Set unknown type as plain text, length: %d\n", len(plainText))
+	}
+	return nil
+}
+
+// setRichText restores the RTF and RTFD representations of a text clip, if
+// they were captured, so pasting into rich text editors keeps the original
+// formatting and images
+func setRichText(w pasteboardWriter, clip types.Clip) {
+	if rtf := clip.Metadata.Formats[types.TypeRTF]; len(rtf) > 0 {
+		w.SetDataForType(rtf, appkit.PasteboardType("public.rtf"))
+		debugLog("Debug: Restored RTF representation, length: %d\n", len(rtf))
+	}
+	if rtfd := clip.Metadata.Formats[types.TypeRTFD]; len(rtfd) > 0 && clip.Type != types.TypeRTFD {
+		w.SetDataForType(rtfd, appkit.PasteboardType("com.apple.flat-rtfd"))
+		debugLog("Debug: Restored RTFD representation, length: %d\n", len(rtfd))
+	}
+}
+
+// setAlternates restores the image and plain text kept alongside a document,
+// for apps that can't paste the document itself
+func setAlternates(w pasteboardWriter, clip types.Clip) {
+	if tiff := clip.Metadata.Formats[types.TypeTIFF]; len(tiff) > 0 {
+		w.SetDataForType(tiff, appkit.PasteboardType("public.tiff"))
+	}
+	if png := clip.Metadata.Formats[types.TypePNG]; len(png) > 0 {
+		w.SetDataForType(png, appkit.PasteboardType("public.png"))
+	}
+	if text := clip.Metadata.Formats[types.TypeText]; len(text) > 0 {
+		w.SetStringForType(string(text), appkit.PasteboardType("public.utf8-plain-text"))
+	}
+}
@@ -0,0 +1,73 @@
+package clipboard
+
+import "time"
+
+// Default polling intervals. AppKit does not post a notification when the
+// general pasteboard changes, so monitors poll its change count instead.
+const (
+	DefaultMinPollInterval = 250 * time.Millisecond
+	DefaultMaxPollInterval = 2 * time.Second
+)
+
+// DefaultConcealedExpiry is how long clips that password managers mark as
+// concealed are kept before they are deleted
+const DefaultConcealedExpiry = 90 * time.Second
+
+// Config holds clipboard monitor configuration
+type Config struct {
+	// MinPollInterval is used right after clipboard activity
+	MinPollInterval time.Duration
+	// MaxPollInterval is the slowest interval reached while idle
+	MaxPollInterval time.Duration
+	// ConcealedExpiry is how long concealed clips are kept. A negative
+	// value keeps them until they are deleted by hand.
+	ConcealedExpiry time.Duration
+}
+
+// withDefaults fills in unset or inconsistent intervals
+func (c Config) withDefaults() Config {
+	if c.MinPollInterval <= 0 {
+		c.MinPollInterval = DefaultMinPollInterval
+	}
+	if c.MaxPollInterval <= 0 {
+		c.MaxPollInterval = DefaultMaxPollInterval
+	}
+	if c.MaxPollInterval < c.MinPollInterval {
+		c.MaxPollInterval = c.MinPollInterval
+	}
+	if c.ConcealedExpiry == 0 {
+		c.ConcealedExpiry = DefaultConcealedExpiry
+	}
+	return c
+}
+
+// adaptivePoller computes polling intervals that speed up after clipboard
+// activity and back off exponentially while the clipboard is idle
+type adaptivePoller struct {
+	min      time.Duration
+	max      time.Duration
+	interval time.Duration
+}
+
+func newAdaptivePoller(config Config) *adaptivePoller {
+	config = config.withDefaults()
+	return &adaptivePoller{
+		min:      config.MinPollInterval,
+		max:      config.MaxPollInterval,
+		interval: config.MinPollInterval,
+	}
+}
+
+// next returns the delay before the next poll given whether the last poll saw a change
+func (p *adaptivePoller) next(changed bool) time.Duration {
+	if changed {
+		p.interval = p.min
+		return p.interval
+	}
+
+	p.interval += p.interval / 2
+	if p.interval > p.max {
+		p.interval = p.max
+	}
+	return p.interval
+}
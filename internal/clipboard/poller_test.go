@@ -0,0 +1,39 @@
+package clipboard
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAdaptivePoller(t *testing.T) {
+	p := newAdaptivePoller(Config{
+		MinPollInterval: 100 * time.Millisecond,
+		MaxPollInterval: 400 * time.Millisecond,
+	})
+
+	// Backs off while idle, capped at the maximum
+	want := []time.Duration{150, 225, 337, 400, 400}
+	for i, w := range want {
+		got := p.next(false)
+		if got.Milliseconds() != int64(w) {
+			t.Errorf("idle poll %d: got %v, want %dms", i, got, w)
+		}
+	}
+
+	// Resets to the fastest interval after activity
+	if got := p.next(true); got != 100*time.Millisecond {
+		t.Errorf("after change: got %v, want 100ms", got)
+	}
+}
+
+func TestConfigDefaults(t *testing.T) {
+	c := Config{MinPollInterval: 5 * time.Second}.withDefaults()
+	if c.MaxPollInterval != c.MinPollInterval {
+		t.Errorf("max interval should be raised to min, got %v", c.MaxPollInterval)
+	}
+
+	c = Config{}.withDefaults()
+	if c.MinPollInterval != DefaultMinPollInterval || c.MaxPollInterval != DefaultMaxPollInterval {
+		t.Errorf("unexpected defaults: %+v", c)
+	}
+}
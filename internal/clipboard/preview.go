@@ -0,0 +1,88 @@
+package clipboard
+
+import (
+	"clipboard-manager/pkg/types"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// Title returns a one line summary of a clip, at most maxLen runes long, for
+// showing it in a list
+func Title(clip *types.Clip, maxLen int) string {
+	text := string(clip.Content)
+	switch clip.Type {
+	case types.TypePNG, types.TypeTIFF:
+		return "Image"
+	case types.TypeScreenshot:
+		return "Screenshot"
+	case types.TypeRTF:
+		return "Rich text"
+	case types.TypePDF:
+		// Documents are titled by their text, when it could be extracted
+		text = string(clip.Metadata.Formats[types.TypeText])
+		if strings.TrimSpace(text) == "" {
+			return "PDF"
+		}
+	case types.TypeRTFD:
+		text = string(clip.Metadata.Formats[types.TypeText])
+		if strings.TrimSpace(text) == "" {
+			return "Rich text"
+		}
+	case types.TypeHTML:
+		text = HTMLToText(text)
+	case types.TypeFile:
+		name := filepath.Base(strings.TrimPrefix(text, "file://"))
+		switch media := clip.Metadata.Media; {
+		case media != nil && media.Kind == "video":
+			return "Video: " + name
+		case media != nil && media.Kind == "audio":
+			return "Audio: " + name
+		}
+		return "File: " + name
+	case types.TypeFileList:
+		if urls, err := DecodeFileList(clip.Content); err == nil {
+			return fmt.Sprintf("%d files", len(urls))
+		}
+	}
+
+	// Links show the title of their page once it has been fetched
+	if link := clip.Metadata.Link; link != nil && link.Title != "" {
+		text = link.Title
+	}
+
+	text = strings.Join(strings.Fields(text), " ")
+	if text == "" {
+		return fmt.Sprintf("(%s)", clip.Type)
+	}
+	if maxLen > 0 && utf8.RuneCountInString(text) > maxLen {
+		text = string([]rune(text)[:maxLen-1]) + "…"
+	}
+	return text
+}
+
+// PlainText returns the text of a clip for tools that only handle text: the
+// plain text copy kept alongside rich content, the text of HTML, or the paths
+// of copied files. It reports false for clips with no text, such as images.
+func PlainText(clip *types.Clip) (string, bool) {
+	if text, ok := clip.Metadata.Formats[types.TypeText]; ok {
+		return string(text), true
+	}
+
+	switch clip.Type {
+	case types.TypeText:
+		return string(clip.Content), true
+	case types.TypeHTML:
+		return HTMLToText(string(clip.Content)), true
+	case types.TypeFile:
+		return FileListText([]string{string(clip.Content)}), true
+	case types.TypeFileList:
+		urls, err := DecodeFileList(clip.Content)
+		if err != nil {
+			return "", false
+		}
+		return FileListText(urls), true
+	}
+	return "", false
+}
@@ -0,0 +1,55 @@
+package clipboard
+
+import (
+	"clipboard-manager/pkg/types"
+	"strings"
+	"testing"
+)
+
+func TestTitle(t *testing.T) {
+	tests := []struct {
+		clip types.Clip
+		want string
+	}{
+		{types.Clip{Type: "text/plain", Content: []byte("  hello\n\tworld  ")}, "hello world"},
+		{types.Clip{Type: "text/html", Content: []byte("<p>Hello <b>there</b></p>")}, "Hello there"},
+		{types.Clip{Type: "image/png", Content: []byte{0x89, 'P', 'N', 'G'}}, "Image"},
+		{types.Clip{Type: "file", Content: []byte("file:///Users/me/report.pdf")}, "File: report.pdf"},
+		{types.Clip{Type: "file-list", Content: []byte(`["file:///a","file:///b"]`)}, "2 files"},
+		{types.Clip{Type: "text/plain", Content: []byte("   ")}, "(text/plain)"},
+		{types.Clip{Type: types.TypePDF, Content: []byte("%PDF-1.7")}, "PDF"},
+		{types.Clip{Type: types.TypePDF, Content: []byte("%PDF-1.7"), Metadata: types.Metadata{
+			Formats: map[string][]byte{"text/plain": []byte("Quarterly report\n\nRevenue")},
+		}}, "Quarterly report Revenue"},
+	}
+	for _, tt := range tests {
+		if got := Title(&tt.clip, 50); got != tt.want {
+			t.Errorf("Title(%s %q) = %q, want %q", tt.clip.Type, tt.clip.Content, got, tt.want)
+		}
+	}
+
+	long := types.Clip{Type: "text/plain", Content: []byte(strings.Repeat("é", 80))}
+	if got := []rune(Title(&long, 50)); len(got) != 50 || got[len(got)-1] != '…' {
+		t.Errorf("long title not truncated: %q", string(got))
+	}
+}
+
+func TestPlainText(t *testing.T) {
+	html := types.Clip{
+		Type:     "text/html",
+		Content:  []byte("<b>bold</b>"),
+		Metadata: types.Metadata{Formats: map[string][]byte{"text/plain": []byte("copied text")}},
+	}
+	if got, ok := PlainText(&html); !ok || got != "copied text" {
+		t.Errorf("PlainText(html) = %q, %v; want the plain text copy", got, ok)
+	}
+
+	files := types.Clip{Type: "file-list", Content: []byte(`["file:///tmp/a.txt","file:///tmp/b%20c.txt"]`)}
+	if got, ok := PlainText(&files); !ok || got != "/tmp/a.txt\n/tmp/b c.txt" {
+		t.Errorf("PlainText(file-list) = %q, %v", got, ok)
+	}
+
+	if _, ok := PlainText(&types.Clip{Type: "image/png", Content: []byte{0x89}}); ok {
+		t.Error("PlainText(image) reported text")
+	}
+}
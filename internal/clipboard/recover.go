@@ -0,0 +1,68 @@
+package clipboard
+
+import (
+	"fmt"
+	"runtime/debug"
+	"time"
+)
+
+// restartDelay is how long Supervise waits before restarting a loop that
+// panicked, so one that panics at once doesn't spin
+const restartDelay = time.Second
+
+// PanicError is a panic recovered on the capture path
+type PanicError struct {
+	Where string // The loop, stage or handler that panicked
+	Value any    // What was passed to panic
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic in %s: %v", e.Where, e.Value)
+}
+
+// Recovered turns the value recover returned into a *PanicError, or nil if
+// there was no panic. It has to be called from the deferred function that
+// called recover, for the stack to be the panic's.
+func Recovered(where string, value any) error {
+	if value == nil {
+		return nil
+	}
+	return &PanicError{Where: where, Value: value, Stack: debug.Stack()}
+}
+
+// ErrorReporter is implemented by monitors that recover from panics in
+// their polling loop and restart it
+type ErrorReporter interface {
+	// OnError sets the function told about each recovered panic
+	OnError(handler func(error))
+}
+
+// Supervise runs loop until it returns or stop is closed. Each time loop
+// panics, onError is told and loop is started again after restartDelay.
+func Supervise(where string, stop <-chan struct{}, loop func(), onError func(error)) {
+	for {
+		err := runRecovered(where, loop)
+		if err == nil {
+			return
+		}
+		if onError != nil {
+			onError(err)
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-time.After(restartDelay):
+		}
+	}
+}
+
+// runRecovered runs fn, returning the panic it recovered from if any
+func runRecovered(where string, fn func()) (err error) {
+	defer func() {
+		err = Recovered(where, recover())
+	}()
+	fn()
+	return nil
+}
@@ -0,0 +1,41 @@
+package clipboard
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSupervise(t *testing.T) {
+	stop := make(chan struct{})
+	runs := 0
+	var reported []error
+	loop := func() {
+		runs++
+		if runs == 1 {
+			panic("first run fails")
+		}
+	}
+
+	Supervise("test loop", stop, loop, func(err error) { reported = append(reported, err) })
+
+	if runs != 2 {
+		t.Errorf("loop ran %d times, want 2", runs)
+	}
+	var panicErr *PanicError
+	if len(reported) != 1 || !errors.As(reported[0], &panicErr) {
+		t.Fatalf("reported %v, want one *PanicError", reported)
+	}
+	if panicErr.Where != "test loop" || panicErr.Value != "first run fails" || len(panicErr.Stack) == 0 {
+		t.Errorf("unexpected panic error %+v", panicErr)
+	}
+}
+
+func TestSupervise_Stopped(t *testing.T) {
+	stop := make(chan struct{})
+	close(stop)
+	runs := 0
+	Supervise("test loop", stop, func() { runs++; panic("fails") }, nil)
+	if runs != 1 {
+		t.Errorf("loop ran %d times after stop, want 1", runs)
+	}
+}
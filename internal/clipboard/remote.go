@@ -0,0 +1,10 @@
+package clipboard
+
+// RemoteClipboardType is the pasteboard type macOS adds to content that
+// arrived over Universal Clipboard (Handoff) from another device
+const RemoteClipboardType = "com.apple.is-remote-clipboard"
+
+// RemoteDevice is the source app and device of clips that arrived over
+// Universal Clipboard. The pasteboard doesn't say which device sent them,
+// and they usually come from a phone.
+const RemoteDevice = "iOS Device"
@@ -0,0 +1,65 @@
+package clipboard
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+)
+
+// selfWriteType is a private pasteboard type holding a nonce for every write
+// we make, so the monitor can tell our own writes apart from user copies
+const selfWriteType = "com.rockstar.clipboard-manager.self-write"
+
+// maxRecentWrites bounds how many write nonces are remembered. Keeping more
+// than one covers rapid successive writes that land between two polls.
+const maxRecentWrites = 8
+
+// markerPasteboard is the pasteboard access needed for self-write detection
+type markerPasteboard interface {
+	SetString(value string, pasteboardType string) bool
+	String(pasteboardType string) string
+}
+
+// writeGuard marks pasteboard writes made by the monitor and recognizes them later
+type writeGuard struct {
+	mu     sync.Mutex
+	nonces []string
+}
+
+// mark writes a fresh nonce to the pasteboard. It must be called right after
+// the pasteboard is cleared and before any content is written, so a poll that
+// races with the write either sees no content or sees the marker.
+func (g *writeGuard) mark(pb markerPasteboard) error {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return err
+	}
+	nonce := hex.EncodeToString(buf)
+
+	g.mu.Lock()
+	g.nonces = append(g.nonces, nonce)
+	if len(g.nonces) > maxRecentWrites {
+		g.nonces = g.nonces[len(g.nonces)-maxRecentWrites:]
+	}
+	g.mu.Unlock()
+
+	pb.SetString(nonce, selfWriteType)
+	return nil
+}
+
+// isSelfWrite reports whether the current pasteboard contents were written by us
+func (g *writeGuard) isSelfWrite(pb markerPasteboard) bool {
+	marker := pb.String(selfWriteType)
+	if marker == "" {
+		return false
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, nonce := range g.nonces {
+		if nonce == marker {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+package clipboard
+
+import "testing"
+
+// fakePasteboard is an in-memory pasteboard for testing
+type fakePasteboard struct {
+	changeCount int
+	values      map[string]string
+}
+
+func newFakePasteboard() *fakePasteboard {
+	return &fakePasteboard{values: make(map[string]string)}
+}
+
+func (p *fakePasteboard) clear() {
+	p.changeCount++
+	p.values = make(map[string]string)
+}
+
+func (p *fakePasteboard) SetString(value string, pasteboardType string) bool {
+	p.values[pasteboardType] = value
+	return true
+}
+
+func (p *fakePasteboard) String(pasteboardType string) string {
+	return p.values[pasteboardType]
+}
+
+func TestWriteGuard_SuppressesOwnWrites(t *testing.T) {
+	var guard writeGuard
+	pb := newFakePasteboard()
+
+	// Our own write: clear, mark, then content
+	pb.clear()
+	if err := guard.mark(pb); err != nil {
+		t.Fatalf("failed to mark write: %v", err)
+	}
+	pb.SetString("restored clip", "public.utf8-plain-text")
+
+	if !guard.isSelfWrite(pb) {
+		t.Error("own write should be recognized")
+	}
+
+	// A user copy replaces the pasteboard contents, including the marker
+	pb.clear()
+	pb.SetString("user copy", "public.utf8-plain-text")
+
+	if guard.isSelfWrite(pb) {
+		t.Error("user copy should not be treated as own write")
+	}
+}
+
+func TestWriteGuard_RapidWrites(t *testing.T) {
+	var guard writeGuard
+	pb := newFakePasteboard()
+
+	// Several writes before the monitor polls; only the last marker survives
+	for i := 0; i < 3; i++ {
+		pb.clear()
+		if err := guard.mark(pb); err != nil {
+			t.Fatalf("failed to mark write: %v", err)
+		}
+	}
+	if !guard.isSelfWrite(pb) {
+		t.Error("latest write should be recognized")
+	}
+
+	// A marker we never wrote is ignored
+	pb.clear()
+	pb.SetString("forged", selfWriteType)
+	if guard.isSelfWrite(pb) {
+		t.Error("unknown marker should not be recognized")
+	}
+}
+
+func TestWriteGuard_ForgetsOldWrites(t *testing.T) {
+	var guard writeGuard
+	pb := newFakePasteboard()
+
+	pb.clear()
+	if err := guard.mark(pb); err != nil {
+		t.Fatalf("failed to mark write: %v", err)
+	}
+	first := pb.String(selfWriteType)
+
+	for i := 0; i < maxRecentWrites; i++ {
+		if err := guard.mark(newFakePasteboard()); err != nil {
+			t.Fatalf("failed to mark write: %v", err)
+		}
+	}
+
+	pb.SetString(first, selfWriteType)
+	if guard.isSelfWrite(pb) {
+		t.Error("nonce older than the recent write window should be forgotten")
+	}
+}
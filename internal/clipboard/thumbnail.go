@@ -0,0 +1,74 @@
+package clipboard
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+)
+
+// DefaultThumbnailSize is the longest side of a thumbnail, in pixels
+const DefaultThumbnailSize = 256
+
+// ImageSize returns the pixel size of a PNG image without decoding it
+func ImageSize(data []byte) (width, height int, ok bool) {
+	config, err := png.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return 0, 0, false
+	}
+	return config.Width, config.Height, true
+}
+
+// Thumbnail scales a PNG image down so its longest side is at most maxSize
+// pixels and returns it as PNG. Images already small enough are returned
+// as they are.
+func Thumbnail(data []byte, maxSize int) ([]byte, error) {
+	src, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if maxSize <= 0 || (width <= maxSize && height <= maxSize) {
+		return data, nil
+	}
+
+	// Keep the aspect ratio, fitting the longest side
+	dstWidth, dstHeight := maxSize, height*maxSize/width
+	if height > width {
+		dstWidth, dstHeight = width*maxSize/height, maxSize
+	}
+	dstWidth, dstHeight = max(dstWidth, 1), max(dstHeight, 1)
+
+	// Average the source pixels that fall in each destination pixel
+	dst := image.NewRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+	for y := 0; y < dstHeight; y++ {
+		y0 := bounds.Min.Y + y*height/dstHeight
+		y1 := max(bounds.Min.Y+(y+1)*height/dstHeight, y0+1)
+		for x := 0; x < dstWidth; x++ {
+			x0 := bounds.Min.X + x*width/dstWidth
+			x1 := max(bounds.Min.X+(x+1)*width/dstWidth, x0+1)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(cr), g+uint64(cg), b+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			i := dst.PixOffset(x, y)
+			dst.Pix[i+0] = uint8(r / n >> 8)
+			dst.Pix[i+1] = uint8(g / n >> 8)
+			dst.Pix[i+2] = uint8(b / n >> 8)
+			dst.Pix[i+3] = uint8(a / n >> 8)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, dst); err != nil {
+		return nil, fmt.Errorf("failed to encode thumbnail: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,56 @@
+package clipboard
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+func encodePNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: 200, G: 100, B: 50, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestThumbnail(t *testing.T) {
+	data := encodePNG(t, 1000, 400)
+	if width, height, ok := ImageSize(data); !ok || width != 1000 || height != 400 {
+		t.Fatalf("ImageSize = %d, %d, %v", width, height, ok)
+	}
+
+	thumb, err := Thumbnail(data, 100)
+	if err != nil {
+		t.Fatalf("failed to make thumbnail: %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(thumb))
+	if err != nil {
+		t.Fatalf("thumbnail is not a PNG: %v", err)
+	}
+	if size := img.Bounds().Size(); size.X != 100 || size.Y != 40 {
+		t.Errorf("expected a 100x40 thumbnail, got %v", size)
+	}
+	if r, g, b, _ := img.At(50, 20).RGBA(); r>>8 != 200 || g>>8 != 100 || b>>8 != 50 {
+		t.Errorf("thumbnail colors changed: %d %d %d", r>>8, g>>8, b>>8)
+	}
+
+	// Small images are left alone
+	small := encodePNG(t, 20, 30)
+	if thumb, err := Thumbnail(small, 100); err != nil || !bytes.Equal(thumb, small) {
+		t.Errorf("expected a small image back as it is, got %d bytes, %v", len(thumb), err)
+	}
+
+	if _, err := Thumbnail([]byte("not an image"), 100); err == nil {
+		t.Error("expected an error for content that isn't a PNG")
+	}
+}
@@ -0,0 +1,49 @@
+package clipboard
+
+/*
+#cgo LDFLAGS: -framework CoreGraphics -framework CoreFoundation
+#include <CoreGraphics/CoreGraphics.h>
+
+// frontWindowTitle copies the title of the frontmost window of process pid
+// into buf as UTF-8. It returns 0 if the process has no window with a title
+// or the title doesn't fit. Window lists are ordered front to back, and only
+// hold titles of other apps' windows with the Screen Recording permission.
+static int frontWindowTitle(int pid, char *buf, int size) {
+	CFArrayRef windows = CGWindowListCopyWindowInfo(
+		kCGWindowListOptionOnScreenOnly | kCGWindowListExcludeDesktopElements, kCGNullWindowID);
+	if (windows == NULL) return 0;
+
+	int found = 0;
+	for (CFIndex i = 0; i < CFArrayGetCount(windows); i++) {
+		CFDictionaryRef window = CFArrayGetValueAtIndex(windows, i);
+		int owner = 0, layer = -1;
+		CFNumberRef n = CFDictionaryGetValue(window, kCGWindowOwnerPID);
+		if (n == NULL || !CFNumberGetValue(n, kCFNumberIntType, &owner) || owner != pid) continue;
+		// Menus, panels and the like sit above the normal window layer
+		n = CFDictionaryGetValue(window, kCGWindowLayer);
+		if (n == NULL || !CFNumberGetValue(n, kCFNumberIntType, &layer) || layer != 0) continue;
+
+		CFStringRef name = CFDictionaryGetValue(window, kCGWindowName);
+		if (name != NULL && CFStringGetLength(name) > 0) {
+			found = CFStringGetCString(name, buf, size, kCFStringEncodingUTF8);
+		}
+		break;
+	}
+	CFRelease(windows);
+	return found;
+}
+*/
+import "C"
+
+// windowTitleSize is the most bytes of a window title read
+const windowTitleSize = 1024
+
+// windowTitle returns the title of the frontmost window of the process with
+// pid, or "" if it has none or it can't be read
+func windowTitle(pid int) string {
+	var buf [windowTitleSize]C.char
+	if C.frontWindowTitle(C.int(pid), &buf[0], C.int(len(buf))) == 0 {
+		return ""
+	}
+	return C.GoString(&buf[0])
+}
@@ -0,0 +1,535 @@
+// Package config loads the daemon settings that can change while it runs
+// and hands each new version to the components that use them
+package config
+
+import (
+	"clipboard-manager/internal/plugin"
+	"clipboard-manager/internal/rules"
+	"clipboard-manager/internal/script"
+	"clipboard-manager/internal/translate"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileName is the name of the settings file in the data directory
+const FileName = "config.json"
+
+// Log levels
+const (
+	LogInfo  = "info"
+	LogDebug = "debug"
+)
+
+// Config holds the settings read from the settings file. Settings missing
+// from the file keep the values from the environment and flags.
+type Config struct {
+	Poll      Poll     `json:"poll"`
+	Obsidian  Obsidian `json:"obsidian"`
+	Ignore    Ignore   `json:"ignore"`
+	TrashDays *int     `json:"trash_days,omitempty"` // Nil keeps -trash-days
+	LogLevel  string   `json:"log_level"`            // info or debug
+
+	// UnfurlLinks fetches the title, description and icon of copied links.
+	// It is off by default since it contacts the sites linked to.
+	UnfurlLinks bool `json:"unfurl_links"`
+
+	Notifications Notifications `json:"notifications"`
+
+	// ShareURL is the address others reach the daemon at, such as a tunnel
+	// or reverse proxy, used to build share links. Empty uses localhost.
+	ShareURL string `json:"share_url,omitempty"`
+
+	Publish   Publish   `json:"publish"`
+	Send      Send      `json:"send"`
+	Translate Translate `json:"translate"`
+
+	// Logseq and Markdown sync clips to other Markdown tools, alongside
+	// Obsidian and on its sync_interval
+	Logseq   Logseq   `json:"logseq"`
+	Markdown Markdown `json:"markdown"`
+
+	// AppleNotes exports clips to Apple Notes on macOS. Chosen clips can be
+	// exported whether or not it is enabled; enabling it syncs every clip.
+	AppleNotes AppleNotes `json:"apple_notes"`
+
+	// CORS lets web pages on other origins, such as a local dashboard, call
+	// /api and open /ws
+	CORS CORS `json:"cors"`
+
+	Maintenance Maintenance `json:"maintenance"`
+
+	// QuotaMB caps the space clip content takes up, in the database and in
+	// files. Once over it the least recently used clips that aren't pinned
+	// are removed. Zero means no quota.
+	QuotaMB int `json:"quota_mb"`
+
+	// CopyFiles keeps a copy of each copied file, so it can still be pasted
+	// once the original changes or is removed. Without it file clips only
+	// point to the file, and pasting one that changed fails.
+	CopyFiles bool `json:"copy_files"`
+
+	// SizeLimitsMB caps the size of clips by type, below the overall 100 MB
+	// limit. Keys are clip types such as image/png or screenshot, major
+	// types such as image or text, or audio and video for media files. Zero
+	// never keeps clips of the type.
+	SizeLimitsMB map[string]int `json:"size_limits_mb,omitempty"`
+
+	// DedupNormalized treats copied text that differs from a clip only in
+	// surrounding or repeated whitespace, or in Unicode form, as that clip,
+	// so copying a command with a trailing newline doesn't add a second one
+	DedupNormalized bool `json:"dedup_normalized"`
+
+	// Rules tag, categorize, skip or notify about clips as they are
+	// captured, in order. /api/rules also edits them one at a time.
+	Rules []rules.Rule `json:"rules,omitempty"`
+
+	// Plugins are programs run alongside the daemon that process captured
+	// clips and add actions, see package plugin
+	Plugins []plugin.Config `json:"plugins,omitempty"`
+
+	// Scripts are Lua scripts run on each captured clip, reloaded when they
+	// change, see package script
+	Scripts script.Config `json:"scripts"`
+
+	// Profiles keeps separate histories, such as work and personal, keyed
+	// by name. Only the active profile's settings apply.
+	Profiles map[string]Profile `json:"profiles,omitempty"`
+}
+
+// Profile holds the settings of one profile. Each profile has its own
+// database; its ignore rules add to the global ones and its Obsidian
+// settings, if set, replace them.
+type Profile struct {
+	DSN      string    `json:"dsn,omitempty"` // Needed for postgres, optional for file databases
+	Ignore   Ignore    `json:"ignore"`
+	Obsidian *Obsidian `json:"obsidian,omitempty"`
+}
+
+// Poll sets how often the clipboard is checked for changes, from Min right
+// after activity backing off to Max while idle
+type Poll struct {
+	Min Duration `json:"min"`
+	Max Duration `json:"max"`
+}
+
+// Maintenance schedules database upkeep for when the clipboard is idle.
+// Zero values use the defaults.
+type Maintenance struct {
+	Disabled   bool     `json:"disabled"`
+	Interval   Duration `json:"interval"`     // Between full runs, default 24h
+	IdleAfter  Duration `json:"idle_after"`   // Quiet time before a run, default 5m
+	WALLimitMB int      `json:"wal_limit_mb"` // Truncate the write-ahead log past this, default 64
+}
+
+// Duration is a time.Duration written as a string such as "250ms"
+type Duration time.Duration
+
+// MarshalJSON implements json.Marshaler
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("expected a duration such as \"250ms\"")
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// Obsidian configures syncing clips to an Obsidian vault, like the
+// OBSIDIAN_* environment variables
+type Obsidian struct {
+	Enabled      bool   `json:"enabled"`
+	VaultPath    string `json:"vault_path"`
+	SyncInterval int    `json:"sync_interval"` // Minutes
+}
+
+// Logseq configures syncing clips to the journal of a Logseq graph
+type Logseq struct {
+	Enabled   bool   `json:"enabled"`
+	GraphPath string `json:"graph_path"`
+}
+
+// Markdown configures syncing each clip to a Markdown file of its own, with
+// its details as frontmatter
+type Markdown struct {
+	Enabled bool   `json:"enabled"`
+	Path    string `json:"path"` // Folder the files are written to
+}
+
+// AppleNotes configures exporting clips to Apple Notes
+type AppleNotes struct {
+	Enabled bool   `json:"enabled"`
+	Folder  string `json:"folder"` // "" is "Clipboard"; each category gets a subfolder
+}
+
+// Ignore lists clips that are never recorded
+type Ignore struct {
+	Apps     []string `json:"apps"`     // Source app names or bundle IDs
+	Patterns []string `json:"patterns"` // Regular expressions matched against text clips
+}
+
+// Notifications turns desktop notifications on for each kind of event. All
+// are off by default.
+type Notifications struct {
+	LargeFile  bool `json:"large_file"`  // A clip is stored as a file, or is too large to keep
+	Ignored    bool `json:"ignored"`     // A clip is dropped by the ignore rules
+	SyncFailed bool `json:"sync_failed"` // Syncing clips fails
+	Pasted     bool `json:"pasted"`      // A clip is copied back to the clipboard
+}
+
+// Publish configures where clips are uploaded to be shared
+type Publish struct {
+	GistToken  string `json:"gist_token,omitempty"` // GitHub token with the gist scope, default GITHUB_TOKEN
+	GistPublic bool   `json:"gist_public"`          // Gists are secret unless set
+	PasteURL   string `json:"paste_url,omitempty"`  // 0x0-compatible endpoint, default https://0x0.st
+}
+
+// Send configures the chat channels clips can be sent to
+type Send struct {
+	Slack   Slack   `json:"slack"`
+	Discord Discord `json:"discord"`
+}
+
+// Slack posts text through an incoming webhook, or as a bot, which is needed
+// to upload images
+type Slack struct {
+	WebhookURL string `json:"webhook_url,omitempty"`
+	Token      string `json:"token,omitempty"`   // Bot token with chat:write and files:write
+	Channel    string `json:"channel,omitempty"` // ID of the channel the bot posts to
+}
+
+// Discord posts to a channel webhook
+type Discord struct {
+	WebhookURL string `json:"webhook_url,omitempty"`
+}
+
+// Translate picks the service text clips are translated with
+type Translate struct {
+	Backend string `json:"backend,omitempty"` // libretranslate (the default) or deepl
+	URL     string `json:"url,omitempty"`     // Server, default a local LibreTranslate or DeepL's API for the key
+	APIKey  string `json:"api_key,omitempty"` // LibreTranslate API key, or DeepL authentication key
+	Target  string `json:"target,omitempty"`  // Language translated into when none is given, default en
+}
+
+// CORS lists the origins allowed to call the API from a browser. Without
+// origins only pages served by the daemon itself can.
+type CORS struct {
+	Origins     []string `json:"origins"`     // Such as "http://localhost:3000", or "*" for any
+	Credentials bool     `json:"credentials"` // Let pages send cookies and HTTP authentication
+}
+
+// Allows reports whether a page on origin may call the API
+func (c CORS) Allows(origin string) bool {
+	origin = strings.TrimRight(origin, "/")
+	for _, allowed := range c.Origins {
+		if allowed == "*" || strings.EqualFold(strings.TrimRight(allowed, "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// FromEnv returns the settings given by environment variables
+func FromEnv() Config {
+	config := Config{
+		Obsidian: Obsidian{
+			Enabled:      os.Getenv("OBSIDIAN_ENABLED") == "true",
+			VaultPath:    os.Getenv("OBSIDIAN_VAULT_PATH"),
+			SyncInterval: 5,
+		},
+		LogLevel: LogInfo,
+		Publish:  Publish{GistToken: os.Getenv("GITHUB_TOKEN")},
+	}
+	if minutes, err := strconv.Atoi(os.Getenv("OBSIDIAN_SYNC_INTERVAL")); err == nil && minutes >= 1 {
+		config.Obsidian.SyncInterval = minutes
+	}
+	if os.Getenv("DEBUG") == "1" {
+		config.LogLevel = LogDebug
+	}
+	return config
+}
+
+// Load reads the settings file at path over base. A missing file leaves base
+// as it is.
+func Load(path string, base Config) (Config, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return base, nil
+	}
+	if err != nil {
+		return base, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	config := base
+	if err := json.Unmarshal(data, &config); err != nil {
+		return base, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if err := config.Validate(); err != nil {
+		return base, fmt.Errorf("invalid %s: %w", path, err)
+	}
+	return config, nil
+}
+
+// Save validates config and writes it to path, replacing the file in one
+// step so a watcher never reads it half written
+func Save(path string, config Config) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode settings: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+	return nil
+}
+
+// Validate checks settings that would otherwise fail when applied
+func (c Config) Validate() error {
+	if c.Poll.Min < 0 || c.Poll.Max < 0 {
+		return fmt.Errorf("poll intervals must not be negative")
+	}
+	if c.Poll.Min > 0 && c.Poll.Max > 0 && c.Poll.Max < c.Poll.Min {
+		return fmt.Errorf("poll.max must not be less than poll.min")
+	}
+	if c.Maintenance.Interval < 0 || c.Maintenance.IdleAfter < 0 || c.Maintenance.WALLimitMB < 0 {
+		return fmt.Errorf("maintenance settings must not be negative")
+	}
+	if c.QuotaMB < 0 {
+		return fmt.Errorf("quota_mb must not be negative")
+	}
+	for clipType, limit := range c.SizeLimitsMB {
+		if clipType == "" || limit < 0 {
+			return fmt.Errorf("size_limits_mb needs a type and a limit that isn't negative, got %q: %d", clipType, limit)
+		}
+	}
+	if c.Obsidian.Enabled && c.Obsidian.VaultPath == "" {
+		return fmt.Errorf("obsidian.vault_path is required when sync is enabled")
+	}
+	if c.Obsidian.SyncInterval < 1 {
+		return fmt.Errorf("obsidian.sync_interval must be at least 1 minute")
+	}
+	if c.Logseq.Enabled && c.Logseq.GraphPath == "" {
+		return fmt.Errorf("logseq.graph_path is required when sync is enabled")
+	}
+	if c.Markdown.Enabled && c.Markdown.Path == "" {
+		return fmt.Errorf("markdown.path is required when sync is enabled")
+	}
+	if _, err := c.Ignore.Compile(); err != nil {
+		return err
+	}
+	if err := rules.Validate(c.Rules); err != nil {
+		return err
+	}
+	if err := plugin.Validate(c.Plugins); err != nil {
+		return err
+	}
+	if err := c.Scripts.Validate(); err != nil {
+		return err
+	}
+	if c.TrashDays != nil && *c.TrashDays < 0 {
+		return fmt.Errorf("trash_days must not be negative")
+	}
+	if c.ShareURL != "" && !isWebURL(c.ShareURL) {
+		return fmt.Errorf("share_url must be an http or https URL")
+	}
+	if c.Publish.PasteURL != "" && !isWebURL(c.Publish.PasteURL) {
+		return fmt.Errorf("publish.paste_url must be an http or https URL")
+	}
+	if c.Send.Slack.WebhookURL != "" && !isWebURL(c.Send.Slack.WebhookURL) {
+		return fmt.Errorf("send.slack.webhook_url must be an http or https URL")
+	}
+	if c.Send.Discord.WebhookURL != "" && !isWebURL(c.Send.Discord.WebhookURL) {
+		return fmt.Errorf("send.discord.webhook_url must be an http or https URL")
+	}
+	switch c.Translate.Backend {
+	case "", translate.LibreTranslate, translate.DeepL:
+	default:
+		return fmt.Errorf("unknown translate.backend %q, expected libretranslate or deepl", c.Translate.Backend)
+	}
+	if c.Translate.URL != "" && !isWebURL(c.Translate.URL) {
+		return fmt.Errorf("translate.url must be an http or https URL")
+	}
+	if c.Translate.Target != "" && !translate.ValidLanguage(c.Translate.Target) {
+		return fmt.Errorf("invalid translate.target %q, expected a language code such as de", c.Translate.Target)
+	}
+	for _, origin := range c.CORS.Origins {
+		if origin == "*" {
+			if c.CORS.Credentials {
+				return fmt.Errorf("cors.credentials can't be used with the origin \"*\", list the origins")
+			}
+			continue
+		}
+		if parsed, err := url.Parse(origin); err != nil || !isWebURL(origin) || strings.TrimRight(parsed.Path, "/") != "" || parsed.RawQuery != "" {
+			return fmt.Errorf("invalid cors origin %q, expected a scheme and host such as http://localhost:3000", origin)
+		}
+	}
+	for name, profile := range c.Profiles {
+		if !ValidProfileName(name) {
+			return fmt.Errorf("invalid profile name %q, use up to 32 lower case letters, digits, - and _", name)
+		}
+		if _, err := profile.Ignore.Compile(); err != nil {
+			return fmt.Errorf("profile %s: %w", name, err)
+		}
+		if o := profile.Obsidian; o != nil && ((o.Enabled && o.VaultPath == "") || o.SyncInterval < 1) {
+			return fmt.Errorf("profile %s: obsidian needs a vault_path and a sync_interval of at least 1 minute", name)
+		}
+	}
+	switch c.LogLevel {
+	case LogInfo, LogDebug:
+	default:
+		return fmt.Errorf("unknown log_level %q, expected info or debug", c.LogLevel)
+	}
+	return nil
+}
+
+func isWebURL(s string) bool {
+	parsed, err := url.Parse(s)
+	return err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != ""
+}
+
+// ValidProfileName reports whether name can name a profile. Names are used
+// as directory names.
+func ValidProfileName(name string) bool {
+	return profileName.MatchString(name)
+}
+
+var profileName = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+
+// ForProfile returns the settings that apply while the named profile is
+// active
+func (c Config) ForProfile(name string) Config {
+	profile, ok := c.Profiles[name]
+	if !ok {
+		return c
+	}
+	c = c.clone()
+	c.Ignore.Apps = append(c.Ignore.Apps, profile.Ignore.Apps...)
+	c.Ignore.Patterns = append(c.Ignore.Patterns, profile.Ignore.Patterns...)
+	if profile.Obsidian != nil {
+		c.Obsidian = *profile.Obsidian
+	}
+	return c
+}
+
+// Compile compiles the ignore patterns
+func (i Ignore) Compile() ([]*regexp.Regexp, error) {
+	patterns := make([]*regexp.Regexp, 0, len(i.Patterns))
+	for _, pattern := range i.Patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ignore pattern %q: %w", pattern, err)
+		}
+		patterns = append(patterns, re)
+	}
+	return patterns, nil
+}
+
+// clone copies c, including the slices and pointers it shares
+func (c Config) clone() Config {
+	c.Ignore.Apps = append([]string(nil), c.Ignore.Apps...)
+	c.Ignore.Patterns = append([]string(nil), c.Ignore.Patterns...)
+	c.CORS.Origins = append([]string(nil), c.CORS.Origins...)
+	if c.Rules != nil {
+		list := make([]rules.Rule, len(c.Rules))
+		for i, rule := range c.Rules {
+			rule.Then.Tags = append([]string(nil), rule.Then.Tags...)
+			list[i] = rule
+		}
+		c.Rules = list
+	}
+	if c.Plugins != nil {
+		list := make([]plugin.Config, len(c.Plugins))
+		for i, p := range c.Plugins {
+			p.Args = append([]string(nil), p.Args...)
+			p.Env = append([]string(nil), p.Env...)
+			list[i] = p
+		}
+		c.Plugins = list
+	}
+	if c.TrashDays != nil {
+		days := *c.TrashDays
+		c.TrashDays = &days
+	}
+	if c.Profiles != nil {
+		profiles := make(map[string]Profile, len(c.Profiles))
+		for name, profile := range c.Profiles {
+			profile.Ignore.Apps = append([]string(nil), profile.Ignore.Apps...)
+			profile.Ignore.Patterns = append([]string(nil), profile.Ignore.Patterns...)
+			if profile.Obsidian != nil {
+				obsidian := *profile.Obsidian
+				profile.Obsidian = &obsidian
+			}
+			profiles[name] = profile
+		}
+		c.Profiles = profiles
+	}
+	return c
+}
+
+// Bus holds the current settings and passes each new version to its
+// subscribers
+type Bus struct {
+	mu          sync.Mutex
+	current     Config
+	subscribers []func(Config)
+}
+
+// NewBus creates a bus holding initial
+func NewBus(initial Config) *Bus {
+	return &Bus{current: initial}
+}
+
+// Current returns a copy of the latest settings, safe to modify
+func (b *Bus) Current() Config {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.current.clone()
+}
+
+// Subscribe calls fn with the current settings and then with every new
+// version published
+func (b *Bus) Subscribe(fn func(Config)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscribers = append(b.subscribers, fn)
+	fn(b.current)
+}
+
+// Publish makes config the current settings and passes it to every
+// subscriber, in the order they subscribed
+func (b *Bus) Publish(config Config) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.current = config
+	for _, fn := range b.subscribers {
+		fn(config)
+	}
+}
@@ -0,0 +1,173 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, FileName)
+	days := 30
+	base := Config{
+		Obsidian:  Obsidian{VaultPath: "/vault", SyncInterval: 5},
+		TrashDays: &days,
+		LogLevel:  LogInfo,
+	}
+
+	// A missing file keeps the base settings
+	config, err := Load(path, base)
+	if err != nil || config.Obsidian.VaultPath != "/vault" {
+		t.Fatalf("Load of a missing file = %+v, %v", config, err)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"obsidian": {"enabled": true}, "ignore": {"apps": ["1Password"]}, "log_level": "debug"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config, err = Load(path, base)
+	if err != nil {
+		t.Fatalf("failed to load settings: %v", err)
+	}
+	if !config.Obsidian.Enabled || config.Obsidian.VaultPath != "/vault" || config.Obsidian.SyncInterval != 5 {
+		t.Errorf("expected file settings over base, got %+v", config.Obsidian)
+	}
+	if len(config.Ignore.Apps) != 1 || config.LogLevel != LogDebug || *config.TrashDays != 30 {
+		t.Errorf("unexpected settings %+v", config)
+	}
+
+	for _, invalid := range []string{
+		`{"ignore": {"patterns": ["("]}}`,
+		`{"log_level": "loud"}`,
+		`{"obsidian": {"sync_interval": 0}}`,
+		`{"logseq": {"enabled": true}}`,
+		`{"markdown": {"enabled": true}}`,
+		`{"trash_days": -1}`,
+		`{"profiles": {"Work!": {}}}`,
+		`{"profiles": {"work": {"ignore": {"patterns": ["("]}}}}`,
+		`{"cors": {"origins": ["localhost:3000"]}}`,
+		`{"cors": {"origins": ["http://localhost:3000/app"]}}`,
+		`{"cors": {"origins": ["*"], "credentials": true}}`,
+		`{"maintenance": {"interval": "-1h"}}`,
+		`{"quota_mb": -1}`,
+		`{"size_limits_mb": {"image": -1}}`,
+		`not json`,
+	} {
+		if err := os.WriteFile(path, []byte(invalid), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Load(path, base); err == nil {
+			t.Errorf("expected %s to be rejected", invalid)
+		}
+	}
+}
+
+func TestCORS_Allows(t *testing.T) {
+	cors := CORS{Origins: []string{"http://localhost:3000/", "https://dash.example.com"}}
+	for origin, want := range map[string]bool{
+		"http://localhost:3000":    true,
+		"HTTPS://dash.example.com": true,
+		"http://localhost:3001":    false,
+		"http://dash.example.com":  false,
+		"":                         false,
+	} {
+		if got := cors.Allows(origin); got != want {
+			t.Errorf("Allows(%q) = %v, want %v", origin, got, want)
+		}
+	}
+	if !(CORS{Origins: []string{"*"}}).Allows("https://anywhere.example") {
+		t.Error("expected * to allow any origin")
+	}
+}
+
+func TestForProfile(t *testing.T) {
+	config := Config{
+		Obsidian: Obsidian{Enabled: true, VaultPath: "/personal", SyncInterval: 5},
+		Ignore:   Ignore{Apps: []string{"1Password"}},
+		Profiles: map[string]Profile{
+			"work": {
+				Ignore:   Ignore{Apps: []string{"Messages"}},
+				Obsidian: &Obsidian{VaultPath: "/work", SyncInterval: 5},
+			},
+		},
+	}
+
+	work := config.ForProfile("work")
+	if len(work.Ignore.Apps) != 2 || work.Obsidian.VaultPath != "/work" || work.Obsidian.Enabled {
+		t.Errorf("expected the work profile's settings, got %+v", work)
+	}
+	if len(config.Ignore.Apps) != 1 {
+		t.Errorf("ForProfile changed the global settings: %+v", config.Ignore)
+	}
+	if other := config.ForProfile("default"); other.Obsidian.VaultPath != "/personal" {
+		t.Errorf("expected the global settings for other profiles, got %+v", other.Obsidian)
+	}
+}
+
+func TestSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", FileName)
+	config := Config{
+		Poll:     Poll{Min: Duration(100 * time.Millisecond), Max: Duration(3 * time.Second)},
+		Obsidian: Obsidian{SyncInterval: 10},
+		Ignore:   Ignore{Patterns: []string{`^\d{6}$`}},
+		LogLevel: LogInfo,
+	}
+	if err := Save(path, config); err != nil {
+		t.Fatalf("failed to save settings: %v", err)
+	}
+
+	loaded, err := Load(path, Config{})
+	if err != nil {
+		t.Fatalf("failed to load saved settings: %v", err)
+	}
+	if loaded.Poll != config.Poll || loaded.Obsidian.SyncInterval != 10 || len(loaded.Ignore.Patterns) != 1 {
+		t.Errorf("expected %+v, got %+v", config, loaded)
+	}
+
+	config.Poll.Max = Duration(50 * time.Millisecond)
+	if err := Save(path, config); err == nil {
+		t.Error("expected poll.max below poll.min to be rejected")
+	}
+	if loaded, _ := Load(path, Config{}); loaded.Poll.Max != Duration(3*time.Second) {
+		t.Errorf("invalid settings overwrote the file, got %+v", loaded.Poll)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, FileName)
+	base := Config{Obsidian: Obsidian{SyncInterval: 5}, LogLevel: LogInfo}
+	bus := NewBus(base)
+
+	updates := make(chan Config, 10)
+	bus.Subscribe(func(c Config) { updates <- c })
+	<-updates // The current settings
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go Watch(ctx, path, base, bus)
+	time.Sleep(50 * time.Millisecond) // Let the watcher start
+
+	// Write elsewhere and rename into place, like an editor
+	tmp := filepath.Join(dir, "config.json.tmp")
+	if err := os.WriteFile(tmp, []byte(`{"log_level": "debug"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case config := <-updates:
+		if config.LogLevel != LogDebug {
+			t.Errorf("expected reloaded log level debug, got %q", config.LogLevel)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("settings were not reloaded")
+	}
+	if bus.Current().LogLevel != LogDebug {
+		t.Errorf("bus still holds the old settings")
+	}
+}
@@ -0,0 +1,76 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// reloadDelay lets an editor finish writing before the file is read again
+const reloadDelay = 100 * time.Millisecond
+
+// Watch reloads the settings file at path over base whenever it changes or
+// the process receives SIGHUP, and publishes the result to bus. A file that
+// fails to load is logged and the current settings are kept. Watch returns
+// when ctx is done.
+func Watch(ctx context.Context, path string, base Config, bus *Bus) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to watch %s: %w", path, err)
+	}
+	defer watcher.Close()
+
+	// Watch the directory, editors often replace the file rather than write it
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	if err := watcher.Add(dir); err != nil {
+		return fmt.Errorf("failed to watch %s: %w", dir, err)
+	}
+
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+
+	reload := time.NewTimer(reloadDelay)
+	reload.Stop()
+	defer reload.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if filepath.Clean(event.Name) == filepath.Clean(path) {
+				reload.Reset(reloadDelay)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			log.Printf("[WARN] Config watcher: %v", err)
+		case <-hangup:
+			log.Printf("Received SIGHUP, reloading %s", path)
+			reload.Reset(0)
+		case <-reload.C:
+			config, err := Load(path, base)
+			if err != nil {
+				log.Printf("[ERROR] Keeping current settings: %v", err)
+				continue
+			}
+			log.Printf("Reloaded settings from %s", path)
+			bus.Publish(config)
+		}
+	}
+}
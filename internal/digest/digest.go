@@ -0,0 +1,242 @@
+// Package digest writes Markdown summaries of the clips copied in a day or a
+// week: what was copied from which app, the links, and the most used snippets.
+package digest
+
+import (
+	"clipboard-manager/internal/clipboard"
+	"clipboard-manager/internal/storage"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Period is the span of time a digest covers
+type Period string
+
+const (
+	Daily  Period = "day"
+	Weekly Period = "week"
+)
+
+const (
+	titleLength  = 100 // Longest clip title listed
+	snippetCount = 5   // Most used snippets shown
+	snippetLines = 5   // Lines of each snippet shown
+)
+
+// ParsePeriod validates a period name
+func ParsePeriod(name string) (Period, error) {
+	switch Period(name) {
+	case Daily, Weekly:
+		return Period(name), nil
+	}
+	return "", fmt.Errorf("unknown digest period %q: expected day or week", name)
+}
+
+// Range returns the period containing t, in t's location. Weeks start on
+// Monday.
+func (p Period) Range(t time.Time) (start, end time.Time) {
+	start = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	if p == Weekly {
+		// Go weeks start on Sunday
+		start = start.AddDate(0, 0, -(int(start.Weekday())+6)%7)
+		return start, start.AddDate(0, 0, 7)
+	}
+	return start, start.AddDate(0, 0, 1)
+}
+
+// Name returns the file name, without extension, of the digest for the
+// period starting at start: 2006-01-02 for a day, 2006-W01 for a week
+func (p Period) Name(start time.Time) string {
+	if p == Weekly {
+		year, week := start.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	}
+	return start.Format("2006-01-02")
+}
+
+// title is the heading of the digest for the period starting at start
+func (p Period) title(start time.Time) string {
+	if p == Weekly {
+		return "Clipboard digest: week of " + start.Format("2 January 2006")
+	}
+	return "Clipboard digest: " + start.Format("Monday, 2 January 2006")
+}
+
+// Render formats the clips copied in the period starting at start as
+// Markdown. results are expected in the order they were copied.
+// The front matter marks the note's type for Obsidian.
+func Render(period Period, start time.Time, results []storage.SearchResult) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "---\ntype: clipboard-digest\nperiod: %s\ndate: %s\nclips: %d\ntags: [clipboard, digest]\n---\n",
+		period, start.Format("2006-01-02"), len(results))
+	fmt.Fprintf(&b, "# %s\n\n", period.title(start))
+
+	if len(results) == 0 {
+		b.WriteString("Nothing was copied.\n")
+		return b.String()
+	}
+
+	apps := make(map[string]bool)
+	for _, result := range results {
+		apps[appName(result)] = true
+	}
+	fmt.Fprintf(&b, "%s from %s.\n", plural(len(results), "clip"), plural(len(apps), "app"))
+
+	writeLinks(&b, results)
+	writeSnippets(&b, results)
+	writeGroups(&b, period, results)
+	return b.String()
+}
+
+// writeLinks lists the pages clips were copied from and copied links, once each
+func writeLinks(b *strings.Builder, results []storage.SearchResult) {
+	seen := make(map[string]bool)
+	var links []string
+	for _, result := range results {
+		clip := result.Clip
+		if u := clip.Metadata.SourceURL; u != "" && !seen[u] {
+			seen[u] = true
+			title := clip.Metadata.SourceTitle
+			if title == "" {
+				title = u
+			}
+			links = append(links, fmt.Sprintf("- [%s](%s)", escape(title), u))
+		}
+		if text, ok := clipboard.PlainText(clip); ok {
+			text = strings.TrimSpace(text)
+			if isLink(text) && !seen[text] {
+				seen[text] = true
+				if link := clip.Metadata.Link; link != nil && link.Title != "" {
+					links = append(links, fmt.Sprintf("- [%s](%s)", escape(link.Title), text))
+				} else {
+					links = append(links, fmt.Sprintf("- <%s>", text))
+				}
+			}
+		}
+	}
+	if len(links) == 0 {
+		return
+	}
+	b.WriteString("\n## Links\n\n")
+	b.WriteString(strings.Join(links, "\n"))
+	b.WriteString("\n")
+}
+
+// writeSnippets quotes the text clips used most, skipping links
+func writeSnippets(b *strings.Builder, results []storage.SearchResult) {
+	var snippets []storage.SearchResult
+	for _, result := range results {
+		if text, ok := clipboard.PlainText(result.Clip); ok && strings.TrimSpace(text) != "" && !isLink(strings.TrimSpace(text)) {
+			snippets = append(snippets, result)
+		}
+	}
+	if len(snippets) == 0 {
+		return
+	}
+	// Stable, so equal counts keep the order they were copied in
+	sort.SliceStable(snippets, func(i, j int) bool {
+		return snippets[i].UseCount > snippets[j].UseCount
+	})
+	if len(snippets) > snippetCount {
+		snippets = snippets[:snippetCount]
+	}
+
+	b.WriteString("\n## Top snippets\n")
+	for _, result := range snippets {
+		text, _ := clipboard.PlainText(result.Clip)
+		lines := strings.Split(strings.TrimSpace(text), "\n")
+		if len(lines) > snippetLines {
+			lines = append(lines[:snippetLines], "…")
+		}
+		b.WriteString("\n")
+		for _, line := range lines {
+			fmt.Fprintf(b, "> %s\n", strings.TrimRight(line, "\r"))
+		}
+		uses := ""
+		if result.UseCount > 1 {
+			uses = fmt.Sprintf(", copied %d times", result.UseCount)
+		}
+		fmt.Fprintf(b, "\n— %s%s\n", appName(result), uses)
+	}
+}
+
+// writeGroups lists every clip under its category, or its app if it has none
+func writeGroups(b *strings.Builder, period Period, results []storage.SearchResult) {
+	type group struct {
+		name  string
+		lines []string
+	}
+	var categories, apps []*group
+	index := make(map[string]*group)
+	for _, result := range results {
+		clip := result.Clip
+		key, list := "app:"+appName(result), &apps
+		if clip.Metadata.Category != "" {
+			key, list = "category:"+clip.Metadata.Category, &categories
+		}
+		g, ok := index[key]
+		if !ok {
+			g = &group{name: strings.SplitN(key, ":", 2)[1]}
+			index[key] = g
+			*list = append(*list, g)
+		}
+
+		when := clip.CreatedAt.Format("15:04")
+		if period == Weekly {
+			when = clip.CreatedAt.Format("Mon 15:04")
+		}
+		line := fmt.Sprintf("- %s %s", when, escape(clipboard.Title(clip, titleLength)))
+		if clip.Metadata.Category != "" && clip.Metadata.SourceApp != "" {
+			line += " (" + clip.Metadata.SourceApp + ")"
+		}
+		g.lines = append(g.lines, line)
+	}
+
+	for _, section := range []struct {
+		heading string
+		groups  []*group
+	}{{"By category", categories}, {"By app", apps}} {
+		if len(section.groups) == 0 {
+			continue
+		}
+		// Busiest first
+		sort.SliceStable(section.groups, func(i, j int) bool {
+			return len(section.groups[i].lines) > len(section.groups[j].lines)
+		})
+		fmt.Fprintf(b, "\n## %s\n", section.heading)
+		for _, g := range section.groups {
+			fmt.Fprintf(b, "\n### %s (%d)\n\n%s\n", g.name, len(g.lines), strings.Join(g.lines, "\n"))
+		}
+	}
+}
+
+func appName(result storage.SearchResult) string {
+	if app := result.Clip.Metadata.SourceApp; app != "" {
+		return app
+	}
+	return "Unknown app"
+}
+
+// isLink reports whether text is a single web link
+func isLink(text string) bool {
+	if strings.ContainsAny(text, " \t\n") {
+		return false
+	}
+	u, err := url.Parse(text)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// escape keeps clip text from being read as Markdown links or wiki links
+func escape(text string) string {
+	return strings.NewReplacer("[", `\[`, "]", `\]`, "<", `\<`).Replace(text)
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
@@ -0,0 +1,76 @@
+package digest
+
+import (
+	"clipboard-manager/internal/storage"
+	"clipboard-manager/pkg/types"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRange(t *testing.T) {
+	// A Sunday afternoon
+	now := time.Date(2024, 6, 2, 15, 4, 5, 0, time.UTC)
+
+	start, end := Daily.Range(now)
+	if !start.Equal(time.Date(2024, 6, 2, 0, 0, 0, 0, time.UTC)) || !end.Equal(start.AddDate(0, 0, 1)) {
+		t.Errorf("unexpected day %v - %v", start, end)
+	}
+	if name := Daily.Name(start); name != "2024-06-02" {
+		t.Errorf("unexpected day name %q", name)
+	}
+
+	start, end = Weekly.Range(now)
+	if !start.Equal(time.Date(2024, 5, 27, 0, 0, 0, 0, time.UTC)) || !end.Equal(start.AddDate(0, 0, 7)) {
+		t.Errorf("unexpected week %v - %v", start, end)
+	}
+	if name := Weekly.Name(start); name != "2024-W22" {
+		t.Errorf("unexpected week name %q", name)
+	}
+}
+
+func TestRender(t *testing.T) {
+	day := time.Date(2024, 6, 3, 0, 0, 0, 0, time.UTC)
+	clip := func(content, app, category string, uses int) storage.SearchResult {
+		return storage.SearchResult{
+			Clip: &types.Clip{
+				Type:      "text/plain",
+				Content:   []byte(content),
+				Metadata:  types.Metadata{SourceApp: app, Category: category},
+				CreatedAt: day.Add(9 * time.Hour),
+			},
+			UseCount: uses,
+		}
+	}
+	results := []storage.SearchResult{
+		clip("https://example.com/docs", "Safari", "", 1),
+		clip("go test ./...", "Terminal", "", 4),
+		clip("standup notes", "Notes", "Work", 1),
+	}
+	results[2].Clip.Metadata.SourceURL = "https://wiki.example.com"
+	results[2].Clip.Metadata.SourceTitle = "Team [wiki]"
+
+	got := Render(Daily, day, results)
+	for _, want := range []string{
+		"type: clipboard-digest\n",
+		"# Clipboard digest: Monday, 3 June 2024\n",
+		"3 clips from 3 apps.\n",
+		"- <https://example.com/docs>\n",
+		`- [Team \[wiki\]](https://wiki.example.com)`,
+		"> go test ./...\n\n— Terminal, copied 4 times\n",
+		"### Work (1)\n\n- 09:00 standup notes (Notes)\n",
+		"### Safari (1)\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("digest is missing %q:\n%s", want, got)
+		}
+	}
+	// The most used snippet comes first and links are not snippets
+	if strings.Index(got, "> go test") > strings.Index(got, "> standup") || strings.Contains(got, "> https://") {
+		t.Errorf("unexpected snippets:\n%s", got)
+	}
+
+	if empty := Render(Weekly, day, nil); !strings.Contains(empty, "Nothing was copied.") {
+		t.Errorf("unexpected empty digest:\n%s", empty)
+	}
+}
@@ -0,0 +1,95 @@
+package digest
+
+import (
+	"clipboard-manager/internal/storage"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Config configures scheduled digests
+type Config struct {
+	Period Period
+	Dir    string // Where digests are written, e.g. a folder in an Obsidian vault
+}
+
+// SearchFunc finds clips, like ClipboardService.Search
+type SearchFunc func(ctx context.Context, opts storage.SearchOptions) ([]storage.SearchResult, error)
+
+// Build renders the digest of the period containing t
+func Build(ctx context.Context, search SearchFunc, period Period, t time.Time) (string, error) {
+	start, end := period.Range(t)
+	results, err := search(ctx, storage.SearchOptions{
+		From:      start,
+		To:        end.Add(-time.Nanosecond),
+		SortBy:    "created_at",
+		SortOrder: "asc",
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to find clips: %w", err)
+	}
+	return Render(period, start, results), nil
+}
+
+// Write builds the digest of the period containing t and saves it in dir,
+// replacing an earlier one. It returns the path written.
+func Write(ctx context.Context, search SearchFunc, period Period, dir string, t time.Time) (string, error) {
+	content, err := Build(ctx, search, period, t)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create digest directory: %w", err)
+	}
+
+	start, _ := period.Range(t)
+	path := filepath.Join(dir, period.Name(start)+".md")
+	// Write then rename, so a vault never sees half a note
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(content), 0644); err != nil {
+		return "", fmt.Errorf("failed to write digest: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return "", fmt.Errorf("failed to write digest: %w", err)
+	}
+	return path, nil
+}
+
+// Run writes the digest of each period shortly after it ends, until ctx is
+// done. The digest of the last period is written on start if it is missing,
+// so periods that ended while the daemon was stopped are not skipped.
+func Run(ctx context.Context, search SearchFunc, config Config) {
+	now := time.Now()
+	current, _ := config.Period.Range(now)
+	previous := current.Add(-time.Nanosecond)
+	start, _ := config.Period.Range(previous)
+	if _, err := os.Stat(filepath.Join(config.Dir, config.Period.Name(start)+".md")); os.IsNotExist(err) {
+		write(ctx, search, config, previous)
+	}
+
+	for {
+		_, end := config.Period.Range(time.Now())
+		// A minute late, so clips copied at the very end are stored
+		timer := time.NewTimer(time.Until(end) + time.Minute)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+			write(ctx, search, config, end.Add(-time.Nanosecond))
+		}
+	}
+}
+
+func write(ctx context.Context, search SearchFunc, config Config, t time.Time) {
+	path, err := Write(ctx, search, config.Period, config.Dir, t)
+	if err != nil {
+		log.Printf("Failed to write digest: %v", err)
+		return
+	}
+	log.Printf("Wrote digest %s", path)
+}
@@ -0,0 +1,94 @@
+// Package doctor checks the permissions the clipboard manager needs, and
+// says where to grant the ones that are missing
+package doctor
+
+import (
+	"clipboard-manager/internal/config"
+	"clipboard-manager/internal/paste"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+)
+
+// Check is the outcome of one check
+type Check struct {
+	Name     string `json:"name"`
+	OK       bool   `json:"ok"`
+	Message  string `json:"message,omitempty"`  // What is wrong and what to do, unless OK
+	Settings string `json:"settings,omitempty"` // URL of the System Settings pane that fixes it, if any
+}
+
+// Folder is a folder clips are synced to
+type Folder struct {
+	Name string // Such as "Obsidian vault"
+	Path string
+}
+
+// Options says what to check
+type Options struct {
+	Paster  paste.Paster // Nil skips checking paste simulation
+	Folders []Folder
+}
+
+// Run runs the checks opts asks for
+func Run(opts Options) []Check {
+	checks := []Check{}
+	if opts.Paster != nil {
+		checks = append(checks, checkPaste(opts.Paster))
+	}
+	for _, folder := range opts.Folders {
+		checks = append(checks, checkFolder(folder))
+	}
+	return checks
+}
+
+// SyncFolders lists the folders c syncs clips to
+func SyncFolders(c config.Config) []Folder {
+	var folders []Folder
+	if c.Obsidian.Enabled && c.Obsidian.VaultPath != "" {
+		folders = append(folders, Folder{Name: "Obsidian vault", Path: c.Obsidian.VaultPath})
+	}
+	if c.Logseq.Enabled && c.Logseq.GraphPath != "" {
+		folders = append(folders, Folder{Name: "Logseq graph", Path: c.Logseq.GraphPath})
+	}
+	if c.Markdown.Enabled && c.Markdown.Path != "" {
+		folders = append(folders, Folder{Name: "Markdown folder", Path: c.Markdown.Path})
+	}
+	return folders
+}
+
+// checkPaste checks that the paste shortcut can be pressed. macOS asks for
+// the Accessibility permission.
+func checkPaste(paster paste.Paster) Check {
+	check := Check{Name: "Paste simulation", OK: true}
+	if err := paster.Check(); err != nil {
+		check.OK = false
+		check.Message = err.Error()
+		if errors.Is(err, paste.ErrPermission) {
+			check.Settings = accessibilitySettings
+		}
+	}
+	return check
+}
+
+// checkFolder checks that folder can be read. macOS asks for Full Disk
+// Access to read folders such as iCloud Drive or another app's container.
+func checkFolder(folder Folder) Check {
+	check := Check{Name: folder.Name, OK: true}
+	_, err := os.ReadDir(folder.Path)
+	switch {
+	case err == nil:
+	case errors.Is(err, fs.ErrPermission) && fullDiskAccessSettings != "":
+		check.OK = false
+		check.Message = fmt.Sprintf("%s can't be read: allow Clipboard Manager in System Settings > Privacy & Security > Full Disk Access, then restart it", folder.Path)
+		check.Settings = fullDiskAccessSettings
+	case errors.Is(err, fs.ErrNotExist):
+		check.OK = false
+		check.Message = fmt.Sprintf("%s does not exist", folder.Path)
+	default:
+		check.OK = false
+		check.Message = err.Error()
+	}
+	return check
+}
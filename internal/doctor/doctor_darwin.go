@@ -0,0 +1,20 @@
+package doctor
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// The System Settings panes that grant each permission
+const (
+	accessibilitySettings  = "x-apple.systempreferences:com.apple.preference.security?Privacy_Accessibility"
+	fullDiskAccessSettings = "x-apple.systempreferences:com.apple.preference.security?Privacy_AllFiles"
+)
+
+// OpenSettings opens the System Settings pane of a check
+func OpenSettings(url string) error {
+	if output, err := exec.Command("open", url).CombinedOutput(); err != nil {
+		return fmt.Errorf("open failed: %w: %s", err, output)
+	}
+	return nil
+}
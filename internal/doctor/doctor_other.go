@@ -0,0 +1,16 @@
+//go:build !darwin
+
+package doctor
+
+import "errors"
+
+// Only macOS has permissions granted in System Settings
+const (
+	accessibilitySettings  = ""
+	fullDiskAccessSettings = ""
+)
+
+// OpenSettings fails: there are no System Settings panes to open here
+func OpenSettings(url string) error {
+	return errors.New("there are no System Settings on this platform")
+}
@@ -0,0 +1,61 @@
+package doctor
+
+import (
+	"clipboard-manager/internal/config"
+	"clipboard-manager/internal/paste"
+	"context"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// fakePaster fails its check with err
+type fakePaster struct{ err error }
+
+func (p fakePaster) Check() error                { return p.err }
+func (p fakePaster) Paste(context.Context) error { return p.err }
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	missing := filepath.Join(dir, "missing")
+
+	checks := Run(Options{
+		Paster: fakePaster{err: fmt.Errorf("%w: allow it", paste.ErrPermission)},
+		Folders: []Folder{
+			{Name: "Obsidian vault", Path: dir},
+			{Name: "Logseq graph", Path: missing},
+		},
+	})
+	if len(checks) != 3 {
+		t.Fatalf("got %d checks, want 3: %+v", len(checks), checks)
+	}
+
+	if check := checks[0]; check.OK || check.Settings != accessibilitySettings {
+		t.Errorf("paste check = %+v, want failed with the Accessibility pane", check)
+	}
+	if check := checks[1]; !check.OK || check.Message != "" {
+		t.Errorf("vault check = %+v, want OK", check)
+	}
+	if check := checks[2]; check.OK || check.Message != missing+" does not exist" {
+		t.Errorf("graph check = %+v, want failed as missing", check)
+	}
+}
+
+func TestRun_Empty(t *testing.T) {
+	if checks := Run(Options{}); checks == nil || len(checks) != 0 {
+		t.Errorf("Run() = %#v, want no checks", checks)
+	}
+}
+
+func TestSyncFolders(t *testing.T) {
+	folders := SyncFolders(config.Config{
+		Obsidian: config.Obsidian{Enabled: true, VaultPath: "/vault"},
+		Logseq:   config.Logseq{Enabled: false, GraphPath: "/graph"},
+		Markdown: config.Markdown{Enabled: true, Path: "/notes"},
+	})
+	want := []Folder{{Name: "Obsidian vault", Path: "/vault"}, {Name: "Markdown folder", Path: "/notes"}}
+	if !reflect.DeepEqual(folders, want) {
+		t.Errorf("SyncFolders() = %+v, want %+v", folders, want)
+	}
+}
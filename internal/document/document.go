@@ -0,0 +1,80 @@
+// Package document extracts the text of document clips, such as PDFs, so
+// they can be searched and previewed
+package document
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// extractTimeout bounds a pdftotext run, since large or malformed PDFs can
+// take a long time
+const extractTimeout = 15 * time.Second
+
+// ErrNotInstalled is returned when pdftotext can't be found
+var ErrNotInstalled = errors.New("pdftotext is not installed")
+
+// PDFText returns the text of a PDF using pdftotext from poppler. Pages are
+// separated by blank lines.
+func PDFText(ctx context.Context, pdf []byte) (string, error) {
+	tool, err := exec.LookPath("pdftotext")
+	if err != nil {
+		return "", ErrNotInstalled
+	}
+
+	// Older versions of pdftotext can't read standard input
+	file, err := os.CreateTemp("", "clip-*.pdf")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.Write(pdf); err != nil {
+		file.Close()
+		return "", fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return "", fmt.Errorf("failed to write temp file: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, extractTimeout)
+	defer cancel()
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, tool, "-enc", "UTF-8", file.Name(), "-")
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("pdftotext failed: %s", msg)
+		}
+		return "", fmt.Errorf("pdftotext failed: %w", err)
+	}
+	return Clean(stdout.String()), nil
+}
+
+// Clean tidies extracted text: form feeds between pages become blank lines,
+// trailing spaces are dropped and runs of blank lines are collapsed
+func Clean(text string) string {
+	text = strings.ReplaceAll(text, "\f", "\n\n")
+	lines := strings.Split(text, "\n")
+	cleaned := make([]string, 0, len(lines))
+	blank := 0
+	for _, line := range lines {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" {
+			blank++
+			if blank > 1 {
+				continue
+			}
+		} else {
+			blank = 0
+		}
+		cleaned = append(cleaned, line)
+	}
+	return strings.TrimSpace(strings.Join(cleaned, "\n"))
+}
@@ -0,0 +1,11 @@
+package document
+
+import "testing"
+
+func TestClean(t *testing.T) {
+	input := "Title   \n\n\n\nFirst page\r\n\fSecond page\n\f"
+	want := "Title\n\nFirst page\n\nSecond page"
+	if got := Clean(input); got != want {
+		t.Errorf("Clean = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,305 @@
+// Package export renders clipboard history through Go templates into any
+// text format, such as an HTML report, an org-mode file or CSV with chosen
+// columns
+package export
+
+import (
+	"bytes"
+	"clipboard-manager/internal/clipboard"
+	"clipboard-manager/pkg/types"
+	"embed"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	texttemplate "text/template"
+	"time"
+	"unicode/utf8"
+)
+
+//go:embed templates
+var builtins embed.FS
+
+// Builtins names the templates that come with the clipboard manager
+var Builtins = []string{"html", "org", "csv", "markdown"}
+
+// builtinFiles maps builtin template names to their files
+var builtinFiles = map[string]string{
+	"html":     "templates/report.html.tmpl",
+	"org":      "templates/clips.org.tmpl",
+	"csv":      "templates/clips.csv.tmpl",
+	"markdown": "templates/clips.md.tmpl",
+}
+
+// imageExtensions names exported images by their clip type
+var imageExtensions = map[string]string{
+	"screenshot": ".png", "image/png": ".png", "image/jpeg": ".jpg",
+	"image/gif": ".gif", "image/tiff": ".tiff", "image/webp": ".webp",
+	"image/svg+xml": ".svg",
+}
+
+// Data is what a template renders
+type Data struct {
+	Clips    []*types.Clip
+	Query    string // Search query the clips match, if any
+	Exported time.Time
+}
+
+// Options configures rendering
+type Options struct {
+	// Content loads the full content of a clip listed with only part of it,
+	// or none, as large text and images may be. Nil uses what clips hold.
+	Content func(clip *types.Clip) ([]byte, error)
+
+	// ImageDir is where the image function saves images, "images" if empty
+	ImageDir string
+
+	// OutputDir is the folder of the rendered file, which image paths are
+	// relative to. Empty leaves them relative to the working directory.
+	OutputDir string
+}
+
+// Template is a parsed export template. Templates whose name ends in .html
+// or .htm, before any .tmpl, escape what they render as HTML.
+type Template struct {
+	name string
+	text string
+	html bool
+}
+
+// Load reads the template at path, or the builtin template of that name
+func Load(path string) (*Template, error) {
+	if file, ok := builtinFiles[path]; ok {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			text, err := builtins.ReadFile(file)
+			if err != nil {
+				return nil, err
+			}
+			return Parse(filepath.Base(file), string(text))
+		}
+	}
+	text, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template: %w", err)
+	}
+	return Parse(filepath.Base(path), string(text))
+}
+
+// Parse checks a template's syntax. The name decides whether it is HTML.
+func Parse(name, text string) (*Template, error) {
+	base := strings.ToLower(strings.TrimSuffix(name, ".tmpl"))
+	t := &Template{
+		name: name,
+		text: text,
+		html: strings.HasSuffix(base, ".html") || strings.HasSuffix(base, ".htm"),
+	}
+	if _, err := t.compile(funcs(Options{})); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// executor is a parsed text or HTML template
+type executor interface {
+	Execute(w io.Writer, data interface{}) error
+}
+
+// compile parses the template with funcs bound to one rendering
+func (t *Template) compile(fm map[string]interface{}) (executor, error) {
+	var tmpl executor
+	var err error
+	if t.html {
+		tmpl, err = htmltemplate.New(t.name).Funcs(fm).Parse(t.text)
+	} else {
+		tmpl, err = texttemplate.New(t.name).Funcs(fm).Parse(t.text)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// Execute renders clips to w
+func (t *Template) Execute(w io.Writer, data Data, opts Options) error {
+	tmpl, err := t.compile(funcs(opts))
+	if err != nil {
+		return err
+	}
+	if data.Exported.IsZero() {
+		data.Exported = time.Now()
+	}
+	if err := tmpl.Execute(w, data); err != nil {
+		return fmt.Errorf("failed to render template: %w", err)
+	}
+	return nil
+}
+
+// funcs returns the functions templates can call
+func funcs(opts Options) map[string]interface{} {
+	r := &renderer{opts: opts}
+	return map[string]interface{}{
+		"date":     date,
+		"truncate": truncate,
+		"text":     r.text,
+		"title":    r.title,
+		"isImage":  isImage,
+		"image":    r.image,
+		"csv":      csvRecord,
+		"json":     toJSON,
+		"join":     join,
+		"lower":    strings.ToLower,
+		"upper":    strings.ToUpper,
+		"trim":     strings.TrimSpace,
+		"replace":  replace,
+		"indent":   indent,
+	}
+}
+
+// renderer holds the functions that load clip content
+type renderer struct {
+	opts Options
+}
+
+// content returns all of a clip's content
+func (r *renderer) content(clip *types.Clip) ([]byte, error) {
+	if r.opts.Content == nil || (!clip.Truncated && len(clip.Content) > 0) {
+		return clip.Content, nil
+	}
+	return r.opts.Content(clip)
+}
+
+// text returns the text of a clip, or "" for clips without text
+func (r *renderer) text(clip *types.Clip) (string, error) {
+	if isImage(clip) {
+		return "", nil
+	}
+	if clip.Truncated {
+		content, err := r.content(clip)
+		if err != nil {
+			return "", err
+		}
+		full := *clip
+		full.Content, full.Truncated = content, false
+		clip = &full
+	}
+	text, _ := clipboard.PlainText(clip)
+	return text, nil
+}
+
+// title returns a one line summary of a clip, at most n runes long
+func (r *renderer) title(n int, clip *types.Clip) string {
+	return clipboard.Title(clip, n)
+}
+
+// image saves an image clip in the image folder and returns its path, or ""
+// for clips that aren't images
+func (r *renderer) image(clip *types.Clip) (string, error) {
+	if !isImage(clip) {
+		return "", nil
+	}
+	dir := r.opts.ImageDir
+	if dir == "" {
+		dir = "images"
+	}
+	ext, ok := imageExtensions[clip.Type]
+	if !ok {
+		ext = ".png"
+	}
+	path := filepath.Join(dir, fmt.Sprintf("%s-%s%s", clip.CreatedAt.Format("20060102-150405"), clip.ID, ext))
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		content, err := r.content(clip)
+		if err != nil {
+			return "", err
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return "", fmt.Errorf("failed to create image folder: %w", err)
+		}
+		if err := os.WriteFile(path, content, 0644); err != nil {
+			return "", fmt.Errorf("failed to write image: %w", err)
+		}
+	}
+	return r.link(path), nil
+}
+
+// link returns the path a rendered file refers to a saved file by
+func (r *renderer) link(path string) string {
+	if r.opts.OutputDir != "" {
+		abs, err := filepath.Abs(path)
+		if err == nil {
+			if out, err := filepath.Abs(r.opts.OutputDir); err == nil {
+				if rel, err := filepath.Rel(out, abs); err == nil {
+					path = rel
+				}
+			}
+		}
+	}
+	return filepath.ToSlash(path)
+}
+
+// isImage reports whether a clip is an image
+func isImage(clip *types.Clip) bool {
+	return types.IsImage(clip.Type)
+}
+
+// date formats a time with a Go layout, such as "2006-01-02 15:04". A nil
+// time formats as "".
+func date(layout string, t interface{}) (string, error) {
+	switch t := t.(type) {
+	case time.Time:
+		return t.Format(layout), nil
+	case *time.Time:
+		if t == nil {
+			return "", nil
+		}
+		return t.Format(layout), nil
+	}
+	return "", fmt.Errorf("date: expected a time, got %T", t)
+}
+
+// truncate shortens s to n runes, ending it with "…" if it was longer
+func truncate(n int, s string) string {
+	if n <= 0 || utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n-1]) + "…"
+}
+
+// csvRecord quotes fields as a line of CSV
+func csvRecord(fields ...interface{}) (string, error) {
+	record := make([]string, len(fields))
+	for i, field := range fields {
+		record[i] = fmt.Sprint(field)
+	}
+	var b bytes.Buffer
+	w := csv.NewWriter(&b)
+	if err := w.Write(record); err != nil {
+		return "", err
+	}
+	w.Flush()
+	return b.String(), w.Error()
+}
+
+// toJSON encodes v, for embedding values in JSON or scripts
+func toJSON(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	return string(b), err
+}
+
+// join joins elems with sep
+func join(sep string, elems []string) string {
+	return strings.Join(elems, sep)
+}
+
+// replace replaces every old in s with new
+func replace(old, new, s string) string {
+	return strings.ReplaceAll(s, old, new)
+}
+
+// indent prefixes every line of s, such as to nest text in a list or quote
+func indent(prefix, s string) string {
+	return prefix + strings.ReplaceAll(strings.TrimRight(s, "\n"), "\n", "\n"+prefix)
+}
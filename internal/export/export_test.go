@@ -0,0 +1,90 @@
+package export
+
+import (
+	"bytes"
+	"clipboard-manager/pkg/types"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testClips() []*types.Clip {
+	created := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+	return []*types.Clip{
+		{ID: "1", Type: "text/plain", Content: []byte(`<b>"hi"</b>`), CreatedAt: created,
+			Metadata: types.Metadata{SourceApp: "Safari", Tags: []string{"a", "b"}}},
+		{ID: "2", Type: "text/plain", Content: []byte("head"), Truncated: true, CreatedAt: created},
+		{ID: "3", Type: "image/png", CreatedAt: created},
+	}
+}
+
+func TestTemplate_Execute(t *testing.T) {
+	dir := t.TempDir()
+	opts := Options{
+		ImageDir: filepath.Join(dir, "images"),
+		Content: func(clip *types.Clip) ([]byte, error) {
+			if clip.ID == "3" {
+				return []byte("png"), nil
+			}
+			return []byte("head and tail"), nil
+		},
+	}
+
+	tmpl, err := Parse("clips.tmpl", `{{range .Clips}}{{csv .ID (date "2006-01-02" .CreatedAt) (join ";" .Metadata.Tags) (text . | truncate 8) (image .)}}{{end}}`)
+	if err != nil {
+		t.Fatalf("failed to parse template: %v", err)
+	}
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, Data{Clips: testClips()}, opts); err != nil {
+		t.Fatalf("failed to render: %v", err)
+	}
+	image := filepath.ToSlash(filepath.Join(opts.ImageDir, "20240102-150405-3.png"))
+	want := `1,2024-01-02,a;b,"<b>""hi""…",` + "\n" +
+		"2,2024-01-02,,head an…,\n" +
+		"3,2024-01-02,,," + image + "\n"
+	if out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+	if content, err := os.ReadFile(image); err != nil || string(content) != "png" {
+		t.Errorf("expected the image to be saved, got %q, %v", content, err)
+	}
+
+	// HTML templates escape what they render
+	tmpl, err = Parse("report.html", `{{range .Clips}}<p>{{text .}}</p>{{end}}`)
+	if err != nil {
+		t.Fatalf("failed to parse template: %v", err)
+	}
+	out.Reset()
+	if err := tmpl.Execute(&out, Data{Clips: testClips()[:1]}, opts); err != nil {
+		t.Fatalf("failed to render: %v", err)
+	}
+	if want := "<p>&lt;b&gt;&#34;hi&#34;&lt;/b&gt;</p>"; out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+
+	if _, err := Parse("bad.tmpl", "{{range .Clips}}"); err == nil {
+		t.Error("expected an unterminated range to be rejected")
+	}
+	if _, err := Parse("bad.tmpl", "{{nope .}}"); err == nil {
+		t.Error("expected an unknown function to be rejected")
+	}
+}
+
+func TestLoad_Builtins(t *testing.T) {
+	for _, name := range Builtins {
+		tmpl, err := Load(name)
+		if err != nil {
+			t.Fatalf("failed to load %s: %v", name, err)
+		}
+		var out bytes.Buffer
+		opts := Options{ImageDir: t.TempDir(), Content: func(*types.Clip) ([]byte, error) { return []byte("full"), nil }}
+		if err := tmpl.Execute(&out, Data{Clips: testClips(), Query: "q"}, opts); err != nil {
+			t.Fatalf("failed to render %s: %v", name, err)
+		}
+		if !strings.Contains(out.String(), "full") {
+			t.Errorf("%s does not include the full text of a truncated clip:\n%s", name, out.String())
+		}
+	}
+}
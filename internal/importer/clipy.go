@@ -0,0 +1,97 @@
+package importer
+
+import (
+	"bytes"
+	"clipboard-manager/internal/storage"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Image data starts with one of these
+var (
+	pngSignature = []byte("\x89PNG\r\n\x1a\n")
+	tiffLittle   = []byte("II*\x00")
+	tiffBig      = []byte("MM\x00*")
+)
+
+// readClipy reads the clips Clipy archives, a .data file each in its
+// folder. Their times live in a Realm database this can't read, so clips
+// are dated when their file was written, which is when they were copied.
+func readClipy(path string) ([]storage.ImportedClip, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open Clipy history: %w", err)
+	}
+	files := []string{path}
+	if info.IsDir() {
+		if files, err = filepath.Glob(filepath.Join(path, "*.data")); err != nil {
+			return nil, err
+		}
+		if len(files) == 0 {
+			return nil, fmt.Errorf("no Clipy clips in %s", path)
+		}
+	}
+
+	var clips []storage.ImportedClip
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		info, err := os.Stat(file)
+		if err != nil {
+			return nil, err
+		}
+		clip, ok, err := parseClipyData(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read Clipy clip %s: %w", filepath.Base(file), err)
+		}
+		if !ok {
+			continue
+		}
+		clip.CreatedAt = info.ModTime()
+		clips = append(clips, clip)
+	}
+	return clips, nil
+}
+
+// parseClipyData decodes an archived CPYClipData. It reports false for
+// clips with nothing that can be imported.
+func parseClipyData(data []byte) (storage.ImportedClip, bool, error) {
+	archive, err := parseKeyedArchive(data)
+	if err != nil {
+		return storage.ImportedClip{}, false, err
+	}
+	root := archive.root
+
+	formats := make(map[string][]byte)
+	if text, ok := archive.string(archive.field(root, "stringValue")); ok && text != "" {
+		formats["text/plain"] = []byte(text)
+	}
+	if rtf := archive.data(archive.field(root, "RTFData")); len(rtf) > 0 {
+		formats["text/rtf"] = rtf
+	}
+	if files := archive.strings(archive.field(root, "filenames", "fileNames")); len(files) > 0 {
+		formats["text/uri-list"] = []byte(files[0])
+	}
+	if _, ok := formats["text/plain"]; !ok {
+		if urls := archive.strings(archive.field(root, "URL", "URLs")); len(urls) > 0 {
+			formats["text/plain"] = []byte(urls[0])
+		}
+	}
+	if image := archive.field(root, "image"); image != nil {
+		found := archive.find(image, func(b []byte) bool {
+			return bytes.HasPrefix(b, pngSignature) || bytes.HasPrefix(b, tiffLittle) || bytes.HasPrefix(b, tiffBig)
+		})
+		switch {
+		case bytes.HasPrefix(found, pngSignature):
+			formats["image/png"] = found
+		case found != nil:
+			formats["image/tiff"] = found
+		}
+	}
+
+	clip, ok := fromFormats(formats)
+	return clip, ok, nil
+}
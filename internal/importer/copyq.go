@@ -0,0 +1,260 @@
+package importer
+
+import (
+	"bytes"
+	"clipboard-manager/internal/storage"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// CopyQ keeps each tab in a file of Qt data streams. The newer item format
+// starts with copyQItemFormat and shortens MIME types to a code for their
+// prefix.
+const (
+	copyQItemFormat     = -2
+	copyQEncryptedTab   = "CopyQ_encrypted_tab"
+	copyQTabFilePattern = "copyq_tab_*.dat"
+)
+
+// copyQMimePrefixes maps the codes CopyQ shortens MIME types to
+var copyQMimePrefixes = map[byte]string{
+	'0': "",
+	'1': "application/x-copyq-",
+	'2': "application/",
+	'3': "text/",
+}
+
+// readCopyQ reads the tabs in CopyQ's configuration folder, or the tab file
+// at path. Clips are tagged with the name of their tab.
+func readCopyQ(path string) ([]storage.ImportedClip, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open CopyQ history: %w", err)
+	}
+	files := []string{path}
+	if info.IsDir() {
+		if files, err = filepath.Glob(filepath.Join(path, copyQTabFilePattern)); err != nil {
+			return nil, err
+		}
+		if len(files) == 0 {
+			return nil, fmt.Errorf("no CopyQ tabs in %s", path)
+		}
+	}
+
+	var clips []storage.ImportedClip
+	for _, file := range files {
+		tab, err := readCopyQTab(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CopyQ tab %s: %w", filepath.Base(file), err)
+		}
+		clips = append(clips, tab...)
+	}
+	return clips, nil
+}
+
+// readCopyQTab reads the items of a tab, newest first in the file. CopyQ
+// doesn't record when items were copied, so they keep their order ending at
+// the time the tab was saved, a second apart.
+func readCopyQTab(path string) ([]storage.ImportedClip, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	items, err := parseCopyQTab(data)
+	if err != nil {
+		return nil, err
+	}
+
+	tab := copyQTabName(path)
+	var clips []storage.ImportedClip
+	for i, formats := range items {
+		clip, ok := fromFormats(formats)
+		if !ok {
+			continue
+		}
+		clip.CreatedAt = info.ModTime().Add(-time.Duration(i) * time.Second)
+		if tab != "" {
+			clip.Metadata.Tags = []string{tab}
+		}
+		clips = append(clips, clip)
+	}
+	return clips, nil
+}
+
+// copyQTabName decodes the tab name in a tab file's name, base64 with / as
+// -, or returns "" if it can't
+func copyQTabName(path string) string {
+	name := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(path), "copyq_tab_"), ".dat")
+	decoded, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(name, "-", "/"))
+	if err != nil || !utf8.Valid(decoded) {
+		return ""
+	}
+	return string(decoded)
+}
+
+// parseCopyQTab parses a tab file into the formats of each item
+func parseCopyQTab(data []byte) ([]map[string][]byte, error) {
+	r := &qDataStream{r: bytes.NewReader(data)}
+
+	// Newer versions start with a header naming the format
+	if header, ok := r.peekHeader(); ok {
+		if header == copyQEncryptedTab {
+			return nil, fmt.Errorf("%w: encrypted tabs can't be read", ErrUnsupported)
+		}
+	}
+
+	count := r.int32()
+	if r.err != nil || count < 0 {
+		return nil, fmt.Errorf("invalid CopyQ tab")
+	}
+	items := make([]map[string][]byte, 0, count)
+	for i := int32(0); i < count; i++ {
+		item := r.item()
+		if r.err != nil {
+			return nil, fmt.Errorf("invalid CopyQ item %d: %w", i, r.err)
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// qDataStream reads the Qt data stream types CopyQ writes, big endian. The
+// first error stops reading and is kept in err.
+type qDataStream struct {
+	r   *bytes.Reader
+	err error
+}
+
+func (q *qDataStream) read(v interface{}) {
+	if q.err == nil {
+		q.err = binary.Read(q.r, binary.BigEndian, v)
+	}
+}
+
+func (q *qDataStream) int32() int32 {
+	var v int32
+	q.read(&v)
+	return v
+}
+
+func (q *qDataStream) bool() bool {
+	var v uint8
+	q.read(&v)
+	return v != 0
+}
+
+// bytes reads a QByteArray: its length, all ones for a null array, then
+// its bytes
+func (q *qDataStream) bytes() []byte {
+	var n uint32
+	q.read(&n)
+	if q.err != nil || n == 0xFFFFFFFF {
+		return nil
+	}
+	if int64(n) > int64(q.r.Len()) {
+		q.err = io.ErrUnexpectedEOF
+		return nil
+	}
+	b := make([]byte, n)
+	_, q.err = io.ReadFull(q.r, b)
+	return b
+}
+
+// string reads a QString, a QByteArray of UTF-16
+func (q *qDataStream) string() string {
+	b := q.bytes()
+	if len(b)%2 != 0 {
+		q.err = errors.New("invalid string")
+		return ""
+	}
+	units := make([]uint16, len(b)/2)
+	for i := range units {
+		units[i] = binary.BigEndian.Uint16(b[2*i:])
+	}
+	return string(utf16.Decode(units))
+}
+
+// peekHeader reads a header string naming the format, if the stream starts
+// with one, and leaves the stream where it was otherwise
+func (q *qDataStream) peekHeader() (string, bool) {
+	start := q.r.Len()
+	header := q.string()
+	if q.err == nil && strings.HasPrefix(header, "CopyQ") {
+		return header, true
+	}
+	q.err = nil
+	q.r.Seek(int64(q.r.Size())-int64(start), io.SeekStart)
+	return "", false
+}
+
+// item reads the formats of an item
+func (q *qDataStream) item() map[string][]byte {
+	formats := make(map[string][]byte)
+	n := q.int32()
+	if n == copyQItemFormat {
+		n = q.int32()
+		for i := int32(0); i < n && q.err == nil; i++ {
+			mime := copyQMime(q.string())
+			compressed := q.bool()
+			data := q.bytes()
+			if compressed {
+				data = qUncompress(data)
+			}
+			formats[mime] = data
+		}
+		return formats
+	}
+	if n < 0 {
+		q.err = fmt.Errorf("%w: unknown CopyQ item format %d", ErrUnsupported, n)
+		return nil
+	}
+	// The older format compresses all data
+	for i := int32(0); i < n && q.err == nil; i++ {
+		mime := q.string()
+		formats[mime] = qUncompress(q.bytes())
+	}
+	return formats
+}
+
+// copyQMime expands a MIME type CopyQ shortened
+func copyQMime(mime string) string {
+	if mime == "" || strings.Contains(mime, "/") {
+		return mime
+	}
+	if prefix, ok := copyQMimePrefixes[mime[0]]; ok {
+		return prefix + mime[1:]
+	}
+	return mime
+}
+
+// qUncompress undoes Qt's qCompress: the uncompressed length, then zlib
+// data. Data that isn't compressed is returned as it is.
+func qUncompress(data []byte) []byte {
+	if len(data) < 4 {
+		return data
+	}
+	zr, err := zlib.NewReader(bytes.NewReader(data[4:]))
+	if err != nil {
+		return data
+	}
+	defer zr.Close()
+	out, err := io.ReadAll(zr)
+	if err != nil {
+		return data
+	}
+	return out
+}
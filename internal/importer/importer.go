@@ -0,0 +1,153 @@
+// Package importer reads the history of other clipboard managers, so
+// switching to this one doesn't mean losing it
+package importer
+
+import (
+	"clipboard-manager/internal/storage"
+	"clipboard-manager/pkg/types"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Sources
+const (
+	Maccy = "maccy"
+	CopyQ = "copyq"
+	Clipy = "clipy"
+	Paste = "paste"
+)
+
+// Sources lists the clipboard managers history can be imported from
+var Sources = []string{Maccy, CopyQ, Clipy, Paste}
+
+var (
+	ErrUnknownSource = errors.New("unknown clipboard manager, expected maccy, copyq, clipy or paste")
+	ErrUnsupported   = errors.New("history can't be imported")
+)
+
+// Read returns the clips in the history of the clipboard manager from, kept
+// at path or, if path is empty, where it keeps it by default. Clips are
+// oldest first.
+func Read(from, path string) ([]storage.ImportedClip, error) {
+	if path == "" {
+		var err error
+		if path, err = DefaultPath(from); err != nil {
+			return nil, err
+		}
+	}
+
+	var clips []storage.ImportedClip
+	var err error
+	switch from {
+	case Maccy:
+		clips, err = readMaccy(path)
+	case CopyQ:
+		clips, err = readCopyQ(path)
+	case Clipy:
+		clips, err = readClipy(path)
+	case Paste:
+		// Paste keeps its library in a format of its own that isn't
+		// documented, and it has no export to read instead
+		return nil, fmt.Errorf("%w from Paste: its library format isn't documented", ErrUnsupported)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownSource, from)
+	}
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(clips, func(i, j int) bool {
+		return clips[i].CreatedAt.Before(clips[j].CreatedAt)
+	})
+	return clips, nil
+}
+
+// DefaultPath returns where a clipboard manager keeps its history
+func DefaultPath(from string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	switch from {
+	case Maccy:
+		return filepath.Join(home, "Library", "Containers", "org.p0deje.Maccy", "Data", "Library", "Application Support", "Maccy", "Storage.sqlite"), nil
+	case CopyQ:
+		if runtime.GOOS == "windows" {
+			config, err := os.UserConfigDir()
+			if err != nil {
+				return "", err
+			}
+			return filepath.Join(config, "copyq"), nil
+		}
+		return filepath.Join(home, ".config", "copyq"), nil
+	case Clipy:
+		return filepath.Join(home, "Library", "Application Support", "Clipy"), nil
+	case Paste:
+		return "", fmt.Errorf("%w from Paste: its library format isn't documented", ErrUnsupported)
+	}
+	return "", fmt.Errorf("%w: %q", ErrUnknownSource, from)
+}
+
+// preferredTypes orders the types a clip may be kept as, the first one a
+// clip has being its type and the others alternate formats
+var preferredTypes = []string{types.TypeFile, types.TypePNG, types.TypeTIFF, types.TypeJPEG, types.TypeText, types.TypeHTML, types.TypeRTF}
+
+// fromFormats builds a clip from the formats another clipboard manager kept
+// for it, keyed by their MIME or pasteboard types. It reports false if none
+// of them can be imported.
+func fromFormats(formats map[string][]byte) (storage.ImportedClip, bool) {
+	byType := make(map[string][]byte)
+	for format, data := range formats {
+		clipType := types.NormalizeType(format)
+		if !slices.Contains(preferredTypes, clipType) || len(data) == 0 {
+			continue
+		}
+		if _, seen := byType[clipType]; !seen {
+			byType[clipType] = data
+		}
+	}
+
+	var clip storage.ImportedClip
+	for _, clipType := range preferredTypes {
+		data, ok := byType[clipType]
+		if !ok {
+			continue
+		}
+		if clip.Type == "" {
+			clip.Type, clip.Content = clipType, data
+			if clipType == types.TypeFile {
+				clip.Content = firstFileURL(data)
+			}
+			continue
+		}
+		// Text formats go along with the clip, as they do when copying
+		if strings.HasPrefix(clipType, "text/") {
+			if clip.Metadata.Formats == nil {
+				clip.Metadata.Formats = make(map[string][]byte)
+			}
+			clip.Metadata.Formats[clipType] = data
+		}
+	}
+	return clip, clip.Type != ""
+}
+
+// firstFileURL returns the first file URL of a URI list, making a path a
+// file URL
+func firstFileURL(data []byte) []byte {
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if !strings.HasPrefix(line, "file://") {
+			line = "file://" + line
+		}
+		return []byte(line)
+	}
+	return data
+}
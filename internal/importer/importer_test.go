@@ -0,0 +1,166 @@
+package importer
+
+import (
+	"bytes"
+	"compress/zlib"
+	"database/sql"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"unicode/utf16"
+)
+
+func TestReadMaccy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "Storage.sqlite")
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	for _, stmt := range []string{
+		`CREATE TABLE ZHISTORYITEM (Z_PK INTEGER PRIMARY KEY, ZAPPLICATION VARCHAR, ZFIRSTCOPIEDAT TIMESTAMP, ZLASTCOPIEDAT TIMESTAMP, ZNUMBEROFCOPIES INTEGER)`,
+		`CREATE TABLE ZHISTORYITEMCONTENT (Z_PK INTEGER PRIMARY KEY, ZITEM INTEGER, ZTYPE VARCHAR, ZVALUE BLOB)`,
+		`INSERT INTO ZHISTORYITEM VALUES (1, 'com.apple.Safari', 700000000, 700000100, 3), (2, 'com.apple.finder', 600000000, 600000000, 1)`,
+		`INSERT INTO ZHISTORYITEMCONTENT VALUES (1, 1, 'public.utf8-plain-text', 'hello'), (2, 1, 'public.html', '<b>hello</b>'), (3, 2, 'public.file-url', 'file:///tmp/a.txt')`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("failed to set up database: %v", err)
+		}
+	}
+	db.Close()
+
+	clips, err := Read(Maccy, path)
+	if err != nil {
+		t.Fatalf("failed to read history: %v", err)
+	}
+	if len(clips) != 2 {
+		t.Fatalf("expected 2 clips, got %d", len(clips))
+	}
+	// Oldest first
+	file, text := clips[0], clips[1]
+	if file.Type != "file" || string(file.Content) != "file:///tmp/a.txt" {
+		t.Errorf("unexpected file clip %q %q", file.Type, file.Content)
+	}
+	if text.Type != "text/plain" || string(text.Content) != "hello" {
+		t.Errorf("unexpected text clip %q %q", text.Type, text.Content)
+	}
+	if string(text.Metadata.Formats["text/html"]) != "<b>hello</b>" {
+		t.Errorf("expected the HTML format to be kept, got %v", text.Metadata.Formats)
+	}
+	if text.Metadata.SourceBundleID != "com.apple.Safari" || text.UseCount != 3 {
+		t.Errorf("unexpected metadata %q, use count %d", text.Metadata.SourceBundleID, text.UseCount)
+	}
+	if want := coreDataEpoch.Add(700000000 * time.Second); !text.CreatedAt.Equal(want) {
+		t.Errorf("expected created at %v, got %v", want, text.CreatedAt)
+	}
+	if want := coreDataEpoch.Add(700000100 * time.Second); !text.LastUsed.Equal(want) {
+		t.Errorf("expected last used %v, got %v", want, text.LastUsed)
+	}
+}
+
+// copyQWriter writes the Qt data streams CopyQ saves tabs as
+type copyQWriter struct{ bytes.Buffer }
+
+func (w *copyQWriter) int32(v int32) { binary.Write(&w.Buffer, binary.BigEndian, v) }
+
+func (w *copyQWriter) bytes(b []byte) {
+	w.int32(int32(len(b)))
+	w.Write(b)
+}
+
+func (w *copyQWriter) string(s string) {
+	units := utf16.Encode([]rune(s))
+	w.int32(int32(2 * len(units)))
+	binary.Write(&w.Buffer, binary.BigEndian, units)
+}
+
+func qCompress(t *testing.T, data []byte) []byte {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.BigEndian, uint32(len(data)))
+	zw := zlib.NewWriter(&buf)
+	zw.Write(data)
+	if err := zw.Close(); err != nil {
+		t.Fatalf("failed to compress: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestReadCopyQ(t *testing.T) {
+	var w copyQWriter
+	w.string("CopyQ v3")
+	w.int32(2)
+	// Newest first, with shortened MIME types
+	w.int32(copyQItemFormat)
+	w.int32(2)
+	w.string("3plain")
+	w.Write([]byte{0})
+	w.bytes([]byte("newer"))
+	w.string("3html")
+	w.Write([]byte{1})
+	w.bytes(qCompress(t, []byte("<i>newer</i>")))
+	w.int32(copyQItemFormat)
+	w.int32(1)
+	w.string("text/plain")
+	w.Write([]byte{0})
+	w.bytes([]byte("older"))
+
+	dir := t.TempDir()
+	// "work/notes" in base64, / written as -
+	if err := os.WriteFile(filepath.Join(dir, "copyq_tab_d29yay9ub3Rlcw==.dat"), w.Bytes(), 0o644); err != nil {
+		t.Fatalf("failed to write tab: %v", err)
+	}
+
+	clips, err := Read(CopyQ, dir)
+	if err != nil {
+		t.Fatalf("failed to read history: %v", err)
+	}
+	if len(clips) != 2 {
+		t.Fatalf("expected 2 clips, got %d", len(clips))
+	}
+	if string(clips[0].Content) != "older" || string(clips[1].Content) != "newer" {
+		t.Errorf("expected oldest first, got %q, %q", clips[0].Content, clips[1].Content)
+	}
+	if string(clips[1].Metadata.Formats["text/html"]) != "<i>newer</i>" {
+		t.Errorf("expected the compressed HTML format to be kept, got %v", clips[1].Metadata.Formats)
+	}
+	if tags := clips[1].Metadata.Tags; len(tags) != 1 || tags[0] != "work/notes" {
+		t.Errorf("expected the tab as a tag, got %v", tags)
+	}
+}
+
+func TestReadCopyQ_Encrypted(t *testing.T) {
+	var w copyQWriter
+	w.string(copyQEncryptedTab)
+	path := filepath.Join(t.TempDir(), "copyq_tab_&clipboard.dat")
+	if err := os.WriteFile(path, w.Bytes(), 0o644); err != nil {
+		t.Fatalf("failed to write tab: %v", err)
+	}
+	if _, err := Read(CopyQ, path); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("expected ErrUnsupported, got %v", err)
+	}
+}
+
+func TestReadClipy(t *testing.T) {
+	// An archived CPYClipData of a string, written by plistlib
+	clips, err := Read(Clipy, filepath.Join("testdata", "clipy.data"))
+	if err != nil {
+		t.Fatalf("failed to read history: %v", err)
+	}
+	if len(clips) != 1 {
+		t.Fatalf("expected 1 clip, got %d", len(clips))
+	}
+	if clips[0].Type != "text/plain" || string(clips[0].Content) != "hello from clipy" {
+		t.Errorf("unexpected clip %q %q", clips[0].Type, clips[0].Content)
+	}
+}
+
+func TestRead_Unsupported(t *testing.T) {
+	if _, err := Read(Paste, ""); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("expected ErrUnsupported for Paste, got %v", err)
+	}
+	if _, err := Read("ditto", ""); !errors.Is(err, ErrUnknownSource) {
+		t.Errorf("expected ErrUnknownSource, got %v", err)
+	}
+}
@@ -0,0 +1,91 @@
+package importer
+
+import (
+	"clipboard-manager/internal/storage"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// coreDataEpoch is when Core Data timestamps, in seconds, start
+var coreDataEpoch = time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// readMaccy reads Maccy's Core Data store: an item per clip, with a row of
+// content for each pasteboard type copied
+func readMaccy(path string) ([]storage.ImportedClip, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("failed to open Maccy history: %w", err)
+	}
+	db, err := sql.Open("sqlite3", "file:"+(&url.URL{Path: path}).EscapedPath()+"?mode=ro")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open Maccy history: %w", err)
+	}
+	defer db.Close()
+
+	// Core Data declares dates as TIMESTAMP, which the driver would parse
+	// as times rather than the seconds they are
+	rows, err := db.Query(`
+		SELECT i.Z_PK, i.ZAPPLICATION, CAST(i.ZFIRSTCOPIEDAT AS REAL), CAST(i.ZLASTCOPIEDAT AS REAL),
+			i.ZNUMBEROFCOPIES, c.ZTYPE, c.ZVALUE
+		FROM ZHISTORYITEM i JOIN ZHISTORYITEMCONTENT c ON c.ZITEM = i.Z_PK
+		ORDER BY i.Z_PK`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Maccy history: %w", err)
+	}
+	defer rows.Close()
+
+	type item struct {
+		app         string
+		first, last float64
+		copies      int64
+		formats     map[string][]byte
+	}
+	var order []int64
+	items := make(map[int64]*item)
+	for rows.Next() {
+		var (
+			id               int64
+			app, contentType sql.NullString
+			first, last      sql.NullFloat64
+			copies           sql.NullInt64
+			value            []byte
+		)
+		if err := rows.Scan(&id, &app, &first, &last, &copies, &contentType, &value); err != nil {
+			return nil, fmt.Errorf("failed to read Maccy history: %w", err)
+		}
+		it, ok := items[id]
+		if !ok {
+			it = &item{app: app.String, first: first.Float64, last: last.Float64, copies: copies.Int64, formats: make(map[string][]byte)}
+			items[id] = it
+			order = append(order, id)
+		}
+		it.formats[contentType.String] = value
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read Maccy history: %w", err)
+	}
+
+	clips := make([]storage.ImportedClip, 0, len(order))
+	for _, id := range order {
+		it := items[id]
+		clip, ok := fromFormats(it.formats)
+		if !ok {
+			continue
+		}
+		clip.Metadata.SourceBundleID = it.app
+		clip.CreatedAt = coreDataTime(it.first)
+		clip.LastUsed = coreDataTime(it.last)
+		clip.UseCount = int(it.copies)
+		clips = append(clips, clip)
+	}
+	return clips, nil
+}
+
+// coreDataTime converts a Core Data timestamp
+func coreDataTime(seconds float64) time.Time {
+	return coreDataEpoch.Add(time.Duration(seconds * float64(time.Second))).Local()
+}
@@ -0,0 +1,302 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"unicode/utf16"
+)
+
+// errInvalidPlist is returned for data that isn't a binary property list
+var errInvalidPlist = errors.New("invalid binary property list")
+
+// plistUID is a reference to an object in a keyed archive
+type plistUID uint64
+
+// parseBinaryPlist decodes a binary property list into nil, bool, int64,
+// float64, []byte, string, plistUID, []interface{} and
+// map[string]interface{} values. Dates decode as float64 seconds since 2001.
+func parseBinaryPlist(data []byte) (interface{}, error) {
+	if len(data) < 8+32 || !bytes.HasPrefix(data, []byte("bplist00")) {
+		return nil, errInvalidPlist
+	}
+	trailer := data[len(data)-32:]
+	p := &plistParser{
+		data:       data,
+		offsetSize: int(trailer[6]),
+		refSize:    int(trailer[7]),
+		numObjects: binary.BigEndian.Uint64(trailer[8:]),
+		tableStart: binary.BigEndian.Uint64(trailer[24:]),
+	}
+	top := binary.BigEndian.Uint64(trailer[16:])
+	if p.offsetSize == 0 || p.refSize == 0 || p.numObjects > uint64(len(data)) ||
+		p.tableStart+p.numObjects*uint64(p.offsetSize) > uint64(len(data)) {
+		return nil, errInvalidPlist
+	}
+	return p.object(top, 0)
+}
+
+// maxPlistDepth bounds nesting, so a list that refers to itself can't
+// recurse forever
+const maxPlistDepth = 64
+
+type plistParser struct {
+	data       []byte
+	offsetSize int
+	refSize    int
+	numObjects uint64
+	tableStart uint64
+}
+
+// uint reads a big endian unsigned integer of size bytes at offset
+func (p *plistParser) uint(offset uint64, size int) (uint64, error) {
+	if offset+uint64(size) > uint64(len(p.data)) || size > 8 {
+		return 0, errInvalidPlist
+	}
+	var v uint64
+	for _, b := range p.data[offset : offset+uint64(size)] {
+		v = v<<8 | uint64(b)
+	}
+	return v, nil
+}
+
+// object decodes the object numbered ref
+func (p *plistParser) object(ref uint64, depth int) (interface{}, error) {
+	if ref >= p.numObjects || depth > maxPlistDepth {
+		return nil, errInvalidPlist
+	}
+	offset, err := p.uint(p.tableStart+ref*uint64(p.offsetSize), p.offsetSize)
+	if err != nil || offset >= uint64(len(p.data)) {
+		return nil, errInvalidPlist
+	}
+	marker := p.data[offset]
+	kind, info := marker>>4, int(marker&0x0F)
+	offset++
+
+	switch kind {
+	case 0x0:
+		switch marker {
+		case 0x08:
+			return false, nil
+		case 0x09:
+			return true, nil
+		}
+		return nil, nil
+	case 0x1:
+		v, err := p.uint(offset, 1<<info)
+		return int64(v), err
+	case 0x2, 0x3:
+		size := 1 << info
+		if kind == 0x3 {
+			size = 8
+		}
+		v, err := p.uint(offset, size)
+		if err != nil {
+			return nil, err
+		}
+		if size == 4 {
+			return float64(math.Float32frombits(uint32(v))), nil
+		}
+		return math.Float64frombits(v), nil
+	case 0x8:
+		v, err := p.uint(offset, info+1)
+		return plistUID(v), err
+	}
+
+	// The rest have a length, which doesn't fit in the marker past 14
+	length := uint64(info)
+	if info == 0x0F {
+		if offset >= uint64(len(p.data)) || p.data[offset]>>4 != 0x1 {
+			return nil, errInvalidPlist
+		}
+		size := 1 << (p.data[offset] & 0x0F)
+		if length, err = p.uint(offset+1, size); err != nil {
+			return nil, err
+		}
+		offset += 1 + uint64(size)
+	}
+
+	switch kind {
+	case 0x4, 0x5:
+		if offset+length > uint64(len(p.data)) {
+			return nil, errInvalidPlist
+		}
+		b := p.data[offset : offset+length]
+		if kind == 0x5 {
+			return string(b), nil
+		}
+		return append([]byte(nil), b...), nil
+	case 0x6:
+		if offset+2*length > uint64(len(p.data)) {
+			return nil, errInvalidPlist
+		}
+		units := make([]uint16, length)
+		for i := range units {
+			units[i] = binary.BigEndian.Uint16(p.data[offset+2*uint64(i):])
+		}
+		return string(utf16.Decode(units)), nil
+	case 0xA, 0xC:
+		values := make([]interface{}, length)
+		for i := range values {
+			ref, err := p.uint(offset+uint64(i*p.refSize), p.refSize)
+			if err != nil {
+				return nil, err
+			}
+			if values[i], err = p.object(ref, depth+1); err != nil {
+				return nil, err
+			}
+		}
+		return values, nil
+	case 0xD:
+		dict := make(map[string]interface{}, length)
+		for i := uint64(0); i < length; i++ {
+			keyRef, err := p.uint(offset+i*uint64(p.refSize), p.refSize)
+			if err != nil {
+				return nil, err
+			}
+			valueRef, err := p.uint(offset+(length+i)*uint64(p.refSize), p.refSize)
+			if err != nil {
+				return nil, err
+			}
+			key, err := p.object(keyRef, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			value, err := p.object(valueRef, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			dict[fmt.Sprint(key)] = value
+		}
+		return dict, nil
+	}
+	return nil, errInvalidPlist
+}
+
+// keyedArchive is an NSKeyedArchiver archive: objects that refer to each
+// other by their index
+type keyedArchive struct {
+	objects []interface{}
+	root    interface{}
+}
+
+// parseKeyedArchive decodes an NSKeyedArchiver archive
+func parseKeyedArchive(data []byte) (*keyedArchive, error) {
+	v, err := parseBinaryPlist(data)
+	if err != nil {
+		return nil, err
+	}
+	top, _ := v.(map[string]interface{})
+	objects, _ := top["$objects"].([]interface{})
+	roots, _ := top["$top"].(map[string]interface{})
+	if objects == nil || roots == nil {
+		return nil, fmt.Errorf("%w: not a keyed archive", errInvalidPlist)
+	}
+	a := &keyedArchive{objects: objects}
+	a.root = a.resolve(roots["root"])
+	return a, nil
+}
+
+// resolve follows a reference, returning nil for $null
+func (a *keyedArchive) resolve(v interface{}) interface{} {
+	if uid, ok := v.(plistUID); ok {
+		if uint64(uid) >= uint64(len(a.objects)) {
+			return nil
+		}
+		v = a.objects[uid]
+	}
+	if s, ok := v.(string); ok && s == "$null" {
+		return nil
+	}
+	return v
+}
+
+// field returns the value of the first of keys an archived object has
+func (a *keyedArchive) field(object interface{}, keys ...string) interface{} {
+	dict, _ := object.(map[string]interface{})
+	for _, key := range keys {
+		if v, ok := dict[key]; ok {
+			return a.resolve(v)
+		}
+	}
+	return nil
+}
+
+// string returns an archived string
+func (a *keyedArchive) string(v interface{}) (string, bool) {
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case map[string]interface{}:
+		return a.string(a.field(v, "NS.string"))
+	}
+	return "", false
+}
+
+// data returns archived bytes
+func (a *keyedArchive) data(v interface{}) []byte {
+	switch v := v.(type) {
+	case []byte:
+		return v
+	case map[string]interface{}:
+		b, _ := a.field(v, "NS.data").([]byte)
+		return b
+	}
+	return nil
+}
+
+// strings returns an archived array of strings
+func (a *keyedArchive) strings(v interface{}) []string {
+	items, _ := a.field(v, "NS.objects").([]interface{})
+	var out []string
+	for _, item := range items {
+		if s, ok := a.string(a.resolve(item)); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// find returns the first bytes reachable from v that match, such as the
+// image data somewhere inside an archived NSImage
+func (a *keyedArchive) find(v interface{}, match func([]byte) bool) []byte {
+	seen := make(map[plistUID]bool)
+	var walk func(v interface{}, depth int) []byte
+	walk = func(v interface{}, depth int) []byte {
+		if depth > maxPlistDepth {
+			return nil
+		}
+		if uid, ok := v.(plistUID); ok {
+			if seen[uid] {
+				return nil
+			}
+			seen[uid] = true
+			v = a.resolve(uid)
+		}
+		switch v := v.(type) {
+		case []byte:
+			if match(v) {
+				return v
+			}
+		case []interface{}:
+			for _, item := range v {
+				if b := walk(item, depth+1); b != nil {
+					return b
+				}
+			}
+		case map[string]interface{}:
+			for key, item := range v {
+				if key == "$class" {
+					continue
+				}
+				if b := walk(item, depth+1); b != nil {
+					return b
+				}
+			}
+		}
+		return nil
+	}
+	return walk(v, 0)
+}
@@ -0,0 +1,174 @@
+// Package media describes audio and video files copied to the clipboard,
+// using ffprobe and ffmpeg when they are installed
+package media
+
+import (
+	"bytes"
+	"clipboard-manager/pkg/types"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/url"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Kinds of media
+const (
+	Audio = "audio"
+	Video = "video"
+)
+
+// probeTimeout bounds each ffprobe and ffmpeg run, so a file on a slow or
+// hung network share doesn't hold up capture
+const probeTimeout = 10 * time.Second
+
+// ErrNotInstalled is returned when ffprobe or ffmpeg can't be found
+var ErrNotInstalled = errors.New("not installed")
+
+// extensionTypes covers media extensions the system MIME table may not know
+var extensionTypes = map[string]string{
+	".mp4": "video/mp4", ".m4v": "video/x-m4v", ".mov": "video/quicktime",
+	".mkv": "video/x-matroska", ".webm": "video/webm", ".avi": "video/x-msvideo",
+	".mp3": "audio/mpeg", ".m4a": "audio/mp4", ".aac": "audio/aac",
+	".wav": "audio/wav", ".flac": "audio/flac", ".ogg": "audio/ogg",
+	".opus": "audio/opus", ".aif": "audio/aiff", ".aiff": "audio/aiff",
+}
+
+// TypeOf returns the MIME type of an audio or video file from its name, or
+// "" if it is not media
+func TypeOf(path string) string {
+	ext := strings.ToLower(filepath.Ext(path))
+	mimeType, ok := extensionTypes[ext]
+	if !ok {
+		mimeType, _, _ = strings.Cut(mime.TypeByExtension(ext), ";")
+	}
+	if Kind(mimeType) == "" {
+		return ""
+	}
+	return mimeType
+}
+
+// Kind returns Audio or Video for a media MIME type, or "" for anything else
+func Kind(mimeType string) string {
+	major, _, _ := strings.Cut(mimeType, "/")
+	if major == Audio || major == Video {
+		return major
+	}
+	return ""
+}
+
+// FilePath returns the local path of a file clip's URL
+func FilePath(fileURL string) (string, bool) {
+	parsed, err := url.Parse(strings.TrimSpace(fileURL))
+	if err != nil || parsed.Scheme != "file" || parsed.Path == "" {
+		return "", false
+	}
+	return parsed.Path, true
+}
+
+// Describe returns what can be found out about the media file at path, or nil
+// if it is not audio or video. Without ffprobe only the type is known; the
+// error says why the rest is missing.
+func Describe(ctx context.Context, path string) (*types.Media, error) {
+	mimeType := TypeOf(path)
+	if mimeType == "" {
+		return nil, nil
+	}
+	info := &types.Media{Kind: Kind(mimeType), MIMEType: mimeType}
+
+	output, err := run(ctx, "ffprobe", "-v", "error", "-print_format", "json",
+		"-show_format", "-show_streams", path)
+	if err != nil {
+		return info, err
+	}
+	if err := parseProbe(output, info); err != nil {
+		return info, err
+	}
+	return info, nil
+}
+
+// Poster returns the frame a second into a video, or its first frame for
+// shorter videos, as a PNG at most size pixels wide
+func Poster(ctx context.Context, path string, duration float64, size int) ([]byte, error) {
+	args := []string{"-v", "error"}
+	if duration > 2 {
+		args = append(args, "-ss", "1")
+	}
+	args = append(args, "-i", path, "-frames:v", "1",
+		"-vf", fmt.Sprintf("scale='min(%d,iw)':-2", size),
+		"-f", "image2pipe", "-vcodec", "png", "-")
+	poster, err := run(ctx, "ffmpeg", args...)
+	if err != nil {
+		return nil, err
+	}
+	if len(poster) == 0 {
+		return nil, errors.New("ffmpeg returned no frame")
+	}
+	return poster, nil
+}
+
+// run runs a tool and returns its standard output
+func run(ctx context.Context, tool string, args ...string) ([]byte, error) {
+	path, err := exec.LookPath(tool)
+	if err != nil {
+		return nil, fmt.Errorf("%s %w", tool, ErrNotInstalled)
+	}
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, path, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s failed: %s", tool, msg)
+		}
+		return nil, fmt.Errorf("%s failed: %w", tool, err)
+	}
+	return stdout.Bytes(), nil
+}
+
+// probeOutput is the part of ffprobe's JSON output we use
+type probeOutput struct {
+	Format struct {
+		Duration string `json:"duration"`
+	} `json:"format"`
+	Streams []struct {
+		CodecType   string `json:"codec_type"`
+		CodecName   string `json:"codec_name"`
+		Width       int    `json:"width"`
+		Height      int    `json:"height"`
+		Disposition struct {
+			AttachedPic int `json:"attached_pic"`
+		} `json:"disposition"`
+	} `json:"streams"`
+}
+
+// parseProbe fills in info from ffprobe's JSON output. Cover art in audio
+// files is a video stream too, so it doesn't make them videos.
+func parseProbe(output []byte, info *types.Media) error {
+	var probe probeOutput
+	if err := json.Unmarshal(output, &probe); err != nil {
+		return fmt.Errorf("invalid ffprobe output: %w", err)
+	}
+	if duration, err := strconv.ParseFloat(probe.Format.Duration, 64); err == nil {
+		info.Duration = duration
+	}
+	for _, stream := range probe.Streams {
+		switch {
+		case stream.CodecType == "video" && stream.Disposition.AttachedPic == 0 && info.Width == 0:
+			info.Width, info.Height = stream.Width, stream.Height
+			info.VideoCodec = stream.CodecName
+		case stream.CodecType == "audio" && info.AudioCodec == "":
+			info.AudioCodec = stream.CodecName
+		}
+	}
+	return nil
+}
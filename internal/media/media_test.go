@@ -0,0 +1,51 @@
+package media
+
+import (
+	"clipboard-manager/pkg/types"
+	"testing"
+)
+
+func TestTypeOf(t *testing.T) {
+	tests := map[string]string{
+		"/Users/me/Movies/clip.MOV": "video/quicktime",
+		"song.mp3":                  "audio/mpeg",
+		"voice memo.m4a":            "audio/mp4",
+		"notes.txt":                 "",
+		"photo.png":                 "",
+		"no-extension":              "",
+	}
+	for path, want := range tests {
+		if got := TypeOf(path); got != want {
+			t.Errorf("TypeOf(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestFilePath(t *testing.T) {
+	if path, ok := FilePath("file:///Users/me/My%20Movies/a.mp4\n"); !ok || path != "/Users/me/My Movies/a.mp4" {
+		t.Errorf("FilePath = %q, %v", path, ok)
+	}
+	if _, ok := FilePath("https://example.com/a.mp4"); ok {
+		t.Error("web URLs are not local files")
+	}
+}
+
+func TestParseProbe(t *testing.T) {
+	output := `{
+		"streams": [
+			{"codec_type": "video", "codec_name": "mjpeg", "width": 600, "height": 600, "disposition": {"attached_pic": 1}},
+			{"codec_type": "audio", "codec_name": "aac"},
+			{"codec_type": "video", "codec_name": "h264", "width": 1920, "height": 1080, "disposition": {"attached_pic": 0}}
+		],
+		"format": {"duration": "12.480000"}
+	}`
+	var info types.Media
+	if err := parseProbe([]byte(output), &info); err != nil {
+		t.Fatalf("parseProbe: %v", err)
+	}
+	want := types.Media{Duration: 12.48, Width: 1920, Height: 1080, VideoCodec: "h264", AudioCodec: "aac"}
+	if info.Duration != want.Duration || info.Width != want.Width || info.Height != want.Height ||
+		info.VideoCodec != want.VideoCodec || info.AudioCodec != want.AudioCodec {
+		t.Errorf("parseProbe = %+v, want %+v", info, want)
+	}
+}
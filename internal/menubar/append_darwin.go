@@ -0,0 +1,36 @@
+package menubar
+
+import (
+	"clipboard-manager/internal/service"
+	"log"
+
+	"github.com/progrium/darwinkit/macos/appkit"
+	"github.com/progrium/darwinkit/objc"
+)
+
+const (
+	keyC            = 8
+	appendHotKeyMod = appkit.EventModifierFlagControl | appkit.EventModifierFlagOption | appkit.EventModifierFlagCommand
+)
+
+// watchAppendHotKey toggles append mode on ⌃⌥⌘C in any app. Like the picker
+// hotkey, it needs the Accessibility permission.
+func watchAppendHotKey(svc *service.ClipboardService) {
+	appkit.Event_AddGlobalMonitorForEventsMatchingMaskHandler(appkit.EventMaskKeyDown, func(event appkit.Event) {
+		if !isAppendHotKey(event) {
+			return
+		}
+		status := svc.ToggleAppend()
+		if status.Active {
+			log.Printf("Menu bar: appending copies")
+		} else {
+			log.Printf("Menu bar: saved %d appended copies", status.Clips)
+		}
+	})
+}
+
+// isAppendHotKey reports whether event is ⌃⌥⌘C
+func isAppendHotKey(event appkit.Event) bool {
+	flags := objc.Call[appkit.EventModifierFlags](event, objc.Sel("modifierFlags"))
+	return event.KeyCode() == keyC && flags&appkit.EventModifierFlagDeviceIndependentFlagsMask == appendHotKeyMod
+}
@@ -0,0 +1,103 @@
+package menubar
+
+import (
+	"clipboard-manager/internal/clipboard"
+	"clipboard-manager/pkg/types"
+
+	"github.com/progrium/darwinkit/macos/appkit"
+	"github.com/progrium/darwinkit/macos/foundation"
+	"github.com/progrium/darwinkit/objc"
+)
+
+// dragOffset staggers the images of a drag of several files
+const dragOffset = 4
+
+// dragSource is the NSDraggingSource of a clip being dragged out of the
+// picker. Drops copy the clip, leaving it in history.
+type dragSource struct {
+	object objc.Object // Its Objective-C counterpart, kept until the drag ends
+	ended  func(operation appkit.DragOperation)
+}
+
+func (d *dragSource) DraggingSessionSourceOperationMaskForDraggingContext(session appkit.DraggingSession, context appkit.DraggingContext) appkit.DragOperation {
+	if context == appkit.DraggingContextOutsideApplication {
+		return appkit.DragOperationCopy
+	}
+	return appkit.DragOperationNone
+}
+
+func (d *dragSource) HasDraggingSessionSourceOperationMaskForDraggingContext() bool {
+	return true
+}
+
+func (d *dragSource) DraggingSessionEndedAtPointOperation(session appkit.DraggingSession, screenPoint foundation.Point, operation appkit.DragOperation) {
+	if d.ended != nil {
+		d.ended(operation)
+	}
+	d.object.Release()
+}
+
+func (d *dragSource) HasDraggingSessionEndedAtPointOperation() bool {
+	return true
+}
+
+func (d *dragSource) DraggingSessionWillBeginAtPoint(appkit.DraggingSession, foundation.Point) {}
+
+func (d *dragSource) HasDraggingSessionWillBeginAtPoint() bool { return false }
+
+func (d *dragSource) DraggingSessionMovedToPoint(appkit.DraggingSession, foundation.Point) {}
+
+func (d *dragSource) HasDraggingSessionMovedToPoint() bool { return false }
+
+func (d *dragSource) IgnoreModifierKeysForDraggingSession(appkit.DraggingSession) bool { return false }
+
+func (d *dragSource) HasIgnoreModifierKeysForDraggingSession() bool { return false }
+
+// beginDrag starts dragging clip out of view, in response to event, with
+// every representation it would have on the pasteboard, so it can be
+// dropped into a mail, a document or Finder. ended is called once it is
+// dropped or cancelled, with DragOperationNone if it was cancelled.
+func beginDrag(view appkit.View, event appkit.Event, clip *types.Clip, ended func(operation appkit.DragOperation)) error {
+	items, err := clipboard.PasteboardItems(*clip)
+	if err != nil {
+		return err
+	}
+
+	image := dragImage(view, clip)
+	frame := view.Bounds()
+	dragItems := make([]appkit.IDraggingItem, 0, len(items))
+	for i, item := range items {
+		dragItem := appkit.NewDraggingItemWithPasteboardWriter(appkit.PasteboardWritingObject{Object: item.Object})
+		itemFrame := frame
+		itemFrame.Origin.X += float64(i * dragOffset)
+		itemFrame.Origin.Y -= float64(i * dragOffset)
+		dragItem.SetDraggingFrameContents(itemFrame, image)
+		dragItems = append(dragItems, dragItem)
+	}
+
+	// Nothing on the Go side refers to the wrapped source once this
+	// returns, so it is retained until the drag ends
+	source := &dragSource{ended: ended}
+	source.object = objc.WrapAsProtocol[appkit.PDraggingSource]("NSDraggingSource", source)
+	objc.Retain(&source.object)
+	session := view.BeginDraggingSessionWithItemsEventSourceObject(dragItems, event, source.object)
+	session.SetAnimatesToStartingPositionsOnCancelOrFail(true)
+	return nil
+}
+
+// dragImage returns what is shown under the pointer while dragging: the
+// image itself for image clips, otherwise a snapshot of the view dragged
+func dragImage(view appkit.View, clip *types.Clip) appkit.Image {
+	if types.IsImage(clip.Type) {
+		if image := appkit.NewImageWithData(clip.Content); !image.IsNil() {
+			image.SetSize(view.Bounds().Size)
+			return image
+		}
+	}
+	bounds := view.Bounds()
+	rep := view.BitmapImageRepForCachingDisplayInRect(bounds)
+	view.CacheDisplayInRectToBitmapImageRep(bounds, rep)
+	image := appkit.NewImageWithSize(bounds.Size)
+	image.AddRepresentation(rep)
+	return image
+}
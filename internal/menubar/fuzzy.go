@@ -0,0 +1,97 @@
+package menubar
+
+import (
+	"clipboard-manager/pkg/types"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// maxSearchText bounds how much of a clip the picker matches against
+const maxSearchText = 4096
+
+// fuzzyScore reports whether the runes of query appear in text in order,
+// ignoring case, and scores the match. Consecutive runes and runes at the
+// start of a word score higher, so "gco" ranks "git checkout" above "logcontrol".
+func fuzzyScore(query, text string) (int, bool) {
+	query = strings.ToLower(query)
+	if query == "" {
+		return 0, true
+	}
+
+	want := []rune(query)
+	score, first, qi := 0, -1, 0
+	prevMatched := false
+	prev := ' '
+	for i, r := range []rune(strings.ToLower(text)) {
+		if qi == len(want) {
+			break
+		}
+		if r != want[qi] {
+			prevMatched = false
+			prev = r
+			continue
+		}
+
+		score++
+		if prevMatched {
+			score += 5
+		}
+		if !unicode.IsLetter(prev) && !unicode.IsDigit(prev) {
+			score += 8
+		}
+		if first < 0 {
+			first = i
+		}
+		qi++
+		prevMatched = true
+		prev = r
+	}
+	if qi < len(want) {
+		return 0, false
+	}
+
+	// Prefer matches near the start
+	if first > 10 {
+		first = 10
+	}
+	return score - first, true
+}
+
+// fuzzyFilter returns the clips matching query, best match first. Clips that
+// score the same keep their order, so recent clips win ties.
+func fuzzyFilter(query string, clips []*types.Clip) []*types.Clip {
+	type match struct {
+		clip  *types.Clip
+		score int
+	}
+
+	matches := make([]match, 0, len(clips))
+	for _, clip := range clips {
+		if score, ok := fuzzyScore(query, searchText(clip)); ok {
+			matches = append(matches, match{clip, score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].score > matches[j].score
+	})
+
+	result := make([]*types.Clip, len(matches))
+	for i, m := range matches {
+		result[i] = m.clip
+	}
+	return result
+}
+
+// searchText is what the picker matches a query against: the text of text
+// clips and the menu title of everything else
+func searchText(clip *types.Clip) string {
+	if !strings.HasPrefix(clip.Type, "text/") || clip.Type == "text/rtf" {
+		return clipTitle(clip)
+	}
+	text := string(clip.Content)
+	if len(text) > maxSearchText {
+		text = text[:maxSearchText]
+	}
+	return text
+}
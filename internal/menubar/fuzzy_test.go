@@ -0,0 +1,50 @@
+package menubar
+
+import (
+	"clipboard-manager/pkg/types"
+	"testing"
+)
+
+func TestFuzzyScore(t *testing.T) {
+	if _, ok := fuzzyScore("gcx", "git checkout"); ok {
+		t.Error("matched runes missing from the text")
+	}
+	if _, ok := fuzzyScore("GCO", "git checkout"); !ok {
+		t.Error("match should ignore case")
+	}
+
+	wordStart, _ := fuzzyScore("gco", "git checkout")
+	scattered, _ := fuzzyScore("gco", "logcontrol")
+	if wordStart <= scattered {
+		t.Errorf("word start match scored %d, scattered match %d", wordStart, scattered)
+	}
+}
+
+func TestFuzzyFilter(t *testing.T) {
+	clips := []*types.Clip{
+		{ID: "1", Type: "text/plain", Content: []byte("hello world")},
+		{ID: "2", Type: "text/plain", Content: []byte("go test ./...")},
+		{ID: "3", Type: "image/png", Content: []byte{0x89}},
+		{ID: "4", Type: "text/plain", Content: []byte("git stash")},
+	}
+
+	got := fuzzyFilter("gst", clips)
+	if len(got) != 2 || got[0].ID != "4" || got[1].ID != "2" {
+		t.Errorf("fuzzyFilter(gst) = %v", ids(got))
+	}
+
+	if got := fuzzyFilter("image", clips); len(got) != 1 || got[0].ID != "3" {
+		t.Errorf("fuzzyFilter(image) = %v", ids(got))
+	}
+	if got := fuzzyFilter("", clips); len(got) != len(clips) || got[0].ID != "1" {
+		t.Errorf("empty query should keep every clip in order, got %v", ids(got))
+	}
+}
+
+func ids(clips []*types.Clip) []string {
+	result := make([]string, len(clips))
+	for i, clip := range clips {
+		result[i] = clip.ID
+	}
+	return result
+}
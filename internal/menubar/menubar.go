@@ -0,0 +1,38 @@
+// Package menubar shows recent clips in a macOS status bar menu
+package menubar
+
+import (
+	"clipboard-manager/internal/clipboard"
+	"clipboard-manager/pkg/types"
+)
+
+// DefaultItems is how many recent clips the menu lists by default
+const DefaultItems = 10
+
+// maxTitleLength keeps menu items from stretching across the screen
+const maxTitleLength = 50
+
+// Config holds the menu bar settings
+type Config struct {
+	Items int // Number of recent clips to list
+	// AppBundleID is the app opened by the "Open Clipboard Manager" item
+	AppBundleID string
+	// Picker enables the quick picker on ⇧⌘V. The app registers the same
+	// hotkey, so only enable it when the app isn't running.
+	Picker bool
+}
+
+func (c Config) withDefaults() Config {
+	if c.Items <= 0 {
+		c.Items = DefaultItems
+	}
+	if c.AppBundleID == "" {
+		c.AppBundleID = "com.hp77.ClipboardManager"
+	}
+	return c
+}
+
+// clipTitle returns a one line summary of a clip for its menu item
+func clipTitle(clip *types.Clip) string {
+	return clipboard.Title(clip, maxTitleLength)
+}
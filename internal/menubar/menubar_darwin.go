@@ -0,0 +1,159 @@
+package menubar
+
+import (
+	"clipboard-manager/internal/service"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+
+	"github.com/progrium/darwinkit/dispatch"
+	"github.com/progrium/darwinkit/macos"
+	"github.com/progrium/darwinkit/macos/appkit"
+	"github.com/progrium/darwinkit/macos/foundation"
+	"github.com/progrium/darwinkit/objc"
+)
+
+// menuBar owns the status item and rebuilds its menu each time it opens
+type menuBar struct {
+	svc      *service.ClipboardService
+	config   Config
+	app      appkit.Application
+	item     appkit.StatusItem
+	delegate *appkit.MenuDelegate
+	picker   *picker
+}
+
+// Run shows the status bar item and runs the AppKit event loop. It must be
+// called from the main goroutine and does not return: Quit, SIGINT and
+// SIGTERM call shutdown and then exit the process.
+func Run(svc *service.ClipboardService, config Config, shutdown func()) error {
+	m := &menuBar{svc: svc, config: config.withDefaults()}
+
+	macos.RunApp(func(app appkit.Application, delegate *appkit.ApplicationDelegate) {
+		m.app = app
+		// No dock icon or app menu, only the status item
+		app.SetActivationPolicy(appkit.ApplicationActivationPolicyAccessory)
+
+		delegate.SetApplicationWillTerminate(func(foundation.Notification) {
+			shutdown()
+		})
+
+		m.item = appkit.StatusBar_SystemStatusBar().StatusItemWithLength(appkit.VariableStatusItemLength)
+		objc.Retain(&m.item)
+		m.item.Button().SetImage(appkit.Image_ImageWithSystemSymbolNameAccessibilityDescription("doc.on.clipboard", "Clipboard history"))
+
+		menu := appkit.NewMenuWithTitle("Clipboard")
+		menu.SetAutoenablesItems(false)
+		m.delegate = &appkit.MenuDelegate{}
+		m.delegate.SetMenuNeedsUpdate(m.update)
+		menu.SetDelegate(m.delegate)
+		m.item.SetMenu(menu)
+
+		if m.config.Picker {
+			m.picker = newPicker(svc)
+		}
+		watchPastes(svc)
+		watchAppendHotKey(svc)
+
+		// Terminate through AppKit so shutdown runs on signals too
+		sigChan := make(chan os.Signal, 1)
+		signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+		go func() {
+			<-sigChan
+			dispatch.MainQueue().DispatchAsync(func() {
+				app.Terminate(nil)
+			})
+		}()
+	})
+	return nil
+}
+
+// update rebuilds the menu with the latest clips and pause state
+func (m *menuBar) update(menu appkit.Menu) {
+	menu.RemoveAllItems()
+
+	clips, err := m.svc.GetClips(context.Background(), m.config.Items, 0)
+	if err != nil {
+		log.Printf("Menu bar: failed to load clips: %v", err)
+	}
+	if len(clips) == 0 {
+		empty := appkit.NewMenuItemWithAction("No clips yet", "", func(objc.Object) {})
+		empty.SetEnabled(false)
+		menu.AddItem(empty)
+	}
+	for i, clip := range clips {
+		id := clip.ID
+		key := ""
+		if i < 9 {
+			key = fmt.Sprint(i + 1)
+		}
+		item := appkit.NewMenuItemWithAction(clipTitle(clip), key, func(objc.Object) {
+			// Writing the pasteboard waits on the monitor, so keep it off the main thread
+			go func() {
+				if err := m.svc.PasteByID(context.Background(), id); err != nil {
+					log.Printf("Menu bar: failed to paste clip %s: %v", id, err)
+				}
+			}()
+		})
+		if clip.Metadata.SourceApp != "" && clip.Metadata.SourceWindow != "" {
+			item.SetToolTip("Copied from " + clip.Metadata.SourceApp + " (" + clip.Metadata.SourceWindow + ")")
+		} else if clip.Metadata.SourceApp != "" {
+			item.SetToolTip("Copied from " + clip.Metadata.SourceApp)
+		}
+		menu.AddItem(item)
+	}
+
+	menu.AddItem(appkit.MenuItem_SeparatorItem())
+
+	paused, until := m.svc.IsPaused()
+	pauseTitle := "Pause History"
+	if paused && !until.IsZero() {
+		pauseTitle = fmt.Sprintf("Paused until %s", until.Format("15:04"))
+	} else if paused {
+		pauseTitle = "Paused"
+	}
+	pause := appkit.NewMenuItemWithAction(pauseTitle, "p", func(objc.Object) {
+		if paused, _ := m.svc.IsPaused(); paused {
+			m.svc.Resume()
+		} else {
+			m.svc.Pause(0)
+		}
+	})
+	if paused {
+		pause.SetState(appkit.ControlStateValueOn)
+	}
+	menu.AddItem(pause)
+
+	appending := m.svc.AppendMode()
+	appendTitle := "Append Copies"
+	if appending.Active {
+		appendTitle = fmt.Sprintf("Appending Copies (%d)", appending.Clips)
+	}
+	appendItem := appkit.NewMenuItemWithAction(appendTitle, "a", func(objc.Object) {
+		m.svc.ToggleAppend()
+	})
+	if appending.Active {
+		appendItem.SetState(appkit.ControlStateValueOn)
+	}
+	menu.AddItem(appendItem)
+
+	if m.picker != nil {
+		menu.AddItem(appkit.NewMenuItemWithAction("Search History…", "f", func(objc.Object) {
+			m.picker.show()
+		}))
+	}
+	menu.AddItem(appkit.NewMenuItemWithAction("Open Clipboard Manager", "o", func(objc.Object) {
+		if err := exec.Command("open", "-b", m.config.AppBundleID).Run(); err != nil {
+			log.Printf("Menu bar: failed to open %s: %v", m.config.AppBundleID, err)
+		}
+	}))
+
+	menu.AddItem(appkit.MenuItem_SeparatorItem())
+	menu.AddItem(appkit.NewMenuItemWithAction("Quit", "q", func(objc.Object) {
+		m.app.Terminate(nil)
+	}))
+}
@@ -0,0 +1,13 @@
+//go:build !darwin
+
+package menubar
+
+import (
+	"clipboard-manager/internal/service"
+	"fmt"
+)
+
+// Run is only supported on macOS
+func Run(svc *service.ClipboardService, config Config, shutdown func()) error {
+	return fmt.Errorf("menu bar mode is only supported on macOS")
+}
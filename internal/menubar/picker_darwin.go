@@ -0,0 +1,353 @@
+package menubar
+
+import (
+	"clipboard-manager/internal/service"
+	"clipboard-manager/pkg/types"
+	"context"
+	"log"
+
+	"github.com/progrium/darwinkit/dispatch"
+	"github.com/progrium/darwinkit/macos/appkit"
+	"github.com/progrium/darwinkit/macos/foundation"
+	"github.com/progrium/darwinkit/objc"
+)
+
+const (
+	pickerRows  = 9   // Matches shown at once
+	pickerClips = 200 // Recent clips loaded each time the picker opens
+	previewSize = 2000
+
+	pickerWidth  = 640
+	pickerHeight = 380
+	rowHeight    = 34
+)
+
+// Virtual key codes from Carbon's HIToolbox/Events.h
+const (
+	keyV           = 9
+	keyReturn      = 36
+	keyEnter       = 76
+	keyEscape      = 53
+	keyDownArrow   = 125
+	keyUpArrow     = 126
+	hotKeyModifier = appkit.EventModifierFlagCommand | appkit.EventModifierFlagShift
+)
+
+// picker is a floating panel for finding a clip by typing part of it and
+// copying it back with Enter, or dragging it where it's wanted, summoned
+// with ⇧⌘V
+type picker struct {
+	svc     *service.ClipboardService
+	panel   appkit.Panel
+	search  appkit.SearchField
+	rows    []appkit.TextField
+	preview appkit.TextField
+	image   appkit.ImageView
+
+	clips    []*types.Clip // Recent clips, loaded when the picker opens
+	matches  []*types.Clip // Clips matching the search, best first
+	selected int
+	query    string
+	pressed  int  // Match the mouse went down on, -1 if none
+	dragging bool // Whether a clip is being dragged out
+}
+
+// newPicker builds the panel and installs the hotkey. It must be called on
+// the main thread once the application has launched.
+func newPicker(svc *service.ClipboardService) *picker {
+	p := &picker{svc: svc, pressed: -1}
+
+	// A titled panel with a hidden title bar looks borderless but, unlike a
+	// borderless window, can become key and take typing. Being nonactivating,
+	// it does so without taking focus from the app the user will paste into.
+	p.panel = appkit.NewPanelWithContentRectStyleMaskBackingDefer(
+		foundation.Rect{Size: foundation.Size{Width: pickerWidth, Height: pickerHeight}},
+		appkit.WindowStyleMaskTitled|appkit.WindowStyleMaskFullSizeContentView|appkit.WindowStyleMaskNonactivatingPanel,
+		appkit.BackingStoreBuffered, false)
+	objc.Retain(&p.panel)
+	p.panel.SetTitlebarAppearsTransparent(true)
+	p.panel.SetTitleVisibility(appkit.WindowTitleHidden)
+	p.panel.StandardWindowButton(appkit.WindowCloseButton).SetHidden(true)
+	p.panel.StandardWindowButton(appkit.WindowMiniaturizeButton).SetHidden(true)
+	p.panel.StandardWindowButton(appkit.WindowZoomButton).SetHidden(true)
+	p.panel.SetFloatingPanel(true)
+	p.panel.SetLevel(appkit.FloatingWindowLevel)
+	p.panel.SetMovableByWindowBackground(true)
+	p.panel.SetReleasedWhenClosed(false)
+
+	content := p.panel.ContentView()
+
+	p.search = appkit.NewSearchFieldWithFrame(foundation.Rect{
+		Origin: foundation.Point{X: 12, Y: pickerHeight - 40},
+		Size:   foundation.Size{Width: pickerWidth - 24, Height: 28},
+	})
+	p.search.SetPlaceholderString("Search clipboard history")
+	content.AddSubview(p.search)
+
+	top := float64(pickerHeight - 48)
+	for i := 0; i < pickerRows; i++ {
+		row := appkit.TextField_LabelWithString("")
+		row.SetFrame(foundation.Rect{
+			Origin: foundation.Point{X: 12, Y: top - float64(i+1)*rowHeight},
+			Size:   foundation.Size{Width: 300, Height: rowHeight - 4},
+		})
+		row.SetMaximumNumberOfLines(1)
+		row.SetFont(appkit.Font_SystemFontOfSize(13))
+		content.AddSubview(row)
+		p.rows = append(p.rows, row)
+	}
+
+	previewFrame := foundation.Rect{
+		Origin: foundation.Point{X: 324, Y: 12},
+		Size:   foundation.Size{Width: pickerWidth - 336, Height: top - 12},
+	}
+	p.preview = appkit.TextField_WrappingLabelWithString("")
+	p.preview.SetFrame(previewFrame)
+	p.preview.SetTextColor(appkit.Color_SecondaryLabelColor())
+	p.preview.SetFont(appkit.Font_SystemFontOfSize(12))
+	content.AddSubview(p.preview)
+
+	p.image = appkit.NewImageViewWithFrame(previewFrame)
+	p.image.SetImageScaling(appkit.ImageScaleProportionallyDown)
+	p.image.SetHidden(true)
+	content.AddSubview(p.image)
+
+	// The global monitor sees the hotkey in other apps and needs the
+	// Accessibility permission; the local one sees our own key events
+	appkit.Event_AddGlobalMonitorForEventsMatchingMaskHandler(appkit.EventMaskKeyDown, func(event appkit.Event) {
+		if isHotKey(event) {
+			p.toggle()
+		}
+	})
+	appkit.Event_AddLocalMonitorForEventsMatchingMaskHandler(appkit.EventMaskKeyDown, p.handleKey)
+	appkit.Event_AddLocalMonitorForEventsMatchingMaskHandler(appkit.EventMaskLeftMouseDown|appkit.EventMaskLeftMouseDragged, p.handleMouse)
+
+	return p
+}
+
+// isHotKey reports whether event is ⇧⌘V
+func isHotKey(event appkit.Event) bool {
+	flags := objc.Call[appkit.EventModifierFlags](event, objc.Sel("modifierFlags"))
+	return event.KeyCode() == keyV && flags&appkit.EventModifierFlagDeviceIndependentFlagsMask == hotKeyModifier
+}
+
+// toggle shows the picker, or hides it if it is already showing
+func (p *picker) toggle() {
+	if p.panel.IsVisible() {
+		p.hide()
+		return
+	}
+	p.show()
+}
+
+func (p *picker) show() {
+	clips, err := p.svc.GetClips(context.Background(), pickerClips, 0)
+	if err != nil {
+		log.Printf("Picker: failed to load clips: %v", err)
+	}
+	p.clips = clips
+	p.query = ""
+	p.search.SetStringValue("")
+	p.filter()
+
+	p.panel.Center()
+	p.panel.MakeKeyAndOrderFront(nil)
+	p.panel.MakeFirstResponder(p.search)
+}
+
+func (p *picker) hide() {
+	p.panel.OrderOut(nil)
+	// Don't hold on to clip content between uses
+	p.clips, p.matches = nil, nil
+	p.pressed = -1
+}
+
+// handleKey navigates and picks with the keyboard while the picker has focus.
+// Returning an empty event swallows the key.
+func (p *picker) handleKey(event appkit.Event) appkit.Event {
+	if isHotKey(event) {
+		p.toggle()
+		return appkit.Event{}
+	}
+	if !p.panel.IsKeyWindow() {
+		return event
+	}
+
+	switch event.KeyCode() {
+	case keyDownArrow:
+		p.move(1)
+	case keyUpArrow:
+		p.move(-1)
+	case keyReturn, keyEnter:
+		p.pick()
+	case keyEscape:
+		p.hide()
+	default:
+		// Let the search field take the key, then filter on its new text
+		dispatch.MainQueue().DispatchAsync(func() {
+			if query := p.search.StringValue(); query != p.query {
+				p.query = query
+				p.filter()
+			}
+		})
+		return event
+	}
+	return appkit.Event{}
+}
+
+// handleMouse selects the row clicked and drags its clip out of the picker
+// when the mouse moves with the button down
+func (p *picker) handleMouse(event appkit.Event) appkit.Event {
+	if event.Window().Ptr() != p.panel.Ptr() {
+		return event
+	}
+
+	if event.Type() == appkit.EventTypeLeftMouseDown {
+		p.pressed = p.rowAt(event.LocationInWindow())
+		if p.pressed >= 0 {
+			p.selected = p.pressed
+			p.render()
+		}
+		return event
+	}
+
+	row := p.pressed - p.firstRow()
+	if p.pressed < 0 || p.pressed >= len(p.matches) || row < 0 || row >= len(p.rows) || p.dragging {
+		return event
+	}
+	// Dragging needs all of the clip, not what was loaded to list it
+	id := p.matches[p.pressed].ID
+	clip, err := p.svc.GetClipByID(context.Background(), id)
+	if err != nil {
+		log.Printf("Picker: failed to load clip %s to drag: %v", id, err)
+		return event
+	}
+	p.dragging = true
+	err = beginDrag(p.rows[row].View, event, clip, func(operation appkit.DragOperation) {
+		p.dragging, p.pressed = false, -1
+		if operation != appkit.DragOperationNone {
+			p.hide()
+		}
+	})
+	if err != nil {
+		p.dragging = false
+		log.Printf("Picker: failed to drag clip %s: %v", id, err)
+		return event
+	}
+	return appkit.Event{}
+}
+
+// rowAt returns the index of the match shown at point, in window
+// coordinates, or -1. The content view fills the window, so its coordinates
+// are the window's.
+func (p *picker) rowAt(point foundation.Point) int {
+	first := p.firstRow()
+	for i, row := range p.rows {
+		if first+i >= len(p.matches) || row.IsHidden() {
+			continue
+		}
+		frame := row.Frame()
+		if point.X >= frame.Origin.X && point.X < frame.Origin.X+frame.Size.Width &&
+			point.Y >= frame.Origin.Y && point.Y < frame.Origin.Y+frame.Size.Height {
+			return first + i
+		}
+	}
+	return -1
+}
+
+func (p *picker) filter() {
+	p.matches = fuzzyFilter(p.query, p.clips)
+	p.selected = 0
+	p.render()
+}
+
+func (p *picker) move(delta int) {
+	if len(p.matches) == 0 {
+		return
+	}
+	p.selected = (p.selected + delta + len(p.matches)) % len(p.matches)
+	p.render()
+}
+
+// pick copies the selected clip to the clipboard and closes the picker, so
+// the user can paste it into the app they were in
+func (p *picker) pick() {
+	if p.selected >= len(p.matches) {
+		return
+	}
+	id := p.matches[p.selected].ID
+	p.hide()
+
+	// Writing the pasteboard waits on the monitor, so keep it off the main thread
+	go func() {
+		if err := p.svc.PasteByID(context.Background(), id); err != nil {
+			log.Printf("Picker: failed to paste clip %s: %v", id, err)
+		}
+	}()
+}
+
+// firstRow returns the index of the match shown in the top row, the page
+// of matches ending at the selection
+func (p *picker) firstRow() int {
+	if p.selected >= pickerRows {
+		return p.selected - pickerRows + 1
+	}
+	return 0
+}
+
+// render shows the page of matches around the selection and its preview
+func (p *picker) render() {
+	first := p.firstRow()
+
+	for i, row := range p.rows {
+		index := first + i
+		if index >= len(p.matches) {
+			row.SetHidden(true)
+			continue
+		}
+		row.SetHidden(false)
+		row.SetStringValue(clipTitle(p.matches[index]))
+
+		selected := index == p.selected
+		row.SetDrawsBackground(selected)
+		if selected {
+			row.SetBackgroundColor(appkit.Color_SelectedContentBackgroundColor())
+			row.SetTextColor(appkit.Color_AlternateSelectedControlTextColor())
+		} else {
+			row.SetTextColor(appkit.Color_LabelColor())
+		}
+	}
+
+	p.renderPreview()
+}
+
+func (p *picker) renderPreview() {
+	if p.selected >= len(p.matches) {
+		p.preview.SetStringValue("")
+		p.preview.SetHidden(false)
+		p.image.SetHidden(true)
+		return
+	}
+
+	clip := p.matches[p.selected]
+	if types.IsImage(clip.Type) {
+		p.image.SetImage(appkit.NewImageWithData(clip.Content))
+		p.image.SetHidden(false)
+		p.preview.SetHidden(true)
+		return
+	}
+
+	text := searchText(clip)
+	if len(text) > previewSize {
+		text = text[:previewSize] + "…"
+	}
+	if clip.Metadata.SourceApp != "" {
+		text += "\n\nCopied from " + clip.Metadata.SourceApp
+		if clip.Metadata.SourceWindow != "" {
+			text += " (" + clip.Metadata.SourceWindow + ")"
+		}
+	}
+	p.preview.SetStringValue(text)
+	p.preview.SetHidden(false)
+	p.image.SetHidden(true)
+}
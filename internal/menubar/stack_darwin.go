@@ -0,0 +1,37 @@
+package menubar
+
+import (
+	"clipboard-manager/internal/service"
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/progrium/darwinkit/macos/appkit"
+	"github.com/progrium/darwinkit/objc"
+)
+
+// stackAdvanceDelay gives the app being pasted into time to read the
+// clipboard before the next clip replaces it
+const stackAdvanceDelay = 200 * time.Millisecond
+
+// watchPastes copies the next clip on the paste stack after each ⌘V in
+// another app. Like the picker hotkey, it needs the Accessibility permission.
+func watchPastes(svc *service.ClipboardService) {
+	appkit.Event_AddGlobalMonitorForEventsMatchingMaskHandler(appkit.EventMaskKeyDown, func(event appkit.Event) {
+		if !isPaste(event) || !svc.PasteStack().Active {
+			return
+		}
+		time.AfterFunc(stackAdvanceDelay, func() {
+			if _, err := svc.PasteNext(context.Background()); err != nil && !errors.Is(err, service.ErrStackEmpty) {
+				log.Printf("Menu bar: failed to copy the next clip on the paste stack: %v", err)
+			}
+		})
+	})
+}
+
+// isPaste reports whether event is ⌘V
+func isPaste(event appkit.Event) bool {
+	flags := objc.Call[appkit.EventModifierFlags](event, objc.Sel("modifierFlags"))
+	return event.KeyCode() == keyV && flags&appkit.EventModifierFlagDeviceIndependentFlagsMask == appkit.EventModifierFlagCommand
+}
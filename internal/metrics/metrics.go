@@ -0,0 +1,173 @@
+// Package metrics exposes daemon metrics in the Prometheus text format
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "clipboard"
+
+// Registry holds every metric served on /metrics
+var Registry = prometheus.NewRegistry()
+
+var (
+	// ClipsCaptured counts clipboard changes captured by the monitor, by clip type
+	ClipsCaptured = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "clips_captured_total",
+		Help:      "Clipboard changes captured, by clip type.",
+	}, []string{"type"})
+
+	// StoreDuration tracks how long storing a captured clip takes
+	StoreDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "store_duration_seconds",
+		Help:      "Time taken to store a captured clip.",
+		Buckets:   prometheus.DefBuckets,
+	})
+
+	// SearchDuration tracks how long searches take
+	SearchDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "search_duration_seconds",
+		Help:      "Time taken to run a search.",
+		Buckets:   prometheus.DefBuckets,
+	})
+
+	// Dedup counts stored clips by whether their content was already stored
+	Dedup = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "store_dedup_total",
+		Help:      "Stored clips by deduplication result (hit or miss).",
+	}, []string{"result"})
+
+	// CapturesLimited counts clipboard changes dropped by capture limits, by
+	// reason (coalesced, rate or size)
+	CapturesLimited = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "captures_limited_total",
+		Help:      "Clipboard changes dropped by capture limits, by reason (coalesced, rate or size).",
+	}, []string{"reason"})
+
+	// StageDuration tracks how long each capture pipeline stage takes per clip
+	StageDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "pipeline_stage_duration_seconds",
+		Help:      "Time taken by a capture pipeline stage for one clip, by stage.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"stage"})
+
+	// StageQueued is the number of clips waiting for each pipeline stage
+	StageQueued = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "pipeline_stage_queued",
+		Help:      "Clips waiting for a capture pipeline stage, by stage.",
+	}, []string{"stage"})
+
+	// StageBlocked counts clips that waited because a stage's queue was full
+	StageBlocked = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "pipeline_stage_blocked_total",
+		Help:      "Clips that waited for room in a full capture pipeline queue, by stage.",
+	}, []string{"stage"})
+
+	// CapturePanics counts panics recovered while capturing clips, by where
+	// they happened
+	CapturePanics = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "capture_panics_total",
+		Help:      "Panics recovered while capturing clips, by the monitor, stage or handler that panicked.",
+	}, []string{"where"})
+
+	// Syncs counts sync runs by target and result
+	Syncs = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "syncs_total",
+		Help:      "Sync runs by target and result (success or failure).",
+	}, []string{"target", "result"})
+
+	// ClipsEvicted counts clips removed to stay under the storage quota
+	ClipsEvicted = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "clips_evicted_total",
+		Help:      "Clips removed to stay under the storage quota.",
+	})
+
+	// MaintenanceRuns counts database maintenance runs by kind and result
+	MaintenanceRuns = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "maintenance_runs_total",
+		Help:      "Database maintenance runs by kind (full or checkpoint) and result (success or failure).",
+	}, []string{"kind", "result"})
+
+	// MaintenanceDuration tracks how long database maintenance runs take
+	MaintenanceDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "maintenance_duration_seconds",
+		Help:      "Time taken by a database maintenance run.",
+		Buckets:   prometheus.DefBuckets,
+	})
+
+	// MaintenanceFreedBytes counts space maintenance returned to the file system
+	MaintenanceFreedBytes = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "maintenance_freed_bytes_total",
+		Help:      "Space returned to the file system by database maintenance in bytes.",
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		ClipsCaptured,
+		StoreDuration,
+		SearchDuration,
+		Dedup,
+		CapturesLimited,
+		StageDuration,
+		StageQueued,
+		StageBlocked,
+		CapturePanics,
+		Syncs,
+		ClipsEvicted,
+		MaintenanceRuns,
+		MaintenanceDuration,
+		MaintenanceFreedBytes,
+	)
+}
+
+// RegisterGaugeFunc exposes a value owned by another component, such as a
+// connection count, read each time metrics are scraped. Registering a gauge
+// with the same name again replaces the previous one.
+func RegisterGaugeFunc(name, help string, fn func() float64) {
+	gauge := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      name,
+		Help:      help,
+	}, fn)
+
+	if err := Registry.Register(gauge); err != nil {
+		if existing, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			Registry.Unregister(existing.ExistingCollector)
+			Registry.MustRegister(gauge)
+		}
+	}
+}
+
+// Result returns the label value for the outcome of an operation
+func Result(err error) string {
+	if err != nil {
+		return "failure"
+	}
+	return "success"
+}
+
+// Handler serves the registry in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,37 @@
+// Package notify shows native desktop notifications
+package notify
+
+import "errors"
+
+// Event names a kind of notification that can be turned on or off
+type Event string
+
+// Events the daemon can notify about
+const (
+	EventLargeFile  Event = "large_file"  // A clip was stored as a file, or was too large to keep
+	EventIgnored    Event = "ignored"     // A clip was dropped by the ignore rules
+	EventSyncFailed Event = "sync_failed" // Syncing to Obsidian failed
+	EventPasted     Event = "pasted"      // A clip was copied back to the clipboard
+	EventRule       Event = "rule"        // A clip matched a rule that notifies, which is turned on by the rule
+)
+
+// ErrUnsupported is returned by the notifier on platforms without one
+var ErrUnsupported = errors.New("notifications are not supported on this platform")
+
+// Notifier shows a notification
+type Notifier interface {
+	Notify(title, message string) error
+}
+
+// NotifierFunc adapts a function to a Notifier
+type NotifierFunc func(title, message string) error
+
+// Notify implements Notifier
+func (f NotifierFunc) Notify(title, message string) error {
+	return f(title, message)
+}
+
+// New returns the native notifier for this platform
+func New() Notifier {
+	return NotifierFunc(notify)
+}
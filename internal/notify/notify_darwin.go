@@ -0,0 +1,24 @@
+package notify
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+)
+
+// notify uses terminal-notifier when it is installed, since its notifications
+// can be configured in System Settings under their own name, and falls back
+// to AppleScript
+func notify(title, message string) error {
+	var cmd *exec.Cmd
+	if path, err := exec.LookPath("terminal-notifier"); err == nil {
+		cmd = exec.Command(path, "-title", title, "-message", message, "-group", "clipboard-manager")
+	} else {
+		script := fmt.Sprintf("display notification %s with title %s", strconv.Quote(message), strconv.Quote(title))
+		cmd = exec.Command("osascript", "-e", script)
+	}
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s failed: %w: %s", cmd.Path, err, output)
+	}
+	return nil
+}
@@ -0,0 +1,15 @@
+package notify
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// notify uses notify-send, which talks to the desktop's notification daemon
+func notify(title, message string) error {
+	output, err := exec.Command("notify-send", "--app-name=Clipboard Manager", title, message).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("notify-send failed: %w: %s", err, output)
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package notify
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// balloonScript shows a tray balloon, which Windows 10 and later display as a
+// toast, without needing any PowerShell modules
+const balloonScript = `
+Add-Type -AssemblyName System.Windows.Forms
+$icon = New-Object System.Windows.Forms.NotifyIcon
+$icon.Icon = [System.Drawing.SystemIcons]::Information
+$icon.Visible = $true
+$icon.ShowBalloonTip(5000, '%s', '%s', 'Info')
+Start-Sleep -Seconds 5
+$icon.Dispose()
+`
+
+// notify shows a balloon through PowerShell. It returns once the balloon
+// has been taken down.
+func notify(title, message string) error {
+	// Single quoted PowerShell strings escape a quote by doubling it
+	quote := strings.NewReplacer("'", "''")
+	script := fmt.Sprintf(balloonScript, quote.Replace(title), quote.Replace(message))
+	output, err := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", script).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("powershell failed: %w: %s", err, output)
+	}
+	return nil
+}
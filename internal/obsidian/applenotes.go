@@ -0,0 +1,147 @@
+package obsidian
+
+import (
+	"clipboard-manager/pkg/types"
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultAppleNotesFolder is the Apple Notes folder clips go to when none is
+// configured
+const DefaultAppleNotesFolder = "Clipboard"
+
+// maxNoteTitle is how many characters of a clip name its note
+const maxNoteTitle = 60
+
+var (
+	ErrNotesUnavailable = errors.New("Apple Notes is only available on macOS")
+	ErrNotes            = errors.New("Apple Notes export failed")
+	ErrSensitive        = errors.New("sensitive clips are never exported")
+)
+
+// appleNotesScript adds a note to a folder, in a subfolder for the clip's
+// category if it has one, unless a note there already holds the marker
+const appleNotesScript = `on run argv
+	set {folderName, category, noteTitle, noteBody, marker, imagePath} to argv
+	tell application "Notes"
+		if not (exists folder folderName) then make new folder with properties {name:folderName}
+		set target to folder folderName
+		if category is not "" then
+			if not (exists folder category of target) then make new folder at target with properties {name:category}
+			set target to folder category of target
+		end if
+		if (count of (notes of target whose body contains marker)) > 0 then return
+		set theNote to make new note at target with properties {name:noteTitle, body:noteBody}
+		if imagePath is not "" then make new attachment at end of attachments of theNote with data (POSIX file imagePath)
+	end tell
+end run`
+
+// appleNotes exports clips to Apple Notes through AppleScript, a note per
+// clip in a folder per category, with images attached
+type appleNotes struct {
+	folder string
+	// run runs appleNotesScript with args, see runAppleScript
+	run func(ctx context.Context, script string, args ...string) error
+}
+
+// NewAppleNotes returns a target that exports clips to folder in Apple
+// Notes, or DefaultAppleNotesFolder if folder is empty. It can also be used
+// on its own to export chosen clips.
+func NewAppleNotes(folder string) SyncTarget {
+	if folder == "" {
+		folder = DefaultAppleNotesFolder
+	}
+	return &appleNotes{folder: folder, run: runAppleScript}
+}
+
+func (a *appleNotes) Name() string {
+	return "apple-notes"
+}
+
+func (a *appleNotes) Check() error {
+	return checkAppleScript()
+}
+
+// Write leaves a clip already in its folder alone, finding it by its marker
+func (a *appleNotes) Write(ctx context.Context, clip *types.Clip) error {
+	if clip.Metadata.ExpiresAt != nil {
+		return ErrSensitive
+	}
+
+	var imagePath string
+	if isImage(clip) {
+		dir, err := os.MkdirTemp("", "clipboard-notes-")
+		if err != nil {
+			return fmt.Errorf("failed to create temporary directory: %w", err)
+		}
+		defer os.RemoveAll(dir)
+		name, err := writeImage(dir, clip)
+		if err != nil {
+			return err
+		}
+		imagePath = filepath.Join(dir, name)
+	}
+
+	title, body := appleNote(clip)
+	category := strings.ReplaceAll(clip.Metadata.Category, "/", "-")
+	return a.run(ctx, appleNotesScript, a.folder, category, title, body, clipMarker(clip), imagePath)
+}
+
+// appleNote returns the title and HTML body of a clip's note. The body ends
+// with the clip's details and marker, which Notes shows as a footer.
+func appleNote(clip *types.Clip) (title, body string) {
+	var text string
+	if isImage(clip) {
+		title = "Image " + clip.CreatedAt.Format("2006-01-02 15:04:05")
+	} else {
+		text = string(clip.Content)
+		title = strings.TrimSpace(strings.SplitN(strings.TrimSpace(text), "\n", 2)[0])
+		if link := clip.Metadata.Link; link != nil && link.Title != "" {
+			title = link.Title
+		}
+		if utf8.RuneCountInString(title) > maxNoteTitle {
+			title = string([]rune(title)[:maxNoteTitle]) + "…"
+		}
+		if title == "" {
+			title = "Clip " + clip.CreatedAt.Format("2006-01-02 15:04:05")
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "<h1>%s</h1>\n", html.EscapeString(title))
+	if text != "" {
+		if link := clip.Metadata.Link; link != nil && link.Title != "" {
+			url := strings.TrimSpace(text)
+			fmt.Fprintf(&b, "<p><a href=\"%s\">%s</a></p>\n", html.EscapeString(url), html.EscapeString(url))
+			if link.Description != "" {
+				fmt.Fprintf(&b, "<blockquote>%s</blockquote>\n", html.EscapeString(link.Description))
+			}
+		} else {
+			lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+			for i, line := range lines {
+				lines[i] = html.EscapeString(line)
+			}
+			fmt.Fprintf(&b, "<p>%s</p>\n", strings.Join(lines, "<br>"))
+		}
+		if clip.Truncated {
+			fmt.Fprintf(&b, "<p><i>Truncated: the full clip %s is kept by the clipboard manager</i></p>\n", html.EscapeString(clip.ID))
+		}
+	}
+
+	details := []string{clip.CreatedAt.Format("2006-01-02 15:04:05")}
+	if clip.Metadata.SourceApp != "" {
+		details = append(details, clip.Metadata.SourceApp)
+	}
+	for _, tag := range cleanTags(clip.Metadata.Tags) {
+		details = append(details, "#"+tag)
+	}
+	details = append(details, clipMarker(clip))
+	fmt.Fprintf(&b, "<p><small>%s</small></p>", html.EscapeString(strings.Join(details, " · ")))
+	return title, b.String()
+}
@@ -0,0 +1,27 @@
+package obsidian
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// checkAppleScript makes sure osascript is there to run scripts
+func checkAppleScript() error {
+	if _, err := exec.LookPath("osascript"); err != nil {
+		return fmt.Errorf("%w: %v", ErrNotesUnavailable, err)
+	}
+	return nil
+}
+
+// runAppleScript runs script with args as its argv. Passing values as
+// arguments rather than in the script spares quoting them.
+func runAppleScript(ctx context.Context, script string, args ...string) error {
+	cmd := exec.CommandContext(ctx, "osascript", append([]string{"-"}, args...)...)
+	cmd.Stdin = strings.NewReader(script)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%w: %v: %s", ErrNotes, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
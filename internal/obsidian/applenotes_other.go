@@ -0,0 +1,13 @@
+//go:build !darwin
+
+package obsidian
+
+import "context"
+
+func checkAppleScript() error {
+	return ErrNotesUnavailable
+}
+
+func runAppleScript(ctx context.Context, script string, args ...string) error {
+	return ErrNotesUnavailable
+}
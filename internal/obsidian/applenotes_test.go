@@ -0,0 +1,69 @@
+package obsidian
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAppleNotes_Write(t *testing.T) {
+	var args []string
+	target := &appleNotes{folder: "Clipboard", run: func(ctx context.Context, script string, a ...string) error {
+		args = a
+		return nil
+	}}
+	ctx := context.Background()
+
+	clip := testClip()
+	clip.Content = []byte("<b>hello</b>\nworld")
+	clip.Metadata.Category = "code/go"
+	if err := target.Write(ctx, clip); err != nil {
+		t.Fatalf("failed to export clip: %v", err)
+	}
+	if len(args) != 6 {
+		t.Fatalf("expected 6 script arguments, got %q", args)
+	}
+	folder, category, title, body, marker, image := args[0], args[1], args[2], args[3], args[4], args[5]
+	if folder != "Clipboard" || category != "code-go" || title != "<b>hello</b>" || marker != clipMarker(clip) || image != "" {
+		t.Errorf("unexpected arguments %q", args)
+	}
+	for _, want := range []string{
+		"<h1>&lt;b&gt;hello&lt;/b&gt;</h1>",
+		"<p>&lt;b&gt;hello&lt;/b&gt;<br>world</p>",
+		"Safari · #read-later · " + clipMarker(clip),
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("body %q does not contain %q", body, want)
+		}
+	}
+
+	// Images are attached from a temporary file, removed afterwards
+	var imageExists bool
+	target.run = func(ctx context.Context, script string, a ...string) error {
+		args = a
+		_, err := os.Stat(a[5])
+		imageExists = err == nil
+		return nil
+	}
+	picture := testClip()
+	picture.Type, picture.Content = "image/png", []byte("png")
+	if err := target.Write(ctx, picture); err != nil {
+		t.Fatalf("failed to export image: %v", err)
+	}
+	if !imageExists || !strings.HasSuffix(args[5], ".png") || args[2] != "Image 2024-01-02 15:04:05" {
+		t.Errorf("unexpected image arguments %q", args)
+	}
+	if _, err := os.Stat(args[5]); !os.IsNotExist(err) {
+		t.Errorf("expected the temporary image to be removed, got %v", err)
+	}
+
+	expires := time.Now().Add(time.Hour)
+	sensitive := testClip()
+	sensitive.Metadata.ExpiresAt = &expires
+	if err := target.Write(ctx, sensitive); !errors.Is(err, ErrSensitive) {
+		t.Errorf("expected a sensitive clip to be refused, got %v", err)
+	}
+}
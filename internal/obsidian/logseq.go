@@ -0,0 +1,72 @@
+package obsidian
+
+import (
+	"clipboard-manager/pkg/types"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// logseq syncs clips to the journal page of the day they were copied in a
+// Logseq graph, one block each, with images in the graph's assets folder
+type logseq struct {
+	graph string
+	notes *notes
+}
+
+func (l *logseq) Name() string {
+	return "logseq"
+}
+
+func (l *logseq) Check() error {
+	if _, err := os.Stat(l.graph); err != nil {
+		return fmt.Errorf("graph path error: %w", err)
+	}
+	return nil
+}
+
+func (l *logseq) Write(ctx context.Context, clip *types.Clip) error {
+	journals := filepath.Join(l.graph, "journals")
+	if err := os.MkdirAll(journals, 0755); err != nil {
+		return fmt.Errorf("failed to create journals directory: %w", err)
+	}
+
+	var body string
+	if isImage(clip) {
+		name, err := writeImage(filepath.Join(l.graph, "assets"), clip)
+		if err != nil {
+			return err
+		}
+		body = fmt.Sprintf("![%s](../assets/%s)", name, name)
+	} else {
+		body = clipText(clip)
+	}
+
+	// The clip is a block tagged with its tags, holding its details as
+	// block properties and its content as a child block. The marker is a
+	// property too, which keeps the block from being added twice.
+	marker := "clipboard-id:: " + clipMarker(clip)
+	var b strings.Builder
+	fmt.Fprintf(&b, "- %s #clipboard", clip.CreatedAt.Format("15:04:05"))
+	for _, tag := range cleanTags(clip.Metadata.Tags) {
+		fmt.Fprintf(&b, " #[[%s]]", tag)
+	}
+	b.WriteString("\n")
+	if clip.Metadata.SourceApp != "" {
+		fmt.Fprintf(&b, "  source:: %s\n", clip.Metadata.SourceApp)
+	}
+	fmt.Fprintf(&b, "  type:: %s\n", clip.Type)
+	fmt.Fprintf(&b, "  %s\n", marker)
+	for i, line := range strings.Split(strings.TrimRight(body, "\n"), "\n") {
+		if i == 0 {
+			fmt.Fprintf(&b, "\t- %s\n", line)
+		} else {
+			fmt.Fprintf(&b, "\t  %s\n", line)
+		}
+	}
+
+	path := filepath.Join(journals, clip.CreatedAt.Format("2006_01_02")+".md")
+	return l.notes.append(ctx, path, "", b.String(), marker)
+}
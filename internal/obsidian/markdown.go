@@ -0,0 +1,67 @@
+package obsidian
+
+import (
+	"clipboard-manager/pkg/types"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// markdownDir syncs each clip to a Markdown file of its own in a folder,
+// with its details as YAML frontmatter, for tools that read plain Markdown
+type markdownDir struct {
+	dir string
+}
+
+func (m *markdownDir) Name() string {
+	return "markdown"
+}
+
+func (m *markdownDir) Check() error {
+	if _, err := os.Stat(m.dir); err != nil {
+		return fmt.Errorf("markdown folder error: %w", err)
+	}
+	return nil
+}
+
+// Write names the file after the clip, so writing it again replaces it
+func (m *markdownDir) Write(ctx context.Context, clip *types.Clip) error {
+	name := fmt.Sprintf("%s-%s", clip.CreatedAt.Format("2006-01-02-150405"), clip.ID)
+
+	var body string
+	if isImage(clip) {
+		image, err := writeImage(m.dir, clip)
+		if err != nil {
+			return err
+		}
+		body = fmt.Sprintf("![](%s)", image)
+	} else {
+		body = clipText(clip)
+	}
+
+	var b strings.Builder
+	b.WriteString("---\n")
+	fmt.Fprintf(&b, "id: %s\n", strconv.Quote(clip.ID))
+	fmt.Fprintf(&b, "type: %s\n", strconv.Quote(clip.Type))
+	fmt.Fprintf(&b, "created: %s\n", clip.CreatedAt.Format(time.RFC3339))
+	if clip.Metadata.SourceApp != "" {
+		fmt.Fprintf(&b, "source: %s\n", strconv.Quote(clip.Metadata.SourceApp))
+	}
+	if clip.Metadata.SourceURL != "" {
+		fmt.Fprintf(&b, "source_url: %s\n", strconv.Quote(clip.Metadata.SourceURL))
+	}
+	tags := append([]string{"clipboard"}, cleanTags(clip.Metadata.Tags)...)
+	for i, tag := range tags {
+		tags[i] = strconv.Quote(tag)
+	}
+	fmt.Fprintf(&b, "tags: [%s]\n", strings.Join(tags, ", "))
+	b.WriteString("---\n\n")
+	b.WriteString(strings.TrimRight(body, "\n"))
+	b.WriteString("\n")
+
+	return writeFileAtomic(filepath.Join(m.dir, name+".md"), []byte(b.String()), 0644)
+}
@@ -0,0 +1,231 @@
+package obsidian
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// errNoteChanged is returned when a note keeps changing while it is written,
+// as when Obsidian Sync is updating it
+var errNoteChanged = errors.New("note changed while it was being written")
+
+// errNoteWaiting is returned for a note that failed to be written and isn't
+// tried again yet
+var errNoteWaiting = errors.New("note is waiting to be retried")
+
+// Retries of a note that changed while it was written, within one sync
+const (
+	noteWriteAttempts = 3
+	noteWriteBackoff  = 200 * time.Millisecond
+)
+
+// Backoff of a note that failed to be written, across syncs. Its clips are
+// left unsynced until then.
+const (
+	noteRetryMin = 30 * time.Second
+	noteRetryMax = time.Hour
+)
+
+// noteLocks serializes writes to each note within the process, including
+// from sync services replaced while a sync was running
+var noteLocks sync.Map // path -> *sync.Mutex
+
+func lockNote(path string) func() {
+	lock, _ := noteLocks.LoadOrStore(path, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	return lock.(*sync.Mutex).Unlock
+}
+
+// fileStamp identifies a version of a file
+type fileStamp struct {
+	size    int64
+	modTime int64 // Unix nanoseconds
+	hash    [sha256.Size]byte
+}
+
+// notes tracks the notes clips are appended to, backing off from notes that
+// fail to be written
+type notes struct {
+	mu    sync.Mutex
+	state map[string]*noteState
+}
+
+func newNotes() *notes {
+	return &notes{state: make(map[string]*noteState)}
+}
+
+// noteState is what sync knows about a note it writes to
+type noteState struct {
+	written  fileStamp // The note as sync last left it
+	failures int       // Failed writes in a row
+	retryAt  time.Time // When to write again after a failure
+}
+
+// readNote returns the content of the note at path and its stamp. A note
+// that doesn't exist yet has no content and a zero stamp.
+func readNote(path string) ([]byte, fileStamp, error) {
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, fileStamp{}, nil
+	}
+	if err != nil {
+		return nil, fileStamp{}, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fileStamp{}, err
+	}
+	return content, fileStamp{size: info.Size(), modTime: info.ModTime().UnixNano(), hash: sha256.Sum256(content)}, nil
+}
+
+// writeFileAtomic replaces the file at path with content. The content is
+// written to a temporary file next to it and renamed over it, so a crash
+// leaves either the old file or the new one, never part of it.
+func writeFileAtomic(path string, content []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	_, err = tmp.Write(content)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), perm)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// append appends entry to the note at path, starting a new note with
+// heading. An entry holding marker that is already in the note, written by
+// a sync that failed before marking its clip synced, is not added again. A
+// note that failed to be written is left alone for a while, failing with
+// errNoteWaiting.
+//
+// The note is read, and replaced with its content plus the entry, only if
+// it didn't change in between; otherwise it is read again, a few times at
+// most before failing with errNoteChanged. Changes made outside of sync
+// since it last wrote the note are kept.
+func (n *notes) append(ctx context.Context, path, heading, entry, marker string) error {
+	if n.waiting(path, time.Now()) {
+		return fmt.Errorf("%s: %w", path, errNoteWaiting)
+	}
+	err := n.write(ctx, path, heading, entry, marker)
+	n.written(path, err, time.Now())
+	return err
+}
+
+// write does the work of append
+func (n *notes) write(ctx context.Context, path, heading, entry, marker string) error {
+	defer lockNote(path)()
+	state := n.note(path)
+
+	for attempt := 1; ; attempt++ {
+		existing, stamp, err := readNote(path)
+		if err != nil {
+			return fmt.Errorf("failed to read existing file: %w", err)
+		}
+		if state.written != (fileStamp{}) && stamp != state.written {
+			log.Printf("Note %s was changed outside of sync, appending to the changed note", path)
+		}
+		if bytes.Contains(existing, []byte(marker)) {
+			state.written = stamp
+			return nil
+		}
+
+		content := existing
+		if content == nil {
+			content = []byte(heading)
+		} else if len(content) > 0 && content[len(content)-1] != '\n' {
+			content = append(content, '\n')
+		}
+		content = append(content, entry...)
+
+		// Another writer may have changed the note since it was read
+		_, current, err := readNote(path)
+		if err != nil {
+			return fmt.Errorf("failed to read existing file: %w", err)
+		}
+		if current == stamp {
+			if err := writeFileAtomic(path, content, 0644); err != nil {
+				return err
+			}
+			_, state.written, err = readNote(path)
+			return err
+		}
+
+		if attempt == noteWriteAttempts {
+			return fmt.Errorf("%s: %w", path, errNoteChanged)
+		}
+		log.Printf("Note %s changed while it was being written, retrying", path)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(noteWriteBackoff * time.Duration(attempt)):
+		}
+	}
+}
+
+// note returns the state of the note at path
+func (n *notes) note(path string) *noteState {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	state, ok := n.state[path]
+	if !ok {
+		state = &noteState{}
+		n.state[path] = state
+	}
+	return state
+}
+
+// waiting reports whether the note at path failed to be written and should
+// not be tried again yet
+func (n *notes) waiting(path string, now time.Time) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	state, ok := n.state[path]
+	return ok && now.Before(state.retryAt)
+}
+
+// written records whether writing to the note at path failed, backing off
+// from it for longer after each failure in a row
+func (n *notes) written(path string, err error, now time.Time) {
+	state := n.note(path)
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if err == nil {
+		state.failures = 0
+		state.retryAt = time.Time{}
+		return
+	}
+	state.failures++
+	backoff := noteRetryMin
+	for i := 1; i < state.failures && backoff < noteRetryMax; i++ {
+		backoff *= 2
+	}
+	if backoff > noteRetryMax {
+		backoff = noteRetryMax
+	}
+	state.retryAt = now.Add(backoff)
+	log.Printf("Failed to write note %s (%d failures in a row), retrying in %v: %v", path, state.failures, backoff, err)
+}
@@ -0,0 +1,67 @@
+package obsidian
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAppendNote(t *testing.T) {
+	n := newNotes()
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "2024-01-02.md")
+
+	if err := n.append(ctx, path, "# 2024-01-02\n", "first <!-- 1 -->\n", "<!-- 1 -->"); err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+	// Edits made in between are kept, and an entry is only added once
+	if err := os.WriteFile(path, []byte("# 2024-01-02\nedited\n"), 0644); err != nil {
+		t.Fatalf("failed to edit note: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := n.append(ctx, path, "# 2024-01-02\n", "second <!-- 2 -->\n", "<!-- 2 -->"); err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read note: %v", err)
+	}
+	if want := "# 2024-01-02\nedited\nsecond <!-- 2 -->\n"; string(content) != want {
+		t.Errorf("note = %q, want %q", content, want)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+		t.Errorf("expected no temporary files left, got %d files", len(entries))
+	}
+}
+
+func TestNoteWritten_Backoff(t *testing.T) {
+	n := newNotes()
+	now := time.Now()
+
+	failed := errors.New("failed")
+	for i, want := range []time.Duration{noteRetryMin, 2 * noteRetryMin, 4 * noteRetryMin} {
+		n.written("note.md", failed, now)
+		if got := n.state["note.md"].retryAt.Sub(now); got != want {
+			t.Errorf("backoff after %d failures = %v, want %v", i+1, got, want)
+		}
+	}
+	if !n.waiting("note.md", now) || n.waiting("other.md", now) {
+		t.Error("expected only the failed note to wait")
+	}
+	for i := 0; i < 20; i++ {
+		n.written("note.md", failed, now)
+	}
+	if got := n.state["note.md"].retryAt.Sub(now); got != noteRetryMax {
+		t.Errorf("backoff = %v, want at most %v", got, noteRetryMax)
+	}
+
+	n.written("note.md", nil, now)
+	if n.waiting("note.md", now) {
+		t.Error("expected a written note not to wait")
+	}
+}
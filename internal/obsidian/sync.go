@@ -1,93 +1,131 @@
+// Package obsidian syncs clips to notes: an Obsidian vault, a Logseq
+// graph's journal, a plain folder of Markdown files, or Apple Notes
 package obsidian
 
 import (
+	"clipboard-manager/internal/metrics"
 	"clipboard-manager/internal/storage"
+	"clipboard-manager/pkg/types"
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
-	"path/filepath"
-	"strings"
-	"sync"
 	"time"
 )
 
-// SyncService handles syncing clipboard content to Obsidian vault
+// SyncService handles syncing clipboard content to its targets
 type SyncService struct {
 	store      storage.Storage
-	vaultPath  string
+	onError    func(error)
+	targets    []SyncTarget
+	vault      *vault // Also in targets, if syncing to Obsidian
 	syncTicker *time.Ticker
 	done       chan struct{}
-	mu         sync.RWMutex // Protects vaultPath
 }
 
 // UpdateVaultPath updates the vault path while the service is running
 func (s *SyncService) UpdateVaultPath(path string) error {
+	if s.vault == nil {
+		return fmt.Errorf("not syncing to an Obsidian vault")
+	}
 	// Verify new path exists
 	if _, err := os.Stat(path); os.IsNotExist(err) {
 		return fmt.Errorf("new vault path does not exist: %s", path)
 	}
-
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	log.Printf("Updating vault path from %s to %s", s.vaultPath, path)
-	s.vaultPath = path
+	s.vault.setPath(path)
 	return nil
 }
 
-// Config holds configuration for the Obsidian sync service
+// Config holds configuration for the sync service. Clips are synced to
+// each target with a path set.
 type Config struct {
-	VaultPath    string
-	SyncInterval time.Duration
+	VaultPath    string // Obsidian vault
+	LogseqPath   string // Logseq graph
+	MarkdownPath string // Folder of Markdown files, one per clip
+	// AppleNotesFolder is the Apple Notes folder clips are exported to, if
+	// set
+	AppleNotesFolder string
+	SyncInterval     time.Duration
+	OnError          func(error) // Called when a sync fails, if set
 }
 
-// New creates a new Obsidian sync service
+// New creates a new sync service
 func New(store storage.Storage, config Config) (*SyncService, error) {
-	if config.VaultPath == "" {
-		return nil, fmt.Errorf("vault path is required")
+	s := &SyncService{
+		store:   store,
+		onError: config.OnError,
+		done:    make(chan struct{}),
 	}
-
-	// Verify vault path exists
-	if _, err := os.Stat(config.VaultPath); os.IsNotExist(err) {
-		return nil, fmt.Errorf("vault path does not exist: %s", config.VaultPath)
+	notes := newNotes()
+	for _, target := range []struct {
+		name, path string
+		target     SyncTarget
+	}{
+		{"vault", config.VaultPath, &vault{path: config.VaultPath, notes: notes}},
+		{"Logseq graph", config.LogseqPath, &logseq{graph: config.LogseqPath, notes: notes}},
+		{"markdown folder", config.MarkdownPath, &markdownDir{dir: config.MarkdownPath}},
+	} {
+		if target.path == "" {
+			continue
+		}
+		// Verify the path exists
+		if _, err := os.Stat(target.path); os.IsNotExist(err) {
+			return nil, fmt.Errorf("%s path does not exist: %s", target.name, target.path)
+		}
+		if v, ok := target.target.(*vault); ok {
+			s.vault = v
+		}
+		s.targets = append(s.targets, target.target)
+	}
+	if config.AppleNotesFolder != "" {
+		s.targets = append(s.targets, NewAppleNotes(config.AppleNotesFolder))
+	}
+	if len(s.targets) == 0 {
+		return nil, fmt.Errorf("a vault, Logseq graph, markdown folder path or Apple Notes folder is required")
 	}
 
 	// Validate sync interval
 	if config.SyncInterval <= 0 {
 		return nil, fmt.Errorf("sync interval must be positive, got: %v", config.SyncInterval)
 	}
+	s.syncTicker = time.NewTicker(config.SyncInterval)
+	return s, nil
+}
 
-	return &SyncService{
-		store:      store,
-		vaultPath:  config.VaultPath,
-		syncTicker: time.NewTicker(config.SyncInterval),
-		done:       make(chan struct{}),
-	}, nil
+// Targets names the targets clips are synced to
+func (s *SyncService) Targets() []string {
+	names := make([]string, len(s.targets))
+	for i, target := range s.targets {
+		names[i] = target.Name()
+	}
+	return names
 }
 
 // Start begins the sync service
 func (s *SyncService) Start(ctx context.Context) error {
-	log.Printf("Starting Obsidian sync service (vault: %s)", s.vaultPath)
+	log.Printf("Starting sync service (targets: %v)", s.Targets())
 
 	// Perform initial sync
 	if err := s.sync(ctx); err != nil {
 		log.Printf("Initial sync error: %v", err)
+		s.failed(err)
 	}
 
 	go func() {
 		for {
 			select {
 			case <-ctx.Done():
-				log.Printf("Obsidian sync service stopped (context done)")
+				log.Printf("Sync service stopped (context done)")
 				return
 			case <-s.done:
-				log.Printf("Obsidian sync service stopped (done signal)")
+				log.Printf("Sync service stopped (done signal)")
 				return
 			case <-s.syncTicker.C:
 				log.Printf("Running scheduled sync...")
 				if err := s.sync(ctx); err != nil {
 					log.Printf("Error during sync: %v", err)
+					s.failed(err)
 				}
 			}
 		}
@@ -96,9 +134,16 @@ func (s *SyncService) Start(ctx context.Context) error {
 	return nil
 }
 
+// failed reports a failed sync to the OnError callback
+func (s *SyncService) failed(err error) {
+	if s.onError != nil {
+		s.onError(err)
+	}
+}
+
 // Stop stops the sync service
 func (s *SyncService) Stop() {
-	log.Printf("Stopping Obsidian sync service")
+	log.Printf("Stopping sync service")
 	if s.syncTicker != nil {
 		s.syncTicker.Stop()
 	}
@@ -108,7 +153,7 @@ func (s *SyncService) Stop() {
 	default:
 		close(s.done)
 	}
-	log.Printf("Obsidian sync service stopped")
+	log.Printf("Sync service stopped")
 }
 
 // UpdateSyncInterval updates the sync interval while the service is running
@@ -124,201 +169,103 @@ func (s *SyncService) UpdateSyncInterval(interval time.Duration) {
 }
 
 // sync performs the actual synchronization
-func (s *SyncService) sync(ctx context.Context) error {
-	log.Printf("Starting sync operation in vault: %s", s.vaultPath)
-	
-	// Get current vault path (thread-safe)
-	s.mu.RLock()
-	vaultPath := s.vaultPath
-	s.mu.RUnlock()
+func (s *SyncService) sync(ctx context.Context) (err error) {
+	// A target that fails to take a clip fails the sync run
+	failed := make(map[string]error)
+	defer func() {
+		for _, target := range s.targets {
+			targetErr := failed[target.Name()]
+			if targetErr == nil {
+				targetErr = err
+			}
+			metrics.Syncs.WithLabelValues(target.Name(), metrics.Result(targetErr)).Inc()
+		}
+	}()
+
+	log.Printf("Starting sync operation")
+
+	// Clips are only marked synced once every target has them, so each
+	// target must be reachable
+	for _, target := range s.targets {
+		if err := target.Check(); err != nil {
+			return fmt.Errorf("%s: %w", target.Name(), err)
+		}
+	}
 
-	// Verify vault path still exists and is accessible
-	if info, err := os.Stat(vaultPath); err != nil {
-		return fmt.Errorf("vault path error: %w", err)
+	// Get unsynced clips. Clips that failed to sync wait to be retried, if
+	// storage tracks them.
+	states, tracked := s.store.(storage.SyncStateService)
+	var clips []*types.Clip
+	if tracked {
+		clips, err = states.ListSyncDue(ctx, 100)
 	} else {
-		log.Printf("Vault path verified: %s (%s)", vaultPath, info.Mode())
+		clips, err = s.store.ListUnsynced(ctx, 100) // Adjust limit as needed
 	}
-	
-	// Get unsynced clips
-	clips, err := s.store.ListUnsynced(ctx, 100) // Adjust limit as needed
 	if err != nil {
 		return fmt.Errorf("failed to list clips: %w", err)
 	}
 	log.Printf("Found %d clips to process", len(clips))
 
+	// A clip that can't be synced holds back only itself
+	var errs []error
 	for _, clip := range clips {
 		// Process clip content
 		log.Printf("Processing clip - ID: %s, Type: %s", clip.ID, clip.Type)
-		
-		// Convert content bytes to string
-		content := string(clip.Content)
-		if content == "" {
+		if len(clip.Content) == 0 {
 			log.Printf("Skipping empty content")
 			continue
 		}
-		log.Printf("Content length: %d bytes", len(content))
-
-		// Generate filename based on date
-		filename := fmt.Sprintf("%s.md", clip.CreatedAt.Format("2006-01-02"))
-		clipboardDir := filepath.Join(vaultPath, "Clipboard")
-		path := filepath.Join(clipboardDir, filename)
 
-		log.Printf("File operations:")
-		log.Printf("- Filename: %s", filename)
-		log.Printf("- Clipboard dir: %s", clipboardDir)
-		log.Printf("- Full path: %s", path)
-
-		// Ensure Clipboard directory exists with proper permissions
-		if err := os.MkdirAll(clipboardDir, 0755); err != nil {
-			log.Printf("Failed to create directory: %v", err)
-			return fmt.Errorf("failed to create directory: %w", err)
-		}
-
-		// Verify directory permissions
-		if info, err := os.Stat(clipboardDir); err != nil {
-			log.Printf("Failed to verify directory: %v", err)
-			return fmt.Errorf("failed to verify directory: %w", err)
-		} else {
-			log.Printf("Directory permissions: %v", info.Mode().Perm())
-			if info.Mode().Perm()&0200 == 0 { // Check write permission
-				log.Printf("Warning: No write permission on directory")
-				return fmt.Errorf("no write permission on directory: %s", clipboardDir)
+		var clipErrs []error
+		waiting := false
+		for _, target := range s.targets {
+			err := target.Write(ctx, clip)
+			switch {
+			case errors.Is(err, errNoteWaiting):
+				// Left unsynced until the note can be written again
+				waiting = true
+			case err != nil:
+				failed[target.Name()] = err
+				clipErrs = append(clipErrs, fmt.Errorf("%s: %w", target.Name(), err))
 			}
 		}
-		log.Printf("Clipboard directory created/verified with write permissions")
-
-		// Get tags from metadata
-		tags := clip.Metadata.Tags
-		log.Printf("Tags: %v", tags)
-
-		// Generate entry content based on type
-		var entryContent string
-		if strings.HasPrefix(clip.Type, "image/") {
-			// Create assets directory if it doesn't exist
-			assetsDir := filepath.Join(clipboardDir, "assets")
-			if err := os.MkdirAll(assetsDir, 0755); err != nil {
-				log.Printf("Failed to create assets directory: %v", err)
-				return fmt.Errorf("failed to create assets directory: %w", err)
-			}
-
-			// Generate unique image filename using timestamp
-			imageFilename := fmt.Sprintf("%s-%s%s",
-				clip.CreatedAt.Format("20060102-150405"),
-				clip.ID,
-				s.getImageExtension(clip.Type))
-			imagePath := filepath.Join(assetsDir, imageFilename)
-
-			// Save image file
-			if err := os.WriteFile(imagePath, clip.Content, 0644); err != nil {
-				log.Printf("Failed to write image file: %v", err)
-				return fmt.Errorf("failed to write image file: %w", err)
-			}
-
-			// Use relative path for markdown
-			relImagePath := filepath.Join("assets", imageFilename)
-			entryContent = fmt.Sprintf("![[%s]]", relImagePath)
-		} else {
-			entryContent = content
-		}
-
-		// Generate entry with metadata and content
-		entry := fmt.Sprintf(`
-## %s
----
-source: %s
-tags: [clipboard%s]
-type: %s
----
-
-%s
-
-`,
-			clip.CreatedAt.Format("15:04:05"),
-			clip.Metadata.SourceApp,
-			s.formatTags(tags),
-			clip.Type,
-			entryContent)
-
-		var fileContent string
-		if _, err := os.Stat(path); os.IsNotExist(err) {
-			// Create new file with date heading
-			fileContent = fmt.Sprintf("# %s\n%s", 
-				clip.CreatedAt.Format("2006-01-02"),
-				entry)
-		} else {
-			// Read existing file
-			existingContent, err := os.ReadFile(path)
-			if err != nil {
-				log.Printf("Failed to read existing file: %v", err)
-				return fmt.Errorf("failed to read existing file: %w", err)
-			}
-			fileContent = string(existingContent) + entry
-		}
-
-		// Write to file with explicit permissions
-		log.Printf("Writing/Updating note: %s", path)
-		if err := os.WriteFile(path, []byte(fileContent), 0644); err != nil {
-			log.Printf("Failed to write file: %v", err)
-			return fmt.Errorf("failed to write file: %w", err)
+		if len(clipErrs) > 0 {
+			errs = append(errs, s.clipFailed(ctx, clip.ID, errors.Join(clipErrs...)))
+			continue
 		}
-
-		// Verify file was created with correct permissions
-		if info, err := os.Stat(path); err != nil {
-			log.Printf("Failed to verify file: %v", err)
-			return fmt.Errorf("failed to verify file: %w", err)
-		} else {
-			log.Printf("File created with permissions: %v", info.Mode().Perm())
+		if waiting {
+			continue
 		}
 
-		log.Printf("Successfully created note: %s", filename)
-
 		// Mark clip as synced
 		if err := s.store.MarkAsSynced(ctx, clip.ID); err != nil {
 			log.Printf("Failed to mark clip as synced: %v", err)
-			return fmt.Errorf("failed to mark clip as synced: %w", err)
+			errs = append(errs, s.clipFailed(ctx, clip.ID, fmt.Errorf("failed to mark clip as synced: %w", err)))
+			continue
 		}
 		log.Printf("Marked clip %s as synced", clip.ID)
 	}
 
 	log.Printf("Sync operation completed")
-	return nil
+	return errors.Join(errs...)
 }
 
-// getImageExtension returns the appropriate file extension based on MIME type
-func (s *SyncService) getImageExtension(mimeType string) string {
-	switch mimeType {
-	case "image/png":
-		return ".png"
-	case "image/jpeg", "image/jpg":
-		return ".jpg"
-	case "image/gif":
-		return ".gif"
-	case "image/webp":
-		return ".webp"
-	case "image/svg+xml":
-		return ".svg"
-	default:
-		return ".png" // default to png if unknown
+// clipFailed records that syncing a clip failed, if storage tracks it, and
+// returns err naming the clip
+func (s *SyncService) clipFailed(ctx context.Context, id string, err error) error {
+	err = fmt.Errorf("clip %s: %w", id, err)
+	states, ok := s.store.(storage.SyncStateService)
+	if !ok {
+		return err
 	}
-}
-
-// formatTags formats tags for frontmatter
-func (s *SyncService) formatTags(tags []string) string {
-	if len(tags) == 0 {
-		return ""
-	}
-
-	var formattedTags []string
-	for _, tag := range tags {
-		// Clean tag: remove spaces and special characters
-		cleanTag := strings.Map(func(r rune) rune {
-			if r == ' ' {
-				return '-'
-			}
-			return r
-		}, tag)
-		formattedTags = append(formattedTags, cleanTag)
+	state, recordErr := states.SyncFailed(ctx, id, err)
+	switch {
+	case recordErr != nil:
+		log.Printf("Failed to record sync failure of clip %s: %v", id, recordErr)
+	case state.GivenUp:
+		log.Printf("Giving up on syncing clip %s after %d attempts", id, state.Attempts)
+	default:
+		log.Printf("Retrying sync of clip %s at %s", id, state.NextRetry.Format(time.RFC3339))
 	}
-
-	return ", " + strings.Join(formattedTags, ", ")
+	return err
 }
@@ -0,0 +1,94 @@
+package obsidian
+
+import (
+	"clipboard-manager/pkg/types"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SyncTarget is somewhere clips are synced to, such as an Obsidian vault.
+// A clip is marked synced once every target has it.
+type SyncTarget interface {
+	// Name identifies the target in logs and metrics
+	Name() string
+
+	// Check makes sure the target can be written to, before a sync
+	Check() error
+
+	// Write adds a clip to the target. A clip another target failed to
+	// take is written again on the next sync, so writing a clip twice must
+	// not add it twice.
+	Write(ctx context.Context, clip *types.Clip) error
+}
+
+// clipMarker identifies a clip in notes, so it isn't added twice. Clip IDs
+// alone can repeat across profiles syncing to the same place.
+func clipMarker(clip *types.Clip) string {
+	return fmt.Sprintf("clip:%s:%d", clip.ID, clip.CreatedAt.Unix())
+}
+
+// writeImage saves an image clip in dir, named after when it was copied,
+// and returns the file's name
+func writeImage(dir string, clip *types.Clip) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create assets directory: %w", err)
+	}
+	name := fmt.Sprintf("%s-%s%s", clip.CreatedAt.Format("20060102-150405"), clip.ID, imageExtension(clip.Type))
+	if err := writeFileAtomic(filepath.Join(dir, name), clip.Content, 0644); err != nil {
+		return "", fmt.Errorf("failed to write image file: %w", err)
+	}
+	return name, nil
+}
+
+// clipText returns the Markdown for a clip that isn't an image. Links show
+// the title of their page, with its description.
+func clipText(clip *types.Clip) string {
+	content := string(clip.Content)
+	text := content
+	if link := clip.Metadata.Link; link != nil && link.Title != "" {
+		text = fmt.Sprintf("[%s](%s)", strings.NewReplacer("[", `\[`, "]", `\]`).Replace(link.Title), strings.TrimSpace(content))
+		if link.Description != "" {
+			text += "\n> " + link.Description
+		}
+	}
+	if clip.Truncated {
+		// Large text only brings its head along
+		text += fmt.Sprintf("\n\n*Truncated: the full clip %s is kept by the clipboard manager*", clip.ID)
+	}
+	return text
+}
+
+// isImage reports whether a clip is synced as an image file
+func isImage(clip *types.Clip) bool {
+	return strings.HasPrefix(clip.Type, "image/")
+}
+
+// imageExtension returns the appropriate file extension based on MIME type
+func imageExtension(mimeType string) string {
+	switch mimeType {
+	case "image/png":
+		return ".png"
+	case "image/jpeg", "image/jpg":
+		return ".jpg"
+	case "image/gif":
+		return ".gif"
+	case "image/webp":
+		return ".webp"
+	case "image/svg+xml":
+		return ".svg"
+	default:
+		return ".png" // default to png if unknown
+	}
+}
+
+// cleanTags replaces the spaces in tags, which tag syntax doesn't allow
+func cleanTags(tags []string) []string {
+	cleaned := make([]string, len(tags))
+	for i, tag := range tags {
+		cleaned[i] = strings.ReplaceAll(tag, " ", "-")
+	}
+	return cleaned
+}
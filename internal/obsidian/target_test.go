@@ -0,0 +1,81 @@
+package obsidian
+
+import (
+	"clipboard-manager/pkg/types"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testClip() *types.Clip {
+	return &types.Clip{
+		ID:        "42",
+		Content:   []byte("hello\nworld"),
+		Type:      "text/plain",
+		CreatedAt: time.Date(2024, 1, 2, 15, 4, 5, 0, time.Local),
+		Metadata:  types.Metadata{SourceApp: "Safari", Tags: []string{"read later"}},
+	}
+}
+
+func TestLogseq_Write(t *testing.T) {
+	graph := t.TempDir()
+	target := &logseq{graph: graph, notes: newNotes()}
+	ctx := context.Background()
+
+	// Writing a clip twice adds its block once
+	for i := 0; i < 2; i++ {
+		if err := target.Write(ctx, testClip()); err != nil {
+			t.Fatalf("failed to write clip: %v", err)
+		}
+	}
+
+	content, err := os.ReadFile(filepath.Join(graph, "journals", "2024_01_02.md"))
+	if err != nil {
+		t.Fatalf("failed to read journal: %v", err)
+	}
+	want := "- 15:04:05 #clipboard #[[read-later]]\n" +
+		"  source:: Safari\n" +
+		"  type:: text/plain\n" +
+		"  clipboard-id:: " + clipMarker(testClip()) + "\n" +
+		"\t- hello\n" +
+		"\t  world\n"
+	if string(content) != want {
+		t.Errorf("journal = %q, want %q", content, want)
+	}
+}
+
+func TestMarkdownDir_Write(t *testing.T) {
+	dir := t.TempDir()
+	target := &markdownDir{dir: dir}
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if err := target.Write(ctx, testClip()); err != nil {
+			t.Fatalf("failed to write clip: %v", err)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("failed to read folder: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "2024-01-02-150405-42.md" {
+		t.Fatalf("expected one file for the clip, got %v", entries)
+	}
+	content, err := os.ReadFile(filepath.Join(dir, entries[0].Name()))
+	if err != nil {
+		t.Fatalf("failed to read file: %v", err)
+	}
+	for _, want := range []string{
+		"---\nid: \"42\"\ntype: \"text/plain\"\n",
+		"source: \"Safari\"\n",
+		"tags: [\"clipboard\", \"read-later\"]\n---\n\nhello\nworld\n",
+	} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("file %q does not contain %q", content, want)
+		}
+	}
+}
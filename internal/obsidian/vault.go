@@ -0,0 +1,109 @@
+package obsidian
+
+import (
+	"clipboard-manager/pkg/types"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// vault syncs clips to a daily note per day in the Clipboard folder of an
+// Obsidian vault, with images in Clipboard/assets
+type vault struct {
+	mu    sync.RWMutex // Protects path
+	path  string
+	notes *notes
+}
+
+func (v *vault) Name() string {
+	return "obsidian"
+}
+
+// vaultPath returns the current vault path
+func (v *vault) vaultPath() string {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.path
+}
+
+// setPath moves the vault while the service is running
+func (v *vault) setPath(path string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	log.Printf("Updating vault path from %s to %s", v.path, path)
+	v.path = path
+}
+
+func (v *vault) Check() error {
+	vaultPath := v.vaultPath()
+	info, err := os.Stat(vaultPath)
+	if err != nil {
+		return fmt.Errorf("vault path error: %w", err)
+	}
+	log.Printf("Vault path verified: %s (%s)", vaultPath, info.Mode())
+	return nil
+}
+
+func (v *vault) Write(ctx context.Context, clip *types.Clip) error {
+	clipboardDir := filepath.Join(v.vaultPath(), "Clipboard")
+	path := filepath.Join(clipboardDir, clip.CreatedAt.Format("2006-01-02")+".md")
+
+	// Ensure Clipboard directory exists with proper permissions
+	if err := os.MkdirAll(clipboardDir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	if info, err := os.Stat(clipboardDir); err != nil {
+		return fmt.Errorf("failed to verify directory: %w", err)
+	} else if info.Mode().Perm()&0200 == 0 { // Check write permission
+		return fmt.Errorf("no write permission on directory: %s", clipboardDir)
+	}
+
+	// Generate entry content based on type
+	var entryContent string
+	if isImage(clip) {
+		name, err := writeImage(filepath.Join(clipboardDir, "assets"), clip)
+		if err != nil {
+			return err
+		}
+		// Use relative path for markdown
+		entryContent = fmt.Sprintf("![[%s]]", filepath.Join("assets", name))
+	} else {
+		entryContent = clipText(clip)
+	}
+
+	var tags string
+	for _, tag := range cleanTags(clip.Metadata.Tags) {
+		tags += ", " + tag
+	}
+
+	// Generate entry with metadata and content. The marker, hidden in
+	// Obsidian, keeps the entry from being added twice.
+	marker := fmt.Sprintf("<!-- %s -->", clipMarker(clip))
+	entry := fmt.Sprintf(`
+## %s
+---
+source: %s
+tags: [clipboard%s]
+type: %s
+---
+
+%s
+
+%s
+
+`,
+		clip.CreatedAt.Format("15:04:05"),
+		clip.Metadata.SourceApp,
+		tags,
+		clip.Type,
+		entryContent,
+		marker)
+
+	// Append to the note, with a date heading if it is new
+	log.Printf("Writing/Updating note: %s", path)
+	heading := fmt.Sprintf("# %s\n", clip.CreatedAt.Format("2006-01-02"))
+	return v.notes.append(ctx, path, heading, entry, marker)
+}
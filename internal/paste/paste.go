@@ -0,0 +1,50 @@
+// Package paste presses the paste shortcut in the frontmost app, so a clip
+// copied back to the clipboard lands where the user is typing
+package paste
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrUnavailable is returned where there is no way to press keys, such
+	// as an unsupported platform or a missing tool
+	ErrUnavailable = errors.New("simulating paste is not available")
+	// ErrPermission is returned when the system won't let us press keys
+	// until the user allows it
+	ErrPermission = errors.New("simulating paste is not permitted")
+)
+
+// Paster presses the paste shortcut
+type Paster interface {
+	// Check reports whether pasting can work, with what to do if it can't
+	Check() error
+	// Paste presses the shortcut in the frontmost app
+	Paste(ctx context.Context) error
+}
+
+type native struct{}
+
+func (native) Check() error                    { return check() }
+func (native) Paste(ctx context.Context) error { return paste(ctx) }
+
+// New returns the paster for this platform
+func New() Paster {
+	return native{}
+}
+
+type disabled struct{ reason string }
+
+func (d disabled) Check() error {
+	return fmt.Errorf("%w: %s", ErrUnavailable, d.reason)
+}
+
+func (d disabled) Paste(context.Context) error { return d.Check() }
+
+// Disabled returns a paster that always fails with ErrUnavailable for
+// reason, such as running without a desktop
+func Disabled(reason string) Paster {
+	return disabled{reason: reason}
+}
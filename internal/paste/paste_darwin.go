@@ -0,0 +1,76 @@
+//go:build darwin && cgo
+
+package paste
+
+/*
+#cgo LDFLAGS: -framework ApplicationServices
+#include <ApplicationServices/ApplicationServices.h>
+
+// trusted reports whether the process may post keyboard events. With
+// prompt, the system shows its Accessibility prompt if not.
+static int trusted(int prompt) {
+	const void *keys[] = { kAXTrustedCheckOptionPrompt };
+	const void *values[] = { prompt ? kCFBooleanTrue : kCFBooleanFalse };
+	CFDictionaryRef options = CFDictionaryCreate(NULL, keys, values, 1,
+		&kCFCopyStringDictionaryKeyCallBacks, &kCFTypeDictionaryValueCallBacks);
+	int ok = AXIsProcessTrustedWithOptions(options);
+	CFRelease(options);
+	return ok;
+}
+
+// pressCommandV posts ⌘V. 9 is the virtual key code of V.
+static int pressCommandV(void) {
+	CGEventSourceRef source = CGEventSourceCreate(kCGEventSourceStateCombinedSessionState);
+	CGEventRef down = CGEventCreateKeyboardEvent(source, (CGKeyCode)9, true);
+	CGEventRef up = CGEventCreateKeyboardEvent(source, (CGKeyCode)9, false);
+	int ok = down != NULL && up != NULL;
+	if (ok) {
+		CGEventSetFlags(down, kCGEventFlagMaskCommand);
+		CGEventSetFlags(up, kCGEventFlagMaskCommand);
+		CGEventPost(kCGHIDEventTap, down);
+		CGEventPost(kCGHIDEventTap, up);
+	}
+	if (down != NULL) CFRelease(down);
+	if (up != NULL) CFRelease(up);
+	if (source != NULL) CFRelease(source);
+	return ok;
+}
+*/
+import "C"
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// check needs the Accessibility permission, without which macOS drops the
+// key presses without saying so
+func check() error {
+	return trusted(false)
+}
+
+// trusted fails with ErrPermission unless we have the Accessibility
+// permission, asking for it with prompt
+func trusted(prompt bool) error {
+	var p C.int
+	if prompt {
+		p = 1
+	}
+	if C.trusted(p) == 0 {
+		return fmt.Errorf("%w: allow Clipboard Manager in System Settings > Privacy & Security > Accessibility, then try again", ErrPermission)
+	}
+	return nil
+}
+
+// paste posts ⌘V through Core Graphics. Pasting is when the user wants the
+// permission, so this is when the system is asked to prompt for it.
+func paste(ctx context.Context) error {
+	if err := trusted(true); err != nil {
+		return err
+	}
+	if C.pressCommandV() == 0 {
+		return errors.New("failed to create the ⌘V key events")
+	}
+	return nil
+}
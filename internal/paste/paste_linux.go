@@ -0,0 +1,52 @@
+package paste
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// check finds the tool that presses keys for this session
+func check() error {
+	_, err := command(os.Getenv, exec.LookPath)
+	return err
+}
+
+// paste presses Ctrl+V with wtype on Wayland or xdotool on X11
+func paste(ctx context.Context) error {
+	args, err := command(os.Getenv, exec.LookPath)
+	if err != nil {
+		return err
+	}
+	output, err := exec.CommandContext(ctx, args[0], args[1:]...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s failed: %w: %s", args[0], err, output)
+	}
+	return nil
+}
+
+// command returns the command that presses Ctrl+V in the session env
+// describes. Wayland compositors only take key presses from wtype; XWayland
+// apps still get them from xdotool when wtype is missing.
+func command(env func(string) string, lookPath func(string) (string, error)) ([]string, error) {
+	wayland, x11 := env("WAYLAND_DISPLAY") != "", env("DISPLAY") != ""
+	if wayland {
+		if _, err := lookPath("wtype"); err == nil {
+			return []string{"wtype", "-M", "ctrl", "v", "-m", "ctrl"}, nil
+		}
+	}
+	if x11 {
+		if _, err := lookPath("xdotool"); err == nil {
+			return []string{"xdotool", "key", "--clearmodifiers", "ctrl+v"}, nil
+		}
+	}
+
+	switch {
+	case wayland:
+		return nil, fmt.Errorf("%w: install wtype, and use a compositor that supports virtual keyboards", ErrUnavailable)
+	case x11:
+		return nil, fmt.Errorf("%w: install xdotool", ErrUnavailable)
+	}
+	return nil, fmt.Errorf("%w: no graphical session, neither WAYLAND_DISPLAY nor DISPLAY is set", ErrUnavailable)
+}
@@ -0,0 +1,75 @@
+package paste
+
+import (
+	"errors"
+	"os/exec"
+	"reflect"
+	"testing"
+)
+
+func TestCommand(t *testing.T) {
+	tests := []struct {
+		name      string
+		env       map[string]string
+		installed []string
+		want      []string
+		wantErr   bool
+	}{
+		{
+			name:      "wayland",
+			env:       map[string]string{"WAYLAND_DISPLAY": "wayland-0", "DISPLAY": ":0"},
+			installed: []string{"wtype", "xdotool"},
+			want:      []string{"wtype", "-M", "ctrl", "v", "-m", "ctrl"},
+		},
+		{
+			name:      "xwayland without wtype",
+			env:       map[string]string{"WAYLAND_DISPLAY": "wayland-0", "DISPLAY": ":0"},
+			installed: []string{"xdotool"},
+			want:      []string{"xdotool", "key", "--clearmodifiers", "ctrl+v"},
+		},
+		{
+			name:      "x11",
+			env:       map[string]string{"DISPLAY": ":0"},
+			installed: []string{"wtype", "xdotool"},
+			want:      []string{"xdotool", "key", "--clearmodifiers", "ctrl+v"},
+		},
+		{
+			name:    "x11 without xdotool",
+			env:     map[string]string{"DISPLAY": ":0"},
+			wantErr: true,
+		},
+		{
+			name:      "no session",
+			installed: []string{"wtype", "xdotool"},
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := func(key string) string { return tt.env[key] }
+			lookPath := func(file string) (string, error) {
+				for _, installed := range tt.installed {
+					if file == installed {
+						return "/usr/bin/" + file, nil
+					}
+				}
+				return "", exec.ErrNotFound
+			}
+
+			got, err := command(env, lookPath)
+			if tt.wantErr {
+				if !errors.Is(err, ErrUnavailable) {
+					t.Errorf("command() error = %v, want ErrUnavailable", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("command() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("command() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,18 @@
+//go:build !(darwin && cgo) && !linux && !windows
+
+package paste
+
+import (
+	"context"
+	"fmt"
+)
+
+// check fails: there is no way to press keys here, or, on macOS, this was
+// built without cgo
+func check() error {
+	return fmt.Errorf("%w on this platform", ErrUnavailable)
+}
+
+func paste(context.Context) error {
+	return check()
+}
@@ -0,0 +1,54 @@
+package paste
+
+import (
+	"context"
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+var sendInput = syscall.NewLazyDLL("user32.dll").NewProc("SendInput")
+
+const (
+	inputKeyboard = 1
+	keyEventKeyUp = 0x0002
+	vkControl     = 0x11
+	vkV           = 0x56
+)
+
+// keyboardInput is an INPUT holding a KEYBDINPUT, padded to the size of the
+// union's largest member, MOUSEINPUT
+type keyboardInput struct {
+	inputType uint32
+	ki        keybdInput
+	_         [8]byte
+}
+
+type keybdInput struct {
+	vk        uint16
+	scan      uint16
+	flags     uint32
+	time      uint32
+	extraInfo uintptr
+}
+
+// check has nothing to check: SendInput needs no permission, though
+// Windows drops input to apps running as administrator
+func check() error {
+	return sendInput.Find()
+}
+
+// paste presses Ctrl+V with SendInput
+func paste(ctx context.Context) error {
+	inputs := []keyboardInput{
+		{inputType: inputKeyboard, ki: keybdInput{vk: vkControl}},
+		{inputType: inputKeyboard, ki: keybdInput{vk: vkV}},
+		{inputType: inputKeyboard, ki: keybdInput{vk: vkV, flags: keyEventKeyUp}},
+		{inputType: inputKeyboard, ki: keybdInput{vk: vkControl, flags: keyEventKeyUp}},
+	}
+	sent, _, err := sendInput.Call(uintptr(len(inputs)), uintptr(unsafe.Pointer(&inputs[0])), unsafe.Sizeof(inputs[0]))
+	if int(sent) != len(inputs) {
+		return fmt.Errorf("%w: SendInput was blocked, as it is for apps running as administrator: %v", ErrPermission, err)
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+package plugin
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// maxMessageSize is the longest line read from a plugin
+const maxMessageSize = 64 << 20
+
+// exitTimeout is how long a plugin has to exit once its input is closed
+// before it is killed
+const exitTimeout = 5 * time.Second
+
+type request struct {
+	ID     int64       `json:"id"`
+	Method string      `json:"method"`
+	Params interface{} `json:"params,omitempty"`
+}
+
+type response struct {
+	ID     int64           `json:"id"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// conn is a running plugin process
+type conn struct {
+	name  string
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+
+	writeMu sync.Mutex // Keeps requests on their own lines
+	mu      sync.Mutex
+	nextID  int64
+	pending map[int64]chan response
+
+	done chan struct{} // Closed once the process has exited
+	err  error         // Why it exited, set before done is closed
+}
+
+// startConn starts the process of a plugin
+func startConn(config Config) (*conn, error) {
+	cmd := exec.Command(config.Command, config.Args...)
+	cmd.Env = append(os.Environ(), config.Env...)
+	cmd.Stderr = &logWriter{prefix: fmt.Sprintf("[plugin %s] ", config.Name)}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start %s: %w", config.Command, err)
+	}
+
+	c := &conn{
+		name:    config.Name,
+		cmd:     cmd,
+		stdin:   stdin,
+		pending: make(map[int64]chan response),
+		done:    make(chan struct{}),
+	}
+	go c.read(stdout)
+	return c, nil
+}
+
+// read hands responses to the requests waiting for them until the process
+// exits
+func (c *conn) read(stdout io.Reader) {
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), maxMessageSize)
+	for scanner.Scan() {
+		var resp response
+		if err := json.Unmarshal(scanner.Bytes(), &resp); err != nil {
+			log.Printf("[WARN] Plugin %s wrote an invalid response: %v", c.name, err)
+			continue
+		}
+		c.mu.Lock()
+		ch, ok := c.pending[resp.ID]
+		delete(c.pending, resp.ID)
+		c.mu.Unlock()
+		if ok {
+			ch <- resp
+		}
+	}
+	scanErr := scanner.Err()
+	if scanErr != nil {
+		// Unblock the plugin, which may be writing the rest of the line
+		c.cmd.Process.Kill()
+	}
+
+	err := c.cmd.Wait()
+	switch {
+	case scanErr != nil:
+		c.err = fmt.Errorf("failed to read from plugin: %w", scanErr)
+	case err != nil:
+		c.err = fmt.Errorf("plugin exited: %w", err)
+	default:
+		c.err = fmt.Errorf("plugin exited")
+	}
+	close(c.done)
+}
+
+// call sends a request and decodes its result into result
+func (c *conn) call(ctx context.Context, method string, params, result interface{}) error {
+	c.mu.Lock()
+	c.nextID++
+	id := c.nextID
+	ch := make(chan response, 1)
+	c.pending[id] = ch
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+
+	data, err := json.Marshal(request{ID: id, Method: method, Params: params})
+	if err != nil {
+		return err
+	}
+	c.writeMu.Lock()
+	_, err = c.stdin.Write(append(data, '\n'))
+	c.writeMu.Unlock()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrNotRunning, err)
+	}
+
+	select {
+	case resp := <-ch:
+		if resp.Error != "" {
+			return fmt.Errorf("%s", resp.Error)
+		}
+		if result == nil || len(resp.Result) == 0 {
+			return nil
+		}
+		if err := json.Unmarshal(resp.Result, result); err != nil {
+			return fmt.Errorf("invalid %s result: %w", method, err)
+		}
+		return nil
+	case <-c.done:
+		return fmt.Errorf("%w: %v", ErrNotRunning, c.err)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// close asks the plugin to exit by closing its input, killing it if it
+// hasn't after exitTimeout
+func (c *conn) close() {
+	c.stdin.Close()
+	select {
+	case <-c.done:
+	case <-time.After(exitTimeout):
+		c.cmd.Process.Kill()
+		<-c.done
+	}
+}
+
+// logWriter logs what a plugin writes to standard error, line by line
+type logWriter struct {
+	prefix string
+	mu     sync.Mutex
+	buf    []byte
+}
+
+func (w *logWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		log.Print(w.prefix + string(w.buf[:i]))
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
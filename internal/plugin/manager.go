@@ -0,0 +1,420 @@
+package plugin
+
+import (
+	"clipboard-manager/internal/storage"
+	"clipboard-manager/pkg/types"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Plugin states, as Info reports them
+const (
+	StateStarting = "starting"
+	StateRunning  = "running"
+	StateFailed   = "failed"  // Exited and waiting to restart, or given up on
+	StateStopped  = "stopped" // The daemon isn't running plugins
+	StateDisabled = "disabled"
+)
+
+// Timeouts of the requests made of plugins
+const (
+	manifestTimeout = 10 * time.Second
+	processTimeout  = 30 * time.Second
+	actionTimeout   = 2 * time.Minute
+)
+
+// Restarting after failures waits restartDelay, doubling with each failure
+// in a row up to maxRestartDelay. A plugin that fails maxFailures times in a
+// row is given up on until it is restarted by hand or its settings change.
+// One that ran for stableAfter has its failures forgotten.
+const (
+	restartDelay    = time.Second
+	maxRestartDelay = time.Minute
+	maxFailures     = 5
+	stableAfter     = time.Minute
+)
+
+// Info describes a plugin and how it is doing
+type Info struct {
+	Config
+	State     string     `json:"state"`
+	Error     string     `json:"error,omitempty"` // Why it last failed
+	Restarts  int        `json:"restarts"`
+	StartedAt *time.Time `json:"started_at,omitempty"`
+	Manifest
+}
+
+// Manager starts the configured plugins, restarts them when they fail and
+// sends them requests
+type Manager struct {
+	mu      sync.Mutex
+	plugins []*instance // In the order of the settings
+	running bool
+}
+
+// NewManager creates a manager without plugins
+func NewManager() *Manager {
+	return &Manager{}
+}
+
+// instance is a configured plugin and the process running it
+type instance struct {
+	config Config
+
+	mu        sync.Mutex
+	state     string
+	err       string
+	restarts  int
+	startedAt time.Time
+	conn      *conn // Set while running
+	manifest  Manifest
+
+	stop chan struct{} // Closed to stop supervising
+	done chan struct{} // Closed once supervising has stopped
+}
+
+// Apply changes the plugins to configs. Plugins whose settings are
+// unchanged keep running; others are stopped or started.
+func (m *Manager) Apply(configs []Config) {
+	m.mu.Lock()
+	existing := make(map[string]*instance, len(m.plugins))
+	for _, p := range m.plugins {
+		existing[p.config.Name] = p
+	}
+	var plugins, stale []*instance
+	for _, config := range configs {
+		if p, ok := existing[config.Name]; ok && p.config.equal(config) {
+			plugins = append(plugins, p)
+			delete(existing, config.Name)
+			continue
+		}
+		p := newInstance(config)
+		if m.running {
+			p.start()
+		}
+		plugins = append(plugins, p)
+	}
+	for _, p := range existing {
+		stale = append(stale, p)
+	}
+	m.plugins = plugins
+	m.mu.Unlock()
+
+	for _, p := range stale {
+		p.shutdown()
+	}
+}
+
+// Start starts the enabled plugins
+func (m *Manager) Start() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.running {
+		return
+	}
+	m.running = true
+	for _, p := range m.plugins {
+		p.start()
+	}
+}
+
+// Stop stops every plugin, waiting for them to exit
+func (m *Manager) Stop() {
+	m.mu.Lock()
+	m.running = false
+	plugins := m.plugins
+	m.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, p := range plugins {
+		wg.Add(1)
+		go func(p *instance) {
+			defer wg.Done()
+			p.shutdown()
+		}(p)
+	}
+	wg.Wait()
+}
+
+// Restart restarts a plugin, including one that was given up on
+func (m *Manager) Restart(name string) error {
+	m.mu.Lock()
+	var old, p *instance
+	for i, candidate := range m.plugins {
+		if candidate.config.Name == name {
+			old, p = candidate, newInstance(candidate.config)
+			m.plugins[i] = p
+		}
+	}
+	m.mu.Unlock()
+	if old == nil {
+		return ErrNotFound
+	}
+	if old.config.Disabled {
+		return fmt.Errorf("plugin %s is disabled", name)
+	}
+
+	// The new process starts once the old one has exited
+	old.shutdown()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.running {
+		p.start()
+	}
+	return nil
+}
+
+// List describes the plugins in the order of the settings
+func (m *Manager) List() []Info {
+	m.mu.Lock()
+	plugins := m.plugins
+	m.mu.Unlock()
+
+	list := make([]Info, 0, len(plugins))
+	for _, p := range plugins {
+		list = append(list, p.info())
+	}
+	return list
+}
+
+// Process runs the processors of the running plugins that take clip on it,
+// in the order of the settings. Processors that fail are skipped; their
+// errors are returned together. It reports whether a processor asked for
+// the clip to be dropped.
+func (m *Manager) Process(ctx context.Context, clip *types.Clip) (skip bool, err error) {
+	if clip.Metadata.ExpiresAt != nil {
+		return false, nil // Sensitive clips stay in the daemon
+	}
+	m.mu.Lock()
+	plugins := m.plugins
+	m.mu.Unlock()
+
+	var errs []error
+	for _, p := range plugins {
+		c, manifest := p.connection()
+		if c == nil {
+			continue
+		}
+		for _, processor := range manifest.Processors {
+			if !takesType(processor.Types, clip.Type) {
+				continue
+			}
+			var result ProcessResult
+			callCtx, cancel := context.WithTimeout(ctx, processTimeout)
+			err := c.call(callCtx, "process", map[string]interface{}{"processor": processor.Name, "clip": newClip(clip)}, &result)
+			cancel()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("plugin %s processor %s: %w", p.config.Name, processor.Name, err))
+				continue
+			}
+			result.apply(clip)
+			if result.Skip {
+				return true, errors.Join(errs...)
+			}
+		}
+	}
+	return false, errors.Join(errs...)
+}
+
+// RunAction runs an action of a plugin on clip
+func (m *Manager) RunAction(ctx context.Context, name, action string, clip *types.Clip) (*ActionResult, error) {
+	if clip.Metadata.ExpiresAt != nil {
+		return nil, ErrSensitive
+	}
+	m.mu.Lock()
+	var p *instance
+	for _, candidate := range m.plugins {
+		if candidate.config.Name == name {
+			p = candidate
+		}
+	}
+	m.mu.Unlock()
+	if p == nil {
+		return nil, ErrNotFound
+	}
+
+	c, manifest := p.connection()
+	if c == nil {
+		return nil, ErrNotRunning
+	}
+	found := false
+	for _, a := range manifest.Actions {
+		if a.Name == action {
+			found = true
+			if !takesType(a.Types, clip.Type) {
+				return nil, fmt.Errorf("%w: action %s of plugin %s doesn't take %s clips", storage.ErrInvalidType, action, name, clip.Type)
+			}
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("%w: plugin %s has no action %s", ErrNotFound, name, action)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, actionTimeout)
+	defer cancel()
+	var result ActionResult
+	if err := c.call(ctx, "action", map[string]interface{}{"action": action, "clip": newClip(clip)}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// equal reports whether c and other run the same plugin the same way
+func (c Config) equal(other Config) bool {
+	return c.Name == other.Name && c.Command == other.Command && c.Disabled == other.Disabled &&
+		slices.Equal(c.Args, other.Args) && slices.Equal(c.Env, other.Env)
+}
+
+func newInstance(config Config) *instance {
+	p := &instance{config: config, state: StateStopped}
+	if config.Disabled {
+		p.state = StateDisabled
+	}
+	return p
+}
+
+// start supervises the plugin's process, unless it is disabled or already
+// running
+func (p *instance) start() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.config.Disabled || p.stop != nil {
+		return
+	}
+	p.stop = make(chan struct{})
+	p.done = make(chan struct{})
+	p.state = StateStarting
+	go p.supervise(p.stop, p.done)
+}
+
+// shutdown stops the plugin's process and waits for it to exit
+func (p *instance) shutdown() {
+	p.mu.Lock()
+	stop, done := p.stop, p.done
+	p.stop, p.done = nil, nil
+	p.mu.Unlock()
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+}
+
+// supervise runs the plugin's process, restarting it when it exits, until
+// stop is closed
+func (p *instance) supervise(stop, done chan struct{}) {
+	defer close(done)
+	failures := 0
+	for {
+		started := time.Now()
+		c, err := p.launch()
+		if err == nil {
+			select {
+			case <-c.done:
+				err = c.err
+			case <-stop:
+				c.close()
+				p.stopped()
+				return
+			}
+			if time.Since(started) > stableAfter {
+				failures = 0
+			}
+		}
+
+		failures++
+		p.failed(err, failures >= maxFailures)
+		if failures >= maxFailures {
+			log.Printf("[ERROR] Plugin %s failed %d times in a row, not restarting it: %v", p.config.Name, failures, err)
+			<-stop
+			p.stopped()
+			return
+		}
+		delay := restartDelay << (failures - 1)
+		if delay > maxRestartDelay {
+			delay = maxRestartDelay
+		}
+		log.Printf("[WARN] Plugin %s failed, restarting in %v: %v", p.config.Name, delay, err)
+		select {
+		case <-time.After(delay):
+		case <-stop:
+			p.stopped()
+			return
+		}
+		p.mu.Lock()
+		p.restarts++
+		p.state = StateStarting
+		p.mu.Unlock()
+	}
+}
+
+// launch starts the plugin's process and asks for its manifest
+func (p *instance) launch() (*conn, error) {
+	c, err := startConn(p.config)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), manifestTimeout)
+	defer cancel()
+	var manifest Manifest
+	if err := c.call(ctx, "manifest", nil, &manifest); err != nil {
+		c.close()
+		return nil, fmt.Errorf("no manifest: %w", err)
+	}
+
+	p.mu.Lock()
+	p.conn, p.manifest = c, manifest
+	p.state, p.err = StateRunning, ""
+	p.startedAt = time.Now()
+	p.mu.Unlock()
+	return c, nil
+}
+
+// failed records why the plugin stopped running
+func (p *instance) failed(err error, givenUp bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.conn = nil
+	p.state = StateFailed
+	p.err = err.Error()
+	if givenUp {
+		p.err = fmt.Sprintf("gave up after %d failures in a row: %v", maxFailures, err)
+	}
+}
+
+func (p *instance) stopped() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.conn = nil
+	p.state = StateStopped
+}
+
+// connection returns the plugin's process and manifest, or nil if it isn't
+// running
+func (p *instance) connection() (*conn, Manifest) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.conn, p.manifest
+}
+
+func (p *instance) info() Info {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	info := Info{
+		Config:   p.config,
+		State:    p.state,
+		Error:    p.err,
+		Restarts: p.restarts,
+		Manifest: p.manifest,
+	}
+	if p.conn != nil {
+		started := p.startedAt
+		info.StartedAt = &started
+	}
+	return info
+}
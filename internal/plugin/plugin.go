@@ -0,0 +1,180 @@
+// Package plugin runs plugins: programs that add clip processors, such as
+// OCR or translation, and actions, such as sending a clip somewhere, without
+// changing the daemon.
+//
+// A plugin is an executable the daemon starts and keeps running. They talk
+// in JSON, one object per line: the daemon writes requests to the plugin's
+// standard input and reads responses from its standard output. What the
+// plugin writes to standard error goes to the daemon's log.
+//
+//	{"id": 1, "method": "manifest"}
+//	{"id": 1, "result": {"version": "1.0", "processors": [{"name": "ocr", "types": ["image"]}]}}
+//	{"id": 2, "method": "process", "params": {"processor": "ocr", "clip": {"type": "image/png", "content": "iVBO..."}}}
+//	{"id": 2, "result": {"text": "Words in the image", "tags": ["ocr"]}}
+//	{"id": 3, "method": "action", "params": {"action": "send", "clip": {...}}}
+//	{"id": 3, "error": "not signed in"}
+//
+// The daemon asks for the manifest first, then sends process requests for
+// each captured clip a processor takes and action requests when one is run.
+// Requests may be sent before earlier ones are answered; responses carry
+// the ID of their request. Closing standard input asks the plugin to exit.
+// A plugin that exits is restarted, waiting longer after each failure.
+package plugin
+
+import (
+	"clipboard-manager/internal/clipboard"
+	"clipboard-manager/pkg/types"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Errors reported for plugins and actions
+var (
+	ErrNotFound   = errors.New("plugin not found")
+	ErrNotRunning = errors.New("plugin is not running")
+	ErrSensitive  = errors.New("sensitive clips aren't given to plugins")
+)
+
+// Config is a plugin as the settings list it
+type Config struct {
+	Name     string   `json:"name"`
+	Command  string   `json:"command"` // Path of the executable
+	Args     []string `json:"args,omitempty"`
+	Env      []string `json:"env,omitempty"` // KEY=value pairs added to the daemon's environment
+	Disabled bool     `json:"disabled,omitempty"`
+}
+
+// validName matches plugin names, which appear in API paths
+var validName = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// Validate checks each plugin has a unique name and a command
+func Validate(plugins []Config) error {
+	names := make(map[string]bool, len(plugins))
+	for _, plugin := range plugins {
+		if !validName.MatchString(plugin.Name) {
+			return fmt.Errorf("plugin name %q must be letters, digits, dots, dashes or underscores", plugin.Name)
+		}
+		if names[plugin.Name] {
+			return fmt.Errorf("plugin %q is defined twice", plugin.Name)
+		}
+		names[plugin.Name] = true
+		if strings.TrimSpace(plugin.Command) == "" {
+			return fmt.Errorf("plugin %q needs a command", plugin.Name)
+		}
+		for _, env := range plugin.Env {
+			if key, _, ok := strings.Cut(env, "="); !ok || key == "" {
+				return fmt.Errorf("plugin %q has an invalid environment variable %q, expected KEY=value", plugin.Name, env)
+			}
+		}
+	}
+	return nil
+}
+
+// Manifest is what a plugin offers, answered to the manifest request
+type Manifest struct {
+	Version     string      `json:"version,omitempty"`
+	Description string      `json:"description,omitempty"`
+	Processors  []Processor `json:"processors,omitempty"`
+	Actions     []Action    `json:"actions,omitempty"`
+}
+
+// Processor is run on each captured clip of its types before it is stored
+type Processor struct {
+	Name  string   `json:"name"`
+	Types []string `json:"types,omitempty"` // Clip types or major types such as image; empty takes every clip
+}
+
+// Action is run on a clip when asked to
+type Action struct {
+	Name  string   `json:"name"`
+	Title string   `json:"title,omitempty"` // Shown in menus; defaults to the name
+	Types []string `json:"types,omitempty"` // As for Processor
+}
+
+// Clip is a clip as plugins receive it
+type Clip struct {
+	ID             string   `json:"id,omitempty"` // Empty for clips not stored yet
+	Type           string   `json:"type"`
+	Content        []byte   `json:"content"`        // Base64 encoded
+	Text           string   `json:"text,omitempty"` // The clip's text, for clips that have any
+	SourceApp      string   `json:"source_app,omitempty"`
+	SourceBundleID string   `json:"source_bundle_id,omitempty"`
+	Tags           []string `json:"tags,omitempty"`
+	Category       string   `json:"category,omitempty"`
+}
+
+func newClip(clip *types.Clip) Clip {
+	text, _ := clipboard.PlainText(clip)
+	return Clip{
+		ID:             clip.ID,
+		Type:           clip.Type,
+		Content:        clip.Content,
+		Text:           text,
+		SourceApp:      clip.Metadata.SourceApp,
+		SourceBundleID: clip.Metadata.SourceBundleID,
+		Tags:           clip.Metadata.Tags,
+		Category:       clip.Metadata.Category,
+	}
+}
+
+// ProcessResult is what a processor does to a clip. Tags are added, the
+// category is set unless the clip has one, and text is kept as the text of
+// clips that aren't plain text, so they can be searched: the words OCR
+// finds in an image, say. Skip drops the clip.
+type ProcessResult struct {
+	Tags     []string `json:"tags,omitempty"`
+	Category string   `json:"category,omitempty"`
+	Text     string   `json:"text,omitempty"`
+	Skip     bool     `json:"skip,omitempty"`
+}
+
+// apply makes the changes of r to clip
+func (r ProcessResult) apply(clip *types.Clip) {
+	for _, tag := range r.Tags {
+		if tag = strings.TrimSpace(tag); tag != "" && !contains(clip.Metadata.Tags, tag) {
+			clip.Metadata.Tags = append(clip.Metadata.Tags, tag)
+		}
+	}
+	if clip.Metadata.Category == "" {
+		clip.Metadata.Category = r.Category
+	}
+	if r.Text != "" && clip.Type != types.TypeText {
+		if clip.Metadata.Formats == nil {
+			clip.Metadata.Formats = make(map[string][]byte)
+		}
+		clip.Metadata.Formats[types.TypeText] = []byte(r.Text)
+	}
+}
+
+// ActionResult is what running an action did. Text, if any, is copied to
+// the clipboard, like the result of a transform.
+type ActionResult struct {
+	Message string `json:"message,omitempty"`
+	Text    string `json:"text,omitempty"`
+}
+
+// takesType reports whether a processor or action with accepted types
+// takes clips of clipType
+func takesType(accepted []string, clipType string) bool {
+	if len(accepted) == 0 {
+		return true
+	}
+	major := types.MajorType(clipType)
+	for _, t := range accepted {
+		if strings.EqualFold(t, major) || types.NormalizeType(t) == types.NormalizeType(clipType) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
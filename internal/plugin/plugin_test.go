@@ -0,0 +1,200 @@
+package plugin
+
+import (
+	"bufio"
+	"clipboard-manager/pkg/types"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestMain runs the test binary as a plugin when asked to, so tests have a
+// plugin to start
+func TestMain(m *testing.M) {
+	if os.Getenv("PLUGIN_TEST_HELPER") == "1" {
+		runHelper()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// runHelper is a plugin with an "upper" processor that tags text clips and
+// drops ones saying "drop", and an "upper" action that upper-cases them.
+// With PLUGIN_TEST_CRASH set it exits after answering the manifest.
+func runHelper() {
+	scanner := bufio.NewScanner(os.Stdin)
+	encoder := json.NewEncoder(os.Stdout)
+	for scanner.Scan() {
+		var req struct {
+			ID     int64  `json:"id"`
+			Method string `json:"method"`
+			Params struct {
+				Clip Clip `json:"clip"`
+			} `json:"params"`
+		}
+		json.Unmarshal(scanner.Bytes(), &req)
+		var result interface{}
+		switch req.Method {
+		case "manifest":
+			result = Manifest{
+				Version:    "1.0",
+				Processors: []Processor{{Name: "upper", Types: []string{"text"}}},
+				Actions:    []Action{{Name: "upper"}},
+			}
+		case "process":
+			result = ProcessResult{Tags: []string{"seen"}, Skip: req.Params.Clip.Text == "drop"}
+		case "action":
+			result = ActionResult{Message: "done", Text: strings.ToUpper(req.Params.Clip.Text)}
+		}
+		encoder.Encode(map[string]interface{}{"id": req.ID, "result": result})
+		fmt.Fprintln(os.Stderr, "handled", req.Method)
+		if os.Getenv("PLUGIN_TEST_CRASH") != "" {
+			return
+		}
+	}
+}
+
+func helperConfig(t *testing.T, name string, env ...string) Config {
+	executable, err := os.Executable()
+	if err != nil {
+		t.Fatalf("failed to find test binary: %v", err)
+	}
+	return Config{Name: name, Command: executable, Env: append([]string{"PLUGIN_TEST_HELPER=1"}, env...)}
+}
+
+// waitForState waits for the named plugin to reach state
+func waitForState(t *testing.T, m *Manager, name, state string) Info {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		for _, info := range m.List() {
+			if info.Name == name && info.State == state {
+				return info
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("plugin %s didn't become %s: %+v", name, state, m.List())
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		plugins []Config
+		valid   bool
+	}{
+		{"valid", []Config{{Name: "ocr", Command: "/usr/local/bin/ocr", Env: []string{"LANG=en"}}}, true},
+		{"no name", []Config{{Command: "ocr"}}, false},
+		{"name in a path", []Config{{Name: "a/b", Command: "ocr"}}, false},
+		{"no command", []Config{{Name: "ocr"}}, false},
+		{"twice", []Config{{Name: "ocr", Command: "a"}, {Name: "ocr", Command: "b"}}, false},
+		{"bad env", []Config{{Name: "ocr", Command: "a", Env: []string{"LANG"}}}, false},
+	}
+	for _, tt := range tests {
+		if err := Validate(tt.plugins); (err == nil) != tt.valid {
+			t.Errorf("%s: Validate() = %v, want valid %v", tt.name, err, tt.valid)
+		}
+	}
+}
+
+func TestManager(t *testing.T) {
+	m := NewManager()
+	m.Apply([]Config{helperConfig(t, "helper"), {Name: "off", Command: "missing", Disabled: true}})
+	if info := m.List()[0]; info.State != StateStopped {
+		t.Errorf("state before Start = %s, want %s", info.State, StateStopped)
+	}
+	m.Start()
+	defer m.Stop()
+
+	info := waitForState(t, m, "helper", StateRunning)
+	if info.Version != "1.0" || len(info.Processors) != 1 || info.StartedAt == nil {
+		t.Errorf("unexpected plugin info %+v", info)
+	}
+	if state := m.List()[1].State; state != StateDisabled {
+		t.Errorf("disabled plugin state = %s", state)
+	}
+
+	// Processors run on the clips they take
+	ctx := context.Background()
+	clip := &types.Clip{Type: types.TypeText, Content: []byte("hello")}
+	if skip, err := m.Process(ctx, clip); skip || err != nil {
+		t.Fatalf("Process() = %v, %v", skip, err)
+	}
+	if len(clip.Metadata.Tags) != 1 || clip.Metadata.Tags[0] != "seen" {
+		t.Errorf("tags = %v, want [seen]", clip.Metadata.Tags)
+	}
+	if skip, _ := m.Process(ctx, &types.Clip{Type: types.TypeText, Content: []byte("drop")}); !skip {
+		t.Error("expected the clip to be dropped")
+	}
+	image := &types.Clip{Type: types.TypePNG, Content: []byte("png")}
+	if m.Process(ctx, image); len(image.Metadata.Tags) != 0 {
+		t.Errorf("expected images to be left alone, got tags %v", image.Metadata.Tags)
+	}
+	expiring := time.Now().Add(time.Minute)
+	secret := &types.Clip{Type: types.TypeText, Content: []byte("secret"), Metadata: types.Metadata{ExpiresAt: &expiring}}
+	if m.Process(ctx, secret); len(secret.Metadata.Tags) != 0 {
+		t.Error("expected sensitive clips not to be given to plugins")
+	}
+
+	// Actions
+	result, err := m.RunAction(ctx, "helper", "upper", &types.Clip{Type: types.TypeText, Content: []byte("shout")})
+	if err != nil {
+		t.Fatalf("RunAction() error = %v", err)
+	}
+	if result.Message != "done" || result.Text != "SHOUT" {
+		t.Errorf("RunAction() = %+v", result)
+	}
+	if _, err := m.RunAction(ctx, "helper", "missing", clip); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound for an unknown action, got %v", err)
+	}
+	if _, err := m.RunAction(ctx, "off", "upper", clip); !errors.Is(err, ErrNotRunning) {
+		t.Errorf("expected ErrNotRunning for a disabled plugin, got %v", err)
+	}
+	if _, err := m.RunAction(ctx, "helper", "upper", secret); !errors.Is(err, ErrSensitive) {
+		t.Errorf("expected ErrSensitive, got %v", err)
+	}
+
+	// Restarting starts a new process; removing a plugin stops it
+	if err := m.Restart("helper"); err != nil {
+		t.Fatalf("Restart() error = %v", err)
+	}
+	waitForState(t, m, "helper", StateRunning)
+	if err := m.Restart("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound restarting an unknown plugin, got %v", err)
+	}
+	m.Apply(nil)
+	if list := m.List(); len(list) != 0 {
+		t.Errorf("expected no plugins, got %+v", list)
+	}
+}
+
+func TestManager_RestartsFailedPlugins(t *testing.T) {
+	m := NewManager()
+	m.Apply([]Config{helperConfig(t, "crashy", "PLUGIN_TEST_CRASH=1")})
+	m.Start()
+	defer m.Stop()
+
+	info := waitForState(t, m, "crashy", StateFailed)
+	if info.Error == "" {
+		t.Error("expected the failure to be reported")
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for m.List()[0].Restarts == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("plugin wasn't restarted: %+v", m.List()[0])
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	m.Stop()
+	if state := m.List()[0].State; state != StateStopped {
+		t.Errorf("state after Stop = %s, want %s", state, StateStopped)
+	}
+}
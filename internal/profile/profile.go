@@ -0,0 +1,95 @@
+// Package profile keeps separate clip histories, such as work and personal,
+// each with its own database, and remembers which one is active
+package profile
+
+import (
+	"clipboard-manager/internal/config"
+	"clipboard-manager/internal/storage"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Default is the profile whose history lives directly in the data directory
+const Default = "default"
+
+const (
+	activeFile = "profile"  // Holds the name of the active profile
+	dirName    = "profiles" // Holds a directory per profile other than Default
+)
+
+var ErrInvalidName = errors.New("invalid profile name, use up to 32 lower case letters, digits, - and _")
+
+// Opener opens the storage of a profile whose data lives in dir
+type Opener func(name, dir string) (storage.Storage, error)
+
+// Manager opens profiles under a data directory and remembers the active one
+type Manager struct {
+	baseDir string
+	open    Opener
+}
+
+// NewManager creates a manager for the profiles in baseDir
+func NewManager(baseDir string, open Opener) *Manager {
+	return &Manager{baseDir: baseDir, open: open}
+}
+
+// Dir returns the data directory of a profile
+func (m *Manager) Dir(name string) string {
+	if name == Default {
+		return m.baseDir
+	}
+	return filepath.Join(m.baseDir, dirName, name)
+}
+
+// Open opens the storage of a profile, creating its directory
+func (m *Manager) Open(name string) (storage.Storage, error) {
+	if !config.ValidProfileName(name) {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidName, name)
+	}
+	dir := m.Dir(name)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	return m.open(name, dir)
+}
+
+// Active returns the profile last made active, or Default
+func (m *Manager) Active() string {
+	data, err := os.ReadFile(filepath.Join(m.baseDir, activeFile))
+	if err != nil {
+		return Default
+	}
+	if name := strings.TrimSpace(string(data)); config.ValidProfileName(name) {
+		return name
+	}
+	return Default
+}
+
+// SetActive remembers name as the active profile for the next start
+func (m *Manager) SetActive(name string) error {
+	if !config.ValidProfileName(name) {
+		return fmt.Errorf("%w: %q", ErrInvalidName, name)
+	}
+	path := filepath.Join(m.baseDir, activeFile)
+	if err := os.WriteFile(path, []byte(name+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// List returns Default and every profile that has been opened, sorted
+func (m *Manager) List() []string {
+	names := []string{Default}
+	entries, _ := os.ReadDir(filepath.Join(m.baseDir, dirName))
+	for _, entry := range entries {
+		if entry.IsDir() && config.ValidProfileName(entry.Name()) && entry.Name() != Default {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names[1:])
+	return names
+}
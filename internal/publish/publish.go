@@ -0,0 +1,187 @@
+// Package publish uploads text clips to GitHub Gist or a 0x0-compatible
+// paste service and returns their link
+package publish
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Targets
+const (
+	Gist  = "gist"
+	Paste = "paste"
+)
+
+const (
+	// DefaultPasteURL is the paste service used when none is configured
+	DefaultPasteURL = "https://0x0.st"
+	// DefaultGistAPI is the GitHub API gists are created through
+	DefaultGistAPI = "https://api.github.com"
+
+	userAgent       = "clipboard-manager"
+	maxResponseSize = 64 * 1024
+)
+
+var (
+	ErrUnknownTarget = errors.New("unknown publish target, expected gist or paste")
+	ErrNoToken       = errors.New("publishing to gist needs a GitHub token in publish.gist_token or GITHUB_TOKEN")
+	ErrSensitive     = errors.New("sensitive clips are never published")
+	ErrUpload        = errors.New("upload failed")
+)
+
+// extensions names gist files so GitHub highlights them, keyed by the
+// languages snippet.Detect returns
+var extensions = map[string]string{
+	"go": ".go", "python": ".py", "javascript": ".js", "typescript": ".ts",
+	"rust": ".rs", "java": ".java", "c": ".c", "c++": ".cpp", "bash": ".sh",
+	"sql": ".sql", "php": ".php", "ruby": ".rb", "css": ".css", "json": ".json",
+	"yaml": ".yaml", "html": ".html", "markdown": ".md",
+}
+
+// FileName returns the name an uploaded clip gets, with the extension of its
+// language
+func FileName(language string) string {
+	if ext, ok := extensions[strings.ToLower(language)]; ok {
+		return "clip" + ext
+	}
+	return "clip.txt"
+}
+
+// Publisher uploads text to the configured services
+type Publisher struct {
+	Client     *http.Client
+	GistToken  string
+	GistPublic bool   // Public gists are listed on the owner's profile
+	GistAPI    string // "" uses DefaultGistAPI
+	PasteURL   string // "" uses DefaultPasteURL
+}
+
+// Publish uploads text as a file called filename to target and returns its
+// URL
+func (p Publisher) Publish(ctx context.Context, target, filename, text string) (string, error) {
+	switch target {
+	case Gist:
+		return p.gist(ctx, filename, text)
+	case Paste:
+		return p.paste(ctx, filename, text)
+	}
+	return "", fmt.Errorf("%w: %q", ErrUnknownTarget, target)
+}
+
+// gist creates a gist holding a single file
+func (p Publisher) gist(ctx context.Context, filename, text string) (string, error) {
+	if p.GistToken == "" {
+		return "", ErrNoToken
+	}
+	api := p.GistAPI
+	if api == "" {
+		api = DefaultGistAPI
+	}
+	body, err := json.Marshal(map[string]any{
+		"description": "Shared from clipboard-manager",
+		"public":      p.GistPublic,
+		"files":       map[string]any{filename: map[string]string{"content": text}},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode gist: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(api, "/")+"/gists", bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrUpload, err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+p.GistToken)
+	req.Header.Set("Content-Type", "application/json")
+	response, err := p.do(req)
+	if err != nil {
+		return "", err
+	}
+
+	var created struct {
+		HTMLURL string `json:"html_url"`
+	}
+	if err := json.Unmarshal(response, &created); err != nil || created.HTMLURL == "" {
+		return "", fmt.Errorf("%w: GitHub returned no gist URL", ErrUpload)
+	}
+	return created.HTMLURL, nil
+}
+
+// paste uploads a file the way 0x0.st and compatible services take it, as
+// the "file" field of a form, and reads the URL from the response
+func (p Publisher) paste(ctx context.Context, filename, text string) (string, error) {
+	endpoint := p.PasteURL
+	if endpoint == "" {
+		endpoint = DefaultPasteURL
+	}
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", filename)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode paste: %w", err)
+	}
+	io.WriteString(part, text)
+	if err := form.Close(); err != nil {
+		return "", fmt.Errorf("failed to encode paste: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, &body)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrUpload, err)
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	response, err := p.do(req)
+	if err != nil {
+		return "", err
+	}
+
+	link := strings.TrimSpace(string(response))
+	if parsed, err := url.Parse(link); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return "", fmt.Errorf("%w: %s returned no URL", ErrUpload, endpoint)
+	}
+	return link, nil
+}
+
+// do sends a request and returns the body of a successful response
+func (p Publisher) do(req *http.Request) ([]byte, error) {
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	req.Header.Set("User-Agent", userAgent)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUpload, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUpload, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		// GitHub explains errors in a message field
+		var failure struct {
+			Message string `json:"message"`
+		}
+		message := strings.TrimSpace(string(body))
+		if json.Unmarshal(body, &failure) == nil && failure.Message != "" {
+			message = failure.Message
+		}
+		if len(message) > 200 {
+			message = message[:200]
+		}
+		return nil, fmt.Errorf("%w: %s returned %s: %s", ErrUpload, req.URL.Host, resp.Status, message)
+	}
+	return body, nil
+}
@@ -0,0 +1,72 @@
+package publish
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPublish_Gist(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/gists" || r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"message": "Bad credentials"}`))
+			return
+		}
+		var gist struct {
+			Public bool
+			Files  map[string]struct{ Content string }
+		}
+		json.NewDecoder(r.Body).Decode(&gist)
+		if gist.Public || gist.Files["clip.go"].Content != "package main" {
+			t.Errorf("unexpected gist %+v", gist)
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"html_url": "https://gist.github.com/me/abc"}`))
+	}))
+	defer server.Close()
+
+	p := Publisher{Client: server.Client(), GistToken: "token", GistAPI: server.URL}
+	link, err := p.Publish(context.Background(), Gist, FileName("go"), "package main")
+	if err != nil || link != "https://gist.github.com/me/abc" {
+		t.Errorf("Publish() = %q, %v", link, err)
+	}
+
+	p.GistToken = "wrong"
+	if _, err := p.Publish(context.Background(), Gist, "clip.txt", "x"); !errors.Is(err, ErrUpload) {
+		t.Errorf("Publish() with a bad token error = %v, want ErrUpload", err)
+	}
+	p.GistToken = ""
+	if _, err := p.Publish(context.Background(), Gist, "clip.txt", "x"); !errors.Is(err, ErrNoToken) {
+		t.Errorf("Publish() without a token error = %v, want ErrNoToken", err)
+	}
+}
+
+func TestPublish_Paste(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		content, _ := io.ReadAll(file)
+		if header.Filename != "clip.txt" || string(content) != "hello" {
+			t.Errorf("unexpected upload %s: %q", header.Filename, content)
+		}
+		w.Write([]byte("https://0x0.st/abc.txt\n"))
+	}))
+	defer server.Close()
+
+	p := Publisher{Client: server.Client(), PasteURL: server.URL}
+	link, err := p.Publish(context.Background(), Paste, FileName(""), "hello")
+	if err != nil || link != "https://0x0.st/abc.txt" {
+		t.Errorf("Publish() = %q, %v", link, err)
+	}
+	if _, err := p.Publish(context.Background(), "pastebin", "clip.txt", "hello"); !errors.Is(err, ErrUnknownTarget) {
+		t.Errorf("Publish() to an unknown target error = %v, want ErrUnknownTarget", err)
+	}
+}
@@ -0,0 +1,35 @@
+// Package qr renders text clips as QR codes, so links, Wi-Fi settings
+// (WIFI:S:name;T:WPA;P:password;;) and short notes can be scanned with a phone
+package qr
+
+import (
+	"errors"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+const (
+	DefaultSize = 256
+	MinSize     = 64
+	MaxSize     = 1024
+
+	// MaxBytes is the most a code holds at the medium error correction level
+	MaxBytes = 2331
+)
+
+// ErrTooLong is returned for text that doesn't fit in a QR code
+var ErrTooLong = errors.New("text is too long for a QR code")
+
+// PNG returns text as a QR code image size pixels wide, clamped to
+// MinSize..MaxSize
+func PNG(text string, size int) ([]byte, error) {
+	if len(text) > MaxBytes {
+		return nil, ErrTooLong
+	}
+	size = min(max(size, MinSize), MaxSize)
+	code, err := qrcode.New(text, qrcode.Medium)
+	if err != nil {
+		return nil, errors.Join(ErrTooLong, err)
+	}
+	return code.PNG(size)
+}
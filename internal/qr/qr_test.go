@@ -0,0 +1,27 @@
+package qr
+
+import (
+	"bytes"
+	"errors"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func TestPNG(t *testing.T) {
+	code, err := PNG("WIFI:S:home;T:WPA;P:secret;;", 10)
+	if err != nil {
+		t.Fatalf("PNG() error = %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(code))
+	if err != nil {
+		t.Fatalf("PNG() returned an invalid image: %v", err)
+	}
+	if width := img.Bounds().Dx(); width != MinSize {
+		t.Errorf("width = %d, want the minimum %d", width, MinSize)
+	}
+
+	if _, err := PNG(strings.Repeat("x", MaxBytes+1), DefaultSize); !errors.Is(err, ErrTooLong) {
+		t.Errorf("PNG() of long text error = %v, want ErrTooLong", err)
+	}
+}
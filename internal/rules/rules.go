@@ -0,0 +1,235 @@
+// Package rules acts on clips as they are captured. Each rule has
+// conditions, such as a pattern the text matches or the app it was copied
+// from, and actions taken on the clips that meet them: tagging, filing
+// under a category, skipping, notifying or translating.
+package rules
+
+import (
+	"clipboard-manager/internal/clipboard"
+	"clipboard-manager/internal/translate"
+	"clipboard-manager/pkg/types"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Rule takes its actions on clips that meet all of its conditions
+type Rule struct {
+	Name     string    `json:"name"`
+	Disabled bool      `json:"disabled,omitempty"`
+	If       Condition `json:"if"`
+	Then     Actions   `json:"then"`
+}
+
+// Condition holds what a clip must match. Conditions left empty match any
+// clip, but a rule needs at least one.
+type Condition struct {
+	Pattern string `json:"pattern,omitempty"` // Regular expression matched against the clip's text
+	App     string `json:"app,omitempty"`     // Source app name or bundle ID, ignoring case
+	Type    string `json:"type,omitempty"`    // Clip type, or major type such as text or image
+	Secret  bool   `json:"secret,omitempty"`  // Text looks like a key, token or password, see LooksSecret
+}
+
+// Actions are taken on a matching clip. Tags add up across rules; the first
+// matching rule with a category sets it.
+type Actions struct {
+	Tags     []string `json:"tags,omitempty"`
+	Category string   `json:"category,omitempty"`
+	Skip     bool     `json:"skip,omitempty"`   // Don't record the clip
+	Notify   bool     `json:"notify,omitempty"` // Show a notification naming the rule
+
+	// Translate stores a translation of the clip's text into this
+	// language, such as en, alongside it. The first matching rule's wins.
+	Translate string `json:"translate,omitempty"`
+}
+
+// Result is what a set of rules does to a clip
+type Result struct {
+	Matched   []string `json:"matched"` // Names of the matching rules, in order
+	Tags      []string `json:"tags,omitempty"`
+	Category  string   `json:"category,omitempty"`
+	Skip      bool     `json:"skip"`
+	Notify    bool     `json:"notify"`
+	Translate string   `json:"translate,omitempty"`
+}
+
+// Apply adds the tags and category of r to clip. A category the clip
+// already has is kept.
+func (r Result) Apply(clip *types.Clip) {
+	for _, tag := range r.Tags {
+		if !contains(clip.Metadata.Tags, tag) {
+			clip.Metadata.Tags = append(clip.Metadata.Tags, tag)
+		}
+	}
+	if clip.Metadata.Category == "" {
+		clip.Metadata.Category = r.Category
+	}
+}
+
+// Validate checks rules can be compiled, and that each has a unique name,
+// a condition and an action
+func Validate(rules []Rule) error {
+	_, err := Compile(rules)
+	return err
+}
+
+// Find returns the index of the rule named name, or -1
+func Find(rules []Rule, name string) int {
+	for i, rule := range rules {
+		if rule.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Set is a compiled list of rules, ready to evaluate
+type Set struct {
+	rules []compiled
+}
+
+type compiled struct {
+	Rule
+	pattern *regexp.Regexp
+}
+
+// Compile checks and compiles rules. Disabled rules are checked but left
+// out of the set.
+func Compile(rules []Rule) (*Set, error) {
+	set := &Set{}
+	names := make(map[string]bool, len(rules))
+	for _, rule := range rules {
+		if strings.TrimSpace(rule.Name) == "" {
+			return nil, fmt.Errorf("every rule needs a name")
+		}
+		if names[rule.Name] {
+			return nil, fmt.Errorf("rule %q is defined twice", rule.Name)
+		}
+		names[rule.Name] = true
+
+		c := rule.If
+		if c.Pattern == "" && c.App == "" && c.Type == "" && !c.Secret {
+			return nil, fmt.Errorf("rule %q needs a condition: pattern, app, type or secret", rule.Name)
+		}
+		a := rule.Then
+		if len(a.Tags) == 0 && a.Category == "" && !a.Skip && !a.Notify && a.Translate == "" {
+			return nil, fmt.Errorf("rule %q needs an action: tags, category, skip, notify or translate", rule.Name)
+		}
+		if a.Translate != "" && !translate.ValidLanguage(a.Translate) {
+			return nil, fmt.Errorf("rule %q translates into %q, expected a language code such as en", rule.Name, a.Translate)
+		}
+		for _, tag := range a.Tags {
+			if strings.TrimSpace(tag) == "" {
+				return nil, fmt.Errorf("rule %q has an empty tag", rule.Name)
+			}
+		}
+
+		compiledRule := compiled{Rule: rule}
+		if c.Pattern != "" {
+			re, err := regexp.Compile(c.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("rule %q has an invalid pattern: %w", rule.Name, err)
+			}
+			compiledRule.pattern = re
+		}
+		if !rule.Disabled {
+			set.rules = append(set.rules, compiledRule)
+		}
+	}
+	return set, nil
+}
+
+// Len returns the number of enabled rules
+func (s *Set) Len() int {
+	if s == nil {
+		return 0
+	}
+	return len(s.rules)
+}
+
+// Evaluate runs the rules against clip without changing it. A nil set
+// matches nothing.
+func (s *Set) Evaluate(clip *types.Clip) Result {
+	result := Result{Matched: []string{}}
+	if s.Len() == 0 {
+		return result
+	}
+	text, hasText := clipboard.PlainText(clip)
+
+	for _, rule := range s.rules {
+		if !rule.matches(clip, text, hasText) {
+			continue
+		}
+		result.Matched = append(result.Matched, rule.Name)
+		for _, tag := range rule.Then.Tags {
+			if !contains(result.Tags, tag) {
+				result.Tags = append(result.Tags, tag)
+			}
+		}
+		if result.Category == "" {
+			result.Category = rule.Then.Category
+		}
+		result.Skip = result.Skip || rule.Then.Skip
+		result.Notify = result.Notify || rule.Then.Notify
+		if result.Translate == "" {
+			result.Translate = rule.Then.Translate
+		}
+	}
+	return result
+}
+
+// matches reports whether clip, with text if it has any, meets every
+// condition of the rule
+func (r compiled) matches(clip *types.Clip, text string, hasText bool) bool {
+	c := r.If
+	if c.App != "" && !strings.EqualFold(c.App, clip.Metadata.SourceApp) && !strings.EqualFold(c.App, clip.Metadata.SourceBundleID) {
+		return false
+	}
+	if c.Type != "" {
+		if !strings.EqualFold(c.Type, types.MajorType(clip.Type)) && types.NormalizeType(c.Type) != types.NormalizeType(clip.Type) {
+			return false
+		}
+	}
+	if r.pattern != nil && (!hasText || !r.pattern.MatchString(text)) {
+		return false
+	}
+	if c.Secret && (!hasText || !LooksSecret(text)) {
+		return false
+	}
+	return true
+}
+
+// secretPatterns match credentials in the formats services issue them in
+var secretPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----`),
+	regexp.MustCompile(`\b(AKIA|ASIA)[0-9A-Z]{16}\b`),                                        // AWS access key
+	regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b`),                                     // GitHub token
+	regexp.MustCompile(`\bgithub_pat_[A-Za-z0-9_]{60,}\b`),                                   // GitHub fine-grained token
+	regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}\b`),                                  // Slack token
+	regexp.MustCompile(`\b[rs]k_live_[A-Za-z0-9]{20,}\b`),                                    // Stripe key
+	regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`),                                          // Google API key
+	regexp.MustCompile(`\bsk-[A-Za-z0-9_-]{32,}\b`),                                          // OpenAI-style API key
+	regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{10,}\.eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}`), // JSON Web Token
+	regexp.MustCompile(`(?i)\b(password|passwd|secret|api[_-]?key|token)\s*[:=]\s*\S{8,}`),   // Assignments in config files
+}
+
+// LooksSecret reports whether text contains something that looks like a
+// credential: a private key, a cloud or API token, or a password assigned
+// in a config file
+func LooksSecret(text string) bool {
+	for _, pattern := range secretPatterns {
+		if pattern.MatchString(text) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,369 @@
+package script
+
+import (
+	"clipboard-manager/internal/clipboard"
+	"clipboard-manager/pkg/types"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime/metrics"
+	"strings"
+	"sync"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// runTimeout is how long a script has to load or to process a clip
+const runTimeout = time.Second
+
+// Limits on the stacks of a script, which also bound its recursion
+const (
+	callStackSize   = 200
+	registrySize    = 1024 * 20
+	registryMaxSize = 1024 * 80
+)
+
+// Limits on the memory of a script. Lua can't count what a state
+// allocates, so string.rep, which builds a string in one call, is checked
+// before it runs, and the heap is watched for everything else. A script is
+// stopped once the heap grows by memoryLimit while it runs.
+const maxStringSize = 16 << 20
+
+var (
+	memoryLimit uint64 = 128 << 20
+	memoryPoll         = 10 * time.Millisecond
+)
+
+// heapMetric is the runtime metric memoryLimit is checked against
+const heapMetric = "/memory/classes/heap/objects:bytes"
+
+// errMemory ends a script that used more than memoryLimit
+var errMemory = errors.New("script used too much memory")
+
+// processFunction is the function a script defines
+const processFunction = "process"
+
+// blocked are the base functions scripts don't get, which load code or
+// files
+var blocked = []string{"dofile", "load", "loadfile", "loadstring", "module", "require"}
+
+// script is a loaded script file
+type script struct {
+	name    string
+	path    string
+	modTime time.Time
+	size    int64
+
+	mu       sync.Mutex // Lua states run one call at a time
+	state    *lua.LState
+	loadedAt time.Time
+	err      string
+	errAt    time.Time
+	runs     int
+	failures int
+}
+
+// load compiles and runs a script file in a new sandbox. A script that
+// fails to load is returned without a state and with its error.
+func load(file scriptFile, allowFiles bool) *script {
+	s := &script{name: file.name, path: file.path, modTime: file.modTime, size: file.size}
+	state := newState(s.name, allowFiles)
+	ctx, cancel := withLimits(context.Background())
+	defer cancel()
+	state.SetContext(ctx)
+
+	err := limitError(ctx, run(state, file.path))
+	if err == nil {
+		if _, ok := state.GetGlobal(processFunction).(*lua.LFunction); !ok {
+			err = fmt.Errorf("no %s function", processFunction)
+		}
+	}
+	if err != nil {
+		state.Close()
+		s.err, s.errAt = luaError(err), time.Now()
+		return s
+	}
+	s.state, s.loadedAt = state, time.Now()
+	return s
+}
+
+// run runs the file at path in state, naming it by its file name in errors
+func run(state *lua.LState, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fn, err := state.Load(f, filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	state.Push(fn)
+	return state.PCall(0, lua.MultRet, nil)
+}
+
+// withLimits returns a context for running a script, which ends after
+// runTimeout or once the heap grows by memoryLimit
+func withLimits(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancelCause := context.WithCancelCause(parent)
+	ctx, cancelTimeout := context.WithTimeout(ctx, runTimeout)
+	go func() {
+		sample := []metrics.Sample{{Name: heapMetric}}
+		metrics.Read(sample)
+		start := sample[0].Value.Uint64()
+		ticker := time.NewTicker(memoryPoll)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			metrics.Read(sample)
+			if sample[0].Value.Uint64() > start+memoryLimit {
+				cancelCause(errMemory)
+				return
+			}
+		}
+	}()
+	return ctx, func() {
+		cancelTimeout()
+		cancelCause(nil)
+	}
+}
+
+// limitError is err, or errMemory if the script was stopped for using too
+// much memory rather than failing by itself
+func limitError(ctx context.Context, err error) error {
+	if err != nil && errors.Is(context.Cause(ctx), errMemory) {
+		return errMemory
+	}
+	return err
+}
+
+// strRep is string.rep, refusing to build strings over maxStringSize
+func strRep(L *lua.LState) int {
+	str := L.CheckString(1)
+	n := L.CheckInt(2)
+	if n > 0 && len(str) > 0 && n > maxStringSize/len(str) {
+		L.RaiseError("string.rep: result is over %d bytes", maxStringSize)
+	}
+	L.Push(lua.LString(strings.Repeat(str, max(n, 0))))
+	return 1
+}
+
+// newState creates a sandbox for the script name
+func newState(name string, allowFiles bool) *lua.LState {
+	state := lua.NewState(lua.Options{
+		SkipOpenLibs:    true,
+		CallStackSize:   callStackSize,
+		RegistrySize:    registrySize,
+		RegistryMaxSize: registryMaxSize,
+	})
+	for _, lib := range []struct {
+		name string
+		open lua.LGFunction
+	}{
+		{lua.BaseLibName, lua.OpenBase},
+		{lua.TabLibName, lua.OpenTable},
+		{lua.StringLibName, lua.OpenString},
+		{lua.MathLibName, lua.OpenMath},
+	} {
+		state.Push(state.NewFunction(lib.open))
+		state.Push(lua.LString(lib.name))
+		state.Call(1, 0)
+	}
+	for _, fn := range blocked {
+		state.SetGlobal(fn, lua.LNil)
+	}
+	if stringLib, ok := state.GetGlobal(lua.StringLibName).(*lua.LTable); ok {
+		stringLib.RawSetString("dump", lua.LNil)
+		stringLib.RawSetString("rep", state.NewFunction(strRep))
+	}
+
+	logFn := state.NewFunction(func(L *lua.LState) int {
+		parts := make([]string, 0, L.GetTop())
+		for i := 1; i <= L.GetTop(); i++ {
+			parts = append(parts, L.ToStringMeta(L.Get(i)).String())
+		}
+		log.Printf("[script %s] %s", name, strings.Join(parts, " "))
+		return 0
+	})
+	state.SetGlobal("log", logFn)
+	state.SetGlobal("print", logFn)
+	if allowFiles {
+		state.SetGlobal("files", state.SetFuncs(state.NewTable(), map[string]lua.LGFunction{
+			"read":  readFile,
+			"write": writeFile,
+		}))
+	}
+	return state
+}
+
+// readFile is files.read(path), returning the file's content or nil and an
+// error
+func readFile(L *lua.LState) int {
+	data, err := os.ReadFile(L.CheckString(1))
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+	L.Push(lua.LString(data))
+	return 1
+}
+
+// writeFile is files.write(path, content), returning true or nil and an
+// error
+func writeFile(L *lua.LState) int {
+	if err := os.WriteFile(L.CheckString(1), []byte(L.CheckString(2)), 0644); err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+	L.Push(lua.LTrue)
+	return 1
+}
+
+// run calls the script's process function on clip and makes its changes,
+// returning false if the script drops the clip. A script that failed to
+// load is skipped.
+func (s *script) run(ctx context.Context, clip *types.Clip) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.state == nil {
+		return true, nil
+	}
+
+	ctx, cancel := withLimits(ctx)
+	defer cancel()
+	state := s.state
+	state.SetContext(ctx)
+	defer state.RemoveContext()
+
+	text, _ := clipboard.PlainText(clip)
+	table := clipTable(s.state, clip, text)
+	s.runs++
+	err := limitError(ctx, s.state.CallByParam(lua.P{
+		Fn:      s.state.GetGlobal(processFunction),
+		NRet:    1,
+		Protect: true,
+	}, table))
+	if err != nil {
+		s.failures++
+		s.err, s.errAt = luaError(err), time.Now()
+		// What it holds on to is only freed with its state, so it stays
+		// unloaded until it changes
+		if errors.Is(err, errMemory) {
+			state.Close()
+			s.state = nil
+		}
+		return true, errors.New(s.err)
+	}
+	result := s.state.Get(-1)
+	s.state.Pop(1)
+	if result == lua.LFalse {
+		return false, nil
+	}
+	applyTable(table, clip, text)
+	return true, nil
+}
+
+// clipTable is clip as scripts are given it
+func clipTable(L *lua.LState, clip *types.Clip, text string) *lua.LTable {
+	tags := L.NewTable()
+	for _, tag := range clip.Metadata.Tags {
+		tags.Append(lua.LString(tag))
+	}
+	table := L.NewTable()
+	table.RawSetString("type", lua.LString(clip.Type))
+	table.RawSetString("text", lua.LString(text))
+	table.RawSetString("size", lua.LNumber(len(clip.Content)))
+	table.RawSetString("source_app", lua.LString(clip.Metadata.SourceApp))
+	table.RawSetString("source_bundle_id", lua.LString(clip.Metadata.SourceBundleID))
+	table.RawSetString("category", lua.LString(clip.Metadata.Category))
+	table.RawSetString("tags", tags)
+	return table
+}
+
+// applyTable makes the changes a script made to the table of clip. Changed
+// text replaces the content of plain text clips and is kept as the text of
+// others, so they can be searched.
+func applyTable(table *lua.LTable, clip *types.Clip, text string) {
+	var tags []string
+	if list, ok := table.RawGetString("tags").(*lua.LTable); ok {
+		list.ForEach(func(_, value lua.LValue) {
+			tag := strings.TrimSpace(lua.LVAsString(value))
+			if tag != "" && !contains(tags, tag) {
+				tags = append(tags, tag)
+			}
+		})
+	}
+	clip.Metadata.Tags = tags
+	clip.Metadata.Category = lua.LVAsString(table.RawGetString("category"))
+
+	changed := lua.LVAsString(table.RawGetString("text"))
+	switch {
+	case changed == text:
+	case clip.Type == types.TypeText:
+		clip.Content = []byte(changed)
+	default:
+		if clip.Metadata.Formats == nil {
+			clip.Metadata.Formats = make(map[string][]byte)
+		}
+		clip.Metadata.Formats[types.TypeText] = []byte(changed)
+	}
+}
+
+func (s *script) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.state != nil {
+		s.state.Close()
+		s.state = nil
+	}
+}
+
+func (s *script) info() Info {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	info := Info{
+		Name:     s.name,
+		Path:     s.path,
+		Loaded:   s.state != nil,
+		Error:    s.err,
+		Runs:     s.runs,
+		Failures: s.failures,
+	}
+	if s.state != nil {
+		loadedAt := s.loadedAt
+		info.LoadedAt = &loadedAt
+	}
+	if s.err != "" {
+		errAt := s.errAt
+		info.ErrorAt = &errAt
+	}
+	return info
+}
+
+// luaError is the message of an error from Lua, without its stack trace
+func luaError(err error) string {
+	var apiErr *lua.ApiError
+	if errors.As(err, &apiErr) {
+		return apiErr.Object.String()
+	}
+	return err.Error()
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,245 @@
+// Package script runs Lua scripts users drop in a directory on each
+// captured clip. A script defines a process function that is given the clip
+// and can tag it, file it, change its text or drop it:
+//
+//	function process(clip)
+//	  if clip.text:match("JIRA%-%d+") then
+//	    table.insert(clip.tags, "jira")
+//	    clip.category = "work"
+//	  end
+//	  clip.text = clip.text:gsub("%s+$", "")
+//	  if clip.source_app == "1Password" then
+//	    return false -- Drops the clip
+//	  end
+//	end
+//
+// Scripts run in name order, each in its own sandbox with the base, string,
+// table and math libraries and log. They can't load code or touch files
+// unless files are allowed, which adds files.read and files.write. Scripts
+// are reloaded when they change; one that fails to load or run is skipped
+// and its error is reported.
+package script
+
+import (
+	"clipboard-manager/pkg/types"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Extension is the extension of script files
+const Extension = ".lua"
+
+// Config is the scripts section of the settings
+type Config struct {
+	Dir        string `json:"dir,omitempty"` // Where scripts are read from, ~/.clipboard-manager/scripts by default
+	Disabled   bool   `json:"disabled,omitempty"`
+	AllowFiles bool   `json:"allow_files,omitempty"` // Gives scripts files.read and files.write
+}
+
+// Validate checks the scripts settings
+func (c Config) Validate() error {
+	if c.Dir != "" && !filepath.IsAbs(c.Dir) {
+		return fmt.Errorf("scripts dir %q must be an absolute path", c.Dir)
+	}
+	return nil
+}
+
+// Info describes a script and how it is doing
+type Info struct {
+	Name     string     `json:"name"`
+	Path     string     `json:"path"`
+	Loaded   bool       `json:"loaded"`
+	LoadedAt *time.Time `json:"loaded_at,omitempty"`
+	Error    string     `json:"error,omitempty"` // Why it failed to load, or last failed to run
+	ErrorAt  *time.Time `json:"error_at,omitempty"`
+	Runs     int        `json:"runs"`
+	Failures int        `json:"failures"`
+}
+
+// Engine loads the scripts in a directory, reloads them when they change
+// and runs them on clips
+type Engine struct {
+	mu      sync.Mutex
+	config  Config
+	scripts []*script // In name order
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// NewEngine creates an engine without scripts
+func NewEngine() *Engine {
+	return &Engine{}
+}
+
+// Apply changes the scripts settings, loading the scripts in the directory
+// and watching it for changes. An empty directory runs no scripts.
+func (e *Engine) Apply(config Config) {
+	e.mu.Lock()
+	if config == e.config {
+		e.mu.Unlock()
+		return
+	}
+	e.config = config
+	e.mu.Unlock()
+
+	e.stopWatching()
+	e.reload(true)
+	if config.Dir == "" || config.Disabled {
+		return
+	}
+	stop, done := make(chan struct{}), make(chan struct{})
+	e.mu.Lock()
+	e.stop, e.done = stop, done
+	e.mu.Unlock()
+	go e.watch(config.Dir, stop, done)
+}
+
+// Close stops watching for changes and unloads the scripts
+func (e *Engine) Close() {
+	e.stopWatching()
+	e.mu.Lock()
+	scripts := e.scripts
+	e.scripts = nil
+	e.config = Config{}
+	e.mu.Unlock()
+	for _, s := range scripts {
+		s.close()
+	}
+}
+
+// List describes the scripts in name order
+func (e *Engine) List() []Info {
+	e.mu.Lock()
+	scripts := e.scripts
+	e.mu.Unlock()
+
+	list := make([]Info, 0, len(scripts))
+	for _, s := range scripts {
+		list = append(list, s.info())
+	}
+	return list
+}
+
+// Run runs the scripts on clip in name order. Scripts that fail are skipped;
+// their errors are returned together. It returns false if a script drops
+// the clip. Sensitive clips aren't given to scripts.
+func (e *Engine) Run(ctx context.Context, clip *types.Clip) (keep bool, err error) {
+	if clip.Metadata.ExpiresAt != nil {
+		return true, nil
+	}
+	e.mu.Lock()
+	scripts := e.scripts
+	e.mu.Unlock()
+
+	var errs []error
+	for _, s := range scripts {
+		keep, err := s.run(ctx, clip)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("script %s: %w", s.name, err))
+			continue
+		}
+		if !keep {
+			return false, errors.Join(errs...)
+		}
+	}
+	return true, errors.Join(errs...)
+}
+
+func (e *Engine) stopWatching() {
+	e.mu.Lock()
+	stop, done := e.stop, e.done
+	e.stop, e.done = nil, nil
+	e.mu.Unlock()
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+}
+
+// reload loads the scripts in the directory. Scripts whose files haven't
+// changed are kept unless all is set.
+func (e *Engine) reload(all bool) {
+	e.mu.Lock()
+	config := e.config
+	old := e.scripts
+	e.mu.Unlock()
+
+	existing := make(map[string]*script, len(old))
+	for _, s := range old {
+		existing[s.path] = s
+	}
+	var scripts []*script
+	if config.Dir != "" && !config.Disabled {
+		files, err := scriptFiles(config.Dir)
+		if err != nil {
+			log.Printf("[ERROR] Failed to read scripts: %v", err)
+		}
+		for _, file := range files {
+			if s, ok := existing[file.path]; ok && !all && s.modTime.Equal(file.modTime) && s.size == file.size {
+				scripts = append(scripts, s)
+				delete(existing, file.path)
+				continue
+			}
+			s := load(file, config.AllowFiles)
+			if s.state != nil {
+				log.Printf("Loaded script %s", s.name)
+			} else {
+				log.Printf("[ERROR] Failed to load script %s: %s", s.name, s.err)
+			}
+			scripts = append(scripts, s)
+		}
+	}
+
+	e.mu.Lock()
+	e.scripts = scripts
+	e.mu.Unlock()
+	for _, s := range existing {
+		s.close()
+	}
+}
+
+// scriptFile is a script file as found in the directory
+type scriptFile struct {
+	name    string
+	path    string
+	modTime time.Time
+	size    int64
+}
+
+// scriptFiles lists the scripts in dir in name order. A missing directory
+// has none.
+func scriptFiles(dir string) ([]scriptFile, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var files []scriptFile
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != Extension || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue // Removed since the directory was read
+		}
+		files = append(files, scriptFile{
+			name:    strings.TrimSuffix(entry.Name(), Extension),
+			path:    filepath.Join(dir, entry.Name()),
+			modTime: info.ModTime(),
+			size:    info.Size(),
+		})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].name < files[j].name })
+	return files, nil
+}
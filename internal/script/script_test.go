@@ -0,0 +1,235 @@
+package script
+
+import (
+	"clipboard-manager/pkg/types"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeScript(t *testing.T, dir, name, source string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name+Extension), []byte(source), 0644); err != nil {
+		t.Fatalf("failed to write script: %v", err)
+	}
+}
+
+// waitFor waits for the scripts to be described by want
+func waitFor(t *testing.T, e *Engine, want func([]Info) bool) []Info {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		list := e.List()
+		if want(list) {
+			return list
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("scripts didn't change as expected: %+v", list)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestEngine(t *testing.T) {
+	dir := t.TempDir()
+	writeScript(t, dir, "10-jira", `
+function process(clip)
+  if clip.text:match("JIRA%-%d+") then
+    table.insert(clip.tags, "jira")
+    clip.category = "work"
+  end
+  clip.text = clip.text:gsub("%s+$", "")
+end`)
+	writeScript(t, dir, "20-drop", `
+function process(clip)
+  if clip.source_app == "1Password" then
+    return false
+  end
+end`)
+	writeScript(t, dir, "30-broken", `function process(clip) return clip.missing.field end`)
+	writeScript(t, dir, "40-syntax", `function process(clip`)
+
+	e := NewEngine()
+	e.Apply(Config{Dir: dir})
+	defer e.Close()
+
+	list := e.List()
+	if len(list) != 4 || !list[0].Loaded || list[3].Loaded || list[3].Error == "" {
+		t.Fatalf("unexpected scripts %+v", list)
+	}
+
+	ctx := context.Background()
+	clip := &types.Clip{Type: types.TypeText, Content: []byte("fix JIRA-123  \n")}
+	keep, err := e.Run(ctx, clip)
+	if !keep || err == nil || !strings.Contains(err.Error(), "30-broken") {
+		t.Errorf("Run() = %v, %v, want the clip kept and the broken script's error", keep, err)
+	}
+	if string(clip.Content) != "fix JIRA-123" || clip.Metadata.Category != "work" || len(clip.Metadata.Tags) != 1 {
+		t.Errorf("clip after scripts = %q %+v", clip.Content, clip.Metadata)
+	}
+	if keep, _ := e.Run(ctx, &types.Clip{Type: types.TypeText, Content: []byte("pw"), Metadata: types.Metadata{SourceApp: "1Password"}}); keep {
+		t.Error("expected the clip to be dropped")
+	}
+
+	html := &types.Clip{Type: types.TypeHTML, Content: []byte("<b>JIRA-1</b> "), Metadata: types.Metadata{Formats: map[string][]byte{types.TypeText: []byte("JIRA-1 ")}}}
+	e.Run(ctx, html)
+	if string(html.Content) != "<b>JIRA-1</b> " || string(html.Metadata.Formats[types.TypeText]) != "JIRA-1" {
+		t.Errorf("expected only the text of an HTML clip to change, got %q and %q", html.Content, html.Metadata.Formats[types.TypeText])
+	}
+
+	expiring := time.Now().Add(time.Minute)
+	secret := &types.Clip{Type: types.TypeText, Content: []byte("JIRA-9"), Metadata: types.Metadata{ExpiresAt: &expiring}}
+	if e.Run(ctx, secret); len(secret.Metadata.Tags) != 0 {
+		t.Error("expected sensitive clips not to be given to scripts")
+	}
+
+	broken := e.List()[2]
+	if broken.Failures != 2 || broken.Runs != 2 || !strings.HasPrefix(broken.Error, "30-broken.lua:1:") || broken.ErrorAt == nil {
+		t.Errorf("broken script info = %+v", broken)
+	}
+}
+
+func TestEngine_Sandbox(t *testing.T) {
+	dir := t.TempDir()
+	secret := filepath.Join(dir, "secret.txt")
+	os.WriteFile(secret, []byte("hunter2"), 0644)
+	writeScript(t, dir, "escape", `
+function process(clip)
+  if io or os or require or dofile or loadstring or files then
+    table.insert(clip.tags, "escaped")
+  end
+end`)
+	writeScript(t, dir, "read", `
+function process(clip)
+  clip.text = files.read("`+secret+`")
+end`)
+	writeScript(t, dir, "spin", `
+function process(clip)
+  if clip.text == "spin" then
+    while true do end
+  end
+end`)
+
+	e := NewEngine()
+	e.Apply(Config{Dir: dir})
+	defer e.Close()
+
+	clip := &types.Clip{Type: types.TypeText, Content: []byte("hello")}
+	if _, err := e.Run(context.Background(), clip); err == nil {
+		t.Error("expected reading files to fail without allow_files")
+	}
+	if len(clip.Metadata.Tags) != 0 || string(clip.Content) != "hello" {
+		t.Errorf("script escaped the sandbox: %q %v", clip.Content, clip.Metadata.Tags)
+	}
+
+	start := time.Now()
+	if _, err := e.Run(context.Background(), &types.Clip{Type: types.TypeText, Content: []byte("spin")}); err == nil || time.Since(start) > runTimeout+time.Second {
+		t.Errorf("expected the spinning script to time out, got %v after %v", err, time.Since(start))
+	}
+
+	e.Apply(Config{Dir: dir, AllowFiles: true})
+	clip = &types.Clip{Type: types.TypeText, Content: []byte("hello")}
+	e.Run(context.Background(), clip)
+	if string(clip.Content) != "hunter2" {
+		t.Errorf("expected files.read with allow_files, got %q", clip.Content)
+	}
+}
+
+func TestEngine_Memory(t *testing.T) {
+	limit := memoryLimit
+	memoryLimit = 32 << 20
+	defer func() { memoryLimit = limit }()
+
+	dir := t.TempDir()
+	writeScript(t, dir, "rep", `
+function process(clip)
+  if clip.text == "rep" then
+    clip.text = string.rep("x", 1073741824)
+  elseif clip.text == "method" then
+    clip.text = ("xy"):rep(536870912)
+  end
+end`)
+	writeScript(t, dir, "grow", `
+function process(clip)
+  if clip.text == "grow" then
+    local s = string.rep("x", 1048576)
+    for i = 1, 12 do
+      s = s .. s
+    end
+    clip.text = s
+  end
+end`)
+
+	e := NewEngine()
+	e.Apply(Config{Dir: dir})
+	defer e.Close()
+
+	for _, text := range []string{"rep", "method"} {
+		clip := &types.Clip{Type: types.TypeText, Content: []byte(text)}
+		if _, err := e.Run(context.Background(), clip); err == nil || !strings.Contains(err.Error(), "string.rep") {
+			t.Errorf("expected string.rep to refuse a huge string, got %v", err)
+		}
+		if string(clip.Content) != text {
+			t.Errorf("clip changed to %d bytes", len(clip.Content))
+		}
+	}
+
+	clip := &types.Clip{Type: types.TypeText, Content: []byte("grow")}
+	if _, err := e.Run(context.Background(), clip); err == nil || !strings.Contains(err.Error(), errMemory.Error()) {
+		t.Errorf("expected the growing script to be stopped, got %v", err)
+	}
+	list := e.List()
+	if len(list) != 2 || list[0].Name != "grow" || list[0].Loaded || !list[1].Loaded {
+		t.Errorf("expected only grow to be unloaded, got %+v", list)
+	}
+
+	// Small strings still work
+	clip = &types.Clip{Type: types.TypeText, Content: []byte("hello")}
+	if _, err := e.Run(context.Background(), clip); err != nil {
+		t.Errorf("Run() error = %v", err)
+	}
+}
+
+func TestEngine_Reload(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "scripts")
+	e := NewEngine()
+	e.Apply(Config{Dir: dir})
+	defer e.Close()
+
+	// The directory is created and watched
+	waitFor(t, e, func([]Info) bool {
+		_, err := os.Stat(dir)
+		return err == nil
+	})
+	writeScript(t, dir, "tag", `function process(clip) table.insert(clip.tags, "one") end`)
+	waitFor(t, e, func(list []Info) bool { return len(list) == 1 && list[0].Loaded })
+
+	writeScript(t, dir, "tag", `function process(clip) table.insert(clip.tags, "two") end`)
+	waitFor(t, e, func([]Info) bool {
+		clip := &types.Clip{Type: types.TypeText, Content: []byte("x")}
+		e.Run(context.Background(), clip)
+		return len(clip.Metadata.Tags) == 1 && clip.Metadata.Tags[0] == "two"
+	})
+
+	os.Remove(filepath.Join(dir, "tag"+Extension))
+	waitFor(t, e, func(list []Info) bool { return len(list) == 0 })
+
+	e.Apply(Config{Dir: dir, Disabled: true})
+	writeScript(t, dir, "tag", `function process(clip) end`)
+	time.Sleep(3 * reloadDelay)
+	if list := e.List(); len(list) != 0 {
+		t.Errorf("expected disabled scripts not to load, got %+v", list)
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	if err := (Config{Dir: "scripts"}).Validate(); err == nil {
+		t.Error("expected a relative directory to be rejected")
+	}
+	if err := (Config{Dir: t.TempDir()}).Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+}
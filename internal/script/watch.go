@@ -0,0 +1,60 @@
+package script
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// reloadDelay lets an editor finish writing before scripts are reloaded
+const reloadDelay = 100 * time.Millisecond
+
+// watch reloads the scripts whenever a script in dir changes, until stop is
+// closed
+func (e *Engine) watch(dir string, stop, done chan struct{}) {
+	defer close(done)
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.Printf("[WARN] Scripts won't reload: %v", err)
+		return
+	}
+	defer watcher.Close()
+
+	// Created so scripts can be dropped in
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		log.Printf("[WARN] Scripts won't reload: failed to create %s: %v", dir, err)
+		return
+	}
+	if err := watcher.Add(dir); err != nil {
+		log.Printf("[WARN] Scripts won't reload: failed to watch %s: %v", dir, err)
+		return
+	}
+
+	reload := time.NewTimer(reloadDelay)
+	reload.Stop()
+	defer reload.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if filepath.Ext(event.Name) == Extension {
+				reload.Reset(reloadDelay)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("[WARN] Scripts watcher: %v", err)
+		case <-reload.C:
+			e.reload(false)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"clipboard-manager/internal/audit"
+	"clipboard-manager/internal/trace"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// audited records requests to a route in the audit log as action, once they
+// are answered
+func (s *Server) audited(action string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if s.config.Audit == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r)
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			s.recordAudit(r, action, status)
+		})
+	}
+}
+
+// recordAudit adds a request to the audit log, if there is one
+func (s *Server) recordAudit(r *http.Request, action string, status int) {
+	if s.config.Audit == nil {
+		return
+	}
+	entry := audit.Entry{
+		Action:  action,
+		Remote:  r.RemoteAddr,
+		Client:  r.UserAgent(),
+		Method:  r.Method,
+		Path:    r.URL.Path,
+		Status:  status,
+		Request: trace.RequestID(r.Context()),
+	}
+	if entry.Client == audit.CLIUserAgent {
+		entry.Client = "cli"
+	}
+	if user, ok := requestUser(r); ok {
+		entry.User = user.Name
+	}
+	if token, ok := requestToken(r); ok {
+		entry.Token = token.ID
+	}
+	if id := chi.URLParam(r, "id"); id != "" {
+		entry.Clip = id
+	} else if index := chi.URLParam(r, "index"); index != "" {
+		entry.Clip = index
+	}
+	if err := s.config.Audit.Record(entry); err != nil {
+		log.Printf("[ERROR] Failed to write the audit log: %v", err)
+	}
+}
+
+// handleGetAudit returns audit log entries, newest first. ?action= and
+// ?user= filter them, ?since= takes a duration like 24h or an RFC 3339 time
+// and ?limit= defaults to 100.
+func (s *Server) handleGetAudit(w http.ResponseWriter, r *http.Request) {
+	if s.config.Audit == nil {
+		writeError(w, r, http.StatusNotImplemented, "the audit log is not available")
+		return
+	}
+
+	filter := audit.Filter{
+		Action: r.URL.Query().Get("action"),
+		User:   r.URL.Query().Get("user"),
+		Limit:  100,
+	}
+	if l := r.URL.Query().Get("limit"); l != "" {
+		limit, err := strconv.Atoi(l)
+		if err != nil || limit < 0 {
+			writeError(w, r, http.StatusBadRequest, "invalid limit")
+			return
+		}
+		filter.Limit = limit
+	}
+	if since := r.URL.Query().Get("since"); since != "" {
+		if d, err := time.ParseDuration(since); err == nil {
+			filter.Since = time.Now().Add(-d)
+		} else if t, err := time.Parse(time.RFC3339, since); err == nil {
+			filter.Since = t
+		} else {
+			writeError(w, r, http.StatusBadRequest, "invalid since, expected a duration like 24h or an RFC 3339 time")
+			return
+		}
+	}
+
+	entries, err := s.config.Audit.Query(filter)
+	if err != nil {
+		writeServiceError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+	if entries == nil {
+		entries = []audit.Entry{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
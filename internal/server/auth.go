@@ -0,0 +1,179 @@
+package server
+
+import (
+	"clipboard-manager/internal/audit"
+	"clipboard-manager/internal/auth"
+	"clipboard-manager/internal/clipboard"
+	"clipboard-manager/internal/paste"
+	"clipboard-manager/internal/service"
+	"clipboard-manager/internal/speech"
+	"clipboard-manager/internal/trace"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// userKey is the request context key of the authenticated user
+type userKey struct{}
+
+// tokenKey is the request context key of the token a request was
+// authenticated with
+type tokenKey struct{}
+
+// requestUser returns the user a request was authenticated as, if users are
+// enabled
+func requestUser(r *http.Request) (auth.User, bool) {
+	user, ok := r.Context().Value(userKey{}).(auth.User)
+	return user, ok
+}
+
+// requestToken returns the token a request was authenticated with, if users
+// are enabled
+func requestToken(r *http.Request) (auth.Token, bool) {
+	token, ok := r.Context().Value(tokenKey{}).(auth.Token)
+	return token, ok
+}
+
+// authenticate requires a valid bearer token once there are users. Without
+// users every request goes through, as before.
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		users := s.config.Users
+		if users == nil || !users.Enabled() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		user, record, err := users.Authenticate(strings.TrimSpace(token))
+		if !ok || err != nil {
+			s.recordAudit(r, audit.ActionDenied, http.StatusUnauthorized)
+			w.Header().Set("WWW-Authenticate", `Bearer realm="clipboard-manager"`)
+			writeError(w, r, http.StatusUnauthorized, auth.ErrUnauthorized.Error())
+			return
+		}
+		ctx := context.WithValue(r.Context(), userKey{}, user)
+		ctx = context.WithValue(ctx, tokenKey{}, record)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// requireScope limits a route to tokens that grant scope. Without users
+// there are no tokens, and every route is open.
+func (s *Server) requireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if token, ok := requestToken(r); ok && !token.Allows(scope) {
+				s.recordAudit(r, audit.ActionDenied, http.StatusForbidden)
+				w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="clipboard-manager", error="insufficient_scope", scope=%q`, scope))
+				writeError(w, r, http.StatusForbidden, fmt.Sprintf("this token doesn't have the %s scope", scope))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// requireAdmin limits a route to admins, for everything that reaches beyond
+// a user's own history: the system clipboard, settings and share links
+func requireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, ok := requestUser(r); ok && !user.Admin {
+			writeError(w, r, http.StatusForbidden, "only admins can do this")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// service returns the service holding the history of the request's user:
+// the daemon's own for admins and when users are off, otherwise one backed
+// by the user's own database
+func (s *Server) service(r *http.Request) *service.ClipboardService {
+	user, ok := requestUser(r)
+	if !ok || user.Admin {
+		return s.clipService
+	}
+	svc, err := s.userService(user.Name)
+	if err != nil {
+		// Never fall back to another history
+		trace.Logf(r.Context(), "[ERROR] Failed to open the history of %s: %v", user.Name, err)
+		return nil
+	}
+	return svc
+}
+
+// withUserService answers 503 when a user's history can't be opened, so
+// handlers can rely on service returning one
+func (s *Server) withUserService(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.service(r) == nil {
+			writeError(w, r, http.StatusServiceUnavailable, "history is not available")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// userService returns the service of a user's history, opening it the first
+// time. These services only serve the API: they don't watch the clipboard
+// or press keys and speak.
+func (s *Server) userService(name string) (*service.ClipboardService, error) {
+	s.usersMu.Lock()
+	defer s.usersMu.Unlock()
+	if svc, ok := s.userServices[name]; ok {
+		return svc, nil
+	}
+	store, err := s.config.OpenUserStore(name)
+	if err != nil {
+		return nil, err
+	}
+	svc := service.New(clipboard.NewMemoryMonitor(), store)
+	// Keys pressed and speech would reach the desktop of whoever runs the daemon
+	svc.SetPaster(paste.Disabled("only admins can paste into the desktop"))
+	svc.SetSpeaker(speech.Disabled("only admins can read clips aloud"))
+	if s.userServices == nil {
+		s.userServices = make(map[string]*service.ClipboardService)
+	}
+	s.userServices[name] = svc
+	return svc, nil
+}
+
+// userSummary is a user as listed to admins, without token hashes
+type userSummary struct {
+	Name      string    `json:"name"`
+	Admin     bool      `json:"admin"`
+	CreatedAt time.Time `json:"created_at"`
+	Tokens    int       `json:"tokens"`
+	Clips     *int64    `json:"clips,omitempty"` // Nil when the history can't be read
+}
+
+// handleGetUsers lists the users and the size of their histories
+func (s *Server) handleGetUsers(w http.ResponseWriter, r *http.Request) {
+	summaries := []userSummary{}
+	if s.config.Users != nil {
+		for _, user := range s.config.Users.List() {
+			summary := userSummary{
+				Name:      user.Name,
+				Admin:     user.Admin,
+				CreatedAt: user.CreatedAt,
+				Tokens:    len(user.Tokens),
+			}
+			svc := s.clipService
+			if !user.Admin {
+				svc, _ = s.userService(user.Name)
+			}
+			if svc != nil {
+				if stats, err := svc.Stats(r.Context()); err == nil {
+					summary.Clips = &stats.Clips
+				}
+			}
+			summaries = append(summaries, summary)
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summaries)
+}
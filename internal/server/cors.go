@@ -0,0 +1,94 @@
+package server
+
+import (
+	"clipboard-manager/internal/config"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// corsMethods are the methods pages on allowed origins may use
+const corsMethods = "GET, POST, PUT, PATCH, DELETE"
+
+// corsSettings returns the CORS settings, none if there are no settings
+func (s *Server) corsSettings() config.CORS {
+	if s.config.Settings == nil {
+		return config.CORS{}
+	}
+	return s.config.Settings.Current().CORS
+}
+
+// cors adds CORS headers to /api and /ws responses for pages on allowed
+// origins, and answers their preflight requests before authentication, since
+// browsers send those without a token. Other pages may read nothing, and
+// change nothing either: browsers send them form and text POSTs without
+// asking first.
+func (s *Server) cors(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" || !(r.URL.Path == "/ws" || strings.HasPrefix(r.URL.Path, "/api/")) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		settings := s.corsSettings()
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+		w.Header().Add("Vary", "Origin")
+		if !settings.Allows(origin) {
+			if preflight || (!safeMethod(r.Method) && !sameOrigin(r)) {
+				writeError(w, r, http.StatusForbidden, "origin not allowed")
+				return
+			}
+			// Same-origin requests and non-browser clients need no headers;
+			// browsers block responses to other pages
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if settings.Credentials {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+		} else if len(settings.Origins) == 1 && settings.Origins[0] == "*" {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+		} else {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+		}
+		if !preflight {
+			w.Header().Set("Access-Control-Expose-Headers", "Content-Disposition, WWW-Authenticate, X-Request-Id")
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Access-Control-Request-Method")
+		w.Header().Add("Vary", "Access-Control-Request-Headers")
+		w.Header().Set("Access-Control-Allow-Methods", corsMethods)
+		headers := r.Header.Get("Access-Control-Request-Headers")
+		if headers == "" {
+			headers = "Authorization, Content-Type"
+		}
+		w.Header().Set("Access-Control-Allow-Headers", headers)
+		w.Header().Set("Access-Control-Max-Age", "600")
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// safeMethod reports whether method only reads
+func safeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// sameOrigin reports whether r comes from a page served by the daemon
+func sameOrigin(r *http.Request) bool {
+	u, err := url.Parse(r.Header.Get("Origin"))
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
+// checkOrigin lets the WebSocket be opened by clients that aren't browsers,
+// pages served by the daemon and pages on the allowed origins
+func (s *Server) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" || sameOrigin(r) {
+		return true
+	}
+	return s.corsSettings().Allows(origin)
+}
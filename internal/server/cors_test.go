@@ -0,0 +1,57 @@
+package server
+
+import (
+	"clipboard-manager/internal/config"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestServer_CORS(t *testing.T) {
+	ts := newTestServer(t)
+	settings := config.FromEnv()
+	settings.CORS.Origins = []string{"https://dash.example.com"}
+	ts.config.Settings = config.NewBus(settings)
+
+	send := func(method, path, origin string) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(method, ts.url+path, strings.NewReader("text"))
+		if err != nil {
+			t.Fatalf("NewRequest: %v", err)
+		}
+		req.Header.Set("Content-Type", "text/plain")
+		req.Header.Set("Origin", origin)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		return resp
+	}
+
+	// Other sites can't change anything with the simple requests browsers
+	// send them without a preflight
+	if resp := send(http.MethodPost, "/api/pause", "https://evil.example"); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("POST from another site = %d, want 403", resp.StatusCode)
+	}
+	if resp := send(http.MethodPost, "/api/clips", "https://evil.example"); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("POST of a clip from another site = %d, want 403", resp.StatusCode)
+	}
+	if paused, _ := ts.svc.IsPaused(); paused {
+		t.Error("another site paused capture")
+	}
+	// nor read what they get back
+	resp := send(http.MethodGet, "/api/clips", "https://evil.example")
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("GET from another site = %d with origin %q, want no CORS headers", resp.StatusCode, resp.Header.Get("Access-Control-Allow-Origin"))
+	}
+
+	if resp := send(http.MethodPost, "/api/clips", "https://dash.example.com"); resp.StatusCode != http.StatusCreated || resp.Header.Get("Access-Control-Allow-Origin") != "https://dash.example.com" {
+		t.Errorf("POST from an allowed origin = %d, want 201 with CORS headers", resp.StatusCode)
+	}
+	if resp := send(http.MethodPost, "/api/clips", ts.url); resp.StatusCode != http.StatusCreated {
+		t.Errorf("POST from the daemon's own pages = %d, want 201", resp.StatusCode)
+	}
+}
@@ -0,0 +1,24 @@
+package server
+
+import (
+	_ "embed"
+	"fmt"
+	"net/http"
+)
+
+// dashboardHTML is the web UI served at /. It uses the API like any other
+// client, with a token when the daemon requires one.
+//
+//go:embed web/index.html
+var dashboardHTML []byte
+
+// handleDashboard serves the web UI. The page holds no clips, so it needs no
+// token itself.
+func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	// Inline script and style only; clips are rendered as text and
+	// thumbnails loaded as blobs
+	w.Header().Set("Content-Security-Policy", fmt.Sprintf("default-src 'none'; script-src 'unsafe-inline'; style-src 'unsafe-inline'; img-src blob:; connect-src 'self' ws://%[1]s wss://%[1]s; frame-ancestors 'none'", r.Host))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Write(dashboardHTML)
+}
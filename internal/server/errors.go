@@ -0,0 +1,115 @@
+package server
+
+import (
+	"clipboard-manager/internal/paste"
+	"clipboard-manager/internal/service"
+	"clipboard-manager/internal/speech"
+	"clipboard-manager/internal/storage"
+	"clipboard-manager/internal/trace"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// errorCodes names each status the API answers with, for clients that would
+// rather switch on a string
+var errorCodes = map[int]string{
+	http.StatusBadRequest:            "bad_request",
+	http.StatusUnauthorized:          "unauthorized",
+	http.StatusForbidden:             "forbidden",
+	http.StatusNotFound:              "not_found",
+	http.StatusMethodNotAllowed:      "method_not_allowed",
+	http.StatusConflict:              "conflict",
+	http.StatusRequestEntityTooLarge: "too_large",
+	http.StatusUnsupportedMediaType:  "unsupported_type",
+	http.StatusUnprocessableEntity:   "invalid",
+	http.StatusInternalServerError:   "internal",
+	http.StatusNotImplemented:        "not_implemented",
+	http.StatusBadGateway:            "upstream_failed",
+	http.StatusServiceUnavailable:    "unavailable",
+}
+
+// apiError describes a failed request. Code follows the status, Message is
+// meant for people, and Details holds what caused it when that's safe to
+// show.
+type apiError struct {
+	Code      string            `json:"code"`
+	Message   string            `json:"message"`
+	Details   map[string]string `json:"details,omitempty"`
+	RequestID string            `json:"request_id,omitempty"`
+}
+
+// errorResponse is the body of every error response from /api and /ws
+type errorResponse struct {
+	Error apiError `json:"error"`
+}
+
+// writeError answers with a JSON error
+func writeError(w http.ResponseWriter, r *http.Request, status int, message string) {
+	writeAPIError(w, r, status, apiError{Message: message})
+}
+
+// writeServiceError answers with a JSON error for err, an error from the
+// service or storage. Not-found, too-large, changed-file and paste
+// simulation errors get their own status, otherwise status is used. The
+// causes of server errors are logged rather than returned.
+func writeServiceError(w http.ResponseWriter, r *http.Request, err error, status int) {
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, storage.ErrFileTooLarge):
+		status = http.StatusRequestEntityTooLarge
+	case errors.Is(err, storage.ErrFileChanged):
+		status = http.StatusConflict
+	case errors.Is(err, paste.ErrPermission):
+		status = http.StatusForbidden
+	case errors.Is(err, paste.ErrUnavailable), errors.Is(err, speech.ErrUnavailable):
+		status = http.StatusNotImplemented
+	}
+
+	e := apiError{Message: err.Error()}
+	var clipErr *service.ClipboardError
+	if errors.As(err, &clipErr) {
+		e.Message = clipErr.Message
+		if clipErr.Err != nil && status < http.StatusInternalServerError {
+			cause := clipErr.Err.Error()
+			var inner *service.ClipboardError
+			if errors.As(clipErr.Err, &inner) {
+				cause = inner.Message
+			}
+			if cause != e.Message {
+				e.Details = map[string]string{"cause": cause}
+			}
+		}
+	}
+	if status >= http.StatusInternalServerError && status != http.StatusBadGateway {
+		trace.Logf(r.Context(), "[ERROR] %s %s: %v", r.Method, r.URL.Path, err)
+		if clipErr == nil {
+			e.Message = http.StatusText(status)
+		}
+	}
+	writeAPIError(w, r, status, e)
+}
+
+func writeAPIError(w http.ResponseWriter, r *http.Request, status int, e apiError) {
+	e.Code = errorCodes[status]
+	if e.Code == "" {
+		e.Code = "error"
+	}
+	e.RequestID = trace.RequestID(r.Context())
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(errorResponse{Error: e})
+}
+
+// handleNotFound answers requests for routes under /api that don't exist
+func handleNotFound(w http.ResponseWriter, r *http.Request) {
+	writeError(w, r, http.StatusNotFound, "no such route")
+}
+
+// handleMethodNotAllowed answers requests under /api with the wrong method
+func handleMethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	writeError(w, r, http.StatusMethodNotAllowed, "method not allowed")
+}
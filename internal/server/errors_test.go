@@ -0,0 +1,84 @@
+package server
+
+import (
+	"clipboard-manager/internal/service"
+	"clipboard-manager/internal/storage"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWriteServiceError(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		status      int
+		wantStatus  int
+		wantCode    string
+		wantMessage string
+		wantCause   string
+	}{
+		{
+			name: "not found",
+			err: &service.ClipboardError{Op: "GetClipByID", Index: -1, Message: "clip 7 not found",
+				Err: fmt.Errorf("failed to get clip: %w", storage.ErrNotFound)},
+			status:      http.StatusInternalServerError,
+			wantStatus:  http.StatusNotFound,
+			wantCode:    "not_found",
+			wantMessage: "clip 7 not found",
+			wantCause:   "failed to get clip: clip not found",
+		},
+		{
+			name:        "too large",
+			err:         &service.ClipboardError{Op: "AddClip", Index: -1, Message: "failed to store clip", Err: storage.ErrFileTooLarge},
+			status:      http.StatusInternalServerError,
+			wantStatus:  http.StatusRequestEntityTooLarge,
+			wantCode:    "too_large",
+			wantMessage: "failed to store clip",
+			wantCause:   storage.ErrFileTooLarge.Error(),
+		},
+		{
+			name:        "validation",
+			err:         errors.New("invalid character 'h'"),
+			status:      http.StatusUnprocessableEntity,
+			wantStatus:  http.StatusUnprocessableEntity,
+			wantCode:    "invalid",
+			wantMessage: "invalid character 'h'",
+		},
+		{
+			name:        "internal cause is hidden",
+			err:         errors.New("database is locked"),
+			status:      http.StatusInternalServerError,
+			wantStatus:  http.StatusInternalServerError,
+			wantCode:    "internal",
+			wantMessage: "Internal Server Error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			writeServiceError(w, httptest.NewRequest(http.MethodGet, "/api/clips", nil), tt.err, tt.status)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			var resp errorResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("body is not a JSON error: %v", err)
+			}
+			if resp.Error.Code != tt.wantCode {
+				t.Errorf("code = %q, want %q", resp.Error.Code, tt.wantCode)
+			}
+			if resp.Error.Message != tt.wantMessage {
+				t.Errorf("message = %q, want %q", resp.Error.Message, tt.wantMessage)
+			}
+			if cause := resp.Error.Details["cause"]; cause != tt.wantCause {
+				t.Errorf("cause = %q, want %q", cause, tt.wantCause)
+			}
+		})
+	}
+}
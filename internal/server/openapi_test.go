@@ -0,0 +1,58 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// TestOpenAPI_CoversRoutes checks that openapi.json documents every route
+// and only routes that exist
+func TestOpenAPI_CoversRoutes(t *testing.T) {
+	var spec struct {
+		OpenAPI string                                `json:"openapi"`
+		Paths   map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(openAPISpec, &spec); err != nil {
+		t.Fatalf("openapi.json is not valid JSON: %v", err)
+	}
+	if !strings.HasPrefix(spec.OpenAPI, "3.") {
+		t.Errorf("openapi = %q, want 3.x", spec.OpenAPI)
+	}
+
+	documented := make(map[string]bool)
+	for path, operations := range spec.Paths {
+		for method := range operations {
+			documented[strings.ToUpper(method)+" "+path] = true
+		}
+	}
+
+	routes := make(map[string]bool)
+	err := chi.Walk((&Server{}).routes(), func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		switch {
+		case route == "/":
+			return nil // The dashboard isn't part of the API
+		case route == "/metrics" && method != http.MethodGet:
+			return nil // Registered for every method, but only GET is meant
+		}
+		routes[method+" "+route] = true
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Walk: %v", err)
+	}
+
+	for route := range routes {
+		if !documented[route] {
+			t.Errorf("%s is missing from openapi.json", route)
+		}
+	}
+	for route := range documented {
+		if !routes[route] {
+			t.Errorf("openapi.json documents %s, which isn't routed", route)
+		}
+	}
+}
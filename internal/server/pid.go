@@ -1,11 +1,16 @@
 package server
 
 import (
+	"encoding/json"
 	"fmt"
+	"log"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"syscall"
+	"time"
 )
 
 // pidFile manages the PID file for the server
@@ -47,7 +52,7 @@ func (p *pidFile) read() (int, error) {
 		return 0, err
 	}
 
-	pid, err := strconv.Atoi(string(data))
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
 	if err != nil {
 		return 0, fmt.Errorf("invalid PID in file: %w", err)
 	}
@@ -92,3 +97,73 @@ func killProcess(pid int) error {
 
 	return nil
 }
+
+// RunningPID returns the PID of the running daemon, or 0 if none is running
+func RunningPID() (int, error) {
+	pidFile, err := newPIDFile()
+	if err != nil {
+		return 0, err
+	}
+	pid, err := pidFile.read()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read PID file: %w", err)
+	}
+	if pid == 0 || !isRunning(pid) {
+		return 0, nil
+	}
+	return pid, nil
+}
+
+// CheckExisting looks for a daemon started earlier. A healthy one, answering
+// on port, is left alone and reported as an error unless replace is set.
+// Anything else holding the PID file is terminated and the file removed.
+func CheckExisting(port int, replace bool) error {
+	pidFile, err := newPIDFile()
+	if err != nil {
+		return err
+	}
+	existingPID, err := pidFile.read()
+	if err != nil {
+		return fmt.Errorf("failed to read PID file: %w", err)
+	}
+	if existingPID == 0 || existingPID == os.Getpid() {
+		return nil
+	}
+
+	if isRunning(existingPID) {
+		if !replace && isHealthy(port, existingPID) {
+			return fmt.Errorf("clipboard manager is already running (PID: %d), stop it first or use -replace", existingPID)
+		}
+		log.Printf("Found existing clipboard manager process (PID: %d), attempting to terminate", existingPID)
+		if err := killProcess(existingPID); err != nil {
+			return fmt.Errorf("failed to terminate existing process: %w", err)
+		}
+		// Give the process time to cleanup
+		time.Sleep(500 * time.Millisecond)
+	}
+
+	// Clean up stale PID file
+	if err := pidFile.remove(); err != nil {
+		return fmt.Errorf("failed to remove stale PID file: %w", err)
+	}
+	return nil
+}
+
+// isHealthy reports whether the daemon with pid answers status checks on port
+func isHealthy(port, pid int) bool {
+	client := &http.Client{Timeout: 2 * time.Second}
+	resp, err := client.Get(fmt.Sprintf("http://localhost:%d/status", port))
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+
+	var status struct {
+		Status string `json:"status"`
+		PID    int    `json:"pid"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return false
+	}
+	return status.Status == "ok" && status.PID == pid
+}
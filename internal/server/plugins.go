@@ -0,0 +1,51 @@
+package server
+
+import (
+	"clipboard-manager/internal/plugin"
+	"clipboard-manager/internal/storage"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func (s *Server) handleGetPlugins(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.service(r).Plugins())
+}
+
+func (s *Server) handleRestartPlugin(w http.ResponseWriter, r *http.Request) {
+	if err := s.service(r).RestartPlugin(chi.URLParam(r, "name")); err != nil {
+		writeServiceError(w, r, err, pluginErrorStatus(err, http.StatusConflict))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleRunPluginAction runs an action of a plugin on a clip
+func (s *Server) handleRunPluginAction(w http.ResponseWriter, r *http.Request) {
+	result, err := s.service(r).RunPluginAction(r.Context(), chi.URLParam(r, "id"), chi.URLParam(r, "name"), chi.URLParam(r, "action"))
+	if err != nil {
+		writeServiceError(w, r, err, pluginErrorStatus(err, http.StatusBadGateway))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// pluginErrorStatus maps a plugin error to a status, or fallback, such as
+// for errors the plugin reported
+func pluginErrorStatus(err error, fallback int) int {
+	switch {
+	case errors.Is(err, plugin.ErrNotFound), errors.Is(err, storage.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, plugin.ErrNotRunning):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, plugin.ErrSensitive):
+		return http.StatusForbidden
+	case errors.Is(err, storage.ErrInvalidType):
+		return http.StatusUnsupportedMediaType
+	}
+	return fallback
+}
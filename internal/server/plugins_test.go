@@ -0,0 +1,80 @@
+package server
+
+import (
+	"clipboard-manager/internal/config"
+	"clipboard-manager/internal/plugin"
+	"net/http"
+	"path/filepath"
+	"testing"
+)
+
+func TestServer_Plugins(t *testing.T) {
+	ts := newTestServer(t)
+
+	var list []plugin.Info
+	status, body := ts.do(t, http.MethodGet, "/api/plugins", "", "")
+	decode(t, body, &list)
+	if status != http.StatusOK || len(list) != 0 {
+		t.Fatalf("GET plugins = %d: %s, want none", status, body)
+	}
+
+	settings := config.FromEnv()
+	settings.Plugins = []plugin.Config{{Name: "ocr", Command: "/usr/local/bin/ocr", Disabled: true}}
+	ts.svc.ApplyConfig(settings)
+	_, body = ts.do(t, http.MethodGet, "/api/plugins", "", "")
+	decode(t, body, &list)
+	if len(list) != 1 || list[0].Name != "ocr" || list[0].State != plugin.StateDisabled {
+		t.Fatalf("GET plugins = %s, want the disabled ocr plugin", body)
+	}
+
+	clip := ts.addClip(t, "scan me")
+	if status, body := ts.do(t, http.MethodPost, "/api/clips/"+clip.ID+"/plugins/ocr/scan", "", ""); status != http.StatusServiceUnavailable {
+		t.Errorf("POST action of a disabled plugin = %d: %s, want 503", status, body)
+	}
+	if status, _ := ts.do(t, http.MethodPost, "/api/clips/"+clip.ID+"/plugins/missing/scan", "", ""); status != http.StatusNotFound {
+		t.Errorf("POST action of a missing plugin = %d, want 404", status)
+	}
+	if status, _ := ts.do(t, http.MethodPost, "/api/clips/999/plugins/ocr/scan", "", ""); status != http.StatusNotFound {
+		t.Errorf("POST action on a missing clip = %d, want 404", status)
+	}
+	if status, _ := ts.do(t, http.MethodPost, "/api/plugins/ocr/restart", "", ""); status != http.StatusConflict {
+		t.Errorf("POST restart of a disabled plugin = %d, want 409", status)
+	}
+	if status, _ := ts.do(t, http.MethodPost, "/api/plugins/missing/restart", "", ""); status != http.StatusNotFound {
+		t.Errorf("POST restart of a missing plugin = %d, want 404", status)
+	}
+}
+
+func TestServer_PluginSettings(t *testing.T) {
+	ts := newTestServer(t)
+	settings := config.FromEnv()
+	settings.Plugins = []plugin.Config{{Name: "ocr", Command: "/usr/local/bin/ocr", Args: []string{"-fast"}}}
+	ts.config.Settings = config.NewBus(settings)
+	ts.config.SettingsPath = filepath.Join(t.TempDir(), config.FileName)
+
+	// Running a program of the caller's choosing is left to the settings file
+	for _, body := range []string{
+		`{"plugins": [{"name": "ocr", "command": "/bin/sh", "args": ["-c", "id"]}]}`,
+		`{"plugins": [{"name": "ocr", "command": "/usr/local/bin/ocr", "args": ["-fast"]}, {"name": "sh", "command": "/bin/sh"}]}`,
+		`{"plugins": []}`,
+	} {
+		if status, resp := ts.do(t, http.MethodPut, "/api/settings", "application/json", body); status != http.StatusForbidden {
+			t.Errorf("PUT %s = %d: %s, want 403", body, status, resp)
+		}
+	}
+	if got := ts.config.Settings.Current().Plugins; len(got) != 1 || got[0].Command != "/usr/local/bin/ocr" {
+		t.Errorf("plugins = %+v, want them unchanged", got)
+	}
+
+	// Settings read back, plugins and all, can still be saved
+	status, body := ts.do(t, http.MethodGet, "/api/settings", "", "")
+	if status != http.StatusOK {
+		t.Fatalf("GET settings = %d: %s", status, body)
+	}
+	if status, resp := ts.do(t, http.MethodPut, "/api/settings", "application/json", string(body)); status != http.StatusOK {
+		t.Errorf("PUT of the current settings = %d: %s", status, resp)
+	}
+	if status, resp := ts.do(t, http.MethodPut, "/api/settings", "application/json", `{"dedup_normalized": true}`); status != http.StatusOK {
+		t.Errorf("PUT of another setting = %d: %s", status, resp)
+	}
+}
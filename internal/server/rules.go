@@ -0,0 +1,203 @@
+package server
+
+import (
+	"clipboard-manager/internal/rules"
+	"clipboard-manager/pkg/types"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// ruleTest is the body of POST /api/rules/test: a clip, given by its
+// content or the ID of a stored one, and optionally the rules to try
+// instead of the saved ones
+type ruleTest struct {
+	Content  string       `json:"content"`
+	Type     string       `json:"type"` // Default text/plain
+	App      string       `json:"app"`
+	BundleID string       `json:"bundle_id"`
+	ClipID   string       `json:"clip_id"`
+	Rules    []rules.Rule `json:"rules"`
+}
+
+// currentRules returns the saved rules, reporting an error if settings
+// aren't available
+func (s *Server) currentRules(w http.ResponseWriter, r *http.Request) ([]rules.Rule, bool) {
+	if s.config.Settings == nil {
+		writeError(w, r, http.StatusNotFound, "settings are not available")
+		return nil, false
+	}
+	list := s.config.Settings.Current().Rules
+	if list == nil {
+		list = []rules.Rule{}
+	}
+	return list, true
+}
+
+// saveRules saves list as the rules, keeping the other settings
+func (s *Server) saveRules(w http.ResponseWriter, r *http.Request, list []rules.Rule) bool {
+	settings := s.config.Settings.Current()
+	settings.Rules = list
+	return s.saveSettings(w, r, settings)
+}
+
+// decodeRule reads a rule from the request body
+func decodeRule(w http.ResponseWriter, r *http.Request) (rules.Rule, bool) {
+	var rule rules.Rule
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&rule); err != nil {
+		writeError(w, r, http.StatusBadRequest, fmt.Sprintf("invalid rule: %v", err))
+		return rule, false
+	}
+	return rule, true
+}
+
+func (s *Server) handleGetRules(w http.ResponseWriter, r *http.Request) {
+	list, ok := s.currentRules(w, r)
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+func (s *Server) handleGetRule(w http.ResponseWriter, r *http.Request) {
+	list, ok := s.currentRules(w, r)
+	if !ok {
+		return
+	}
+	i := rules.Find(list, chi.URLParam(r, "name"))
+	if i < 0 {
+		writeError(w, r, http.StatusNotFound, "rule not found")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list[i])
+}
+
+// handleAddRule adds a rule after the existing ones
+func (s *Server) handleAddRule(w http.ResponseWriter, r *http.Request) {
+	list, ok := s.currentRules(w, r)
+	if !ok {
+		return
+	}
+	rule, ok := decodeRule(w, r)
+	if !ok {
+		return
+	}
+	if rules.Find(list, rule.Name) >= 0 {
+		writeError(w, r, http.StatusConflict, fmt.Sprintf("a rule named %q already exists", rule.Name))
+		return
+	}
+	if !s.saveRules(w, r, append(list, rule)) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(rule)
+}
+
+// handlePutRule replaces a rule in place. A body without a name keeps the
+// rule's name; one with another name renames it.
+func (s *Server) handlePutRule(w http.ResponseWriter, r *http.Request) {
+	list, ok := s.currentRules(w, r)
+	if !ok {
+		return
+	}
+	name := chi.URLParam(r, "name")
+	i := rules.Find(list, name)
+	if i < 0 {
+		writeError(w, r, http.StatusNotFound, "rule not found")
+		return
+	}
+	rule, ok := decodeRule(w, r)
+	if !ok {
+		return
+	}
+	if rule.Name == "" {
+		rule.Name = name
+	}
+	if rule.Name != name && rules.Find(list, rule.Name) >= 0 {
+		writeError(w, r, http.StatusConflict, fmt.Sprintf("a rule named %q already exists", rule.Name))
+		return
+	}
+	list[i] = rule
+	if !s.saveRules(w, r, list) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rule)
+}
+
+func (s *Server) handleDeleteRule(w http.ResponseWriter, r *http.Request) {
+	list, ok := s.currentRules(w, r)
+	if !ok {
+		return
+	}
+	i := rules.Find(list, chi.URLParam(r, "name"))
+	if i < 0 {
+		writeError(w, r, http.StatusNotFound, "rule not found")
+		return
+	}
+	if !s.saveRules(w, r, append(list[:i], list[i+1:]...)) {
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleTestRules runs rules against a clip without recording or changing
+// anything, to try rules out before saving them
+func (s *Server) handleTestRules(w http.ResponseWriter, r *http.Request) {
+	var test ruleTest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&test); err != nil {
+		writeError(w, r, http.StatusBadRequest, fmt.Sprintf("invalid rule test: %v", err))
+		return
+	}
+
+	list := test.Rules
+	if list == nil {
+		var ok bool
+		if list, ok = s.currentRules(w, r); !ok {
+			return
+		}
+	}
+	set, err := rules.Compile(list)
+	if err != nil {
+		writeError(w, r, http.StatusUnprocessableEntity, fmt.Sprintf("invalid rules: %v", err))
+		return
+	}
+
+	var clip *types.Clip
+	switch {
+	case test.ClipID != "":
+		if clip, err = s.service(r).GetClipByID(r.Context(), test.ClipID); err != nil {
+			writeServiceError(w, r, err, http.StatusNotFound)
+			return
+		}
+	case test.Content != "":
+		clip = &types.Clip{
+			Content: []byte(test.Content),
+			Type:    test.Type,
+			Metadata: types.Metadata{
+				SourceApp:      test.App,
+				SourceBundleID: test.BundleID,
+			},
+		}
+		if clip.Type == "" {
+			clip.Type = types.TypeText
+		}
+	default:
+		writeError(w, r, http.StatusBadRequest, "content or clip_id is required")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(set.Evaluate(clip))
+}
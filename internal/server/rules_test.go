@@ -0,0 +1,66 @@
+package server
+
+import (
+	"clipboard-manager/internal/config"
+	"clipboard-manager/internal/rules"
+	"net/http"
+	"path/filepath"
+	"testing"
+)
+
+func TestServer_Rules(t *testing.T) {
+	ts := newTestServer(t)
+	ts.config.Settings = config.NewBus(config.FromEnv())
+	ts.config.SettingsPath = filepath.Join(t.TempDir(), config.FileName)
+
+	jira := `{"name":"jira","if":{"pattern":"JIRA-\\d+"},"then":{"tags":["jira"],"category":"work"}}`
+	if status, body := ts.do(t, http.MethodPost, "/api/rules", "application/json", jira); status != http.StatusCreated {
+		t.Fatalf("POST rule = %d: %s", status, body)
+	}
+	if status, _ := ts.do(t, http.MethodPost, "/api/rules", "application/json", jira); status != http.StatusConflict {
+		t.Errorf("POST the same rule again = %d, want 409", status)
+	}
+	if status, _ := ts.do(t, http.MethodPost, "/api/rules", "application/json", `{"name":"bad","if":{"pattern":"("},"then":{"skip":true}}`); status != http.StatusUnprocessableEntity {
+		t.Errorf("POST a rule with an invalid pattern = %d, want 422", status)
+	}
+	terminal := `{"if":{"app":"Terminal"},"then":{"category":"code"}}`
+	if status, body := ts.do(t, http.MethodPut, "/api/rules/terminal", "application/json", terminal); status != http.StatusNotFound {
+		t.Errorf("PUT a missing rule = %d: %s, want 404", status, body)
+	}
+	ts.do(t, http.MethodPost, "/api/rules", "application/json", `{"name":"terminal","if":{"app":"iTerm2"},"then":{"category":"code"}}`)
+	if status, body := ts.do(t, http.MethodPut, "/api/rules/terminal", "application/json", terminal); status != http.StatusOK {
+		t.Errorf("PUT rule = %d: %s", status, body)
+	}
+
+	saved, err := config.Load(ts.config.SettingsPath, config.FromEnv())
+	if err != nil || len(saved.Rules) != 2 || saved.Rules[1].If.App != "Terminal" {
+		t.Fatalf("saved rules = %+v (%v), want jira and terminal", saved.Rules, err)
+	}
+
+	// Dry runs, with the saved rules and with rules being tried out
+	var result rules.Result
+	status, body := ts.do(t, http.MethodPost, "/api/rules/test", "application/json", `{"content":"fix JIRA-123","app":"Terminal"}`)
+	decode(t, body, &result)
+	if status != http.StatusOK || len(result.Matched) != 2 || result.Category != "work" || len(result.Tags) != 1 {
+		t.Errorf("POST test = %d: %s, want both rules with the work category", status, body)
+	}
+	status, body = ts.do(t, http.MethodPost, "/api/rules/test", "application/json",
+		`{"content":"token = hunter2hunter2","rules":[{"name":"secrets","if":{"secret":true},"then":{"skip":true}}]}`)
+	decode(t, body, &result)
+	if status != http.StatusOK || !result.Skip {
+		t.Errorf("POST test of a secret rule = %d: %s, want the clip skipped", status, body)
+	}
+	if status, _ := ts.do(t, http.MethodPost, "/api/rules/test", "application/json", `{}`); status != http.StatusBadRequest {
+		t.Errorf("POST test without a clip = %d, want 400", status)
+	}
+
+	if status, _ := ts.do(t, http.MethodDelete, "/api/rules/jira", "", ""); status != http.StatusNoContent {
+		t.Errorf("DELETE rule = %d, want 204", status)
+	}
+	var list []rules.Rule
+	_, body = ts.do(t, http.MethodGet, "/api/rules", "", "")
+	decode(t, body, &list)
+	if len(list) != 1 || list[0].Name != "terminal" {
+		t.Errorf("GET rules = %s, want only terminal", body)
+	}
+}
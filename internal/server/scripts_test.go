@@ -0,0 +1,52 @@
+package server
+
+import (
+	"clipboard-manager/internal/config"
+	"clipboard-manager/internal/script"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestServer_Scripts(t *testing.T) {
+	ts := newTestServer(t)
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "tag.lua"), []byte(`function process(clip) table.insert(clip.tags, "scripted") end`), 0644)
+	os.WriteFile(filepath.Join(dir, "broken.lua"), []byte(`function process(clip`), 0644)
+	settings := config.FromEnv()
+	settings.Scripts = script.Config{Dir: dir}
+	ts.svc.ApplyConfig(settings)
+	defer ts.svc.ApplyConfig(config.FromEnv())
+
+	var list []script.Info
+	status, body := ts.do(t, http.MethodGet, "/api/scripts", "", "")
+	decode(t, body, &list)
+	if status != http.StatusOK || len(list) != 2 || list[0].Name != "broken" || list[0].Loaded || list[0].Error == "" || !list[1].Loaded {
+		t.Errorf("GET scripts = %d: %s, want broken failing to load and tag loaded", status, body)
+	}
+}
+
+func TestServer_ScriptSettings(t *testing.T) {
+	ts := newTestServer(t)
+	ts.config.Settings = config.NewBus(config.FromEnv())
+	ts.config.SettingsPath = filepath.Join(t.TempDir(), config.FileName)
+
+	// Where scripts come from and what they may touch is left to the
+	// settings file
+	for _, body := range []string{
+		`{"scripts": {"dir": "/tmp/uploaded"}}`,
+		`{"scripts": {"allow_files": true}}`,
+	} {
+		if status, resp := ts.do(t, http.MethodPut, "/api/settings", "application/json", body); status != http.StatusForbidden {
+			t.Errorf("PUT %s = %d: %s, want 403", body, status, resp)
+		}
+	}
+	if got := ts.config.Settings.Current().Scripts; got != (script.Config{}) {
+		t.Errorf("scripts = %+v, want them unchanged", got)
+	}
+	if status, resp := ts.do(t, http.MethodPut, "/api/settings", "application/json", `{"scripts": {}}`); status != http.StatusOK {
+		t.Errorf("PUT of unchanged scripts = %d: %s", status, resp)
+	}
+}
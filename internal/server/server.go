@@ -1,14 +1,38 @@
 package server
 
 import (
+	"clipboard-manager/internal/analytics"
+	"clipboard-manager/internal/audit"
+	"clipboard-manager/internal/auth"
+	"clipboard-manager/internal/chat"
+	"clipboard-manager/internal/clipboard"
+	"clipboard-manager/internal/config"
+	"clipboard-manager/internal/digest"
+	"clipboard-manager/internal/media"
+	"clipboard-manager/internal/metrics"
+	"clipboard-manager/internal/obsidian"
+	"clipboard-manager/internal/plugin"
+	"clipboard-manager/internal/publish"
+	"clipboard-manager/internal/qr"
 	"clipboard-manager/internal/service"
+	"clipboard-manager/internal/share"
 	"clipboard-manager/internal/storage"
+	"clipboard-manager/internal/trace"
+	"clipboard-manager/internal/transform"
+	"clipboard-manager/internal/translate"
+	"clipboard-manager/pkg/types"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"os"
+	"slices"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -21,10 +45,31 @@ type Server struct {
 	config      Config
 	pidFile     *pidFile
 	hub         *Hub
+
+	// Services of non-admin users' histories, opened on first use
+	usersMu      sync.Mutex
+	userServices map[string]*service.ClipboardService
 }
 
 type Config struct {
-	Port int
+	Port    int
+	Replace bool // Terminate a daemon that is already running
+
+	// Settings and SettingsPath serve /api/settings. Changes are saved to the
+	// file and published on the bus.
+	Settings     *config.Bus
+	SettingsPath string
+
+	// Shares serves share links. Nil turns sharing off.
+	Shares *share.Manager
+
+	// Users requires API tokens once it has users. Admins use the daemon's
+	// history; OpenUserStore opens the history of everyone else.
+	Users         *auth.Users
+	OpenUserStore func(name string) (storage.Storage, error)
+
+	// Audit records who read, changed or exported clips. Nil turns it off.
+	Audit *audit.Log
 }
 
 func New(clipService *service.ClipboardService, config Config) (*Server, error) {
@@ -47,52 +92,156 @@ func New(clipService *service.ClipboardService, config Config) (*Server, error)
 	// Register the hub as a clipboard change handler
 	clipService.RegisterHandler(hub)
 
+	metrics.RegisterGaugeFunc("websocket_clients", "Connected WebSocket clients.", func() float64 {
+		return float64(hub.ClientCount())
+	})
+
 	return server, nil
 }
 
-func (s *Server) Start() error {
-	// Check for existing process
-	if existingPID, err := s.pidFile.read(); err != nil {
-		return fmt.Errorf("failed to read PID file: %w", err)
-	} else if existingPID != 0 {
-		if isRunning(existingPID) {
-			log.Printf("Found existing clipboard manager process (PID: %d), attempting to terminate", existingPID)
-			if err := killProcess(existingPID); err != nil {
-				return fmt.Errorf("failed to terminate existing process: %w", err)
-			}
-			// Give the process time to cleanup
-			time.Sleep(500 * time.Millisecond)
-		}
-		// Clean up stale PID file
-		if err := s.pidFile.remove(); err != nil {
-			return fmt.Errorf("failed to remove stale PID file: %w", err)
-		}
-	}
-
-	// Write current PID
-	if err := s.pidFile.write(); err != nil {
-		return fmt.Errorf("failed to write PID file: %w", err)
-	}
-
+// routes builds the router serving the API, the WebSocket and the dashboard.
+// Keep openapi.json in sync with the routes.
+func (s *Server) routes() chi.Router {
 	r := chi.NewRouter()
 
 	// Middleware
+	r.Use(middleware.RequestID)
+	r.Use(traceRequests)
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 	r.Use(middleware.Timeout(10 * time.Second))
+	r.Use(s.cors)
 
 	// Routes
+	r.Get("/", s.handleDashboard)
 	r.Get("/status", s.handleStatus)
-	r.Get("/ws", s.serveWs) // WebSocket endpoint
-	r.Route("/api", func(r chi.Router) {
-		r.Get("/clips", s.handleGetClips)
-		r.Get("/clips/{index}", s.handleGetClip)
-		r.Post("/clips/{index}/paste", s.handlePasteClip)
-		r.Delete("/clips/id/{id}", s.handleDeleteClip)
-		r.Delete("/clips", s.handleClearClips)
-		r.Get("/search", s.handleSearch)
+	r.Handle("/metrics", metrics.Handler())
+	r.Get("/share/{token}", s.handleShare)
+	r.Post("/share/{token}", s.handleShare)
+	r.Get("/api/openapi.json", s.handleOpenAPI)
+	r.Get("/api/docs", s.handleDocs)
+	r.Group(func(r chi.Router) {
+		r.Use(s.authenticate)
+		r.With(requireAdmin, s.requireScope(auth.ScopeRead), s.audited(audit.ActionRead)).Get("/ws", s.serveWs) // WebSocket endpoint
+		r.Route("/api", func(r chi.Router) {
+			r.NotFound(handleNotFound)
+			r.MethodNotAllowed(handleMethodNotAllowed)
+
+			// Each user's own history
+			r.Group(func(r chi.Router) {
+				r.Use(s.withUserService)
+
+				// Reading history
+				r.Group(func(r chi.Router) {
+					r.Use(s.requireScope(auth.ScopeRead))
+					r.With(s.audited(audit.ActionRead)).Get("/clips", s.handleGetClips)
+					r.With(s.audited(audit.ActionRead)).Get("/clips/latest.txt", s.handleGetClipText)
+					r.With(s.audited(audit.ActionRead)).Get("/clips/{id}.txt", s.handleGetClipText)
+					r.With(s.audited(audit.ActionRead)).Get("/clips/{index}", s.handleGetClip)
+					r.With(s.audited(audit.ActionRead)).Get("/clips/{id}/preview.html", s.handleGetPreviewHTML)
+					r.With(s.audited(audit.ActionRead)).Get("/clips/id/{id}", s.handleGetClipByID)
+					r.With(s.audited(audit.ActionRead)).Get("/clips/id/{id}/content", s.handleGetClipContent)
+					r.With(s.audited(audit.ActionRead)).Get("/clips/id/{id}/thumbnail", s.handleGetThumbnail)
+					r.With(s.audited(audit.ActionExport)).Get("/clips/{id}/qr.png", s.handleGetQRCode)
+					r.With(s.audited(audit.ActionRead)).Get("/clips/id/{id}/versions", s.handleGetClipVersions)
+					r.With(s.audited(audit.ActionRead)).Get("/clips/{id}/related", s.handleGetRelatedClips)
+					r.With(s.audited(audit.ActionRead)).Get("/trash", s.handleGetTrash)
+					r.With(s.audited(audit.ActionRead)).Get("/changes", s.handleGetChanges)
+					r.With(s.audited(audit.ActionRead)).Get("/search", s.handleSearch)
+					r.With(s.audited(audit.ActionRead)).Get("/screenshots", s.handleGetScreenshots)
+					r.With(s.audited(audit.ActionRead)).Get("/media", s.handleGetMedia)
+					r.Get("/apps", s.handleGetApps)
+					r.Get("/stats", s.handleGetStats)
+					r.Get("/timeline", s.handleGetTimeline)
+					r.Get("/analytics", s.handleGetAnalytics)
+					r.Get("/analytics/heatmap.svg", s.handleGetHeatmap)
+					r.With(s.audited(audit.ActionRead)).Get("/digest", s.handleGetDigest)
+					r.Get("/apps/{bundleID}/icon", s.handleGetAppIcon)
+				})
+
+				// Adding clips and pasting, which don't reveal history
+				r.Group(func(r chi.Router) {
+					r.Use(s.requireScope(auth.ScopePaste))
+					r.With(s.audited(audit.ActionAdd)).Post("/clips", s.handleAddClip)
+					r.With(s.audited(audit.ActionPaste)).Post("/clips/{index}/paste", s.handlePasteClip)
+					r.With(s.audited(audit.ActionPaste)).Post("/clips/id/{id}/paste", s.handlePasteClipByID)
+				})
+
+				// Changing history and sending clips elsewhere
+				r.Group(func(r chi.Router) {
+					r.Use(s.requireScope(auth.ScopeFull))
+					r.With(s.audited(audit.ActionPaste)).Post("/clips/{id}/transform", s.handleTransformClip)
+					r.With(s.audited(audit.ActionExport)).Post("/clips/{id}/publish", s.handlePublishClip)
+					r.With(s.audited(audit.ActionExport)).Post("/clips/{id}/send", s.handleSendClip)
+					r.With(s.audited(audit.ActionExport)).Post("/clips/{id}/translate", s.handleTranslateClip)
+					r.With(s.audited(audit.ActionRead)).Post("/clips/{id}/speak", s.handleSpeakClip)
+					r.Delete("/speak", s.handleStopSpeaking)
+					r.With(s.audited(audit.ActionExport)).Post("/clips/{id}/plugins/{name}/{action}", s.handleRunPluginAction)
+					r.With(s.audited(audit.ActionModify)).Patch("/clips/id/{id}", s.handleUpdateClip)
+					r.With(s.audited(audit.ActionModify)).Put("/clips/id/{id}", s.handleEditClip)
+					r.With(s.audited(audit.ActionModify)).Post("/clips/id/{id}/versions/{version}/revert", s.handleRevertClip)
+					r.With(s.audited(audit.ActionDelete)).Delete("/clips/id/{id}", s.handleDeleteClip)
+					r.With(s.audited(audit.ActionDelete)).Delete("/clips", s.handleClearClips)
+					r.With(s.audited(audit.ActionModify)).Post("/clips/merge", s.handleMergeClips)
+					r.With(s.audited(audit.ActionModify)).Post("/clips/bulk", s.handleBulkClips)
+					r.With(s.audited(audit.ActionModify)).Post("/trash/{id}/restore", s.handleRestoreClip)
+					r.With(s.audited(audit.ActionDelete)).Delete("/trash", s.handleEmptyTrash)
+				})
+			})
+
+			// The system clipboard, settings, share links, Apple Notes and users
+			r.Group(func(r chi.Router) {
+				r.Use(requireAdmin, s.requireScope(auth.ScopeFull))
+				r.With(s.audited(audit.ActionExport)).Post("/clips/{id}/share", s.handleCreateShare)
+				r.With(s.audited(audit.ActionExport)).Post("/clips/{id}/apple-notes", s.handleExportToAppleNotes)
+				r.Get("/shares", s.handleGetShares)
+				r.With(s.audited(audit.ActionModify)).Delete("/shares/{shareID}", s.handleRevokeShare)
+				r.Get("/settings", s.handleGetSettings)
+				r.With(s.audited(audit.ActionModify)).Put("/settings", s.handlePutSettings)
+				r.Get("/rules", s.handleGetRules)
+				r.With(s.audited(audit.ActionModify)).Post("/rules", s.handleAddRule)
+				r.With(s.audited(audit.ActionRead)).Post("/rules/test", s.handleTestRules)
+				r.Get("/rules/{name}", s.handleGetRule)
+				r.With(s.audited(audit.ActionModify)).Put("/rules/{name}", s.handlePutRule)
+				r.With(s.audited(audit.ActionModify)).Delete("/rules/{name}", s.handleDeleteRule)
+				r.Get("/plugins", s.handleGetPlugins)
+				r.With(s.audited(audit.ActionModify)).Post("/plugins/{name}/restart", s.handleRestartPlugin)
+				r.Get("/scripts", s.handleGetScripts)
+				r.Get("/stack", s.handleGetStack)
+				r.Post("/stack", s.handleSetStack)
+				r.Post("/stack/next", s.handleStackNext)
+				r.Delete("/stack", s.handleClearStack)
+				r.Get("/sync", s.handleGetSync)
+				r.With(s.audited(audit.ActionModify)).Delete("/sync/failures", s.handleRetrySync)
+				r.Get("/profile", s.handleGetProfile)
+				r.With(s.audited(audit.ActionModify)).Put("/profile", s.handleUseProfile)
+				r.Get("/append", s.handleGetAppend)
+				r.Post("/append", s.handleStartAppend)
+				r.Delete("/append", s.handleStopAppend)
+				r.With(s.audited(audit.ActionModify)).Post("/pause", s.handlePause)
+				r.With(s.audited(audit.ActionModify)).Post("/resume", s.handleResume)
+				r.Get("/users", s.handleGetUsers)
+				r.Get("/audit", s.handleGetAudit)
+			})
+		})
 	})
 
+	return r
+}
+
+func (s *Server) Start() error {
+	// Check for existing process
+	if err := CheckExisting(s.config.Port, s.config.Replace); err != nil {
+		return err
+	}
+
+	// Write current PID
+	if err := s.pidFile.write(); err != nil {
+		return fmt.Errorf("failed to write PID file: %w", err)
+	}
+
+	r := s.routes()
+
 	// Try different addresses if one fails
 	addresses := []string{
 		fmt.Sprintf("localhost:%d", s.config.Port),
@@ -159,15 +308,173 @@ func (s *Server) Stop() error {
 }
 
 func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
-	log.Printf("Status check from %s", r.RemoteAddr)
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{
+	trace.Logf(r.Context(), "Status check from %s", r.RemoteAddr)
+	status := map[string]interface{}{
 		"status": "ok",
 		"time":   time.Now().Format(time.RFC3339),
 		"addr":   s.srv.Addr,
+		"pid":    os.Getpid(),
+	}
+	for key, value := range s.pauseStatus() {
+		status[key] = value
+	}
+	status["stack"] = s.clipService.PasteStack()
+	status["append"] = s.clipService.AppendMode()
+	if profile := s.clipService.Profile(); profile != "" {
+		status["profile"] = profile
+	}
+	status["permissions"] = s.clipService.Permissions()
+	status["capture"] = s.clipService.CaptureStatus()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// pauseStatus describes whether history recording is paused
+func (s *Server) pauseStatus() map[string]interface{} {
+	paused, until := s.clipService.IsPaused()
+	status := map[string]interface{}{"paused": paused}
+	if paused && !until.IsZero() {
+		status["paused_until"] = until.Format(time.RFC3339)
+	}
+	return status
+}
+
+// stackRequest is the body of POST /api/stack
+type stackRequest struct {
+	IDs []string `json:"ids"` // Clips in the order they are pasted
+}
+
+func (s *Server) handleGetStack(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.service(r).PasteStack())
+}
+
+// handleSetStack starts a paste stack and copies its first clip
+func (s *Server) handleSetStack(w http.ResponseWriter, r *http.Request) {
+	var req stackRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	status, err := s.service(r).SetPasteStack(r.Context(), req.IDs)
+	if err != nil {
+		code := http.StatusNotFound
+		if errors.Is(err, service.ErrStackEmpty) {
+			code = http.StatusBadRequest
+		}
+		writeServiceError(w, r, err, code)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// handleStackNext copies the next clip on the paste stack
+func (s *Server) handleStackNext(w http.ResponseWriter, r *http.Request) {
+	id, err := s.service(r).PasteNext(r.Context())
+	if err != nil {
+		code := http.StatusNotFound
+		if errors.Is(err, service.ErrStackEmpty) {
+			code = http.StatusConflict
+		}
+		writeServiceError(w, r, err, code)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":    id,
+		"stack": s.service(r).PasteStack(),
+	})
+}
+
+func (s *Server) handleClearStack(w http.ResponseWriter, r *http.Request) {
+	s.service(r).ClearPasteStack()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// profileStatus is the body of GET /api/profile
+type profileStatus struct {
+	Active   string   `json:"active"`
+	Profiles []string `json:"profiles"`
+}
+
+func (s *Server) handleGetProfile(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(profileStatus{
+		Active:   s.service(r).Profile(),
+		Profiles: s.service(r).Profiles(),
 	})
 }
 
+// handleUseProfile switches to the profile named in the body, such as
+// {"name": "work"}
+func (s *Server) handleUseProfile(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Name == "" {
+		writeError(w, r, http.StatusBadRequest, "a profile name is required")
+		return
+	}
+	if !config.ValidProfileName(req.Name) {
+		writeError(w, r, http.StatusUnprocessableEntity, "invalid profile name, use up to 32 lower case letters, digits, - and _")
+		return
+	}
+	if err := s.service(r).UseProfile(req.Name); err != nil {
+		writeServiceError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+	s.handleGetProfile(w, r)
+}
+
+func (s *Server) handleGetAppend(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.service(r).AppendMode())
+}
+
+// handleStartAppend turns on append mode. The separator query parameter
+// joins the copies, a newline by default.
+func (s *Server) handleStartAppend(w http.ResponseWriter, r *http.Request) {
+	separator := service.DefaultAppendSeparator
+	if r.URL.Query().Has("separator") {
+		separator = r.URL.Query().Get("separator")
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.service(r).StartAppend(separator))
+}
+
+// handleStopAppend turns off append mode, saving what it collected as one
+// clip
+func (s *Server) handleStopAppend(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.service(r).StopAppend())
+}
+
+func (s *Server) handlePause(w http.ResponseWriter, r *http.Request) {
+	var duration time.Duration
+	if d := r.URL.Query().Get("duration"); d != "" {
+		parsed, err := time.ParseDuration(d)
+		if err != nil || parsed < 0 {
+			writeError(w, r, http.StatusBadRequest, "invalid duration")
+			return
+		}
+		duration = parsed
+	}
+
+	s.service(r).Pause(duration)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.pauseStatus())
+}
+
+func (s *Server) handleResume(w http.ResponseWriter, r *http.Request) {
+	s.service(r).Resume()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.pauseStatus())
+}
+
 func (s *Server) handleGetClips(w http.ResponseWriter, r *http.Request) {
 	// Get limit and offset from query params
 	limit := 10 // default
@@ -183,101 +490,1312 @@ func (s *Server) handleGetClips(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	clips, err := s.clipService.GetClips(r.Context(), limit, offset)
+	// With ?cursor= (empty for the first page) the response is a page
+	// holding the cursor of the next one
+	if r.URL.Query().Has("cursor") {
+		cursor, ok := parseCursorParam(w, r)
+		if !ok {
+			return
+		}
+		clips, next, err := s.service(r).GetClipsAfter(r.Context(), cursor, limit)
+		if err != nil {
+			writeServiceError(w, r, err, http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(clipPage{Clips: clips, NextCursor: next})
+		return
+	}
+
+	clips, err := s.service(r).GetClips(r.Context(), limit, offset)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeServiceError(w, r, err, http.StatusInternalServerError)
 		return
 	}
 
 	json.NewEncoder(w).Encode(clips)
 }
 
-func (s *Server) handleGetClip(w http.ResponseWriter, r *http.Request) {
-	index, err := strconv.Atoi(chi.URLParam(r, "index"))
+// handleGetPreviewHTML serves a text clip as an HTML page, highlighting code
+func (s *Server) handleGetPreviewHTML(w http.ResponseWriter, r *http.Request) {
+	page, err := s.service(r).PreviewHTML(r.Context(), chi.URLParam(r, "id"))
 	if err != nil {
-		http.Error(w, "invalid index", http.StatusBadRequest)
+		status := http.StatusNotFound
+		if errors.Is(err, storage.ErrInvalidType) {
+			status = http.StatusUnsupportedMediaType
+		}
+		writeServiceError(w, r, err, status)
 		return
 	}
 
-	clip, err := s.clipService.GetClipByIndex(r.Context(), index)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	// The page is self-contained, nothing in it should load or run
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'")
+	io.WriteString(w, page)
+}
+
+// clipTransform is the body of a transform request. Op is one of
+// transform.Ops and Path is the path json-path extracts, e.g. .items[0].name.
+type clipTransform struct {
+	Op   string `json:"op"`
+	Path string `json:"path"`
+}
+
+// handleTransformClip reformats a text clip, such as pretty-printing JSON,
+// copies the result and answers with it
+func (s *Server) handleTransformClip(w http.ResponseWriter, r *http.Request) {
+	var req clipTransform
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.Op == "" {
+		writeError(w, r, http.StatusBadRequest, "op is required")
+		return
+	}
+
+	out, err := s.service(r).TransformClip(r.Context(), chi.URLParam(r, "id"), req.Op, req.Path)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+		var inputErr *transform.InputError
+		status := http.StatusNotFound
+		switch {
+		case errors.Is(err, transform.ErrUnknownOp), errors.Is(err, transform.ErrInvalidPath), errors.As(err, &inputErr):
+			status = http.StatusUnprocessableEntity
+		case errors.Is(err, storage.ErrInvalidType):
+			status = http.StatusUnsupportedMediaType
+		}
+		writeServiceError(w, r, err, status)
 		return
 	}
 
-	json.NewEncoder(w).Encode(clip)
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write(out)
 }
 
-func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
-	query := r.URL.Query().Get("q")
-	if query == "" {
-		http.Error(w, "search query is required", http.StatusBadRequest)
+// handlePublishClip uploads a text clip to the service named by ?to=, gist
+// or paste (the default), and returns the link
+func (s *Server) handlePublishClip(w http.ResponseWriter, r *http.Request) {
+	target := r.URL.Query().Get("to")
+	if target == "" {
+		target = publish.Paste
+	}
+	link, err := s.service(r).PublishClip(r.Context(), chi.URLParam(r, "id"), target)
+	if err != nil {
+		status := http.StatusNotFound
+		switch {
+		case errors.Is(err, publish.ErrUnknownTarget), errors.Is(err, publish.ErrNoToken):
+			status = http.StatusBadRequest
+		case errors.Is(err, publish.ErrSensitive):
+			status = http.StatusForbidden
+		case errors.Is(err, storage.ErrInvalidType):
+			status = http.StatusUnsupportedMediaType
+		case errors.Is(err, publish.ErrUpload):
+			status = http.StatusBadGateway
+		}
+		writeServiceError(w, r, err, status)
 		return
 	}
 
-	results, err := s.clipService.Search(r.Context(), storage.SearchOptions{
-		Query: query,
-		Limit: 50, // reasonable default
-	})
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"url": link})
+}
+
+// handleSendClip posts a clip to the chat target named by ?target=, slack or
+// discord
+func (s *Server) handleSendClip(w http.ResponseWriter, r *http.Request) {
+	target := r.URL.Query().Get("target")
+	if target == "" {
+		writeError(w, r, http.StatusBadRequest, "target is required")
+		return
+	}
+	if err := s.service(r).SendClip(r.Context(), chi.URLParam(r, "id"), target); err != nil {
+		status := http.StatusNotFound
+		switch {
+		case errors.Is(err, chat.ErrUnknownTarget), errors.Is(err, chat.ErrNotConfigured):
+			status = http.StatusBadRequest
+		case errors.Is(err, chat.ErrSensitive):
+			status = http.StatusForbidden
+		case errors.Is(err, storage.ErrInvalidType):
+			status = http.StatusUnsupportedMediaType
+		case errors.Is(err, chat.ErrSend):
+			status = http.StatusBadGateway
+		}
+		writeServiceError(w, r, err, status)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleTranslateClip translates a text clip into ?to=, or the configured
+// language, and answers with the translation. With ?store=true it is stored
+// as a related clip, otherwise it is copied.
+func (s *Server) handleTranslateClip(w http.ResponseWriter, r *http.Request) {
+	store, _ := strconv.ParseBool(r.URL.Query().Get("store"))
+	translation, err := s.service(r).TranslateClip(r.Context(), chi.URLParam(r, "id"), r.URL.Query().Get("to"), store)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		status := http.StatusNotFound
+		switch {
+		case errors.Is(err, translate.ErrInvalidLanguage), errors.Is(err, translate.ErrUnknownBackend), errors.Is(err, translate.ErrNotConfigured):
+			status = http.StatusBadRequest
+		case errors.Is(err, translate.ErrSensitive):
+			status = http.StatusForbidden
+		case errors.Is(err, translate.ErrTooLong):
+			status = http.StatusRequestEntityTooLarge
+		case errors.Is(err, storage.ErrInvalidType):
+			status = http.StatusUnsupportedMediaType
+		case errors.Is(err, translate.ErrTranslate):
+			status = http.StatusBadGateway
+		}
+		writeServiceError(w, r, err, status)
 		return
 	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(translation)
+}
 
-	json.NewEncoder(w).Encode(results)
+// handleSpeakClip starts reading a text clip aloud and answers without
+// waiting for it to finish
+func (s *Server) handleSpeakClip(w http.ResponseWriter, r *http.Request) {
+	if err := s.service(r).SpeakClip(r.Context(), chi.URLParam(r, "id")); err != nil {
+		status := http.StatusNotFound
+		switch {
+		case errors.Is(err, service.ErrSpeakSensitive):
+			status = http.StatusForbidden
+		case errors.Is(err, storage.ErrInvalidType):
+			status = http.StatusUnsupportedMediaType
+		}
+		writeServiceError(w, r, err, status)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
 }
 
-func (s *Server) handleDeleteClip(w http.ResponseWriter, r *http.Request) {
-	id := chi.URLParam(r, "id")
-	if id == "" {
-		http.Error(w, "clip ID is required", http.StatusBadRequest)
+// handleStopSpeaking stops reading a clip aloud
+func (s *Server) handleStopSpeaking(w http.ResponseWriter, r *http.Request) {
+	s.service(r).StopSpeaking()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleExportToAppleNotes adds a clip to Apple Notes
+func (s *Server) handleExportToAppleNotes(w http.ResponseWriter, r *http.Request) {
+	if _, err := s.service(r).ExportToAppleNotes(r.Context(), []string{chi.URLParam(r, "id")}); err != nil {
+		writeServiceError(w, r, err, appleNotesErrorStatus(err, http.StatusNotFound))
 		return
 	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// appleNotesErrorStatus maps an Apple Notes export error to a status, or
+// fallback
+func appleNotesErrorStatus(err error, fallback int) int {
+	switch {
+	case errors.Is(err, obsidian.ErrSensitive):
+		return http.StatusForbidden
+	case errors.Is(err, obsidian.ErrNotesUnavailable):
+		return http.StatusNotImplemented
+	case errors.Is(err, obsidian.ErrNotes):
+		return http.StatusBadGateway
+	}
+	return fallback
+}
+
+// screenshotSummary lists a screenshot without its image, which is fetched
+// from ContentURL or ThumbnailURL
+type screenshotSummary struct {
+	ID           string            `json:"id"`
+	CreatedAt    time.Time         `json:"created_at"`
+	WindowTitle  string            `json:"window_title"`
+	Screenshot   *types.Screenshot `json:"screenshot,omitempty"`
+	ContentURL   string            `json:"content_url"`
+	ThumbnailURL string            `json:"thumbnail_url"`
+}
+
+func (s *Server) handleGetScreenshots(w http.ResponseWriter, r *http.Request) {
+	limit := 50
+	offset := 0
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 {
+			limit = parsed
+		}
+	}
+	if o := r.URL.Query().Get("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil && parsed >= 0 {
+			offset = parsed
+		}
+	}
 
-	if err := s.clipService.DeleteClip(r.Context(), id); err != nil {
-		log.Printf("Error deleting clip %s: %v", id, err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	clips, err := s.service(r).ListScreenshots(r.Context(), limit, offset)
+	if err != nil {
+		writeServiceError(w, r, err, http.StatusInternalServerError)
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
+	summaries := make([]screenshotSummary, 0, len(clips))
+	for _, clip := range clips {
+		summary := screenshotSummary{
+			ID:           clip.ID,
+			CreatedAt:    clip.CreatedAt,
+			Screenshot:   clip.Metadata.Screenshot,
+			ContentURL:   "/api/clips/id/" + clip.ID + "/content",
+			ThumbnailURL: "/api/clips/id/" + clip.ID + "/thumbnail",
+		}
+		// Screenshots captured before their details were kept have the
+		// window title as their source app
+		if summary.Screenshot != nil {
+			summary.WindowTitle = summary.Screenshot.WindowTitle
+		} else {
+			summary.WindowTitle = clip.Metadata.SourceApp
+		}
+		summaries = append(summaries, summary)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summaries)
 }
 
-func (s *Server) handleClearClips(w http.ResponseWriter, r *http.Request) {
-	if err := s.clipService.ClearClips(r.Context()); err != nil {
-		log.Printf("Error clearing clips: %v", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+// mediaSummary lists an audio or video clip without its poster, which is
+// fetched from ThumbnailURL
+type mediaSummary struct {
+	ID           string       `json:"id"`
+	CreatedAt    time.Time    `json:"created_at"`
+	Path         string       `json:"path,omitempty"` // Of copied files
+	Media        *types.Media `json:"media"`
+	ContentURL   string       `json:"content_url"`
+	ThumbnailURL string       `json:"thumbnail_url,omitempty"`
+}
+
+// handleGetMedia lists audio and video clips. ?kind=audio or ?kind=video
+// lists only one of them.
+func (s *Server) handleGetMedia(w http.ResponseWriter, r *http.Request) {
+	kind := r.URL.Query().Get("kind")
+	if kind != "" && kind != media.Audio && kind != media.Video {
+		writeError(w, r, http.StatusBadRequest, "kind must be audio or video")
 		return
 	}
+	limit := 50
+	offset := 0
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 {
+			limit = parsed
+		}
+	}
+	if o := r.URL.Query().Get("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil && parsed >= 0 {
+			offset = parsed
+		}
+	}
 
-	w.WriteHeader(http.StatusOK)
+	clips, err := s.service(r).ListMedia(r.Context(), kind, limit, offset)
+	if err != nil {
+		writeServiceError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	summaries := make([]mediaSummary, 0, len(clips))
+	for _, clip := range clips {
+		// Media added through the API is stored as it is, not as a file
+		info := types.Media{Kind: media.Kind(clip.Type), MIMEType: clip.Type}
+		if clip.Metadata.Media != nil {
+			info = *clip.Metadata.Media
+		}
+		summary := mediaSummary{
+			ID:         clip.ID,
+			CreatedAt:  clip.CreatedAt,
+			Media:      &info,
+			ContentURL: "/api/clips/id/" + clip.ID + "/content",
+		}
+		if clip.Type == storage.TypeFile {
+			summary.Path, _ = media.FilePath(string(clip.Content))
+		}
+		if len(info.Poster) > 0 {
+			summary.ThumbnailURL = "/api/clips/id/" + clip.ID + "/thumbnail"
+			info.Poster = nil
+		}
+		summaries = append(summaries, summary)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summaries)
 }
 
-func (s *Server) handlePasteClip(w http.ResponseWriter, r *http.Request) {
-	index, err := strconv.Atoi(chi.URLParam(r, "index"))
+// handleGetThumbnail serves a PNG thumbnail of an image clip or of a video's
+// poster frame. The size parameter sets its longest side in pixels.
+func (s *Server) handleGetThumbnail(w http.ResponseWriter, r *http.Request) {
+	size := clipboard.DefaultThumbnailSize
+	if v := r.URL.Query().Get("size"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed < 1 || parsed > 2048 {
+			writeError(w, r, http.StatusBadRequest, "size must be between 1 and 2048")
+			return
+		}
+		size = parsed
+	}
+
+	thumbnail, err := s.service(r).Thumbnail(r.Context(), chi.URLParam(r, "id"), size)
 	if err != nil {
-		log.Printf("Invalid index parameter: %v", err)
-		http.Error(w, "invalid index", http.StatusBadRequest)
+		status := http.StatusNotFound
+		if errors.Is(err, storage.ErrInvalidType) {
+			status = http.StatusUnsupportedMediaType
+		}
+		writeServiceError(w, r, err, status)
 		return
 	}
 
-	log.Printf("Handling paste request for index: %d", index)
-	
-	if err := s.clipService.PasteByIndex(r.Context(), index); err != nil {
-		log.Printf("Error pasting clip at index %d: %v", index, err)
-		
-		// Create a detailed error response
-		errorResponse := map[string]string{
-			"error": err.Error(),
-			"detail": fmt.Sprintf("Failed to paste clip at index %d", index),
+	w.Header().Set("Content-Type", "image/png")
+	w.Write(thumbnail)
+}
+
+func (s *Server) handleGetQRCode(w http.ResponseWriter, r *http.Request) {
+	size := qr.DefaultSize
+	if v := r.URL.Query().Get("size"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed < qr.MinSize || parsed > qr.MaxSize {
+			writeError(w, r, http.StatusBadRequest, fmt.Sprintf("size must be between %d and %d", qr.MinSize, qr.MaxSize))
+			return
 		}
-		
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(errorResponse)
+		size = parsed
+	}
+
+	code, err := s.service(r).QRCode(r.Context(), chi.URLParam(r, "id"), size)
+	if err != nil {
+		status := http.StatusNotFound
+		switch {
+		case errors.Is(err, storage.ErrInvalidType):
+			status = http.StatusUnsupportedMediaType
+		case errors.Is(err, qr.ErrTooLong):
+			status = http.StatusRequestEntityTooLarge
+		}
+		writeServiceError(w, r, err, status)
+		return
+	}
+
+	// The code holds the clip, which may be sensitive
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Type", "image/png")
+	w.Write(code)
+}
+
+// clipPage is a page of clips listed with a cursor. NextCursor is empty on
+// the last page.
+type clipPage struct {
+	Clips      []*types.Clip `json:"clips"`
+	NextCursor string        `json:"next_cursor"`
+}
+
+// searchPage is a page of search results requested with a cursor
+type searchPage struct {
+	Results    []storage.SearchResult `json:"results"`
+	NextCursor string                 `json:"next_cursor"`
+}
+
+// parseCursorParam validates the cursor query parameter, answering with an
+// error if it is malformed
+func parseCursorParam(w http.ResponseWriter, r *http.Request) (string, bool) {
+	cursor := r.URL.Query().Get("cursor")
+	if cursor == "" {
+		return "", true
+	}
+	if _, err := storage.ParseCursor(cursor); err != nil {
+		writeServiceError(w, r, err, http.StatusBadRequest)
+		return "", false
+	}
+	return cursor, true
+}
+
+func (s *Server) handleGetClip(w http.ResponseWriter, r *http.Request) {
+	index, err := strconv.Atoi(chi.URLParam(r, "index"))
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, "invalid index")
+		return
+	}
+
+	clip, err := s.service(r).GetClipByIndex(r.Context(), index)
+	if err != nil {
+		writeServiceError(w, r, err, http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(clip)
+}
+
+func (s *Server) handleGetClipByID(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		writeError(w, r, http.StatusBadRequest, "clip ID is required")
+		return
+	}
+
+	clip, err := s.service(r).GetClipByID(r.Context(), id)
+	if err != nil {
+		writeServiceError(w, r, err, http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(clip)
+}
+
+// handleGetClipText serves the plain text of a clip, or of the latest clip,
+// for tools like tmux and vim that don't want to parse JSON
+func (s *Server) handleGetClipText(w http.ResponseWriter, r *http.Request) {
+	var clip *types.Clip
+	var err error
+	if id := chi.URLParam(r, "id"); id != "" {
+		clip, err = s.service(r).GetClipByID(r.Context(), id)
+	} else {
+		clip, err = s.service(r).GetClipByIndex(r.Context(), 0)
+	}
+	if err != nil {
+		writeServiceError(w, r, err, http.StatusNotFound)
 		return
 	}
 
-	log.Printf("Successfully pasted clip at index %d", index)
+	text, ok := clipboard.PlainText(clip)
+	if !ok {
+		writeError(w, r, http.StatusUnsupportedMediaType, fmt.Sprintf("clip of type %s has no text", clip.Type))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	io.WriteString(w, text)
+}
+
+func (s *Server) handleGetClipContent(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	reader, clip, err := s.service(r).GetClipContent(r.Context(), id)
+	if err != nil {
+		writeServiceError(w, r, err, http.StatusNotFound)
+		return
+	}
+	defer reader.Close()
+
+	w.Header().Set("Content-Security-Policy", rawContentPolicy)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Type", contentTypeFor(clip.Type))
+	if file, ok := reader.(*os.File); ok {
+		if info, err := file.Stat(); err == nil {
+			w.Header().Set("Content-Length", strconv.FormatInt(info.Size(), 10))
+		}
+	}
+	if _, err := io.Copy(w, reader); err != nil {
+		trace.Logf(r.Context(), "Error streaming clip %s: %v", id, err)
+	}
+}
+
+func (s *Server) handleAddClip(w http.ResponseWriter, r *http.Request) {
+	clipType := r.URL.Query().Get("type")
+	if clipType == "" {
+		clipType = r.Header.Get("Content-Type")
+	}
+	if clipType == "" {
+		writeError(w, r, http.StatusBadRequest, "clip type is required")
+		return
+	}
+
+	clip, err := s.service(r).AddClip(r.Context(), r.Body, clipType, types.Metadata{
+		SourceApp: r.URL.Query().Get("source"),
+	})
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, storage.ErrFileTooLarge) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		writeServiceError(w, r, err, status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(clip)
+}
+
+// rawContentPolicy sandboxes clip content served as is, so HTML or SVG
+// copied from a page can't run scripts on the daemon's origin
+const rawContentPolicy = "sandbox; default-src 'none'; img-src data:; style-src 'unsafe-inline'"
+
+// contentTypeFor maps a clip type to the Content-Type of its raw content
+func contentTypeFor(clipType string) string {
+	switch clipType {
+	case types.TypeText:
+		return "text/plain; charset=utf-8"
+	case types.TypeHTML:
+		return "text/html; charset=utf-8"
+	case types.TypeScreenshot:
+		return "image/png"
+	case types.TypeFile:
+		return "text/uri-list"
+	case types.TypeFileList:
+		return "application/json"
+	}
+	if strings.Contains(clipType, "/") {
+		return clipType
+	}
+	return "application/octet-stream"
+}
+
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	opts := storage.SearchOptions{
+		Query:          params.Get("q"),
+		Type:           params.Get("type"),
+		Format:         params.Get("format"),
+		SourceBundleID: params.Get("app"),
+		SourceURL:      params.Get("url"),
+		Device:         params.Get("device"),
+		Limit:          50, // reasonable default
+		GroupSimilar:   params.Get("group") == "similar",
+	}
+	var ok bool
+	if opts.From, opts.To, ok = parseTimeRange(w, r); !ok {
+		return
+	}
+	if opts.Query == "" && opts.Type == "" && opts.Format == "" && opts.SourceBundleID == "" && opts.SourceURL == "" && opts.Device == "" &&
+		opts.From.IsZero() && opts.To.IsZero() {
+		writeError(w, r, http.StatusBadRequest, "search query or filter is required")
+		return
+	}
+	if _, err := storage.ParseQuery(opts.Query); err != nil {
+		writeServiceError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	if l := params.Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 {
+			opts.Limit = parsed
+		}
+	}
+
+	if params.Has("cursor") {
+		if opts.Cursor, ok = parseCursorParam(w, r); !ok {
+			return
+		}
+		results, next, err := s.service(r).SearchPage(r.Context(), opts)
+		if err != nil {
+			writeServiceError(w, r, err, http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(searchPage{Results: results, NextCursor: next})
+		return
+	}
+
+	results, err := s.service(r).Search(r.Context(), opts)
+	if err != nil {
+		writeServiceError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(results)
+}
+
+func (s *Server) handleGetApps(w http.ResponseWriter, r *http.Request) {
+	apps, err := s.service(r).ListApps(r.Context())
+	if err != nil {
+		writeServiceError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(apps)
+}
+
+func (s *Server) handleGetStats(w http.ResponseWriter, r *http.Request) {
+	stats, err := s.service(r).Stats(r.Context())
+	if err != nil {
+		writeServiceError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
+
+// handleGetTimeline counts clips by the day, or with ?granularity=hour the
+// hour, they were copied in, optionally between ?from= and ?to=
+func (s *Server) handleGetTimeline(w http.ResponseWriter, r *http.Request) {
+	opts := storage.TimelineOptions{Granularity: storage.GranularityDay}
+	if name := r.URL.Query().Get("granularity"); name != "" {
+		var err error
+		if opts.Granularity, err = storage.ParseGranularity(name); err != nil {
+			writeServiceError(w, r, err, http.StatusBadRequest)
+			return
+		}
+	}
+	var ok bool
+	if opts.From, opts.To, ok = parseTimeRange(w, r); !ok {
+		return
+	}
+
+	buckets, err := s.service(r).Timeline(r.Context(), opts)
+	if err != nil {
+		writeServiceError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(buckets)
+}
+
+// handleGetAnalytics reports clipboard activity between ?from= and ?to=,
+// the last 30 days by default, as JSON or with ?format=csv as CSV rows
+func (s *Server) handleGetAnalytics(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "csv" {
+		writeError(w, r, http.StatusBadRequest, "invalid format, expected json or csv")
+		return
+	}
+	report, ok := s.analyticsReport(w, r)
+	if !ok {
+		return
+	}
+
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="clipboard-analytics.csv"`)
+		report.WriteCSV(w)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// handleGetHeatmap draws clipboard activity between ?from= and ?to= by
+// weekday and hour of day as an SVG heatmap
+func (s *Server) handleGetHeatmap(w http.ResponseWriter, r *http.Request) {
+	report, ok := s.analyticsReport(w, r)
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "image/svg+xml")
+	report.WriteSVG(w)
+}
+
+// analyticsReport builds the report for the range of r, reporting errors
+func (s *Server) analyticsReport(w http.ResponseWriter, r *http.Request) (*analytics.Report, bool) {
+	from, to, ok := parseTimeRange(w, r)
+	if !ok {
+		return nil, false
+	}
+	report, err := s.service(r).Analytics(r.Context(), from, to)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, analytics.ErrInvalidRange) {
+			status = http.StatusBadRequest
+		}
+		writeServiceError(w, r, err, status)
+		return nil, false
+	}
+	return report, true
+}
+
+// parseTimeRange reads the ?from= and ?to= parameters, RFC 3339 times or
+// YYYY-MM-DD dates in local time. A date in to stands for the end of the
+// day, so from and to of the same date cover that day. An invalid
+// parameter is reported and ok is false.
+func parseTimeRange(w http.ResponseWriter, r *http.Request) (from, to time.Time, ok bool) {
+	parse := func(name string, endOfDay bool) (time.Time, bool) {
+		value := r.URL.Query().Get(name)
+		if value == "" {
+			return time.Time{}, true
+		}
+		if t, err := time.Parse(time.RFC3339, value); err == nil {
+			return t, true
+		}
+		day, err := time.ParseInLocation("2006-01-02", value, time.Local)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, "invalid "+name+", expected an RFC 3339 time or YYYY-MM-DD")
+			return time.Time{}, false
+		}
+		if endOfDay {
+			day = day.AddDate(0, 0, 1).Add(-time.Nanosecond)
+		}
+		return day, true
+	}
+
+	if from, ok = parse("from", false); !ok {
+		return
+	}
+	to, ok = parse("to", true)
+	return
+}
+
+// handleGetDigest renders the Markdown digest of a day or week, today's by
+// default. The period is chosen with ?period=day|week and ?date=YYYY-MM-DD.
+func (s *Server) handleGetDigest(w http.ResponseWriter, r *http.Request) {
+	period := digest.Daily
+	if name := r.URL.Query().Get("period"); name != "" {
+		var err error
+		if period, err = digest.ParsePeriod(name); err != nil {
+			writeServiceError(w, r, err, http.StatusBadRequest)
+			return
+		}
+	}
+	day := time.Now()
+	if date := r.URL.Query().Get("date"); date != "" {
+		var err error
+		if day, err = time.ParseInLocation("2006-01-02", date, time.Local); err != nil {
+			writeError(w, r, http.StatusBadRequest, "invalid date, expected YYYY-MM-DD")
+			return
+		}
+	}
+
+	content, err := s.service(r).Digest(r.Context(), period, day)
+	if err != nil {
+		writeServiceError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+	io.WriteString(w, content)
+}
+
+func (s *Server) handleGetSettings(w http.ResponseWriter, r *http.Request) {
+	if s.config.Settings == nil {
+		writeError(w, r, http.StatusNotFound, "settings are not available")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.config.Settings.Current())
+}
+
+// handlePutSettings changes settings. The body is read over the current
+// settings, so it only needs the keys being changed. Plugins and scripts are
+// left to the settings file, see fileOnlySetting.
+func (s *Server) handlePutSettings(w http.ResponseWriter, r *http.Request) {
+	if s.config.Settings == nil {
+		writeError(w, r, http.StatusNotFound, "settings are not available")
+		return
+	}
+
+	settings := s.config.Settings.Current()
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&settings); err != nil {
+		writeError(w, r, http.StatusBadRequest, fmt.Sprintf("invalid settings: %v", err))
+		return
+	}
+	if !s.saveSettings(w, r, settings) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(settings)
+}
+
+// saveSettings validates settings, saves them and publishes them to the
+// running daemon. It reports the error and returns false if any step fails.
+func (s *Server) saveSettings(w http.ResponseWriter, r *http.Request, settings config.Config) bool {
+	if name := fileOnlySetting(s.config.Settings.Current(), settings); name != "" {
+		writeError(w, r, http.StatusForbidden, fmt.Sprintf("%s can only be changed in the settings file", name))
+		return false
+	}
+	if err := settings.Validate(); err != nil {
+		writeError(w, r, http.StatusUnprocessableEntity, fmt.Sprintf("invalid settings: %v", err))
+		return false
+	}
+
+	if err := config.Save(s.config.SettingsPath, settings); err != nil {
+		trace.Logf(r.Context(), "Error saving settings: %v", err)
+		writeServiceError(w, r, err, http.StatusInternalServerError)
+		return false
+	}
+	s.config.Settings.Publish(settings)
+	return true
+}
+
+// fileOnlySetting returns the name of a setting that differs between current
+// and settings but can only be changed in the settings file, or "". Plugins
+// and scripts run code as whoever runs the daemon, which no API token may
+// choose.
+func fileOnlySetting(current, settings config.Config) string {
+	if !slices.EqualFunc(current.Plugins, settings.Plugins, func(a, b plugin.Config) bool {
+		return a.Name == b.Name && a.Command == b.Command && a.Disabled == b.Disabled &&
+			slices.Equal(a.Args, b.Args) && slices.Equal(a.Env, b.Env)
+	}) {
+		return "plugins"
+	}
+	if current.Scripts != settings.Scripts {
+		return "scripts"
+	}
+	return ""
+}
+
+func (s *Server) handleGetAppIcon(w http.ResponseWriter, r *http.Request) {
+	bundleID := chi.URLParam(r, "bundleID")
+	icon, err := s.service(r).GetAppIcon(r.Context(), bundleID)
+	if err != nil {
+		writeServiceError(w, r, err, http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "max-age=86400")
+	w.Write(icon)
+}
+
+// clipMerge is the body of a merge request. The clips in IDs are folded into
+// Keep, or into the first of them if Keep is empty. With Action "concat"
+// their text is instead joined by Separator (a newline if nil) into a new
+// clip, in the order given or, with Order "time", oldest first.
+type clipMerge struct {
+	Action    string   `json:"action"`
+	Keep      string   `json:"keep"`
+	IDs       []string `json:"ids"`
+	Separator *string  `json:"separator"`
+	Order     string   `json:"order"`
+}
+
+func (s *Server) handleMergeClips(w http.ResponseWriter, r *http.Request) {
+	var merge clipMerge
+	if err := json.NewDecoder(r.Body).Decode(&merge); err != nil {
+		writeError(w, r, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if merge.Action == "concat" {
+		s.concatClips(w, r, merge)
+		return
+	}
+	if merge.Action != "" {
+		writeError(w, r, http.StatusUnprocessableEntity, "action must be concat or empty")
+		return
+	}
+	if merge.Keep == "" && len(merge.IDs) > 0 {
+		merge.Keep = merge.IDs[0]
+	}
+	if merge.Keep == "" {
+		writeError(w, r, http.StatusBadRequest, "clip IDs are required")
+		return
+	}
+
+	clip, err := s.service(r).MergeClips(r.Context(), merge.Keep, merge.IDs)
+	if err != nil {
+		writeServiceError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(clip)
+}
+
+// concatClips joins the clips of a concat merge request into a new clip
+func (s *Server) concatClips(w http.ResponseWriter, r *http.Request, merge clipMerge) {
+	if merge.Order == "" {
+		merge.Order = service.OrderSelection
+	}
+	if merge.Order != service.OrderSelection && merge.Order != service.OrderTime {
+		writeError(w, r, http.StatusUnprocessableEntity, "order must be selection or time")
+		return
+	}
+	if len(merge.IDs) < 2 {
+		writeError(w, r, http.StatusBadRequest, "at least two clip IDs are required")
+		return
+	}
+	separator := "\n"
+	if merge.Separator != nil {
+		separator = *merge.Separator
+	}
+
+	clip, err := s.service(r).ConcatClips(r.Context(), merge.IDs, separator, merge.Order)
+	if err != nil {
+		status := http.StatusNotFound
+		if errors.Is(err, storage.ErrInvalidType) {
+			status = http.StatusUnsupportedMediaType
+		}
+		writeServiceError(w, r, err, status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(clip)
+}
+
+// clipBulk is the body of a bulk request. Action is "delete", "tag" or
+// "apple-notes". The clips changed are those in IDs or, for delete, those
+// matching the search query Filter. Tag replaces their tags with Tags, and
+// apple-notes exports them to Apple Notes.
+type clipBulk struct {
+	Action string   `json:"action"`
+	IDs    []string `json:"ids"`
+	Filter string   `json:"filter"`
+	Tags   []string `json:"tags"`
+}
+
+// bulkResult reports how many clips a bulk request changed
+type bulkResult struct {
+	Affected int64 `json:"affected"`
+}
+
+func (s *Server) handleBulkClips(w http.ResponseWriter, r *http.Request) {
+	var bulk clipBulk
+	if err := json.NewDecoder(r.Body).Decode(&bulk); err != nil {
+		writeError(w, r, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if len(bulk.IDs) > 0 && bulk.Filter != "" {
+		writeError(w, r, http.StatusBadRequest, "send either ids or filter, not both")
+		return
+	}
+
+	var affected int64
+	var err error
+	switch bulk.Action {
+	case "delete":
+		if bulk.Filter != "" {
+			if parsed, err := storage.ParseQuery(bulk.Filter); err != nil {
+				writeServiceError(w, r, err, http.StatusBadRequest)
+				return
+			} else if parsed.Empty() {
+				writeError(w, r, http.StatusBadRequest, "filter matches every clip, use DELETE /api/clips to clear history")
+				return
+			}
+			affected, err = s.service(r).DeleteClipsMatching(r.Context(), bulk.Filter)
+		} else if len(bulk.IDs) > 0 {
+			affected, err = s.service(r).DeleteClips(r.Context(), bulk.IDs)
+		} else {
+			writeError(w, r, http.StatusBadRequest, "ids or filter is required")
+			return
+		}
+	case "tag":
+		if len(bulk.IDs) == 0 {
+			writeError(w, r, http.StatusBadRequest, "ids are required")
+			return
+		}
+		affected, err = s.service(r).SetClipsTags(r.Context(), bulk.IDs, bulk.Tags)
+	case "apple-notes":
+		// Notes go to the account of whoever runs the daemon
+		if user, ok := requestUser(r); ok && !user.Admin {
+			writeError(w, r, http.StatusForbidden, "only admins can do this")
+			return
+		}
+		if len(bulk.IDs) == 0 {
+			writeError(w, r, http.StatusBadRequest, "ids are required")
+			return
+		}
+		affected, err = s.service(r).ExportToAppleNotes(r.Context(), bulk.IDs)
+	default:
+		writeError(w, r, http.StatusUnprocessableEntity, fmt.Sprintf("unknown action %q, expected delete, tag or apple-notes", bulk.Action))
+		return
+	}
+	if err != nil {
+		trace.Logf(r.Context(), "Error running bulk %s: %v", bulk.Action, err)
+		writeServiceError(w, r, err, appleNotesErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(bulkResult{Affected: affected})
+}
+
+// handleEditClip replaces the content of a text clip with the request body,
+// keeping the old content as a version
+func (s *Server) handleEditClip(w http.ResponseWriter, r *http.Request) {
+	content, err := io.ReadAll(http.MaxBytesReader(w, r.Body, storage.MaxInlineStorageSize))
+	if err != nil {
+		writeError(w, r, http.StatusRequestEntityTooLarge, "failed to read content")
+		return
+	}
+
+	clip, err := s.service(r).EditClip(r.Context(), chi.URLParam(r, "id"), content)
+	if err != nil {
+		writeServiceError(w, r, err, versionErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(clip)
+}
+
+func (s *Server) handleGetClipVersions(w http.ResponseWriter, r *http.Request) {
+	versions, err := s.service(r).ListClipVersions(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeServiceError(w, r, err, http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(versions)
+}
+
+// handleGetRelatedClips lists the clips copied in the same session as a
+// clip, such as the other cells copied from a spreadsheet
+func (s *Server) handleGetRelatedClips(w http.ResponseWriter, r *http.Request) {
+	clips, err := s.service(r).RelatedClips(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeServiceError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(clips)
+}
+
+func (s *Server) handleRevertClip(w http.ResponseWriter, r *http.Request) {
+	version, err := strconv.Atoi(chi.URLParam(r, "version"))
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, "invalid version")
+		return
+	}
+
+	clip, err := s.service(r).RevertClip(r.Context(), chi.URLParam(r, "id"), version)
+	if err != nil {
+		writeServiceError(w, r, err, versionErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(clip)
+}
+
+// versionErrorStatus maps an edit or revert error to a response status
+func versionErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, storage.ErrDuplicate):
+		return http.StatusConflict
+	case errors.Is(err, storage.ErrNotEditable):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// clipUpdate is the body of a PATCH request for a clip. Either field sets
+// when the clip expires; sending both as null clears the expiry.
+type clipUpdate struct {
+	ExpiresAt *time.Time `json:"expires_at"`
+	ExpiresIn string     `json:"expires_in"` // Duration from now, e.g. "90s"
+}
+
+func (s *Server) handleUpdateClip(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	var update clipUpdate
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		writeError(w, r, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	expiresAt := update.ExpiresAt
+	if update.ExpiresIn != "" {
+		duration, err := time.ParseDuration(update.ExpiresIn)
+		if err != nil || duration <= 0 {
+			writeError(w, r, http.StatusUnprocessableEntity, "invalid expires_in duration")
+			return
+		}
+		at := time.Now().Add(duration)
+		expiresAt = &at
+	}
+
+	clip, err := s.service(r).SetClipExpiry(r.Context(), id, expiresAt)
+	if err != nil {
+		trace.Logf(r.Context(), "Error updating clip %s: %v", id, err)
+		writeServiceError(w, r, err, http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(clip)
+}
+
+func (s *Server) handleDeleteClip(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		writeError(w, r, http.StatusBadRequest, "clip ID is required")
+		return
+	}
+
+	if err := s.service(r).DeleteClip(r.Context(), id); err != nil {
+		trace.Logf(r.Context(), "Error deleting clip %s: %v", id, err)
+		writeServiceError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) handleClearClips(w http.ResponseWriter, r *http.Request) {
+	if err := s.service(r).ClearClips(r.Context()); err != nil {
+		trace.Logf(r.Context(), "Error clearing clips: %v", err)
+		writeServiceError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) handleGetTrash(w http.ResponseWriter, r *http.Request) {
+	limit := 50 // default
+	offset := 0
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 {
+			limit = parsed
+		}
+	}
+	if o := r.URL.Query().Get("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil && parsed >= 0 {
+			offset = parsed
+		}
+	}
+
+	clips, err := s.service(r).ListTrash(r.Context(), limit, offset)
+	if err != nil {
+		writeServiceError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(clips)
+}
+
+// changePage is a page of the change feed. Next is the since of the next
+// page, and HasMore says whether it has changes yet.
+type changePage struct {
+	Changes []storage.Change `json:"changes"`
+	Next    int64            `json:"next"`
+	HasMore bool             `json:"has_more"`
+}
+
+func (s *Server) handleGetChanges(w http.ResponseWriter, r *http.Request) {
+	var since int64
+	if v := r.URL.Query().Get("since"); v != "" {
+		parsed, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || parsed < 0 {
+			writeError(w, r, http.StatusBadRequest, "since must be a change sequence number")
+			return
+		}
+		since = parsed
+	}
+	limit := storage.DefaultChangesLimit
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 {
+			limit = parsed
+		}
+	}
+
+	changes, latest, err := s.service(r).Changes(r.Context(), since, limit)
+	if err != nil {
+		writeServiceError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	if since > latest {
+		// The feed was started over, as with a new database
+		writeError(w, r, http.StatusConflict, "since is past the latest change, list the history again")
+		return
+	}
+
+	page := changePage{Changes: changes, Next: since}
+	if len(changes) > 0 {
+		page.Next = changes[len(changes)-1].Seq
+	}
+	page.HasMore = page.Next < latest
+	if page.Changes == nil {
+		page.Changes = []storage.Change{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
+func (s *Server) handleRestoreClip(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	clip, err := s.service(r).RestoreClip(r.Context(), id)
+	if err != nil {
+		trace.Logf(r.Context(), "Error restoring clip %s: %v", id, err)
+		writeServiceError(w, r, err, http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(clip)
+}
+
+func (s *Server) handleEmptyTrash(w http.ResponseWriter, r *http.Request) {
+	purged, err := s.service(r).EmptyTrash(r.Context())
+	if err != nil {
+		trace.Logf(r.Context(), "Error emptying trash: %v", err)
+		writeServiceError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int64{"purged": purged})
+}
+
+func (s *Server) handleGetSync(w http.ResponseWriter, r *http.Request) {
+	status, err := s.service(r).SyncStatus(r.Context())
+	if err != nil {
+		writeServiceError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+func (s *Server) handleRetrySync(w http.ResponseWriter, r *http.Request) {
+	reset, err := s.service(r).RetrySync(r.Context())
+	if err != nil {
+		writeServiceError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int64{"reset": reset})
+}
+
+// simulate reports whether a paste request asks for the paste shortcut to
+// be pressed after copying, with ?simulate=true
+func simulate(r *http.Request) bool {
+	on, _ := strconv.ParseBool(r.URL.Query().Get("simulate"))
+	return on
+}
+
+func (s *Server) handlePasteClip(w http.ResponseWriter, r *http.Request) {
+	index, err := strconv.Atoi(chi.URLParam(r, "index"))
+	if err != nil {
+		trace.Logf(r.Context(), "Invalid index parameter: %v", err)
+		writeError(w, r, http.StatusBadRequest, "invalid index")
+		return
+	}
+
+	trace.Logf(r.Context(), "Handling paste request for index: %d", index)
+	
+	if err := s.service(r).PasteByIndex(r.Context(), index); err != nil {
+		trace.Logf(r.Context(), "Error pasting clip at index %d: %v", index, err)
+		writeServiceError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+	if simulate(r) {
+		if err := s.service(r).SimulatePaste(r.Context()); err != nil {
+			writeServiceError(w, r, err, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	trace.Logf(r.Context(), "Successfully pasted clip at index %d", index)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) handlePasteClipByID(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		writeError(w, r, http.StatusBadRequest, "clip ID is required")
+		return
+	}
+
+	trace.Logf(r.Context(), "Handling paste request for clip ID: %s", id)
+
+	if err := s.service(r).PasteByID(r.Context(), id); err != nil {
+		trace.Logf(r.Context(), "Error pasting clip %s: %v", id, err)
+		writeServiceError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+	if simulate(r) {
+		if err := s.service(r).SimulatePaste(r.Context()); err != nil {
+			writeServiceError(w, r, err, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	trace.Logf(r.Context(), "Successfully pasted clip %s", id)
 	w.WriteHeader(http.StatusOK)
 }
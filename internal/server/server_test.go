@@ -0,0 +1,598 @@
+package server
+
+import (
+	"clipboard-manager/internal/auth"
+	"clipboard-manager/internal/clipboard"
+	"clipboard-manager/internal/paste"
+	"clipboard-manager/internal/service"
+	"clipboard-manager/internal/speech"
+	"clipboard-manager/internal/storage"
+	"clipboard-manager/internal/storage/sqlite"
+	"clipboard-manager/pkg/types"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// testServer serves the API of a service with a memory monitor and a
+// temporary sqlite store
+type testServer struct {
+	*Server
+	svc     *service.ClipboardService
+	monitor *clipboard.MemoryMonitor
+	url     string
+	token   string // Sent as a bearer token once set by withUser
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir()) // The PID file lives in the home directory
+
+	dir := t.TempDir()
+	store, err := sqlite.New(storage.Config{
+		DBPath: filepath.Join(dir, "test.db"),
+		FSPath: filepath.Join(dir, "files"),
+	})
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	monitor := clipboard.NewMemoryMonitor()
+	svc := service.New(monitor, store)
+	svc.SetPaster(paste.Disabled("testing"))
+	svc.SetSpeaker(speech.Disabled("testing"))
+	if err := svc.Start(); err != nil {
+		t.Fatalf("failed to start service: %v", err)
+	}
+	t.Cleanup(func() { svc.Stop() })
+
+	s, err := New(svc, Config{})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	ts := httptest.NewServer(s.routes())
+	t.Cleanup(ts.Close)
+	s.srv = &http.Server{Addr: ts.Listener.Addr().String()}
+
+	return &testServer{Server: s, svc: svc, monitor: monitor, url: ts.URL}
+}
+
+// do sends a request and returns the response's status and body
+func (ts *testServer) do(t *testing.T, method, path, contentType, body string) (int, []byte) {
+	t.Helper()
+	req, err := http.NewRequest(method, ts.url+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("NewRequest: %v", err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if ts.token != "" {
+		req.Header.Set("Authorization", "Bearer "+ts.token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("failed to read response: %v", err)
+	}
+	return resp.StatusCode, data
+}
+
+// withUser turns on users and sends later requests as a new user, with a
+// token of scope. Users other than admins get a history of their own.
+func (ts *testServer) withUser(t *testing.T, name string, admin bool, scope string) {
+	t.Helper()
+	if ts.config.Users == nil {
+		dir := t.TempDir()
+		users, err := auth.Open(filepath.Join(dir, "users.json"))
+		if err != nil {
+			t.Fatalf("failed to open users: %v", err)
+		}
+		ts.config.Users = users
+		ts.config.OpenUserStore = func(name string) (storage.Storage, error) {
+			store, err := sqlite.New(storage.Config{
+				DBPath: filepath.Join(dir, name+".db"),
+				FSPath: filepath.Join(dir, name),
+			})
+			if err == nil {
+				t.Cleanup(func() { store.Close() })
+			}
+			return store, err
+		}
+	}
+	_, token, err := ts.config.Users.Add(name, admin, scope)
+	if err != nil {
+		t.Fatalf("failed to add user %s: %v", name, err)
+	}
+	ts.token = token
+}
+
+// fakeTool puts a command called name that always succeeds first on PATH
+func fakeTool(t *testing.T, name string) {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\ncat >/dev/null\n"), 0o755); err != nil {
+		t.Fatalf("failed to write %s: %v", name, err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+// addClip adds a text clip through the API and returns it
+func (ts *testServer) addClip(t *testing.T, text string) types.Clip {
+	t.Helper()
+	status, body := ts.do(t, http.MethodPost, "/api/clips", "text/plain", text)
+	if status != http.StatusCreated {
+		t.Fatalf("POST /api/clips = %d: %s", status, body)
+	}
+	var clip types.Clip
+	if err := json.Unmarshal(body, &clip); err != nil {
+		t.Fatalf("failed to decode clip: %v", err)
+	}
+	time.Sleep(10 * time.Millisecond) // Keeps the order of clips deterministic
+	return clip
+}
+
+// decode unmarshals body into v
+func decode(t *testing.T, body []byte, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(body, v); err != nil {
+		t.Fatalf("failed to decode %s: %v", body, err)
+	}
+}
+
+func TestServer_Clips(t *testing.T) {
+	ts := newTestServer(t)
+	first := ts.addClip(t, "first")
+	second := ts.addClip(t, "second")
+
+	status, body := ts.do(t, http.MethodGet, "/api/clips", "", "")
+	if status != http.StatusOK {
+		t.Fatalf("GET /api/clips = %d: %s", status, body)
+	}
+	var clips []types.Clip
+	decode(t, body, &clips)
+	if len(clips) != 2 || clips[0].ID != second.ID || clips[1].ID != first.ID {
+		t.Errorf("GET /api/clips = %+v, want the second clip then the first", clips)
+	}
+
+	status, body = ts.do(t, http.MethodGet, "/api/clips/1", "", "")
+	var clip types.Clip
+	decode(t, body, &clip)
+	if status != http.StatusOK || string(clip.Content) != "first" {
+		t.Errorf("GET /api/clips/1 = %d %q, want the first clip", status, clip.Content)
+	}
+
+	status, body = ts.do(t, http.MethodGet, "/api/clips/id/"+second.ID, "", "")
+	decode(t, body, &clip)
+	if status != http.StatusOK || string(clip.Content) != "second" {
+		t.Errorf("GET /api/clips/id/%s = %d %q, want the second clip", second.ID, status, clip.Content)
+	}
+
+	if status, _ := ts.do(t, http.MethodGet, "/api/clips/id/999", "", ""); status != http.StatusNotFound {
+		t.Errorf("GET of a missing clip = %d, want 404", status)
+	}
+	if status, _ := ts.do(t, http.MethodPost, "/api/clips", "", "untyped"); status != http.StatusBadRequest {
+		t.Errorf("POST without a type = %d, want 400", status)
+	}
+}
+
+func TestServer_ClipContent(t *testing.T) {
+	ts := newTestServer(t)
+	status, body := ts.do(t, http.MethodPost, "/api/clips?type=text/html", "", `<script>fetch("/api/clips")</script>`)
+	if status != http.StatusCreated {
+		t.Fatalf("POST /api/clips = %d: %s", status, body)
+	}
+	var clip types.Clip
+	decode(t, body, &clip)
+
+	// Copied HTML must not run on the daemon's origin
+	resp, err := http.Get(ts.url + "/api/clips/id/" + clip.ID + "/content")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if csp := resp.Header.Get("Content-Security-Policy"); !strings.HasPrefix(csp, "sandbox") {
+		t.Errorf("Content-Security-Policy = %q, want a sandbox", csp)
+	}
+	if resp.Header.Get("X-Content-Type-Options") != "nosniff" {
+		t.Error("expected X-Content-Type-Options: nosniff")
+	}
+}
+
+func TestServer_Search(t *testing.T) {
+	ts := newTestServer(t)
+	ts.addClip(t, "alpha note")
+	ts.addClip(t, "beta note")
+
+	status, body := ts.do(t, http.MethodGet, "/api/search?q=alpha", "", "")
+	if status != http.StatusOK {
+		t.Fatalf("GET /api/search = %d: %s", status, body)
+	}
+	var results []storage.SearchResult
+	decode(t, body, &results)
+	if len(results) != 1 || string(results[0].Clip.Content) != "alpha note" {
+		t.Errorf("search for alpha found %d results, want the alpha clip", len(results))
+	}
+
+	status, body = ts.do(t, http.MethodGet, "/api/search?q=note", "", "")
+	decode(t, body, &results)
+	if status != http.StatusOK || len(results) != 2 {
+		t.Errorf("search for note = %d with %d results, want both clips", status, len(results))
+	}
+
+	if status, _ := ts.do(t, http.MethodGet, "/api/search", "", ""); status != http.StatusBadRequest {
+		t.Errorf("search without a query = %d, want 400", status)
+	}
+}
+
+func TestServer_Paste(t *testing.T) {
+	ts := newTestServer(t)
+	first := ts.addClip(t, "first")
+	second := ts.addClip(t, "second")
+
+	if status, body := ts.do(t, http.MethodPost, "/api/clips/1/paste", "", ""); status != http.StatusOK {
+		t.Fatalf("paste by index = %d: %s", status, body)
+	}
+	if current, ok := ts.monitor.Current(); !ok || string(current.Content) != "first" {
+		t.Errorf("clipboard holds %q after pasting the first clip", current.Content)
+	}
+
+	if status, body := ts.do(t, http.MethodPost, "/api/clips/id/"+first.ID+"/paste", "", ""); status != http.StatusOK {
+		t.Errorf("paste by ID = %d: %s", status, body)
+	}
+	if status, _ := ts.do(t, http.MethodPost, "/api/clips/9/paste", "", ""); status != http.StatusNotFound {
+		t.Errorf("paste of a missing clip = %d, want 404", status)
+	}
+	if status, _ := ts.do(t, http.MethodPost, "/api/clips/x/paste", "", ""); status != http.StatusBadRequest {
+		t.Errorf("paste with an invalid index = %d, want 400", status)
+	}
+
+	// Copied, but the shortcut can't be pressed here
+	status, body := ts.do(t, http.MethodPost, "/api/clips/id/"+second.ID+"/paste?simulate=true", "", "")
+	if status != http.StatusNotImplemented {
+		t.Errorf("simulated paste = %d: %s, want 501", status, body)
+	}
+	if current, _ := ts.monitor.Current(); string(current.Content) != "second" {
+		t.Errorf("clipboard holds %q after a simulated paste of the second clip", current.Content)
+	}
+}
+
+func TestServer_UserPaste(t *testing.T) {
+	// Pressing keys would work here
+	fakeTool(t, "xdotool")
+	t.Setenv("DISPLAY", ":0")
+	t.Setenv("WAYLAND_DISPLAY", "")
+
+	ts := newTestServer(t)
+	ts.withUser(t, "guest", false, auth.ScopePaste)
+	clip := ts.addClip(t, "guest's clip")
+
+	if status, body := ts.do(t, http.MethodPost, "/api/clips/id/"+clip.ID+"/paste", "", ""); status != http.StatusOK {
+		t.Errorf("paste into the user's own clipboard = %d: %s", status, body)
+	}
+	// but not into the desktop of whoever runs the daemon
+	status, body := ts.do(t, http.MethodPost, "/api/clips/id/"+clip.ID+"/paste?simulate=true", "", "")
+	if status != http.StatusNotImplemented {
+		t.Errorf("simulated paste by a user = %d: %s, want 501", status, body)
+	}
+	if current, ok := ts.monitor.Current(); ok && string(current.Content) == "guest's clip" {
+		t.Error("a user's clip reached the system clipboard")
+	}
+}
+
+func TestServer_UserAppleNotes(t *testing.T) {
+	ts := newTestServer(t)
+	ts.withUser(t, "guest", false, auth.ScopeFull)
+	clip := ts.addClip(t, "guest's clip")
+
+	// Notes would go to the account of whoever runs the daemon
+	if status, body := ts.do(t, http.MethodPost, "/api/clips/"+clip.ID+"/apple-notes", "", ""); status != http.StatusForbidden {
+		t.Errorf("export by a user = %d: %s, want 403", status, body)
+	}
+	body := fmt.Sprintf(`{"action": "apple-notes", "ids": [%q]}`, clip.ID)
+	if status, body := ts.do(t, http.MethodPost, "/api/clips/bulk", "application/json", body); status != http.StatusForbidden {
+		t.Errorf("bulk export by a user = %d: %s, want 403", status, body)
+	}
+}
+
+func TestServer_Delete(t *testing.T) {
+	ts := newTestServer(t)
+	clip := ts.addClip(t, "doomed")
+
+	if status, body := ts.do(t, http.MethodDelete, "/api/clips/id/"+clip.ID, "", ""); status != http.StatusOK {
+		t.Fatalf("DELETE = %d: %s", status, body)
+	}
+	if status, _ := ts.do(t, http.MethodGet, "/api/clips/id/"+clip.ID, "", ""); status != http.StatusNotFound {
+		t.Errorf("GET of a deleted clip = %d, want 404", status)
+	}
+
+	status, body := ts.do(t, http.MethodGet, "/api/trash", "", "")
+	if status != http.StatusOK || !strings.Contains(string(body), clip.ID) {
+		t.Errorf("GET /api/trash = %d %s, want the deleted clip", status, body)
+	}
+}
+
+func TestServer_Related(t *testing.T) {
+	ts := newTestServer(t)
+	clip := ts.addClip(t, "alone")
+
+	status, body := ts.do(t, http.MethodGet, "/api/clips/"+clip.ID+"/related", "", "")
+	var related []types.Clip
+	decode(t, body, &related)
+	if status != http.StatusOK || len(related) != 0 {
+		t.Errorf("GET related = %d with %d clips, want none", status, len(related))
+	}
+	if status, _ := ts.do(t, http.MethodGet, "/api/clips/999/related", "", ""); status != http.StatusNotFound {
+		t.Errorf("GET related of a missing clip = %d, want 404", status)
+	}
+}
+
+func TestServer_Timeline(t *testing.T) {
+	ts := newTestServer(t)
+	ts.addClip(t, "today")
+
+	status, body := ts.do(t, http.MethodGet, "/api/timeline?granularity=hour", "", "")
+	var buckets []storage.TimelineBucket
+	decode(t, body, &buckets)
+	if status != http.StatusOK || len(buckets) != 1 || buckets[0].Clips != 1 || buckets[0].End.Sub(buckets[0].Start) != time.Hour {
+		t.Errorf("GET timeline = %d: %s, want this hour's clip", status, body)
+	}
+
+	// A date as to covers the whole day; yesterday had no clips
+	yesterday := time.Now().AddDate(0, 0, -1).Format("2006-01-02")
+	status, body = ts.do(t, http.MethodGet, "/api/timeline?from="+yesterday+"&to="+yesterday, "", "")
+	decode(t, body, &buckets)
+	if status != http.StatusOK || len(buckets) != 0 {
+		t.Errorf("GET yesterday's timeline = %d: %s, want no buckets", status, body)
+	}
+	status, body = ts.do(t, http.MethodGet, "/api/search?from="+time.Now().Format("2006-01-02"), "", "")
+	var results []storage.SearchResult
+	decode(t, body, &results)
+	if status != http.StatusOK || len(results) != 1 {
+		t.Errorf("GET search from today = %d: %s, want today's clip", status, body)
+	}
+
+	for _, query := range []string{"granularity=week", "from=tuesday", "to=2024-13-01"} {
+		if status, _ := ts.do(t, http.MethodGet, "/api/timeline?"+query, "", ""); status != http.StatusBadRequest {
+			t.Errorf("GET timeline?%s = %d, want 400", query, status)
+		}
+	}
+}
+
+func TestServer_Status(t *testing.T) {
+	ts := newTestServer(t)
+	status, body := ts.do(t, http.MethodGet, "/status", "", "")
+	if status != http.StatusOK {
+		t.Fatalf("GET /status = %d: %s", status, body)
+	}
+	var got struct {
+		Status  string `json:"status"`
+		PID     int    `json:"pid"`
+		Paused  bool   `json:"paused"`
+		Capture struct {
+			Panics int `json:"panics"`
+		} `json:"capture"`
+	}
+	decode(t, body, &got)
+	if got.Status != "ok" || got.PID != os.Getpid() || got.Paused || got.Capture.Panics != 0 {
+		t.Errorf("GET /status = %s", body)
+	}
+}
+
+// waitForClients polls until the hub has n clients
+func waitForClients(t *testing.T, hub *Hub, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for hub.ClientCount() != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("hub has %d clients, want %d", hub.ClientCount(), n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestHub_Broadcast(t *testing.T) {
+	ts := newTestServer(t)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.url, "http")+"/ws", nil)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer conn.Close()
+	waitForClients(t, ts.hub, 1)
+
+	ts.monitor.InjectClip(types.Clip{Content: []byte("copied"), Type: "text/plain"})
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var message struct {
+		Type    string     `json:"type"`
+		Payload types.Clip `json:"payload"`
+	}
+	if err := conn.ReadJSON(&message); err != nil {
+		t.Fatalf("ReadJSON: %v", err)
+	}
+	if message.Type != "clipboard_change" || string(message.Payload.Content) != "copied" {
+		t.Errorf("got %s message with %q, want clipboard_change with the copied clip", message.Type, message.Payload.Content)
+	}
+}
+
+func TestHub_EvictsSlowClients(t *testing.T) {
+	hub := newHub()
+	go hub.run()
+
+	// Neither client has a writer draining its queue; the slow one has no
+	// room left for another message
+	slow := &Client{hub: hub, send: make(chan []byte)}
+	fast := &Client{hub: hub, send: make(chan []byte, 1)}
+	hub.register <- slow
+	hub.register <- fast
+	waitForClients(t, hub, 2)
+
+	hub.broadcast <- []byte("message")
+	waitForClients(t, hub, 1)
+
+	if _, ok := <-slow.send; ok {
+		t.Error("the slow client's queue is still open")
+	}
+	if message := <-fast.send; string(message) != "message" {
+		t.Errorf("fast client got %q", message)
+	}
+}
+
+func TestCheckExisting(t *testing.T) {
+	sleep, err := exec.LookPath("sleep")
+	if err != nil {
+		t.Skip("sleep is not installed")
+	}
+	t.Setenv("HOME", t.TempDir())
+	pidFile, err := newPIDFile()
+	if err != nil {
+		t.Fatalf("newPIDFile: %v", err)
+	}
+	writePID := func(pid int) {
+		t.Helper()
+		if err := os.WriteFile(pidFile.path, []byte(strconv.Itoa(pid)), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	exists := func() bool {
+		_, err := os.Stat(pidFile.path)
+		return err == nil
+	}
+
+	// Another daemon that answers on the port is left alone
+	daemon := exec.Command(sleep, "30")
+	if err := daemon.Start(); err != nil {
+		t.Fatalf("failed to start a process: %v", err)
+	}
+	exited := make(chan struct{})
+	go func() {
+		daemon.Wait()
+		close(exited)
+	}()
+	t.Cleanup(func() { daemon.Process.Kill() })
+
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"status": "ok", "pid": %d}`, daemon.Process.Pid)
+	}))
+	defer healthy.Close()
+	port := healthy.Listener.Addr().(*net.TCPAddr).Port
+
+	writePID(daemon.Process.Pid)
+	if err := CheckExisting(port, false); err == nil || !strings.Contains(err.Error(), "already running") {
+		t.Errorf("CheckExisting with a healthy daemon = %v, want already running", err)
+	}
+	if !exists() {
+		t.Error("the PID file of a healthy daemon was removed")
+	}
+
+	// With -replace, or when it doesn't answer, it is terminated
+	if err := CheckExisting(port, true); err != nil {
+		t.Fatalf("CheckExisting with replace = %v", err)
+	}
+	select {
+	case <-exited:
+	case <-time.After(2 * time.Second):
+		t.Error("the running daemon was not terminated")
+	}
+	if exists() {
+		t.Error("the PID file was not removed")
+	}
+
+	// A PID file left by a daemon that crashed is removed
+	writePID(daemon.Process.Pid)
+	if err := CheckExisting(port, false); err != nil || exists() {
+		t.Errorf("CheckExisting with a stale PID file = %v, file kept: %v", err, exists())
+	}
+
+	// Our own PID file is ours to keep
+	writePID(os.Getpid())
+	if err := CheckExisting(port, false); err != nil || !exists() {
+		t.Errorf("CheckExisting with our own PID = %v, file kept: %v", err, exists())
+	}
+}
+
+func TestServer_Speak(t *testing.T) {
+	ts := newTestServer(t)
+	clip := ts.addClip(t, "read me")
+
+	// Nothing can speak here
+	if status, body := ts.do(t, http.MethodPost, "/api/clips/"+clip.ID+"/speak", "", ""); status != http.StatusNotImplemented {
+		t.Errorf("speak = %d: %s, want 501", status, body)
+	}
+	if status, _ := ts.do(t, http.MethodPost, "/api/clips/999/speak", "", ""); status != http.StatusNotFound {
+		t.Errorf("speak of a missing clip = %d, want 404", status)
+	}
+	if status, _ := ts.do(t, http.MethodDelete, "/api/speak", "", ""); status != http.StatusNoContent {
+		t.Errorf("stop speaking = %d, want 204", status)
+	}
+}
+
+func TestServer_UserSpeak(t *testing.T) {
+	// Speaking would work here
+	fakeTool(t, "espeak-ng")
+
+	ts := newTestServer(t)
+	ts.withUser(t, "guest", false, auth.ScopeFull)
+	clip := ts.addClip(t, "guest's clip")
+
+	// The daemon's speakers aren't the user's
+	status, body := ts.do(t, http.MethodPost, "/api/clips/"+clip.ID+"/speak", "", "")
+	if status != http.StatusNotImplemented {
+		t.Errorf("speak by a user = %d: %s, want 501", status, body)
+	}
+}
+
+func TestServer_Analytics(t *testing.T) {
+	ts := newTestServer(t)
+	ts.addClip(t, "first")
+	ts.addClip(t, "second")
+
+	status, body := ts.do(t, http.MethodGet, "/api/analytics", "", "")
+	if status != http.StatusOK {
+		t.Fatalf("analytics = %d: %s", status, body)
+	}
+	var report struct {
+		Clips int64 `json:"clips"`
+		Hours []struct {
+			Clips int64 `json:"clips"`
+		} `json:"hours"`
+	}
+	decode(t, body, &report)
+	if report.Clips != 2 || len(report.Hours) == 0 {
+		t.Errorf("unexpected report %s", body)
+	}
+
+	status, body = ts.do(t, http.MethodGet, "/api/analytics?format=csv", "", "")
+	if status != http.StatusOK || !strings.HasPrefix(string(body), "hour,app,bundle_id,type,clips,bytes\n") {
+		t.Errorf("CSV analytics = %d: %s", status, body)
+	}
+	status, body = ts.do(t, http.MethodGet, "/api/analytics/heatmap.svg", "", "")
+	if status != http.StatusOK || !strings.HasPrefix(string(body), "<svg") {
+		t.Errorf("heatmap = %d: %s", status, body)
+	}
+
+	if status, _ := ts.do(t, http.MethodGet, "/api/analytics?format=xml", "", ""); status != http.StatusBadRequest {
+		t.Errorf("analytics as XML = %d, want 400", status)
+	}
+	if status, _ := ts.do(t, http.MethodGet, "/api/analytics?from=2024-06-02&to=2024-06-01", "", ""); status != http.StatusBadRequest {
+		t.Errorf("analytics of a backwards range = %d, want 400", status)
+	}
+}
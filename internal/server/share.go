@@ -0,0 +1,245 @@
+package server
+
+import (
+	"clipboard-manager/internal/share"
+	"clipboard-manager/internal/trace"
+	"clipboard-manager/pkg/types"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// shareRequest is the body of POST /api/clips/{id}/share. Both fields are
+// optional.
+type shareRequest struct {
+	ExpiresIn string `json:"expires_in"` // Duration such as "1h", default 24h
+	Password  string `json:"password"`
+}
+
+// shareSummary describes a share without its password hash
+type shareSummary struct {
+	ID          string         `json:"id"`
+	ClipID      string         `json:"clip_id"`
+	Profile     string         `json:"profile,omitempty"`
+	URL         string         `json:"url,omitempty"` // Only returned when the share is created
+	CreatedAt   time.Time      `json:"created_at"`
+	ExpiresAt   time.Time      `json:"expires_at"`
+	RevokedAt   *time.Time     `json:"revoked_at,omitempty"`
+	HasPassword bool           `json:"has_password"`
+	Views       int            `json:"views"`
+	Accesses    []share.Access `json:"accesses,omitempty"`
+}
+
+func summarizeShare(sh *share.Share) shareSummary {
+	summary := shareSummary{
+		ID:          sh.ID,
+		ClipID:      sh.ClipID,
+		Profile:     sh.Profile,
+		CreatedAt:   sh.CreatedAt,
+		ExpiresAt:   sh.ExpiresAt,
+		RevokedAt:   sh.RevokedAt,
+		HasPassword: sh.HasPassword(),
+		Accesses:    sh.Accesses,
+	}
+	for _, access := range sh.Accesses {
+		if access.Granted {
+			summary.Views++
+		}
+	}
+	return summary
+}
+
+// shareBaseURL returns the address share links start with
+func (s *Server) shareBaseURL() string {
+	if s.config.Settings != nil {
+		if base := s.config.Settings.Current().ShareURL; base != "" {
+			return strings.TrimRight(base, "/")
+		}
+	}
+	return fmt.Sprintf("http://localhost:%d", s.config.Port)
+}
+
+func (s *Server) handleCreateShare(w http.ResponseWriter, r *http.Request) {
+	if s.config.Shares == nil {
+		writeError(w, r, http.StatusNotImplemented, "sharing is not available")
+		return
+	}
+	var req shareRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, r, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	ttl := share.DefaultTTL
+	if req.ExpiresIn != "" {
+		parsed, err := time.ParseDuration(req.ExpiresIn)
+		if err != nil {
+			writeError(w, r, http.StatusUnprocessableEntity, "invalid expires_in: "+err.Error())
+			return
+		}
+		ttl = parsed
+	}
+
+	clip, err := s.service(r).GetClipByID(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeServiceError(w, r, err, http.StatusNotFound)
+		return
+	}
+	// File clips are paths on this Mac, which are no use to anyone else
+	switch {
+	case clip.Type == types.TypeFile || clip.Type == types.TypeFileList:
+		writeError(w, r, http.StatusUnsupportedMediaType, "file clips can't be shared")
+		return
+	case clip.Metadata.ExpiresAt != nil:
+		writeError(w, r, http.StatusForbidden, "sensitive clips can't be shared")
+		return
+	}
+
+	created, token, err := s.config.Shares.Create(clip.ID, s.clipService.Profile(), ttl, req.Password)
+	if err != nil {
+		writeServiceError(w, r, err, http.StatusUnprocessableEntity)
+		return
+	}
+	summary := summarizeShare(created)
+	summary.URL = s.shareBaseURL() + "/share/" + token
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(summary)
+}
+
+func (s *Server) handleGetShares(w http.ResponseWriter, r *http.Request) {
+	if s.config.Shares == nil {
+		writeError(w, r, http.StatusNotImplemented, "sharing is not available")
+		return
+	}
+	shares := s.config.Shares.List()
+	summaries := make([]shareSummary, 0, len(shares))
+	for i := range shares {
+		summaries = append(summaries, summarizeShare(&shares[i]))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summaries)
+}
+
+func (s *Server) handleRevokeShare(w http.ResponseWriter, r *http.Request) {
+	if s.config.Shares == nil {
+		writeError(w, r, http.StatusNotImplemented, "sharing is not available")
+		return
+	}
+	revoked, err := s.config.Shares.Revoke(chi.URLParam(r, "shareID"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, share.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		writeServiceError(w, r, err, status)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summarizeShare(revoked))
+}
+
+// sharePasswordPage asks for the password of a protected share
+var sharePasswordPage = template.Must(template.New("password").Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><meta name="viewport" content="width=device-width">
+<title>Shared clip</title></head>
+<body style="font-family: -apple-system, sans-serif; max-width: 24em; margin: 4em auto">
+<form method="post">
+<p>This clip is protected by a password.</p>
+{{if .}}<p style="color: #b00">{{.}}</p>{{end}}
+<input type="password" name="password" autofocus required>
+<button type="submit">Open</button>
+</form>
+</body></html>
+`))
+
+// handleShare serves a shared clip to anyone with the link. It is outside
+// /api, so a proxy in front of the daemon can expose only /share/.
+func (s *Server) handleShare(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Robots-Tag", "noindex, nofollow")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	if s.config.Shares == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	remote := r.RemoteAddr
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		remote = forwarded + " via " + remote
+	}
+	sh, err := s.config.Shares.Lookup(chi.URLParam(r, "token"))
+	if err != nil {
+		if sh != nil {
+			s.recordShareAccess(sh.ID, share.Access{RemoteAddr: remote, Reason: err.Error()})
+		}
+		status := http.StatusGone
+		if errors.Is(err, share.ErrInvalid) || errors.Is(err, share.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	if sh.HasPassword() {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			sharePasswordPage.Execute(w, "")
+			return
+		}
+		if !share.CheckPassword(sh, r.PostFormValue("password")) {
+			if err := s.config.Shares.RecordWrongPassword(sh.ID, remote); err != nil {
+				trace.Logf(r.Context(), "Failed to record access to share %s: %v", sh.ID, err)
+			}
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.WriteHeader(http.StatusUnauthorized)
+			sharePasswordPage.Execute(w, "Wrong password.")
+			return
+		}
+	}
+
+	// Clip IDs are only unique within a profile, so while another one is
+	// active the link must not show its clip
+	if sh.Profile != s.clipService.Profile() {
+		s.recordShareAccess(sh.ID, share.Access{RemoteAddr: remote, Reason: "profile " + sh.Profile + " not active"})
+		http.Error(w, "shared clip is not available right now", http.StatusGone)
+		return
+	}
+	reader, clip, err := s.service(r).GetClipContent(r.Context(), sh.ClipID)
+	if err != nil {
+		s.recordShareAccess(sh.ID, share.Access{RemoteAddr: remote, Reason: "clip deleted"})
+		http.Error(w, "shared clip no longer exists", http.StatusGone)
+		return
+	}
+	defer reader.Close()
+	s.recordShareAccess(sh.ID, share.Access{RemoteAddr: remote, Granted: true})
+
+	// The sandbox keeps shared HTML from running scripts on the daemon's origin
+	w.Header().Set("Content-Security-Policy", rawContentPolicy)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Type", contentTypeFor(clip.Type))
+	if file, ok := reader.(*os.File); ok {
+		if info, err := file.Stat(); err == nil {
+			w.Header().Set("Content-Length", strconv.FormatInt(info.Size(), 10))
+		}
+	}
+	if _, err := io.Copy(w, reader); err != nil {
+		trace.Logf(r.Context(), "Error streaming shared clip %s: %v", clip.ID, err)
+	}
+}
+
+func (s *Server) recordShareAccess(id string, access share.Access) {
+	if err := s.config.Shares.Record(id, access); err != nil {
+		log.Printf("Failed to record access to share %s: %v", id, err)
+	}
+}
@@ -0,0 +1,62 @@
+package server
+
+import (
+	"clipboard-manager/internal/profile"
+	"clipboard-manager/internal/share"
+	"clipboard-manager/internal/storage"
+	"clipboard-manager/internal/storage/sqlite"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestServer_ShareProfiles(t *testing.T) {
+	ts := newTestServer(t)
+	shares, err := share.Open(filepath.Join(t.TempDir(), share.FileName))
+	if err != nil {
+		t.Fatalf("failed to open shares: %v", err)
+	}
+	ts.config.Shares = shares
+	profiles := profile.NewManager(t.TempDir(), func(name, dir string) (storage.Storage, error) {
+		return sqlite.New(storage.Config{
+			DBPath: filepath.Join(dir, "clipboard.db"),
+			FSPath: filepath.Join(dir, "files"),
+		})
+	})
+	ts.svc.SetProfiles(profiles, profile.Default)
+	if err := ts.svc.UseProfile("personal"); err != nil {
+		t.Fatalf("failed to switch profiles: %v", err)
+	}
+
+	clip := ts.addClip(t, "shared note")
+	status, body := ts.do(t, http.MethodPost, "/api/clips/"+clip.ID+"/share", "", "")
+	if status != http.StatusCreated {
+		t.Fatalf("POST share = %d: %s", status, body)
+	}
+	var created shareSummary
+	decode(t, body, &created)
+	link := strings.TrimPrefix(created.URL, ts.shareBaseURL())
+
+	if status, body := ts.do(t, http.MethodGet, link, "", ""); status != http.StatusOK || string(body) != "shared note" {
+		t.Errorf("GET share = %d %q, want the shared note", status, body)
+	}
+
+	// The work profile's clips get the same IDs, which the link must not reach
+	if err := ts.svc.UseProfile("work"); err != nil {
+		t.Fatalf("failed to switch profiles: %v", err)
+	}
+	if other := ts.addClip(t, "work secret"); other.ID != clip.ID {
+		t.Fatalf("the work clip has ID %s, want %s", other.ID, clip.ID)
+	}
+	if status, body := ts.do(t, http.MethodGet, link, "", ""); status != http.StatusGone {
+		t.Errorf("GET share in another profile = %d %q, want 410", status, body)
+	}
+
+	if err := ts.svc.UseProfile("personal"); err != nil {
+		t.Fatalf("failed to switch profiles: %v", err)
+	}
+	if status, body := ts.do(t, http.MethodGet, link, "", ""); status != http.StatusOK || string(body) != "shared note" {
+		t.Errorf("GET share back in its profile = %d %q, want the shared note", status, body)
+	}
+}
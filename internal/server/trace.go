@@ -0,0 +1,23 @@
+package server
+
+import (
+	"clipboard-manager/internal/trace"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// traceRequests passes the ID middleware.RequestID gave a request on to the
+// service and storage, and back to the client in X-Request-Id. Clients that
+// send their own X-Request-Id keep it.
+func traceRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := middleware.GetReqID(r.Context())
+		if id == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set(middleware.RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(trace.WithRequestID(r.Context(), id)))
+	})
+}
@@ -1,6 +1,8 @@
 package server
 
 import (
+	"clipboard-manager/internal/service"
+	"clipboard-manager/internal/storage"
 	"clipboard-manager/pkg/types"
 	"encoding/json"
 	"log"
@@ -11,12 +13,11 @@ import (
 	"github.com/gorilla/websocket"
 )
 
+// upgrader accepts WebSocket connections. Server.serveWs checks origins
+// against the CORS settings.
 var upgrader = websocket.Upgrader{
 	ReadBufferSize:  1024,
 	WriteBufferSize: 1024,
-	CheckOrigin: func(r *http.Request) bool {
-		return true // Allow all origins in development
-	},
 }
 
 // Hub maintains the set of active clients and broadcasts messages to them
@@ -63,7 +64,8 @@ func (h *Hub) run() {
 			log.Printf("Client disconnected. Total clients: %d", len(h.clients))
 
 		case message := <-h.broadcast:
-			h.mu.RLock()
+			// Clients too slow to keep up are dropped, so take the write lock
+			h.mu.Lock()
 			for client := range h.clients {
 				select {
 				case client.send <- message:
@@ -72,11 +74,18 @@ func (h *Hub) run() {
 					delete(h.clients, client)
 				}
 			}
-			h.mu.RUnlock()
+			h.mu.Unlock()
 		}
 	}
 }
 
+// ClientCount returns the number of connected WebSocket clients
+func (h *Hub) ClientCount() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.clients)
+}
+
 // HandleClipboardChange implements service.ClipboardChangeHandler
 func (h *Hub) HandleClipboardChange(clip types.Clip) {
 	// Create a notification message
@@ -98,6 +107,47 @@ func (h *Hub) HandleClipboardChange(clip types.Clip) {
 	h.broadcast <- message
 }
 
+// HandleCaptureError implements service.CaptureErrorHandler, telling
+// clients that a clip may have been missed
+func (h *Hub) HandleCaptureError(event service.CaptureError) {
+	notification := struct {
+		Type    string               `json:"type"`
+		Payload service.CaptureError `json:"payload"`
+	}{Type: "capture_error", Payload: event}
+
+	message, err := json.Marshal(notification)
+	if err != nil {
+		log.Printf("Error marshaling capture error notification: %v", err)
+		return
+	}
+
+	h.broadcast <- message
+}
+
+// HandleEviction implements service.EvictionHandler, telling clients which
+// clips were removed to stay under the storage quota
+func (h *Hub) HandleEviction(eviction storage.Eviction) {
+	notification := struct {
+		Type    string `json:"type"`
+		Payload struct {
+			IDs        []string `json:"ids"`
+			FreedBytes int64    `json:"freed_bytes"`
+			UsedBytes  int64    `json:"used_bytes"`
+		} `json:"payload"`
+	}{Type: "clips_evicted"}
+	notification.Payload.IDs = eviction.IDs
+	notification.Payload.FreedBytes = eviction.FreedBytes
+	notification.Payload.UsedBytes = eviction.UsedBytes
+
+	message, err := json.Marshal(notification)
+	if err != nil {
+		log.Printf("Error marshaling eviction notification: %v", err)
+		return
+	}
+
+	h.broadcast <- message
+}
+
 // writePump pumps messages from the hub to the websocket connection
 func (c *Client) writePump() {
 	defer func() {
@@ -134,10 +184,12 @@ func (s *Server) serveWs(w http.ResponseWriter, r *http.Request) {
 	// Check if it's a websocket upgrade request
 	if !websocket.IsWebSocketUpgrade(r) {
 		log.Printf("Not a WebSocket upgrade request from %s", r.RemoteAddr)
-		http.Error(w, "Expected WebSocket Upgrade", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, "Expected WebSocket Upgrade")
 		return
 	}
 
+	upgrader := upgrader
+	upgrader.CheckOrigin = s.checkOrigin
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Printf("Error upgrading connection from %s: %v", r.RemoteAddr, err)
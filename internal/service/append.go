@@ -0,0 +1,144 @@
+package service
+
+import (
+	"clipboard-manager/internal/clipboard"
+	"clipboard-manager/pkg/types"
+	"log"
+	"strings"
+	"time"
+)
+
+// DefaultAppendSeparator joins copies in append mode unless another
+// separator is given
+const DefaultAppendSeparator = "\n"
+
+// AppendStatus describes append mode
+type AppendStatus struct {
+	Active    bool   `json:"active"`
+	Separator string `json:"separator,omitempty"`
+	Clips     int    `json:"clips"`  // Copies appended so far
+	Length    int    `json:"length"` // Length of the combined text in bytes
+}
+
+// StartAppend turns on append mode: text copied from then on is added to an
+// accumulator instead of being saved as separate clips, and the clipboard
+// holds everything copied so far. StopAppend saves it as one clip. Starting
+// again while active keeps what was collected and changes the separator.
+func (s *ClipboardService) StartAppend(separator string) AppendStatus {
+	s.appendMu.Lock()
+	defer s.appendMu.Unlock()
+	if !s.appending {
+		s.appending = true
+		s.appendParts = nil
+		log.Printf("Append mode started")
+	}
+	s.appendSeparator = separator
+	return s.appendStatusLocked()
+}
+
+// StopAppend turns off append mode and saves the combined text as a single
+// clip. It returns what was collected.
+func (s *ClipboardService) StopAppend() AppendStatus {
+	s.appendMu.Lock()
+	status := s.appendStatusLocked()
+	combined := strings.Join(s.appendParts, s.appendSeparator)
+	wasActive := s.appending
+	s.appending = false
+	s.appendParts = nil
+	s.appendMu.Unlock()
+
+	status.Active = false
+	if !wasActive {
+		return status
+	}
+	log.Printf("Append mode stopped after %d copies", status.Clips)
+	if status.Clips == 0 || s.pipeline == nil {
+		return status
+	}
+	// Like any capture, so the combined clip is classified and checked
+	// against the ignore rules
+	clip := types.Clip{Content: []byte(combined), Type: types.TypeText, CreatedAt: time.Now()}
+	if !s.pipeline.enqueue(clip) {
+		debugLog("Service is stopping, dropping the appended clip")
+	}
+	return status
+}
+
+// ToggleAppend starts append mode with the default separator, or stops it
+// if it is active
+func (s *ClipboardService) ToggleAppend() AppendStatus {
+	if s.AppendMode().Active {
+		return s.StopAppend()
+	}
+	return s.StartAppend(DefaultAppendSeparator)
+}
+
+// AppendMode returns the state of append mode
+func (s *ClipboardService) AppendMode() AppendStatus {
+	s.appendMu.Lock()
+	defer s.appendMu.Unlock()
+	return s.appendStatusLocked()
+}
+
+func (s *ClipboardService) appendStatusLocked() AppendStatus {
+	status := AppendStatus{Active: s.appending, Clips: len(s.appendParts)}
+	if s.appending {
+		status.Separator = s.appendSeparator
+	}
+	for i, part := range s.appendParts {
+		if i > 0 {
+			status.Length += len(s.appendSeparator)
+		}
+		status.Length += len(part)
+	}
+	return status
+}
+
+// appendClip adds a copied clip to the accumulator and reports whether it
+// did. Clips that aren't text, or match the ignore rules, are captured as
+// usual.
+func (s *ClipboardService) appendClip(clip types.Clip) bool {
+	if !s.AppendMode().Active {
+		return false
+	}
+	text, ok := clipboard.PlainText(&clip)
+	if !ok || text == "" || s.ignored(clip) {
+		return false
+	}
+
+	s.appendMu.Lock()
+	if !s.appending {
+		s.appendMu.Unlock()
+		return false
+	}
+	s.appendParts = append(s.appendParts, text)
+	first := len(s.appendParts) == 1
+	s.appendMu.Unlock()
+	debugLog("Appended %d bytes to the accumulator", len(text))
+
+	// The first copy is already on the clipboard. Later ones replace it with
+	// the combined text; the monitor calls this from its own loop and waits
+	// for writes, so write from another goroutine.
+	if !first {
+		go s.writeAppended()
+	}
+	return true
+}
+
+// writeAppended puts the combined text on the clipboard. Writes are
+// serialized and each writes the latest text, so the last one wins.
+func (s *ClipboardService) writeAppended() {
+	s.appendWriteMu.Lock()
+	defer s.appendWriteMu.Unlock()
+
+	s.appendMu.Lock()
+	active := s.appending
+	combined := strings.Join(s.appendParts, s.appendSeparator)
+	s.appendMu.Unlock()
+	if !active {
+		return
+	}
+	if err := s.monitor.SetContent(types.Clip{Content: []byte(combined), Type: types.TypeText}); err != nil {
+		log.Printf("[ERROR] Error setting the appended clipboard content: %v", err)
+	}
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"clipboard-manager/internal/config"
+	"clipboard-manager/internal/obsidian"
+	"clipboard-manager/pkg/types"
+	"context"
+	"fmt"
+)
+
+// ExportToAppleNotes adds clips to Apple Notes, a note each in a folder per
+// category, and returns how many it exported. Clips already there are left
+// alone. Sensitive clips are never exported; one among ids fails the export
+// before any clip is added.
+func (s *ClipboardService) ExportToAppleNotes(ctx context.Context, ids []string) (int64, error) {
+	clips := make([]*types.Clip, 0, len(ids))
+	for _, id := range ids {
+		clip, err := s.GetClipByID(ctx, id)
+		if err != nil {
+			return 0, err
+		}
+		if clip.Metadata.ExpiresAt != nil {
+			return 0, &ClipboardError{
+				Op:      "ExportToAppleNotes",
+				Index:   -1,
+				Message: fmt.Sprintf("clip %s: %v", id, obsidian.ErrSensitive),
+				Err:     obsidian.ErrSensitive,
+			}
+		}
+		clips = append(clips, clip)
+	}
+
+	s.mu.RLock()
+	target := obsidian.NewAppleNotes(appleNotesFolder(s.syncSettings.AppleNotes))
+	s.mu.RUnlock()
+	if err := target.Check(); err != nil {
+		return 0, &ClipboardError{
+			Op:      "ExportToAppleNotes",
+			Index:   -1,
+			Message: err.Error(),
+			Err:     err,
+		}
+	}
+
+	var exported int64
+	for _, clip := range clips {
+		if err := target.Write(ctx, clip); err != nil {
+			return exported, &ClipboardError{
+				Op:      "ExportToAppleNotes",
+				Index:   -1,
+				Message: fmt.Sprintf("failed to export clip %s", clip.ID),
+				Err:     err,
+			}
+		}
+		exported++
+	}
+	return exported, nil
+}
+
+// appleNotesFolder returns the Apple Notes folder clips are exported to
+func appleNotesFolder(settings config.AppleNotes) string {
+	if settings.Folder == "" {
+		return obsidian.DefaultAppleNotesFolder
+	}
+	return settings.Folder
+}
@@ -0,0 +1,35 @@
+package service
+
+import (
+	"clipboard-manager/internal/storage"
+	"context"
+)
+
+// Changes returns the clips changed after the change numbered since, each
+// with its latest change, and the sequence number of the latest change. It
+// is the feed sync clients follow to mirror history.
+func (s *ClipboardService) Changes(ctx context.Context, since int64, limit int) ([]storage.Change, int64, error) {
+	feed, ok := s.storage().(storage.ChangeFeed)
+	if !ok {
+		return nil, 0, &ClipboardError{
+			Op:      "Changes",
+			Index:   -1,
+			Message: "storage does not implement a change feed",
+		}
+	}
+
+	// Read after the changes, so the latest change is never before them
+	changes, err := feed.Changes(ctx, since, limit)
+	if err == nil {
+		var latest int64
+		if latest, err = feed.LatestChange(ctx); err == nil {
+			return changes, latest, nil
+		}
+	}
+	return nil, 0, &ClipboardError{
+		Op:      "Changes",
+		Index:   -1,
+		Message: "failed to list changes",
+		Err:     err,
+	}
+}
@@ -1,23 +1,51 @@
 package service
 
 import (
+	"clipboard-manager/internal/analytics"
 	"clipboard-manager/internal/clipboard"
+	"clipboard-manager/internal/config"
+	"clipboard-manager/internal/digest"
+	"clipboard-manager/internal/metrics"
+	"clipboard-manager/internal/notify"
 	"clipboard-manager/internal/obsidian"
+	"clipboard-manager/internal/paste"
+	"clipboard-manager/internal/plugin"
+	"clipboard-manager/internal/rules"
+	"clipboard-manager/internal/script"
+	"clipboard-manager/internal/snippet"
+	"clipboard-manager/internal/speech"
 	"clipboard-manager/internal/storage"
+	"clipboard-manager/internal/trace"
 	"clipboard-manager/pkg/types"
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"net/http"
 	"os"
+	"regexp"
 	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
-var debugMode = os.Getenv("DEBUG") == "1"
+// debugMode turns on verbose logging, from DEBUG=1 or the log_level setting
+var debugMode atomic.Bool
+
+func init() {
+	debugMode.Store(os.Getenv("DEBUG") == "1")
+}
+
+// pruneInterval is how often expired clips are looked for. Expiry is meant
+// for secrets, so it is checked far more often than the trash.
+const pruneInterval = 15 * time.Second
 
 func debugLog(format string, args ...interface{}) {
-	if debugMode {
+	if debugMode.Load() {
 		log.Printf("[DEBUG] "+format, args...)
 	}
 }
@@ -44,27 +72,115 @@ func (e *ClipboardError) Unwrap() error {
 // ClipboardService manages clipboard monitoring and storage
 type ClipboardService struct {
 	monitor        clipboard.Monitor
-	store          storage.Storage
+	store          storage.Storage // Guarded by storeMu, see storage
+	storeMu        sync.RWMutex
 	obsidianSync   *obsidian.SyncService
 	ctx            context.Context
 	cancel         context.CancelFunc
 	wg             sync.WaitGroup
 	handlers       []ClipboardChangeHandler
+	processors     []ClipProcessor
+	plugins        *plugin.Manager
+	scripts        *script.Engine
 	mu             sync.RWMutex
+	pipeline       *pipeline // Capture post-processing, running once started
+	limits         CaptureLimits
+	limiter        *captureLimiter
+	notifier       notify.Notifier
+	paster         paste.Paster // Presses the paste shortcut, see SimulatePaste
+	speaker        speech.Speaker
+	stopSpeaking   context.CancelFunc // Stops the clip being read aloud; guarded by mu
+	spoken         chan struct{}      // Closed once it has stopped; guarded by mu
+	linkClient     *http.Client
+	unfurls        chan struct{} // Link previews being fetched
+	translations   chan struct{} // Translations rules asked for, being fetched
+	started        bool // Guarded by mu, like the settings below
+
+	// Profiles; each has its own storage and adds to the settings
+	profiles  ProfileManager
+	profile   string     // Guarded by mu
+	profileMu sync.Mutex // Serializes UseProfile
+
+	// Settings applied by ApplyConfig
+	settings         config.Config // As given, before the profile's settings are added
+	pollSettings     config.Poll
+	syncSettings     syncSettings
+	ignoreApps       map[string]bool // Lower case app names and bundle IDs
+	ignorePatterns   []*regexp.Regexp
+	rules            *rules.Set
+	notifySettings   config.Notifications
+	unfurlLinks      bool
+	publishSettings  config.Publish
+	sendSettings     config.Send
+	translateSettings config.Translate
+	trashRetention time.Duration
+	digest         *digest.Config // Scheduled digests, if enabled
+	restoreLast    bool           // Restore the latest clip at startup
+	maintenance    config.Maintenance
+	quota          int64 // Bytes of clip content kept at most, zero for no quota
+	copyFiles      bool  // Keep copies of copied files, see copyFile
+	sizeLimits     map[string]int64 // Bytes kept at most by type, see sizeLimit
+	dedupNormalized bool            // Fold text into clips it matches once normalized, see normalizedDuplicate
+
+	// Database maintenance, see maintenanceLoop
+	lastActivity    atomic.Int64 // Unix nanoseconds of the last clip stored
+	maintenanceMu   sync.Mutex
+	maintenanceRuns int64
+	lastMaintenance *storage.MaintenanceReport
+
+	// Pause state; while paused clipboard changes are not recorded
+	pauseMu     sync.Mutex
+	paused      bool
+	pausedUntil time.Time // Zero when paused until resumed explicitly
+	resumeTimer *time.Timer
+
+	// Panics recovered while capturing, see CaptureStatus
+	health captureHealth
+
+	// Paste stack; each paste copies the next of these clips
+	stackMu   sync.Mutex
+	stack     []string
+	stackNext int // Index of the clip the next paste copies
+
+	// Append mode; while on, copied text is collected into one clip
+	appendMu        sync.Mutex
+	appending       bool
+	appendSeparator string
+	appendParts     []string
+	appendWriteMu   sync.Mutex // Serializes writes of the combined text
+
+	// Copy session of the last clip captured, see copySession
+	sessionMu   sync.Mutex
+	session     string
+	sessionApp  string    // Bundle ID or name of the app it was copied from
+	sessionLast time.Time // When it was captured
 }
 
 // New creates a new ClipboardService
 func New(monitor clipboard.Monitor, store storage.Storage) *ClipboardService {
 	ctx, cancel := context.WithCancel(context.Background())
 	service := &ClipboardService{
-		monitor: monitor,
-		store:   store,
-		ctx:     ctx,
-		cancel:  cancel,
+		monitor:        monitor,
+		store:          store,
+		ctx:            ctx,
+		cancel:         cancel,
+		trashRetention: storage.DefaultTrashRetention,
+		limits:         DefaultCaptureLimits,
+		notifier:       notify.New(),
+		paster:         paste.New(),
+		speaker:        speech.New(),
+		plugins:        plugin.NewManager(),
+		scripts:        script.NewEngine(),
+		linkClient:     &http.Client{},
+		unfurls:        make(chan struct{}, maxUnfurls),
+		translations:   make(chan struct{}, maxTranslations),
+		// Sync below is set up from the environment, so a config with the
+		// same settings leaves it alone
+		syncSettings: syncSettings{Obsidian: config.FromEnv().Obsidian},
 	}
 
 	// Log environment variables in debug mode
-	if debugMode {
+	if debugMode.Load() {
 		debugLog("Environment variables:")
 		for _, env := range []string{"OBSIDIAN_ENABLED", "OBSIDIAN_VAULT_PATH", "OBSIDIAN_SYNC_INTERVAL", 
 			"HOME", "TMPDIR", "USER", "CLIPBOARD_DB_PATH", "CLIPBOARD_FS_PATH", "CLIPBOARD_API_PORT"} {
@@ -152,6 +268,7 @@ func New(monitor clipboard.Monitor, store storage.Storage) *ClipboardService {
 		syncService, err := obsidian.New(store, obsidian.Config{
 			VaultPath:    vaultPath,
 			SyncInterval: interval,
+			OnError:      service.syncFailed,
 		})
 		if err != nil {
 			log.Printf("[ERROR] Failed to initialize Obsidian sync: %v", err)
@@ -164,6 +281,45 @@ func New(monitor clipboard.Monitor, store storage.Storage) *ClipboardService {
 	return service
 }
 
+// SetTrashRetention sets how long deleted clips are kept before being purged
+func (s *ClipboardService) SetTrashRetention(retention time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.trashRetention = retention
+}
+
+// SetCaptureLimits sets how bursts of clipboard changes are thinned out. It
+// must be called before Start.
+func (s *ClipboardService) SetCaptureLimits(limits CaptureLimits) {
+	s.limits = limits
+}
+
+// SetRestoreLast puts the latest clip back on the clipboard when the
+// service starts, if the clipboard is empty. It must be called before Start.
+func (s *ClipboardService) SetRestoreLast(restore bool) {
+	s.restoreLast = restore
+}
+
+// SetDigest writes a Markdown digest of each day or week's clips to
+// config.Dir. It must be called before Start.
+func (s *ClipboardService) SetDigest(config digest.Config) {
+	s.digest = &config
+}
+
+// Digest renders the Markdown digest of the period containing t
+func (s *ClipboardService) Digest(ctx context.Context, period digest.Period, t time.Time) (string, error) {
+	content, err := digest.Build(ctx, s.Search, period, t)
+	if err != nil {
+		return "", &ClipboardError{
+			Op:      "Digest",
+			Index:   -1,
+			Message: fmt.Sprintf("failed to build %s digest", period),
+			Err:     err,
+		}
+	}
+	return content, nil
+}
+
 // RegisterHandler adds a new clipboard change handler
 func (s *ClipboardService) RegisterHandler(handler ClipboardChangeHandler) {
 	s.mu.Lock()
@@ -171,12 +327,25 @@ func (s *ClipboardService) RegisterHandler(handler ClipboardChangeHandler) {
 	s.handlers = append(s.handlers, handler)
 }
 
+// RegisterProcessor adds a step that enriches clips before they are stored.
+// Processors run in the order they were registered.
+func (s *ClipboardService) RegisterProcessor(processor ClipProcessor) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.processors = append(s.processors, processor)
+}
+
 // Start begins monitoring and storing clipboard changes
 func (s *ClipboardService) Start() error {
+	s.mu.Lock()
+	s.started = true
+	obsidianSync := s.obsidianSync
+	s.mu.Unlock()
+
 	// Start Obsidian sync if configured
-	if s.obsidianSync != nil {
+	if obsidianSync != nil {
 		debugLog("Starting Obsidian sync service...")
-		if err := s.obsidianSync.Start(s.ctx); err != nil {
+		if err := obsidianSync.Start(s.ctx); err != nil {
 			log.Printf("[ERROR] Failed to start Obsidian sync: %v", err)
 		} else {
 			debugLog("Obsidian sync service started successfully")
@@ -185,28 +354,85 @@ func (s *ClipboardService) Start() error {
 		debugLog("No Obsidian sync service configured")
 	}
 
-	// Set up clipboard change handler
-	s.monitor.OnChange(func(clip types.Clip) {
+	// Report storage size on /metrics
+	if _, ok := s.storage().(storage.UsageReporter); ok {
+		metrics.RegisterGaugeFunc("database_bytes", "Size of the clip database in bytes.", func() float64 {
+			return float64(s.usage().DatabaseBytes)
+		})
+		metrics.RegisterGaugeFunc("external_storage_bytes", "Size of large clips stored as files in bytes.", func() float64 {
+			return float64(s.usage().ExternalBytes)
+		})
+	}
+
+	// Remove expired clips and empty old clips out of the trash periodically
+	s.wg.Add(1)
+	go s.pruneLoop()
+
+	// Keep the database in shape while the clipboard is idle
+	s.lastActivity.Store(time.Now().UnixNano())
+	if _, ok := s.storage().(storage.Maintainer); ok {
+		metrics.RegisterGaugeFunc("database_wal_bytes", "Size of the database write-ahead log in bytes.", func() float64 {
+			return float64(s.databaseState().WALBytes)
+		})
+		metrics.RegisterGaugeFunc("database_free_bytes", "Space in the database file no data uses in bytes.", func() float64 {
+			return float64(s.databaseState().FreeBytes)
+		})
+		metrics.RegisterGaugeFunc("maintenance_last_run_timestamp_seconds", "Time the last database maintenance run started.", func() float64 {
+			s.maintenanceMu.Lock()
+			defer s.maintenanceMu.Unlock()
+			if s.lastMaintenance == nil {
+				return 0
+			}
+			return float64(s.lastMaintenance.Started.Unix())
+		})
+		s.wg.Add(1)
+		go s.maintenanceLoop()
+	}
+
+	if s.digest != nil {
 		s.wg.Add(1)
 		go func() {
 			defer s.wg.Done()
-			if err := s.handleClipboardChange(clip); err != nil {
-				log.Printf("[ERROR] Error handling clipboard change: %v", err)
-				return
-			}
-			
-			// Notify all registered handlers
-			s.mu.RLock()
-			handlers := s.handlers // Copy to avoid holding lock during callbacks
-			s.mu.RUnlock()
-			
-			for _, handler := range handlers {
-				handler.HandleClipboardChange(clip)
-			}
+			digest.Run(s.ctx, s.Search, *s.digest)
 		}()
+	}
+
+	s.plugins.Start()
+
+	// Clipboard changes are processed in the background, see pipeline
+	s.pipeline = newPipeline([]stage{
+		{name: "classify", run: s.classifyClip},
+		{name: "enrich", run: s.enrichClip},
+		{name: "store", run: s.storeClip},
+		{name: "notify", run: s.notifyClip},
+	}, s.recoverCapture)
+	s.limiter = newCaptureLimiter(s.limits, func(clip types.Clip) {
+		if !s.pipeline.enqueue(clip) {
+			debugLog("Service is stopping, ignoring clipboard change")
+		}
 	})
+	s.monitor.OnChange(func(clip types.Clip) {
+		defer s.recoverCapture("clipboard change")
+		if paused, _ := s.IsPaused(); paused {
+			debugLog("History is paused, ignoring clipboard change")
+			return
+		}
+		if s.appendClip(clip) {
+			return
+		}
+		s.limiter.add(clip)
+	})
+
+	// Before the monitor starts, so the restored clip isn't recorded again
+	if s.restoreLast {
+		s.restoreLatest()
+	}
 
-	// Start the monitor
+	// Start the monitor. Monitors that recover from panics restart polling,
+	// which counts as a restart in CaptureStatus.
+	if reporter, ok := s.monitor.(clipboard.ErrorReporter); ok {
+		reporter.OnError(s.monitorFailed)
+	}
 	if err := s.monitor.Start(); err != nil {
 		return &ClipboardError{
 			Op:      "Start",
@@ -215,17 +441,25 @@ func (s *ClipboardService) Start() error {
 			Err:     err,
 		}
 	}
+	s.monitorStarted()
 
 	return nil
 }
 
-// Stop gracefully shuts down the service
+// Stop gracefully shuts down the service. Clips already captured are stored
+// before it returns.
 func (s *ClipboardService) Stop() error {
-	// Signal shutdown
-	s.cancel()
+	s.pauseMu.Lock()
+	if s.resumeTimer != nil {
+		s.resumeTimer.Stop()
+	}
+	s.pauseMu.Unlock()
 
 	// Stop the monitor
 	if err := s.monitor.Stop(); err != nil {
+		s.plugins.Stop()
+		s.scripts.Close()
+		s.cancel()
 		return &ClipboardError{
 			Op:      "Stop",
 			Index:   -1,
@@ -234,9 +468,26 @@ func (s *ClipboardService) Stop() error {
 		}
 	}
 
+	// Save what append mode collected, then finish processing captured
+	// clips and signal shutdown
+	s.StopAppend()
+	if s.limiter != nil {
+		s.limiter.stop()
+	}
+	if s.pipeline != nil {
+		s.pipeline.drain()
+	}
+	s.plugins.Stop()
+	s.scripts.Close()
+	s.cancel()
+
 	// Stop Obsidian sync if running
-	if s.obsidianSync != nil {
-		s.obsidianSync.Stop()
+	s.mu.Lock()
+	obsidianSync := s.obsidianSync
+	s.started = false
+	s.mu.Unlock()
+	if obsidianSync != nil {
+		obsidianSync.Stop()
 	}
 
 	// Wait for ongoing operations to complete
@@ -245,9 +496,56 @@ func (s *ClipboardService) Stop() error {
 	return nil
 }
 
+// Pause stops recording clipboard changes. With a positive duration recording
+// resumes automatically once it has passed.
+func (s *ClipboardService) Pause(duration time.Duration) {
+	s.pauseMu.Lock()
+	defer s.pauseMu.Unlock()
+
+	if s.resumeTimer != nil {
+		s.resumeTimer.Stop()
+		s.resumeTimer = nil
+	}
+
+	s.paused = true
+	s.pausedUntil = time.Time{}
+	if duration > 0 {
+		s.pausedUntil = time.Now().Add(duration)
+		s.resumeTimer = time.AfterFunc(duration, s.Resume)
+	}
+
+	log.Printf("Clipboard history paused")
+}
+
+// Resume starts recording clipboard changes again
+func (s *ClipboardService) Resume() {
+	s.pauseMu.Lock()
+	defer s.pauseMu.Unlock()
+
+	if s.resumeTimer != nil {
+		s.resumeTimer.Stop()
+		s.resumeTimer = nil
+	}
+	if !s.paused {
+		return
+	}
+
+	s.paused = false
+	s.pausedUntil = time.Time{}
+	log.Printf("Clipboard history resumed")
+}
+
+// IsPaused reports whether recording is paused and, for a timed pause, when
+// it will resume
+func (s *ClipboardService) IsPaused() (bool, time.Time) {
+	s.pauseMu.Lock()
+	defer s.pauseMu.Unlock()
+	return s.paused, s.pausedUntil
+}
+
 // GetClips returns a paginated list of clips
 func (s *ClipboardService) GetClips(ctx context.Context, limit, offset int) ([]*types.Clip, error) {
-	clips, err := s.store.List(ctx, storage.ListFilter{
+	clips, err := s.storage().List(ctx, storage.ListFilter{
 		Limit:  limit,
 		Offset: offset,
 	})
@@ -262,15 +560,99 @@ func (s *ClipboardService) GetClips(ctx context.Context, limit, offset int) ([]*
 	return clips, nil
 }
 
+// ListScreenshots returns a paginated list of screenshot clips, most recently
+// used first
+func (s *ClipboardService) ListScreenshots(ctx context.Context, limit, offset int) ([]*types.Clip, error) {
+	clips, err := s.storage().List(ctx, storage.ListFilter{
+		Type:   types.TypeScreenshot,
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		return nil, &ClipboardError{
+			Op:      "ListScreenshots",
+			Index:   -1,
+			Message: "failed to list screenshots",
+			Err:     err,
+		}
+	}
+	return clips, nil
+}
+
+// Thumbnail returns a PNG thumbnail of an image clip, or of the poster frame
+// of a video, at most size pixels on its longest side
+func (s *ClipboardService) Thumbnail(ctx context.Context, id string, size int) ([]byte, error) {
+	clip, err := s.GetClipByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	image := clip.Content
+	if clip.Metadata.Media != nil && len(clip.Metadata.Media.Poster) > 0 {
+		image = clip.Metadata.Media.Poster // Videos show their poster frame
+	} else if clip.Type != types.TypeScreenshot && clip.Type != types.TypePNG {
+		return nil, &ClipboardError{
+			Op:      "Thumbnail",
+			Index:   -1,
+			Message: fmt.Sprintf("clip %s is %s, thumbnails need a PNG image or a video", id, clip.Type),
+			Err:     storage.ErrInvalidType,
+		}
+	}
+
+	thumbnail, err := clipboard.Thumbnail(image, size)
+	if err != nil {
+		return nil, &ClipboardError{
+			Op:      "Thumbnail",
+			Index:   -1,
+			Message: "failed to make thumbnail",
+			Err:     fmt.Errorf("%w: %v", storage.ErrInvalidType, err),
+		}
+	}
+	return thumbnail, nil
+}
+
+// PreviewHTML renders a text clip as an HTML page, with syntax highlighting
+// for code
+func (s *ClipboardService) PreviewHTML(ctx context.Context, id string) (string, error) {
+	clip, err := s.GetClipByID(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	text, ok := clipboard.PlainText(clip)
+	if !ok {
+		return "", &ClipboardError{
+			Op:      "PreviewHTML",
+			Index:   -1,
+			Message: fmt.Sprintf("clip %s is %s, previews need text", id, clip.Type),
+			Err:     storage.ErrInvalidType,
+		}
+	}
+
+	// Clips captured before languages were detected are detected now
+	language := clip.Metadata.Language
+	if language == "" {
+		language = snippet.Detect(text)
+	}
+	page, err := snippet.HTML(text, language, clipboard.Title(clip, 80))
+	if err != nil {
+		return "", &ClipboardError{
+			Op:      "PreviewHTML",
+			Index:   -1,
+			Message: "failed to render preview",
+			Err:     err,
+		}
+	}
+	return page, nil
+}
+
 // GetClipByIndex returns the nth most recent clip (0 being the most recent)
 func (s *ClipboardService) GetClipByIndex(ctx context.Context, index int) (*types.Clip, error) {
 	debugLog("Getting clip at index %d", index)
-	clips, err := s.store.List(ctx, storage.ListFilter{
+	clips, err := s.storage().List(ctx, storage.ListFilter{
 		Limit:  index + 1,
 		Offset: 0,
 	})
 	if err != nil {
-		log.Printf("[ERROR] Error getting clips: %v", err)
+		trace.Logf(ctx, "[ERROR] Error getting clips: %v", err)
 		return nil, &ClipboardError{
 			Op:      "GetClipByIndex",
 			Index:   index,
@@ -286,19 +668,79 @@ func (s *ClipboardService) GetClipByIndex(ctx context.Context, index int) (*type
 			Op:      "GetClipByIndex",
 			Index:   index,
 			Message: "clip not found",
-			Err:     nil,
+			Err:     storage.ErrNotFound,
 		}
 	}
 
 	clip := clips[index]
+	if clip.Truncated {
+		// Lists only hold the head of large text
+		return s.GetClipByID(ctx, clip.ID)
+	}
 	debugLog("Retrieved clip - Type: %s, Content Length: %d", clip.Type, len(clip.Content))
 	return clip, nil
 }
 
+// GetClipByID returns the clip with the given ID
+func (s *ClipboardService) GetClipByID(ctx context.Context, id string) (*types.Clip, error) {
+	debugLog("Getting clip with ID %s", id)
+	clip, err := s.storage().Get(ctx, id)
+	if err != nil {
+		return nil, &ClipboardError{
+			Op:      "GetClipByID",
+			Index:   -1,
+			Message: fmt.Sprintf("clip %s not found", id),
+			Err:     err,
+		}
+	}
+	return clip, nil
+}
+
+// GetClipContent returns a reader for the content of a clip, which avoids
+// loading large external content into memory. The caller must close it.
+func (s *ClipboardService) GetClipContent(ctx context.Context, id string) (io.ReadCloser, *types.Clip, error) {
+	reader, clip, err := s.storage().GetStream(ctx, id)
+	if err != nil {
+		return nil, nil, &ClipboardError{
+			Op:      "GetClipContent",
+			Index:   -1,
+			Message: fmt.Sprintf("clip %s not found", id),
+			Err:     err,
+		}
+	}
+	return reader, clip, nil
+}
+
+// AddClip stores content read from r as a new clip, e.g. for uploads
+func (s *ClipboardService) AddClip(ctx context.Context, r io.Reader, clipType string, metadata types.Metadata) (*types.Clip, error) {
+	r, err := s.limitSize(r, clipType)
+	if err != nil {
+		return nil, &ClipboardError{
+			Op:      "AddClip",
+			Index:   -1,
+			Message: "clip is over the size limit",
+			Err:     err,
+		}
+	}
+
+	span := trace.Start(ctx, "store")
+	clip, err := s.storage().StoreStream(ctx, r, clipType, metadata)
+	span.End()
+	if err != nil {
+		return nil, &ClipboardError{
+			Op:      "AddClip",
+			Index:   -1,
+			Message: "failed to store clip",
+			Err:     err,
+		}
+	}
+	return clip, nil
+}
+
 // SetClipboard sets the system clipboard to the content of the specified clip
 func (s *ClipboardService) SetClipboard(ctx context.Context, clip *types.Clip) error {
 	if clip == nil {
-		log.Printf("[ERROR] clip is nil")
+		trace.Logf(ctx, "[ERROR] clip is nil")
 		return &ClipboardError{
 			Op:      "SetClipboard",
 			Index:   -1,
@@ -307,9 +749,20 @@ func (s *ClipboardService) SetClipboard(ctx context.Context, clip *types.Clip) e
 		}
 	}
 
+	clip, err := s.checkFile(ctx, clip)
+	if err != nil {
+		trace.Logf(ctx, "[ERROR] Error checking copied file: %v", err)
+		return &ClipboardError{
+			Op:      "SetClipboard",
+			Index:   -1,
+			Message: "file changed since it was copied",
+			Err:     err,
+		}
+	}
+
 	debugLog("Setting clipboard - Type: %s, Content Length: %d", clip.Type, len(clip.Content))
 	if err := s.monitor.SetContent(*clip); err != nil {
-		log.Printf("[ERROR] Error setting clipboard content: %v", err)
+		trace.Logf(ctx, "[ERROR] Error setting clipboard content: %v", err)
 		return &ClipboardError{
 			Op:      "SetClipboard",
 			Index:   -1,
@@ -323,10 +776,11 @@ func (s *ClipboardService) SetClipboard(ctx context.Context, clip *types.Clip) e
 
 // PasteByIndex sets the clipboard to the nth most recent clip
 func (s *ClipboardService) PasteByIndex(ctx context.Context, index int) error {
+	defer trace.Start(ctx, "paste").End()
 	debugLog("Paste request for index %d", index)
 	clip, err := s.GetClipByIndex(ctx, index)
 	if err != nil {
-		log.Printf("[ERROR] Error getting clip at index %d: %v", index, err)
+		trace.Logf(ctx, "[ERROR] Error getting clip at index %d: %v", index, err)
 		return &ClipboardError{
 			Op:      "PasteByIndex",
 			Index:   index,
@@ -337,7 +791,7 @@ func (s *ClipboardService) PasteByIndex(ctx context.Context, index int) error {
 
 	debugLog("Found clip at index %d - Type: %s, Content Length: %d", index, clip.Type, len(clip.Content))
 	if err := s.SetClipboard(ctx, clip); err != nil {
-		log.Printf("[ERROR] Error setting clipboard: %v", err)
+		trace.Logf(ctx, "[ERROR] Error setting clipboard: %v", err)
 		return &ClipboardError{
 			Op:      "PasteByIndex",
 			Index:   index,
@@ -346,12 +800,40 @@ func (s *ClipboardService) PasteByIndex(ctx context.Context, index int) error {
 		}
 	}
 	debugLog("Successfully pasted clip at index %d", index)
+	s.notifyPasted(clip)
+	return nil
+}
+
+// PasteByID sets the clipboard to the clip with the given ID
+func (s *ClipboardService) PasteByID(ctx context.Context, id string) error {
+	defer trace.Start(ctx, "paste").End()
+	debugLog("Paste request for ID %s", id)
+	clip, err := s.GetClipByID(ctx, id)
+	if err != nil {
+		return &ClipboardError{
+			Op:      "PasteByID",
+			Index:   -1,
+			Message: "failed to retrieve clip",
+			Err:     err,
+		}
+	}
+
+	if err := s.SetClipboard(ctx, clip); err != nil {
+		return &ClipboardError{
+			Op:      "PasteByID",
+			Index:   -1,
+			Message: "failed to set clipboard content",
+			Err:     err,
+		}
+	}
+	debugLog("Successfully pasted clip %s", id)
+	s.notifyPasted(clip)
 	return nil
 }
 
 // DeleteClip deletes a clip by its ID
 func (s *ClipboardService) DeleteClip(ctx context.Context, id string) error {
-	if err := s.store.Delete(ctx, id); err != nil {
+	if err := s.storage().Delete(ctx, id); err != nil {
 		return &ClipboardError{
 			Op:      "DeleteClip",
 			Message: "failed to delete clip",
@@ -363,6 +845,17 @@ func (s *ClipboardService) DeleteClip(ctx context.Context, id string) error {
 
 // ClearClips deletes all stored clips
 func (s *ClipboardService) ClearClips(ctx context.Context) error {
+	if bulk, ok := s.storage().(storage.BulkService); ok {
+		if _, err := bulk.DeleteWhere(ctx, storage.Query{}); err != nil {
+			return &ClipboardError{
+				Op:      "ClearClips",
+				Message: "failed to delete clips",
+				Err:     err,
+			}
+		}
+		return nil
+	}
+
 	clips, err := s.GetClips(ctx, 1000, 0) // Get all clips
 	if err != nil {
 		return &ClipboardError{
@@ -373,7 +866,7 @@ func (s *ClipboardService) ClearClips(ctx context.Context) error {
 	}
 	
 	for _, clip := range clips {
-		if err := s.store.Delete(ctx, clip.ID); err != nil {
+		if err := s.storage().Delete(ctx, clip.ID); err != nil {
 			return &ClipboardError{
 				Op:      "ClearClips",
 				Message: fmt.Sprintf("failed to delete clip %s", clip.ID),
@@ -384,31 +877,703 @@ func (s *ClipboardService) ClearClips(ctx context.Context) error {
 	return nil
 }
 
+// bulkService returns the storage as a BulkService, or an error for op if it
+// does not support bulk changes
+func (s *ClipboardService) bulkService(op string) (storage.BulkService, error) {
+	bulk, ok := s.storage().(storage.BulkService)
+	if !ok {
+		return nil, &ClipboardError{
+			Op:      op,
+			Index:   -1,
+			Message: "storage does not implement bulk operations",
+		}
+	}
+	return bulk, nil
+}
+
+// DeleteClips moves the clips in ids to the trash and returns how many were
+// deleted
+func (s *ClipboardService) DeleteClips(ctx context.Context, ids []string) (int64, error) {
+	bulk, err := s.bulkService("DeleteClips")
+	if err != nil {
+		return 0, err
+	}
+
+	deleted, err := bulk.DeleteMany(ctx, ids)
+	if err != nil {
+		return 0, &ClipboardError{
+			Op:      "DeleteClips",
+			Index:   -1,
+			Message: "failed to delete clips",
+			Err:     err,
+		}
+	}
+	return deleted, nil
+}
+
+// DeleteClipsMatching moves the clips matching a search query to the trash
+// and returns how many were deleted. The query must not be empty; use
+// ClearClips to delete everything.
+func (s *ClipboardService) DeleteClipsMatching(ctx context.Context, query string) (int64, error) {
+	bulk, err := s.bulkService("DeleteClipsMatching")
+	if err != nil {
+		return 0, err
+	}
+
+	parsed, err := storage.ParseQuery(query)
+	if err != nil {
+		return 0, &ClipboardError{
+			Op:      "DeleteClipsMatching",
+			Index:   -1,
+			Message: "invalid query",
+			Err:     err,
+		}
+	}
+	if parsed.Empty() {
+		return 0, &ClipboardError{
+			Op:      "DeleteClipsMatching",
+			Index:   -1,
+			Message: "query is empty",
+		}
+	}
+
+	deleted, err := bulk.DeleteWhere(ctx, parsed)
+	if err != nil {
+		return 0, &ClipboardError{
+			Op:      "DeleteClipsMatching",
+			Index:   -1,
+			Message: "failed to delete clips",
+			Err:     err,
+		}
+	}
+	return deleted, nil
+}
+
+// SetClipsTags replaces the tags of the clips in ids and returns how many
+// were updated
+func (s *ClipboardService) SetClipsTags(ctx context.Context, ids []string, tags []string) (int64, error) {
+	bulk, err := s.bulkService("SetClipsTags")
+	if err != nil {
+		return 0, err
+	}
+
+	updated, err := bulk.UpdateTagsMany(ctx, ids, tags)
+	if err != nil {
+		return 0, &ClipboardError{
+			Op:      "SetClipsTags",
+			Index:   -1,
+			Message: "failed to update tags",
+			Err:     err,
+		}
+	}
+	return updated, nil
+}
+
+// ListTrash returns deleted clips that can still be restored
+func (s *ClipboardService) ListTrash(ctx context.Context, limit, offset int) ([]*types.Clip, error) {
+	trash, ok := s.storage().(storage.TrashService)
+	if !ok {
+		return nil, &ClipboardError{
+			Op:      "ListTrash",
+			Index:   -1,
+			Message: "storage does not implement trash",
+		}
+	}
+
+	clips, err := trash.ListTrash(ctx, limit, offset)
+	if err != nil {
+		return nil, &ClipboardError{
+			Op:      "ListTrash",
+			Index:   -1,
+			Message: "failed to list trash",
+			Err:     err,
+		}
+	}
+	return clips, nil
+}
+
+// RestoreClip moves a deleted clip out of the trash
+func (s *ClipboardService) RestoreClip(ctx context.Context, id string) (*types.Clip, error) {
+	trash, ok := s.storage().(storage.TrashService)
+	if !ok {
+		return nil, &ClipboardError{
+			Op:      "RestoreClip",
+			Index:   -1,
+			Message: "storage does not implement trash",
+		}
+	}
+
+	clip, err := trash.Restore(ctx, id)
+	if err != nil {
+		return nil, &ClipboardError{
+			Op:      "RestoreClip",
+			Index:   -1,
+			Message: fmt.Sprintf("failed to restore clip %s", id),
+			Err:     err,
+		}
+	}
+	return clip, nil
+}
+
+// EmptyTrash permanently removes every clip in the trash
+func (s *ClipboardService) EmptyTrash(ctx context.Context) (int64, error) {
+	trash, ok := s.storage().(storage.TrashService)
+	if !ok {
+		return 0, &ClipboardError{
+			Op:      "EmptyTrash",
+			Index:   -1,
+			Message: "storage does not implement trash",
+		}
+	}
+
+	purged, err := trash.PurgeTrash(ctx, time.Now())
+	if err != nil {
+		return purged, &ClipboardError{
+			Op:      "EmptyTrash",
+			Index:   -1,
+			Message: "failed to empty trash",
+			Err:     err,
+		}
+	}
+	return purged, nil
+}
+
+// SetClipExpiry sets when a clip is deleted automatically, or clears it with a nil time
+func (s *ClipboardService) SetClipExpiry(ctx context.Context, id string, expiresAt *time.Time) (*types.Clip, error) {
+	expiry, ok := s.storage().(storage.ExpiryService)
+	if !ok {
+		return nil, &ClipboardError{
+			Op:      "SetClipExpiry",
+			Index:   -1,
+			Message: "storage does not implement clip expiry",
+		}
+	}
+
+	clip, err := expiry.SetExpiry(ctx, id, expiresAt)
+	if err != nil {
+		return nil, &ClipboardError{
+			Op:      "SetClipExpiry",
+			Index:   -1,
+			Message: fmt.Sprintf("failed to set expiry for clip %s", id),
+			Err:     err,
+		}
+	}
+	return clip, nil
+}
+
+// usage reads the storage size for metrics, reporting zero if it can't be read
+func (s *ClipboardService) usage() storage.Usage {
+	reporter, ok := s.storage().(storage.UsageReporter)
+	if !ok {
+		return storage.Usage{}
+	}
+	usage, err := reporter.Usage(s.ctx)
+	if err != nil {
+		debugLog("Failed to read storage usage: %v", err)
+	}
+	return usage
+}
+
+// pruneLoop enforces retention: expired clips are deleted as soon as the
+// next check runs, clips that have been in the trash longer than the
+// retention period are purged once an hour, and clips over the storage
+// quota are evicted at each check
+func (s *ClipboardService) pruneLoop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+
+	var lastTrashPurge time.Time
+	for {
+		now := time.Now()
+		// Looked up each time, since switching profiles replaces the storage
+		store := s.storage()
+		expiry, canExpire := store.(storage.ExpiryService)
+		trash, hasTrash := store.(storage.TrashService)
+
+		if canExpire {
+			deleted, err := expiry.DeleteExpired(s.ctx, now)
+			if err != nil {
+				log.Printf("[ERROR] Failed to delete expired clips: %v", err)
+			} else if deleted > 0 {
+				debugLog("Deleted %d expired clips", deleted)
+			}
+		}
+
+		s.mu.RLock()
+		retention := s.trashRetention
+		s.mu.RUnlock()
+		if hasTrash && retention > 0 && now.Sub(lastTrashPurge) >= time.Hour {
+			purged, err := trash.PurgeTrash(s.ctx, now.Add(-retention))
+			if err != nil {
+				log.Printf("[ERROR] Failed to purge trash: %v", err)
+			} else if purged > 0 {
+				debugLog("Purged %d clips from trash", purged)
+			}
+			lastTrashPurge = now
+		}
+
+		s.mu.RLock()
+		quota := s.quota
+		s.mu.RUnlock()
+		if evictor, ok := store.(storage.Evictor); ok && quota > 0 {
+			s.enforceQuota(evictor, quota)
+		}
+
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// enforceQuota evicts clips until their content fits in quota bytes and
+// tells the handlers that want to know which clips went
+func (s *ClipboardService) enforceQuota(evictor storage.Evictor, quota int64) {
+	eviction, err := evictor.Evict(s.ctx, quota)
+	if eviction != nil && len(eviction.IDs) > 0 {
+		metrics.ClipsEvicted.Add(float64(len(eviction.IDs)))
+		log.Printf("Evicted %d clips, freeing %d bytes, to stay under the storage quota", len(eviction.IDs), eviction.FreedBytes)
+
+		s.mu.RLock()
+		handlers := s.handlers
+		s.mu.RUnlock()
+		for _, handler := range handlers {
+			if h, ok := handler.(EvictionHandler); ok {
+				h.HandleEviction(*eviction)
+			}
+		}
+	}
+	if err != nil {
+		log.Printf("[ERROR] Failed to evict clips over the storage quota: %v", err)
+	} else if eviction.UsedBytes > quota {
+		debugLog("Pinned clips keep the storage %d bytes over its quota", eviction.UsedBytes-quota)
+	}
+}
+
 // Search searches for clips matching the given criteria
 func (s *ClipboardService) Search(ctx context.Context, opts storage.SearchOptions) ([]storage.SearchResult, error) {
-	if searchService, ok := s.store.(storage.SearchService); ok {
-		return searchService.Search(opts)
+	results, _, err := s.SearchPage(ctx, opts)
+	return results, err
+}
+
+// SearchPage is Search that also returns the cursor of the next page, or ""
+// on the last page. The cursor is taken before GroupSimilar folds results,
+// so grouped pages may be short without being the last.
+func (s *ClipboardService) SearchPage(ctx context.Context, opts storage.SearchOptions) ([]storage.SearchResult, string, error) {
+	searchService, ok := s.storage().(storage.SearchService)
+	if !ok {
+		return nil, "", &ClipboardError{
+			Op:      "Search",
+			Message: "storage does not implement search",
+		}
+	}
+
+	span := trace.Start(ctx, "search")
+	defer func() { metrics.SearchDuration.Observe(span.End().Seconds()) }()
+	results, err := searchService.Search(ctx, opts)
+	if err != nil {
+		return nil, "", err
+	}
+	next := storage.NextCursor(results, opts.Limit)
+	if opts.GroupSimilar {
+		results = storage.GroupSimilar(results)
+	}
+	return results, next, nil
+}
+
+// GetClipsAfter returns up to limit clips used before the cursor position,
+// most recent first, and the cursor of the next page. An empty cursor starts
+// at the most recent clip.
+func (s *ClipboardService) GetClipsAfter(ctx context.Context, cursor string, limit int) ([]*types.Clip, string, error) {
+	results, next, err := s.SearchPage(ctx, storage.SearchOptions{Limit: limit, Cursor: cursor})
+	if err != nil {
+		return nil, "", &ClipboardError{
+			Op:      "GetClipsAfter",
+			Index:   -1,
+			Message: "failed to list clips",
+			Err:     err,
+		}
+	}
+	clips := make([]*types.Clip, len(results))
+	for i, result := range results {
+		clips[i] = result.Clip
+	}
+	return clips, next, nil
+}
+
+// MergeClips collapses near-duplicate clips into keepID, adding their use
+// counts to it and moving them to the trash
+func (s *ClipboardService) MergeClips(ctx context.Context, keepID string, ids []string) (*types.Clip, error) {
+	merger, ok := s.storage().(storage.Merger)
+	if !ok {
+		return nil, &ClipboardError{
+			Op:      "MergeClips",
+			Index:   -1,
+			Message: "storage does not implement merging",
+		}
+	}
+
+	clip, err := merger.Merge(ctx, keepID, ids)
+	if err != nil {
+		return nil, &ClipboardError{
+			Op:      "MergeClips",
+			Index:   -1,
+			Message: fmt.Sprintf("failed to merge clips into %s", keepID),
+			Err:     err,
+		}
+	}
+	return clip, nil
+}
+
+// versionService returns the storage as a VersionService, or an error for op
+// if it does not support editing
+func (s *ClipboardService) versionService(op string) (storage.VersionService, error) {
+	versions, ok := s.storage().(storage.VersionService)
+	if !ok {
+		return nil, &ClipboardError{
+			Op:      op,
+			Index:   -1,
+			Message: "storage does not implement clip versions",
+		}
+	}
+	return versions, nil
+}
+
+// EditClip replaces the content of a text clip, keeping the old content as a
+// version. HTML is sanitized and its plain text shadow copy rebuilt; other
+// alternate formats no longer match the content and are dropped.
+func (s *ClipboardService) EditClip(ctx context.Context, id string, content []byte) (*types.Clip, error) {
+	versions, err := s.versionService("EditClip")
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := s.storage().Get(ctx, id)
+	if err != nil {
+		return nil, &ClipboardError{
+			Op:      "EditClip",
+			Index:   -1,
+			Message: fmt.Sprintf("failed to get clip %s", id),
+			Err:     err,
+		}
+	}
+
+	var formats map[string][]byte
+	switch {
+	case current.Type == types.TypeHTML:
+		content = []byte(clipboard.SanitizeHTML(string(content)))
+		formats = map[string][]byte{storage.FormatPlainText: []byte(clipboard.HTMLToText(string(content)))}
+	case types.IsText(current.Type) && current.Type != types.TypeRTFD:
+	default:
+		return nil, &ClipboardError{
+			Op:      "EditClip",
+			Index:   -1,
+			Message: fmt.Sprintf("clip %s is %s, only text clips can be edited", id, current.Type),
+			Err:     storage.ErrNotEditable,
+		}
+	}
+
+	clip, err := versions.Edit(ctx, id, content, formats)
+	if err != nil {
+		return nil, &ClipboardError{
+			Op:      "EditClip",
+			Index:   -1,
+			Message: fmt.Sprintf("failed to edit clip %s", id),
+			Err:     err,
+		}
+	}
+	return clip, nil
+}
+
+// ListClipVersions returns the earlier contents of an edited clip, oldest first
+func (s *ClipboardService) ListClipVersions(ctx context.Context, id string) ([]storage.ClipVersion, error) {
+	versions, err := s.versionService("ListClipVersions")
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := versions.ListVersions(ctx, id)
+	if err != nil {
+		return nil, &ClipboardError{
+			Op:      "ListClipVersions",
+			Index:   -1,
+			Message: fmt.Sprintf("failed to list versions of clip %s", id),
+			Err:     err,
+		}
+	}
+	return list, nil
+}
+
+// RevertClip restores an earlier version of a clip. The content it replaces
+// becomes a new version.
+func (s *ClipboardService) RevertClip(ctx context.Context, id string, version int) (*types.Clip, error) {
+	versions, err := s.versionService("RevertClip")
+	if err != nil {
+		return nil, err
+	}
+
+	clip, err := versions.Revert(ctx, id, version)
+	if err != nil {
+		return nil, &ClipboardError{
+			Op:      "RevertClip",
+			Index:   -1,
+			Message: fmt.Sprintf("failed to revert clip %s to version %d", id, version),
+			Err:     err,
+		}
+	}
+	return clip, nil
+}
+
+// Stats summarizes the clipboard history by type, source app and time, with
+// the space it takes up when the storage reports it
+func (s *ClipboardService) Stats(ctx context.Context) (*storage.Stats, error) {
+	statsService, ok := s.storage().(storage.StatsService)
+	if !ok {
+		return nil, &ClipboardError{
+			Op:      "Stats",
+			Index:   -1,
+			Message: "storage does not implement statistics",
+		}
+	}
+
+	stats, err := statsService.Stats(ctx)
+	if err != nil {
+		return nil, &ClipboardError{
+			Op:      "Stats",
+			Index:   -1,
+			Message: "failed to compute statistics",
+			Err:     err,
+		}
+	}
+	if _, ok := s.storage().(storage.UsageReporter); ok {
+		stats.Usage = s.usage()
+	}
+	if maintainer, ok := s.storage().(storage.Maintainer); ok {
+		stats.Maintenance = s.maintenanceStatus(ctx, maintainer)
+	}
+	return stats, nil
+}
+
+// Analytics reports the clips copied between from and to, by hour, app and
+// type, for exporting to personal dashboards. Zero times default to the
+// last analytics.DefaultDays days.
+func (s *ClipboardService) Analytics(ctx context.Context, from, to time.Time) (*analytics.Report, error) {
+	from, to, err := analytics.Range(from, to, time.Now())
+	if err != nil {
+		return nil, &ClipboardError{
+			Op:      "Analytics",
+			Index:   -1,
+			Message: err.Error(),
+			Err:     err,
+		}
+	}
+	report, err := analytics.Build(ctx, s.Search, from, to)
+	if err != nil {
+		return nil, &ClipboardError{
+			Op:      "Analytics",
+			Index:   -1,
+			Message: "failed to summarize clipboard activity",
+			Err:     err,
+		}
+	}
+	return report, nil
+}
+
+// Timeline counts the clips copied in each day or hour of the range, for
+// browsing the history by time
+func (s *ClipboardService) Timeline(ctx context.Context, opts storage.TimelineOptions) ([]storage.TimelineBucket, error) {
+	timelineService, ok := s.storage().(storage.TimelineService)
+	if !ok {
+		return nil, &ClipboardError{
+			Op:      "Timeline",
+			Index:   -1,
+			Message: "storage does not implement the timeline",
+		}
+	}
+
+	buckets, err := timelineService.Timeline(ctx, opts)
+	if err != nil {
+		return nil, &ClipboardError{
+			Op:      "Timeline",
+			Index:   -1,
+			Message: "failed to count clips by time",
+			Err:     err,
+		}
+	}
+	return buckets, nil
+}
+
+// ListApps returns the source applications seen in the clipboard history
+func (s *ClipboardService) ListApps(ctx context.Context) ([]storage.AppInfo, error) {
+	if appService, ok := s.storage().(storage.AppService); ok {
+		return appService.ListApps(ctx)
+	}
+	return nil, &ClipboardError{
+		Op:      "ListApps",
+		Index:   -1,
+		Message: "storage does not implement app listing",
+	}
+}
+
+// GetAppIcon returns the cached PNG icon for a source application
+func (s *ClipboardService) GetAppIcon(ctx context.Context, bundleID string) ([]byte, error) {
+	if appService, ok := s.storage().(storage.AppService); ok {
+		return appService.GetAppIcon(ctx, bundleID)
 	}
 	return nil, &ClipboardError{
-		Op:      "Search",
-		Message: "storage does not implement search",
+		Op:      "GetAppIcon",
+		Index:   -1,
+		Message: "storage does not implement app listing",
+	}
+}
+
+// classifyClip counts a captured clip by type, detects the language of code,
+// runs the rules and drops empty, ignored and skipped clips
+func (s *ClipboardService) classifyClip(job *captureJob) bool {
+	if len(job.clip.Content) == 0 {
+		return false
+	}
+	if s.ignored(job.clip) {
+		debugLog("Clip from %q matches the ignore rules, ignoring clipboard change", job.clip.Metadata.SourceApp)
+		s.notify(notify.EventIgnored, "Clip not saved", fmt.Sprintf("Copied from %s, which matches the ignore rules", sourceName(job.clip)))
+		return false
+	}
+	if !s.applyRules(job) {
+		return false
+	}
+	metrics.ClipsCaptured.WithLabelValues(job.clip.Type).Inc()
+
+	if job.clip.Metadata.Language == "" && strings.HasPrefix(job.clip.Type, "text/") {
+		if text, ok := clipboard.PlainText(&job.clip); ok {
+			job.clip.Metadata.Language = snippet.Detect(text)
+		}
+	}
+	return true
+}
+
+// enrichClip describes copied media files, snapshots copied files, extracts
+// the text of documents and runs the registered processors, then the user's
+// scripts and the processors of plugins. A processor or script that fails
+// is logged and the clip goes on without its changes.
+func (s *ClipboardService) enrichClip(job *captureJob) bool {
+	s.describeMedia(&job.clip)
+	s.snapshotFile(&job.clip)
+	s.extractText(&job.clip)
+
+	s.mu.RLock()
+	processors := s.processors
+	s.mu.RUnlock()
+
+	for _, processor := range processors {
+		clip := job.clip
+		if err := processor.ProcessClip(s.ctx, &clip); err != nil {
+			log.Printf("[ERROR] Error processing clip: %v", err)
+			continue
+		}
+		job.clip = clip
+	}
+	return s.runScripts(&job.clip) && s.processWithPlugins(&job.clip)
+}
+
+// storeClip saves a clip, dropping it if it is too large or fails to store
+func (s *ClipboardService) storeClip(job *captureJob) bool {
+	stored, err := s.handleClipboardChange(job.clip)
+	if err != nil {
+		log.Printf("[ERROR] Error handling clipboard change: %v", err)
+		return false
+	}
+	if stored == nil {
+		return false
 	}
+	s.copyFile(stored)
+	s.unfurl(stored)
+	s.translateCaptured(stored, job.translate)
+	return true
 }
 
-// handleClipboardChange processes and stores clipboard content
-func (s *ClipboardService) handleClipboardChange(clip types.Clip) error {
-	// Skip empty content
-	if len(clip.Content) == 0 {
+// normalizedDuplicate returns the clip that text clip matches once both are
+// normalized, if deduplicating normalized text is on and the backend keeps
+// normalized hashes. Storing its content instead makes the backend bump that
+// clip rather than add another.
+func (s *ClipboardService) normalizedDuplicate(clip types.Clip) *types.Clip {
+	s.mu.RLock()
+	enabled := s.dedupNormalized
+	s.mu.RUnlock()
+	if !enabled {
 		return nil
 	}
+	finder, ok := s.storage().(storage.NormalizedFinder)
+	if !ok {
+		return nil
+	}
+	hash := storage.NormalizedHash(clip.Content, clip.Type)
+	if hash == "" {
+		return nil
+	}
+	dup, err := finder.FindNormalized(s.ctx, hash)
+	if err != nil {
+		if !errors.Is(err, storage.ErrNotFound) {
+			log.Printf("[WARN] Failed to look up normalized duplicates: %v", err)
+		}
+		return nil
+	}
+	if dup.Type != clip.Type || bytes.Equal(dup.Content, clip.Content) {
+		return nil
+	}
+	debugLog("Copied text matches clip %s once normalized", dup.ID)
+	return dup
+}
+
+// notifyClip tells the registered handlers about a stored clip
+func (s *ClipboardService) notifyClip(job *captureJob) bool {
+	s.mu.RLock()
+	handlers := s.handlers // Copy to avoid holding lock during callbacks
+	s.mu.RUnlock()
 
-	// Store the clip
-	_, err := s.store.Store(s.ctx, clip.Content, clip.Type, clip.Metadata)
+	// One handler panicking doesn't keep the clip from the others
+	for _, handler := range handlers {
+		s.callHandler(handler, job.clip)
+	}
+	return true
+}
+
+// callHandler tells handler about clip, recovering if it panics
+func (s *ClipboardService) callHandler(handler ClipboardChangeHandler, clip types.Clip) {
+	defer s.recoverCapture(fmt.Sprintf("handler %T", handler))
+	handler.HandleClipboardChange(clip)
+}
+
+// handleClipboardChange stores clipboard content. It returns nil without an
+// error if the content is over the size limit of its type or too large to
+// keep.
+func (s *ClipboardService) handleClipboardChange(clip types.Clip) (*types.Clip, error) {
+	if err := s.checkSize(clip); err != nil {
+		s.notifyTooLarge(clip, err)
+		return nil, nil
+	}
+
+	if clip.Metadata.Session == "" {
+		clip.Metadata.Session = s.copySession(clip)
+	}
+
+	// Store the clip, as the clip it duplicates if there is one
+	start := time.Now()
+	span := trace.Start(s.ctx, "store")
+	content := clip.Content
+	if dup := s.normalizedDuplicate(clip); dup != nil {
+		content = dup.Content
+	}
+	stored, err := s.storage().Store(s.ctx, content, clip.Type, clip.Metadata)
+	metrics.StoreDuration.Observe(span.End().Seconds())
+	s.lastActivity.Store(time.Now().UnixNano())
 	if err == storage.ErrFileTooLarge {
-		debugLog("Content too large to store (size: %d bytes)", len(clip.Content))
-		return nil
+		s.notifyTooLarge(clip, &storage.SizeLimitError{Type: clip.Type, Size: int64(len(clip.Content)), Limit: storage.MaxStorageSize})
+		return nil, nil
 	} else if err != nil {
-		return &ClipboardError{
+		return nil, &ClipboardError{
 			Op:      "handleClipboardChange",
 			Index:   -1,
 			Message: "failed to store clip",
@@ -416,8 +1581,20 @@ func (s *ClipboardService) handleClipboardChange(clip types.Clip) error {
 		}
 	}
 
+	// A clip created before we stored it means the content was already there
+	if stored.CreatedAt.Before(start) {
+		metrics.Dedup.WithLabelValues("hit").Inc()
+	} else {
+		metrics.Dedup.WithLabelValues("miss").Inc()
+	}
+
+	if len(clip.Content) > storage.MaxInlineStorageSize && !stored.CreatedAt.Before(start) {
+		s.notify(notify.EventLargeFile, "Large clip saved",
+			fmt.Sprintf("%s from %s was saved as a file", formatSize(len(clip.Content)), sourceName(clip)))
+	}
+
 	debugLog("Stored new clipboard content (type: %s, source: %s)", 
 		clip.Type, clip.Metadata.SourceApp)
 
-	return nil
+	return stored, nil
 }
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"bytes"
+	"clipboard-manager/internal/clipboard"
+	"clipboard-manager/internal/storage"
+	"clipboard-manager/pkg/types"
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Orders for ConcatClips
+const (
+	OrderSelection = "selection" // The order the IDs were given in
+	OrderTime      = "time"      // Oldest clip first
+)
+
+// ConcatClips joins the text of several clips, separated by separator, into
+// a new clip and returns it. The sources are left as they are. If any of
+// them is sensitive the new clip expires with the first of them.
+func (s *ClipboardService) ConcatClips(ctx context.Context, ids []string, separator, order string) (*types.Clip, error) {
+	if len(ids) < 2 {
+		return nil, &ClipboardError{
+			Op:      "ConcatClips",
+			Index:   -1,
+			Message: "at least two clips are needed to concatenate",
+		}
+	}
+
+	clips := make([]*types.Clip, 0, len(ids))
+	for _, id := range ids {
+		clip, err := s.GetClipByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		clips = append(clips, clip)
+	}
+	if order == OrderTime {
+		sort.SliceStable(clips, func(i, j int) bool {
+			return clips[i].CreatedAt.Before(clips[j].CreatedAt)
+		})
+	}
+
+	var joined bytes.Buffer
+	var expiresAt *time.Time
+	for i, clip := range clips {
+		text, ok := clipboard.PlainText(clip)
+		if !ok {
+			return nil, &ClipboardError{
+				Op:      "ConcatClips",
+				Index:   -1,
+				Message: fmt.Sprintf("clip %s is %s, only text can be concatenated", clip.ID, clip.Type),
+				Err:     storage.ErrInvalidType,
+			}
+		}
+		if i > 0 {
+			joined.WriteString(separator)
+		}
+		joined.WriteString(text)
+		if at := clip.Metadata.ExpiresAt; at != nil && (expiresAt == nil || at.Before(*expiresAt)) {
+			expiresAt = at
+		}
+	}
+
+	return s.AddClip(ctx, &joined, types.TypeText, types.Metadata{ExpiresAt: expiresAt})
+}
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"clipboard-manager/internal/clipboard"
+	"clipboard-manager/internal/config"
+	"clipboard-manager/internal/obsidian"
+	"clipboard-manager/internal/rules"
+	"clipboard-manager/pkg/types"
+	"log"
+	"strings"
+	"time"
+)
+
+// ApplyConfig applies the settings that can change while the daemon runs:
+// polling intervals, Obsidian sync, ignore rules, capture rules, trash retention, the
+// storage quota, file copies, size limits, normalized deduplication, notifications, link unfurling,
+// publishing, chat targets, database maintenance and log level, with the
+// active profile's settings added. Subscribe it to a config.Bus to apply each reload.
+func (s *ClipboardService) ApplyConfig(c config.Config) {
+	s.mu.Lock()
+	s.settings = c
+	profile := s.profile
+	s.mu.Unlock()
+	c = c.ForProfile(profile)
+
+	debugMode.Store(c.LogLevel == config.LogDebug)
+	clipboard.SetDebug(c.LogLevel == config.LogDebug)
+
+	if poller, ok := s.monitor.(clipboard.PollIntervalSetter); ok {
+		s.mu.Lock()
+		changed := c.Poll != s.pollSettings
+		s.pollSettings = c.Poll
+		s.mu.Unlock()
+		if changed {
+			poller.SetPollIntervals(time.Duration(c.Poll.Min), time.Duration(c.Poll.Max))
+		}
+	}
+
+	patterns, err := c.Ignore.Compile()
+	if err != nil {
+		log.Printf("[ERROR] Ignoring ignore patterns: %v", err)
+	}
+	ruleSet, err := rules.Compile(c.Rules)
+	if err != nil {
+		log.Printf("[ERROR] Ignoring rules: %v", err)
+	}
+	apps := make(map[string]bool, len(c.Ignore.Apps))
+	for _, app := range c.Ignore.Apps {
+		apps[strings.ToLower(app)] = true
+	}
+
+	s.mu.Lock()
+	s.ignoreApps, s.ignorePatterns = apps, patterns
+	s.rules = ruleSet
+	s.notifySettings = c.Notifications
+	s.unfurlLinks = c.UnfurlLinks
+	s.publishSettings = c.Publish
+	s.sendSettings = c.Send
+	s.translateSettings = c.Translate
+	s.maintenance = c.Maintenance
+	s.quota = int64(c.QuotaMB) << 20
+	s.copyFiles = c.CopyFiles
+	s.dedupNormalized = c.DedupNormalized
+	s.sizeLimits = make(map[string]int64, len(c.SizeLimitsMB))
+	for clipType, limit := range c.SizeLimitsMB {
+		s.sizeLimits[clipType] = int64(limit) << 20
+	}
+	if c.TrashDays != nil {
+		s.trashRetention = time.Duration(*c.TrashDays) * 24 * time.Hour
+	}
+	s.mu.Unlock()
+
+	s.plugins.Apply(c.Plugins)
+	s.scripts.Apply(c.Scripts)
+	s.applySync(syncSettings{Obsidian: c.Obsidian, Logseq: c.Logseq, Markdown: c.Markdown, AppleNotes: c.AppleNotes})
+}
+
+// syncSettings are the settings of every place clips are synced to, which
+// share one sync service
+type syncSettings struct {
+	Obsidian   config.Obsidian
+	Logseq     config.Logseq
+	Markdown   config.Markdown
+	AppleNotes config.AppleNotes
+}
+
+// enabled reports whether clips are synced anywhere
+func (settings syncSettings) enabled() bool {
+	return settings.Obsidian.Enabled || settings.Logseq.Enabled || settings.Markdown.Enabled ||
+		settings.AppleNotes.Enabled
+}
+
+// applySync starts, stops or reconfigures syncing clips to Obsidian, Logseq,
+// Markdown folders and Apple Notes
+func (s *ClipboardService) applySync(settings syncSettings) {
+	s.mu.Lock()
+	if settings == s.syncSettings {
+		s.mu.Unlock()
+		return
+	}
+	previous := s.syncSettings
+	s.syncSettings = settings
+	current, started := s.obsidianSync, s.started
+	interval := time.Duration(settings.Obsidian.SyncInterval) * time.Minute
+
+	// Moving the vault or changing the interval leaves the service running
+	if current != nil && settings.enabled() && settings.Obsidian.Enabled == previous.Obsidian.Enabled &&
+		settings.Logseq == previous.Logseq && settings.Markdown == previous.Markdown &&
+		settings.AppleNotes == previous.AppleNotes {
+		s.mu.Unlock()
+		if settings.Obsidian.Enabled {
+			if err := current.UpdateVaultPath(settings.Obsidian.VaultPath); err != nil {
+				log.Printf("[ERROR] Failed to update vault path: %v", err)
+			}
+		}
+		current.UpdateSyncInterval(interval)
+		return
+	}
+
+	var next *obsidian.SyncService
+	if settings.enabled() {
+		syncConfig := obsidian.Config{SyncInterval: interval, OnError: s.syncFailed}
+		if settings.Obsidian.Enabled {
+			syncConfig.VaultPath = settings.Obsidian.VaultPath
+		}
+		if settings.Logseq.Enabled {
+			syncConfig.LogseqPath = settings.Logseq.GraphPath
+		}
+		if settings.Markdown.Enabled {
+			syncConfig.MarkdownPath = settings.Markdown.Path
+		}
+		if settings.AppleNotes.Enabled {
+			syncConfig.AppleNotesFolder = appleNotesFolder(settings.AppleNotes)
+		}
+		var err error
+		next, err = obsidian.New(s.storage(), syncConfig)
+		if err != nil {
+			log.Printf("[ERROR] Failed to initialize sync: %v", err)
+		}
+	}
+	s.obsidianSync = next
+	s.mu.Unlock()
+
+	// A stopped sync service can't be restarted, so each enable starts a new one
+	if current != nil && started {
+		current.Stop()
+	}
+	if next != nil && started {
+		if err := next.Start(s.ctx); err != nil {
+			log.Printf("[ERROR] Failed to start sync: %v", err)
+		}
+	}
+}
+
+// ignored reports whether a clip matches the ignore rules
+func (s *ClipboardService) ignored(clip types.Clip) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.ignoreApps[strings.ToLower(clip.Metadata.SourceApp)] || s.ignoreApps[strings.ToLower(clip.Metadata.SourceBundleID)] {
+		return true
+	}
+	if len(s.ignorePatterns) == 0 || !strings.HasPrefix(clip.Type, "text/") {
+		return false
+	}
+	text := string(clip.Content)
+	for _, pattern := range s.ignorePatterns {
+		if pattern.MatchString(text) {
+			return true
+		}
+	}
+	return false
+}
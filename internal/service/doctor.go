@@ -0,0 +1,19 @@
+package service
+
+import (
+	"clipboard-manager/internal/config"
+	"clipboard-manager/internal/doctor"
+)
+
+// Permissions checks that the daemon may simulate paste and read the
+// folders clips are synced to
+func (s *ClipboardService) Permissions() []doctor.Check {
+	s.mu.RLock()
+	sync := s.syncSettings
+	s.mu.RUnlock()
+
+	return doctor.Run(doctor.Options{
+		Paster:  s.paster,
+		Folders: doctor.SyncFolders(config.Config{Obsidian: sync.Obsidian, Logseq: sync.Logseq, Markdown: sync.Markdown}),
+	})
+}
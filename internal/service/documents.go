@@ -0,0 +1,30 @@
+package service
+
+import (
+	"clipboard-manager/internal/document"
+	"clipboard-manager/internal/storage"
+	"clipboard-manager/pkg/types"
+	"errors"
+)
+
+// extractText keeps the text of a PDF clip as its plain text format, so it
+// can be searched and previewed. PDFs stay unsearchable without pdftotext.
+func (s *ClipboardService) extractText(clip *types.Clip) {
+	if clip.Type != types.TypePDF || len(clip.Metadata.Formats[storage.FormatPlainText]) > 0 {
+		return
+	}
+	text, err := document.PDFText(s.ctx, clip.Content)
+	if err != nil {
+		if !errors.Is(err, document.ErrNotInstalled) {
+			debugLog("Failed to extract PDF text: %v", err)
+		}
+		return
+	}
+	if text == "" {
+		return // Scanned pages have no text layer
+	}
+	if clip.Metadata.Formats == nil {
+		clip.Metadata.Formats = make(map[string][]byte)
+	}
+	clip.Metadata.Formats[storage.FormatPlainText] = []byte(text)
+}
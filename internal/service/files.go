@@ -0,0 +1,161 @@
+package service
+
+import (
+	"clipboard-manager/internal/media"
+	"clipboard-manager/internal/storage"
+	"clipboard-manager/pkg/types"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+)
+
+// snapshotFile records the size, modification time and hash of a copied
+// file in its clip, so pasting the clip later can tell whether the file
+// changed. The clip itself only keeps the file's URL.
+func (s *ClipboardService) snapshotFile(clip *types.Clip) {
+	if clip.Type != storage.TypeFile || clip.Metadata.File != nil {
+		return
+	}
+	path, ok := media.FilePath(string(clip.Content))
+	if !ok {
+		return
+	}
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		return
+	}
+
+	hash, err := hashFile(path)
+	if err != nil {
+		debugLog("Failed to hash %s: %v", path, err)
+		return
+	}
+	clip.Metadata.File = &types.FileRef{
+		Path:    path,
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+		Hash:    hash,
+	}
+}
+
+// copyFile keeps a copy of the file a stored clip points to, if copying
+// files is on and the storage can keep one
+func (s *ClipboardService) copyFile(clip *types.Clip) {
+	s.mu.RLock()
+	enabled := s.copyFiles
+	s.mu.RUnlock()
+
+	copier, ok := s.storage().(storage.FileCopier)
+	ref := clip.Metadata.File
+	if !enabled || !ok || ref == nil || ref.Copied {
+		return
+	}
+
+	file, err := os.Open(ref.Path)
+	if err != nil {
+		debugLog("Failed to open %s: %v", ref.Path, err)
+		return
+	}
+	defer file.Close()
+
+	if _, err := copier.CopyFile(s.ctx, clip.ID, file); errors.Is(err, storage.ErrFileChanged) {
+		debugLog("%s changed before it could be copied", ref.Path)
+	} else if err != nil {
+		log.Printf("[ERROR] Failed to copy %s: %v", ref.Path, err)
+	}
+}
+
+// checkFile makes sure the file a file clip points to is still the one that
+// was copied. If it changed or is gone, the clip is pasted from the kept
+// copy, written to a temporary file; without a copy it fails with
+// storage.ErrFileChanged.
+func (s *ClipboardService) checkFile(ctx context.Context, clip *types.Clip) (*types.Clip, error) {
+	ref := clip.Metadata.File
+	if clip.Type != storage.TypeFile || ref == nil {
+		return clip, nil
+	}
+
+	// A file with the same size and modification time is taken as
+	// unchanged, and otherwise hashed in case only its time changed
+	if info, err := os.Stat(ref.Path); err == nil {
+		if info.Size() == ref.Size && info.ModTime().Equal(ref.ModTime) {
+			return clip, nil
+		}
+		if hash, err := hashFile(ref.Path); err == nil && hash == ref.Hash {
+			return clip, nil
+		}
+	}
+
+	copier, ok := s.storage().(storage.FileCopier)
+	if !ref.Copied || !ok {
+		return nil, storage.ErrFileChanged
+	}
+	path, err := restoreFileCopy(ctx, copier, clip.ID, ref)
+	if err != nil {
+		return nil, err
+	}
+	debugLog("%s changed since it was copied, pasting the copy at %s", ref.Path, path)
+
+	restored := *clip
+	restored.Content = []byte((&url.URL{Scheme: "file", Path: path}).String())
+	return &restored, nil
+}
+
+// restoreFileCopy writes the kept copy of a file to a temporary directory
+// named after its hash, keeping the file's name, and returns its path. A
+// copy already written is reused.
+func restoreFileCopy(ctx context.Context, copier storage.FileCopier, id string, ref *types.FileRef) (string, error) {
+	dir := filepath.Join(os.TempDir(), "clipboard-manager", ref.Hash)
+	path := filepath.Join(dir, filepath.Base(ref.Path))
+	if info, err := os.Stat(path); err == nil && info.Size() == ref.Size {
+		return path, nil
+	}
+
+	src, err := copier.OpenFileCopy(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, ".restore-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp file: %w", err)
+	}
+	_, err = io.Copy(tmp, src)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("failed to restore file copy: %w", err)
+	}
+	return path, nil
+}
+
+// hashFile returns the hex SHA-256 of the file at path
+func hashFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
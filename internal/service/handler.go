@@ -1,8 +1,31 @@
 package service
 
-import "clipboard-manager/pkg/types"
+import (
+	"clipboard-manager/internal/storage"
+	"clipboard-manager/pkg/types"
+	"context"
+)
 
 // ClipboardChangeHandler is implemented by components that need to be notified of clipboard changes
 type ClipboardChangeHandler interface {
 	HandleClipboardChange(clip types.Clip)
 }
+
+// EvictionHandler is implemented by clipboard change handlers that also
+// need to know when clips are removed to stay under the storage quota
+type EvictionHandler interface {
+	HandleEviction(eviction storage.Eviction)
+}
+
+// ClipProcessor is implemented by components that enrich a clip before it is
+// stored, such as OCR or thumbnail generation. Processors may change the
+// clip's content, type and metadata.
+type ClipProcessor interface {
+	ProcessClip(ctx context.Context, clip *types.Clip) error
+}
+
+// CaptureErrorHandler is implemented by clipboard change handlers that also
+// need to know when capturing a clip failed with a panic
+type CaptureErrorHandler interface {
+	HandleCaptureError(event CaptureError)
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"clipboard-manager/internal/clipboard"
+	"clipboard-manager/internal/metrics"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+// CaptureError describes a panic recovered while capturing a clip
+type CaptureError struct {
+	Where   string    `json:"where"` // The monitor, stage or handler that panicked
+	Message string    `json:"message"`
+	Time    time.Time `json:"time"`
+}
+
+// CaptureStatus reports how clipboard capture is doing, for /status
+type CaptureStatus struct {
+	MonitorStartedAt *time.Time    `json:"monitor_started_at,omitempty"` // Since the last restart
+	MonitorUptime    float64       `json:"monitor_uptime_seconds"`
+	MonitorRestarts  int           `json:"monitor_restarts"`
+	Panics           int           `json:"panics"`
+	LastError        *CaptureError `json:"last_error,omitempty"`
+}
+
+// captureHealth records the panics recovered on the capture path
+type captureHealth struct {
+	mu               sync.Mutex
+	monitorStartedAt time.Time
+	monitorRestarts  int
+	panics           int
+	lastError        *CaptureError
+}
+
+// CaptureStatus reports when the monitor last started and the last panic
+// recovered while capturing
+func (s *ClipboardService) CaptureStatus() CaptureStatus {
+	s.health.mu.Lock()
+	defer s.health.mu.Unlock()
+
+	status := CaptureStatus{
+		MonitorRestarts: s.health.monitorRestarts,
+		Panics:          s.health.panics,
+		LastError:       s.health.lastError,
+	}
+	if started := s.health.monitorStartedAt; !started.IsZero() {
+		status.MonitorStartedAt = &started
+		status.MonitorUptime = time.Since(started).Seconds()
+	}
+	return status
+}
+
+// monitorStarted records that the monitor started polling
+func (s *ClipboardService) monitorStarted() {
+	s.health.mu.Lock()
+	s.health.monitorStartedAt = time.Now()
+	s.health.mu.Unlock()
+}
+
+// monitorFailed records a panic the monitor recovered from by restarting
+// its polling
+func (s *ClipboardService) monitorFailed(err error) {
+	s.health.mu.Lock()
+	s.health.monitorRestarts++
+	s.health.monitorStartedAt = time.Now()
+	s.health.mu.Unlock()
+	s.captureFailed(err)
+}
+
+// recoverCapture recovers from a panic on the capture path and records it,
+// so the goroutine it happened on carries on with the next clip. It must be
+// deferred directly.
+func (s *ClipboardService) recoverCapture(where string) {
+	if err := clipboard.Recovered(where, recover()); err != nil {
+		s.captureFailed(err)
+	}
+}
+
+// captureFailed logs a recovered panic, records it for CaptureStatus and
+// tells the handlers that want to know
+func (s *ClipboardService) captureFailed(err error) {
+	event := CaptureError{Where: "capture", Message: err.Error(), Time: time.Now()}
+	var panicErr *clipboard.PanicError
+	if errors.As(err, &panicErr) {
+		event.Where = panicErr.Where
+		log.Printf("[ERROR] Recovered from %v\n%s", err, panicErr.Stack)
+	} else {
+		log.Printf("[ERROR] Capture failed: %v", err)
+	}
+	metrics.CapturePanics.WithLabelValues(event.Where).Inc()
+
+	s.health.mu.Lock()
+	s.health.panics++
+	s.health.lastError = &event
+	s.health.mu.Unlock()
+
+	s.mu.RLock()
+	handlers := s.handlers
+	s.mu.RUnlock()
+	for _, handler := range handlers {
+		if h, ok := handler.(CaptureErrorHandler); ok {
+			h.HandleCaptureError(event)
+		}
+	}
+}
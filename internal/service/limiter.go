@@ -0,0 +1,133 @@
+package service
+
+import (
+	"clipboard-manager/internal/metrics"
+	"clipboard-manager/pkg/types"
+	"sync"
+	"time"
+)
+
+// CaptureLimits protects history from apps, like spreadsheets and automation
+// scripts, that change the clipboard many times a second
+type CaptureLimits struct {
+	// Coalesce keeps only the last change within this window of the first.
+	// Zero records every change.
+	Coalesce time.Duration
+
+	// PerAppPerMinute caps the clips recorded from one app in any minute.
+	// Zero is unlimited.
+	PerAppPerMinute int
+}
+
+// DefaultCaptureLimits are the limits used unless SetCaptureLimits is called
+var DefaultCaptureLimits = CaptureLimits{
+	Coalesce:        200 * time.Millisecond,
+	PerAppPerMinute: 60,
+}
+
+// captureLimiter applies CaptureLimits to clipboard changes before passing
+// the ones it keeps to emit
+type captureLimiter struct {
+	limits CaptureLimits
+	emit   func(types.Clip)
+	now    func() time.Time
+
+	mu      sync.Mutex
+	pending *types.Clip            // Latest change in the coalescing window
+	timer   *time.Timer            // Ends the coalescing window
+	recent  map[string][]time.Time // When each app's recent clips were kept
+	flushes sync.WaitGroup         // Windows that have not been flushed
+}
+
+func newCaptureLimiter(limits CaptureLimits, emit func(types.Clip)) *captureLimiter {
+	return &captureLimiter{
+		limits: limits,
+		emit:   emit,
+		now:    time.Now,
+		recent: make(map[string][]time.Time),
+	}
+}
+
+// add takes a clipboard change. With coalescing it is held until its window
+// ends and dropped if another change replaces it first.
+func (l *captureLimiter) add(clip types.Clip) {
+	if l.limits.Coalesce <= 0 {
+		l.admit(clip)
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.pending != nil {
+		metrics.CapturesLimited.WithLabelValues("coalesced").Inc()
+	}
+	l.pending = &clip
+	if l.timer == nil {
+		l.flushes.Add(1)
+		l.timer = time.AfterFunc(l.limits.Coalesce, func() {
+			defer l.flushes.Done()
+			l.flush()
+		})
+	}
+}
+
+// flush ends the coalescing window, passing on its last change
+func (l *captureLimiter) flush() {
+	l.mu.Lock()
+	clip := l.pending
+	l.pending, l.timer = nil, nil
+	l.mu.Unlock()
+
+	if clip != nil {
+		l.admit(*clip)
+	}
+}
+
+// stop passes on a change still waiting for its window to end and returns
+// once every window has been flushed
+func (l *captureLimiter) stop() {
+	l.mu.Lock()
+	stopped := l.timer != nil && l.timer.Stop()
+	l.mu.Unlock()
+
+	if stopped {
+		l.flush()
+		l.flushes.Done()
+	}
+	l.flushes.Wait()
+}
+
+// admit passes a change on unless its app has reached its limit for the
+// last minute
+func (l *captureLimiter) admit(clip types.Clip) {
+	if l.limits.PerAppPerMinute > 0 {
+		app := clip.Metadata.SourceBundleID
+		if app == "" {
+			app = clip.Metadata.SourceApp
+		}
+
+		now := l.now()
+		l.mu.Lock()
+		recent := l.recent[app]
+		for len(recent) > 0 && now.Sub(recent[0]) >= time.Minute {
+			recent = recent[1:]
+		}
+		limited := len(recent) >= l.limits.PerAppPerMinute
+		if !limited {
+			recent = append(recent, now)
+		}
+		if len(recent) == 0 {
+			delete(l.recent, app)
+		} else {
+			l.recent[app] = recent
+		}
+		l.mu.Unlock()
+
+		if limited {
+			metrics.CapturesLimited.WithLabelValues("rate").Inc()
+			debugLog("Capture limit reached for %q, ignoring clipboard change", app)
+			return
+		}
+	}
+	l.emit(clip)
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"clipboard-manager/pkg/types"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// collect returns a limiter that records the clips it keeps
+func collect(limits CaptureLimits) (*captureLimiter, func() []string) {
+	var mu sync.Mutex
+	var kept []string
+	limiter := newCaptureLimiter(limits, func(clip types.Clip) {
+		mu.Lock()
+		defer mu.Unlock()
+		kept = append(kept, string(clip.Content))
+	})
+	return limiter, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), kept...)
+	}
+}
+
+func TestCaptureLimiter_Coalesce(t *testing.T) {
+	limiter, kept := collect(CaptureLimits{Coalesce: time.Hour})
+
+	for i := 0; i < 5; i++ {
+		limiter.add(types.Clip{Content: []byte(fmt.Sprint(i))})
+	}
+	if got := kept(); len(got) != 0 {
+		t.Fatalf("expected changes to be held until the window ends, got %q", got)
+	}
+
+	// Stopping flushes the window early
+	limiter.stop()
+	if got := kept(); len(got) != 1 || got[0] != "4" {
+		t.Errorf("expected only the last change, got %q", got)
+	}
+}
+
+func TestCaptureLimiter_PerApp(t *testing.T) {
+	limiter, kept := collect(CaptureLimits{PerAppPerMinute: 2})
+	now := time.Now()
+	limiter.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		limiter.add(types.Clip{Content: []byte(fmt.Sprintf("sheet %d", i)), Metadata: types.Metadata{SourceBundleID: "com.example.sheets"}})
+	}
+	limiter.add(types.Clip{Content: []byte("editor"), Metadata: types.Metadata{SourceBundleID: "com.example.editor"}})
+
+	// Clips leave the window a minute after they were kept
+	now = now.Add(time.Minute)
+	limiter.add(types.Clip{Content: []byte("sheet 3"), Metadata: types.Metadata{SourceBundleID: "com.example.sheets"}})
+
+	want := []string{"sheet 0", "sheet 1", "editor", "sheet 3"}
+	if got := kept(); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("kept %q, want %q", got, want)
+	}
+}
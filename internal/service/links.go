@@ -0,0 +1,57 @@
+package service
+
+import (
+	"clipboard-manager/internal/clipboard"
+	"clipboard-manager/internal/storage"
+	"clipboard-manager/internal/unfurl"
+	"clipboard-manager/pkg/types"
+	"log"
+	"strings"
+)
+
+// maxUnfurls caps the link previews fetched at once. Links copied while all
+// are busy go without a preview.
+const maxUnfurls = 4
+
+// unfurl fetches the preview of a stored link clip in the background, if
+// link unfurling is turned on. Sensitive clips are never looked up.
+func (s *ClipboardService) unfurl(clip *types.Clip) {
+	s.mu.RLock()
+	enabled := s.unfurlLinks
+	s.mu.RUnlock()
+	if !enabled || clip.Metadata.Link != nil || clip.Metadata.ExpiresAt != nil {
+		return
+	}
+
+	text, ok := clipboard.PlainText(clip)
+	if !ok || !unfurl.IsLink(text) {
+		return
+	}
+	links, ok := s.storage().(storage.LinkService)
+	if !ok {
+		return
+	}
+
+	select {
+	case s.unfurls <- struct{}{}:
+	default:
+		debugLog("Too many link previews in progress, skipping %s", text)
+		return
+	}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer func() { <-s.unfurls }()
+
+		preview, err := unfurl.Fetch(s.ctx, s.linkClient, strings.TrimSpace(text))
+		if err != nil {
+			debugLog("No preview for %s: %v", text, err)
+			return
+		}
+		if err := links.SetLinkPreview(s.ctx, clip.ID, preview); err != nil {
+			log.Printf("[ERROR] Failed to save link preview: %v", err)
+			return
+		}
+		debugLog("Saved preview of %s: %q", text, preview.Title)
+	}()
+}
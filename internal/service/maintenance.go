@@ -0,0 +1,149 @@
+package service
+
+import (
+	"clipboard-manager/internal/config"
+	"clipboard-manager/internal/metrics"
+	"clipboard-manager/internal/storage"
+	"context"
+	"log"
+	"time"
+)
+
+// maintenanceCheck is how often the maintenance loop looks for work
+const maintenanceCheck = time.Minute
+
+// Defaults for the maintenance settings left at zero
+const (
+	defaultMaintenanceInterval = 24 * time.Hour
+	defaultMaintenanceIdle     = 5 * time.Minute
+	defaultWALLimitMB          = 64
+)
+
+// maintenanceSchedule tracks the runs the maintenance loop made
+type maintenanceSchedule struct {
+	lastFull time.Time // Last full run attempted
+	lastRun  time.Time // Last run of either kind attempted
+}
+
+// maintenanceSettings returns the maintenance settings with defaults filled in
+func (s *ClipboardService) maintenanceSettings() config.Maintenance {
+	s.mu.RLock()
+	settings := s.maintenance
+	s.mu.RUnlock()
+
+	if settings.Interval == 0 {
+		settings.Interval = config.Duration(defaultMaintenanceInterval)
+	}
+	if settings.IdleAfter == 0 {
+		settings.IdleAfter = config.Duration(defaultMaintenanceIdle)
+	}
+	if settings.WALLimitMB == 0 {
+		settings.WALLimitMB = defaultWALLimitMB
+	}
+	return settings
+}
+
+// maintenanceLoop keeps the database in shape. Once the clipboard has been
+// idle for a while it vacuums and analyzes the database at most once per
+// interval, and otherwise checkpoints what was written since the last run.
+// A write-ahead log past the limit is checkpointed and truncated even while
+// clips are being copied.
+func (s *ClipboardService) maintenanceLoop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(maintenanceCheck)
+	defer ticker.Stop()
+
+	var schedule maintenanceSchedule
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		s.maintainIfDue(time.Now(), &schedule)
+	}
+}
+
+// maintainIfDue runs whichever maintenance is due at now
+func (s *ClipboardService) maintainIfDue(now time.Time, schedule *maintenanceSchedule) {
+	settings := s.maintenanceSettings()
+	// Looked up each time, since switching profiles replaces the storage
+	maintainer, ok := s.storage().(storage.Maintainer)
+	if settings.Disabled || !ok {
+		return
+	}
+
+	opts := storage.MaintenanceOptions{WALLimit: int64(settings.WALLimitMB) << 20}
+	lastActivity := time.Unix(0, s.lastActivity.Load())
+	idle := now.Sub(lastActivity) >= time.Duration(settings.IdleAfter)
+	switch {
+	case idle && now.Sub(schedule.lastFull) >= time.Duration(settings.Interval):
+		opts.Full = true
+		schedule.lastFull = now
+	case idle && lastActivity.After(schedule.lastRun):
+		// Checkpoint what was copied since the last run
+	default:
+		state, err := maintainer.DatabaseState(s.ctx)
+		if err != nil {
+			debugLog("Failed to read database state: %v", err)
+			return
+		}
+		if state.WALBytes <= opts.WALLimit {
+			return
+		}
+	}
+	schedule.lastRun = now
+
+	kind := "checkpoint"
+	if opts.Full {
+		kind = "full"
+	}
+	report, err := maintainer.Maintain(s.ctx, opts)
+	metrics.MaintenanceRuns.WithLabelValues(kind, metrics.Result(err)).Inc()
+	if err != nil {
+		if s.ctx.Err() == nil {
+			log.Printf("[ERROR] Database maintenance failed: %v", err)
+		}
+		return
+	}
+	metrics.MaintenanceDuration.Observe(report.Duration.Seconds())
+	metrics.MaintenanceFreedBytes.Add(float64(report.FreedBytes))
+
+	s.maintenanceMu.Lock()
+	s.maintenanceRuns++
+	s.lastMaintenance = report
+	s.maintenanceMu.Unlock()
+	debugLog("Database maintenance (%s) took %v, freed %d bytes, WAL was %d bytes (truncated: %v)",
+		kind, report.Duration, report.FreedBytes, report.WALBytes, report.WALTruncated)
+}
+
+// maintenanceStatus reports maintenance for statistics
+func (s *ClipboardService) maintenanceStatus(ctx context.Context, maintainer storage.Maintainer) *storage.MaintenanceStatus {
+	state, err := maintainer.DatabaseState(ctx)
+	if err != nil {
+		debugLog("Failed to read database state: %v", err)
+	}
+
+	s.maintenanceMu.Lock()
+	defer s.maintenanceMu.Unlock()
+	return &storage.MaintenanceStatus{
+		DatabaseState: state,
+		Runs:          s.maintenanceRuns,
+		LastRun:       s.lastMaintenance,
+	}
+}
+
+// databaseState reads the database state for metrics, reporting zero if it
+// can't be read
+func (s *ClipboardService) databaseState() storage.DatabaseState {
+	maintainer, ok := s.storage().(storage.Maintainer)
+	if !ok {
+		return storage.DatabaseState{}
+	}
+	state, err := maintainer.DatabaseState(s.ctx)
+	if err != nil {
+		debugLog("Failed to read database state: %v", err)
+	}
+	return state
+}
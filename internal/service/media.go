@@ -0,0 +1,64 @@
+package service
+
+import (
+	"clipboard-manager/internal/clipboard"
+	"clipboard-manager/internal/media"
+	"clipboard-manager/internal/storage"
+	"clipboard-manager/pkg/types"
+	"context"
+	"errors"
+	"fmt"
+)
+
+// describeMedia adds the type, duration and size of a copied audio or video
+// file to its clip, and a poster frame for videos. Whatever ffprobe and
+// ffmpeg can't provide is left out.
+func (s *ClipboardService) describeMedia(clip *types.Clip) {
+	if clip.Type != storage.TypeFile || clip.Metadata.Media != nil {
+		return
+	}
+	path, ok := media.FilePath(string(clip.Content))
+	if !ok {
+		return
+	}
+
+	info, err := media.Describe(s.ctx, path)
+	if info == nil {
+		return
+	}
+	if err != nil && !errors.Is(err, media.ErrNotInstalled) {
+		debugLog("Failed to probe %s: %v", path, err)
+	}
+	if info.Kind == media.Video {
+		poster, err := media.Poster(s.ctx, path, info.Duration, clipboard.DefaultThumbnailSize)
+		if err == nil {
+			info.Poster = poster
+		} else if !errors.Is(err, media.ErrNotInstalled) {
+			debugLog("No poster for %s: %v", path, err)
+		}
+	}
+	clip.Metadata.Media = info
+}
+
+// ListMedia returns audio and video clips, most recently used first. Kind
+// limits them to media.Audio or media.Video.
+func (s *ClipboardService) ListMedia(ctx context.Context, kind string, limit, offset int) ([]*types.Clip, error) {
+	query := "type:" + media.Audio + " OR type:" + media.Video
+	if kind != "" {
+		query = "type:" + kind
+	}
+	results, err := s.Search(ctx, storage.SearchOptions{Query: query, Limit: limit, Offset: offset})
+	if err != nil {
+		return nil, &ClipboardError{
+			Op:      "ListMedia",
+			Index:   -1,
+			Message: fmt.Sprintf("failed to list %s clips", query),
+			Err:     err,
+		}
+	}
+	clips := make([]*types.Clip, 0, len(results))
+	for _, result := range results {
+		clips = append(clips, result.Clip)
+	}
+	return clips, nil
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"clipboard-manager/internal/clipboard"
+	"clipboard-manager/internal/metrics"
+	"clipboard-manager/internal/notify"
+	"clipboard-manager/internal/storage"
+	"clipboard-manager/pkg/types"
+	"fmt"
+	"log"
+)
+
+// SetNotifier replaces the native notifier, for tests and for clients that
+// show notifications themselves. It must be called before ApplyConfig turns
+// any notifications on.
+func (s *ClipboardService) SetNotifier(notifier notify.Notifier) {
+	s.notifier = notifier
+}
+
+// notify shows a notification if its event is turned on in the settings.
+// Notifiers may run external commands, so this doesn't wait for it.
+func (s *ClipboardService) notify(event notify.Event, title, message string) {
+	s.mu.RLock()
+	settings := s.notifySettings
+	s.mu.RUnlock()
+
+	enabled := map[notify.Event]bool{
+		notify.EventLargeFile:  settings.LargeFile,
+		notify.EventIgnored:    settings.Ignored,
+		notify.EventSyncFailed: settings.SyncFailed,
+		notify.EventPasted:     settings.Pasted,
+		notify.EventRule:       true, // The rule asked for it
+	}
+	if !enabled[event] || s.notifier == nil {
+		return
+	}
+
+	go func() {
+		if err := s.notifier.Notify(title, message); err != nil {
+			log.Printf("[WARN] Failed to show %s notification: %v", event, err)
+		}
+	}()
+}
+
+// notifyPasted confirms a paste. Sensitive clips are not described.
+func (s *ClipboardService) notifyPasted(clip *types.Clip) {
+	message := clipboard.Title(clip, 80)
+	if clip.Metadata.ExpiresAt != nil {
+		message = "Sensitive clip"
+	}
+	s.notify(notify.EventPasted, "Copied to clipboard", message)
+}
+
+// notifyTooLarge tells the user a clip was dropped for its size, err being
+// the *storage.SizeLimitError it was dropped with
+func (s *ClipboardService) notifyTooLarge(clip types.Clip, err *storage.SizeLimitError) {
+	metrics.CapturesLimited.WithLabelValues("size").Inc()
+	debugLog("Content too large to store (%v, size: %d bytes)", err, err.Size)
+
+	message := fmt.Sprintf("%s from %s is over the %s limit for %s clips",
+		formatSize(int(err.Size)), sourceName(clip), formatSize(int(err.Limit)), err.Type)
+	if err.Limit == 0 {
+		message = fmt.Sprintf("%s clips aren't kept, see size_limits_mb in the settings", err.Type)
+	}
+	s.notify(notify.EventLargeFile, "Clip too large to save", message)
+}
+
+// syncFailed is the Obsidian sync error callback
+func (s *ClipboardService) syncFailed(err error) {
+	s.notify(notify.EventSyncFailed, "Sync failed", err.Error())
+}
+
+// sourceName names the app a clip was copied from
+func sourceName(clip types.Clip) string {
+	if clip.Metadata.SourceApp != "" {
+		return clip.Metadata.SourceApp
+	}
+	if clip.Metadata.SourceBundleID != "" {
+		return clip.Metadata.SourceBundleID
+	}
+	return "an unknown app"
+}
+
+// formatSize writes a byte count in MB
+func formatSize(n int) string {
+	return fmt.Sprintf("%.1f MB", float64(n)/(1024*1024))
+}
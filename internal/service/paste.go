@@ -0,0 +1,39 @@
+package service
+
+import (
+	"clipboard-manager/internal/paste"
+	"clipboard-manager/internal/trace"
+	"context"
+)
+
+// SetPaster replaces the native paster, for tests and for clients that
+// press the paste shortcut themselves
+func (s *ClipboardService) SetPaster(paster paste.Paster) {
+	s.paster = paster
+}
+
+// SimulatePaste presses the paste shortcut in the frontmost app, so what
+// was just copied lands where the user is typing. When the system can't or
+// won't let us press keys, the error's message says what to do about it.
+func (s *ClipboardService) SimulatePaste(ctx context.Context) error {
+	defer trace.Start(ctx, "simulate paste").End()
+	if s.paster == nil {
+		return &ClipboardError{
+			Op:      "SimulatePaste",
+			Index:   -1,
+			Message: "simulating paste is not available",
+			Err:     paste.ErrUnavailable,
+		}
+	}
+	if err := s.paster.Paste(ctx); err != nil {
+		trace.Logf(ctx, "[ERROR] Error simulating paste: %v", err)
+		return &ClipboardError{
+			Op:      "SimulatePaste",
+			Index:   -1,
+			Message: err.Error(),
+			Err:     err,
+		}
+	}
+	debugLog("Simulated paste")
+	return nil
+}
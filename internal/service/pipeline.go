@@ -0,0 +1,125 @@
+package service
+
+import (
+	"clipboard-manager/internal/metrics"
+	"clipboard-manager/pkg/types"
+	"sync"
+	"time"
+)
+
+// pipelineQueueSize is how many clips may wait in front of each stage before
+// the stage feeding it, and ultimately the monitor, has to wait
+const pipelineQueueSize = 32
+
+// captureJob is a clipboard change moving through the capture pipeline
+type captureJob struct {
+	clip      types.Clip
+	translate string // Language a rule asked the clip to be translated into
+}
+
+// stage is one step of capture post-processing. Run returns false to drop
+// the clip, skipping the stages after it.
+type stage struct {
+	name string
+	run  func(job *captureJob) bool
+}
+
+// pipeline runs clipboard changes through its stages in order, each stage
+// with a single worker and a bounded queue in front of it. One worker per
+// stage keeps clips in the order they were copied, which history ordering
+// depends on, while a slow store no longer holds up classifying the next
+// clip.
+type pipeline struct {
+	input        chan *captureJob
+	first        string             // Name of the first stage, for metrics
+	recoverStage func(where string) // Deferred around each stage, see ClipboardService.recoverCapture
+
+	mu     sync.RWMutex // Guards closed against concurrent enqueues
+	closed bool
+	done   chan struct{} // Closed once the last stage has finished
+}
+
+// newPipeline starts a worker for each stage. A stage that panics drops the
+// clip it was given, once recoverStage has recovered from the panic.
+func newPipeline(stages []stage, recoverStage func(where string)) *pipeline {
+	p := &pipeline{
+		input:        make(chan *captureJob, pipelineQueueSize),
+		first:        stages[0].name,
+		recoverStage: recoverStage,
+		done:         make(chan struct{}),
+	}
+
+	in := p.input
+	for i, st := range stages {
+		var out chan *captureJob
+		next := ""
+		if i < len(stages)-1 {
+			out = make(chan *captureJob, pipelineQueueSize)
+			next = stages[i+1].name
+		}
+		go p.work(st, in, out, next)
+		in = out
+	}
+	return p
+}
+
+// work runs one stage until its queue is closed and empty, then closes the
+// queue of the next stage
+func (p *pipeline) work(st stage, in <-chan *captureJob, out chan *captureJob, next string) {
+	if out != nil {
+		defer close(out)
+	} else {
+		defer close(p.done)
+	}
+
+	for job := range in {
+		metrics.StageQueued.WithLabelValues(st.name).Dec()
+		start := time.Now()
+		keep := p.run(st, job)
+		metrics.StageDuration.WithLabelValues(st.name).Observe(time.Since(start).Seconds())
+		if keep && out != nil {
+			send(out, next, job)
+		}
+	}
+}
+
+// run runs one stage for job. keep stays false if the stage panics.
+func (p *pipeline) run(st stage, job *captureJob) (keep bool) {
+	defer p.recoverStage(st.name + " stage")
+	return st.run(job)
+}
+
+// send queues job for a stage, waiting for room if its queue is full
+func send(queue chan<- *captureJob, name string, job *captureJob) {
+	metrics.StageQueued.WithLabelValues(name).Inc()
+	select {
+	case queue <- job:
+	default:
+		metrics.StageBlocked.WithLabelValues(name).Inc()
+		queue <- job
+	}
+}
+
+// enqueue adds a clipboard change to the pipeline. It waits while the first
+// queue is full and returns false once the pipeline is draining.
+func (p *pipeline) enqueue(clip types.Clip) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return false
+	}
+	send(p.input, p.first, &captureJob{clip: clip})
+	return true
+}
+
+// drain stops taking new clips and waits until every queued clip has been
+// through all the stages
+func (p *pipeline) drain() {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.input)
+	}
+	p.mu.Unlock()
+	<-p.done
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"clipboard-manager/internal/plugin"
+	"clipboard-manager/pkg/types"
+	"context"
+	"log"
+)
+
+// Plugins describes the configured plugins and how they are doing
+func (s *ClipboardService) Plugins() []plugin.Info {
+	return s.plugins.List()
+}
+
+// RestartPlugin restarts a plugin, such as one given up on after failing
+// repeatedly
+func (s *ClipboardService) RestartPlugin(name string) error {
+	if err := s.plugins.Restart(name); err != nil {
+		return &ClipboardError{
+			Op:      "RestartPlugin",
+			Index:   -1,
+			Message: err.Error(),
+			Err:     err,
+		}
+	}
+	return nil
+}
+
+// RunPluginAction runs an action of a plugin on a clip. Text the action
+// returns is put on the clipboard.
+func (s *ClipboardService) RunPluginAction(ctx context.Context, id, name, action string) (*plugin.ActionResult, error) {
+	clip, err := s.GetClipByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	result, err := s.plugins.RunAction(ctx, name, action, clip)
+	if err != nil {
+		return nil, &ClipboardError{
+			Op:      "RunPluginAction",
+			Index:   -1,
+			Message: err.Error(),
+			Err:     err,
+		}
+	}
+
+	if result.Text != "" {
+		copied := &types.Clip{Content: []byte(result.Text), Type: types.TypeText}
+		if err := s.SetClipboard(ctx, copied); err != nil {
+			return nil, err
+		}
+		s.notifyPasted(copied)
+	}
+	return result, nil
+}
+
+// processWithPlugins runs the processors of plugins on a captured clip,
+// reporting false if one of them drops it. Processors that fail are logged
+// and the clip goes on without their changes.
+func (s *ClipboardService) processWithPlugins(clip *types.Clip) bool {
+	skip, err := s.plugins.Process(s.ctx, clip)
+	if err != nil {
+		log.Printf("[ERROR] Error processing clip with plugins: %v", err)
+	}
+	if skip {
+		debugLog("A plugin dropped the clip")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"clipboard-manager/internal/config"
+	"clipboard-manager/internal/storage"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+)
+
+// ProfileManager opens the storage of each profile and remembers which one
+// is active. See profile.Manager.
+type ProfileManager interface {
+	Open(name string) (storage.Storage, error)
+	SetActive(name string) error
+	List() []string // Profiles that have been opened
+}
+
+// SetProfiles lets UseProfile switch to other profiles. active is the
+// profile of the storage the service was created with. Call it before
+// subscribing to settings and before Start.
+func (s *ClipboardService) SetProfiles(profiles ProfileManager, active string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.profiles = profiles
+	s.profile = active
+}
+
+// Profile returns the active profile, or "" if profiles aren't set up
+func (s *ClipboardService) Profile() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.profile
+}
+
+// Profiles lists the profiles that have been used or have settings, sorted
+func (s *ClipboardService) Profiles() []string {
+	s.mu.RLock()
+	profiles, settings := s.profiles, s.settings
+	s.mu.RUnlock()
+	if profiles == nil {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	var names []string
+	for _, name := range profiles.List() {
+		seen[name] = true
+		names = append(names, name)
+	}
+	for name := range settings.Profiles {
+		if !seen[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// UseProfile switches to another profile: its storage replaces the current
+// one and its settings apply from then on. Append mode is stopped and the
+// paste stack, whose clips belong to the old profile, cleared.
+func (s *ClipboardService) UseProfile(name string) error {
+	s.profileMu.Lock()
+	defer s.profileMu.Unlock()
+
+	s.mu.RLock()
+	profiles, current, settings := s.profiles, s.profile, s.settings
+	s.mu.RUnlock()
+	if profiles == nil {
+		return &ClipboardError{
+			Op:      "UseProfile",
+			Index:   -1,
+			Message: "profiles are not enabled",
+		}
+	}
+	if !config.ValidProfileName(name) {
+		return &ClipboardError{
+			Op:      "UseProfile",
+			Index:   -1,
+			Message: fmt.Sprintf("invalid profile name %q", name),
+		}
+	}
+	if name == current {
+		return nil
+	}
+
+	next, err := profiles.Open(name)
+	if err != nil {
+		return &ClipboardError{
+			Op:      "UseProfile",
+			Index:   -1,
+			Message: fmt.Sprintf("failed to open profile %s: %v", name, err),
+			Err:     err,
+		}
+	}
+
+	s.StopAppend()
+	s.ClearPasteStack()
+
+	s.storeMu.Lock()
+	previous := s.store
+	s.store = next
+	s.storeMu.Unlock()
+
+	// Obsidian sync is bound to the storage it reads, so start over with
+	// the new profile's settings
+	s.mu.Lock()
+	s.profile = name
+	obsidianSync, started := s.obsidianSync, s.started
+	s.obsidianSync = nil
+	s.syncSettings = syncSettings{}
+	s.mu.Unlock()
+	if obsidianSync != nil && started {
+		obsidianSync.Stop()
+	}
+	s.ApplyConfig(settings)
+
+	if closer, ok := previous.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			log.Printf("[ERROR] Failed to close the storage of profile %s: %v", current, err)
+		}
+	}
+	if err := profiles.SetActive(name); err != nil {
+		log.Printf("[ERROR] Failed to remember the active profile: %v", err)
+	}
+	log.Printf("Switched to profile %s", name)
+	return nil
+}
+
+// storage returns the storage of the active profile
+func (s *ClipboardService) storage() storage.Storage {
+	s.storeMu.RLock()
+	defer s.storeMu.RUnlock()
+	return s.store
+}
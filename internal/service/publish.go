@@ -0,0 +1,62 @@
+package service
+
+import (
+	"clipboard-manager/internal/clipboard"
+	"clipboard-manager/internal/publish"
+	"clipboard-manager/internal/storage"
+	"clipboard-manager/pkg/types"
+	"context"
+	"fmt"
+)
+
+// PublishClip uploads the text of a clip to target, publish.Gist or
+// publish.Paste, and puts the link on the clipboard
+func (s *ClipboardService) PublishClip(ctx context.Context, id, target string) (string, error) {
+	clip, err := s.GetClipByID(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	if clip.Metadata.ExpiresAt != nil {
+		return "", &ClipboardError{
+			Op:      "PublishClip",
+			Index:   -1,
+			Message: publish.ErrSensitive.Error(),
+			Err:     publish.ErrSensitive,
+		}
+	}
+	text, ok := clipboard.PlainText(clip)
+	if !ok {
+		return "", &ClipboardError{
+			Op:      "PublishClip",
+			Index:   -1,
+			Message: fmt.Sprintf("clip %s is %s, only text can be published", id, clip.Type),
+			Err:     storage.ErrInvalidType,
+		}
+	}
+
+	s.mu.RLock()
+	settings := s.publishSettings
+	s.mu.RUnlock()
+	publisher := publish.Publisher{
+		Client:     s.linkClient,
+		GistToken:  settings.GistToken,
+		GistPublic: settings.GistPublic,
+		PasteURL:   settings.PasteURL,
+	}
+	link, err := publisher.Publish(ctx, target, publish.FileName(clip.Metadata.Language), text)
+	if err != nil {
+		return "", &ClipboardError{
+			Op:      "PublishClip",
+			Index:   -1,
+			Message: err.Error(),
+			Err:     err,
+		}
+	}
+
+	result := &types.Clip{Content: []byte(link), Type: types.TypeText}
+	if err := s.SetClipboard(ctx, result); err != nil {
+		return "", err
+	}
+	s.notifyPasted(result)
+	return link, nil
+}
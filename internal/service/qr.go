@@ -0,0 +1,38 @@
+package service
+
+import (
+	"clipboard-manager/internal/clipboard"
+	"clipboard-manager/internal/qr"
+	"clipboard-manager/internal/storage"
+	"context"
+	"fmt"
+	"strings"
+)
+
+// QRCode returns the text of a clip as a QR code PNG size pixels wide
+func (s *ClipboardService) QRCode(ctx context.Context, id string, size int) ([]byte, error) {
+	clip, err := s.GetClipByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	text, ok := clipboard.PlainText(clip)
+	if !ok {
+		return nil, &ClipboardError{
+			Op:      "QRCode",
+			Index:   -1,
+			Message: fmt.Sprintf("clip %s is %s, QR codes need text", id, clip.Type),
+			Err:     storage.ErrInvalidType,
+		}
+	}
+
+	code, err := qr.PNG(strings.TrimSpace(text), size)
+	if err != nil {
+		return nil, &ClipboardError{
+			Op:      "QRCode",
+			Index:   -1,
+			Message: fmt.Sprintf("clip %s has %d bytes of text, QR codes hold at most %d", id, len(text), qr.MaxBytes),
+			Err:     err,
+		}
+	}
+	return code, nil
+}
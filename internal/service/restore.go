@@ -0,0 +1,44 @@
+package service
+
+import (
+	"clipboard-manager/internal/clipboard"
+	"log"
+)
+
+// restoreScan is how many of the latest clips are looked through for one
+// that isn't sensitive
+const restoreScan = 20
+
+// restoreLatest puts the most recent clip that isn't sensitive back on an
+// empty clipboard, which macOS clears when it restarts. Anything copied
+// while the daemon was down is left alone.
+func (s *ClipboardService) restoreLatest() {
+	if checker, ok := s.monitor.(clipboard.ContentChecker); ok && checker.HasContent() {
+		debugLog("Clipboard isn't empty, not restoring the latest clip")
+		return
+	}
+
+	clips, err := s.GetClips(s.ctx, restoreScan, 0)
+	if err != nil {
+		log.Printf("[ERROR] Failed to find the latest clip to restore: %v", err)
+		return
+	}
+	for _, latest := range clips {
+		// Sensitive clips expire, and stay off the clipboard once it's cleared
+		if latest.Metadata.ExpiresAt != nil {
+			continue
+		}
+		clip, err := s.GetClipByID(s.ctx, latest.ID)
+		if err != nil {
+			log.Printf("[ERROR] Failed to load clip %s to restore: %v", latest.ID, err)
+			return
+		}
+		if err := s.SetClipboard(s.ctx, clip); err != nil {
+			log.Printf("[ERROR] Failed to restore the latest clip: %v", err)
+			return
+		}
+		log.Printf("Restored clip %s to the clipboard", clip.ID)
+		return
+	}
+	debugLog("No clip to restore")
+}
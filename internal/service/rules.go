@@ -0,0 +1,36 @@
+package service
+
+import (
+	"clipboard-manager/internal/notify"
+	"fmt"
+	"strings"
+)
+
+// applyRules runs the capture rules against a captured clip, tagging and
+// filing it as they say and noting the translation they ask for. It returns
+// false if a rule skips the clip.
+func (s *ClipboardService) applyRules(job *captureJob) bool {
+	clip := &job.clip
+	s.mu.RLock()
+	ruleSet := s.rules
+	s.mu.RUnlock()
+
+	result := ruleSet.Evaluate(clip)
+	if len(result.Matched) == 0 {
+		return true
+	}
+	debugLog("Clip from %q matches rules %s", clip.Metadata.SourceApp, strings.Join(result.Matched, ", "))
+	if result.Notify {
+		title := "Clip saved"
+		if result.Skip {
+			title = "Clip not saved"
+		}
+		s.notify(notify.EventRule, title, fmt.Sprintf("Copied from %s, which matches the rule %s", sourceName(*clip), strings.Join(result.Matched, ", ")))
+	}
+	if result.Skip {
+		return false
+	}
+	result.Apply(clip)
+	job.translate = result.Translate
+	return true
+}
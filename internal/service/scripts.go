@@ -0,0 +1,27 @@
+package service
+
+import (
+	"clipboard-manager/internal/script"
+	"clipboard-manager/pkg/types"
+	"log"
+)
+
+// Scripts describes the user's scripts and how they are doing
+func (s *ClipboardService) Scripts() []script.Info {
+	return s.scripts.List()
+}
+
+// runScripts runs the user's scripts on a captured clip, reporting false if
+// one of them drops it. Scripts that fail are logged and the clip goes on
+// without their changes.
+func (s *ClipboardService) runScripts(clip *types.Clip) bool {
+	keep, err := s.scripts.Run(s.ctx, clip)
+	if err != nil {
+		log.Printf("[ERROR] Error running scripts: %v", err)
+	}
+	if !keep {
+		debugLog("A script dropped the clip")
+		return false
+	}
+	return true
+}
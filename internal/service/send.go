@@ -0,0 +1,98 @@
+package service
+
+import (
+	"clipboard-manager/internal/chat"
+	"clipboard-manager/internal/clipboard"
+	"clipboard-manager/internal/publish"
+	"clipboard-manager/internal/storage"
+	"clipboard-manager/pkg/types"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// imageExtensions names uploaded images by their clip type
+var imageExtensions = map[string]string{
+	"screenshot": ".png", "image/png": ".png", "image/jpeg": ".jpg",
+	"image/gif": ".gif", "image/tiff": ".tiff", "image/webp": ".webp",
+}
+
+// SendClip posts a clip to a chat target, chat.Slack or chat.Discord. Text
+// is sent as a code block and images are uploaded.
+func (s *ClipboardService) SendClip(ctx context.Context, id, target string) error {
+	clip, err := s.GetClipByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if clip.Metadata.ExpiresAt != nil {
+		return &ClipboardError{
+			Op:      "SendClip",
+			Index:   -1,
+			Message: chat.ErrSensitive.Error(),
+			Err:     chat.ErrSensitive,
+		}
+	}
+
+	msg, err := s.chatMessage(ctx, clip)
+	if err != nil {
+		return err
+	}
+
+	s.mu.RLock()
+	settings := s.sendSettings
+	s.mu.RUnlock()
+	sender := chat.Sender{
+		Client:         s.linkClient,
+		SlackWebhook:   settings.Slack.WebhookURL,
+		SlackToken:     settings.Slack.Token,
+		SlackChannel:   settings.Slack.Channel,
+		DiscordWebhook: settings.Discord.WebhookURL,
+	}
+	if err := sender.Send(ctx, target, msg); err != nil {
+		return &ClipboardError{
+			Op:      "SendClip",
+			Index:   -1,
+			Message: err.Error(),
+			Err:     err,
+		}
+	}
+	return nil
+}
+
+// chatMessage holds the image or text of a clip
+func (s *ClipboardService) chatMessage(ctx context.Context, clip *types.Clip) (chat.Message, error) {
+	if ext, ok := imageExtensions[clip.Type]; ok || strings.HasPrefix(clip.Type, "image/") {
+		reader, _, err := s.GetClipContent(ctx, clip.ID)
+		if err != nil {
+			return chat.Message{}, err
+		}
+		defer reader.Close()
+		image, err := io.ReadAll(reader)
+		if err != nil {
+			return chat.Message{}, &ClipboardError{
+				Op:      "SendClip",
+				Index:   -1,
+				Message: "failed to read image",
+				Err:     err,
+			}
+		}
+		name := "image"
+		if clip.Type == types.TypeScreenshot {
+			name = "screenshot"
+		}
+		return chat.Message{Image: image, FileName: name + ext}, nil
+	}
+
+	text, ok := clipboard.PlainText(clip)
+	if !ok {
+		return chat.Message{}, &ClipboardError{
+			Op:      "SendClip",
+			Index:   -1,
+			Message: fmt.Sprintf("clip %s is %s, only text and images can be sent", clip.ID, clip.Type),
+			Err:     storage.ErrInvalidType,
+		}
+	}
+	language := clip.Metadata.Language
+	return chat.Message{Text: text, Language: language, FileName: publish.FileName(language)}, nil
+}
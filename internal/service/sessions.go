@@ -0,0 +1,56 @@
+package service
+
+import (
+	"clipboard-manager/internal/storage"
+	"clipboard-manager/pkg/types"
+	"context"
+	"fmt"
+	"time"
+)
+
+// copySessionGap is the longest pause between two copies from one app that
+// still puts them in the same copy session
+const copySessionGap = time.Minute
+
+// copySession returns the copy session of a clip captured now: the one of
+// the last clip if both are from the same app and came within
+// copySessionGap of each other, otherwise a new one
+func (s *ClipboardService) copySession(clip types.Clip) string {
+	app := clip.Metadata.SourceBundleID
+	if app == "" {
+		app = clip.Metadata.SourceApp
+	}
+	now := time.Now()
+
+	s.sessionMu.Lock()
+	defer s.sessionMu.Unlock()
+	if s.session == "" || app != s.sessionApp || now.Sub(s.sessionLast) > copySessionGap {
+		s.session, s.sessionApp = storage.NewUUID(), app
+	}
+	s.sessionLast = now
+	return s.session
+}
+
+// RelatedClips returns the other clips copied in the same session as the
+// clip with id, in the order they were copied
+func (s *ClipboardService) RelatedClips(ctx context.Context, id string) ([]*types.Clip, error) {
+	finder, ok := s.storage().(storage.RelatedFinder)
+	if !ok {
+		return nil, &ClipboardError{
+			Op:      "RelatedClips",
+			Index:   -1,
+			Message: "storage does not implement copy sessions",
+		}
+	}
+
+	clips, err := finder.Related(ctx, id)
+	if err != nil {
+		return nil, &ClipboardError{
+			Op:      "RelatedClips",
+			Index:   -1,
+			Message: fmt.Sprintf("failed to find clips related to %s", id),
+			Err:     err,
+		}
+	}
+	return clips, nil
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"clipboard-manager/internal/storage"
+	"clipboard-manager/pkg/types"
+	"io"
+)
+
+// sizeLimit returns the size limit set for clips of clipType, and the type
+// it was set for. The clip type itself takes precedence over the media kind,
+// which takes precedence over the major type, so image/png can be allowed
+// more than other images. Without a limit ok is false.
+func (s *ClipboardService) sizeLimit(clipType, mediaKind string) (limit int64, limitType string, ok bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	clipType = types.NormalizeType(clipType)
+	for _, candidate := range []string{clipType, mediaKind, types.MajorType(clipType)} {
+		if candidate == "" {
+			continue
+		}
+		if limit, ok := s.sizeLimits[candidate]; ok {
+			return limit, candidate, true
+		}
+	}
+	return 0, "", false
+}
+
+// checkSize returns an error if a captured clip is over the size limit of
+// its type. File clips are measured by the file they point to.
+func (s *ClipboardService) checkSize(clip types.Clip) *storage.SizeLimitError {
+	var kind string
+	if clip.Metadata.Media != nil {
+		kind = clip.Metadata.Media.Kind
+	}
+	limit, limitType, ok := s.sizeLimit(clip.Type, kind)
+	if !ok {
+		return nil
+	}
+
+	size := int64(len(clip.Content))
+	if clip.Metadata.File != nil {
+		size = clip.Metadata.File.Size
+	}
+	if limit == 0 || size > limit {
+		return &storage.SizeLimitError{Type: limitType, Size: size, Limit: limit}
+	}
+	return nil
+}
+
+// sizeLimitReader fails with a *storage.SizeLimitError once more than limit
+// bytes are read
+type sizeLimitReader struct {
+	r         io.Reader
+	remaining int64
+	err       *storage.SizeLimitError
+}
+
+// limitSize limits r to the size limit of clipType, if it has one. It fails
+// right away for types that are never kept.
+func (s *ClipboardService) limitSize(r io.Reader, clipType string) (io.Reader, error) {
+	limit, limitType, ok := s.sizeLimit(clipType, "")
+	if !ok {
+		return r, nil
+	}
+	err := &storage.SizeLimitError{Type: limitType, Size: -1, Limit: limit}
+	if limit == 0 {
+		return nil, err
+	}
+	return &sizeLimitReader{r: r, remaining: limit, err: err}, nil
+}
+
+func (l *sizeLimitReader) Read(p []byte) (int, error) {
+	if l.remaining <= 0 {
+		// Only fail if there is more to read than the limit allows
+		var probe [1]byte
+		n, err := l.r.Read(probe[:])
+		if n > 0 {
+			return 0, l.err
+		}
+		return 0, err
+	}
+	if int64(len(p)) > l.remaining {
+		p = p[:l.remaining]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	return n, err
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"clipboard-manager/internal/clipboard"
+	"clipboard-manager/internal/speech"
+	"clipboard-manager/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+)
+
+// ErrSpeakSensitive is returned when a sensitive clip is to be read aloud
+var ErrSpeakSensitive = errors.New("sensitive clips are never read aloud")
+
+// SetSpeaker replaces the native speaker, for tests and for clients that
+// read clips aloud themselves
+func (s *ClipboardService) SetSpeaker(speaker speech.Speaker) {
+	s.speaker = speaker
+}
+
+// SpeakClip reads the text of a clip aloud with the system's text-to-speech,
+// returning once it has started. Speaking another clip or StopSpeaking stops
+// it.
+func (s *ClipboardService) SpeakClip(ctx context.Context, id string) error {
+	clip, err := s.GetClipByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if clip.Metadata.ExpiresAt != nil {
+		return &ClipboardError{
+			Op:      "SpeakClip",
+			Index:   -1,
+			Message: ErrSpeakSensitive.Error(),
+			Err:     ErrSpeakSensitive,
+		}
+	}
+	text, ok := clipboard.PlainText(clip)
+	if !ok {
+		err := fmt.Errorf("%w: clip %s is %s, only text can be read aloud", storage.ErrInvalidType, clip.ID, clip.Type)
+		return &ClipboardError{
+			Op:      "SpeakClip",
+			Index:   -1,
+			Message: err.Error(),
+			Err:     err,
+		}
+	}
+
+	speaker := s.speaker
+	if speaker == nil {
+		speaker = speech.Disabled("no speaker is set")
+	}
+	if err := speaker.Check(); err != nil {
+		return &ClipboardError{
+			Op:      "SpeakClip",
+			Index:   -1,
+			Message: err.Error(),
+			Err:     err,
+		}
+	}
+	s.speak(speaker, clip.ID, text)
+	return nil
+}
+
+// StopSpeaking stops reading a clip aloud, if one is being read
+func (s *ClipboardService) StopSpeaking() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopSpeaking != nil {
+		s.stopSpeaking()
+	}
+}
+
+// speak reads text aloud in the background, after stopping what is being
+// read and waiting for it to stop, so voices never overlap
+func (s *ClipboardService) speak(speaker speech.Speaker, id, text string) {
+	s.mu.Lock()
+	if s.stopSpeaking != nil {
+		s.stopSpeaking()
+	}
+	previous := s.spoken
+	ctx, cancel := context.WithCancel(s.ctx)
+	done := make(chan struct{})
+	s.stopSpeaking, s.spoken = cancel, done
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer close(done)
+		defer cancel()
+		if previous != nil {
+			<-previous
+		}
+		if err := speaker.Speak(ctx, text); err != nil && ctx.Err() == nil {
+			log.Printf("[ERROR] Failed to read clip %s aloud: %v", id, err)
+		}
+	}()
+}
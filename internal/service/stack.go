@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrStackEmpty is returned when the paste stack has no clips left
+var ErrStackEmpty = errors.New("paste stack is empty")
+
+// StackStatus describes the paste stack
+type StackStatus struct {
+	Active    bool     `json:"active"`
+	IDs       []string `json:"ids,omitempty"`
+	Next      int      `json:"next"`      // Index in IDs of the clip the next paste copies
+	Remaining int      `json:"remaining"` // Clips not yet copied
+}
+
+// SetPasteStack starts a paste stack of the given clips, in order, and copies
+// the first one. Each call to PasteNext then copies the next, for filling in
+// forms one field at a time.
+func (s *ClipboardService) SetPasteStack(ctx context.Context, ids []string) (StackStatus, error) {
+	if len(ids) == 0 {
+		return StackStatus{}, &ClipboardError{
+			Op:      "SetPasteStack",
+			Index:   -1,
+			Message: "the paste stack needs at least one clip",
+			Err:     ErrStackEmpty,
+		}
+	}
+	for _, id := range ids {
+		if _, err := s.GetClipByID(ctx, id); err != nil {
+			return StackStatus{}, err
+		}
+	}
+
+	s.stackMu.Lock()
+	s.stack = append([]string(nil), ids...)
+	s.stackNext = 0
+	s.stackMu.Unlock()
+
+	if _, err := s.PasteNext(ctx); err != nil {
+		s.ClearPasteStack()
+		return StackStatus{}, err
+	}
+	return s.PasteStack(), nil
+}
+
+// PasteNext copies the next clip on the paste stack and returns its ID. Once
+// every clip has been copied the stack is cleared and ErrStackEmpty returned.
+func (s *ClipboardService) PasteNext(ctx context.Context) (string, error) {
+	s.stackMu.Lock()
+	defer s.stackMu.Unlock()
+	if s.stackNext >= len(s.stack) {
+		s.stack, s.stackNext = nil, 0
+		return "", &ClipboardError{
+			Op:      "PasteNext",
+			Index:   -1,
+			Message: "no clips left to paste",
+			Err:     ErrStackEmpty,
+		}
+	}
+
+	id := s.stack[s.stackNext]
+	clip, err := s.GetClipByID(ctx, id)
+	if err != nil {
+		return "", &ClipboardError{
+			Op:      "PasteNext",
+			Index:   -1,
+			Message: fmt.Sprintf("clip %s on the paste stack is gone", id),
+			Err:     err,
+		}
+	}
+	if err := s.SetClipboard(ctx, clip); err != nil {
+		return "", err
+	}
+	s.stackNext++
+	debugLog("Paste stack: copied clip %s, %d left", id, len(s.stack)-s.stackNext)
+	return id, nil
+}
+
+// ClearPasteStack stops the paste stack, leaving the clipboard as it is
+func (s *ClipboardService) ClearPasteStack() {
+	s.stackMu.Lock()
+	defer s.stackMu.Unlock()
+	s.stack, s.stackNext = nil, 0
+}
+
+// PasteStack returns the state of the paste stack
+func (s *ClipboardService) PasteStack() StackStatus {
+	s.stackMu.Lock()
+	defer s.stackMu.Unlock()
+	if len(s.stack) == 0 {
+		return StackStatus{}
+	}
+	return StackStatus{
+		Active:    true,
+		IDs:       append([]string(nil), s.stack...),
+		Next:      s.stackNext,
+		Remaining: len(s.stack) - s.stackNext,
+	}
+}
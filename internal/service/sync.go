@@ -0,0 +1,61 @@
+package service
+
+import (
+	"clipboard-manager/internal/storage"
+	"context"
+)
+
+// SyncStatus describes syncing clips to Obsidian
+type SyncStatus struct {
+	Enabled  bool                `json:"enabled"`
+	Failures []storage.SyncState `json:"failures"` // Clips that failed to sync
+}
+
+// SyncStatus reports whether clips are synced to Obsidian and which clips
+// failed to sync. Storage that doesn't track failures reports none.
+func (s *ClipboardService) SyncStatus(ctx context.Context) (*SyncStatus, error) {
+	s.mu.RLock()
+	status := &SyncStatus{Enabled: s.obsidianSync != nil, Failures: []storage.SyncState{}}
+	s.mu.RUnlock()
+
+	states, ok := s.storage().(storage.SyncStateService)
+	if !ok {
+		return status, nil
+	}
+	failures, err := states.ListSyncFailures(ctx)
+	if err != nil {
+		return nil, &ClipboardError{
+			Op:      "SyncStatus",
+			Index:   -1,
+			Message: "failed to list sync failures",
+			Err:     err,
+		}
+	}
+	if failures != nil {
+		status.Failures = failures
+	}
+	return status, nil
+}
+
+// RetrySync forgets the sync failures of every clip, so the next sync tries
+// them again, including clips given up on. It returns how many were reset.
+func (s *ClipboardService) RetrySync(ctx context.Context) (int64, error) {
+	states, ok := s.storage().(storage.SyncStateService)
+	if !ok {
+		return 0, &ClipboardError{
+			Op:      "RetrySync",
+			Index:   -1,
+			Message: "storage does not track sync failures",
+		}
+	}
+	reset, err := states.ResetSyncFailures(ctx)
+	if err != nil {
+		return 0, &ClipboardError{
+			Op:      "RetrySync",
+			Index:   -1,
+			Message: "failed to reset sync failures",
+			Err:     err,
+		}
+	}
+	return reset, nil
+}
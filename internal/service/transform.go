@@ -0,0 +1,46 @@
+package service
+
+import (
+	"clipboard-manager/internal/clipboard"
+	"clipboard-manager/internal/storage"
+	"clipboard-manager/internal/transform"
+	"clipboard-manager/pkg/types"
+	"context"
+	"fmt"
+)
+
+// TransformClip applies a transform operation to the text of a clip and puts
+// the result on the clipboard. The stored clip is left unchanged. Path is the
+// path extracted by transform.JSONPath.
+func (s *ClipboardService) TransformClip(ctx context.Context, id, op, path string) ([]byte, error) {
+	clip, err := s.GetClipByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	text, ok := clipboard.PlainText(clip)
+	if !ok {
+		return nil, &ClipboardError{
+			Op:      "TransformClip",
+			Index:   -1,
+			Message: fmt.Sprintf("clip %s is %s, transforms need text", id, clip.Type),
+			Err:     storage.ErrInvalidType,
+		}
+	}
+
+	out, err := transform.Apply(op, path, []byte(text))
+	if err != nil {
+		return nil, &ClipboardError{
+			Op:      "TransformClip",
+			Index:   -1,
+			Message: err.Error(),
+			Err:     err,
+		}
+	}
+
+	result := &types.Clip{Content: out, Type: types.TypeText}
+	if err := s.SetClipboard(ctx, result); err != nil {
+		return nil, err
+	}
+	s.notifyPasted(result)
+	return out, nil
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"clipboard-manager/internal/clipboard"
+	"clipboard-manager/internal/storage"
+	"clipboard-manager/internal/translate"
+	"clipboard-manager/pkg/types"
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// maxTranslations caps the translations rules ask for that are fetched at
+// once. Clips copied while all are busy aren't translated.
+const maxTranslations = 2
+
+// translationTimeout is how long a translation a rule asked for may take
+const translationTimeout = 30 * time.Second
+
+// TranslationTag tags clips that hold a translation of another clip
+const TranslationTag = "translation"
+
+// Translation is a translated clip
+type Translation struct {
+	Text   string      `json:"text"`
+	Source string      `json:"source,omitempty"` // Language of the original, when the backend detected it
+	Target string      `json:"target"`
+	Clip   *types.Clip `json:"clip,omitempty"` // The translation, when stored
+}
+
+// TranslateClip translates the text of a clip into target, or the language
+// in the translate settings if it is empty. The translation is stored as a
+// clip related to the original if store is set, and otherwise put on the
+// clipboard.
+func (s *ClipboardService) TranslateClip(ctx context.Context, id, target string, store bool) (*Translation, error) {
+	clip, err := s.GetClipByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	translation, err := s.translate(ctx, clip, target)
+	if err != nil {
+		return nil, &ClipboardError{
+			Op:      "TranslateClip",
+			Index:   -1,
+			Message: err.Error(),
+			Err:     err,
+		}
+	}
+
+	if store {
+		if translation.Clip, err = s.storeTranslation(ctx, clip, translation); err != nil {
+			return nil, err
+		}
+		return translation, nil
+	}
+	result := &types.Clip{Content: []byte(translation.Text), Type: types.TypeText}
+	if err := s.SetClipboard(ctx, result); err != nil {
+		return nil, err
+	}
+	s.notifyPasted(result)
+	return translation, nil
+}
+
+// translateCaptured stores a translation of a captured clip into target in
+// the background, as a rule asked for. Clips already in the target language
+// aren't translated.
+func (s *ClipboardService) translateCaptured(clip *types.Clip, target string) {
+	if target == "" {
+		return
+	}
+	select {
+	case s.translations <- struct{}{}:
+	default:
+		debugLog("Too many translations in progress, not translating clip %s", clip.ID)
+		return
+	}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer func() { <-s.translations }()
+
+		ctx, cancel := context.WithTimeout(s.ctx, translationTimeout)
+		defer cancel()
+		translation, err := s.translate(ctx, clip, target)
+		if err != nil {
+			log.Printf("[ERROR] Failed to translate clip %s: %v", clip.ID, err)
+			return
+		}
+		if translate.SameLanguage(translation.Source, target) {
+			debugLog("Clip %s is already in %s", clip.ID, target)
+			return
+		}
+		if _, err := s.storeTranslation(ctx, clip, translation); err != nil {
+			log.Printf("[ERROR] Failed to store translation of clip %s: %v", clip.ID, err)
+		}
+	}()
+}
+
+// translate translates the text of clip with the configured backend
+func (s *ClipboardService) translate(ctx context.Context, clip *types.Clip, target string) (*Translation, error) {
+	if clip.Metadata.ExpiresAt != nil {
+		return nil, translate.ErrSensitive
+	}
+	text, ok := clipboard.PlainText(clip)
+	if !ok {
+		return nil, fmt.Errorf("%w: clip %s is %s, only text can be translated", storage.ErrInvalidType, clip.ID, clip.Type)
+	}
+
+	s.mu.RLock()
+	settings := s.translateSettings
+	s.mu.RUnlock()
+	if target == "" {
+		target = settings.Target
+	}
+	if target == "" {
+		target = translate.DefaultTarget
+	}
+	translator := translate.Translator{
+		Client:  s.linkClient,
+		Backend: settings.Backend,
+		URL:     settings.URL,
+		APIKey:  settings.APIKey,
+	}
+	result, err := translator.Translate(ctx, text, target)
+	if err != nil {
+		return nil, err
+	}
+	return &Translation{Text: result.Text, Source: result.Source, Target: target}, nil
+}
+
+// storeTranslation stores translation as a clip in the copy session of the
+// original, so the two are related, tagged as a translation
+func (s *ClipboardService) storeTranslation(ctx context.Context, original *types.Clip, translation *Translation) (*types.Clip, error) {
+	metadata := types.Metadata{
+		SourceApp:      original.Metadata.SourceApp,
+		SourceBundleID: original.Metadata.SourceBundleID,
+		Tags:           []string{TranslationTag},
+		Category:       original.Metadata.Category,
+		Session:        original.Metadata.Session,
+	}
+	stored, err := s.storage().Store(ctx, []byte(translation.Text), types.TypeText, metadata)
+	if err != nil {
+		return nil, &ClipboardError{
+			Op:      "TranslateClip",
+			Index:   -1,
+			Message: "failed to store translation",
+			Err:     err,
+		}
+	}
+	return stored, nil
+}
@@ -0,0 +1,324 @@
+// Package share hands out read-only links to clips. Links are signed, expire,
+// may need a password and can be revoked; every visit is kept for audit.
+package share
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// FileName is the name of the shares file in the data directory
+const FileName = "shares.json"
+
+const (
+	DefaultTTL = 24 * time.Hour
+	MaxTTL     = 30 * 24 * time.Hour
+
+	// maxPasswordFailures locks a share after this many wrong passwords
+	maxPasswordFailures = 10
+	// maxAccesses is how many visits are kept per share, newest last
+	maxAccesses = 100
+	// keepExpired is how long expired and revoked shares stay listed
+	keepExpired = 7 * 24 * time.Hour
+)
+
+var (
+	ErrNotFound = errors.New("share not found")
+	ErrInvalid  = errors.New("invalid share link")
+	ErrExpired  = errors.New("share link has expired")
+	ErrRevoked  = errors.New("share link has been revoked")
+	ErrLocked   = errors.New("share link is locked after too many wrong passwords")
+)
+
+// Share is a link to one clip
+type Share struct {
+	ID           string     `json:"id"`
+	ClipID       string     `json:"clip_id"`
+	Profile      string     `json:"profile,omitempty"` // Profile the clip is in, empty without profiles
+	CreatedAt    time.Time  `json:"created_at"`
+	ExpiresAt    time.Time  `json:"expires_at"`
+	RevokedAt    *time.Time `json:"revoked_at,omitempty"`
+	PasswordHash []byte     `json:"password_hash,omitempty"` // bcrypt, empty without a password
+	Accesses     []Access   `json:"accesses"`
+}
+
+// Access is a visit to a share link
+type Access struct {
+	Time       time.Time `json:"time"`
+	RemoteAddr string    `json:"remote_addr"`
+	Granted    bool      `json:"granted"`
+	Reason     string    `json:"reason,omitempty"` // Why access was denied
+}
+
+// HasPassword reports whether the share needs a password
+func (s *Share) HasPassword() bool {
+	return len(s.PasswordHash) > 0
+}
+
+// failures counts wrong passwords given for the share
+func (s *Share) failures() int {
+	n := 0
+	for _, access := range s.Accesses {
+		if access.Reason == reasonPassword {
+			n++
+		}
+	}
+	return n
+}
+
+const reasonPassword = "wrong password"
+
+// file is the content of the shares file
+type file struct {
+	Secret []byte   `json:"secret"` // Key links are signed with
+	Shares []*Share `json:"shares"`
+}
+
+// Manager keeps shares in a file, which only the user can read
+type Manager struct {
+	path string
+	now  func() time.Time
+
+	mu   sync.Mutex
+	data file
+}
+
+// Open loads the shares file at path, creating it with a new signing key if
+// it doesn't exist
+func Open(path string) (*Manager, error) {
+	m := &Manager{path: path, now: time.Now}
+	content, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		m.data.Secret = make([]byte, 32)
+		if _, err := rand.Read(m.data.Secret); err != nil {
+			return nil, fmt.Errorf("failed to create signing key: %w", err)
+		}
+		if err := m.save(); err != nil {
+			return nil, err
+		}
+		return m, nil
+	case err != nil:
+		return nil, fmt.Errorf("failed to read shares: %w", err)
+	}
+
+	if err := json.Unmarshal(content, &m.data); err != nil {
+		return nil, fmt.Errorf("invalid shares file %s: %w", path, err)
+	}
+	if len(m.data.Secret) == 0 {
+		return nil, fmt.Errorf("invalid shares file %s: no signing key", path)
+	}
+
+	// Old shares are forgotten
+	kept := m.data.Shares[:0]
+	for _, share := range m.data.Shares {
+		end := share.ExpiresAt
+		if share.RevokedAt != nil && share.RevokedAt.Before(end) {
+			end = *share.RevokedAt
+		}
+		if m.now().Sub(end) < keepExpired {
+			kept = append(kept, share)
+		}
+	}
+	m.data.Shares = kept
+	return m, nil
+}
+
+// Create shares a clip of profile for ttl, protected by password unless it is
+// empty. It returns the share and the token of its link.
+func (m *Manager) Create(clipID, profile string, ttl time.Duration, password string) (*Share, string, error) {
+	if ttl <= 0 || ttl > MaxTTL {
+		return nil, "", fmt.Errorf("share duration must be between 1s and %v", MaxTTL)
+	}
+	id := make([]byte, 12)
+	if _, err := rand.Read(id); err != nil {
+		return nil, "", fmt.Errorf("failed to create share ID: %w", err)
+	}
+	now := m.now()
+	share := &Share{
+		ID:        hex.EncodeToString(id),
+		ClipID:    clipID,
+		Profile:   profile,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl).Truncate(time.Second),
+		Accesses:  []Access{},
+	}
+	if password != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to hash password: %w", err)
+		}
+		share.PasswordHash = hash
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.data.Shares = append(m.data.Shares, share)
+	if err := m.save(); err != nil {
+		m.data.Shares = m.data.Shares[:len(m.data.Shares)-1]
+		return nil, "", err
+	}
+	copied := *share
+	return &copied, m.token(share), nil
+}
+
+// token signs the ID and expiry of a share, so links can't be guessed or
+// extended
+func (m *Manager) token(share *Share) string {
+	payload := share.ID + "." + strconv.FormatInt(share.ExpiresAt.Unix(), 10)
+	return payload + "." + m.sign(payload)
+}
+
+func (m *Manager) sign(payload string) string {
+	mac := hmac.New(sha256.New, m.data.Secret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Lookup checks the token of a link and returns its share, with an error if
+// the link may no longer be used. Visits are recorded with Record.
+func (m *Manager) Lookup(token string) (*Share, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalid
+	}
+	payload := parts[0] + "." + parts[1]
+	if !hmac.Equal([]byte(parts[2]), []byte(m.sign(payload))) {
+		return nil, ErrInvalid
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	share := m.find(parts[0])
+	if share == nil {
+		return nil, ErrNotFound
+	}
+	copied := *share
+	switch {
+	case share.RevokedAt != nil:
+		return &copied, ErrRevoked
+	case !m.now().Before(share.ExpiresAt):
+		return &copied, ErrExpired
+	case share.failures() >= maxPasswordFailures:
+		return &copied, ErrLocked
+	}
+	return &copied, nil
+}
+
+// CheckPassword reports whether password opens a share. Shares without a
+// password need none.
+func CheckPassword(share *Share, password string) bool {
+	if !share.HasPassword() {
+		return true
+	}
+	return bcrypt.CompareHashAndPassword(share.PasswordHash, []byte(password)) == nil
+}
+
+// Record adds a visit to the audit log of a share
+func (m *Manager) Record(id string, access Access) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	share := m.find(id)
+	if share == nil {
+		return ErrNotFound
+	}
+	if access.Time.IsZero() {
+		access.Time = m.now()
+	}
+	share.Accesses = append(share.Accesses, access)
+	if len(share.Accesses) > maxAccesses {
+		share.Accesses = share.Accesses[len(share.Accesses)-maxAccesses:]
+	}
+	return m.save()
+}
+
+// RecordWrongPassword records a visit with a wrong password, which counts
+// towards locking the share
+func (m *Manager) RecordWrongPassword(id, remoteAddr string) error {
+	return m.Record(id, Access{RemoteAddr: remoteAddr, Reason: reasonPassword})
+}
+
+// Revoke stops a share's link from working
+func (m *Manager) Revoke(id string) (*Share, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	share := m.find(id)
+	if share == nil {
+		return nil, ErrNotFound
+	}
+	if share.RevokedAt == nil {
+		now := m.now()
+		share.RevokedAt = &now
+		if err := m.save(); err != nil {
+			share.RevokedAt = nil
+			return nil, err
+		}
+	}
+	copied := *share
+	return &copied, nil
+}
+
+// List returns every share, newest first
+func (m *Manager) List() []Share {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	shares := make([]Share, 0, len(m.data.Shares))
+	for _, share := range m.data.Shares {
+		copied := *share
+		copied.Accesses = append([]Access(nil), share.Accesses...)
+		shares = append(shares, copied)
+	}
+	sort.Slice(shares, func(i, j int) bool {
+		return shares[i].CreatedAt.After(shares[j].CreatedAt)
+	})
+	return shares
+}
+
+// find returns the share with the given ID. The caller holds mu.
+func (m *Manager) find(id string) *Share {
+	for _, share := range m.data.Shares {
+		if share.ID == id {
+			return share
+		}
+	}
+	return nil
+}
+
+// save writes the shares file atomically. The caller holds mu, except in Open.
+func (m *Manager) save() error {
+	content, err := json.MarshalIndent(m.data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode shares: %w", err)
+	}
+	temp, err := os.CreateTemp(filepath.Dir(m.path), ".shares-*.json")
+	if err != nil {
+		return fmt.Errorf("failed to save shares: %w", err)
+	}
+	defer os.Remove(temp.Name())
+	if _, err := temp.Write(content); err != nil {
+		temp.Close()
+		return fmt.Errorf("failed to save shares: %w", err)
+	}
+	if err := temp.Close(); err != nil {
+		return fmt.Errorf("failed to save shares: %w", err)
+	}
+	if err := os.Rename(temp.Name(), m.path); err != nil {
+		return fmt.Errorf("failed to save shares: %w", err)
+	}
+	return nil
+}
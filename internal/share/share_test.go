@@ -0,0 +1,125 @@
+package share
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestManager(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	m, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	now := time.Now().Truncate(time.Second)
+	m.now = func() time.Time { return now }
+
+	if _, _, err := m.Create("clip", "", MaxTTL+time.Hour, ""); err == nil {
+		t.Error("Create() accepted a duration over MaxTTL")
+	}
+
+	created, token, err := m.Create("clip", "", time.Hour, "")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if _, err := m.Lookup(token); err != nil {
+		t.Errorf("Lookup() error = %v", err)
+	}
+	if _, err := m.Lookup(token + "x"); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Lookup() with a bad signature error = %v, want ErrInvalid", err)
+	}
+	if err := m.Record(created.ID, Access{RemoteAddr: "10.0.0.1", Granted: true}); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+
+	// Shares, signing key and visits survive a restart
+	m, err = Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	m.now = func() time.Time { return now }
+	shares := m.List()
+	if len(shares) != 1 || len(shares[0].Accesses) != 1 || !shares[0].Accesses[0].Granted {
+		t.Fatalf("List() after reopening = %+v", shares)
+	}
+	if _, err := m.Lookup(token); err != nil {
+		t.Errorf("Lookup() after reopening error = %v", err)
+	}
+
+	now = now.Add(2 * time.Hour)
+	if _, err := m.Lookup(token); !errors.Is(err, ErrExpired) {
+		t.Errorf("Lookup() after expiry error = %v, want ErrExpired", err)
+	}
+
+	_, token, err = m.Create("clip", "", time.Hour, "")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	revoked, err := m.Revoke(shares[0].ID)
+	if err != nil || revoked.RevokedAt == nil {
+		t.Fatalf("Revoke() = %+v, %v", revoked, err)
+	}
+	if _, err := m.Revoke("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Revoke() of a missing share error = %v, want ErrNotFound", err)
+	}
+	if _, err := m.Lookup(token); err != nil {
+		t.Errorf("Lookup() of another share error = %v", err)
+	}
+}
+
+func TestManager_Password(t *testing.T) {
+	m, err := Open(filepath.Join(t.TempDir(), FileName))
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	created, token, err := m.Create("clip", "", time.Hour, "hunter2")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if !created.HasPassword() {
+		t.Fatal("HasPassword() = false")
+	}
+	if !CheckPassword(created, "hunter2") || CheckPassword(created, "wrong") {
+		t.Error("CheckPassword() doesn't match the password")
+	}
+
+	for i := 0; i < maxPasswordFailures; i++ {
+		if _, err := m.Lookup(token); err != nil {
+			t.Fatalf("Lookup() after %d wrong passwords error = %v", i, err)
+		}
+		if err := m.RecordWrongPassword(created.ID, "10.0.0.1"); err != nil {
+			t.Fatalf("RecordWrongPassword() error = %v", err)
+		}
+	}
+	if _, err := m.Lookup(token); !errors.Is(err, ErrLocked) {
+		t.Errorf("Lookup() error = %v, want ErrLocked", err)
+	}
+}
+
+func TestOpen_DropsOldShares(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	m, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	old := time.Now().Add(-keepExpired - 48*time.Hour)
+	m.now = func() time.Time { return old }
+	if _, _, err := m.Create("old", "", time.Hour, ""); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	m.now = time.Now
+	if _, _, err := m.Create("new", "", time.Hour, ""); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	m, err = Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	shares := m.List()
+	if len(shares) != 1 || shares[0].ClipID != "new" {
+		t.Errorf("List() = %+v, want only the new share", shares)
+	}
+}
@@ -0,0 +1,131 @@
+// Package snippet recognizes code copied to the clipboard and renders it
+// with syntax highlighting
+package snippet
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+)
+
+// maxDetectSize skips detection for text too large to be a snippet
+const maxDetectSize = 256 * 1024
+
+// Style is the chroma style used for previews
+const Style = "github"
+
+// signature lists telltale patterns of a language. Text matching at least
+// two of them, more than any other language, is taken to be in it.
+type signature struct {
+	language string
+	patterns []*regexp.Regexp
+}
+
+func patterns(exprs ...string) []*regexp.Regexp {
+	compiled := make([]*regexp.Regexp, len(exprs))
+	for i, expr := range exprs {
+		compiled[i] = regexp.MustCompile(`(?m)` + expr)
+	}
+	return compiled
+}
+
+var signatures = []signature{
+	{"go", patterns(`^package \w+$`, `\bfunc (\(\w+ \*?\w+\) )?\w+\(`, `\w+ := `, `\bif err != nil\b`, `^import \($`)},
+	{"python", patterns(`^\s*def \w+\(.*\):\s*$`, `^\s*(from [\w.]+ )?import \w+`, `^\s*class \w+(\(.*\))?:\s*$`, `\bself\.\w+`, `^\s*(elif .*|else|try|except\b.*):\s*$`, `^#!.*\bpython`)},
+	{"javascript", patterns(`\bfunction\s*\w*\s*\(`, `\b(const|let) \w+ = `, `\) => `, `\bconsole\.log\(`, `\brequire\(['"]|^import .* from ['"]`, `\bdocument\.\w+`)},
+	{"typescript", patterns(`^\s*(export )?(interface|type) \w+`, `\w+\??: (string|number|boolean|any)\b`, `^import .* from ['"]`, `\b(const|let) \w+: \w+`)},
+	{"rust", patterns(`\bfn \w+(<.*>)?\(`, `\blet mut\b`, `^\s*impl\b.*\{$`, `\bprintln!\(`, `^use \w+::`, `->\s*\w+.*\{$`)},
+	{"java", patterns(`\bpublic (static )?(final )?(class|void|int|String)\b`, `System\.out\.println`, `^import java\.`, `\bnew \w+\(.*\);$`, `@Override`)},
+	{"c", patterns(`^#include\s*[<"]\w+\.h[>"]`, `\bint main\(`, `\bprintf\(`, `\b(malloc|free|sizeof)\(`, `^#define \w+`)},
+	{"c++", patterns(`\bstd::`, `^#include <\w+>$`, `\bcout\s*<<`, `^\s*(class|struct) \w+\s*(:.*)?\{?$`, `\btemplate\s*<`)},
+	{"bash", patterns(`^#!.*\b(ba|z)?sh\b`, `^\s*(echo|export|cd|sudo|apt|brew) `, `\$\{\w+\}|\$\(\w+`, `^\s*(fi|done|esac)$`, `^\s*if \[\[? `)},
+	{"sql", patterns(`(?is)^\s*select\b.+?\bfrom\b`, `(?i)^\s*(insert into|update \w+ set|delete from|create table)\b`, `(?i)^\s*(where|group by|order by|(inner |left |right )?join)\b`)},
+	{"php", patterns(`<\?php`, `\$\w+ = `, `\becho\b.*;$`, `\bfunction \w+\(\$`)},
+	{"ruby", patterns(`^\s*def \w+(\(.*\))?$`, `^\s*end$`, `\bputs\b`, `\.each do\b`, `^require ['"]`)},
+	{"css", patterns(`^[\w.#:\-\[\]="' >,]+\s*\{$`, `^\s*[a-z-]+:\s*[^;]+;$`, `^\}$`)},
+}
+
+// codeChars are characters at least some lines of code contain
+var codeChars = regexp.MustCompile(`[{}();=<>\[\]]|^\s*#`)
+
+// Detect returns the language of text that looks like code, or "" for text
+// that doesn't. Languages are lower case chroma names, such as "go" or
+// "javascript".
+func Detect(text string) string {
+	if len(text) > maxDetectSize || !looksLikeCode(text) {
+		return ""
+	}
+
+	best, bestScore, tied := "", 0, false
+	for _, sig := range signatures {
+		score := 0
+		for _, pattern := range sig.patterns {
+			if pattern.MatchString(text) {
+				score++
+			}
+		}
+		switch {
+		case score > bestScore:
+			best, bestScore, tied = sig.language, score, false
+		case score == bestScore:
+			tied = true
+		}
+	}
+	if bestScore >= 2 && !tied {
+		return best
+	}
+
+	// Chroma recognizes a few more languages, mostly by shebang
+	if lexer := lexers.Analyse(text); lexer != nil {
+		return strings.ToLower(lexer.Config().Name)
+	}
+	return ""
+}
+
+// looksLikeCode reports whether enough lines of text have the punctuation
+// of code, so prose mentioning a keyword isn't taken for a snippet
+func looksLikeCode(text string) bool {
+	lines, code := 0, 0
+	for _, line := range strings.Split(text, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		lines++
+		if codeChars.MatchString(line) {
+			code++
+		}
+	}
+	return lines > 0 && code*3 >= lines
+}
+
+// HTML renders text as a standalone HTML page, highlighted as language. An
+// empty or unknown language renders it as plain text.
+func HTML(text, language, title string) (string, error) {
+	lexer := lexers.Get(language)
+	if language == "" || lexer == nil {
+		lexer = lexers.Fallback
+	}
+	lexer = chroma.Coalesce(lexer)
+
+	iterator, err := lexer.Tokenise(nil, text)
+	if err != nil {
+		return "", fmt.Errorf("failed to tokenize snippet: %w", err)
+	}
+
+	var buf bytes.Buffer
+	formatter := chromahtml.New(chromahtml.Standalone(true), chromahtml.WithClasses(false), chromahtml.TabWidth(4))
+	if err := formatter.Format(&buf, styles.Get(Style), iterator); err != nil {
+		return "", fmt.Errorf("failed to render snippet: %w", err)
+	}
+
+	// Chroma's page has no head, add one for the encoding and title
+	head := fmt.Sprintf("<html>\n<head><meta charset=\"utf-8\"><title>%s</title></head>\n", html.EscapeString(title))
+	return strings.Replace(buf.String(), "<html>\n", head, 1), nil
+}
@@ -0,0 +1,52 @@
+package snippet
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"package main\n\nfunc main() {\n\tx := 1\n\tif err != nil {\n\t}\n}", "go"},
+		{"import os\n\ndef main():\n    print(os.getcwd())\n", "python"},
+		{"const add = (a, b) => a + b;\nconsole.log(add(1, 2));", "javascript"},
+		{"fn main() {\n    let mut x = 5;\n    println!(\"{}\", x);\n}", "rust"},
+		{"#include <stdio.h>\nint main() {\n  printf(\"hi\");\n}", "c"},
+		{"SELECT id, name\nFROM users\nWHERE id = 1;", "sql"},
+		{"#!/bin/bash\necho \"hello ${USER}\"\nif [ -f x ]; then\n  cd /tmp\nfi", "bash"},
+		// Prose, even mentioning keywords, is not code
+		{"Let me import the photos and then we can def-initely talk.", ""},
+		{"Meeting notes\n- import budget\n- package the release", ""},
+		{"hello world", ""},
+	}
+	for _, tt := range tests {
+		if got := Detect(tt.text); got != tt.want {
+			t.Errorf("Detect(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestHTML(t *testing.T) {
+	page, err := HTML("package main\n\nfunc main() {}\n", "go", "main.go <1>")
+	if err != nil {
+		t.Fatalf("failed to render: %v", err)
+	}
+	if !strings.Contains(page, "<title>main.go &lt;1&gt;</title>") || !strings.Contains(page, `charset="utf-8"`) {
+		t.Errorf("missing head in %s", page)
+	}
+	if !strings.Contains(page, `style="`) || !strings.Contains(page, "package") {
+		t.Errorf("expected highlighted code, got %s", page)
+	}
+
+	// Unknown languages and plain text are escaped but not highlighted
+	page, err = HTML("<script>alert(1)</script>", "", "x")
+	if err != nil {
+		t.Fatalf("failed to render: %v", err)
+	}
+	if strings.Contains(page, "<script>") {
+		t.Errorf("text was not escaped: %s", page)
+	}
+}
@@ -0,0 +1,45 @@
+// Package speech reads text aloud with the system's text-to-speech
+package speech
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrUnavailable is returned where there is no way to speak, such as an
+// unsupported platform or a missing tool
+var ErrUnavailable = errors.New("text-to-speech is not available")
+
+// Speaker reads text aloud
+type Speaker interface {
+	// Check reports whether speaking can work, with what to do if it can't
+	Check() error
+	// Speak reads text aloud, returning when it is done or ctx is canceled,
+	// which stops it
+	Speak(ctx context.Context, text string) error
+}
+
+type native struct{}
+
+func (native) Check() error                                 { return check() }
+func (native) Speak(ctx context.Context, text string) error { return speak(ctx, text) }
+
+// New returns the speaker for this platform
+func New() Speaker {
+	return native{}
+}
+
+type disabled struct{ reason string }
+
+func (d disabled) Check() error {
+	return fmt.Errorf("%w: %s", ErrUnavailable, d.reason)
+}
+
+func (d disabled) Speak(context.Context, string) error { return d.Check() }
+
+// Disabled returns a speaker that always fails with ErrUnavailable for
+// reason, such as running without a desktop
+func Disabled(reason string) Speaker {
+	return disabled{reason: reason}
+}
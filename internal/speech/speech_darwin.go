@@ -0,0 +1,28 @@
+package speech
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// check finds say, which ships with macOS
+func check() error {
+	if _, err := exec.LookPath("say"); err != nil {
+		return fmt.Errorf("%w: say was not found", ErrUnavailable)
+	}
+	return nil
+}
+
+// speak reads text with say, which speaks through NSSpeechSynthesizer in the
+// voice chosen in System Settings > Accessibility > Spoken Content. The text
+// goes in on standard input, so it is never taken for options.
+func speak(ctx context.Context, text string) error {
+	cmd := exec.CommandContext(ctx, "say", "-f", "-")
+	cmd.Stdin = strings.NewReader(text)
+	if output, err := cmd.CombinedOutput(); err != nil && ctx.Err() == nil {
+		return fmt.Errorf("say failed: %w: %s", err, output)
+	}
+	return ctx.Err()
+}
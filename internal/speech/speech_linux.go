@@ -0,0 +1,41 @@
+package speech
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// check finds espeak-ng or espeak
+func check() error {
+	_, err := command(exec.LookPath)
+	return err
+}
+
+// speak reads text with espeak-ng, or espeak where only it is installed.
+// The text goes in on standard input, so it is never taken for options.
+func speak(ctx context.Context, text string) error {
+	args, err := command(exec.LookPath)
+	if err != nil {
+		return err
+	}
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stdin = strings.NewReader(text)
+	if output, err := cmd.CombinedOutput(); err != nil && ctx.Err() == nil {
+		return fmt.Errorf("%s failed: %w: %s", args[0], err, output)
+	}
+	return ctx.Err()
+}
+
+// command returns the command that reads standard input aloud. espeak-ng
+// is the maintained fork, and distributions link espeak to it or ship the
+// original.
+func command(lookPath func(string) (string, error)) ([]string, error) {
+	for _, tool := range []string{"espeak-ng", "espeak"} {
+		if _, err := lookPath(tool); err == nil {
+			return []string{tool, "--stdin"}, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: install espeak-ng", ErrUnavailable)
+}
@@ -0,0 +1,59 @@
+package speech
+
+import (
+	"errors"
+	"os/exec"
+	"reflect"
+	"testing"
+)
+
+func TestCommand(t *testing.T) {
+	tests := []struct {
+		name      string
+		installed []string
+		want      []string
+		wantErr   bool
+	}{
+		{
+			name:      "espeak-ng",
+			installed: []string{"espeak", "espeak-ng"},
+			want:      []string{"espeak-ng", "--stdin"},
+		},
+		{
+			name:      "espeak",
+			installed: []string{"espeak"},
+			want:      []string{"espeak", "--stdin"},
+		},
+		{
+			name:    "neither",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lookPath := func(file string) (string, error) {
+				for _, installed := range tt.installed {
+					if file == installed {
+						return "/usr/bin/" + file, nil
+					}
+				}
+				return "", exec.ErrNotFound
+			}
+
+			got, err := command(lookPath)
+			if tt.wantErr {
+				if !errors.Is(err, ErrUnavailable) {
+					t.Errorf("command() error = %v, want ErrUnavailable", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("command() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("command() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,17 @@
+//go:build !darwin && !linux
+
+package speech
+
+import (
+	"context"
+	"fmt"
+)
+
+// check fails: there is no text-to-speech tool we know of here
+func check() error {
+	return fmt.Errorf("%w on this platform", ErrUnavailable)
+}
+
+func speak(context.Context, string) error {
+	return check()
+}
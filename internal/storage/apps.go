@@ -0,0 +1,20 @@
+package storage
+
+import "context"
+
+// AppInfo describes a source application seen in the clipboard history
+type AppInfo struct {
+	BundleID string
+	Name     string
+	Count    int64 // Number of clips copied from this app
+	HasIcon  bool
+}
+
+// AppService defines the interface for querying source applications
+type AppService interface {
+	// ListApps returns the distinct source applications with clip counts
+	ListApps(ctx context.Context) ([]AppInfo, error)
+
+	// GetAppIcon returns the cached PNG icon for an application
+	GetAppIcon(ctx context.Context, bundleID string) ([]byte, error)
+}
@@ -0,0 +1,151 @@
+package bolt
+
+import (
+	"clipboard-manager/internal/storage"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	bbolt "go.etcd.io/bbolt"
+)
+
+// getBlob returns the reference record for an external file, or nil
+func getBlob(tx *bbolt.Tx, hash string) (*storage.BlobModel, error) {
+	data := tx.Bucket(blobsBucket).Get([]byte(hash))
+	if data == nil {
+		return nil, nil
+	}
+	var blob storage.BlobModel
+	if err := json.Unmarshal(data, &blob); err != nil {
+		return nil, fmt.Errorf("failed to decode blob: %w", err)
+	}
+	return &blob, nil
+}
+
+// putBlob stores the reference record for an external file
+func putBlob(tx *bbolt.Tx, blob *storage.BlobModel) error {
+	data, err := json.Marshal(blob)
+	if err != nil {
+		return fmt.Errorf("failed to encode blob: %w", err)
+	}
+	return tx.Bucket(blobsBucket).Put([]byte(blob.Hash), data)
+}
+
+// acquireBlob takes a reference to the external file for content, writing the
+// file only if no clip holds it yet or it has gone missing
+func (s *BoltStorage) acquireBlob(tx *bbolt.Tx, content *storage.SpooledContent) error {
+	path := filepath.Join(s.fsPath, content.Hash)
+
+	blob, err := getBlob(tx, content.Hash)
+	if err != nil {
+		return err
+	}
+	if blob == nil {
+		blob = &storage.BlobModel{Hash: content.Hash, Size: content.Size, CreatedAt: time.Now()}
+	}
+
+	if _, err := os.Stat(path); blob.RefCount == 0 || os.IsNotExist(err) {
+		if err := content.MoveTo(path); err != nil {
+			return fmt.Errorf("failed to write file: %w", err)
+		}
+	}
+
+	blob.RefCount++
+	return putBlob(tx, blob)
+}
+
+// releaseBlob drops a reference to an external file, reporting whether no
+// clip refers to it any more. The file is left for removeBlobFiles once the
+// transaction has committed, so rolling it back doesn't lose the file.
+func (s *BoltStorage) releaseBlob(tx *bbolt.Tx, hash string) (bool, error) {
+	blob, err := getBlob(tx, hash)
+	if err != nil || blob == nil {
+		return false, err
+	}
+
+	blob.RefCount--
+	if blob.RefCount > 0 {
+		return false, putBlob(tx, blob)
+	}
+	if err := tx.Bucket(blobsBucket).Delete([]byte(hash)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// removeBlobFiles deletes the files of blobs released by a committed
+// transaction, unless a clip has taken the file again since. A file that
+// can't be removed is left for garbage collection.
+func (s *BoltStorage) removeBlobFiles(hashes []string) {
+	for _, hash := range hashes {
+		var held bool
+		s.db.View(func(tx *bbolt.Tx) error {
+			blob, err := getBlob(tx, hash)
+			held = err != nil || blob != nil
+			return nil
+		})
+		if !held {
+			os.Remove(filepath.Join(s.fsPath, hash))
+		}
+	}
+}
+
+// repairBlobs recomputes blob reference counts from the given clips. This also
+// adopts files stored before reference counting existed.
+func repairBlobs(tx *bbolt.Tx, clips []*storage.ClipModel, dryRun bool) (int, error) {
+	counts := make(map[string]*storage.BlobModel)
+	for _, model := range clips {
+		blob, ok := counts[model.StoragePath]
+		if !ok {
+			blob = &storage.BlobModel{Hash: model.StoragePath, Size: model.Size, CreatedAt: model.CreatedAt}
+			counts[model.StoragePath] = blob
+		}
+		blob.RefCount++
+	}
+
+	var stale [][]byte
+	repaired := 0
+	err := tx.Bucket(blobsBucket).ForEach(func(k, v []byte) error {
+		if _, ok := counts[string(k)]; !ok {
+			// Nothing refers to it; the file is collected as an orphan
+			stale = append(stale, k)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, blob := range counts {
+		existing, err := getBlob(tx, blob.Hash)
+		if err != nil {
+			return 0, err
+		}
+		if existing != nil && existing.RefCount == blob.RefCount {
+			continue
+		}
+		repaired++
+		if dryRun {
+			continue
+		}
+		if existing != nil {
+			blob.CreatedAt = existing.CreatedAt
+		}
+		if err := putBlob(tx, blob); err != nil {
+			return 0, err
+		}
+	}
+
+	repaired += len(stale)
+	if !dryRun {
+		for _, k := range stale {
+			if err := tx.Bucket(blobsBucket).Delete(k); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	return repaired, nil
+}
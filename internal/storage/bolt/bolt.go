@@ -0,0 +1,729 @@
+package bolt
+
+import (
+	"bytes"
+	"clipboard-manager/internal/storage"
+	"clipboard-manager/pkg/types"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	bbolt "go.etcd.io/bbolt"
+	"gorm.io/gorm"
+)
+
+func init() {
+	storage.Register("bolt", func(config storage.Config) (storage.Storage, error) {
+		return New(config)
+	})
+}
+
+// Bucket layout:
+//
+//	clips:     id -> JSON encoded storage.ClipModel (without content)
+//	content:   id -> inline content
+//	hashes:    content hash -> id, for deduplication
+//	last_used: last used (unix nanos) + id -> nil, ordered index for listing
+//	apps:      bundle ID -> JSON encoded storage.AppModel
+//	blobs:     content hash -> JSON encoded storage.BlobModel, external file references
+//	trash:     deleted at (unix nanos) + id -> nil, ordered index of deleted clips
+//	expires:   expires at (unix nanos) + id -> nil, ordered index of expiring clips
+//	versions:  id + version -> JSON encoded storage.VersionModel, content before edits
+//	normalized: normalized hash -> id of the clip with it stored last
+//
+// Deleted clips stay in the clips bucket with DeletedAt set but are removed
+// from the last_used index until they are restored. They keep their hashes
+// entry, so storing the same content again restores them.
+var (
+	clipsBucket      = []byte("clips")
+	contentBucket    = []byte("content")
+	hashesBucket     = []byte("hashes")
+	lastUsedBucket   = []byte("last_used")
+	appsBucket       = []byte("apps")
+	blobsBucket      = []byte("blobs")
+	trashBucket      = []byte("trash")
+	expiresBucket    = []byte("expires")
+	versionsBucket   = []byte("versions")
+	normalizedBucket = []byte("normalized")
+)
+
+// ErrNotFound is returned when a clip does not exist
+var ErrNotFound = storage.ErrNotFound
+
+// BoltStorage is a pure-Go embedded storage backend built on bbolt, for
+// builds without CGO
+type BoltStorage struct {
+	db     *bbolt.DB
+	fsPath string // Base path for file system storage
+}
+
+// New creates a new bolt storage instance
+func New(config storage.Config) (*BoltStorage, error) {
+	path := config.DBPath
+	if path == "" {
+		path = config.DSN
+	}
+	if path == "" {
+		return nil, fmt.Errorf("database path is required")
+	}
+
+	db, err := bbolt.Open(path, 0600, &bbolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	if err := db.Update(func(tx *bbolt.Tx) error {
+		for _, name := range [][]byte{clipsBucket, contentBucket, hashesBucket, lastUsedBucket, appsBucket, blobsBucket, trashBucket, expiresBucket, versionsBucket, normalizedBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create buckets: %w", err)
+	}
+
+	if err := db.Update(normalizeTypes); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate clip types: %w", err)
+	}
+
+	if err := os.MkdirAll(config.FSPath, 0755); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create storage directory: %w", err)
+	}
+
+	return &BoltStorage{
+		db:     db,
+		fsPath: config.FSPath,
+	}, nil
+}
+
+// normalizeTypes renames the types of clips and versions that older
+// versions stored under other names, such as "text", to the names
+// types.NormalizeType gives them
+func normalizeTypes(tx *bbolt.Tx) error {
+	if err := normalizeBucketTypes(tx.Bucket(clipsBucket), func(m *storage.ClipModel) *string { return &m.Type }); err != nil {
+		return err
+	}
+	return normalizeBucketTypes(tx.Bucket(versionsBucket), func(v *storage.VersionModel) *string { return &v.Type })
+}
+
+// normalizeBucketTypes normalizes the type of each record of type T in
+// bucket, typeOf returning a pointer to a record's type
+func normalizeBucketTypes[T any](bucket *bbolt.Bucket, typeOf func(*T) *string) error {
+	updates := make(map[string][]byte)
+	err := bucket.ForEach(func(k, v []byte) error {
+		var record T
+		if err := json.Unmarshal(v, &record); err != nil {
+			return fmt.Errorf("failed to decode record: %w", err)
+		}
+		clipType := typeOf(&record)
+		if canonical := types.NormalizeType(*clipType); canonical != *clipType {
+			*clipType = canonical
+			data, err := json.Marshal(record)
+			if err != nil {
+				return err
+			}
+			updates[string(k)] = data
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	// Buckets can't be changed while iterating over them
+	for k, data := range updates {
+		if err := bucket.Put([]byte(k), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes the database
+func (s *BoltStorage) Close() error {
+	if err := s.db.Close(); err != nil {
+		return fmt.Errorf("failed to close database: %w", err)
+	}
+	return nil
+}
+
+// calculateHash generates SHA-256 hash of content
+func calculateHash(content []byte) string {
+	hash := sha256.Sum256(content)
+	return hex.EncodeToString(hash[:])
+}
+
+// idKey encodes a clip ID as a sortable key
+func idKey(id uint) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(id))
+	return key
+}
+
+// parseID converts a public clip ID to a key
+func parseID(id string) ([]byte, error) {
+	n, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid clip id %q: %w", id, err)
+	}
+	return idKey(uint(n)), nil
+}
+
+// timeKey builds an index key ordering clips by a timestamp such as last use
+func timeKey(t time.Time, id uint) []byte {
+	key := make([]byte, 16)
+	binary.BigEndian.PutUint64(key, uint64(t.UnixNano()))
+	binary.BigEndian.PutUint64(key[8:], uint64(id))
+	return key
+}
+
+// getModel loads a clip record without its content. Clips in the trash are
+// reported as not found.
+func getModel(tx *bbolt.Tx, key []byte) (*storage.ClipModel, error) {
+	model, err := getAnyModel(tx, key)
+	if err != nil {
+		return nil, err
+	}
+	if model.DeletedAt.Valid {
+		return nil, ErrNotFound
+	}
+	return model, nil
+}
+
+// getAnyModel loads a clip record whether or not it is in the trash
+func getAnyModel(tx *bbolt.Tx, key []byte) (*storage.ClipModel, error) {
+	data := tx.Bucket(clipsBucket).Get(key)
+	if data == nil {
+		return nil, ErrNotFound
+	}
+	var model storage.ClipModel
+	if err := json.Unmarshal(data, &model); err != nil {
+		return nil, fmt.Errorf("failed to decode clip: %w", err)
+	}
+	return &model, nil
+}
+
+// putModel saves a clip record, keeping the last used index in sync
+func putModel(tx *bbolt.Tx, model *storage.ClipModel, previousLastUsed time.Time) error {
+	data, err := json.Marshal(model)
+	if err != nil {
+		return fmt.Errorf("failed to encode clip: %w", err)
+	}
+	if err := tx.Bucket(clipsBucket).Put(idKey(model.ID), data); err != nil {
+		return err
+	}
+
+	index := tx.Bucket(lastUsedBucket)
+	if !previousLastUsed.IsZero() {
+		if err := index.Delete(timeKey(previousLastUsed, model.ID)); err != nil {
+			return err
+		}
+	}
+	return index.Put(timeKey(model.LastUsed, model.ID), nil)
+}
+
+// loadContent fills in the content of a clip from the content bucket or filesystem
+func (s *BoltStorage) loadContent(tx *bbolt.Tx, model *storage.ClipModel) error {
+	if model.IsExternal {
+		content, err := os.ReadFile(filepath.Join(s.fsPath, model.StoragePath))
+		if err != nil {
+			return fmt.Errorf("failed to read external content for clip %d: %w", model.ID, err)
+		}
+		model.Content = content
+		return nil
+	}
+	if data := tx.Bucket(contentBucket).Get(idKey(model.ID)); data != nil {
+		model.Content = bytes.Clone(data)
+	}
+	return nil
+}
+
+// Store implements storage.Storage interface
+func (s *BoltStorage) Store(ctx context.Context, content []byte, clipType string, metadata types.Metadata) (*types.Clip, error) {
+	size := int64(len(content))
+	if size > storage.MaxStorageSize {
+		return nil, storage.ErrFileTooLarge
+	}
+
+	return s.storeContent(&storage.SpooledContent{
+		Hash: calculateHash(content),
+		Size: size,
+		Data: content,
+	}, clipType, metadata)
+}
+
+// StoreStream implements storage.Storage interface
+func (s *BoltStorage) StoreStream(ctx context.Context, r io.Reader, clipType string, metadata types.Metadata) (*types.Clip, error) {
+	spooled, err := storage.Spool(r, s.fsPath)
+	if err != nil {
+		return nil, err
+	}
+	defer spooled.Discard()
+
+	return s.storeContent(spooled, clipType, metadata)
+}
+
+// storeContent saves content that has already been hashed and size checked
+func (s *BoltStorage) storeContent(content *storage.SpooledContent, clipType string, metadata types.Metadata) (*types.Clip, error) {
+	clipType = types.NormalizeType(clipType)
+	size := content.Size
+	contentHash := content.Hash
+	now := time.Now()
+	var clip *types.Clip
+
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		if err := saveApp(tx, metadata); err != nil {
+			return err
+		}
+
+		// Check for existing content with same hash
+		if id := tx.Bucket(hashesBucket).Get([]byte(contentHash)); id != nil {
+			existing, err := getAnyModel(tx, id)
+			if err != nil {
+				return err
+			}
+			if existing.DeletedAt.Valid {
+				if err := tx.Bucket(trashBucket).Delete(timeKey(existing.DeletedAt.Time, existing.ID)); err != nil {
+					return err
+				}
+				existing.DeletedAt = gorm.DeletedAt{}
+			}
+			previous := existing.LastUsed
+			existing.LastUsed = now
+			existing.UseCount = existing.Uses() + 1
+			existing.UpdatedAt = now
+			for format, data := range metadata.Formats {
+				if existing.Formats == nil {
+					existing.Formats = storage.FormatMap{}
+				}
+				if _, ok := existing.Formats[format]; !ok {
+					existing.Formats[format] = data
+				}
+			}
+			if metadata.File != nil {
+				existing.File = metadata.File
+			}
+			// and which copy session it was copied in
+			if metadata.Session != "" {
+				existing.Session = metadata.Session
+			}
+			// Clips from before normalized hashes get one
+			if existing.NormalizedHash == "" {
+				existing.NormalizedHash = storage.NormalizedHash(content.Data, existing.Type)
+			}
+			if err := indexNormalized(tx, existing); err != nil {
+				return err
+			}
+			// The most recent copy decides whether the clip expires
+			if err := indexExpiry(tx, existing, metadata.ExpiresAt); err != nil {
+				return err
+			}
+			if err := putModel(tx, existing, previous); err != nil {
+				return fmt.Errorf("failed to update existing clip: %w", err)
+			}
+			clip = existing.ToClip()
+			return nil
+		}
+
+		seq, err := tx.Bucket(clipsBucket).NextSequence()
+		if err != nil {
+			return err
+		}
+
+		model := &storage.ClipModel{
+			ContentHash:    contentHash,
+			Type:           clipType,
+			Size:           size,
+			SourceApp:      metadata.SourceApp,
+			SourceBundleID: metadata.SourceBundleID,
+			SourceURL:      metadata.SourceURL,
+			SourceTitle:    metadata.SourceTitle,
+			SourceWindow:   metadata.SourceWindow,
+			Device:         metadata.Device,
+			Category:       metadata.Category,
+			Tags:           metadata.Tags,
+			Formats:        metadata.Formats,
+			PlainText:      string(metadata.Formats[storage.FormatPlainText]),
+			Screenshot:     metadata.Screenshot,
+			Link:           metadata.Link,
+			Language:       metadata.Language,
+			Media:          metadata.Media,
+			MediaType:      storage.MediaType(metadata.Media),
+			File:           metadata.File,
+			LastUsed:       now,
+			UseCount:       1,
+			UUID:           storage.NewUUID(),
+			NormalizedHash: storage.NormalizedHash(content.Data, clipType),
+			Session:        metadata.Session,
+		}
+		model.ID = uint(seq)
+		model.CreatedAt = now
+		model.UpdatedAt = now
+
+		if size > storage.MaxInlineStorageSize {
+			// Store in filesystem, sharing the file with any clip that has the same content
+			if err := s.acquireBlob(tx, content); err != nil {
+				return err
+			}
+			model.StoragePath = contentHash
+			model.IsExternal = true
+		} else if err := tx.Bucket(contentBucket).Put(idKey(model.ID), content.Data); err != nil {
+			return err
+		}
+
+		if err := tx.Bucket(hashesBucket).Put([]byte(contentHash), idKey(model.ID)); err != nil {
+			return err
+		}
+		if err := indexNormalized(tx, model); err != nil {
+			return err
+		}
+		if err := indexExpiry(tx, model, metadata.ExpiresAt); err != nil {
+			return err
+		}
+		if err := putModel(tx, model, time.Time{}); err != nil {
+			return fmt.Errorf("failed to create clip: %w", err)
+		}
+
+		model.Content = content.Data
+		clip = model.ToClip()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return clip, nil
+}
+
+// Get implements storage.Storage interface
+func (s *BoltStorage) Get(ctx context.Context, id string) (*types.Clip, error) {
+	key, err := parseID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var clip *types.Clip
+	err = s.db.Update(func(tx *bbolt.Tx) error {
+		model, err := getModel(tx, key)
+		if err != nil {
+			return err
+		}
+
+		// Update LastUsed timestamp
+		previous := model.LastUsed
+		model.LastUsed = time.Now()
+		if err := putModel(tx, model, previous); err != nil {
+			return fmt.Errorf("failed to update last used time: %w", err)
+		}
+
+		if err := s.loadContent(tx, model); err != nil {
+			return err
+		}
+		clip = model.ToClip()
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get clip: %w", err)
+	}
+	return clip, nil
+}
+
+// GetStream implements storage.Storage interface
+func (s *BoltStorage) GetStream(ctx context.Context, id string) (io.ReadCloser, *types.Clip, error) {
+	key, err := parseID(id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		reader io.ReadCloser
+		clip   *types.Clip
+	)
+	err = s.db.Update(func(tx *bbolt.Tx) error {
+		model, err := getModel(tx, key)
+		if err != nil {
+			return err
+		}
+
+		// Update LastUsed timestamp
+		previous := model.LastUsed
+		model.LastUsed = time.Now()
+		if err := putModel(tx, model, previous); err != nil {
+			return fmt.Errorf("failed to update last used time: %w", err)
+		}
+
+		if model.IsExternal {
+			file, err := os.Open(filepath.Join(s.fsPath, model.StoragePath))
+			if err != nil {
+				return fmt.Errorf("failed to open external content: %w", err)
+			}
+			reader = file
+		} else {
+			// Bolt values are only valid inside the transaction
+			data := bytes.Clone(tx.Bucket(contentBucket).Get(key))
+			reader = io.NopCloser(bytes.NewReader(data))
+		}
+		clip = model.ToClip()
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get clip: %w", err)
+	}
+	return reader, clip, nil
+}
+
+// Delete implements storage.Storage interface
+func (s *BoltStorage) Delete(ctx context.Context, id string) error {
+	key, err := parseID(id)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		model, err := getModel(tx, key)
+		if err != nil {
+			return fmt.Errorf("failed to get clip: %w", err)
+		}
+		return trashModel(tx, model)
+	})
+}
+
+// trashModel moves a clip to the trash; its content is kept until purged
+func trashModel(tx *bbolt.Tx, model *storage.ClipModel) error {
+	if err := tx.Bucket(lastUsedBucket).Delete(timeKey(model.LastUsed, model.ID)); err != nil {
+		return err
+	}
+
+	model.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	data, err := json.Marshal(model)
+	if err != nil {
+		return fmt.Errorf("failed to encode clip: %w", err)
+	}
+	if err := tx.Bucket(clipsBucket).Put(idKey(model.ID), data); err != nil {
+		return err
+	}
+	return tx.Bucket(trashBucket).Put(timeKey(model.DeletedAt.Time, model.ID), nil)
+}
+
+// unindexHash removes the deduplication entry for a clip, unless another
+// clip with the same content owns it
+func unindexHash(tx *bbolt.Tx, model *storage.ClipModel) error {
+	hashes := tx.Bucket(hashesBucket)
+	if id := hashes.Get([]byte(model.ContentHash)); id != nil && bytes.Equal(id, idKey(model.ID)) {
+		return hashes.Delete([]byte(model.ContentHash))
+	}
+	return nil
+}
+
+// deleteModel permanently removes a clip and its index entries
+func deleteModel(tx *bbolt.Tx, model *storage.ClipModel) error {
+	key := idKey(model.ID)
+	if err := tx.Bucket(clipsBucket).Delete(key); err != nil {
+		return err
+	}
+	if err := tx.Bucket(contentBucket).Delete(key); err != nil {
+		return err
+	}
+	if err := unindexHash(tx, model); err != nil {
+		return err
+	}
+	if err := unindexNormalized(tx, model); err != nil {
+		return err
+	}
+	if err := deleteVersions(tx, model.ID); err != nil {
+		return err
+	}
+	if err := indexExpiry(tx, model, nil); err != nil {
+		return err
+	}
+	if model.DeletedAt.Valid {
+		return tx.Bucket(trashBucket).Delete(timeKey(model.DeletedAt.Time, model.ID))
+	}
+	return tx.Bucket(lastUsedBucket).Delete(timeKey(model.LastUsed, model.ID))
+}
+
+// scanByLastUsed calls fn for every clip ordered by last use until fn returns false
+func scanByLastUsed(tx *bbolt.Tx, ascending bool, fn func(model *storage.ClipModel) (bool, error)) error {
+	c := tx.Bucket(lastUsedBucket).Cursor()
+
+	first, next := c.Last, c.Prev
+	if ascending {
+		first, next = c.First, c.Next
+	}
+
+	k, _ := first()
+	return visitLastUsed(tx, k, next, fn)
+}
+
+// scanByLastUsedBefore is scanByLastUsed, newest first, starting with the
+// clip used just before the last_used index entry key
+func scanByLastUsedBefore(tx *bbolt.Tx, key []byte, fn func(model *storage.ClipModel) (bool, error)) error {
+	c := tx.Bucket(lastUsedBucket).Cursor()
+
+	k, _ := c.Seek(key)
+	if k == nil {
+		k, _ = c.Last()
+	} else {
+		k, _ = c.Prev()
+	}
+	return visitLastUsed(tx, k, c.Prev, fn)
+}
+
+// visitLastUsed calls fn for the clip of each last_used index entry from k on
+func visitLastUsed(tx *bbolt.Tx, k []byte, next func() ([]byte, []byte), fn func(model *storage.ClipModel) (bool, error)) error {
+	for ; k != nil; k, _ = next() {
+		model, err := getModel(tx, k[8:])
+		if err != nil {
+			return err
+		}
+		more, err := fn(model)
+		if err != nil {
+			return err
+		}
+		if !more {
+			return nil
+		}
+	}
+	return nil
+}
+
+// hasTags reports whether a clip has all the given tags
+func hasTags(model *storage.ClipModel, tags []string) bool {
+	for _, tag := range tags {
+		found := false
+		for _, t := range model.Tags {
+			if t == tag {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// List implements storage.Storage interface
+func (s *BoltStorage) List(ctx context.Context, filter storage.ListFilter) ([]*types.Clip, error) {
+	var clips []*types.Clip
+	skipped := 0
+
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		return scanByLastUsed(tx, false, func(model *storage.ClipModel) (bool, error) {
+			if filter.Type != "" && model.Type != types.NormalizeType(filter.Type) {
+				return true, nil
+			}
+			if filter.Category != "" && model.Category != filter.Category {
+				return true, nil
+			}
+			if !hasTags(model, filter.Tags) {
+				return true, nil
+			}
+			if filter.SyncedToObsidian != nil && model.SyncedToObsidian != *filter.SyncedToObsidian {
+				return true, nil
+			}
+			if skipped < filter.Offset {
+				skipped++
+				return true, nil
+			}
+
+			if err := s.loadContent(tx, model); err != nil {
+				return false, err
+			}
+			clips = append(clips, model.ToClip())
+			return filter.Limit <= 0 || len(clips) < filter.Limit, nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list clips: %w", err)
+	}
+	return clips, nil
+}
+
+// MarkAsSynced implements storage.Storage interface
+func (s *BoltStorage) MarkAsSynced(ctx context.Context, id string) error {
+	key, err := parseID(id)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		model, err := getModel(tx, key)
+		if err != nil {
+			return fmt.Errorf("no clip found with id: %s", id)
+		}
+		model.SyncedToObsidian = true
+		return putModel(tx, model, model.LastUsed)
+	})
+}
+
+// ListUnsynced implements storage.Storage interface
+func (s *BoltStorage) ListUnsynced(ctx context.Context, limit int) ([]*types.Clip, error) {
+	var clips []*types.Clip
+
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		// IDs are assigned in creation order, so walk them newest first
+		c := tx.Bucket(clipsBucket).Cursor()
+		for k, _ := c.Last(); k != nil; k, _ = c.Prev() {
+			model, err := getAnyModel(tx, k)
+			if err != nil {
+				return err
+			}
+			// Sensitive clips never leave the machine
+			if model.SyncedToObsidian || model.DeletedAt.Valid || model.ExpiresAt != nil {
+				continue
+			}
+			if err := s.loadContent(tx, model); err != nil {
+				return err
+			}
+			clips = append(clips, model.ToClip())
+			if limit > 0 && len(clips) >= limit {
+				return nil
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list unsynced clips: %w", err)
+	}
+	return clips, nil
+}
+
+// saveApp records the source application of a clip, keeping the first icon we get
+func saveApp(tx *bbolt.Tx, metadata types.Metadata) error {
+	if metadata.SourceBundleID == "" {
+		return nil
+	}
+
+	bucket := tx.Bucket(appsBucket)
+	var app storage.AppModel
+	if data := bucket.Get([]byte(metadata.SourceBundleID)); data != nil {
+		if err := json.Unmarshal(data, &app); err != nil {
+			return fmt.Errorf("failed to decode app: %w", err)
+		}
+	}
+
+	app.BundleID = metadata.SourceBundleID
+	app.Name = metadata.SourceApp
+	app.UpdatedAt = time.Now()
+	if len(metadata.SourceIcon) > 0 {
+		app.Icon = metadata.SourceIcon
+	}
+
+	data, err := json.Marshal(app)
+	if err != nil {
+		return fmt.Errorf("failed to encode app: %w", err)
+	}
+	return bucket.Put([]byte(app.BundleID), data)
+}
@@ -0,0 +1,585 @@
+package bolt
+
+import (
+	"clipboard-manager/internal/storage"
+	"clipboard-manager/pkg/types"
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	bbolt "go.etcd.io/bbolt"
+)
+
+func setupTestDB(t *testing.T) *BoltStorage {
+	tempDir := t.TempDir()
+	store, err := New(storage.Config{
+		DBPath: filepath.Join(tempDir, "test.db"),
+		FSPath: filepath.Join(tempDir, "files"),
+	})
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestStore_BasicOperations(t *testing.T) {
+	store := setupTestDB(t)
+	ctx := context.Background()
+
+	clip, err := store.Store(ctx, []byte("test content"), storage.TypeText, types.Metadata{
+		SourceApp: "test",
+		Tags:      []string{"test"},
+	})
+	if err != nil {
+		t.Fatalf("failed to store clip: %v", err)
+	}
+
+	retrieved, err := store.Get(ctx, clip.ID)
+	if err != nil {
+		t.Fatalf("failed to get clip: %v", err)
+	}
+	if string(retrieved.Content) != "test content" {
+		t.Errorf("content mismatch: got %s", retrieved.Content)
+	}
+
+	clips, err := store.List(ctx, storage.ListFilter{Tags: []string{"test"}, Limit: 10})
+	if err != nil {
+		t.Fatalf("failed to list clips: %v", err)
+	}
+	if len(clips) != 1 {
+		t.Errorf("expected 1 clip, got %d", len(clips))
+	}
+
+	if err := store.Delete(ctx, clip.ID); err != nil {
+		t.Fatalf("failed to delete clip: %v", err)
+	}
+	if _, err := store.Get(ctx, clip.ID); err == nil {
+		t.Error("expected error getting deleted clip")
+	}
+}
+
+func TestNormalizeTypes(t *testing.T) {
+	tempDir := t.TempDir()
+	config := storage.Config{
+		DBPath: filepath.Join(tempDir, "test.db"),
+		FSPath: filepath.Join(tempDir, "files"),
+	}
+	store, err := New(config)
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	ctx := context.Background()
+	clip, err := store.Store(ctx, []byte("old style"), types.TypeText, types.Metadata{})
+	if err != nil {
+		t.Fatalf("failed to store clip: %v", err)
+	}
+
+	// Rewrite the clip as older versions stored it
+	key, _ := parseID(clip.ID)
+	if err := store.db.Update(func(tx *bbolt.Tx) error {
+		model, err := getModel(tx, key)
+		if err != nil {
+			return err
+		}
+		model.Type = "text"
+		return putModel(tx, model, model.LastUsed)
+	}); err != nil {
+		t.Fatalf("failed to rewrite clip: %v", err)
+	}
+	store.Close()
+
+	if store, err = New(config); err != nil {
+		t.Fatalf("failed to reopen storage: %v", err)
+	}
+	defer store.Close()
+	retrieved, err := store.Get(ctx, clip.ID)
+	if err != nil {
+		t.Fatalf("failed to get clip: %v", err)
+	}
+	if retrieved.Type != types.TypeText {
+		t.Errorf("type = %q, want %q", retrieved.Type, types.TypeText)
+	}
+	clips, err := store.List(ctx, storage.ListFilter{Type: "text"})
+	if err != nil {
+		t.Fatalf("failed to list clips: %v", err)
+	}
+	if len(clips) != 1 {
+		t.Errorf("expected 1 text clip, got %d", len(clips))
+	}
+}
+
+func TestStore_DeduplicationAndOrdering(t *testing.T) {
+	store := setupTestDB(t)
+	ctx := context.Background()
+
+	first, err := store.Store(ctx, []byte("first"), storage.TypeText, types.Metadata{})
+	if err != nil {
+		t.Fatalf("failed to store clip: %v", err)
+	}
+	time.Sleep(time.Millisecond)
+	if _, err := store.Store(ctx, []byte("second"), storage.TypeText, types.Metadata{}); err != nil {
+		t.Fatalf("failed to store clip: %v", err)
+	}
+	time.Sleep(time.Millisecond)
+
+	// Copying the first clip again moves it to the top without duplicating it
+	again, err := store.Store(ctx, []byte("first"), storage.TypeText, types.Metadata{})
+	if err != nil {
+		t.Fatalf("failed to store clip: %v", err)
+	}
+	if again.ID != first.ID {
+		t.Errorf("deduplication failed: got %s, want %s", again.ID, first.ID)
+	}
+
+	clips, err := store.List(ctx, storage.ListFilter{})
+	if err != nil {
+		t.Fatalf("failed to list clips: %v", err)
+	}
+	if len(clips) != 2 || string(clips[0].Content) != "first" {
+		t.Errorf("expected 2 clips with %q first, got %d", "first", len(clips))
+	}
+}
+
+func TestSearch(t *testing.T) {
+	store := setupTestDB(t)
+	ctx := context.Background()
+
+	if _, err := store.Store(ctx, []byte("Hello World"), storage.TypeText, types.Metadata{SourceURL: "https://example.com"}); err != nil {
+		t.Fatalf("failed to store clip: %v", err)
+	}
+	if _, err := store.Store(ctx, []byte("goodbye"), storage.TypeText, types.Metadata{}); err != nil {
+		t.Fatalf("failed to store clip: %v", err)
+	}
+
+	results, err := store.Search(ctx, storage.SearchOptions{Query: "hello"})
+	if err != nil {
+		t.Fatalf("failed to search: %v", err)
+	}
+	if len(results) != 1 || string(results[0].Clip.Content) != "Hello World" {
+		t.Errorf("expected 1 match for query, got %d", len(results))
+	}
+
+	results, err = store.Search(ctx, storage.SearchOptions{SourceURL: "EXAMPLE"})
+	if err != nil {
+		t.Fatalf("failed to search: %v", err)
+	}
+	if len(results) != 1 {
+		t.Errorf("expected 1 match for source URL, got %d", len(results))
+	}
+}
+
+func TestTrash(t *testing.T) {
+	store := setupTestDB(t)
+	ctx := context.Background()
+
+	clip, err := store.Store(ctx, []byte("trashed"), storage.TypeText, types.Metadata{})
+	if err != nil {
+		t.Fatalf("failed to store clip: %v", err)
+	}
+	if err := store.Delete(ctx, clip.ID); err != nil {
+		t.Fatalf("failed to delete clip: %v", err)
+	}
+
+	clips, err := store.List(ctx, storage.ListFilter{})
+	if err != nil {
+		t.Fatalf("failed to list clips: %v", err)
+	}
+	if len(clips) != 0 {
+		t.Errorf("deleted clip is still listed")
+	}
+
+	trash, err := store.ListTrash(ctx, 10, 0)
+	if err != nil {
+		t.Fatalf("failed to list trash: %v", err)
+	}
+	if len(trash) != 1 || trash[0].ID != clip.ID || string(trash[0].Content) != "trashed" {
+		t.Fatalf("expected deleted clip in trash, got %+v", trash)
+	}
+
+	if _, err := store.Restore(ctx, clip.ID); err != nil {
+		t.Fatalf("failed to restore clip: %v", err)
+	}
+	if _, err := store.Get(ctx, clip.ID); err != nil {
+		t.Errorf("restored clip is not visible: %v", err)
+	}
+
+	if err := store.Delete(ctx, clip.ID); err != nil {
+		t.Fatalf("failed to delete clip: %v", err)
+	}
+
+	// Copying the same content again restores it from the trash
+	again, err := store.Store(ctx, []byte("trashed"), storage.TypeText, types.Metadata{})
+	if err != nil {
+		t.Fatalf("failed to store clip: %v", err)
+	}
+	if again.ID != clip.ID {
+		t.Errorf("expected clip %s to be restored, got new clip %s", clip.ID, again.ID)
+	}
+	if trash, _ := store.ListTrash(ctx, 10, 0); len(trash) != 0 {
+		t.Errorf("restored clip is still in the trash")
+	}
+
+	if err := store.Delete(ctx, clip.ID); err != nil {
+		t.Fatalf("failed to delete clip: %v", err)
+	}
+	if purged, err := store.PurgeTrash(ctx, time.Now().Add(time.Second)); err != nil || purged != 1 {
+		t.Errorf("PurgeTrash() = %d, %v; want 1, nil", purged, err)
+	}
+	if _, err := store.Store(ctx, []byte("trashed"), storage.TypeText, types.Metadata{}); err != nil {
+		t.Errorf("failed to store content again after purging: %v", err)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	store := setupTestDB(t)
+
+	ctx := context.Background()
+	keep, err := store.Store(ctx, []byte("https://example.com/page"), storage.TypeText, types.Metadata{})
+	if err != nil {
+		t.Fatalf("failed to store clip: %v", err)
+	}
+	// Copied twice, so it counts two uses
+	for i := 0; i < 2; i++ {
+		if _, err := store.Store(ctx, []byte("https://example.com/page#section"), storage.TypeText, types.Metadata{}); err != nil {
+			t.Fatalf("failed to store clip: %v", err)
+		}
+	}
+	dup, err := store.Store(ctx, []byte("https://example.com/page#section"), storage.TypeText, types.Metadata{})
+	if err != nil {
+		t.Fatalf("failed to store clip: %v", err)
+	}
+
+	grouped := storage.GroupSimilar(mustSearch(t, store, storage.SearchOptions{}))
+	if len(grouped) != 1 || len(grouped[0].Duplicates) != 1 || grouped[0].UseCount != 4 {
+		t.Fatalf("expected one group of 4 uses, got %+v", grouped)
+	}
+
+	merged, err := store.Merge(ctx, keep.ID, []string{keep.ID, dup.ID})
+	if err != nil {
+		t.Fatalf("failed to merge clips: %v", err)
+	}
+	if merged.ID != keep.ID || string(merged.Content) != "https://example.com/page" {
+		t.Errorf("merge kept the wrong clip: %+v", merged)
+	}
+	if _, err := store.Get(ctx, dup.ID); err == nil {
+		t.Error("merged duplicate is still visible")
+	}
+
+	results := mustSearch(t, store, storage.SearchOptions{})
+	if len(results) != 1 || results[0].UseCount != 4 {
+		t.Errorf("expected merged clip with 4 uses, got %+v", results)
+	}
+}
+
+func mustSearch(t *testing.T, store storage.SearchService, opts storage.SearchOptions) []storage.SearchResult {
+	t.Helper()
+	results, err := store.Search(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("failed to search: %v", err)
+	}
+	return results
+}
+
+func TestVersions(t *testing.T) {
+	store := setupTestDB(t)
+
+	ctx := context.Background()
+	clip, err := store.Store(ctx, []byte("teh quick fox"), storage.TypeText, types.Metadata{})
+	if err != nil {
+		t.Fatalf("failed to store clip: %v", err)
+	}
+	other, err := store.Store(ctx, []byte("other"), storage.TypeText, types.Metadata{})
+	if err != nil {
+		t.Fatalf("failed to store clip: %v", err)
+	}
+
+	edited, err := store.Edit(ctx, clip.ID, []byte("the quick fox"), nil)
+	if err != nil {
+		t.Fatalf("failed to edit clip: %v", err)
+	}
+	if edited.ID != clip.ID || string(edited.Content) != "the quick fox" {
+		t.Errorf("unexpected edited clip: %+v", edited)
+	}
+	if _, err := store.Edit(ctx, other.ID, []byte("the quick fox"), nil); !errors.Is(err, storage.ErrDuplicate) {
+		t.Errorf("expected duplicate content error, got %v", err)
+	}
+
+	versions, err := store.ListVersions(ctx, clip.ID)
+	if err != nil {
+		t.Fatalf("failed to list versions: %v", err)
+	}
+	if len(versions) != 1 || versions[0].Version != 1 || string(versions[0].Content) != "teh quick fox" {
+		t.Fatalf("expected the original as version 1, got %+v", versions)
+	}
+
+	reverted, err := store.Revert(ctx, clip.ID, 1)
+	if err != nil {
+		t.Fatalf("failed to revert clip: %v", err)
+	}
+	if string(reverted.Content) != "teh quick fox" {
+		t.Errorf("expected original content, got %q", reverted.Content)
+	}
+	versions, err = store.ListVersions(ctx, clip.ID)
+	if err != nil {
+		t.Fatalf("failed to list versions: %v", err)
+	}
+	if len(versions) != 2 || string(versions[1].Content) != "the quick fox" {
+		t.Errorf("expected the edit as version 2, got %+v", versions)
+	}
+
+	// The edited content is what a new copy deduplicates against
+	again, err := store.Store(ctx, []byte("teh quick fox"), storage.TypeText, types.Metadata{})
+	if err != nil {
+		t.Fatalf("failed to store clip: %v", err)
+	}
+	if again.ID != clip.ID {
+		t.Errorf("expected copy to match reverted clip %s, got %s", clip.ID, again.ID)
+	}
+}
+
+func TestSearch_QueryLanguage(t *testing.T) {
+	store := setupTestDB(t)
+
+	ctx := context.Background()
+	clips := []struct {
+		content  string
+		clipType string
+		metadata types.Metadata
+	}{
+		{"meeting notes for work", "text/plain", types.Metadata{SourceApp: "Google Chrome", Tags: []string{"work"}}},
+		{"grocery list", "text/plain", types.Metadata{SourceApp: "Notes", Tags: []string{"home"}}},
+		{"png bytes", "image/png", types.Metadata{SourceApp: "Google Chrome"}},
+		{"copied on my phone", "text/plain", types.Metadata{SourceApp: "iOS Device", Device: "iOS Device"}},
+	}
+	for _, c := range clips {
+		if _, err := store.Store(ctx, []byte(c.content), c.clipType, c.metadata); err != nil {
+			t.Fatalf("failed to store clip: %v", err)
+		}
+	}
+
+	tomorrow := time.Now().AddDate(0, 0, 1).Format("2006-01-02")
+	tests := map[string][]string{
+		"type:image app:chrome":        {"png bytes"},
+		"tag:work":                     {"meeting notes for work"},
+		"notes work":                   {"meeting notes for work"},
+		`"notes for"`:                  {"meeting notes for work"},
+		"grocery OR tag:work":          {"grocery list", "meeting notes for work"},
+		"app:notes AND list":           {"grocery list"},
+		"before:2000-01-01":            nil,
+		"type:text after:" + tomorrow:  nil,
+		"type:text before:" + tomorrow: {"copied on my phone", "grocery list", "meeting notes for work"},
+		"device:ios":                   {"copied on my phone"},
+	}
+	for query, want := range tests {
+		var got []string
+		for _, result := range mustSearch(t, store, storage.SearchOptions{Query: query}) {
+			got = append(got, string(result.Clip.Content))
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("search %q: got %q, want %q", query, got, want)
+		}
+	}
+
+	if _, err := store.Search(ctx, storage.SearchOptions{Query: "before:soon"}); err == nil {
+		t.Error("expected an error for a malformed date")
+	}
+}
+
+func TestSearch_Cursor(t *testing.T) {
+	store := setupTestDB(t)
+
+	ctx := context.Background()
+	for i := 0; i < 5; i++ {
+		if _, err := store.Store(ctx, []byte(fmt.Sprintf("clip %d", i)), storage.TypeText, types.Metadata{}); err != nil {
+			t.Fatalf("failed to store clip: %v", err)
+		}
+	}
+
+	var seen []string
+	cursor := ""
+	for page := 0; ; page++ {
+		results := mustSearch(t, store, storage.SearchOptions{Limit: 2, Cursor: cursor})
+		for _, result := range results {
+			seen = append(seen, string(result.Clip.Content))
+		}
+		if page == 0 {
+			// A new clip doesn't shift the pages after the cursor
+			if _, err := store.Store(ctx, []byte("new clip"), storage.TypeText, types.Metadata{}); err != nil {
+				t.Fatalf("failed to store clip: %v", err)
+			}
+		}
+		if cursor = storage.NextCursor(results, 2); cursor == "" {
+			break
+		}
+	}
+
+	want := []string{"clip 4", "clip 3", "clip 2", "clip 1", "clip 0"}
+	if !reflect.DeepEqual(seen, want) {
+		t.Errorf("pages = %q, want %q", seen, want)
+	}
+
+	if _, err := store.Search(ctx, storage.SearchOptions{Cursor: "1-1", SortBy: "created_at"}); !errors.Is(err, storage.ErrCursorOrder) {
+		t.Errorf("expected cursor order error, got %v", err)
+	}
+}
+
+func TestSearch_Cancelled(t *testing.T) {
+	store := setupTestDB(t)
+
+	ctx := context.Background()
+	if _, err := store.Store(ctx, []byte("hello"), storage.TypeText, types.Metadata{}); err != nil {
+		t.Fatalf("failed to store clip: %v", err)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := store.Search(cancelled, storage.SearchOptions{Query: "hello"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestBulk(t *testing.T) {
+	store := setupTestDB(t)
+
+	ctx := context.Background()
+	var ids []string
+	for _, content := range []string{"keep me", "delete me", "https://example.com", "tag me"} {
+		clip, err := store.Store(ctx, []byte(content), storage.TypeText, types.Metadata{})
+		if err != nil {
+			t.Fatalf("failed to store clip: %v", err)
+		}
+		ids = append(ids, clip.ID)
+	}
+
+	tagged, err := store.UpdateTagsMany(ctx, []string{ids[0], ids[3]}, []string{"work"})
+	if err != nil || tagged != 2 {
+		t.Fatalf("UpdateTagsMany = %d, %v, want 2", tagged, err)
+	}
+	clip, err := store.Get(ctx, ids[3])
+	if err != nil || !reflect.DeepEqual(clip.Metadata.Tags, []string{"work"}) {
+		t.Errorf("expected tags [work], got %+v, %v", clip, err)
+	}
+
+	deleted, err := store.DeleteMany(ctx, []string{ids[1], ids[1]})
+	if err != nil || deleted != 1 {
+		t.Fatalf("DeleteMany = %d, %v, want 1", deleted, err)
+	}
+
+	query, err := storage.ParseQuery("example.com")
+	if err != nil {
+		t.Fatalf("failed to parse query: %v", err)
+	}
+	deleted, err = store.DeleteWhere(ctx, query)
+	if err != nil || deleted != 1 {
+		t.Fatalf("DeleteWhere = %d, %v, want 1", deleted, err)
+	}
+
+	results := mustSearch(t, store, storage.SearchOptions{})
+	if len(results) != 2 || string(results[0].Clip.Content) != "tag me" || string(results[1].Clip.Content) != "keep me" {
+		t.Errorf("expected the tagged clips to remain, got %+v", results)
+	}
+
+	deleted, err = store.DeleteWhere(ctx, storage.Query{})
+	if err != nil || deleted != 2 {
+		t.Fatalf("DeleteWhere with an empty query = %d, %v, want 2", deleted, err)
+	}
+	trash, err := store.ListTrash(ctx, 10, 0)
+	if err != nil || len(trash) != 4 {
+		t.Errorf("expected 4 clips in the trash, got %d, %v", len(trash), err)
+	}
+}
+
+func TestFindNormalized(t *testing.T) {
+	store := setupTestDB(t)
+	ctx := context.Background()
+
+	clip, err := store.Store(ctx, []byte("ls -la"), storage.TypeText, types.Metadata{})
+	if err != nil {
+		t.Fatalf("failed to store clip: %v", err)
+	}
+	found, err := store.FindNormalized(ctx, storage.NormalizedHash([]byte("ls -la\n"), storage.TypeText))
+	if err != nil || found.ID != clip.ID || string(found.Content) != "ls -la" {
+		t.Fatalf("FindNormalized = %v, %v; want clip %s", found, err, clip.ID)
+	}
+
+	// Editing the clip moves its entry to the new text
+	if _, err := store.Edit(ctx, clip.ID, []byte("pwd"), nil); err != nil {
+		t.Fatalf("failed to edit clip: %v", err)
+	}
+	if _, err := store.FindNormalized(ctx, storage.NormalizedHash([]byte("ls -la"), storage.TypeText)); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("FindNormalized of the old text = %v, want ErrNotFound", err)
+	}
+	if found, err := store.FindNormalized(ctx, storage.NormalizedHash([]byte(" pwd "), storage.TypeText)); err != nil || found.ID != clip.ID {
+		t.Errorf("FindNormalized of the new text = %v, %v; want clip %s", found, err, clip.ID)
+	}
+}
+
+func TestRelated(t *testing.T) {
+	store := setupTestDB(t)
+	ctx := context.Background()
+
+	var ids []string
+	for _, text := range []string{"alpha", "beta", "gamma"} {
+		clip, err := store.Store(ctx, []byte(text), storage.TypeText, types.Metadata{Session: "s1"})
+		if err != nil {
+			t.Fatalf("failed to store clip: %v", err)
+		}
+		ids = append(ids, clip.ID)
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := store.Store(ctx, []byte("other"), storage.TypeText, types.Metadata{Session: "s2"}); err != nil {
+		t.Fatalf("failed to store clip: %v", err)
+	}
+
+	related, err := store.Related(ctx, ids[1])
+	if err != nil {
+		t.Fatalf("Related failed: %v", err)
+	}
+	if len(related) != 2 || related[0].ID != ids[0] || related[1].ID != ids[2] {
+		t.Errorf("Related = %v, want clips %s and %s", related, ids[0], ids[2])
+	}
+}
+
+func TestTimeline(t *testing.T) {
+	store := setupTestDB(t)
+	ctx := context.Background()
+
+	tuesday := time.Date(2024, 6, 4, 14, 0, 0, 0, time.Local)
+	for i, createdAt := range []time.Time{tuesday.Add(10 * time.Minute), tuesday.Add(2 * time.Hour), tuesday.AddDate(0, 0, 1)} {
+		clip, err := store.Store(ctx, []byte(fmt.Sprintf("clip %d", i)), storage.TypeText, types.Metadata{})
+		if err != nil {
+			t.Fatalf("failed to store clip: %v", err)
+		}
+		key, _ := parseID(clip.ID)
+		err = store.db.Update(func(tx *bbolt.Tx) error {
+			model, err := getModel(tx, key)
+			if err != nil {
+				return err
+			}
+			model.CreatedAt = createdAt
+			return putModel(tx, model, model.LastUsed)
+		})
+		if err != nil {
+			t.Fatalf("failed to backdate clip: %v", err)
+		}
+	}
+
+	hours, err := store.Timeline(ctx, storage.TimelineOptions{
+		Granularity: storage.GranularityHour,
+		From:        tuesday,
+		To:          tuesday.Add(6 * time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("Timeline failed: %v", err)
+	}
+	if len(hours) != 2 || !hours[0].Start.Equal(tuesday) || hours[0].Clips != 1 || !hours[1].End.Equal(tuesday.Add(3*time.Hour)) {
+		t.Errorf("hours = %+v, want a clip at 14:00 and one at 16:00", hours)
+	}
+}
@@ -0,0 +1,102 @@
+package bolt
+
+import (
+	"clipboard-manager/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+
+	bbolt "go.etcd.io/bbolt"
+)
+
+// DeleteMany implements storage.BulkService interface
+func (s *BoltStorage) DeleteMany(ctx context.Context, ids []string) (int64, error) {
+	keys, err := storage.ParseIDs(ids)
+	if err != nil {
+		return 0, err
+	}
+
+	var deleted int64
+	err = s.db.Update(func(tx *bbolt.Tx) error {
+		for _, key := range keys {
+			model, err := getModel(tx, idKey(key))
+			if errors.Is(err, ErrNotFound) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if err := trashModel(tx, model); err != nil {
+				return err
+			}
+			deleted++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete clips: %w", err)
+	}
+	return deleted, nil
+}
+
+// DeleteWhere implements storage.BulkService interface
+func (s *BoltStorage) DeleteWhere(ctx context.Context, query storage.Query) (int64, error) {
+	var deleted int64
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		// Collect first, trashing changes the index being scanned
+		var matches []*storage.ClipModel
+		err := scanByLastUsed(tx, false, func(model *storage.ClipModel) (bool, error) {
+			if s.matchesTerms(tx, model, query) {
+				model.Content = nil
+				matches = append(matches, model)
+			}
+			return true, nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, model := range matches {
+			if err := trashModel(tx, model); err != nil {
+				return err
+			}
+		}
+		deleted = int64(len(matches))
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete clips: %w", err)
+	}
+	return deleted, nil
+}
+
+// UpdateTagsMany implements storage.BulkService interface
+func (s *BoltStorage) UpdateTagsMany(ctx context.Context, ids []string, tags []string) (int64, error) {
+	keys, err := storage.ParseIDs(ids)
+	if err != nil {
+		return 0, err
+	}
+
+	var updated int64
+	err = s.db.Update(func(tx *bbolt.Tx) error {
+		for _, key := range keys {
+			model, err := getModel(tx, idKey(key))
+			if errors.Is(err, ErrNotFound) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			model.Tags = tags
+			if err := putModel(tx, model, model.LastUsed); err != nil {
+				return err
+			}
+			updated++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to update tags: %w", err)
+	}
+	return updated, nil
+}
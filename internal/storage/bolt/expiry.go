@@ -0,0 +1,103 @@
+package bolt
+
+import (
+	"bytes"
+	"clipboard-manager/internal/storage"
+	"clipboard-manager/pkg/types"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	bbolt "go.etcd.io/bbolt"
+)
+
+// indexExpiry sets when a clip expires, keeping the expires index in sync.
+// The caller saves the model.
+func indexExpiry(tx *bbolt.Tx, model *storage.ClipModel, expiresAt *time.Time) error {
+	index := tx.Bucket(expiresBucket)
+	if model.ExpiresAt != nil {
+		if err := index.Delete(timeKey(*model.ExpiresAt, model.ID)); err != nil {
+			return err
+		}
+	}
+
+	model.ExpiresAt = expiresAt
+	if expiresAt == nil {
+		return nil
+	}
+	return index.Put(timeKey(*expiresAt, model.ID), nil)
+}
+
+// SetExpiry implements storage.ExpiryService interface
+func (s *BoltStorage) SetExpiry(ctx context.Context, id string, expiresAt *time.Time) (*types.Clip, error) {
+	key, err := parseID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var clip *types.Clip
+	err = s.db.Update(func(tx *bbolt.Tx) error {
+		model, err := getModel(tx, key)
+		if err != nil {
+			return err
+		}
+		if err := indexExpiry(tx, model, expiresAt); err != nil {
+			return err
+		}
+		if err := putModel(tx, model, model.LastUsed); err != nil {
+			return err
+		}
+		clip = model.ToClip()
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to set clip expiry: %w", err)
+	}
+	return clip, nil
+}
+
+// DeleteExpired implements storage.ExpiryService interface
+func (s *BoltStorage) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	var deleted int64
+	cutoff := make([]byte, 8)
+	binary.BigEndian.PutUint64(cutoff, uint64(now.UnixNano()))
+
+	var orphaned []string
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		// The index is ordered by expiry time, so stop at now
+		var expired [][]byte
+		c := tx.Bucket(expiresBucket).Cursor()
+		for k, _ := c.First(); k != nil && bytes.Compare(k[:8], cutoff) <= 0; k, _ = c.Next() {
+			expired = append(expired, bytes.Clone(k[8:]))
+		}
+
+		for _, key := range expired {
+			model, err := getAnyModel(tx, key)
+			if err != nil {
+				return err
+			}
+			if err := deleteModel(tx, model); err != nil {
+				return fmt.Errorf("failed to delete expired clip: %w", err)
+			}
+
+			// Delete external file once no other clip shares it
+			if model.IsExternal {
+				released, err := s.releaseBlob(tx, model.StoragePath)
+				if err != nil {
+					return err
+				}
+				if released {
+					orphaned = append(orphaned, model.StoragePath)
+				}
+			}
+			deleted++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	s.removeBlobFiles(orphaned)
+	return deleted, nil
+}
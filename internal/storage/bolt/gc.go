@@ -0,0 +1,70 @@
+package bolt
+
+import (
+	"clipboard-manager/internal/storage"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	bbolt "go.etcd.io/bbolt"
+)
+
+// GC implements storage.GarbageCollector interface
+func (s *BoltStorage) GC(ctx context.Context, dryRun bool) (*storage.GCReport, error) {
+	report := &storage.GCReport{DryRun: dryRun}
+	referenced := make(map[string]bool)
+
+	repair := s.db.Update
+	if dryRun {
+		repair = s.db.View
+	}
+
+	err := repair(func(tx *bbolt.Tx) error {
+		var dangling, external []*storage.ClipModel
+		err := tx.Bucket(clipsBucket).ForEach(func(k, v []byte) error {
+			var model storage.ClipModel
+			if err := json.Unmarshal(v, &model); err != nil {
+				return fmt.Errorf("failed to decode clip: %w", err)
+			}
+			if !model.IsExternal {
+				return nil
+			}
+			if _, err := os.Stat(filepath.Join(s.fsPath, model.StoragePath)); os.IsNotExist(err) {
+				dangling = append(dangling, &model)
+				report.DanglingClips = append(report.DanglingClips, strconv.FormatUint(uint64(model.ID), 10))
+				return nil
+			}
+			referenced[model.StoragePath] = true
+			external = append(external, &model)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		// Clips whose content is gone can't be recovered. Buckets must not
+		// be modified while iterating, so delete once the scan is done.
+		if !dryRun {
+			for _, model := range dangling {
+				if err := deleteModel(tx, model); err != nil {
+					return fmt.Errorf("failed to delete dangling clip: %w", err)
+				}
+			}
+		}
+
+		report.RepairedBlobs, err = repairBlobs(tx, external, dryRun)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := storage.CollectFiles(ctx, s.fsPath, referenced, report); err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
@@ -0,0 +1,30 @@
+package bolt
+
+import (
+	"clipboard-manager/pkg/types"
+	"context"
+	"fmt"
+
+	"go.etcd.io/bbolt"
+)
+
+// SetLinkPreview implements storage.LinkService interface
+func (s *BoltStorage) SetLinkPreview(ctx context.Context, id string, preview *types.LinkPreview) error {
+	key, err := parseID(id)
+	if err != nil {
+		return err
+	}
+
+	err = s.db.Update(func(tx *bbolt.Tx) error {
+		model, err := getModel(tx, key)
+		if err != nil {
+			return err
+		}
+		model.Link = preview
+		return putModel(tx, model, model.LastUsed)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set link preview: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package bolt
+
+import (
+	"clipboard-manager/pkg/types"
+	"context"
+	"fmt"
+
+	bbolt "go.etcd.io/bbolt"
+)
+
+// Merge implements storage.Merger interface
+func (s *BoltStorage) Merge(ctx context.Context, keepID string, ids []string) (*types.Clip, error) {
+	key, err := parseID(keepID)
+	if err != nil {
+		return nil, err
+	}
+
+	var clip *types.Clip
+	err = s.db.Update(func(tx *bbolt.Tx) error {
+		keep, err := getModel(tx, key)
+		if err != nil {
+			return fmt.Errorf("failed to get clip: %w", err)
+		}
+
+		previous := keep.LastUsed
+		keep.UseCount = keep.Uses()
+		for _, id := range ids {
+			if id == keepID {
+				continue
+			}
+			dupKey, err := parseID(id)
+			if err != nil {
+				return err
+			}
+			model, err := getModel(tx, dupKey)
+			if err != nil {
+				return fmt.Errorf("failed to get clip %s: %w", id, err)
+			}
+			keep.UseCount += model.Uses()
+			if model.LastUsed.After(keep.LastUsed) {
+				keep.LastUsed = model.LastUsed
+			}
+
+			// Merged clips go to the trash like any other delete
+			if err := trashModel(tx, model); err != nil {
+				return fmt.Errorf("failed to delete clip %s: %w", id, err)
+			}
+		}
+
+		if err := putModel(tx, keep, previous); err != nil {
+			return fmt.Errorf("failed to update clip: %w", err)
+		}
+		// Content is best effort, like Search
+		_ = s.loadContent(tx, keep)
+		clip = keep.ToClip()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return clip, nil
+}
@@ -0,0 +1,57 @@
+package bolt
+
+import (
+	"bytes"
+	"clipboard-manager/internal/storage"
+	"clipboard-manager/pkg/types"
+	"context"
+	"fmt"
+
+	bbolt "go.etcd.io/bbolt"
+)
+
+// FindNormalized implements storage.NormalizedFinder interface
+func (s *BoltStorage) FindNormalized(ctx context.Context, hash string) (*types.Clip, error) {
+	var clip *types.Clip
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		id := tx.Bucket(normalizedBucket).Get([]byte(hash))
+		if id == nil {
+			return ErrNotFound
+		}
+		model, err := getAnyModel(tx, id)
+		if err != nil {
+			return err
+		}
+		if err := s.loadContent(tx, model); err != nil {
+			return err
+		}
+		clip = model.ToClip()
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find clip: %w", err)
+	}
+	return clip, nil
+}
+
+// indexNormalized makes a clip the one its normalized hash finds, as the
+// clip with it that was stored last
+func indexNormalized(tx *bbolt.Tx, model *storage.ClipModel) error {
+	if model.NormalizedHash == "" {
+		return nil
+	}
+	return tx.Bucket(normalizedBucket).Put([]byte(model.NormalizedHash), idKey(model.ID))
+}
+
+// unindexNormalized removes a clip's normalized hash entry, unless it finds
+// another clip
+func unindexNormalized(tx *bbolt.Tx, model *storage.ClipModel) error {
+	if model.NormalizedHash == "" {
+		return nil
+	}
+	normalized := tx.Bucket(normalizedBucket)
+	if id := normalized.Get([]byte(model.NormalizedHash)); id != nil && bytes.Equal(id, idKey(model.ID)) {
+		return normalized.Delete([]byte(model.NormalizedHash))
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package bolt
+
+import (
+	"clipboard-manager/internal/storage"
+	"clipboard-manager/pkg/types"
+	"context"
+	"fmt"
+
+	bbolt "go.etcd.io/bbolt"
+)
+
+// Related implements storage.RelatedFinder interface
+func (s *BoltStorage) Related(ctx context.Context, id string) ([]*types.Clip, error) {
+	key, err := parseID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	clips := []*types.Clip{}
+	err = s.db.View(func(tx *bbolt.Tx) error {
+		clip, err := getModel(tx, key)
+		if err != nil {
+			return err
+		}
+		if clip.Session == "" {
+			return nil
+		}
+		return scanByLastUsed(tx, true, func(model *storage.ClipModel) (bool, error) {
+			if model.Session != clip.Session || model.ID == clip.ID {
+				return true, nil
+			}
+			if err := s.loadContent(tx, model); err != nil {
+				return false, err
+			}
+			clips = append(clips, model.ToClip())
+			return true, nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find related clips: %w", err)
+	}
+	return clips, nil
+}
@@ -0,0 +1,321 @@
+package bolt
+
+import (
+	"clipboard-manager/internal/storage"
+	"clipboard-manager/pkg/types"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	bbolt "go.etcd.io/bbolt"
+)
+
+// matchesText performs a case-insensitive substring match against the
+// text content and metadata of a clip
+func (s *BoltStorage) matchesText(tx *bbolt.Tx, model *storage.ClipModel, term string) bool {
+	fields := []string{model.PlainText, model.SourceApp, model.Category, model.ContentHash}
+	fields = append(fields, model.Tags...)
+	for _, field := range fields {
+		if strings.Contains(strings.ToLower(field), term) {
+			return true
+		}
+	}
+
+	if !strings.HasPrefix(model.Type, "text") {
+		return false
+	}
+	if err := s.loadContent(tx, model); err != nil {
+		return false
+	}
+	return strings.Contains(strings.ToLower(string(model.Content)), term)
+}
+
+// matchesTerms reports whether a clip matches a parsed query
+func (s *BoltStorage) matchesTerms(tx *bbolt.Tx, model *storage.ClipModel, query storage.Query) bool {
+	if query.Empty() {
+		return true
+	}
+	for _, group := range query.Groups {
+		matched := true
+		for _, term := range group {
+			if !s.matchesTerm(tx, model, term) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesTerm reports whether a clip matches one query term
+func (s *BoltStorage) matchesTerm(tx *bbolt.Tx, model *storage.ClipModel, term storage.Term) bool {
+	switch term.Field {
+	case storage.FieldType:
+		return model.Type == term.Value || strings.HasPrefix(model.Type, term.Value+"/") ||
+			model.MediaType == term.Value || strings.HasPrefix(model.MediaType, term.Value+"/")
+	case storage.FieldApp:
+		return strings.Contains(strings.ToLower(model.SourceApp), term.Value) ||
+			strings.Contains(strings.ToLower(model.SourceBundleID), term.Value)
+	case storage.FieldTag:
+		for _, tag := range model.Tags {
+			if strings.ToLower(tag) == term.Value {
+				return true
+			}
+		}
+		return false
+	case storage.FieldFormat:
+		_, ok := model.Formats[term.Value]
+		return ok || model.Type == term.Value
+	case storage.FieldURL:
+		return strings.Contains(strings.ToLower(model.SourceURL), term.Value)
+	case storage.FieldDevice:
+		return strings.Contains(strings.ToLower(model.Device), term.Value)
+	case storage.FieldWindow:
+		return strings.Contains(strings.ToLower(model.SourceWindow), term.Value)
+	case storage.FieldCategory:
+		return strings.ToLower(model.Category) == term.Value
+	case storage.FieldBefore:
+		return model.CreatedAt.Before(term.Time)
+	case storage.FieldAfter:
+		return !model.CreatedAt.Before(term.Time)
+	}
+	return s.matchesText(tx, model, term.Value)
+}
+
+// matchesFilters applies the non-text search criteria
+func matchesFilters(model *storage.ClipModel, opts storage.SearchOptions) bool {
+	if opts.Type != "" && model.Type != types.NormalizeType(opts.Type) {
+		return false
+	}
+	if opts.Format != "" && model.Type != opts.Format {
+		if _, ok := model.Formats[opts.Format]; !ok {
+			return false
+		}
+	}
+	if opts.SourceApp != "" && model.SourceApp != opts.SourceApp {
+		return false
+	}
+	if opts.SourceBundleID != "" && model.SourceBundleID != opts.SourceBundleID {
+		return false
+	}
+	if opts.SourceURL != "" && !strings.Contains(strings.ToLower(model.SourceURL), strings.ToLower(opts.SourceURL)) {
+		return false
+	}
+	if opts.Device != "" && !strings.Contains(strings.ToLower(model.Device), strings.ToLower(opts.Device)) {
+		return false
+	}
+	if opts.Category != "" && model.Category != opts.Category {
+		return false
+	}
+	for _, tag := range opts.Tags {
+		found := false
+		for _, t := range model.Tags {
+			if strings.Contains(t, tag) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if !opts.From.IsZero() && model.CreatedAt.Before(opts.From) {
+		return false
+	}
+	if !opts.To.IsZero() && model.CreatedAt.After(opts.To) {
+		return false
+	}
+	return true
+}
+
+// Search implements storage.SearchService interface
+func (s *BoltStorage) Search(ctx context.Context, opts storage.SearchOptions) ([]storage.SearchResult, error) {
+	query, err := storage.ParseQuery(opts.Query)
+	if err != nil {
+		return nil, err
+	}
+	usesCursor, err := opts.UsesCursor()
+	if err != nil {
+		return nil, err
+	}
+	var cursor storage.Cursor
+	if usesCursor {
+		if cursor, err = storage.ParseCursor(opts.Cursor); err != nil {
+			return nil, err
+		}
+	}
+	ascending := strings.ToLower(opts.SortOrder) == "asc"
+	byCreated := opts.SortBy == "created_at"
+	// In index order the scan can stop once the requested page is full
+	stopAt := 0
+	if !byCreated && opts.SortBy != "use_count" && opts.Limit > 0 {
+		stopAt = opts.Offset + opts.Limit
+	}
+
+	var models []*storage.ClipModel
+	err = s.db.View(func(tx *bbolt.Tx) error {
+		match := func(model *storage.ClipModel) (bool, error) {
+			if err := ctx.Err(); err != nil {
+				return false, err
+			}
+			if !matchesFilters(model, opts) {
+				return true, nil
+			}
+			if !s.matchesTerms(tx, model, query) {
+				return true, nil
+			}
+			models = append(models, model)
+			return stopAt == 0 || len(models) < stopAt, nil
+		}
+		if usesCursor {
+			return scanByLastUsedBefore(tx, timeKey(cursor.LastUsed, cursor.ID), match)
+		}
+		return scanByLastUsed(tx, ascending, match)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search clips: %w", err)
+	}
+
+	if byCreated {
+		sort.SliceStable(models, func(i, j int) bool {
+			if ascending {
+				return models[i].CreatedAt.Before(models[j].CreatedAt)
+			}
+			return models[i].CreatedAt.After(models[j].CreatedAt)
+		})
+	} else if opts.SortBy == "use_count" {
+		// Stable, so equal counts stay in order of last use
+		sort.SliceStable(models, func(i, j int) bool {
+			if ascending {
+				return models[i].Uses() < models[j].Uses()
+			}
+			return models[i].Uses() > models[j].Uses()
+		})
+	}
+
+	// Apply pagination
+	if opts.Offset > 0 {
+		if opts.Offset >= len(models) {
+			models = nil
+		} else {
+			models = models[opts.Offset:]
+		}
+	}
+	if opts.Limit > 0 && len(models) > opts.Limit {
+		models = models[:opts.Limit]
+	}
+
+	results := make([]storage.SearchResult, len(models))
+	err = s.db.View(func(tx *bbolt.Tx) error {
+		for i, model := range models {
+			if model.Content == nil {
+				// Content is best effort, like the SQL backends
+				_ = s.loadContent(tx, model)
+			}
+			results[i] = storage.SearchResult{
+				Clip:     model.ToClip(),
+				LastUsed: model.LastUsed,
+				UseCount: int(model.Uses()),
+				Score:    float64(model.LastUsed.Unix()),
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	storage.Highlight(results, query)
+	return results, nil
+}
+
+// GetRecent implements storage.SearchService interface
+func (s *BoltStorage) GetRecent(ctx context.Context, opts storage.SearchOptions) ([]storage.SearchResult, error) {
+	return s.Search(ctx, opts.Recent())
+}
+
+// GetMostUsed implements storage.SearchService interface
+func (s *BoltStorage) GetMostUsed(ctx context.Context, opts storage.SearchOptions) ([]storage.SearchResult, error) {
+	return s.Search(ctx, opts.MostUsed())
+}
+
+// GetByType implements storage.SearchService interface
+func (s *BoltStorage) GetByType(ctx context.Context, clipType string, opts storage.SearchOptions) ([]storage.SearchResult, error) {
+	return s.Search(ctx, opts.OfType(clipType))
+}
+
+// ListApps implements storage.AppService interface
+func (s *BoltStorage) ListApps(ctx context.Context) ([]storage.AppInfo, error) {
+	counts := make(map[string]*storage.AppInfo)
+
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		apps := tx.Bucket(appsBucket)
+		return tx.Bucket(clipsBucket).ForEach(func(k, v []byte) error {
+			var model storage.ClipModel
+			if err := json.Unmarshal(v, &model); err != nil {
+				return err
+			}
+			if model.SourceBundleID == "" || model.DeletedAt.Valid {
+				return nil
+			}
+
+			info, ok := counts[model.SourceBundleID]
+			if !ok {
+				info = &storage.AppInfo{BundleID: model.SourceBundleID, Name: model.SourceApp}
+				if data := apps.Get([]byte(model.SourceBundleID)); data != nil {
+					var app storage.AppModel
+					if err := json.Unmarshal(data, &app); err == nil {
+						info.Name = app.Name
+						info.HasIcon = len(app.Icon) > 0
+					}
+				}
+				counts[model.SourceBundleID] = info
+			}
+			info.Count++
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list apps: %w", err)
+	}
+
+	apps := make([]storage.AppInfo, 0, len(counts))
+	for _, info := range counts {
+		apps = append(apps, *info)
+	}
+	sort.Slice(apps, func(i, j int) bool {
+		if apps[i].Count != apps[j].Count {
+			return apps[i].Count > apps[j].Count
+		}
+		return apps[i].BundleID < apps[j].BundleID
+	})
+	return apps, nil
+}
+
+// GetAppIcon implements storage.AppService interface
+func (s *BoltStorage) GetAppIcon(ctx context.Context, bundleID string) ([]byte, error) {
+	var icon []byte
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		data := tx.Bucket(appsBucket).Get([]byte(bundleID))
+		if data == nil {
+			return fmt.Errorf("failed to get app: %s", bundleID)
+		}
+		var app storage.AppModel
+		if err := json.Unmarshal(data, &app); err != nil {
+			return fmt.Errorf("failed to decode app: %w", err)
+		}
+		icon = app.Icon
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(icon) == 0 {
+		return nil, fmt.Errorf("no icon cached for app: %s", bundleID)
+	}
+	return icon, nil
+}
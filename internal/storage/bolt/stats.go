@@ -0,0 +1,32 @@
+package bolt
+
+import (
+	"clipboard-manager/internal/storage"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	bbolt "go.etcd.io/bbolt"
+)
+
+// Stats implements storage.StatsService interface
+func (s *BoltStorage) Stats(ctx context.Context) (*storage.Stats, error) {
+	builder := storage.NewStatsBuilder(time.Now())
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(clipsBucket).ForEach(func(k, v []byte) error {
+			var model storage.ClipModel
+			if err := json.Unmarshal(v, &model); err != nil {
+				return fmt.Errorf("failed to decode clip: %w", err)
+			}
+			if !model.DeletedAt.Valid {
+				builder.Add(model.Type, model.SourceApp, model.Size, model.CreatedAt)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read clips: %w", err)
+	}
+	return builder.Stats(), nil
+}
@@ -0,0 +1,38 @@
+package bolt
+
+import (
+	"clipboard-manager/internal/storage"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	bbolt "go.etcd.io/bbolt"
+)
+
+// Timeline implements storage.TimelineService interface
+func (s *BoltStorage) Timeline(ctx context.Context, opts storage.TimelineOptions) ([]storage.TimelineBucket, error) {
+	builder := storage.NewTimelineBuilder(opts.Granularity)
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(clipsBucket).ForEach(func(k, v []byte) error {
+			var model storage.ClipModel
+			if err := json.Unmarshal(v, &model); err != nil {
+				return fmt.Errorf("failed to decode clip: %w", err)
+			}
+			if model.DeletedAt.Valid {
+				return nil
+			}
+			if !opts.From.IsZero() && model.CreatedAt.Before(opts.From) {
+				return nil
+			}
+			if !opts.To.IsZero() && model.CreatedAt.After(opts.To) {
+				return nil
+			}
+			builder.Add(model.CreatedAt)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read clips: %w", err)
+	}
+	return builder.Buckets(), nil
+}
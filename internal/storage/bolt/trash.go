@@ -0,0 +1,126 @@
+package bolt
+
+import (
+	"bytes"
+	"clipboard-manager/pkg/types"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	bbolt "go.etcd.io/bbolt"
+	"gorm.io/gorm"
+)
+
+// ListTrash implements storage.TrashService interface
+func (s *BoltStorage) ListTrash(ctx context.Context, limit, offset int) ([]*types.Clip, error) {
+	var clips []*types.Clip
+	skipped := 0
+
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		c := tx.Bucket(trashBucket).Cursor()
+		for k, _ := c.Last(); k != nil; k, _ = c.Prev() {
+			if skipped < offset {
+				skipped++
+				continue
+			}
+			model, err := getAnyModel(tx, k[8:])
+			if err != nil {
+				return err
+			}
+			if err := s.loadContent(tx, model); err != nil {
+				return err
+			}
+			clips = append(clips, model.ToClip())
+			if limit > 0 && len(clips) >= limit {
+				return nil
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list trash: %w", err)
+	}
+	return clips, nil
+}
+
+// Restore implements storage.TrashService interface
+func (s *BoltStorage) Restore(ctx context.Context, id string) (*types.Clip, error) {
+	key, err := parseID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var clip *types.Clip
+	err = s.db.Update(func(tx *bbolt.Tx) error {
+		model, err := getAnyModel(tx, key)
+		if err != nil {
+			return err
+		}
+		if !model.DeletedAt.Valid {
+			return ErrNotFound
+		}
+
+		if err := tx.Bucket(trashBucket).Delete(timeKey(model.DeletedAt.Time, model.ID)); err != nil {
+			return err
+		}
+
+		// Restored clips come back at the top of the history
+		model.DeletedAt = gorm.DeletedAt{}
+		model.LastUsed = time.Now()
+		if err := putModel(tx, model, time.Time{}); err != nil {
+			return fmt.Errorf("failed to restore clip: %w", err)
+		}
+		clip = model.ToClip()
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore clip: %w", err)
+	}
+	return clip, nil
+}
+
+// PurgeTrash implements storage.TrashService interface
+func (s *BoltStorage) PurgeTrash(ctx context.Context, before time.Time) (int64, error) {
+	var purged int64
+	cutoff := make([]byte, 8)
+	binary.BigEndian.PutUint64(cutoff, uint64(before.UnixNano()))
+
+	var orphaned []string
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		// The trash index is ordered by deletion time, so stop at the cutoff
+		var expired [][]byte
+		c := tx.Bucket(trashBucket).Cursor()
+		for k, _ := c.First(); k != nil && bytes.Compare(k[:8], cutoff) < 0; k, _ = c.Next() {
+			expired = append(expired, bytes.Clone(k[8:]))
+		}
+
+		for _, key := range expired {
+			model, err := getAnyModel(tx, key)
+			if err != nil {
+				return err
+			}
+			if err := deleteModel(tx, model); err != nil {
+				return fmt.Errorf("failed to purge clip: %w", err)
+			}
+
+			// Delete external file once no other clip shares it
+			if model.IsExternal {
+				released, err := s.releaseBlob(tx, model.StoragePath)
+				if err != nil {
+					return err
+				}
+				if released {
+					orphaned = append(orphaned, model.StoragePath)
+				}
+			}
+			purged++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	s.removeBlobFiles(orphaned)
+	return purged, nil
+}
@@ -0,0 +1,31 @@
+package bolt
+
+import (
+	"clipboard-manager/internal/storage"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	bbolt "go.etcd.io/bbolt"
+)
+
+// Usage implements storage.UsageReporter interface
+func (s *BoltStorage) Usage(ctx context.Context) (storage.Usage, error) {
+	var usage storage.Usage
+
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		usage.DatabaseBytes = tx.Size()
+		return tx.Bucket(blobsBucket).ForEach(func(k, v []byte) error {
+			var blob storage.BlobModel
+			if err := json.Unmarshal(v, &blob); err != nil {
+				return fmt.Errorf("failed to decode blob: %w", err)
+			}
+			usage.ExternalBytes += blob.Size
+			return nil
+		})
+	})
+	if err != nil {
+		return usage, fmt.Errorf("failed to get storage size: %w", err)
+	}
+	return usage, nil
+}
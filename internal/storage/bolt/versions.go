@@ -0,0 +1,192 @@
+package bolt
+
+import (
+	"bytes"
+	"clipboard-manager/internal/storage"
+	"clipboard-manager/pkg/types"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	bbolt "go.etcd.io/bbolt"
+)
+
+// versionKey orders a clip's versions after its ID
+func versionKey(id uint, version int) []byte {
+	key := make([]byte, 12)
+	binary.BigEndian.PutUint64(key, uint64(id))
+	binary.BigEndian.PutUint32(key[8:], uint32(version))
+	return key
+}
+
+// Edit implements storage.VersionService interface
+func (s *BoltStorage) Edit(ctx context.Context, id string, content []byte, formats map[string][]byte) (*types.Clip, error) {
+	if len(content) > storage.MaxInlineStorageSize {
+		return nil, storage.ErrNotEditable
+	}
+	key, err := parseID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var clip *types.Clip
+	err = s.db.Update(func(tx *bbolt.Tx) error {
+		model, err := getModel(tx, key)
+		if err != nil {
+			return fmt.Errorf("failed to get clip: %w", err)
+		}
+		if err := replaceContent(tx, model, content, model.Type, formats); err != nil {
+			return err
+		}
+		clip = model.ToClip()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return clip, nil
+}
+
+// ListVersions implements storage.VersionService interface
+func (s *BoltStorage) ListVersions(ctx context.Context, id string) ([]storage.ClipVersion, error) {
+	key, err := parseID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := []storage.ClipVersion{}
+	err = s.db.View(func(tx *bbolt.Tx) error {
+		if _, err := getModel(tx, key); err != nil {
+			return fmt.Errorf("failed to get clip: %w", err)
+		}
+		c := tx.Bucket(versionsBucket).Cursor()
+		for k, v := c.Seek(key); k != nil && bytes.HasPrefix(k, key); k, v = c.Next() {
+			var model storage.VersionModel
+			if err := json.Unmarshal(v, &model); err != nil {
+				return fmt.Errorf("failed to decode version: %w", err)
+			}
+			versions = append(versions, model.ToVersion())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return versions, nil
+}
+
+// Revert implements storage.VersionService interface
+func (s *BoltStorage) Revert(ctx context.Context, id string, version int) (*types.Clip, error) {
+	key, err := parseID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var clip *types.Clip
+	err = s.db.Update(func(tx *bbolt.Tx) error {
+		model, err := getModel(tx, key)
+		if err != nil {
+			return fmt.Errorf("failed to get clip: %w", err)
+		}
+		data := tx.Bucket(versionsBucket).Get(versionKey(model.ID, version))
+		if data == nil {
+			return fmt.Errorf("failed to get version %d: %w", version, ErrNotFound)
+		}
+		var old storage.VersionModel
+		if err := json.Unmarshal(data, &old); err != nil {
+			return fmt.Errorf("failed to decode version: %w", err)
+		}
+		if err := replaceContent(tx, model, old.Content, old.Type, old.Formats); err != nil {
+			return err
+		}
+		clip = model.ToClip()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return clip, nil
+}
+
+// replaceContent saves a clip's current content as its next version and
+// replaces it. Content shared with another clip, even one in the trash, is
+// refused since each hash belongs to one clip.
+func replaceContent(tx *bbolt.Tx, model *storage.ClipModel, content []byte, clipType string, formats storage.FormatMap) error {
+	if model.IsExternal {
+		return storage.ErrNotEditable
+	}
+
+	key := idKey(model.ID)
+	current := bytes.Clone(tx.Bucket(contentBucket).Get(key))
+	hash := calculateHash(content)
+	if hash == model.ContentHash && clipType == model.Type {
+		model.Content = current
+		return nil
+	}
+	hashes := tx.Bucket(hashesBucket)
+	if id := hashes.Get([]byte(hash)); id != nil && !bytes.Equal(id, key) {
+		return storage.ErrDuplicate
+	}
+
+	// The last key with the clip's prefix holds its latest version
+	versions := tx.Bucket(versionsBucket)
+	latest := 0
+	c := versions.Cursor()
+	for k, _ := c.Seek(versionKey(model.ID, 0)); k != nil && bytes.HasPrefix(k, key); k, _ = c.Next() {
+		latest = int(binary.BigEndian.Uint32(k[8:]))
+	}
+	data, err := json.Marshal(storage.VersionModel{
+		ClipID:    model.ID,
+		Version:   latest + 1,
+		Content:   current,
+		Type:      model.Type,
+		Formats:   model.Formats,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode version: %w", err)
+	}
+	if err := versions.Put(versionKey(model.ID, latest+1), data); err != nil {
+		return fmt.Errorf("failed to save version: %w", err)
+	}
+
+	if err := unindexHash(tx, model); err != nil {
+		return err
+	}
+	if err := hashes.Put([]byte(hash), key); err != nil {
+		return err
+	}
+	if err := unindexNormalized(tx, model); err != nil {
+		return err
+	}
+	model.NormalizedHash = storage.NormalizedHash(content, clipType)
+	if err := indexNormalized(tx, model); err != nil {
+		return err
+	}
+	if err := tx.Bucket(contentBucket).Put(key, content); err != nil {
+		return err
+	}
+
+	model.ContentHash, model.Size = hash, int64(len(content))
+	model.Type, model.Formats, model.PlainText = clipType, formats, string(formats[storage.FormatPlainText])
+	model.UpdatedAt = time.Now()
+	if err := putModel(tx, model, model.LastUsed); err != nil {
+		return fmt.Errorf("failed to update clip: %w", err)
+	}
+	model.Content = content
+	return nil
+}
+
+// deleteVersions removes all versions of a clip
+func deleteVersions(tx *bbolt.Tx, id uint) error {
+	prefix := idKey(id)
+	c := tx.Bucket(versionsBucket).Cursor()
+	for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Seek(prefix) {
+		if err := c.Delete(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
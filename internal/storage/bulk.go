@@ -0,0 +1,37 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+)
+
+// BulkService defines the interface for changing many clips at once. Each
+// call is a single statement or transaction rather than a loop over Delete.
+type BulkService interface {
+	// DeleteMany moves the clips in ids to the trash and returns how many
+	// were deleted. Clips that don't exist or are already in the trash are
+	// skipped.
+	DeleteMany(ctx context.Context, ids []string) (int64, error)
+
+	// DeleteWhere moves the clips matching query to the trash and returns
+	// how many were deleted. An empty query matches every clip.
+	DeleteWhere(ctx context.Context, query Query) (int64, error)
+
+	// UpdateTagsMany replaces the tags of the clips in ids and returns how
+	// many were updated. Their last use is left alone.
+	UpdateTagsMany(ctx context.Context, ids []string, tags []string) (int64, error)
+}
+
+// ParseIDs converts clip IDs to the numeric keys the backends use
+func ParseIDs(ids []string) ([]uint, error) {
+	keys := make([]uint, len(ids))
+	for i, id := range ids {
+		n, err := strconv.ParseUint(id, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid clip ID %q", id)
+		}
+		keys[i] = uint(n)
+	}
+	return keys, nil
+}
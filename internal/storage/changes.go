@@ -0,0 +1,43 @@
+package storage
+
+import (
+	"clipboard-manager/pkg/types"
+	"context"
+	"time"
+)
+
+// Kinds of change in the change feed
+const (
+	ChangeInsert = "insert"
+	ChangeUpdate = "update"
+	ChangeDelete = "delete" // Also moving a clip to the trash
+)
+
+// DefaultChangesLimit is how many changes are returned when no limit is given
+const DefaultChangesLimit = 100
+
+// Change is a change to a clip in the change feed
+type Change struct {
+	Seq       int64       `json:"seq"`
+	Op        string      `json:"op"`
+	ClipID    string      `json:"clip_id"`
+	UUID      string      `json:"uuid,omitempty"`
+	ChangedAt time.Time   `json:"changed_at"`
+	Clip      *types.Clip `json:"clip,omitempty"` // The clip as it is now, unless deleted
+}
+
+// ChangeFeed defines the interface for following changes to clips, so sync
+// clients can mirror history without listing all of it. Every change gets
+// the next sequence number; sequence numbers are never reused.
+type ChangeFeed interface {
+	// Changes returns the clips changed after the change numbered since, in
+	// the order they last changed, at most limit of them. A clip changed more
+	// than once is only returned with its latest change, so a client that
+	// upserts inserted and updated clips and removes deleted ones ends up
+	// with the current history.
+	Changes(ctx context.Context, since int64, limit int) ([]Change, error)
+
+	// LatestChange returns the sequence number of the latest change, or zero
+	// if nothing changed yet
+	LatestChange(ctx context.Context) (int64, error)
+}
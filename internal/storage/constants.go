@@ -1,20 +1,53 @@
 package storage
 
-import "errors"
+import (
+	"clipboard-manager/pkg/types"
+	"errors"
+	"fmt"
+)
 
 const (
 	// Size thresholds
 	MaxInlineStorageSize = 10 * 1024 * 1024  // 10MB - store in DB
 	MaxStorageSize      = 100 * 1024 * 1024 // 100MB - max total size
 	
-	// Content types
-	TypeText  = "text"
-	TypeImage = "image"
-	TypeFile  = "file"
+	// Content types, see pkg/types for the others
+	TypeText = types.TypeText
+	TypeFile = types.TypeFile
+
+	// Alternate representation formats
+	FormatPlainText = types.TypeText
+	FormatRTF       = types.TypeRTF
+	FormatHTML      = types.TypeHTML
 )
 
 // Storage errors
 var (
 	ErrFileTooLarge = errors.New("file size exceeds maximum allowed size")
 	ErrInvalidType  = errors.New("invalid content type")
+	ErrNotEditable  = errors.New("clip is stored as a file and can't be edited")
+	ErrDuplicate    = errors.New("another clip already has this content")
+	ErrNotFound     = errors.New("clip not found")
+	ErrFileChanged  = errors.New("file changed since it was copied")
 )
+
+// SizeLimitError is returned for content over the size limit of its type. It
+// matches ErrFileTooLarge, so callers that only care that content was too
+// large needn't look further.
+type SizeLimitError struct {
+	Type  string // The type the limit is set for, such as image or video
+	Size  int64  // Size of the content, or -1 if it wasn't read in full
+	Limit int64  // Zero if content of the type is never kept
+}
+
+func (e *SizeLimitError) Error() string {
+	if e.Limit == 0 {
+		return fmt.Sprintf("%s clips are not kept", e.Type)
+	}
+	return fmt.Sprintf("%s clip exceeds the %d byte limit", e.Type, e.Limit)
+}
+
+// Is reports whether target is ErrFileTooLarge
+func (e *SizeLimitError) Is(target error) bool {
+	return target == ErrFileTooLarge
+}
@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrCursorOrder is returned when a cursor is used with a sort order other
+// than most recently used first, the only order cursors describe
+var ErrCursorOrder = errors.New("cursors only page through clips ordered by last use, newest first")
+
+// Cursor is a position in clips ordered by last use, most recent first, with
+// the ID breaking ties. Unlike an offset, the page after a cursor doesn't
+// shift when new clips arrive, and storage can seek to it directly.
+type Cursor struct {
+	LastUsed time.Time
+	ID       uint
+}
+
+// String encodes the cursor for an API response
+func (c Cursor) String() string {
+	return fmt.Sprintf("%d-%d", c.LastUsed.UnixNano(), c.ID)
+}
+
+// ParseCursor decodes a cursor produced by Cursor.String
+func ParseCursor(s string) (Cursor, error) {
+	nanos, id, ok := strings.Cut(s, "-")
+	if ok {
+		n, err1 := strconv.ParseInt(nanos, 10, 64)
+		i, err2 := strconv.ParseUint(id, 10, 64)
+		if err1 == nil && err2 == nil {
+			return Cursor{LastUsed: time.Unix(0, n), ID: uint(i)}, nil
+		}
+	}
+	return Cursor{}, fmt.Errorf("invalid cursor %q", s)
+}
+
+// UsesCursor reports whether opts page with a cursor, checking that they are
+// sorted the way cursors need
+func (opts SearchOptions) UsesCursor() (bool, error) {
+	if opts.Cursor == "" {
+		return false, nil
+	}
+	if (opts.SortBy != "" && opts.SortBy != "last_used") || strings.ToLower(opts.SortOrder) == "asc" {
+		return false, ErrCursorOrder
+	}
+	return true, nil
+}
+
+// NextCursor returns the cursor of the page after results, or "" if results
+// is the last page because it holds fewer than limit clips
+func NextCursor(results []SearchResult, limit int) string {
+	if limit <= 0 || len(results) < limit {
+		return ""
+	}
+	last := results[len(results)-1]
+	id, err := strconv.ParseUint(last.Clip.ID, 10, 64)
+	if err != nil {
+		return ""
+	}
+	return Cursor{LastUsed: last.LastUsed, ID: uint(id)}.String()
+}
@@ -0,0 +1,17 @@
+package storage
+
+import (
+	"clipboard-manager/pkg/types"
+	"context"
+	"time"
+)
+
+// ExpiryService defines the interface for clips that delete themselves
+type ExpiryService interface {
+	// SetExpiry sets or, with a nil time, clears when a clip expires
+	SetExpiry(ctx context.Context, id string, expiresAt *time.Time) (*types.Clip, error)
+
+	// DeleteExpired permanently removes clips that expired before now,
+	// including clips in the trash, and returns how many were removed
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+}
@@ -0,0 +1,21 @@
+package storage
+
+import (
+	"clipboard-manager/pkg/types"
+	"context"
+	"io"
+)
+
+// FileCopier defines the interface for storage that keeps copies of the
+// files file clips point to, so they can still be pasted once the original
+// changes or is removed. Copies are shared with clips of the same content.
+type FileCopier interface {
+	// CopyFile keeps the content read from r as the copy of the file clip
+	// id points to, replacing any earlier copy. It fails with
+	// ErrFileChanged unless the content matches the clip's types.FileRef.
+	CopyFile(ctx context.Context, id string, r io.Reader) (*types.Clip, error)
+
+	// OpenFileCopy returns the kept copy of the file clip id points to. The
+	// caller must close it.
+	OpenFileCopy(ctx context.Context, id string) (io.ReadCloser, error)
+}
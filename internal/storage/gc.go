@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// GCGracePeriod is how old a file must be before GC considers it orphaned.
+// Store writes the external file before inserting the row, so a younger file
+// may belong to a store that is still in progress.
+const GCGracePeriod = 10 * time.Minute
+
+// GCReport describes what a garbage collection run found and repaired
+type GCReport struct {
+	OrphanedFiles []string // Files in the file store that no clip references
+	DanglingClips []string // IDs of clips whose external file was missing
+	LostCopies    []string // IDs of file clips whose kept copy was missing, see FileCopier
+	StaleSpools   []string // Leftover temp files from interrupted streaming stores
+	RepairedBlobs int      // External files whose reference count was corrected
+	FreedBytes    int64
+	DryRun        bool
+}
+
+// GarbageCollector defines the interface for repairing the file store
+type GarbageCollector interface {
+	// GC removes orphaned files and clips whose external file is missing.
+	// With dryRun set it only reports what would be repaired.
+	GC(ctx context.Context, dryRun bool) (*GCReport, error)
+}
+
+// CollectFiles removes files in dir that are not referenced and are older than
+// GCGracePeriod, recording them in report. Subdirectories are left alone.
+func CollectFiles(ctx context.Context, dir string, referenced map[string]bool, report *GCReport) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read storage directory: %w", err)
+	}
+
+	cutoff := time.Now().Add(-GCGracePeriod)
+	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if entry.IsDir() || referenced[entry.Name()] {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			// Removed since we listed the directory
+			continue
+		}
+		if info.ModTime().After(cutoff) {
+			continue
+		}
+
+		if strings.HasPrefix(entry.Name(), ".spool-") {
+			report.StaleSpools = append(report.StaleSpools, entry.Name())
+		} else {
+			report.OrphanedFiles = append(report.OrphanedFiles, entry.Name())
+		}
+		report.FreedBytes += info.Size()
+
+		if report.DryRun {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, entry.Name())); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove orphaned file: %w", err)
+		}
+	}
+
+	return nil
+}
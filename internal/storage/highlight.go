@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"clipboard-manager/pkg/types"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// SnippetContext is how many characters of text a snippet shows on each
+// side of the first match
+const SnippetContext = 40
+
+// Highlight fills in the text terms of query each result matched and a
+// snippet of its text around the first match, to show why it was found
+func Highlight(results []SearchResult, query Query) {
+	for i := range results {
+		results[i].Matches, results[i].Snippet = highlight(results[i].Clip, query)
+	}
+}
+
+func highlight(clip *types.Clip, query Query) ([]string, string) {
+	if clip == nil {
+		return nil, ""
+	}
+	text, ok := similarityText(clip)
+	if !ok && strings.HasPrefix(clip.Type, "text") {
+		text = string(clip.Content)
+	}
+	// Lower case rune by rune, so rune offsets match the original text
+	lower := strings.Map(unicode.ToLower, text)
+	metadata := strings.ToLower(strings.Join(append([]string{clip.Metadata.SourceApp, clip.Metadata.Category}, clip.Metadata.Tags...), "\n"))
+
+	var matches []string
+	first, firstLen := -1, 0
+	seen := make(map[string]bool)
+	for _, group := range query.Groups {
+		for _, term := range group {
+			if term.Field != FieldText || seen[term.Value] {
+				continue
+			}
+			seen[term.Value] = true
+
+			index := strings.Index(lower, term.Value)
+			if index < 0 {
+				if strings.Contains(metadata, term.Value) {
+					matches = append(matches, term.Value)
+				}
+				continue
+			}
+			matches = append(matches, term.Value)
+			if first < 0 || index < first {
+				first, firstLen = index, len(term.Value)
+			}
+		}
+	}
+	if first < 0 {
+		return matches, ""
+	}
+	return matches, snippet(text, utf8.RuneCountInString(lower[:first]), utf8.RuneCountInString(lower[first:first+firstLen]))
+}
+
+// snippet cuts text to SnippetContext runes around a match at the given
+// rune offset and length, on one line
+func snippet(text string, start, length int) string {
+	runes := []rune(text)
+	from := start - SnippetContext
+	if from < 0 {
+		from = 0
+	}
+	to := start + length + SnippetContext
+	if to > len(runes) {
+		to = len(runes)
+	}
+
+	s := strings.Join(strings.Fields(string(runes[from:to])), " ")
+	if from > 0 {
+		s = "…" + s
+	}
+	if to < len(runes) {
+		s += "…"
+	}
+	return s
+}
@@ -0,0 +1,34 @@
+package storage
+
+import (
+	"clipboard-manager/pkg/types"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestHighlight(t *testing.T) {
+	long := strings.Repeat("a", 60) + " Needle in\nthe haystack " + strings.Repeat("b", 60)
+	results := []SearchResult{
+		{Clip: &types.Clip{Type: "text/plain", Content: []byte(long), Metadata: types.Metadata{SourceApp: "Safari"}}},
+		{Clip: &types.Clip{Type: "image/png", Content: []byte("needle"), Metadata: types.Metadata{SourceApp: "Safari"}}},
+	}
+	query, err := ParseQuery("needle OR safari type:image")
+	if err != nil {
+		t.Fatal(err)
+	}
+	Highlight(results, query)
+
+	if !reflect.DeepEqual(results[0].Matches, []string{"needle", "safari"}) {
+		t.Errorf("unexpected matches %q", results[0].Matches)
+	}
+	want := "…" + strings.Repeat("a", 39) + " Needle in the haystack " + strings.Repeat("b", 23) + "…"
+	if results[0].Snippet != want {
+		t.Errorf("snippet = %q, want %q", results[0].Snippet, want)
+	}
+
+	// Binary content is never shown, but metadata still explains the match
+	if !reflect.DeepEqual(results[1].Matches, []string{"safari"}) || results[1].Snippet != "" {
+		t.Errorf("unexpected highlight for image: %q %q", results[1].Matches, results[1].Snippet)
+	}
+}
@@ -0,0 +1,25 @@
+package storage
+
+import (
+	"clipboard-manager/pkg/types"
+	"context"
+	"time"
+)
+
+// ImportedClip is a clip read from another clipboard manager
+type ImportedClip struct {
+	Content   []byte
+	Type      string
+	Metadata  types.Metadata
+	CreatedAt time.Time // First copied
+	LastUsed  time.Time // Last copied, CreatedAt if zero
+	UseCount  int       // Times copied, 1 if zero
+}
+
+// ImportService defines the interface for importing history from other
+// clipboard managers, keeping when clips were copied
+type ImportService interface {
+	// Import stores a clip with its original times. Content already in
+	// history, trash included, is left alone and reported as not imported.
+	Import(ctx context.Context, clip ImportedClip) (*types.Clip, bool, error)
+}
@@ -0,0 +1,13 @@
+package storage
+
+import (
+	"clipboard-manager/pkg/types"
+	"context"
+)
+
+// LinkService defines the interface for saving previews of copied links
+type LinkService interface {
+	// SetLinkPreview stores the preview of the page a link clip points to.
+	// The clip's last use is left alone.
+	SetLinkPreview(ctx context.Context, id string, preview *types.LinkPreview) error
+}
@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"context"
+	"time"
+)
+
+// MaintenanceOptions says what a maintenance run does
+type MaintenanceOptions struct {
+	// Full also returns free pages to the file system and refreshes the
+	// query planner's statistics. Without it the run only checkpoints the
+	// write-ahead log.
+	Full bool
+
+	// WALLimit truncates the write-ahead log once it is larger than this
+	// many bytes, rather than only checkpointing it. Zero never truncates.
+	WALLimit int64
+}
+
+// MaintenanceReport describes a maintenance run
+type MaintenanceReport struct {
+	Started      time.Time
+	Duration     time.Duration
+	Full         bool
+	FreedBytes   int64 // Space returned to the file system
+	WALBytes     int64 // Size of the write-ahead log before the checkpoint
+	WALTruncated bool
+}
+
+// DatabaseState is what maintenance would work on
+type DatabaseState struct {
+	WALBytes  int64 // Size of the write-ahead log
+	FreeBytes int64 // Space in the database file no data uses
+}
+
+// MaintenanceStatus summarizes maintenance for statistics
+type MaintenanceStatus struct {
+	DatabaseState
+	Runs    int64              // Since the daemon started
+	LastRun *MaintenanceReport // Nil before the first run
+}
+
+// Maintainer defines the interface for storage that needs routine upkeep
+type Maintainer interface {
+	// Maintain checkpoints the write-ahead log and, with opts.Full, vacuums
+	// and analyzes the database. It holds up writes while it runs.
+	Maintain(ctx context.Context, opts MaintenanceOptions) (*MaintenanceReport, error)
+
+	// DatabaseState returns the sizes maintenance works on
+	DatabaseState(ctx context.Context) (DatabaseState, error)
+}
@@ -0,0 +1,13 @@
+package storage
+
+import (
+	"clipboard-manager/pkg/types"
+	"context"
+)
+
+// Merger defines the interface for collapsing duplicate clips into one
+type Merger interface {
+	// Merge folds the clips in ids into keepID: their use counts are added to
+	// it, it takes the latest last use, and they are deleted
+	Merge(ctx context.Context, keepID string, ids []string) (*types.Clip, error)
+}
@@ -0,0 +1,24 @@
+package storage
+
+import (
+	"context"
+	"time"
+)
+
+// Migration is a versioned change to a backend's schema
+type Migration struct {
+	Version   int
+	Name      string
+	AppliedAt time.Time // Zero while the migration is pending
+}
+
+// MigrationService is implemented by backends that evolve their schema with
+// versioned migrations. Backends apply pending migrations when they open
+// unless Config.NoMigrate is set.
+type MigrationService interface {
+	// Migrations lists every migration in order, applied or pending
+	Migrations(ctx context.Context) ([]Migration, error)
+
+	// Migrate applies the pending migrations in order and returns them
+	Migrate(ctx context.Context) ([]Migration, error)
+}
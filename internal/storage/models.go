@@ -2,8 +2,10 @@ package storage
 
 import (
 	"clipboard-manager/pkg/types"
+	"crypto/rand"
 	"database/sql/driver"  // Provides interfaces for database interaction
 	"encoding/json"        // For JSON encoding/decoding
+	"fmt"
 	"gorm.io/gorm"
 	"strconv"
 	"time"
@@ -53,36 +55,170 @@ func (sa StringArray) Value() (driver.Value, error) {
 	return json.Marshal(sa)
 }
 
+// FormatMap holds alternate representations of a clip keyed by MIME type.
+// Like StringArray it is stored as JSON; byte values are base64 encoded.
+type FormatMap map[string][]byte
+
+// Scan implements sql.Scanner interface
+func (fm *FormatMap) Scan(value interface{}) error {
+	if value == nil {
+		*fm = nil
+		return nil
+	}
+
+	var bytes []byte
+	switch v := value.(type) {
+	case []byte:
+		bytes = v
+	case string:
+		bytes = []byte(v)
+	default:
+		bytes = []byte{}
+	}
+
+	if len(bytes) == 0 {
+		*fm = nil
+		return nil
+	}
+	return json.Unmarshal(bytes, fm)
+}
+
+// Value implements driver.Valuer interface
+func (fm FormatMap) Value() (driver.Value, error) {
+	if len(fm) == 0 {
+		return nil, nil
+	}
+	return json.Marshal(fm)
+}
+
 // ClipModel represents a clipboard entry in storage
 type ClipModel struct {
 	gorm.Model
 	ContentHash string      `gorm:"type:string;uniqueIndex"` // SHA-256 hash for deduplication
-	Content     []byte      `gorm:"type:blob"`              // For inline storage
+	Content     []byte      `gorm:"type:blob"`              // For inline storage, or the head of large text
 	StoragePath string      `gorm:"type:string"`            // For filesystem storage
 	IsExternal  bool        `gorm:"type:boolean"`           // Whether stored in filesystem
 	Size        int64       `gorm:"type:bigint"`            // Content size in bytes
-	Type        string      `gorm:"type:string;not null"`
+	Type        string      `gorm:"type:string;not null;index:idx_clip_models_type_last_used,priority:1"`
 	Metadata    JSON        `gorm:"type:json"`
-	SourceApp   string
+	SourceApp   string      `gorm:"index"`
+	SourceBundleID string   `gorm:"index"`                  // Bundle identifier of the source app
+	SourceURL   string      `gorm:"index"`                  // Page URL for browser copies
+	SourceTitle string                                      // Page title for browser copies
+	SourceWindow string                                     // Title of the window copied from
+	Device      string      `gorm:"index"`                  // Device of Universal Clipboard clips
 	Category    string      `gorm:"index"`
 	Tags        StringArray `gorm:"type:json"`              // Store as JSON in SQLite
-	LastUsed    time.Time   `gorm:"index"`                  // Track when content was last accessed
+	LastUsed    time.Time   `gorm:"index;index:idx_clip_models_type_last_used,priority:2"` // Track when content was last accessed
 	SyncedToObsidian bool   `gorm:"type:boolean;default:false"` // Track if synced to Obsidian
+	Formats     FormatMap   `gorm:"type:json"`              // Alternate representations (e.g. RTF)
+	PlainText   string      `gorm:"type:text"`              // Plain text shadow copy of rich content for search/preview
+	ExpiresAt   *time.Time  `gorm:"index"`                  // When the clip is deleted automatically
+	UseCount    int64       `gorm:"default:1;index"`        // Times the content was copied
+	Screenshot  *types.Screenshot `gorm:"serializer:json"`  // Capture details of screenshots
+	Link        *types.LinkPreview `gorm:"serializer:json"` // Preview of the page a link points to
+	Language    string                                      // Language of code snippets
+	Media       *types.Media `gorm:"serializer:json"`       // Details of audio and video files
+	MediaType   string      `gorm:"index"`                  // MIME type of Media, for type: queries
+	Pinned      bool        `gorm:"index;default:false"`    // Kept at the top of the history
+	UUID        string      `gorm:"uniqueIndex"`            // Identifies the clip across devices, set on create
+	File        *types.FileRef `gorm:"serializer:json"`     // Snapshot of the file a file clip points to
+	FileCopy    string                                      // Hash of the kept copy of File, see FileCopier
+	NormalizedHash string   `gorm:"index"`                  // NormalizedHash of plain text, for near-duplicates
+	Session     string      `gorm:"index"`                  // Copy session the clip was last copied in
+}
+
+// Uses returns how many times the clip's content was copied. Clips stored
+// before copies were counted report one.
+func (cm *ClipModel) Uses() int64 {
+	if cm.UseCount < 1 {
+		return 1
+	}
+	return cm.UseCount
+}
+
+// Recopied records that the clip's content, data, was copied again with
+// metadata, and returns the columns that changed so only those are written.
+// Copying content that is in the trash restores it.
+func (cm *ClipModel) Recopied(metadata types.Metadata, data []byte) []string {
+	cm.LastUsed = time.Now()
+	cm.UseCount = cm.Uses() + 1
+	// The most recent copy decides whether the clip expires
+	cm.ExpiresAt = metadata.ExpiresAt
+	cm.DeletedAt = gorm.DeletedAt{}
+	columns := []string{"last_used", "use_count", "expires_at", "deleted_at"}
+
+	// Keep any representations we didn't have before
+	added := false
+	for format, formatData := range metadata.Formats {
+		if cm.Formats == nil {
+			cm.Formats = FormatMap{}
+		}
+		if _, ok := cm.Formats[format]; !ok {
+			cm.Formats[format] = formatData
+			added = true
+		}
+	}
+	if added {
+		columns = append(columns, "formats")
+	}
+	// and what the file it points to looks like
+	if metadata.File != nil {
+		cm.File = metadata.File
+		columns = append(columns, "file")
+	}
+	// and which copy session it was copied in
+	if metadata.Session != "" {
+		cm.Session = metadata.Session
+		columns = append(columns, "session")
+	}
+	// Clips from before normalized hashes get one
+	if cm.NormalizedHash == "" {
+		cm.NormalizedHash = NormalizedHash(data, cm.Type)
+		columns = append(columns, "normalized_hash")
+	}
+	return columns
+}
+
+// HasHead reports whether the clip is stored in a file with its head kept
+// in Content, as large text clips are. Lists can show the head instead of
+// reading the file.
+func (cm *ClipModel) HasHead() bool {
+	return cm.IsExternal && len(cm.Content) > 0
 }
 
 // ToClip converts ClipModel to public Clip type
 func (cm *ClipModel) ToClip() *types.Clip {
-	return &types.Clip{
+	clip := &types.Clip{
 		ID:      strconv.FormatUint(uint64(cm.ID), 10),
 		Content: cm.Content,
 		Type:    cm.Type,
 		Metadata: types.Metadata{
 			SourceApp: cm.SourceApp,
+			SourceBundleID: cm.SourceBundleID,
+			SourceURL:   cm.SourceURL,
+			SourceTitle: cm.SourceTitle,
+			SourceWindow: cm.SourceWindow,
+			Device:      cm.Device,
 			Tags:      cm.Tags,
 			Category:  cm.Category,
+			Formats:   cm.Formats,
+			ExpiresAt: cm.ExpiresAt,
+			Screenshot: cm.Screenshot,
+			Link:       cm.Link,
+			Language:   cm.Language,
+			Media:      cm.Media,
+			File:       cm.fileRef(),
+			Session:    cm.Session,
 		},
 		CreatedAt: cm.CreatedAt,
+		Truncated: cm.HasHead() && int64(len(cm.Content)) < cm.Size,
+	}
+	if cm.DeletedAt.Valid {
+		deletedAt := cm.DeletedAt.Time
+		clip.DeletedAt = &deletedAt
 	}
+	return clip
 }
 
 // FromClip creates a ClipModel from public Clip type
@@ -91,14 +227,136 @@ func FromClip(clip *types.Clip) *ClipModel {
 		Content:   clip.Content,
 		Type:      clip.Type,
 		SourceApp: clip.Metadata.SourceApp,
+		SourceBundleID: clip.Metadata.SourceBundleID,
+		SourceURL:   clip.Metadata.SourceURL,
+		SourceTitle: clip.Metadata.SourceTitle,
+		SourceWindow: clip.Metadata.SourceWindow,
+		Device:      clip.Metadata.Device,
 		Category:  clip.Metadata.Category,
 		Tags:      clip.Metadata.Tags,
+		Formats:   clip.Metadata.Formats,
+		PlainText: string(clip.Metadata.Formats[FormatPlainText]),
+		ExpiresAt: clip.Metadata.ExpiresAt,
+		Screenshot: clip.Metadata.Screenshot,
+		Link:       clip.Metadata.Link,
+		Language:   clip.Metadata.Language,
+		Media:      clip.Metadata.Media,
+		MediaType:  MediaType(clip.Metadata.Media),
+		File:       clip.Metadata.File,
+		Session:    clip.Metadata.Session,
 		LastUsed:  time.Now(),
 	}
 }
 
+// fileRef returns the clip's file snapshot, noting whether the kept copy
+// still matches it
+func (cm *ClipModel) fileRef() *types.FileRef {
+	if cm.File == nil {
+		return nil
+	}
+	ref := *cm.File
+	ref.Copied = cm.FileCopy != "" && cm.FileCopy == ref.Hash
+	return &ref
+}
+
+// MediaType returns the MIME type of media, or "" when there is none
+func MediaType(media *types.Media) string {
+	if media == nil {
+		return ""
+	}
+	return media.MIMEType
+}
+
+// AppModel caches display information for a source application
+type AppModel struct {
+	BundleID  string `gorm:"primaryKey"`
+	Name      string
+	Icon      []byte `gorm:"type:blob"` // PNG icon
+	UpdatedAt time.Time
+}
+
+// BlobModel tracks how many clips share an external file. Files are named by
+// content hash, so the file is only removed once nothing references it.
+type BlobModel struct {
+	Hash      string `gorm:"primaryKey"`
+	Size      int64
+	RefCount  int64
+	CreatedAt time.Time
+}
+
+// VersionModel keeps content a clip had before it was edited
+type VersionModel struct {
+	ID        uint      `gorm:"primaryKey"`
+	ClipID    uint      `gorm:"uniqueIndex:idx_clip_version"`
+	Version   int       `gorm:"uniqueIndex:idx_clip_version"`
+	Content   []byte    `gorm:"type:blob"`
+	Type      string    `gorm:"type:string;not null"`
+	Formats   FormatMap `gorm:"type:json"`
+	CreatedAt time.Time
+}
+
+// ToVersion converts VersionModel to ClipVersion
+func (vm *VersionModel) ToVersion() ClipVersion {
+	return ClipVersion{
+		Version:   vm.Version,
+		Content:   vm.Content,
+		Type:      vm.Type,
+		Formats:   vm.Formats,
+		CreatedAt: vm.CreatedAt,
+	}
+}
+
+// ChangeModel records a change to a clip for the ChangeFeed
+type ChangeModel struct {
+	Seq       int64  `gorm:"primaryKey;autoIncrement"`
+	ClipID    uint   `gorm:"index"`
+	UUID      string
+	Op        string `gorm:"not null"`
+	ChangedAt time.Time
+}
+
+// SyncStateModel tracks failed attempts to sync a clip, see SyncStateService
+type SyncStateModel struct {
+	ClipID      uint `gorm:"primaryKey;autoIncrement:false"`
+	Attempts    int
+	LastError   string
+	LastAttempt time.Time
+	NextRetry   time.Time
+}
+
+// ToSyncState converts SyncStateModel to SyncState
+func (sm *SyncStateModel) ToSyncState() SyncState {
+	return SyncState{
+		ClipID:      strconv.FormatUint(uint64(sm.ClipID), 10),
+		Attempts:    sm.Attempts,
+		LastError:   sm.LastError,
+		LastAttempt: sm.LastAttempt,
+		NextRetry:   sm.NextRetry,
+		GivenUp:     sm.Attempts >= MaxSyncAttempts,
+	}
+}
+
 // BeforeSave GORM hook to update LastUsed timestamp
 func (cm *ClipModel) BeforeSave(tx *gorm.DB) error {
 	cm.LastUsed = time.Now()
 	return nil
 }
+
+// BeforeCreate GORM hook to give new clips a UUID
+func (cm *ClipModel) BeforeCreate(tx *gorm.DB) error {
+	if cm.UUID == "" {
+		cm.UUID = NewUUID()
+	}
+	return nil
+}
+
+// NewUUID returns a random (version 4) UUID
+func NewUUID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(fmt.Sprintf("storage: failed to read random bytes: %v", err))
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"reflect"
+	"testing"
+	"unicode/utf8"
+)
+
+// FuzzStringArray scans whatever is stored in a tags column and checks the
+// tags survive a roundtrip through Value and Scan
+func FuzzStringArray(f *testing.F) {
+	for _, seed := range []string{`["work","home"]`, `[]`, `null`, `["a\"b","é"]`, `[1]`, `{`, ``, `"tag"`} {
+		f.Add([]byte(seed))
+	}
+
+	f.Fuzz(func(t *testing.T, stored []byte) {
+		var tags StringArray
+		if err := tags.Scan(stored); err != nil {
+			return // Malformed, but rejected rather than panicking
+		}
+		for _, tag := range tags {
+			if !utf8.ValidString(tag) {
+				return // JSON replaces invalid UTF-8, so it can't roundtrip
+			}
+		}
+
+		value, err := tags.Value()
+		if err != nil {
+			t.Fatalf("Value of %q failed: %v", tags, err)
+		}
+		var scanned StringArray
+		if err := scanned.Scan(value); err != nil {
+			t.Fatalf("Scan of %s failed: %v", value, err)
+		}
+		if len(tags) == 0 {
+			if len(scanned) != 0 {
+				t.Fatalf("%q scanned back as %q", tags, scanned)
+			}
+			return
+		}
+		if !reflect.DeepEqual(scanned, tags) {
+			t.Fatalf("%q scanned back as %q", tags, scanned)
+		}
+	})
+}
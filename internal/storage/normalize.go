@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"clipboard-manager/pkg/types"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// NormalizeText trims text, collapses each run of whitespace to a space and
+// puts it in Unicode normal form C, so copies of the same text that differ
+// only in those ways compare equal
+func NormalizeText(text string) string {
+	return strings.Join(strings.Fields(norm.NFC.String(text)), " ")
+}
+
+// NormalizedHash returns the SHA-256 hash of content normalized by
+// NormalizeText, or "" for content that isn't kept whole in the database as
+// plain text, or that is only whitespace
+func NormalizedHash(content []byte, clipType string) string {
+	if clipType != FormatPlainText && clipType != TypeText {
+		return ""
+	}
+	if len(content) == 0 || len(content) > MaxInlineTextSize {
+		return ""
+	}
+	normalized := NormalizeText(string(content))
+	if normalized == "" {
+		return ""
+	}
+	hash := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(hash[:])
+}
+
+// NormalizedFinder is implemented by backends that keep the NormalizedHash
+// of clips. Clips stored before it was kept get theirs the next time their
+// content is stored again.
+type NormalizedFinder interface {
+	// FindNormalized returns the most recently used clip, even one in the
+	// trash, whose content has the normalized hash, or ErrNotFound
+	FindNormalized(ctx context.Context, hash string) (*types.Clip, error)
+}
@@ -0,0 +1,52 @@
+package storage
+
+import "testing"
+
+func TestNormalizeText(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"ls -la\n", "ls -la"},
+		{"  ls\t -la  ", "ls -la"},
+		{"line one\r\n\r\nline two", "line one line two"},
+		{"cafe\u0301", "caf\u00e9"}, // Decomposed accent
+		{" \n\t", ""},
+	}
+	for _, test := range tests {
+		if got := NormalizeText(test.input); got != test.want {
+			t.Errorf("NormalizeText(%q) = %q, want %q", test.input, got, test.want)
+		}
+	}
+}
+
+func TestNormalizedHash(t *testing.T) {
+	hash := NormalizedHash([]byte("ls -la"), FormatPlainText)
+	if hash == "" {
+		t.Fatal("text has no normalized hash")
+	}
+	if got := NormalizedHash([]byte("ls -la\n"), FormatPlainText); got != hash {
+		t.Errorf("text with a trailing newline hashes to %s, want %s", got, hash)
+	}
+	if got := NormalizedHash([]byte("ls  -la"), TypeText); got != hash {
+		t.Errorf("text clip hashes to %s, want %s", got, hash)
+	}
+	if got := NormalizedHash([]byte("ls -l"), FormatPlainText); got == hash {
+		t.Error("different text has the same normalized hash")
+	}
+
+	for _, test := range []struct {
+		content  []byte
+		clipType string
+	}{
+		{[]byte("<b>ls</b>"), "text/html"},
+		{[]byte("\x89PNG"), "image/png"},
+		{[]byte("  \n"), FormatPlainText},
+		{nil, FormatPlainText},
+		{make([]byte, MaxInlineTextSize+1), FormatPlainText},
+	} {
+		if got := NormalizedHash(test.content, test.clipType); got != "" {
+			t.Errorf("%s clip of %d bytes has normalized hash %s", test.clipType, len(test.content), got)
+		}
+	}
+}
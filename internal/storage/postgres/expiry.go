@@ -0,0 +1,38 @@
+package postgres
+
+import (
+	"clipboard-manager/internal/storage"
+	"clipboard-manager/pkg/types"
+	"context"
+	"fmt"
+	"time"
+)
+
+// SetExpiry implements storage.ExpiryService interface
+func (s *PostgresStorage) SetExpiry(ctx context.Context, id string, expiresAt *time.Time) (*types.Clip, error) {
+	var model storage.ClipModel
+	if err := s.db.WithContext(ctx).First(&model, "id = ?", id).Error; err != nil {
+		return nil, fmt.Errorf("failed to get clip: %w", notFound(err))
+	}
+
+	if err := s.db.WithContext(ctx).Model(&model).
+		UpdateColumn("expires_at", expiresAt).Error; err != nil {
+		return nil, fmt.Errorf("failed to set clip expiry: %w", err)
+	}
+	model.ExpiresAt = expiresAt
+
+	return model.ToClip(), nil
+}
+
+// DeleteExpired implements storage.ExpiryService interface
+func (s *PostgresStorage) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	var models []storage.ClipModel
+	if err := s.db.WithContext(ctx).Unscoped().
+		Select("id", "storage_path", "is_external").
+		Where("expires_at IS NOT NULL AND expires_at <= ?", now).
+		Find(&models).Error; err != nil {
+		return 0, fmt.Errorf("failed to list expired clips: %w", err)
+	}
+
+	return s.purge(ctx, models)
+}
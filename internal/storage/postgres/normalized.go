@@ -0,0 +1,18 @@
+package postgres
+
+import (
+	"clipboard-manager/internal/storage"
+	"clipboard-manager/pkg/types"
+	"context"
+	"fmt"
+)
+
+// FindNormalized implements storage.NormalizedFinder interface
+func (s *PostgresStorage) FindNormalized(ctx context.Context, hash string) (*types.Clip, error) {
+	var model storage.ClipModel
+	if err := s.db.WithContext(ctx).Unscoped().Where("normalized_hash = ?", hash).
+		Order("last_used DESC").First(&model).Error; err != nil {
+		return nil, fmt.Errorf("failed to find clip: %w", notFound(err))
+	}
+	return model.ToClip(), nil
+}
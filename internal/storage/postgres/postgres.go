@@ -0,0 +1,472 @@
+package postgres
+
+import (
+	"bytes"
+	"clipboard-manager/internal/storage"
+	"clipboard-manager/pkg/types"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func init() {
+	storage.Register("postgres", func(config storage.Config) (storage.Storage, error) {
+		return New(config)
+	})
+}
+
+// PostgresStorage stores clips in a PostgreSQL database, which is useful
+// when the daemon runs on a shared or home server
+type PostgresStorage struct {
+	db     *gorm.DB
+	fsPath string // Base path for file system storage
+}
+
+// New creates a new Postgres storage instance
+func New(config storage.Config) (*PostgresStorage, error) {
+	if config.DSN == "" {
+		return nil, fmt.Errorf("postgres DSN is required")
+	}
+
+	db, err := gorm.Open(postgres.Open(config.DSN), &gorm.Config{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get underlying *sql.DB: %w", err)
+	}
+
+	// Configure connection pool
+	sqlDB.SetMaxOpenConns(10)
+	sqlDB.SetMaxIdleConns(2)
+	sqlDB.SetConnMaxLifetime(time.Hour)
+
+	// The shared models declare binary columns as blob, which Postgres
+	// doesn't have; map it onto bytea before migrating
+	if err := db.Exec(`
+		DO $$ BEGIN
+			CREATE DOMAIN blob AS bytea;
+		EXCEPTION WHEN duplicate_object THEN NULL;
+		END $$;
+	`).Error; err != nil {
+		return nil, fmt.Errorf("failed to create blob domain: %w", err)
+	}
+
+	if err := db.AutoMigrate(&storage.ClipModel{}, &storage.AppModel{}, &storage.BlobModel{}, &storage.VersionModel{}); err != nil {
+		return nil, fmt.Errorf("failed to migrate schema: %w", err)
+	}
+
+	// Clips from before UUIDs get one, since saving them would otherwise
+	// store the same empty UUID twice
+	if err := db.Exec(`UPDATE clip_models SET uuid = gen_random_uuid()::text WHERE uuid IS NULL`).Error; err != nil {
+		return nil, fmt.Errorf("failed to assign clip UUIDs: %w", err)
+	}
+
+	// Types older versions stored under other names get the names
+	// types.NormalizeType gives them
+	if err := db.Exec(`
+		UPDATE clip_models SET type = 'text/plain' WHERE type IN ('text', 'text/plain;charset=utf-8', 'text/plain; charset=utf-8');
+		UPDATE clip_models SET type = 'image/jpeg' WHERE type = 'image/jpg';
+		UPDATE version_models SET type = 'text/plain' WHERE type IN ('text', 'text/plain;charset=utf-8', 'text/plain; charset=utf-8');
+		UPDATE version_models SET type = 'image/jpeg' WHERE type = 'image/jpg';
+	`).Error; err != nil {
+		return nil, fmt.Errorf("failed to migrate clip types: %w", err)
+	}
+
+	if err := db.Exec(`
+		CREATE INDEX IF NOT EXISTS idx_clips_content_hash ON clip_models(content_hash);
+		CREATE INDEX IF NOT EXISTS idx_clips_last_used ON clip_models(last_used);
+	`).Error; err != nil {
+		return nil, fmt.Errorf("failed to create indexes: %w", err)
+	}
+
+	if config.FSPath != "" {
+		if err := os.MkdirAll(config.FSPath, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create storage directory: %w", err)
+		}
+	}
+
+	return &PostgresStorage{
+		db:     db,
+		fsPath: config.FSPath,
+	}, nil
+}
+
+// calculateHash generates SHA-256 hash of content
+func calculateHash(content []byte) string {
+	hash := sha256.Sum256(content)
+	return hex.EncodeToString(hash[:])
+}
+
+// Close closes the database connection
+func (s *PostgresStorage) Close() error {
+	sqlDB, err := s.db.DB()
+	if err != nil {
+		return fmt.Errorf("failed to get underlying *sql.DB: %w", err)
+	}
+	if err := sqlDB.Close(); err != nil {
+		return fmt.Errorf("failed to close database: %w", err)
+	}
+	return nil
+}
+
+// Store implements storage.Storage interface
+func (s *PostgresStorage) Store(ctx context.Context, content []byte, clipType string, metadata types.Metadata) (*types.Clip, error) {
+	size := int64(len(content))
+	if size > storage.MaxStorageSize {
+		return nil, storage.ErrFileTooLarge
+	}
+
+	return s.storeContent(&storage.SpooledContent{
+		Hash: calculateHash(content),
+		Size: size,
+		Data: content,
+	}, clipType, metadata)
+}
+
+// StoreStream implements storage.Storage interface
+func (s *PostgresStorage) StoreStream(ctx context.Context, r io.Reader, clipType string, metadata types.Metadata) (*types.Clip, error) {
+	spooled, err := storage.Spool(r, s.fsPath)
+	if err != nil {
+		return nil, err
+	}
+	defer spooled.Discard()
+
+	return s.storeContent(spooled, clipType, metadata)
+}
+
+// storeContent saves content that has already been hashed and size checked
+func (s *PostgresStorage) storeContent(content *storage.SpooledContent, clipType string, metadata types.Metadata) (*types.Clip, error) {
+	clipType = types.NormalizeType(clipType)
+	size := content.Size
+	contentHash := content.Hash
+
+	// Cache source app details
+	if err := s.saveApp(metadata); err != nil {
+		return nil, err
+	}
+
+	// Check for existing content with same hash
+	// Content hashes are unique, so copying content that is in the trash restores it
+	var existing storage.ClipModel
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).Where("content_hash = ?", contentHash).First(&existing).Error; err != nil {
+			return err
+		}
+		// Only the columns a copy changes are written, so a file copy, pin or
+		// edit made since the clip was read isn't undone
+		return tx.Unscoped().Model(&existing).Select(existing.Recopied(metadata, content.Data)).Updates(&existing).Error
+	})
+	if err == nil {
+		return existing.ToClip(), nil
+	} else if err != gorm.ErrRecordNotFound {
+		return nil, fmt.Errorf("failed to update existing clip: %w", err)
+	}
+
+	model := &storage.ClipModel{
+		ContentHash:    contentHash,
+		Type:           clipType,
+		Size:           size,
+		SourceApp:      metadata.SourceApp,
+		SourceBundleID: metadata.SourceBundleID,
+		SourceURL:      metadata.SourceURL,
+		SourceTitle:    metadata.SourceTitle,
+		SourceWindow:   metadata.SourceWindow,
+		Device:         metadata.Device,
+		Category:       metadata.Category,
+		Tags:           metadata.Tags,
+		Formats:        metadata.Formats,
+		PlainText:      string(metadata.Formats[storage.FormatPlainText]),
+		ExpiresAt:      metadata.ExpiresAt,
+		Screenshot:     metadata.Screenshot,
+		Link:           metadata.Link,
+		Language:       metadata.Language,
+		Media:          metadata.Media,
+		MediaType:      storage.MediaType(metadata.Media),
+		File:           metadata.File,
+		LastUsed:       time.Now(),
+		UseCount:       1,
+		NormalizedHash: storage.NormalizedHash(content.Data, clipType),
+		Session:        metadata.Session,
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if storage.StoredExternally(clipType, size) && s.fsPath != "" {
+			// Large text keeps its head in the database for searches and lists
+			if storage.IsText(clipType) {
+				head, err := content.Head(storage.TextHeadSize)
+				if err != nil {
+					return err
+				}
+				model.Content = head
+			}
+			// Store in filesystem, sharing the file with any clip that has the same content
+			if err := s.acquireBlob(tx, content); err != nil {
+				return err
+			}
+			model.StoragePath = contentHash
+			model.IsExternal = true
+		} else {
+			model.Content = content.Data
+		}
+
+		if err := tx.Create(model).Error; err != nil {
+			return fmt.Errorf("failed to create clip: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return model.ToClip(), nil
+}
+
+// Get implements storage.Storage interface
+func (s *PostgresStorage) Get(ctx context.Context, id string) (*types.Clip, error) {
+	var model storage.ClipModel
+	if err := s.db.First(&model, "id = ?", id).Error; err != nil {
+		return nil, fmt.Errorf("failed to get clip: %w", notFound(err))
+	}
+
+	if err := s.loadContent(&model); err != nil {
+		return nil, err
+	}
+
+	// Update LastUsed timestamp, and nothing else: saving the whole model
+	// would undo changes made since it was read, such as a file copy
+	model.LastUsed = time.Now()
+	if err := s.db.Model(&model).Update("last_used", model.LastUsed).Error; err != nil {
+		return nil, fmt.Errorf("failed to update last used time: %w", err)
+	}
+
+	return model.ToClip(), nil
+}
+
+// GetStream implements storage.Storage interface
+func (s *PostgresStorage) GetStream(ctx context.Context, id string) (io.ReadCloser, *types.Clip, error) {
+	var model storage.ClipModel
+	if err := s.db.First(&model, "id = ?", id).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to get clip: %w", notFound(err))
+	}
+
+	// Update LastUsed timestamp, and nothing else: saving the whole model
+	// would undo changes made since it was read, such as a file copy
+	model.LastUsed = time.Now()
+	if err := s.db.Model(&model).Update("last_used", model.LastUsed).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to update last used time: %w", err)
+	}
+
+	if !model.IsExternal {
+		content := model.Content
+		model.Content = nil
+		return io.NopCloser(bytes.NewReader(content)), model.ToClip(), nil
+	}
+
+	file, err := os.Open(filepath.Join(s.fsPath, model.StoragePath))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open external content: %w", err)
+	}
+	return file, model.ToClip(), nil
+}
+
+// Delete implements storage.Storage interface
+func (s *PostgresStorage) Delete(ctx context.Context, id string) error {
+	var model storage.ClipModel
+	if err := s.db.First(&model, "id = ?", id).Error; err != nil {
+		return fmt.Errorf("failed to get clip: %w", notFound(err))
+	}
+
+	// Soft delete moves the clip to the trash; its content is kept until purged
+	if err := s.db.Delete(&model).Error; err != nil {
+		return fmt.Errorf("failed to delete clip: %w", err)
+	}
+
+	return nil
+}
+
+// List implements storage.Storage interface
+func (s *PostgresStorage) List(ctx context.Context, filter storage.ListFilter) ([]*types.Clip, error) {
+	query := s.db.Model(&storage.ClipModel{})
+
+	if filter.Type != "" {
+		query = query.Where("type = ?", types.NormalizeType(filter.Type))
+	}
+	if filter.Category != "" {
+		query = query.Where("category = ?", filter.Category)
+	}
+	if len(filter.Tags) > 0 {
+		tags, err := json.Marshal(filter.Tags)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode tags: %w", err)
+		}
+		query = query.Where("tags::jsonb @> ?::jsonb", string(tags))
+	}
+	if filter.SyncedToObsidian != nil {
+		query = query.Where("synced_to_obsidian = ?", *filter.SyncedToObsidian)
+	}
+
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	if filter.Offset > 0 {
+		query = query.Offset(filter.Offset)
+	}
+
+	query = query.Order("last_used DESC")
+
+	var models []storage.ClipModel
+	if err := query.Find(&models).Error; err != nil {
+		return nil, fmt.Errorf("failed to list clips: %w", err)
+	}
+
+	return s.toClips(models)
+}
+
+// MarkAsSynced implements storage.Storage interface
+func (s *PostgresStorage) MarkAsSynced(ctx context.Context, id string) error {
+	result := s.db.Model(&storage.ClipModel{}).
+		Where("id = ?", id).
+		Update("synced_to_obsidian", true)
+
+	if result.Error != nil {
+		return fmt.Errorf("failed to mark clip as synced: %w", result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("no clip found with id: %s", id)
+	}
+
+	return nil
+}
+
+// ListUnsynced implements storage.Storage interface
+func (s *PostgresStorage) ListUnsynced(ctx context.Context, limit int) ([]*types.Clip, error) {
+	query := s.db.Model(&storage.ClipModel{}).
+		Where("synced_to_obsidian = ?", false).
+		Where("expires_at IS NULL"). // Sensitive clips never leave the machine
+		Order("created_at DESC")
+
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	var models []storage.ClipModel
+	if err := query.Find(&models).Error; err != nil {
+		return nil, fmt.Errorf("failed to list unsynced clips: %w", err)
+	}
+
+	return s.toClips(models)
+}
+
+// saveApp records the source application of a clip, keeping the first icon we get
+func (s *PostgresStorage) saveApp(metadata types.Metadata) error {
+	if metadata.SourceBundleID == "" {
+		return nil
+	}
+
+	app := storage.AppModel{
+		BundleID: metadata.SourceBundleID,
+		Name:     metadata.SourceApp,
+		Icon:     metadata.SourceIcon,
+	}
+
+	updates := []string{"name", "updated_at"}
+	if len(metadata.SourceIcon) > 0 {
+		updates = append(updates, "icon")
+	}
+
+	if err := s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "bundle_id"}},
+		DoUpdates: clause.AssignmentColumns(updates),
+	}).Create(&app).Error; err != nil {
+		return fmt.Errorf("failed to save source app: %w", err)
+	}
+	return nil
+}
+
+// ListApps implements storage.AppService interface
+func (s *PostgresStorage) ListApps(ctx context.Context) ([]storage.AppInfo, error) {
+	var apps []storage.AppInfo
+	err := s.db.Model(&storage.ClipModel{}).
+		Select("clip_models.source_bundle_id AS bundle_id, " +
+			"COALESCE(MAX(app_models.name), MAX(clip_models.source_app)) AS name, " +
+			"COUNT(*) AS count, " +
+			"COALESCE(BOOL_OR(app_models.icon IS NOT NULL), false) AS has_icon").
+		Joins("LEFT JOIN app_models ON app_models.bundle_id = clip_models.source_bundle_id").
+		Where("clip_models.source_bundle_id <> ''").
+		Group("clip_models.source_bundle_id").
+		Order("count DESC").
+		Scan(&apps).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list apps: %w", err)
+	}
+	return apps, nil
+}
+
+// GetAppIcon implements storage.AppService interface
+func (s *PostgresStorage) GetAppIcon(ctx context.Context, bundleID string) ([]byte, error) {
+	var app storage.AppModel
+	if err := s.db.Where("bundle_id = ?", bundleID).First(&app).Error; err != nil {
+		return nil, fmt.Errorf("failed to get app: %w", err)
+	}
+	if len(app.Icon) == 0 {
+		return nil, fmt.Errorf("no icon cached for app: %s", bundleID)
+	}
+	return app.Icon, nil
+}
+
+// loadContent reads the content of externally stored clips
+func (s *PostgresStorage) loadContent(model *storage.ClipModel) error {
+	if !model.IsExternal {
+		return nil
+	}
+	content, err := os.ReadFile(filepath.Join(s.fsPath, model.StoragePath))
+	if err != nil {
+		return fmt.Errorf("failed to read external content for clip %d: %w", model.ID, err)
+	}
+	model.Content = content
+	return nil
+}
+
+// loadPreview reads the content of externally stored clips for lists, where
+// large text only shows the head kept in the database
+func (s *PostgresStorage) loadPreview(model *storage.ClipModel) error {
+	if model.HasHead() {
+		return nil
+	}
+	return s.loadContent(model)
+}
+
+// toClips converts models to clips, loading external content
+func (s *PostgresStorage) toClips(models []storage.ClipModel) ([]*types.Clip, error) {
+	clips := make([]*types.Clip, len(models))
+	for i := range models {
+		if err := s.loadPreview(&models[i]); err != nil {
+			return nil, err
+		}
+		clips[i] = models[i].ToClip()
+	}
+	return clips, nil
+}
+
+// notFound reports a missing row as storage.ErrNotFound
+func notFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return storage.ErrNotFound
+	}
+	return err
+}
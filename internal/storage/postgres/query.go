@@ -0,0 +1,60 @@
+package postgres
+
+import (
+	"clipboard-manager/internal/storage"
+	"strings"
+)
+
+// queryCondition translates a parsed query into a WHERE condition
+func queryCondition(query storage.Query) (string, []interface{}) {
+	var groups []string
+	var args []interface{}
+	for _, group := range query.Groups {
+		var terms []string
+		for _, term := range group {
+			condition, termArgs := termCondition(term)
+			terms = append(terms, condition)
+			args = append(args, termArgs...)
+		}
+		groups = append(groups, "("+strings.Join(terms, " AND ")+")")
+	}
+	return "(" + strings.Join(groups, " OR ") + ")", args
+}
+
+// termCondition translates one query term
+func termCondition(term storage.Term) (string, []interface{}) {
+	like := "%" + term.Value + "%"
+	switch term.Field {
+	case storage.FieldType:
+		return "(type = ? OR type LIKE ? OR media_type = ? OR media_type LIKE ?)",
+			[]interface{}{term.Value, term.Value + "/%", term.Value, term.Value + "/%"}
+	case storage.FieldApp:
+		return "(LOWER(source_app) LIKE ? OR LOWER(source_bundle_id) LIKE ?)", []interface{}{like, like}
+	case storage.FieldTag:
+		return "LOWER(tags::text) LIKE ?", []interface{}{"%\"" + term.Value + "\"%"}
+	case storage.FieldFormat:
+		return "(type = ? OR jsonb_exists(formats::jsonb, ?))", []interface{}{term.Value, term.Value}
+	case storage.FieldURL:
+		return "LOWER(source_url) LIKE ?", []interface{}{like}
+	case storage.FieldDevice:
+		return "LOWER(device) LIKE ?", []interface{}{like}
+	case storage.FieldWindow:
+		return "LOWER(source_window) LIKE ?", []interface{}{like}
+	case storage.FieldCategory:
+		return "LOWER(category) = ?", []interface{}{term.Value}
+	case storage.FieldBefore:
+		return "created_at < ?", []interface{}{term.Time}
+	case storage.FieldAfter:
+		return "created_at >= ?", []interface{}{term.Time}
+	}
+
+	// CASE guarantees binary content is never decoded as UTF-8
+	return "((type LIKE 'text%' AND " +
+			"  LOWER(CASE WHEN type LIKE 'text%' THEN convert_from(content, 'UTF8') END) LIKE ?) OR " +
+			"LOWER(content_hash) LIKE ? OR " +
+			"LOWER(plain_text) LIKE ? OR " +
+			"LOWER(source_app) LIKE ? OR " +
+			"LOWER(category) LIKE ? OR " +
+			"LOWER(tags::text) LIKE ?)",
+		[]interface{}{like, like, like, like, like, like}
+}
@@ -0,0 +1,70 @@
+package postgres
+
+import (
+	"clipboard-manager/internal/storage"
+	"context"
+	"fmt"
+	"strconv"
+)
+
+// evictBatch caps the clips looked at for each round of eviction
+const evictBatch = 100
+
+// Evict implements storage.Evictor interface
+func (s *PostgresStorage) Evict(ctx context.Context, limit int64) (*storage.Eviction, error) {
+	used, err := s.contentBytes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	eviction := &storage.Eviction{UsedBytes: used}
+
+	for eviction.UsedBytes > limit {
+		var models []storage.ClipModel
+		if err := s.db.WithContext(ctx).Unscoped().
+			Select("id", "storage_path", "is_external", "size").
+			// Pinned clips have the column set, or the tag from before it
+			Where("NOT COALESCE(pinned, false) AND COALESCE(tags::text, '') NOT LIKE ?", `%"pinned"%`).
+			Order("deleted_at IS NULL, last_used, id").
+			Limit(evictBatch).
+			Find(&models).Error; err != nil {
+			return eviction, fmt.Errorf("failed to list clips to evict: %w", err)
+		}
+		if len(models) == 0 {
+			break
+		}
+
+		// Take the clips that should make enough room. A file other clips
+		// share frees nothing, which the next round makes up for.
+		n, need := 0, eviction.UsedBytes-limit
+		for n < len(models) && need > 0 {
+			need -= models[n].Size
+			n++
+		}
+		purged, err := s.purge(ctx, models[:n])
+		for _, model := range models[:purged] {
+			eviction.IDs = append(eviction.IDs, strconv.FormatUint(uint64(model.ID), 10))
+		}
+		if err != nil {
+			return eviction, err
+		}
+
+		if used, err = s.contentBytes(ctx); err != nil {
+			return eviction, err
+		}
+		eviction.FreedBytes += eviction.UsedBytes - used
+		eviction.UsedBytes = used
+	}
+	return eviction, nil
+}
+
+// contentBytes returns the size of the content quotas cover
+func (s *PostgresStorage) contentBytes(ctx context.Context) (int64, error) {
+	var bytes int64
+	if err := s.db.WithContext(ctx).Raw(`SELECT
+		(SELECT COALESCE(SUM(size), 0) FROM clip_models WHERE NOT is_external) +
+		(SELECT COALESCE(SUM(size), 0) FROM blob_models)`).
+		Scan(&bytes).Error; err != nil {
+		return 0, fmt.Errorf("failed to get content size: %w", err)
+	}
+	return bytes, nil
+}
@@ -0,0 +1,128 @@
+package postgres
+
+import (
+	"clipboard-manager/internal/storage"
+	"clipboard-manager/pkg/types"
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Search implements storage.SearchService interface
+func (s *PostgresStorage) Search(ctx context.Context, opts storage.SearchOptions) ([]storage.SearchResult, error) {
+	query := s.db.WithContext(ctx).Model(&storage.ClipModel{})
+
+	// Apply the query language, see storage.ParseQuery
+	parsed, err := storage.ParseQuery(opts.Query)
+	if err != nil {
+		return nil, err
+	}
+	if !parsed.Empty() {
+		condition, args := queryCondition(parsed)
+		query = query.Where(condition, args...)
+	}
+
+	// Apply filters
+	if opts.Type != "" {
+		query = query.Where("type = ?", types.NormalizeType(opts.Type))
+	}
+	if opts.Format != "" {
+		query = query.Where("(type = ? OR jsonb_exists(formats::jsonb, ?))", opts.Format, opts.Format)
+	}
+	if opts.SourceApp != "" {
+		query = query.Where("source_app = ?", opts.SourceApp)
+	}
+	if opts.SourceBundleID != "" {
+		query = query.Where("source_bundle_id = ?", opts.SourceBundleID)
+	}
+	if opts.SourceURL != "" {
+		query = query.Where("LOWER(source_url) LIKE ?", "%"+strings.ToLower(opts.SourceURL)+"%")
+	}
+	if opts.Device != "" {
+		query = query.Where("LOWER(device) LIKE ?", "%"+strings.ToLower(opts.Device)+"%")
+	}
+	if opts.Category != "" {
+		query = query.Where("category = ?", opts.Category)
+	}
+	for _, tag := range opts.Tags {
+		query = query.Where("tags::text LIKE ?", "%"+tag+"%")
+	}
+
+	// Apply the cursor, see storage.Cursor
+	usesCursor, err := opts.UsesCursor()
+	if err != nil {
+		return nil, err
+	}
+	if usesCursor {
+		cursor, err := storage.ParseCursor(opts.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("(last_used < ? OR (last_used = ? AND id < ?))", cursor.LastUsed, cursor.LastUsed, cursor.ID)
+	}
+
+	// Apply time range
+	if !opts.From.IsZero() {
+		query = query.Where("created_at >= ?", opts.From)
+	}
+	if !opts.To.IsZero() {
+		query = query.Where("created_at <= ?", opts.To)
+	}
+
+	// Apply sorting
+	direction := "DESC"
+	if strings.ToLower(opts.SortOrder) == "asc" {
+		direction = "ASC"
+	}
+	switch opts.SortBy {
+	case "created_at":
+		query = query.Order(fmt.Sprintf("created_at %s", direction))
+	case "use_count":
+		query = query.Order(fmt.Sprintf("use_count %s, last_used DESC", direction))
+	default:
+		query = query.Order(fmt.Sprintf("last_used %s, id %s", direction, direction))
+	}
+
+	// Apply pagination
+	if opts.Limit > 0 {
+		query = query.Limit(opts.Limit)
+	}
+	if opts.Offset > 0 {
+		query = query.Offset(opts.Offset)
+	}
+
+	var models []storage.ClipModel
+	if err := query.Find(&models).Error; err != nil {
+		return nil, fmt.Errorf("failed to search clips: %w", err)
+	}
+
+	results := make([]storage.SearchResult, len(models))
+	for i := range models {
+		// Content of external clips is best effort, like sqlite
+		_ = s.loadPreview(&models[i])
+		results[i] = storage.SearchResult{
+			Clip:     models[i].ToClip(),
+			LastUsed: models[i].LastUsed,
+			UseCount: int(models[i].Uses()),
+			Score:    float64(models[i].LastUsed.Unix()),
+		}
+	}
+
+	storage.Highlight(results, parsed)
+	return results, nil
+}
+
+// GetRecent implements storage.SearchService interface
+func (s *PostgresStorage) GetRecent(ctx context.Context, opts storage.SearchOptions) ([]storage.SearchResult, error) {
+	return s.Search(ctx, opts.Recent())
+}
+
+// GetMostUsed implements storage.SearchService interface
+func (s *PostgresStorage) GetMostUsed(ctx context.Context, opts storage.SearchOptions) ([]storage.SearchResult, error) {
+	return s.Search(ctx, opts.MostUsed())
+}
+
+// GetByType implements storage.SearchService interface
+func (s *PostgresStorage) GetByType(ctx context.Context, clipType string, opts storage.SearchOptions) ([]storage.SearchResult, error) {
+	return s.Search(ctx, opts.OfType(clipType))
+}
@@ -0,0 +1,37 @@
+package postgres
+
+import (
+	"clipboard-manager/internal/storage"
+	"context"
+	"fmt"
+	"time"
+)
+
+// Stats implements storage.StatsService interface. Only the columns counted
+// are read, so content never leaves the database.
+func (s *PostgresStorage) Stats(ctx context.Context) (*storage.Stats, error) {
+	rows, err := s.db.WithContext(ctx).Model(&storage.ClipModel{}).
+		Select("type, source_app, size, created_at").Rows()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read clips: %w", err)
+	}
+	defer rows.Close()
+
+	builder := storage.NewStatsBuilder(time.Now())
+	for rows.Next() {
+		var row struct {
+			Type      string
+			SourceApp string
+			Size      int64
+			CreatedAt time.Time
+		}
+		if err := s.db.ScanRows(rows, &row); err != nil {
+			return nil, fmt.Errorf("failed to read clip: %w", err)
+		}
+		builder.Add(row.Type, row.SourceApp, row.Size, row.CreatedAt)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read clips: %w", err)
+	}
+	return builder.Stats(), nil
+}
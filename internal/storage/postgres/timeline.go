@@ -0,0 +1,38 @@
+package postgres
+
+import (
+	"clipboard-manager/internal/storage"
+	"context"
+	"fmt"
+	"time"
+)
+
+// Timeline implements storage.TimelineService interface. Only creation
+// times are read, and bucketed in local time.
+func (s *PostgresStorage) Timeline(ctx context.Context, opts storage.TimelineOptions) ([]storage.TimelineBucket, error) {
+	query := s.db.WithContext(ctx).Model(&storage.ClipModel{}).Select("created_at")
+	if !opts.From.IsZero() {
+		query = query.Where("created_at >= ?", opts.From)
+	}
+	if !opts.To.IsZero() {
+		query = query.Where("created_at <= ?", opts.To)
+	}
+	rows, err := query.Rows()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read clips: %w", err)
+	}
+	defer rows.Close()
+
+	builder := storage.NewTimelineBuilder(opts.Granularity)
+	for rows.Next() {
+		var createdAt time.Time
+		if err := rows.Scan(&createdAt); err != nil {
+			return nil, fmt.Errorf("failed to read clip: %w", err)
+		}
+		builder.Add(createdAt)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read clips: %w", err)
+	}
+	return builder.Buckets(), nil
+}
@@ -0,0 +1,98 @@
+package postgres
+
+import (
+	"clipboard-manager/internal/storage"
+	"clipboard-manager/pkg/types"
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ListTrash implements storage.TrashService interface
+func (s *PostgresStorage) ListTrash(ctx context.Context, limit, offset int) ([]*types.Clip, error) {
+	query := s.db.WithContext(ctx).Unscoped().
+		Where("deleted_at IS NOT NULL").
+		Order("deleted_at DESC")
+
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	if offset > 0 {
+		query = query.Offset(offset)
+	}
+
+	var models []storage.ClipModel
+	if err := query.Find(&models).Error; err != nil {
+		return nil, fmt.Errorf("failed to list trash: %w", err)
+	}
+
+	return s.toClips(models)
+}
+
+// Restore implements storage.TrashService interface
+func (s *PostgresStorage) Restore(ctx context.Context, id string) (*types.Clip, error) {
+	var model storage.ClipModel
+	if err := s.db.WithContext(ctx).Unscoped().
+		Where("deleted_at IS NOT NULL").
+		First(&model, "id = ?", id).Error; err != nil {
+		return nil, fmt.Errorf("failed to get deleted clip: %w", notFound(err))
+	}
+
+	// Restored clips come back at the top of the history
+	model.DeletedAt = gorm.DeletedAt{}
+	model.LastUsed = time.Now()
+	if err := s.db.WithContext(ctx).Unscoped().Save(&model).Error; err != nil {
+		return nil, fmt.Errorf("failed to restore clip: %w", err)
+	}
+
+	return model.ToClip(), nil
+}
+
+// PurgeTrash implements storage.TrashService interface
+func (s *PostgresStorage) PurgeTrash(ctx context.Context, before time.Time) (int64, error) {
+	var models []storage.ClipModel
+	if err := s.db.WithContext(ctx).Unscoped().
+		Select("id", "storage_path", "is_external").
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", before).
+		Find(&models).Error; err != nil {
+		return 0, fmt.Errorf("failed to list trash: %w", err)
+	}
+
+	return s.purge(ctx, models)
+}
+
+// purge permanently deletes clips, removing external files no other clip uses
+func (s *PostgresStorage) purge(ctx context.Context, models []storage.ClipModel) (int64, error) {
+	var purged int64
+	for _, model := range models {
+		released := false
+		err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			released = false
+			if err := tx.Unscoped().Delete(&model).Error; err != nil {
+				return fmt.Errorf("failed to purge clip: %w", err)
+			}
+			if err := tx.Where("clip_id = ?", model.ID).Delete(&storage.VersionModel{}).Error; err != nil {
+				return fmt.Errorf("failed to purge versions: %w", err)
+			}
+
+			// Delete external file once no other clip shares it
+			if model.IsExternal {
+				var err error
+				released, err = s.releaseBlob(tx, model.StoragePath)
+				return err
+			}
+			return nil
+		})
+		if err != nil {
+			return purged, err
+		}
+		if released {
+			s.removeBlobFiles([]string{model.StoragePath})
+		}
+		purged++
+	}
+
+	return purged, nil
+}
@@ -0,0 +1,26 @@
+package postgres
+
+import (
+	"clipboard-manager/internal/storage"
+	"context"
+	"fmt"
+)
+
+// Usage implements storage.UsageReporter interface
+func (s *PostgresStorage) Usage(ctx context.Context) (storage.Usage, error) {
+	var usage storage.Usage
+
+	if err := s.db.WithContext(ctx).
+		Raw("SELECT pg_database_size(current_database())").
+		Scan(&usage.DatabaseBytes).Error; err != nil {
+		return usage, fmt.Errorf("failed to get database size: %w", err)
+	}
+
+	if err := s.db.WithContext(ctx).Model(&storage.BlobModel{}).
+		Select("COALESCE(SUM(size), 0)").
+		Scan(&usage.ExternalBytes).Error; err != nil {
+		return usage, fmt.Errorf("failed to get external storage size: %w", err)
+	}
+
+	return usage, nil
+}
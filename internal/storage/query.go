@@ -0,0 +1,137 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Query fields. A term without a field matches text anywhere in a clip.
+const (
+	FieldText     = ""
+	FieldType     = "type"     // Content or media type, or its major type: type:image, type:video
+	FieldApp      = "app"      // Source app name or bundle ID, substring
+	FieldTag      = "tag"      // Tag, exact
+	FieldFormat   = "format"   // Primary type or alternate format
+	FieldURL      = "url"      // Source page URL, substring
+	FieldCategory = "category" // Category, exact
+	FieldDevice   = "device"   // Device of Universal Clipboard clips, substring
+	FieldWindow   = "window"   // Title of the window copied from, substring
+	FieldBefore   = "before"   // Copied before the start of a day
+	FieldAfter    = "after"    // Copied on or after the start of a day
+)
+
+// queryDateLayout is the date format of before: and after:
+const queryDateLayout = "2006-01-02"
+
+var queryFields = map[string]bool{
+	FieldType: true, FieldApp: true, FieldTag: true, FieldFormat: true,
+	FieldURL: true, FieldCategory: true, FieldDevice: true, FieldWindow: true,
+	FieldBefore: true, FieldAfter: true,
+}
+
+// Term is one condition of a search query. Values are lower case, except
+// for Time which is set for before: and after:.
+type Term struct {
+	Field string
+	Value string
+	Time  time.Time
+}
+
+// Query is a parsed search query such as
+//
+//	type:image app:Chrome tag:work before:2024-06-01 "exact phrase"
+//
+// Terms next to each other must all match; AND may be written out. OR
+// separates alternatives and binds looser than AND, so a clip matches if it
+// matches every term of at least one group. Unknown fields, like the scheme
+// of a URL, are searched for as text.
+type Query struct {
+	Groups [][]Term
+}
+
+// Empty reports whether the query has no terms and so matches every clip
+func (q Query) Empty() bool {
+	return len(q.Groups) == 0
+}
+
+// ParseQuery parses the search query language. It fails only on malformed
+// dates.
+func ParseQuery(input string) (Query, error) {
+	var query Query
+	var group []Term
+	for _, token := range tokenizeQuery(input) {
+		switch {
+		case !token.quoted && token.text == "OR":
+			if len(group) > 0 {
+				query.Groups = append(query.Groups, group)
+			}
+			group = nil
+			continue
+		case !token.quoted && token.text == "AND":
+			continue
+		}
+
+		term := Term{Field: token.field, Value: strings.ToLower(token.text)}
+		if term.Field == FieldBefore || term.Field == FieldAfter {
+			t, err := time.ParseInLocation(queryDateLayout, token.text, time.Local)
+			if err != nil {
+				return Query{}, fmt.Errorf("invalid date in %s:%s, expected YYYY-MM-DD", term.Field, token.text)
+			}
+			term.Time = t
+		}
+		if term.Value != "" {
+			group = append(group, term)
+		}
+	}
+	if len(group) > 0 {
+		query.Groups = append(query.Groups, group)
+	}
+	return query, nil
+}
+
+// queryToken is a word or quoted phrase, with the field it is qualified by
+type queryToken struct {
+	field  string
+	text   string
+	quoted bool
+}
+
+// tokenizeQuery splits input on whitespace outside quotes. A known field
+// prefix is split off, and its value may be quoted: app:"Visual Studio".
+func tokenizeQuery(input string) []queryToken {
+	var tokens []queryToken
+	var current strings.Builder
+	var token queryToken
+	inQuotes, started := false, false
+
+	flush := func() {
+		if started {
+			token.text = current.String()
+			tokens = append(tokens, token)
+		}
+		current.Reset()
+		token = queryToken{}
+		started = false
+	}
+
+	for _, r := range input {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+			token.quoted = true
+			started = true
+		case r == ':' && !inQuotes && token.field == "" && !token.quoted && queryFields[strings.ToLower(current.String())]:
+			token.field = strings.ToLower(current.String())
+			current.Reset()
+			started = true
+		case (r == ' ' || r == '\t' || r == '\n') && !inQuotes:
+			flush()
+		default:
+			current.WriteRune(r)
+			started = true
+		}
+	}
+	flush()
+	return tokens
+}
@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseQuery(t *testing.T) {
+	day := time.Date(2024, 6, 1, 0, 0, 0, 0, time.Local)
+	tests := []struct {
+		input string
+		want  [][]Term
+	}{
+		{"", nil},
+		{"hello World", [][]Term{{{Value: "hello"}, {Value: "world"}}}},
+		{`"exact phrase" type:image`, [][]Term{{{Value: "exact phrase"}, {Field: FieldType, Value: "image"}}}},
+		{`app:"Visual Studio" before:2024-06-01`, [][]Term{{
+			{Field: FieldApp, Value: "visual studio"},
+			{Field: FieldBefore, Value: "2024-06-01", Time: day},
+		}}},
+		{"tag:work AND foo OR tag:home", [][]Term{
+			{{Field: FieldTag, Value: "work"}, {Value: "foo"}},
+			{{Field: FieldTag, Value: "home"}},
+		}},
+		{`"OR" or`, [][]Term{{{Value: "or"}, {Value: "or"}}}},
+		{"https://example.com", [][]Term{{{Value: "https://example.com"}}}},
+		{"OR tag:", nil},
+	}
+	for _, test := range tests {
+		query, err := ParseQuery(test.input)
+		if err != nil {
+			t.Errorf("ParseQuery(%q) failed: %v", test.input, err)
+			continue
+		}
+		if !reflect.DeepEqual(query.Groups, test.want) {
+			t.Errorf("ParseQuery(%q) = %+v, want %+v", test.input, query.Groups, test.want)
+		}
+	}
+
+	if _, err := ParseQuery("after:yesterday"); err == nil {
+		t.Error("expected an error for a malformed date")
+	}
+}
+
+// FuzzParseQuery checks that any query parses or fails cleanly into terms
+// the search can use
+func FuzzParseQuery(f *testing.F) {
+	for _, seed := range []string{
+		"", "hello World", `"exact phrase" type:image`, `app:"Visual Studio" before:2024-06-01`,
+		"tag:work AND foo OR tag:home", `"OR" or`, "OR tag:", `type:"`, `""`, "after:2024-13-40",
+		"url:https://example.com:8080", "\xff\x00 tag:\"\t",
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		query, err := ParseQuery(input)
+		if err != nil {
+			lower := strings.ToLower(input)
+			if !strings.Contains(lower, FieldBefore+":") && !strings.Contains(lower, FieldAfter+":") {
+				t.Fatalf("ParseQuery(%q) failed without a date: %v", input, err)
+			}
+			return
+		}
+		if query.Empty() != (len(query.Groups) == 0) {
+			t.Fatal("Empty disagrees with the groups")
+		}
+		for _, group := range query.Groups {
+			if len(group) == 0 {
+				t.Fatalf("ParseQuery(%q) has an empty group", input)
+			}
+			for _, term := range group {
+				if term.Value == "" {
+					t.Errorf("ParseQuery(%q) has an empty %q term", input, term.Field)
+				}
+				if term.Field != FieldText && !queryFields[term.Field] {
+					t.Errorf("ParseQuery(%q) has unknown field %q", input, term.Field)
+				}
+				if term.Value != strings.ToLower(term.Value) {
+					t.Errorf("ParseQuery(%q) has %q, which isn't lower case", input, term.Value)
+				}
+				if (term.Field == FieldBefore || term.Field == FieldAfter) && term.Time.IsZero() {
+					t.Errorf("ParseQuery(%q) has %s:%s without a time", input, term.Field, term.Value)
+				}
+			}
+		}
+	})
+}
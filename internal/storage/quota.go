@@ -0,0 +1,21 @@
+package storage
+
+import "context"
+
+// Eviction describes clips removed to bring the storage under its quota
+type Eviction struct {
+	IDs        []string // Removed clips, least recently used first
+	FreedBytes int64
+	UsedBytes  int64 // Content size left
+}
+
+// Evictor defines the interface for storage that can be held to a quota.
+// The quota covers clip content, kept in the database or in files,
+// including clips in the trash; files shared by several clips count once.
+type Evictor interface {
+	// Evict permanently removes clips until the content left takes up no
+	// more than limit bytes. Clips in the trash go first, then the least
+	// recently used. Pinned clips are never evicted, so the content left
+	// can stay over the limit.
+	Evict(ctx context.Context, limit int64) (*Eviction, error)
+}
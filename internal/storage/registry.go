@@ -0,0 +1,58 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Factory creates a storage backend from configuration
+type Factory func(config Config) (Storage, error)
+
+var (
+	backendsMu sync.RWMutex
+	backends   = make(map[string]Factory)
+)
+
+// Register makes a storage backend available by driver name. Backends
+// register themselves from an init function, like database/sql drivers.
+func Register(driver string, factory Factory) {
+	backendsMu.Lock()
+	defer backendsMu.Unlock()
+
+	if factory == nil {
+		panic("storage: Register factory is nil")
+	}
+	if _, dup := backends[driver]; dup {
+		panic("storage: Register called twice for driver " + driver)
+	}
+	backends[driver] = factory
+}
+
+// Drivers returns the names of the registered backends
+func Drivers() []string {
+	backendsMu.RLock()
+	defer backendsMu.RUnlock()
+
+	drivers := make([]string, 0, len(backends))
+	for driver := range backends {
+		drivers = append(drivers, driver)
+	}
+	sort.Strings(drivers)
+	return drivers
+}
+
+// Open opens a storage backend by driver name. The DSN is backend specific:
+// a database file path for sqlite, a connection string for postgres.
+func Open(driver, dsn string, config Config) (Storage, error) {
+	backendsMu.RLock()
+	factory, ok := backends[driver]
+	backendsMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown storage driver %q (available: %v)", driver, Drivers())
+	}
+
+	config.DSN = dsn
+	return factory(config)
+}
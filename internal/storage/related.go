@@ -0,0 +1,15 @@
+package storage
+
+import (
+	"clipboard-manager/pkg/types"
+	"context"
+)
+
+// RelatedFinder is implemented by backends that keep the copy session of
+// clips, see types.Metadata.Session
+type RelatedFinder interface {
+	// Related returns the other clips of the copy session of the clip with
+	// id, in the order they were copied, or ErrNotFound. Clips without a
+	// session have no related clips.
+	Related(ctx context.Context, id string) ([]*types.Clip, error)
+}
@@ -2,6 +2,7 @@ package storage
 
 import (
 	"clipboard-manager/pkg/types"
+	"context"
 	"time"
 )
 
@@ -13,9 +14,23 @@ type SearchOptions struct {
 	// Filter by content type
 	Type string
 
+	// Filter by available representation (e.g. "text/rtf"); matches
+	// clips whose primary type or alternate formats include it
+	Format string
+
 	// Filter by source application
 	SourceApp string
 
+	// Filter by source application bundle identifier
+	SourceBundleID string
+
+	// Filter by source page URL (case-insensitive substring match)
+	SourceURL string
+
+	// Filter by the device clips arrived from over Universal Clipboard
+	// (case-insensitive substring match)
+	Device string
+
 	// Filter by category
 	Category string
 
@@ -30,9 +45,17 @@ type SearchOptions struct {
 	Limit  int
 	Offset int
 
+	// Start after this position instead of at the most recent clip; see
+	// Cursor. Only valid with the default sort by last use, newest first.
+	Cursor string
+
 	// Sort options
-	SortBy    string // "created_at", "last_used"
+	SortBy    string // "created_at", "last_used", "use_count"
 	SortOrder string // "asc", "desc"
+
+	// Collapse near-duplicate clips into the most recently used one. Grouping
+	// happens after pagination, so a page may hold fewer than Limit results.
+	GroupSimilar bool
 }
 
 // SearchResult represents a search result with metadata
@@ -45,19 +68,50 @@ type SearchResult struct {
 	Matches   []string  // Matched terms
 	LastUsed  time.Time // When this clip was last accessed
 	UseCount  int       // Number of times this clip was accessed
+
+	// Text around the first match, filled in by Highlight
+	Snippet string `json:",omitempty"`
+
+	// IDs of near-duplicate clips folded into this result by GroupSimilar
+	Duplicates []string `json:",omitempty"`
 }
 
-// SearchService defines the interface for searching clips
+// SearchService defines the interface for searching clips. Searches stop
+// with ctx's error once ctx is done.
 type SearchService interface {
 	// Search returns clips matching the given criteria
-	Search(opts SearchOptions) ([]SearchResult, error)
+	Search(ctx context.Context, opts SearchOptions) ([]SearchResult, error)
+
+	// GetRecent returns the clips matching opts, most recently used first
+	// whatever opts' sort order
+	GetRecent(ctx context.Context, opts SearchOptions) ([]SearchResult, error)
 
-	// GetRecent returns the most recently used clips
-	GetRecent(limit int) ([]SearchResult, error)
+	// GetMostUsed returns the clips matching opts, most frequently used
+	// first whatever opts' sort order
+	GetMostUsed(ctx context.Context, opts SearchOptions) ([]SearchResult, error)
+
+	// GetByType returns the clips of clipType matching opts, most recently
+	// used first whatever opts' sort order
+	GetByType(ctx context.Context, clipType string, opts SearchOptions) ([]SearchResult, error)
+}
 
-	// GetMostUsed returns the most frequently used clips
-	GetMostUsed(limit int) ([]SearchResult, error)
+// Recent returns opts sorted by last use, most recent first, as GetRecent
+// searches
+func (opts SearchOptions) Recent() SearchOptions {
+	opts.SortBy, opts.SortOrder = "last_used", "desc"
+	return opts
+}
+
+// MostUsed returns opts sorted by use count, highest first, as GetMostUsed
+// searches
+func (opts SearchOptions) MostUsed() SearchOptions {
+	opts.SortBy, opts.SortOrder = "use_count", "desc"
+	return opts
+}
 
-	// GetByType returns clips of a specific type
-	GetByType(clipType string, limit int) ([]SearchResult, error)
+// OfType returns opts limited to clipType and sorted by last use, as
+// GetByType searches
+func (opts SearchOptions) OfType(clipType string) SearchOptions {
+	opts.Type = clipType
+	return opts.Recent()
 }
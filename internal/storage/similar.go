@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"clipboard-manager/pkg/types"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"strings"
+)
+
+// SimilarityKey returns a key shared by near-duplicate clips. Text is compared
+// ignoring surrounding and repeated whitespace, and links ignoring their
+// fragment, so "https://example.com/a#top" and "https://example.com/a " match.
+// Other clips only match when their content is identical.
+func SimilarityKey(clip *types.Clip) string {
+	text, ok := similarityText(clip)
+	if !ok {
+		sum := sha256.Sum256(clip.Content)
+		return clip.Type + ":" + hex.EncodeToString(sum[:])
+	}
+
+	text = strings.Join(strings.Fields(text), " ")
+	if u, err := url.Parse(text); err == nil && !strings.Contains(text, " ") &&
+		(u.Scheme == "http" || u.Scheme == "https") && u.Host != "" {
+		u.Fragment = ""
+		u.RawFragment = ""
+		u.Host = strings.ToLower(u.Host)
+		text = u.String()
+	}
+
+	sum := sha256.Sum256([]byte(text))
+	return "text:" + hex.EncodeToString(sum[:])
+}
+
+// similarityText returns the text near-duplicates are compared by
+func similarityText(clip *types.Clip) (string, bool) {
+	if text, ok := clip.Metadata.Formats[FormatPlainText]; ok {
+		return string(text), true
+	}
+	if clip.Type == TypeText {
+		return string(clip.Content), true
+	}
+	return "", false
+}
+
+// GroupSimilar collapses near-duplicate results into the first of each group,
+// keeping the order of results. The kept result sums the use counts of its
+// group and lists the others in Duplicates.
+func GroupSimilar(results []SearchResult) []SearchResult {
+	grouped := make([]SearchResult, 0, len(results))
+	index := make(map[string]int)
+
+	for _, result := range results {
+		key := SimilarityKey(result.Clip)
+		i, ok := index[key]
+		if !ok {
+			index[key] = len(grouped)
+			grouped = append(grouped, result)
+			continue
+		}
+
+		kept := &grouped[i]
+		kept.UseCount += result.UseCount
+		kept.Duplicates = append(kept.Duplicates, result.Clip.ID)
+		if result.LastUsed.After(kept.LastUsed) {
+			kept.LastUsed = result.LastUsed
+		}
+	}
+	return grouped
+}
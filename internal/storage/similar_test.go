@@ -0,0 +1,57 @@
+package storage
+
+import (
+	"clipboard-manager/pkg/types"
+	"testing"
+	"time"
+)
+
+func TestSimilarityKey(t *testing.T) {
+	text := func(s string) *types.Clip {
+		return &types.Clip{Type: "text/plain", Content: []byte(s)}
+	}
+
+	same := [][2]*types.Clip{
+		{text("hello  world"), text("  hello world\n")},
+		{text("https://example.com/a#top"), text("https://Example.com/a")},
+		{text("plain"), {Type: "text/html", Content: []byte("<b>plain</b>"),
+			Metadata: types.Metadata{Formats: map[string][]byte{FormatPlainText: []byte("plain")}}}},
+	}
+	for _, pair := range same {
+		if SimilarityKey(pair[0]) != SimilarityKey(pair[1]) {
+			t.Errorf("%q and %q should be near-duplicates", pair[0].Content, pair[1].Content)
+		}
+	}
+
+	different := [][2]*types.Clip{
+		{text("hello world"), text("hello there")},
+		{text("https://example.com/a"), text("https://example.com/b")},
+		{{Type: "image/png", Content: []byte{1}}, {Type: "image/png", Content: []byte{2}}},
+	}
+	for _, pair := range different {
+		if SimilarityKey(pair[0]) == SimilarityKey(pair[1]) {
+			t.Errorf("%q and %q should not be near-duplicates", pair[0].Content, pair[1].Content)
+		}
+	}
+}
+
+func TestGroupSimilar(t *testing.T) {
+	now := time.Now()
+	results := []SearchResult{
+		{Clip: &types.Clip{ID: "1", Type: "text/plain", Content: []byte("foo")}, UseCount: 1, LastUsed: now.Add(-time.Minute)},
+		{Clip: &types.Clip{ID: "2", Type: "text/plain", Content: []byte("bar")}, UseCount: 1, LastUsed: now},
+		{Clip: &types.Clip{ID: "3", Type: "text/plain", Content: []byte(" foo ")}, UseCount: 2, LastUsed: now},
+	}
+
+	grouped := GroupSimilar(results)
+	if len(grouped) != 2 {
+		t.Fatalf("expected 2 groups, got %d", len(grouped))
+	}
+	first := grouped[0]
+	if first.Clip.ID != "1" || first.UseCount != 3 || !first.LastUsed.Equal(now) {
+		t.Errorf("unexpected group: id %s, use count %d, last used %v", first.Clip.ID, first.UseCount, first.LastUsed)
+	}
+	if len(first.Duplicates) != 1 || first.Duplicates[0] != "3" {
+		t.Errorf("expected duplicate 3, got %v", first.Duplicates)
+	}
+}
@@ -0,0 +1,67 @@
+package sqlite
+
+import (
+	"clipboard-manager/internal/storage"
+	"clipboard-manager/pkg/types"
+	"context"
+	"fmt"
+
+	"gorm.io/gorm/clause"
+)
+
+// saveApp records the source application of a clip, keeping the first icon we get
+func (s *SQLiteStorage) saveApp(ctx context.Context, metadata types.Metadata) error {
+	if metadata.SourceBundleID == "" {
+		return nil
+	}
+
+	app := storage.AppModel{
+		BundleID: metadata.SourceBundleID,
+		Name:     metadata.SourceApp,
+		Icon:     metadata.SourceIcon,
+	}
+
+	updates := []string{"name", "updated_at"}
+	if len(metadata.SourceIcon) > 0 {
+		updates = append(updates, "icon")
+	}
+
+	if err := s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "bundle_id"}},
+		DoUpdates: clause.AssignmentColumns(updates),
+	}).Create(&app).Error; err != nil {
+		return fmt.Errorf("failed to save source app: %w", err)
+	}
+	return nil
+}
+
+// ListApps implements storage.AppService interface
+func (s *SQLiteStorage) ListApps(ctx context.Context) ([]storage.AppInfo, error) {
+	var apps []storage.AppInfo
+	err := s.reads.WithContext(ctx).Model(&storage.ClipModel{}).
+		Select("clip_models.source_bundle_id AS bundle_id, " +
+			"COALESCE(app_models.name, MAX(clip_models.source_app)) AS name, " +
+			"COUNT(*) AS count, " +
+			"app_models.icon IS NOT NULL AS has_icon").
+		Joins("LEFT JOIN app_models ON app_models.bundle_id = clip_models.source_bundle_id").
+		Where("clip_models.source_bundle_id <> ''").
+		Group("clip_models.source_bundle_id").
+		Order("count DESC").
+		Scan(&apps).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list apps: %w", err)
+	}
+	return apps, nil
+}
+
+// GetAppIcon implements storage.AppService interface
+func (s *SQLiteStorage) GetAppIcon(ctx context.Context, bundleID string) ([]byte, error) {
+	var app storage.AppModel
+	if err := s.reads.WithContext(ctx).Where("bundle_id = ?", bundleID).First(&app).Error; err != nil {
+		return nil, fmt.Errorf("failed to get app: %w", err)
+	}
+	if len(app.Icon) == 0 {
+		return nil, fmt.Errorf("no icon cached for app: %s", bundleID)
+	}
+	return app.Icon, nil
+}
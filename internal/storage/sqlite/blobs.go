@@ -0,0 +1,134 @@
+package sqlite
+
+import (
+	"clipboard-manager/internal/storage"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// acquireBlob takes a reference to the external file for content, writing the
+// file only if no clip holds it yet or it has gone missing
+func (s *SQLiteStorage) acquireBlob(tx *gorm.DB, content *storage.SpooledContent) error {
+	path := filepath.Join(s.fsPath, content.Hash)
+
+	var blob storage.BlobModel
+	err := tx.First(&blob, "hash = ?", content.Hash).Error
+	if err == gorm.ErrRecordNotFound {
+		if err := content.MoveTo(path); err != nil {
+			return fmt.Errorf("failed to write file: %w", err)
+		}
+		blob = storage.BlobModel{Hash: content.Hash, Size: content.Size, RefCount: 1}
+		if err := tx.Create(&blob).Error; err != nil {
+			return fmt.Errorf("failed to create blob: %w", err)
+		}
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to get blob: %w", err)
+	}
+
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if err := content.MoveTo(path); err != nil {
+			return fmt.Errorf("failed to write file: %w", err)
+		}
+	}
+
+	if err := tx.Model(&blob).Update("ref_count", gorm.Expr("ref_count + 1")).Error; err != nil {
+		return fmt.Errorf("failed to update blob references: %w", err)
+	}
+	return nil
+}
+
+// releaseBlob drops a reference to an external file, reporting whether no
+// clip refers to it any more. The file is left for removeBlobFiles once the
+// transaction has committed, so rolling it back doesn't lose the file.
+func (s *SQLiteStorage) releaseBlob(tx *gorm.DB, hash string) (bool, error) {
+	if err := tx.Model(&storage.BlobModel{}).
+		Where("hash = ?", hash).
+		Update("ref_count", gorm.Expr("ref_count - 1")).Error; err != nil {
+		return false, fmt.Errorf("failed to update blob references: %w", err)
+	}
+
+	result := tx.Where("hash = ? AND ref_count <= 0", hash).Delete(&storage.BlobModel{})
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to delete blob: %w", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// removeBlobFiles deletes the files of blobs released by a committed
+// transaction, unless a clip has taken the file again since. A file that
+// can't be removed is left for garbage collection.
+func (s *SQLiteStorage) removeBlobFiles(hashes []string) {
+	for _, hash := range hashes {
+		var held int64
+		if err := s.db.Model(&storage.BlobModel{}).Where("hash = ?", hash).Count(&held).Error; err != nil || held > 0 {
+			continue
+		}
+		os.Remove(filepath.Join(s.fsPath, hash))
+	}
+}
+
+// repairBlobs recomputes blob reference counts from the clips table, counting
+// the content of external clips and kept copies of files. This also adopts
+// files stored before reference counting existed.
+func (s *SQLiteStorage) repairBlobs(tx *gorm.DB, dryRun bool) (int, error) {
+	var counts []struct {
+		StoragePath string
+		Size        int64
+		Refs        int64
+	}
+	if err := tx.Raw(`SELECT storage_path, MAX(size) AS size, COUNT(*) AS refs FROM (
+		SELECT storage_path, size FROM clip_models WHERE is_external = true
+		UNION ALL
+		SELECT file_copy, json_extract(file, '$.Size') FROM clip_models WHERE file_copy != ''
+	) GROUP BY storage_path`).
+		Scan(&counts).Error; err != nil {
+		return 0, fmt.Errorf("failed to count blob references: %w", err)
+	}
+
+	var blobs []storage.BlobModel
+	if err := tx.Find(&blobs).Error; err != nil {
+		return 0, fmt.Errorf("failed to list blobs: %w", err)
+	}
+	existing := make(map[string]int64, len(blobs))
+	for _, blob := range blobs {
+		existing[blob.Hash] = blob.RefCount
+	}
+
+	repaired := 0
+	for _, count := range counts {
+		refs, ok := existing[count.StoragePath]
+		delete(existing, count.StoragePath)
+		if ok && refs == count.Refs {
+			continue
+		}
+		repaired++
+		if dryRun {
+			continue
+		}
+		blob := storage.BlobModel{Hash: count.StoragePath, Size: count.Size, RefCount: count.Refs}
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "hash"}},
+			DoUpdates: clause.AssignmentColumns([]string{"ref_count"}),
+		}).Create(&blob).Error; err != nil {
+			return 0, fmt.Errorf("failed to repair blob: %w", err)
+		}
+	}
+
+	// Blobs nothing refers to; their files are collected as orphans
+	for hash := range existing {
+		repaired++
+		if dryRun {
+			continue
+		}
+		if err := tx.Delete(&storage.BlobModel{}, "hash = ?", hash).Error; err != nil {
+			return 0, fmt.Errorf("failed to delete blob: %w", err)
+		}
+	}
+
+	return repaired, nil
+}
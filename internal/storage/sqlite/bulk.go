@@ -0,0 +1,61 @@
+package sqlite
+
+import (
+	"clipboard-manager/internal/storage"
+	"context"
+	"fmt"
+)
+
+// DeleteMany implements storage.BulkService interface
+func (s *SQLiteStorage) DeleteMany(ctx context.Context, ids []string) (int64, error) {
+	keys, err := storage.ParseIDs(ids)
+	if err != nil {
+		return 0, err
+	}
+	if len(keys) == 0 {
+		return 0, nil
+	}
+
+	// Soft delete moves the clips to the trash in one UPDATE
+	result := s.db.WithContext(ctx).Where("id IN ?", keys).Delete(&storage.ClipModel{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to delete clips: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// DeleteWhere implements storage.BulkService interface
+func (s *SQLiteStorage) DeleteWhere(ctx context.Context, query storage.Query) (int64, error) {
+	condition, args := "1 = 1", []interface{}(nil)
+	if !query.Empty() {
+		var err error
+		if condition, args, err = s.queryCondition(ctx, query); err != nil {
+			return 0, err
+		}
+	}
+
+	result := s.db.WithContext(ctx).Where(condition, args...).Delete(&storage.ClipModel{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to delete clips: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// UpdateTagsMany implements storage.BulkService interface
+func (s *SQLiteStorage) UpdateTagsMany(ctx context.Context, ids []string, tags []string) (int64, error) {
+	keys, err := storage.ParseIDs(ids)
+	if err != nil {
+		return 0, err
+	}
+	if len(keys) == 0 {
+		return 0, nil
+	}
+
+	// UpdateColumn skips the hook that would set last_used to now
+	result := s.db.WithContext(ctx).Model(&storage.ClipModel{}).Where("id IN ?", keys).
+		UpdateColumn("tags", storage.StringArray(tags))
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to update tags: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
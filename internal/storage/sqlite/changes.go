@@ -0,0 +1,98 @@
+package sqlite
+
+import (
+	"clipboard-manager/internal/storage"
+	"context"
+	"fmt"
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+// Changes are recorded by the triggers in migration 0008_changes.sql
+
+// Changes implements storage.ChangeFeed interface
+func (s *SQLiteStorage) Changes(ctx context.Context, since int64, limit int) ([]storage.Change, error) {
+	if limit <= 0 {
+		limit = storage.DefaultChangesLimit
+	}
+
+	var changes []storage.Change
+	// One transaction, so the clips are loaded as they were at the changes
+	err := s.reads.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var models []storage.ChangeModel
+		if err := tx.Where("seq IN (?)", tx.Model(&storage.ChangeModel{}).
+			Select("MAX(seq)").
+			Where("seq > ?", since).
+			Group("clip_id")).
+			Order("seq").
+			Limit(limit).
+			Find(&models).Error; err != nil {
+			return fmt.Errorf("failed to list changes: %w", err)
+		}
+
+		var ids []uint
+		for _, model := range models {
+			if model.Op != storage.ChangeDelete {
+				ids = append(ids, model.ClipID)
+			}
+		}
+		clips := make(map[uint]storage.ClipModel, len(ids))
+		if len(ids) > 0 {
+			var found []storage.ClipModel
+			if err := tx.Where("id IN ?", ids).Find(&found).Error; err != nil {
+				return fmt.Errorf("failed to load changed clips: %w", err)
+			}
+			for _, clip := range found {
+				clips[clip.ID] = clip
+			}
+		}
+
+		changes = make([]storage.Change, len(models))
+		for i, model := range models {
+			changes[i] = storage.Change{
+				Seq:       model.Seq,
+				Op:        model.Op,
+				ClipID:    strconv.FormatUint(uint64(model.ClipID), 10),
+				UUID:      model.UUID,
+				ChangedAt: model.ChangedAt,
+			}
+			clip, ok := clips[model.ClipID]
+			if !ok {
+				continue
+			}
+			// Load external content if needed; large text only shows its head
+			if clip.IsExternal && !clip.HasHead() {
+				content, err := s.readExternalFile(ctx, clip.StoragePath)
+				if err != nil {
+					return fmt.Errorf("failed to read external content for clip %d: %w", clip.ID, err)
+				}
+				clip.Content = content
+			}
+			changes[i].Clip = clip.ToClip()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// LatestChange implements storage.ChangeFeed interface
+func (s *SQLiteStorage) LatestChange(ctx context.Context) (int64, error) {
+	var seq int64
+	if err := s.readSQL.QueryRowContext(ctx, "SELECT COALESCE(MAX(seq), 0) FROM change_models").Scan(&seq); err != nil {
+		return 0, fmt.Errorf("failed to get latest change: %w", err)
+	}
+	return seq, nil
+}
+
+// compactChanges removes changes to clips that changed again later. The
+// feed only returns the latest change to each clip, so it stays the same.
+func (s *SQLiteStorage) compactChanges(ctx context.Context) error {
+	if _, err := s.sqlDB.ExecContext(ctx, "DELETE FROM change_models WHERE seq NOT IN (SELECT MAX(seq) FROM change_models GROUP BY clip_id)"); err != nil {
+		return fmt.Errorf("failed to compact changes: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,687 @@
+package sqlite
+
+import (
+	"bytes"
+	"clipboard-manager/internal/storage"
+	"clipboard-manager/pkg/types"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+func init() {
+	storage.Register("sqlite-direct", func(config storage.Config) (storage.Storage, error) {
+		return NewDirect(config)
+	})
+}
+
+// DirectStorage is the SQLite backend with its hot paths, storing, getting,
+// listing and searching clips, written as SQL against database/sql instead of
+// going through GORM's reflection. Everything else is SQLiteStorage's, and
+// both work on the same database.
+type DirectStorage struct {
+	*SQLiteStorage
+	statements *statements // Prepared reads on the read pool
+}
+
+// NewDirect opens a SQLite database like New
+func NewDirect(config storage.Config) (*DirectStorage, error) {
+	s, err := New(config)
+	if err != nil {
+		return nil, err
+	}
+	return &DirectStorage{SQLiteStorage: s, statements: newStatements(s.readSQL)}, nil
+}
+
+// Close closes the prepared statements and the database
+func (s *DirectStorage) Close() error {
+	if err := s.statements.close(); err != nil {
+		return fmt.Errorf("failed to close statements: %w", err)
+	}
+	return s.SQLiteStorage.Close()
+}
+
+// clipColumns are the clip_models columns scanClip reads. Text columns that
+// older rows may have left NULL are read as empty strings.
+const clipColumns = "id, created_at, updated_at, deleted_at, COALESCE(content_hash, ''), content, " +
+	"COALESCE(storage_path, ''), COALESCE(is_external, false), COALESCE(size, 0), type, " +
+	"COALESCE(source_app, ''), COALESCE(source_bundle_id, ''), COALESCE(source_url, ''), " +
+	"COALESCE(source_title, ''), COALESCE(device, ''), COALESCE(category, ''), tags, last_used, " +
+	"COALESCE(synced_to_obsidian, false), formats, COALESCE(plain_text, ''), expires_at, " +
+	"COALESCE(use_count, 0), screenshot, link, COALESCE(language, ''), media, " +
+	"COALESCE(media_type, ''), COALESCE(pinned, false), COALESCE(uuid, ''), file, COALESCE(file_copy, ''), " +
+	"COALESCE(normalized_hash, ''), COALESCE(session, ''), COALESCE(source_window, '')"
+
+// rowScanner is a *sql.Row or *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanClip reads a row of clipColumns
+func scanClip(row rowScanner) (*storage.ClipModel, error) {
+	var model storage.ClipModel
+	var createdAt, updatedAt, lastUsed, expiresAt sql.NullTime
+	err := row.Scan(&model.ID, &createdAt, &updatedAt, &model.DeletedAt, &model.ContentHash, &model.Content,
+		&model.StoragePath, &model.IsExternal, &model.Size, &model.Type,
+		&model.SourceApp, &model.SourceBundleID, &model.SourceURL,
+		&model.SourceTitle, &model.Device, &model.Category, jsonColumn{&model.Tags}, &lastUsed,
+		&model.SyncedToObsidian, &model.Formats, &model.PlainText, &expiresAt,
+		&model.UseCount, jsonColumn{&model.Screenshot}, jsonColumn{&model.Link}, &model.Language, jsonColumn{&model.Media},
+		&model.MediaType, &model.Pinned, &model.UUID, jsonColumn{&model.File}, &model.FileCopy,
+		&model.NormalizedHash, &model.Session, &model.SourceWindow)
+	if err != nil {
+		return nil, err
+	}
+
+	model.CreatedAt = createdAt.Time
+	model.UpdatedAt = updatedAt.Time
+	model.LastUsed = lastUsed.Time
+	if expiresAt.Valid {
+		model.ExpiresAt = &expiresAt.Time
+	}
+	return &model, nil
+}
+
+// jsonColumn reads a JSON column into the value v points to. Like GORM, it
+// leaves the value alone when the column is NULL.
+type jsonColumn struct {
+	v interface{}
+}
+
+// Scan implements sql.Scanner interface
+func (c jsonColumn) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, c.v)
+}
+
+// jsonValue encodes v the way GORM's JSON serializer does, nil as NULL
+func jsonValue(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil || string(data) == "null" {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// scanClips reads the clips a query selecting clipColumns found
+func scanClips(rows *sql.Rows, err error) ([]*storage.ClipModel, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var models []*storage.ClipModel
+	for rows.Next() {
+		model, err := scanClip(rows)
+		if err != nil {
+			return nil, err
+		}
+		models = append(models, model)
+	}
+	return models, rows.Err()
+}
+
+// getClip returns a clip that isn't in the trash
+func (s *DirectStorage) getClip(ctx context.Context, id string) (*storage.ClipModel, error) {
+	row, err := s.statements.queryRow(ctx, "SELECT "+clipColumns+" FROM clip_models WHERE id = ? AND deleted_at IS NULL", id)
+	if err != nil {
+		return nil, err
+	}
+	model, err := scanClip(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, storage.ErrNotFound
+	}
+	return model, err
+}
+
+// touch records that a clip was used now
+func (s *DirectStorage) touch(ctx context.Context, model *storage.ClipModel) error {
+	now := time.Now()
+	if _, err := s.sqlDB.ExecContext(ctx, "UPDATE clip_models SET last_used = ?, updated_at = ? WHERE id = ?", now, now, model.ID); err != nil {
+		return err
+	}
+	model.LastUsed, model.UpdatedAt = now, now
+	return nil
+}
+
+// Store implements storage.Storage interface
+func (s *DirectStorage) Store(ctx context.Context, content []byte, clipType string, metadata types.Metadata) (*types.Clip, error) {
+	size := int64(len(content))
+	if size > storage.MaxStorageSize {
+		return nil, storage.ErrFileTooLarge
+	}
+
+	return s.storeContent(ctx, &storage.SpooledContent{
+		Hash: calculateHash(content),
+		Size: size,
+		Data: content,
+	}, clipType, metadata)
+}
+
+// StoreStream implements storage.Storage interface
+func (s *DirectStorage) StoreStream(ctx context.Context, r io.Reader, clipType string, metadata types.Metadata) (*types.Clip, error) {
+	spooled, err := storage.Spool(r, s.fsPath)
+	if err != nil {
+		return nil, err
+	}
+	defer spooled.Discard()
+
+	return s.storeContent(ctx, spooled, clipType, metadata)
+}
+
+// storeContent saves content that has already been hashed and size checked
+func (s *DirectStorage) storeContent(ctx context.Context, content *storage.SpooledContent, clipType string, metadata types.Metadata) (*types.Clip, error) {
+	clipType = types.NormalizeType(clipType)
+	if err := s.saveApp(ctx, metadata); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.sqlDB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Content hashes are unique, so copying content that is in the trash restores it
+	now := time.Now()
+	existing, err := scanClip(tx.QueryRowContext(ctx, "SELECT "+clipColumns+" FROM clip_models WHERE content_hash = ?", content.Hash))
+	if err == nil {
+		existing.LastUsed = now
+		existing.UpdatedAt = now
+		existing.UseCount = existing.Uses() + 1
+		// Keep any representations we didn't have before
+		for format, data := range metadata.Formats {
+			if existing.Formats == nil {
+				existing.Formats = storage.FormatMap{}
+			}
+			if _, ok := existing.Formats[format]; !ok {
+				existing.Formats[format] = data
+			}
+		}
+		// The most recent copy decides whether the clip expires
+		existing.ExpiresAt = metadata.ExpiresAt
+		// and what the file it points to looks like
+		if metadata.File != nil {
+			existing.File = metadata.File
+		}
+		// and which copy session it was copied in
+		if metadata.Session != "" {
+			existing.Session = metadata.Session
+		}
+		file, err := jsonValue(existing.File)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode file: %w", err)
+		}
+		existing.DeletedAt.Valid = false
+		// Clips from before normalized hashes get one
+		if existing.NormalizedHash == "" {
+			existing.NormalizedHash = storage.NormalizedHash(content.Data, existing.Type)
+		}
+		if _, err := tx.ExecContext(ctx,
+			"UPDATE clip_models SET last_used = ?, updated_at = ?, use_count = ?, formats = ?, expires_at = ?, file = ?, normalized_hash = ?, session = ?, deleted_at = NULL WHERE id = ?",
+			now, now, existing.UseCount, existing.Formats, existing.ExpiresAt, file, existing.NormalizedHash, existing.Session, existing.ID); err != nil {
+			return nil, fmt.Errorf("failed to update existing clip: %w", err)
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, fmt.Errorf("failed to update existing clip: %w", err)
+		}
+		return existing.ToClip(), nil
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to check for existing content: %w", err)
+	}
+
+	model := &storage.ClipModel{
+		ContentHash:    content.Hash,
+		Type:           clipType,
+		Size:           content.Size,
+		SourceApp:      metadata.SourceApp,
+		SourceBundleID: metadata.SourceBundleID,
+		SourceURL:      metadata.SourceURL,
+		SourceTitle:    metadata.SourceTitle,
+		SourceWindow:   metadata.SourceWindow,
+		Device:         metadata.Device,
+		Category:       metadata.Category,
+		Tags:           metadata.Tags,
+		Formats:        metadata.Formats,
+		PlainText:      string(metadata.Formats[storage.FormatPlainText]),
+		ExpiresAt:      metadata.ExpiresAt,
+		Screenshot:     metadata.Screenshot,
+		Link:           metadata.Link,
+		Language:       metadata.Language,
+		Media:          metadata.Media,
+		MediaType:      storage.MediaType(metadata.Media),
+		File:           metadata.File,
+		LastUsed:       now,
+		UseCount:       1,
+		UUID:           storage.NewUUID(),
+		NormalizedHash: storage.NormalizedHash(content.Data, clipType),
+		Session:        metadata.Session,
+	}
+	model.CreatedAt, model.UpdatedAt = now, now
+
+	if storage.StoredExternally(clipType, content.Size) {
+		// Large text keeps its head in the database for searches and lists
+		if storage.IsText(clipType) {
+			if model.Content, err = content.Head(storage.TextHeadSize); err != nil {
+				return nil, err
+			}
+		}
+		// Store in filesystem, sharing the file with any clip that has the same content
+		if err := s.acquireBlob(ctx, tx, content); err != nil {
+			return nil, err
+		}
+		model.StoragePath = content.Hash
+		model.IsExternal = true
+	} else {
+		model.Content = content.Data
+	}
+
+	screenshot, err := jsonValue(model.Screenshot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode screenshot: %w", err)
+	}
+	link, err := jsonValue(model.Link)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode link: %w", err)
+	}
+	media, err := jsonValue(model.Media)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode media: %w", err)
+	}
+	file, err := jsonValue(model.File)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode file: %w", err)
+	}
+
+	result, err := tx.ExecContext(ctx, "INSERT INTO clip_models ("+
+		"created_at, updated_at, content_hash, content, storage_path, is_external, size, type, "+
+		"source_app, source_bundle_id, source_url, source_title, device, category, tags, last_used, "+
+		"synced_to_obsidian, formats, plain_text, expires_at, use_count, screenshot, link, language, "+
+		"media, media_type, pinned, uuid, file, normalized_hash, session, source_window"+
+		") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		model.CreatedAt, model.UpdatedAt, model.ContentHash, model.Content, model.StoragePath, model.IsExternal, model.Size, model.Type,
+		model.SourceApp, model.SourceBundleID, model.SourceURL, model.SourceTitle, model.Device, model.Category, model.Tags, model.LastUsed,
+		false, model.Formats, model.PlainText, model.ExpiresAt, model.UseCount, screenshot, link, model.Language,
+		media, model.MediaType, false, model.UUID, file, model.NormalizedHash, model.Session, model.SourceWindow)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create clip: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create clip: %w", err)
+	}
+	model.ID = uint(id)
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to create clip: %w", err)
+	}
+	return model.ToClip(), nil
+}
+
+// saveApp records the source application of a clip, keeping the first icon we get
+func (s *DirectStorage) saveApp(ctx context.Context, metadata types.Metadata) error {
+	if metadata.SourceBundleID == "" {
+		return nil
+	}
+
+	updates := "name = excluded.name, updated_at = excluded.updated_at"
+	if len(metadata.SourceIcon) > 0 {
+		updates += ", icon = excluded.icon"
+	}
+	if _, err := s.sqlDB.ExecContext(ctx,
+		"INSERT INTO app_models (bundle_id, name, icon, updated_at) VALUES (?, ?, ?, ?) ON CONFLICT (bundle_id) DO UPDATE SET "+updates,
+		metadata.SourceBundleID, metadata.SourceApp, metadata.SourceIcon, time.Now()); err != nil {
+		return fmt.Errorf("failed to save source app: %w", err)
+	}
+	return nil
+}
+
+// acquireBlob takes a reference to the external file for content, writing the
+// file only if no clip holds it yet or it has gone missing
+func (s *DirectStorage) acquireBlob(ctx context.Context, tx *sql.Tx, content *storage.SpooledContent) error {
+	path := filepath.Join(s.fsPath, content.Hash)
+
+	var refs int64
+	err := tx.QueryRowContext(ctx, "SELECT ref_count FROM blob_models WHERE hash = ?", content.Hash).Scan(&refs)
+	if errors.Is(err, sql.ErrNoRows) {
+		if err := content.MoveTo(path); err != nil {
+			return fmt.Errorf("failed to write file: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, "INSERT INTO blob_models (hash, size, ref_count, created_at) VALUES (?, ?, 1, ?)",
+			content.Hash, content.Size, time.Now()); err != nil {
+			return fmt.Errorf("failed to create blob: %w", err)
+		}
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to get blob: %w", err)
+	}
+
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if err := content.MoveTo(path); err != nil {
+			return fmt.Errorf("failed to write file: %w", err)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE blob_models SET ref_count = ref_count + 1 WHERE hash = ?", content.Hash); err != nil {
+		return fmt.Errorf("failed to update blob references: %w", err)
+	}
+	return nil
+}
+
+// Get implements storage.Storage interface
+func (s *DirectStorage) Get(ctx context.Context, id string) (*types.Clip, error) {
+	model, err := s.getClip(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get clip: %w", err)
+	}
+
+	if model.IsExternal {
+		content, err := readExternalFile(ctx, s.fsPath, model.StoragePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read external content: %w", err)
+		}
+		model.Content = content
+	}
+
+	if err := s.touch(ctx, model); err != nil {
+		return nil, fmt.Errorf("failed to update last used time: %w", err)
+	}
+	return model.ToClip(), nil
+}
+
+// GetStream implements storage.Storage interface
+func (s *DirectStorage) GetStream(ctx context.Context, id string) (io.ReadCloser, *types.Clip, error) {
+	model, err := s.getClip(ctx, id)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get clip: %w", err)
+	}
+	if err := s.touch(ctx, model); err != nil {
+		return nil, nil, fmt.Errorf("failed to update last used time: %w", err)
+	}
+
+	if !model.IsExternal {
+		content := model.Content
+		model.Content = nil
+		return io.NopCloser(bytes.NewReader(content)), model.ToClip(), nil
+	}
+
+	file, err := os.Open(filepath.Join(s.fsPath, model.StoragePath))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open external content: %w", err)
+	}
+	return file, model.ToClip(), nil
+}
+
+// Delete implements storage.Storage interface
+func (s *DirectStorage) Delete(ctx context.Context, id string) error {
+	// Soft delete moves the clip to the trash; its content is kept until purged
+	result, err := s.sqlDB.ExecContext(ctx, "UPDATE clip_models SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL", time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to delete clip: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("failed to get clip: %w", storage.ErrNotFound)
+	}
+	return nil
+}
+
+// List implements storage.Storage interface
+func (s *DirectStorage) List(ctx context.Context, filter storage.ListFilter) ([]*types.Clip, error) {
+	where := []string{"deleted_at IS NULL"}
+	var args []interface{}
+	if filter.Type != "" {
+		where = append(where, "type = ?")
+		args = append(args, types.NormalizeType(filter.Type))
+	}
+	if filter.Category != "" {
+		where = append(where, "category = ?")
+		args = append(args, filter.Category)
+	}
+	for _, tag := range filter.Tags {
+		where = append(where, "tags LIKE ?")
+		args = append(args, "%\""+tag+"\"%")
+	}
+
+	query := "SELECT " + clipColumns + " FROM clip_models WHERE " + strings.Join(where, " AND ") + " ORDER BY last_used DESC"
+	query, args = paginate(query, args, filter.Limit, filter.Offset)
+	models, err := scanClips(s.statements.query(ctx, query, args...))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list clips: %w", err)
+	}
+	return s.clipsWithContent(ctx, models)
+}
+
+// clipsWithContent converts models to clips, reading external content other
+// than large text, which only shows its head
+func (s *DirectStorage) clipsWithContent(ctx context.Context, models []*storage.ClipModel) ([]*types.Clip, error) {
+	clips := make([]*types.Clip, len(models))
+	for i, model := range models {
+		if model.IsExternal && !model.HasHead() {
+			content, err := readExternalFile(ctx, s.fsPath, model.StoragePath)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read external content for clip %d: %w", model.ID, err)
+			}
+			model.Content = content
+		}
+		clips[i] = model.ToClip()
+	}
+	return clips, nil
+}
+
+// paginate adds LIMIT and OFFSET to query when they're set
+func paginate(query string, args []interface{}, limit, offset int) (string, []interface{}) {
+	if limit <= 0 && offset <= 0 {
+		return query, args
+	}
+	if limit <= 0 {
+		// SQLite only takes an offset after a limit
+		limit = -1
+	}
+	query += " LIMIT ?"
+	args = append(args, limit)
+	if offset > 0 {
+		query += " OFFSET ?"
+		args = append(args, offset)
+	}
+	return query, args
+}
+
+// MarkAsSynced implements storage.Storage interface
+func (s *DirectStorage) MarkAsSynced(ctx context.Context, id string) error {
+	result, err := s.sqlDB.ExecContext(ctx, "UPDATE clip_models SET synced_to_obsidian = true, updated_at = ? WHERE id = ? AND deleted_at IS NULL", time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to mark clip as synced: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("no clip found with id: %s", id)
+	}
+	return nil
+}
+
+// ListUnsynced implements storage.Storage interface
+func (s *DirectStorage) ListUnsynced(ctx context.Context, limit int) ([]*types.Clip, error) {
+	// Sensitive clips never leave the machine
+	query := "SELECT " + clipColumns + " FROM clip_models WHERE deleted_at IS NULL AND synced_to_obsidian = false AND expires_at IS NULL ORDER BY created_at DESC"
+	query, args := paginate(query, nil, limit, 0)
+	models, err := scanClips(s.statements.query(ctx, query, args...))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list unsynced clips: %w", err)
+	}
+	return s.clipsWithContent(ctx, models)
+}
+
+// Search implements storage.SearchService interface
+func (s *DirectStorage) Search(ctx context.Context, opts storage.SearchOptions) ([]storage.SearchResult, error) {
+	parsed, err := storage.ParseQuery(opts.Query)
+	if err != nil {
+		return nil, err
+	}
+	query, args, err := s.searchQuery(ctx, opts, parsed)
+	if err != nil {
+		return nil, err
+	}
+
+	// Searches without the query language come in a fixed set of shapes,
+	// which are worth preparing. Query language conditions vary with the
+	// terms, so those searches are planned each time.
+	var models []*storage.ClipModel
+	if parsed.Empty() {
+		models, err = scanClips(s.statements.query(ctx, query, args...))
+	} else {
+		models, err = scanClips(s.readSQL.QueryContext(ctx, query, args...))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to search clips: %w", err)
+	}
+
+	results := make([]storage.SearchResult, len(models))
+	for i, model := range models {
+		clip := model.ToClip()
+
+		// Content is best effort, and large text only shows its head
+		if model.IsExternal && !model.HasHead() {
+			content, err := loadExternalContent(ctx, s.fsPath, model)
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			if err == nil {
+				clip.Content = content
+			}
+		}
+
+		results[i] = storage.SearchResult{
+			Clip:     clip,
+			LastUsed: model.LastUsed,
+			UseCount: int(model.Uses()),
+			Score:    float64(model.LastUsed.Unix()),
+		}
+	}
+
+	storage.Highlight(results, parsed)
+	return results, nil
+}
+
+// searchQuery builds the query Search runs for opts
+func (s *DirectStorage) searchQuery(ctx context.Context, opts storage.SearchOptions, parsed storage.Query) (string, []interface{}, error) {
+	where := []string{"deleted_at IS NULL"}
+	var args []interface{}
+	add := func(condition string, conditionArgs ...interface{}) {
+		where = append(where, condition)
+		args = append(args, conditionArgs...)
+	}
+
+	// Apply the query language, see storage.ParseQuery
+	if !parsed.Empty() {
+		condition, conditionArgs, err := queryCondition(ctx, parsed, s.fsPath, func() ([]storage.ClipModel, error) {
+			external, err := scanClips(s.statements.query(ctx, "SELECT "+clipColumns+" FROM clip_models WHERE deleted_at IS NULL AND type LIKE 'text%' AND is_external = 1 AND COALESCE(LENGTH(content), 0) = 0"))
+			models := make([]storage.ClipModel, len(external))
+			for i, model := range external {
+				models[i] = *model
+			}
+			return models, err
+		})
+		if err != nil {
+			return "", nil, err
+		}
+		add(condition, conditionArgs...)
+	}
+
+	// Apply filters
+	if opts.Type != "" {
+		add("type = ?", types.NormalizeType(opts.Type))
+	}
+	if opts.Format != "" {
+		add("(type = ? OR formats LIKE ?)", opts.Format, "%\""+opts.Format+"\":%")
+	}
+	if opts.SourceApp != "" {
+		add("source_app = ?", opts.SourceApp)
+	}
+	if opts.SourceBundleID != "" {
+		add("source_bundle_id = ?", opts.SourceBundleID)
+	}
+	if opts.SourceURL != "" {
+		add("LOWER(source_url) LIKE ?", "%"+strings.ToLower(opts.SourceURL)+"%")
+	}
+	if opts.Device != "" {
+		add("LOWER(device) LIKE ?", "%"+strings.ToLower(opts.Device)+"%")
+	}
+	if opts.Category != "" {
+		add("category = ?", opts.Category)
+	}
+	for _, tag := range opts.Tags {
+		add("tags LIKE ?", "%"+tag+"%")
+	}
+
+	// Apply the cursor, see storage.Cursor
+	usesCursor, err := opts.UsesCursor()
+	if err != nil {
+		return "", nil, err
+	}
+	if usesCursor {
+		cursor, err := storage.ParseCursor(opts.Cursor)
+		if err != nil {
+			return "", nil, err
+		}
+		add("(last_used, id) < (?, ?)", cursor.LastUsed, cursor.ID)
+	}
+
+	// Apply time range
+	if !opts.From.IsZero() {
+		add("created_at >= ?", opts.From)
+	}
+	if !opts.To.IsZero() {
+		add("created_at <= ?", opts.To)
+	}
+
+	query := "SELECT " + clipColumns + " FROM clip_models WHERE " + strings.Join(where, " AND ")
+
+	// Apply sorting
+	direction := "DESC"
+	if strings.ToLower(opts.SortOrder) == "asc" {
+		direction = "ASC"
+	}
+	switch opts.SortBy {
+	case "":
+		query += " ORDER BY last_used DESC, id DESC"
+	case "created_at":
+		query += fmt.Sprintf(" ORDER BY created_at %s", direction)
+	case "last_used":
+		query += fmt.Sprintf(" ORDER BY last_used %s, id %s", direction, direction)
+	case "use_count":
+		query += fmt.Sprintf(" ORDER BY use_count %s, last_used DESC", direction)
+	}
+
+	query, args = paginate(query, args, opts.Limit, opts.Offset)
+	return query, args, nil
+}
+
+// GetRecent implements storage.SearchService interface
+func (s *DirectStorage) GetRecent(ctx context.Context, opts storage.SearchOptions) ([]storage.SearchResult, error) {
+	return s.Search(ctx, opts.Recent())
+}
+
+// GetMostUsed implements storage.SearchService interface
+func (s *DirectStorage) GetMostUsed(ctx context.Context, opts storage.SearchOptions) ([]storage.SearchResult, error) {
+	return s.Search(ctx, opts.MostUsed())
+}
+
+// GetByType implements storage.SearchService interface
+func (s *DirectStorage) GetByType(ctx context.Context, clipType string, opts storage.SearchOptions) ([]storage.SearchResult, error) {
+	return s.Search(ctx, opts.OfType(clipType))
+}
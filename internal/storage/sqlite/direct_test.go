@@ -0,0 +1,267 @@
+package sqlite
+
+import (
+	"bytes"
+	"clipboard-manager/internal/storage"
+	"clipboard-manager/pkg/types"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func setupDirectDB(t *testing.T) *DirectStorage {
+	t.Helper()
+	dir := t.TempDir()
+	store, err := NewDirect(storage.Config{
+		DBPath: filepath.Join(dir, "test.db"),
+		FSPath: filepath.Join(dir, "files"),
+	})
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+// assertSame fails unless got and want encode to the same JSON
+func assertSame(t *testing.T, what string, got, want interface{}) {
+	t.Helper()
+	gotJSON, _ := json.Marshal(got)
+	wantJSON, _ := json.Marshal(want)
+	if !bytes.Equal(gotJSON, wantJSON) {
+		t.Errorf("%s differs from GORM\ndirect: %s\ngorm:   %s", what, gotJSON, wantJSON)
+	}
+}
+
+func TestDirect_MatchesGORM(t *testing.T) {
+	direct := setupDirectDB(t)
+	gorm := direct.SQLiteStorage
+
+	ctx := context.Background()
+	expires := time.Now().Add(time.Hour).Round(time.Second)
+	clips := []struct {
+		content  []byte
+		clipType string
+		metadata types.Metadata
+	}{
+		{[]byte("plain note"), storage.TypeText, types.Metadata{}},
+		{[]byte("tagged note"), storage.TypeText, types.Metadata{
+			SourceApp: "Notes", SourceBundleID: "com.apple.Notes", SourceIcon: []byte("icon"),
+			Tags: []string{"work", "pinned"}, Category: "notes",
+			Formats: storage.FormatMap{storage.FormatRTF: []byte("{\\rtf1 tagged}")},
+		}},
+		{append([]byte("needle "), bytes.Repeat([]byte("x"), storage.MaxInlineStorageSize)...), storage.TypeText, types.Metadata{}},
+		{[]byte("https://example.com/docs"), storage.TypeText, types.Metadata{
+			SourceURL: "https://example.com/page", SourceTitle: "Docs", Device: "iPhone",
+			Link: &types.LinkPreview{Title: "Docs", Description: "The docs"},
+		}},
+		{[]byte("secret"), storage.TypeText, types.Metadata{ExpiresAt: &expires}},
+		{[]byte("png"), "screenshot", types.Metadata{Screenshot: &types.Screenshot{Width: 10, Height: 20}}},
+	}
+
+	var ids []string
+	for i, c := range clips {
+		// Either backend can read what the other wrote
+		store := storage.Storage(direct)
+		if i%2 == 1 {
+			store = gorm
+		}
+		clip, err := store.Store(ctx, c.content, c.clipType, c.metadata)
+		if err != nil {
+			t.Fatalf("failed to store clip: %v", err)
+		}
+		ids = append(ids, clip.ID)
+	}
+	// Copying again counts a use
+	if _, err := direct.Store(ctx, []byte("plain note"), storage.TypeText, types.Metadata{}); err != nil {
+		t.Fatalf("failed to store clip: %v", err)
+	}
+
+	for _, id := range ids {
+		got, err := direct.Get(ctx, id)
+		if err != nil {
+			t.Fatalf("direct Get(%s): %v", id, err)
+		}
+		want, err := gorm.Get(ctx, id)
+		if err != nil {
+			t.Fatalf("gorm Get(%s): %v", id, err)
+		}
+		assertSame(t, "Get("+id+")", got, want)
+
+		reader, clip, err := direct.GetStream(ctx, id)
+		if err != nil {
+			t.Fatalf("direct GetStream(%s): %v", id, err)
+		}
+		content, _ := io.ReadAll(reader)
+		reader.Close()
+		if !bytes.Equal(content, want.Content) || clip.ID != id {
+			t.Errorf("GetStream(%s) returned different content", id)
+		}
+	}
+
+	gotList, err := direct.List(ctx, storage.ListFilter{Limit: 4, Offset: 1})
+	if err != nil {
+		t.Fatalf("direct List: %v", err)
+	}
+	wantList, err := gorm.List(ctx, storage.ListFilter{Limit: 4, Offset: 1})
+	if err != nil {
+		t.Fatalf("gorm List: %v", err)
+	}
+	assertSame(t, "List", gotList, wantList)
+
+	for _, opts := range []storage.SearchOptions{
+		{},
+		{Query: "needle"},
+		{Query: "note tag:work"},
+		{Query: "app:notes OR url:example"},
+		{Format: storage.FormatRTF},
+		{SourceURL: "EXAMPLE.com"},
+		{Type: "screenshot"},
+		{SortBy: "use_count", SortOrder: "desc"},
+		{SortBy: "created_at", SortOrder: "asc", Limit: 2, Offset: 1},
+		{Limit: 2, Cursor: storage.Cursor{LastUsed: time.Now().Add(time.Hour), ID: 1}.String()},
+	} {
+		got, err := direct.Search(ctx, opts)
+		if err != nil {
+			t.Fatalf("direct Search(%+v): %v", opts, err)
+		}
+		want, err := gorm.Search(ctx, opts)
+		if err != nil {
+			t.Fatalf("gorm Search(%+v): %v", opts, err)
+		}
+		if len(want) == 0 {
+			t.Errorf("Search(%+v) found nothing; the test needs a match", opts)
+		}
+		assertSame(t, "Search", got, want)
+	}
+
+	if err := direct.MarkAsSynced(ctx, ids[0]); err != nil {
+		t.Fatalf("direct MarkAsSynced: %v", err)
+	}
+	gotUnsynced, err := direct.ListUnsynced(ctx, 0)
+	if err != nil {
+		t.Fatalf("direct ListUnsynced: %v", err)
+	}
+	wantUnsynced, err := gorm.ListUnsynced(ctx, 0)
+	if err != nil {
+		t.Fatalf("gorm ListUnsynced: %v", err)
+	}
+	assertSame(t, "ListUnsynced", gotUnsynced, wantUnsynced)
+
+	// Deleting moves the clip to the trash, and copying it again restores it
+	if err := direct.Delete(ctx, ids[0]); err != nil {
+		t.Fatalf("direct Delete: %v", err)
+	}
+	if _, err := gorm.Get(ctx, ids[0]); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("expected the deleted clip to be gone, got %v", err)
+	}
+	if err := direct.Delete(ctx, ids[0]); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("expected deleting twice to fail with ErrNotFound, got %v", err)
+	}
+	trash, err := direct.ListTrash(ctx, 0, 0)
+	if err != nil || len(trash) != 1 {
+		t.Fatalf("expected one clip in the trash, got %d, %v", len(trash), err)
+	}
+	restored, err := direct.Store(ctx, []byte("plain note"), storage.TypeText, types.Metadata{})
+	if err != nil {
+		t.Fatalf("failed to store clip: %v", err)
+	}
+	if restored.ID != ids[0] {
+		t.Errorf("expected the clip to be restored, got ID %s", restored.ID)
+	}
+
+	if _, err := direct.Get(ctx, "12345"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestDirect_Blobs(t *testing.T) {
+	store := setupDirectDB(t)
+
+	ctx := context.Background()
+	content := bytes.Repeat([]byte("s"), storage.MaxInlineStorageSize+1)
+	clip, err := store.StoreStream(ctx, bytes.NewReader(content), storage.TypeFile, types.Metadata{})
+	if err != nil {
+		t.Fatalf("failed to store clip: %v", err)
+	}
+	path := filepath.Join(store.fsPath, calculateHash(content))
+	var blob storage.BlobModel
+	if err := store.db.First(&blob, "hash = ?", calculateHash(content)).Error; err != nil || blob.RefCount != 1 {
+		t.Fatalf("expected one reference to the blob, got %+v, %v", blob, err)
+	}
+
+	got, err := store.Get(ctx, clip.ID)
+	if err != nil || !bytes.Equal(got.Content, content) {
+		t.Fatalf("expected the clip's content, got %v", err)
+	}
+
+	// Purging the trash, which is left to the GORM code, releases the file
+	if err := store.Delete(ctx, clip.ID); err != nil {
+		t.Fatalf("failed to delete clip: %v", err)
+	}
+	if _, err := store.PurgeTrash(ctx, time.Now().Add(time.Second)); err != nil {
+		t.Fatalf("failed to purge trash: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected the file to be removed, got %v", err)
+	}
+}
+
+func TestSearch_QueryPlans(t *testing.T) {
+	store := setupDirectDB(t)
+	ctx := context.Background()
+	cursor := storage.Cursor{LastUsed: time.Now(), ID: 10}.String()
+
+	// The common searches must read an index, and those sorted by last use
+	// must read it in order rather than sort the clips they find, starting
+	// at the cursor if there is one
+	for _, test := range []struct {
+		opts   storage.SearchOptions
+		index  string
+		sorted bool
+	}{
+		{storage.SearchOptions{Limit: 50}, "idx_clip_models_last_used", true},
+		{storage.SearchOptions{Limit: 50, Cursor: cursor}, "idx_clip_models_last_used", true},
+		{storage.SearchOptions{Type: storage.TypeText, Limit: 50}, "idx_clip_models_type_last_used", true},
+		{storage.SearchOptions{Type: storage.TypeText, Limit: 50, Cursor: cursor}, "idx_clip_models_type_last_used", true},
+		{storage.SearchOptions{SourceApp: "Notes", Limit: 50}, "idx_clip_models_source_app", false},
+		{storage.SearchOptions{SourceBundleID: "com.apple.Notes", Limit: 50}, "idx_clip_models_source_bundle_id", false},
+		{storage.SearchOptions{SortBy: "use_count", SortOrder: "desc", Limit: 50}, "idx_clip_models_use_count", false},
+	} {
+		query, args, err := store.searchQuery(ctx, test.opts, storage.Query{})
+		if err != nil {
+			t.Fatalf("failed to build query: %v", err)
+		}
+		rows, err := store.sqlDB.QueryContext(ctx, "EXPLAIN QUERY PLAN "+query, args...)
+		if err != nil {
+			t.Fatalf("failed to explain query: %v", err)
+		}
+		var plan []string
+		for rows.Next() {
+			var id, parent, unused int
+			var detail string
+			if err := rows.Scan(&id, &parent, &unused, &detail); err != nil {
+				t.Fatalf("failed to read plan: %v", err)
+			}
+			plan = append(plan, detail)
+		}
+		rows.Close()
+
+		all := strings.Join(plan, "; ") + ";"
+		if !strings.Contains(all, "INDEX "+test.index+" ") && !strings.Contains(all, "INDEX "+test.index+";") {
+			t.Errorf("search %+v doesn't use %s: %s", test.opts, test.index, all)
+		}
+		if test.opts.Cursor != "" && !strings.Contains(all, "last_used<?") {
+			t.Errorf("search %+v doesn't seek to the cursor: %s", test.opts, all)
+		}
+		if test.sorted && strings.Contains(all, "TEMP B-TREE") {
+			t.Errorf("search %+v sorts its results: %s", test.opts, all)
+		}
+	}
+}
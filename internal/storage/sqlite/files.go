@@ -0,0 +1,76 @@
+package sqlite
+
+import (
+	"clipboard-manager/internal/storage"
+	"clipboard-manager/pkg/types"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"gorm.io/gorm"
+)
+
+// CopyFile implements storage.FileCopier interface
+func (s *SQLiteStorage) CopyFile(ctx context.Context, id string, r io.Reader) (*types.Clip, error) {
+	spooled, err := storage.Spool(r, s.fsPath)
+	if err != nil {
+		return nil, err
+	}
+	defer spooled.Discard()
+
+	var model storage.ClipModel
+	var orphaned []string
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		orphaned = nil
+		if err := tx.First(&model, id).Error; err != nil {
+			return notFound(err)
+		}
+		if model.File == nil || model.File.Hash != spooled.Hash {
+			return storage.ErrFileChanged
+		}
+		if model.FileCopy == spooled.Hash {
+			return nil
+		}
+
+		// Copies are blobs like the content of large clips, so a file
+		// copied twice, or also stored as a clip, is only kept once
+		if err := s.acquireBlob(tx, spooled); err != nil {
+			return err
+		}
+		if model.FileCopy != "" {
+			released, err := s.releaseBlob(tx, model.FileCopy)
+			if err != nil {
+				return err
+			}
+			if released {
+				orphaned = append(orphaned, model.FileCopy)
+			}
+		}
+		model.FileCopy = spooled.Hash
+		return tx.Model(&model).UpdateColumn("file_copy", model.FileCopy).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy file: %w", err)
+	}
+	s.removeBlobFiles(orphaned)
+	return model.ToClip(), nil
+}
+
+// OpenFileCopy implements storage.FileCopier interface
+func (s *SQLiteStorage) OpenFileCopy(ctx context.Context, id string) (io.ReadCloser, error) {
+	var model storage.ClipModel
+	if err := s.reads.WithContext(ctx).Select("id", "file_copy").First(&model, id).Error; err != nil {
+		return nil, fmt.Errorf("failed to get clip: %w", notFound(err))
+	}
+	if model.FileCopy == "" {
+		return nil, fmt.Errorf("no copy of the file of clip %s: %w", id, storage.ErrNotFound)
+	}
+
+	file, err := os.Open(filepath.Join(s.fsPath, model.FileCopy))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file copy: %w", err)
+	}
+	return file, nil
+}
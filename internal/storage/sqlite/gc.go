@@ -0,0 +1,78 @@
+package sqlite
+
+import (
+	"clipboard-manager/internal/storage"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+// GC implements storage.GarbageCollector interface
+func (s *SQLiteStorage) GC(ctx context.Context, dryRun bool) (*storage.GCReport, error) {
+	report := &storage.GCReport{DryRun: dryRun}
+
+	var models []storage.ClipModel
+	// Clips in the trash still own their files
+	if err := s.db.WithContext(ctx).Unscoped().
+		Select("id", "storage_path", "is_external", "file_copy").
+		Where("is_external = ? OR file_copy != ''", true).
+		Find(&models).Error; err != nil {
+		return nil, fmt.Errorf("failed to list external clips: %w", err)
+	}
+
+	referenced := make(map[string]bool, len(models))
+	var dangling, lost []uint
+	for _, model := range models {
+		id := strconv.FormatUint(uint64(model.ID), 10)
+		if model.FileCopy != "" {
+			// The clip still points to the original file, so only the copy is lost
+			if _, err := os.Stat(filepath.Join(s.fsPath, model.FileCopy)); os.IsNotExist(err) {
+				lost = append(lost, model.ID)
+				report.LostCopies = append(report.LostCopies, id)
+			} else {
+				referenced[model.FileCopy] = true
+			}
+		}
+		if !model.IsExternal {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(s.fsPath, model.StoragePath)); os.IsNotExist(err) {
+			dangling = append(dangling, model.ID)
+			report.DanglingClips = append(report.DanglingClips, id)
+			continue
+		}
+		referenced[model.StoragePath] = true
+	}
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Clips whose content is gone can't be recovered
+		if len(dangling) > 0 && !dryRun {
+			if err := tx.Unscoped().Delete(&storage.ClipModel{}, dangling).Error; err != nil {
+				return fmt.Errorf("failed to delete dangling clips: %w", err)
+			}
+		}
+		if len(lost) > 0 && !dryRun {
+			if err := tx.Unscoped().Model(&storage.ClipModel{}).Where("id IN ?", lost).
+				UpdateColumn("file_copy", "").Error; err != nil {
+				return fmt.Errorf("failed to forget lost copies: %w", err)
+			}
+		}
+
+		repaired, err := s.repairBlobs(tx, dryRun)
+		report.RepairedBlobs = repaired
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := storage.CollectFiles(ctx, s.fsPath, referenced, report); err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
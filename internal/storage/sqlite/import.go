@@ -0,0 +1,52 @@
+package sqlite
+
+import (
+	"clipboard-manager/internal/storage"
+	"clipboard-manager/pkg/types"
+	"context"
+	"fmt"
+)
+
+// Import implements storage.ImportService interface
+func (s *SQLiteStorage) Import(ctx context.Context, clip storage.ImportedClip) (*types.Clip, bool, error) {
+	size := int64(len(clip.Content))
+	if size > storage.MaxStorageSize {
+		return nil, false, storage.ErrFileTooLarge
+	}
+	hash := calculateHash(clip.Content)
+
+	var existing storage.ClipModel
+	err := s.db.WithContext(ctx).Unscoped().Where("content_hash = ?", hash).Limit(1).Find(&existing).Error
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to check for existing content: %w", err)
+	}
+	if existing.ID != 0 {
+		return existing.ToClip(), false, nil
+	}
+
+	stored, err := s.storeContent(ctx, &storage.SpooledContent{Hash: hash, Size: size, Data: clip.Content}, clip.Type, clip.Metadata)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Saving sets the times to now, so they are put back afterwards,
+	// skipping the hooks
+	lastUsed, uses := clip.LastUsed, clip.UseCount
+	if lastUsed.IsZero() {
+		lastUsed = clip.CreatedAt
+	}
+	if uses < 1 {
+		uses = 1
+	}
+	err = s.db.WithContext(ctx).Model(&storage.ClipModel{}).Where("id = ?", stored.ID).UpdateColumns(map[string]interface{}{
+		"created_at": clip.CreatedAt,
+		"updated_at": clip.CreatedAt,
+		"last_used":  lastUsed,
+		"use_count":  uses,
+	}).Error
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to set the times of imported clip %s: %w", stored.ID, err)
+	}
+	stored.CreatedAt = clip.CreatedAt
+	return stored, true, nil
+}
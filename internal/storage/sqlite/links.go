@@ -0,0 +1,21 @@
+package sqlite
+
+import (
+	"clipboard-manager/internal/storage"
+	"clipboard-manager/pkg/types"
+	"context"
+	"fmt"
+)
+
+// SetLinkPreview implements storage.LinkService interface
+func (s *SQLiteStorage) SetLinkPreview(ctx context.Context, id string, preview *types.LinkPreview) error {
+	result := s.db.WithContext(ctx).Model(&storage.ClipModel{}).Where("id = ?", id).
+		Select("link").UpdateColumns(&storage.ClipModel{Link: preview})
+	if result.Error != nil {
+		return fmt.Errorf("failed to set link preview: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("failed to set link preview: clip %s not found", id)
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package sqlite
+
+import (
+	"clipboard-manager/internal/storage"
+	"context"
+	"fmt"
+	"os"
+	"time"
+)
+
+// autoVacuumIncremental is the auto_vacuum mode incremental_vacuum needs
+const autoVacuumIncremental = 2
+
+// Maintain implements storage.Maintainer interface
+func (s *SQLiteStorage) Maintain(ctx context.Context, opts storage.MaintenanceOptions) (*storage.MaintenanceReport, error) {
+	report := &storage.MaintenanceReport{Started: time.Now(), Full: opts.Full}
+
+	if opts.Full {
+		if err := s.compactChanges(ctx); err != nil {
+			return nil, err
+		}
+		before, err := s.databaseBytes(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.vacuum(ctx); err != nil {
+			return nil, err
+		}
+		after, err := s.databaseBytes(ctx)
+		if err != nil {
+			return nil, err
+		}
+		report.FreedBytes = before - after
+
+		// Sampling keeps ANALYZE quick however large the history grows
+		if _, err := s.sqlDB.ExecContext(ctx, "PRAGMA analysis_limit = 1000; ANALYZE"); err != nil {
+			return nil, fmt.Errorf("failed to analyze database: %w", err)
+		}
+	}
+
+	wal, err := s.walBytes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	report.WALBytes = wal
+	mode := "PASSIVE"
+	if opts.WALLimit > 0 && wal > opts.WALLimit {
+		mode = "TRUNCATE"
+	}
+	// busy is set when readers kept the checkpoint from finishing
+	var busy, frames, checkpointed int
+	if err := s.sqlDB.QueryRowContext(ctx, "PRAGMA wal_checkpoint("+mode+")").Scan(&busy, &frames, &checkpointed); err != nil {
+		return nil, fmt.Errorf("failed to checkpoint WAL: %w", err)
+	}
+	report.WALTruncated = mode == "TRUNCATE" && busy == 0
+
+	report.Duration = time.Since(report.Started)
+	return report, nil
+}
+
+// vacuum returns the database's free pages to the file system. A database
+// created before auto_vacuum was set is rebuilt once to turn it on.
+func (s *SQLiteStorage) vacuum(ctx context.Context) error {
+	var mode int
+	if err := s.sqlDB.QueryRowContext(ctx, "PRAGMA auto_vacuum").Scan(&mode); err != nil {
+		return fmt.Errorf("failed to read auto_vacuum mode: %w", err)
+	}
+	if mode != autoVacuumIncremental {
+		if _, err := s.sqlDB.ExecContext(ctx, "PRAGMA auto_vacuum = INCREMENTAL; VACUUM"); err != nil {
+			return fmt.Errorf("failed to vacuum database: %w", err)
+		}
+		return nil
+	}
+
+	// Each row returned frees a page, so the rows must all be read
+	rows, err := s.sqlDB.QueryContext(ctx, "PRAGMA incremental_vacuum")
+	if err != nil {
+		return fmt.Errorf("failed to vacuum database: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to vacuum database: %w", err)
+	}
+	return nil
+}
+
+// DatabaseState implements storage.Maintainer interface
+func (s *SQLiteStorage) DatabaseState(ctx context.Context) (storage.DatabaseState, error) {
+	var state storage.DatabaseState
+	if err := s.readSQL.QueryRowContext(ctx, "SELECT freelist_count * page_size FROM pragma_freelist_count(), pragma_page_size()").Scan(&state.FreeBytes); err != nil {
+		return state, fmt.Errorf("failed to get free space: %w", err)
+	}
+	wal, err := s.walBytes(ctx)
+	if err != nil {
+		return state, err
+	}
+	state.WALBytes = wal
+	return state, nil
+}
+
+// databaseBytes returns the size of the database file
+func (s *SQLiteStorage) databaseBytes(ctx context.Context) (int64, error) {
+	var size int64
+	if err := s.sqlDB.QueryRowContext(ctx, "SELECT page_count * page_size FROM pragma_page_count(), pragma_page_size()").Scan(&size); err != nil {
+		return 0, fmt.Errorf("failed to get database size: %w", err)
+	}
+	return size, nil
+}
+
+// walBytes returns the size of the write-ahead log, which sits next to the
+// database file
+func (s *SQLiteStorage) walBytes(ctx context.Context) (int64, error) {
+	var seq int
+	var name, file string
+	if err := s.readSQL.QueryRowContext(ctx, "SELECT seq, name, file FROM pragma_database_list WHERE name = 'main'").Scan(&seq, &name, &file); err != nil {
+		return 0, fmt.Errorf("failed to find database file: %w", err)
+	}
+	if file == "" {
+		// In memory
+		return 0, nil
+	}
+	info, err := os.Stat(file + "-wal")
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get WAL size: %w", err)
+	}
+	return info.Size(), nil
+}
@@ -0,0 +1,61 @@
+package sqlite
+
+import (
+	"clipboard-manager/internal/storage"
+	"clipboard-manager/pkg/types"
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// Merge implements storage.Merger interface
+func (s *SQLiteStorage) Merge(ctx context.Context, keepID string, ids []string) (*types.Clip, error) {
+	var keep storage.ClipModel
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&keep, "id = ?", keepID).Error; err != nil {
+			return fmt.Errorf("failed to get clip: %w", notFound(err))
+		}
+
+		useCount, lastUsed := keep.Uses(), keep.LastUsed
+		for _, id := range ids {
+			if id == keepID {
+				continue
+			}
+			var model storage.ClipModel
+			if err := tx.First(&model, "id = ?", id).Error; err != nil {
+				return fmt.Errorf("failed to get clip %s: %w", id, notFound(err))
+			}
+			useCount += model.Uses()
+			if model.LastUsed.After(lastUsed) {
+				lastUsed = model.LastUsed
+			}
+
+			// Merged clips go to the trash like any other delete
+			if err := tx.Delete(&model).Error; err != nil {
+				return fmt.Errorf("failed to delete clip %s: %w", id, err)
+			}
+		}
+
+		// UpdateColumns skips the hook that would set last_used to now
+		if err := tx.Model(&keep).UpdateColumns(map[string]interface{}{
+			"use_count": useCount,
+			"last_used": lastUsed,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to update clip: %w", err)
+		}
+		keep.UseCount, keep.LastUsed = useCount, lastUsed
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if keep.IsExternal {
+		// Content is best effort, like Search
+		if content, err := s.loadExternalContent(ctx, &keep); err == nil {
+			keep.Content = content
+		}
+	}
+	return keep.ToClip(), nil
+}
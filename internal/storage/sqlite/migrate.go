@@ -0,0 +1,153 @@
+package sqlite
+
+import (
+	"clipboard-manager/internal/storage"
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Migrations are SQL files named NNNN_name.sql, applied in order of NNNN.
+// Each runs in a transaction with the row recording it in schema_version, so
+// a failed migration leaves the schema as it was. Applied files must not be
+// changed; add a new one instead.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migration is a schema change read from migrationFiles
+type migration struct {
+	version int
+	name    string
+	sql     string
+}
+
+// migrations returns the embedded migrations in order
+func migrations() ([]migration, error) {
+	entries, err := migrationFiles.ReadDir("migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	var list []migration
+	for _, entry := range entries {
+		number, name, ok := strings.Cut(strings.TrimSuffix(entry.Name(), ".sql"), "_")
+		version, err := strconv.Atoi(number)
+		if !ok || err != nil || version < 1 {
+			return nil, fmt.Errorf("migration %s is not named NNNN_name.sql", entry.Name())
+		}
+		content, err := migrationFiles.ReadFile(path.Join("migrations", entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, migration{version: version, name: name, sql: string(content)})
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].version < list[j].version })
+	for i := range list {
+		if list[i].version != i+1 {
+			return nil, fmt.Errorf("migration %d is missing", i+1)
+		}
+	}
+	return list, nil
+}
+
+// appliedMigrations returns when each applied migration was applied
+func appliedMigrations(ctx context.Context, db *sql.DB) (map[int]time.Time, error) {
+	if _, err := db.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS schema_version (version integer PRIMARY KEY, name text NOT NULL, applied_at datetime NOT NULL)"); err != nil {
+		return nil, fmt.Errorf("failed to create schema_version table: %w", err)
+	}
+
+	rows, err := db.QueryContext(ctx, "SELECT version, applied_at FROM schema_version")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, fmt.Errorf("failed to read schema version: %w", err)
+		}
+		applied[version] = appliedAt
+	}
+	return applied, rows.Err()
+}
+
+// migrate applies pending migrations to db and returns them
+func migrate(ctx context.Context, db *sql.DB) ([]storage.Migration, error) {
+	list, err := migrations()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := appliedMigrations(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []storage.Migration
+	for _, m := range list {
+		if _, ok := applied[m.version]; ok {
+			continue
+		}
+		appliedAt := time.Now()
+		if err := applyMigration(ctx, db, m, appliedAt); err != nil {
+			return done, fmt.Errorf("failed to apply migration %04d_%s: %w", m.version, m.name, err)
+		}
+		done = append(done, storage.Migration{Version: m.version, Name: m.name, AppliedAt: appliedAt})
+	}
+	return done, nil
+}
+
+// applyMigration runs m and records it in one transaction
+func applyMigration(ctx context.Context, db *sql.DB, m migration, appliedAt time.Time) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, m.sql); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "INSERT INTO schema_version (version, name, applied_at) VALUES (?, ?, ?)", m.version, m.name, appliedAt); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// migrationStatus lists every migration and when it was applied
+func migrationStatus(ctx context.Context, db *sql.DB) ([]storage.Migration, error) {
+	list, err := migrations()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := appliedMigrations(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]storage.Migration, len(list))
+	for i, m := range list {
+		result[i] = storage.Migration{Version: m.version, Name: m.name, AppliedAt: applied[m.version]}
+	}
+	return result, nil
+}
+
+// Migrations implements storage.MigrationService interface
+func (s *SQLiteStorage) Migrations(ctx context.Context) ([]storage.Migration, error) {
+	return migrationStatus(ctx, s.sqlDB)
+}
+
+// Migrate implements storage.MigrationService interface
+func (s *SQLiteStorage) Migrate(ctx context.Context) ([]storage.Migration, error) {
+	return migrate(ctx, s.sqlDB)
+}
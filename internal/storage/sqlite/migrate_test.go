@@ -0,0 +1,181 @@
+package sqlite
+
+import (
+	"clipboard-manager/internal/storage"
+	"clipboard-manager/pkg/types"
+	"context"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// schemaOf lists the columns and indexes of each table in db
+func schemaOf(t *testing.T, db *gorm.DB) map[string][]string {
+	t.Helper()
+	var tables []string
+	if err := db.Raw("SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT IN ('sqlite_sequence', 'schema_version')").Scan(&tables).Error; err != nil {
+		t.Fatalf("failed to list tables: %v", err)
+	}
+
+	schema := make(map[string][]string)
+	for _, table := range tables {
+		var columns, indexes []string
+		if err := db.Raw("SELECT name FROM pragma_table_info(?)", table).Scan(&columns).Error; err != nil {
+			t.Fatalf("failed to list columns: %v", err)
+		}
+		if err := db.Raw("SELECT name FROM sqlite_master WHERE type = 'index' AND tbl_name = ? AND sql IS NOT NULL", table).Scan(&indexes).Error; err != nil {
+			t.Fatalf("failed to list indexes: %v", err)
+		}
+		for _, index := range indexes {
+			columns = append(columns, "index "+index)
+		}
+		sort.Strings(columns)
+		schema[table] = columns
+	}
+	return schema
+}
+
+func TestMigrations_MatchModels(t *testing.T) {
+	store, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	// The migrations must produce the schema the models describe
+	want, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "models.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	if err := want.AutoMigrate(&storage.ClipModel{}, &storage.AppModel{}, &storage.BlobModel{}, &storage.VersionModel{}, &storage.ChangeModel{}, &storage.SyncStateModel{}); err != nil {
+		t.Fatalf("failed to migrate models: %v", err)
+	}
+
+	if got, want := schemaOf(t, store.db), schemaOf(t, want); !reflect.DeepEqual(got, want) {
+		t.Errorf("migrated schema = %v\nwant %v", got, want)
+	}
+}
+
+func TestMigrate_Legacy(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "clipboard.db")
+
+	// A database from before versioned migrations
+	db, err := gorm.Open(sqlite.Open(dbPath), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	list, err := migrations()
+	if err != nil {
+		t.Fatalf("failed to read migrations: %v", err)
+	}
+	if err := db.Exec(list[0].sql).Error; err != nil {
+		t.Fatalf("failed to create legacy schema: %v", err)
+	}
+	if err := db.Exec(`
+		CREATE INDEX idx_clips_content_hash ON clip_models(content_hash);
+		INSERT INTO clip_models (content_hash, content, type, tags, use_count) VALUES
+			('a', 'pinned clip', 'text/plain', '["pinned"]', 0),
+			('b', 'other clip', 'text', '["work"]', 3);
+	`).Error; err != nil {
+		t.Fatalf("failed to create legacy clips: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.Close()
+
+	store, err := New(storage.Config{DBPath: dbPath, FSPath: filepath.Join(dir, "files")})
+	if err != nil {
+		t.Fatalf("failed to open legacy database: %v", err)
+	}
+	defer store.Close()
+
+	var models []storage.ClipModel
+	if err := store.db.Order("id").Find(&models).Error; err != nil {
+		t.Fatalf("failed to load clips: %v", err)
+	}
+	if len(models) != 2 {
+		t.Fatalf("expected 2 clips, got %d", len(models))
+	}
+	if models[0].UseCount != 1 || models[1].UseCount != 3 {
+		t.Errorf("use counts = %d, %d, want 1, 3", models[0].UseCount, models[1].UseCount)
+	}
+	if !models[0].Pinned || models[1].Pinned {
+		t.Errorf("pinned = %v, %v, want true, false", models[0].Pinned, models[1].Pinned)
+	}
+	if models[1].Type != types.TypeText {
+		t.Errorf("type = %q, want %q", models[1].Type, types.TypeText)
+	}
+	if len(models[0].UUID) != 36 || models[0].UUID == models[1].UUID {
+		t.Errorf("expected distinct UUIDs, got %q and %q", models[0].UUID, models[1].UUID)
+	}
+
+	var duplicates int64
+	store.db.Raw("SELECT COUNT(*) FROM sqlite_master WHERE name = 'idx_clips_content_hash'").Scan(&duplicates)
+	if duplicates != 0 {
+		t.Error("expected the duplicate index to be dropped")
+	}
+
+	clip, err := store.Store(context.Background(), []byte("new clip"), storage.TypeText, types.Metadata{})
+	if err != nil {
+		t.Fatalf("failed to store clip: %v", err)
+	}
+	var model storage.ClipModel
+	if err := store.db.First(&model, clip.ID).Error; err != nil {
+		t.Fatalf("failed to load clip: %v", err)
+	}
+	if len(model.UUID) != 36 {
+		t.Errorf("expected a new clip to get a UUID, got %q", model.UUID)
+	}
+
+	// Maintenance turns on incremental vacuum, which a new database starts with
+	if _, err := store.Maintain(context.Background(), storage.MaintenanceOptions{Full: true}); err != nil {
+		t.Fatalf("failed to maintain database: %v", err)
+	}
+	var autoVacuum int
+	store.db.Raw("PRAGMA auto_vacuum").Scan(&autoVacuum)
+	if autoVacuum != autoVacuumIncremental {
+		t.Errorf("auto_vacuum = %d, want %d", autoVacuum, autoVacuumIncremental)
+	}
+}
+
+func TestMigrate_NoMigrate(t *testing.T) {
+	dir := t.TempDir()
+	store, err := New(storage.Config{DBPath: filepath.Join(dir, "clipboard.db"), FSPath: filepath.Join(dir, "files"), NoMigrate: true})
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	pending, err := store.Migrations(ctx)
+	if err != nil {
+		t.Fatalf("failed to list migrations: %v", err)
+	}
+	for _, m := range pending {
+		if !m.AppliedAt.IsZero() {
+			t.Errorf("expected migration %d to be pending", m.Version)
+		}
+	}
+
+	applied, err := store.Migrate(ctx)
+	if err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	if len(applied) != len(pending) {
+		t.Errorf("applied %d migrations, want %d", len(applied), len(pending))
+	}
+	if applied, err := store.Migrate(ctx); err != nil || len(applied) != 0 {
+		t.Errorf("expected nothing left to apply, got %v, %v", applied, err)
+	}
+
+	all, err := store.Migrations(ctx)
+	if err != nil {
+		t.Fatalf("failed to list migrations: %v", err)
+	}
+	for _, m := range all {
+		if m.AppliedAt.IsZero() {
+			t.Errorf("expected migration %d to be applied", m.Version)
+		}
+	}
+}
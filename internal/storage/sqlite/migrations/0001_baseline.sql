@@ -0,0 +1,19 @@
+-- The schema as created by AutoMigrate before versioned migrations. Databases
+-- from then already have these tables, so this only creates what's missing.
+CREATE TABLE IF NOT EXISTS `clip_models` (`id` integer PRIMARY KEY AUTOINCREMENT,`created_at` datetime,`updated_at` datetime,`deleted_at` datetime,`content_hash` text,`content` blob,`storage_path` text,`is_external` boolean,`size` bigint,`type` text NOT NULL,`metadata` json,`source_app` text,`source_bundle_id` text,`source_url` text,`source_title` text,`device` text,`category` text,`tags` json,`last_used` datetime,`synced_to_obsidian` boolean DEFAULT false,`formats` json,`plain_text` text,`expires_at` datetime,`use_count` integer DEFAULT 1,`screenshot` text,`link` text,`language` text,`media` text,`media_type` text);
+CREATE INDEX IF NOT EXISTS `idx_clip_models_media_type` ON `clip_models`(`media_type`);
+CREATE INDEX IF NOT EXISTS `idx_clip_models_last_used` ON `clip_models`(`last_used`);
+CREATE INDEX IF NOT EXISTS `idx_clip_models_category` ON `clip_models`(`category`);
+CREATE INDEX IF NOT EXISTS `idx_clip_models_source_bundle_id` ON `clip_models`(`source_bundle_id`);
+CREATE INDEX IF NOT EXISTS `idx_clip_models_deleted_at` ON `clip_models`(`deleted_at`);
+CREATE INDEX IF NOT EXISTS `idx_clip_models_expires_at` ON `clip_models`(`expires_at`);
+CREATE INDEX IF NOT EXISTS `idx_clip_models_device` ON `clip_models`(`device`);
+CREATE INDEX IF NOT EXISTS `idx_clip_models_source_url` ON `clip_models`(`source_url`);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_clip_models_content_hash` ON `clip_models`(`content_hash`);
+
+CREATE TABLE IF NOT EXISTS `app_models` (`bundle_id` text,`name` text,`icon` blob,`updated_at` datetime,PRIMARY KEY (`bundle_id`));
+
+CREATE TABLE IF NOT EXISTS `blob_models` (`hash` text,`size` integer,`ref_count` integer,`created_at` datetime,PRIMARY KEY (`hash`));
+
+CREATE TABLE IF NOT EXISTS `version_models` (`id` integer PRIMARY KEY AUTOINCREMENT,`clip_id` integer,`version` integer,`content` blob,`type` text NOT NULL,`formats` json,`created_at` datetime);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_clip_version` ON `version_models`(`clip_id`,`version`);
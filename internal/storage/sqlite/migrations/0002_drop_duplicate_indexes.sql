@@ -0,0 +1,4 @@
+-- New used to add these next to the indexes AutoMigrate creates on the same
+-- columns, so every insert updated both
+DROP INDEX IF EXISTS idx_clips_content_hash;
+DROP INDEX IF EXISTS idx_clips_last_used;
@@ -0,0 +1,4 @@
+-- Clips stored before copies were counted have no count; they were copied once.
+-- The index serves sorting by use_count.
+UPDATE `clip_models` SET `use_count` = 1 WHERE `use_count` IS NULL OR `use_count` < 1;
+CREATE INDEX `idx_clip_models_use_count` ON `clip_models`(`use_count`);
@@ -0,0 +1,4 @@
+-- Pinning becomes a column rather than the "pinned" tag the dashboard uses
+ALTER TABLE `clip_models` ADD COLUMN `pinned` numeric DEFAULT false;
+UPDATE `clip_models` SET `pinned` = true WHERE `tags` LIKE '%"pinned"%';
+CREATE INDEX `idx_clip_models_pinned` ON `clip_models`(`pinned`);
@@ -0,0 +1,11 @@
+-- A stable identifier for clips that doesn't depend on the row ID, for sync
+-- between devices. Existing clips get a random (version 4) UUID.
+ALTER TABLE `clip_models` ADD COLUMN `uuid` text;
+UPDATE `clip_models` SET `uuid` =
+	lower(hex(randomblob(4))) || '-' ||
+	lower(hex(randomblob(2))) || '-4' ||
+	substr(lower(hex(randomblob(2))), 2) || '-' ||
+	substr('89ab', 1 + abs(random()) % 4, 1) || substr(lower(hex(randomblob(2))), 2) || '-' ||
+	lower(hex(randomblob(6)))
+WHERE `uuid` IS NULL;
+CREATE UNIQUE INDEX `idx_clip_models_uuid` ON `clip_models`(`uuid`);
@@ -0,0 +1,12 @@
+-- Searches filtered by type read the newest clips of that type straight from
+-- the composite index instead of sorting every clip of the type, and
+-- filtering by app no longer scans the table.
+CREATE INDEX `idx_clip_models_type_last_used` ON `clip_models`(`type`, `last_used`);
+CREATE INDEX `idx_clip_models_source_app` ON `clip_models`(`source_app`);
+
+-- Every search asks for deleted_at IS NULL, which the planner took for a
+-- selective equality on this index, and then sorted all the clips it matched
+-- rather than reading the last_used index in order. Only the trash looks up
+-- deleted clips, so only they are indexed. See TestSearch_QueryPlans.
+DROP INDEX IF EXISTS `idx_clip_models_deleted_at`;
+CREATE INDEX `idx_clip_models_deleted_at` ON `clip_models`(`deleted_at`) WHERE `deleted_at` IS NOT NULL;
@@ -0,0 +1,4 @@
+-- File clips keep a snapshot of the file they point to, and optionally the
+-- hash of a copy of it kept as a blob
+ALTER TABLE `clip_models` ADD COLUMN `file` text;
+ALTER TABLE `clip_models` ADD COLUMN `file_copy` text;
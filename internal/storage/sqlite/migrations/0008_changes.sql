@@ -0,0 +1,26 @@
+-- A feed of changes to clips, so sync clients can follow history instead of
+-- listing it. Triggers record every insert, update and delete however it is
+-- made; moving a clip to the trash counts as deleting it. Marking a clip
+-- synced to Obsidian isn't a change. AUTOINCREMENT keeps sequence numbers
+-- from being reused once old changes are compacted away.
+CREATE TABLE `change_models` (`seq` integer PRIMARY KEY AUTOINCREMENT,`clip_id` integer,`uuid` text,`op` text NOT NULL,`changed_at` datetime);
+CREATE INDEX `idx_change_models_clip_id` ON `change_models`(`clip_id`);
+
+CREATE TRIGGER `clip_models_insert_change` AFTER INSERT ON `clip_models`
+BEGIN
+	INSERT INTO `change_models` (`clip_id`, `uuid`, `op`, `changed_at`)
+	VALUES (NEW.`id`, NEW.`uuid`, CASE WHEN NEW.`deleted_at` IS NULL THEN 'insert' ELSE 'delete' END, strftime('%Y-%m-%d %H:%M:%f', 'now'));
+END;
+
+CREATE TRIGGER `clip_models_update_change` AFTER UPDATE ON `clip_models`
+WHEN OLD.`synced_to_obsidian` IS NEW.`synced_to_obsidian`
+BEGIN
+	INSERT INTO `change_models` (`clip_id`, `uuid`, `op`, `changed_at`)
+	VALUES (NEW.`id`, NEW.`uuid`, CASE WHEN NEW.`deleted_at` IS NULL THEN 'update' ELSE 'delete' END, strftime('%Y-%m-%d %H:%M:%f', 'now'));
+END;
+
+CREATE TRIGGER `clip_models_delete_change` AFTER DELETE ON `clip_models`
+BEGIN
+	INSERT INTO `change_models` (`clip_id`, `uuid`, `op`, `changed_at`)
+	VALUES (OLD.`id`, OLD.`uuid`, 'delete', strftime('%Y-%m-%d %H:%M:%f', 'now'));
+END;
@@ -0,0 +1,15 @@
+-- Failed attempts to sync clips to Obsidian, so a clip that can't be synced
+-- is retried later, and given up on after a few attempts, instead of
+-- holding back the others. The state goes once the clip is synced or gone.
+CREATE TABLE `sync_state_models` (`clip_id` integer,`attempts` integer,`last_error` text,`last_attempt` datetime,`next_retry` datetime,PRIMARY KEY (`clip_id`));
+
+CREATE TRIGGER `clip_models_synced_state` AFTER UPDATE OF `synced_to_obsidian` ON `clip_models`
+WHEN NEW.`synced_to_obsidian`
+BEGIN
+	DELETE FROM `sync_state_models` WHERE `clip_id` = NEW.`id`;
+END;
+
+CREATE TRIGGER `clip_models_delete_state` AFTER DELETE ON `clip_models`
+BEGIN
+	DELETE FROM `sync_state_models` WHERE `clip_id` = OLD.`id`;
+END;
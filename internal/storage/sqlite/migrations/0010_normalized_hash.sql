@@ -0,0 +1,5 @@
+-- The hash of plain text with whitespace collapsed and Unicode normalized,
+-- so copies that differ only in those ways can be found. Existing clips get
+-- theirs the next time their content is stored.
+ALTER TABLE `clip_models` ADD COLUMN `normalized_hash` text;
+CREATE INDEX `idx_clip_models_normalized_hash` ON `clip_models`(`normalized_hash`);
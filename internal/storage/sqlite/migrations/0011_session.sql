@@ -0,0 +1,4 @@
+-- The copy session a clip was last copied in, so clips copied together from
+-- one app can be found from any of them. Older clips have none.
+ALTER TABLE `clip_models` ADD COLUMN `session` text;
+CREATE INDEX `idx_clip_models_session` ON `clip_models`(`session`);
@@ -0,0 +1,2 @@
+-- The title of the window a clip was copied from, searched with window:
+ALTER TABLE `clip_models` ADD COLUMN `source_window` text;
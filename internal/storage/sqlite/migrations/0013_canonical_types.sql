@@ -0,0 +1,5 @@
+-- Types older versions stored under other names, as types.NormalizeType names them
+UPDATE `clip_models` SET `type` = 'text/plain' WHERE `type` IN ('text', 'text/plain;charset=utf-8', 'text/plain; charset=utf-8');
+UPDATE `clip_models` SET `type` = 'image/jpeg' WHERE `type` = 'image/jpg';
+UPDATE `version_models` SET `type` = 'text/plain' WHERE `type` IN ('text', 'text/plain;charset=utf-8', 'text/plain; charset=utf-8');
+UPDATE `version_models` SET `type` = 'image/jpeg' WHERE `type` = 'image/jpg';
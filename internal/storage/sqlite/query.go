@@ -0,0 +1,108 @@
+package sqlite
+
+import (
+	"clipboard-manager/internal/storage"
+	"context"
+	"fmt"
+	"strings"
+)
+
+// queryCondition translates a parsed query into a WHERE condition
+func (s *SQLiteStorage) queryCondition(ctx context.Context, query storage.Query) (string, []interface{}, error) {
+	return queryCondition(ctx, query, s.fsPath, func() ([]storage.ClipModel, error) {
+		var external []storage.ClipModel
+		err := s.reads.WithContext(ctx).Where("type LIKE 'text%' AND is_external = 1 AND COALESCE(LENGTH(content), 0) = 0").Find(&external).Error
+		return external, err
+	})
+}
+
+// queryCondition translates a parsed query into a WHERE condition without
+// GORM. Text is also searched for in the files under fsPath of the external
+// text clips loadExternal returns, which are those stored before large text
+// kept its head in the database.
+func queryCondition(ctx context.Context, query storage.Query, fsPath string, loadExternal func() ([]storage.ClipModel, error)) (string, []interface{}, error) {
+	var external []storage.ClipModel
+	loadedExternal := false
+
+	var groups []string
+	var args []interface{}
+	for _, group := range query.Groups {
+		var terms []string
+		for _, term := range group {
+			if term.Field == storage.FieldText && !loadedExternal {
+				var err error
+				if external, err = loadExternal(); err != nil {
+					return "", nil, fmt.Errorf("failed to load external text clips: %w", err)
+				}
+				loadedExternal = true
+			}
+			condition, termArgs, err := termCondition(ctx, term, fsPath, external)
+			if err != nil {
+				return "", nil, err
+			}
+			terms = append(terms, condition)
+			args = append(args, termArgs...)
+		}
+		groups = append(groups, "("+strings.Join(terms, " AND ")+")")
+	}
+	return "(" + strings.Join(groups, " OR ") + ")", args, nil
+}
+
+// termCondition translates one query term. Text is also searched for in the
+// files of external text clips, which stops when ctx is done.
+func termCondition(ctx context.Context, term storage.Term, fsPath string, external []storage.ClipModel) (string, []interface{}, error) {
+	like := "%" + term.Value + "%"
+	switch term.Field {
+	case storage.FieldType:
+		return "(type = ? OR type LIKE ? OR media_type = ? OR media_type LIKE ?)",
+			[]interface{}{term.Value, term.Value + "/%", term.Value, term.Value + "/%"}, nil
+	case storage.FieldApp:
+		return "(LOWER(source_app) LIKE ? OR LOWER(source_bundle_id) LIKE ?)", []interface{}{like, like}, nil
+	case storage.FieldTag:
+		return "LOWER(tags) LIKE ?", []interface{}{"%\"" + term.Value + "\"%"}, nil
+	case storage.FieldFormat:
+		return "(type = ? OR formats LIKE ?)", []interface{}{term.Value, "%\"" + term.Value + "\":%"}, nil
+	case storage.FieldURL:
+		return "LOWER(source_url) LIKE ?", []interface{}{like}, nil
+	case storage.FieldDevice:
+		return "LOWER(device) LIKE ?", []interface{}{like}, nil
+	case storage.FieldWindow:
+		return "LOWER(source_window) LIKE ?", []interface{}{like}, nil
+	case storage.FieldCategory:
+		return "LOWER(category) = ?", []interface{}{term.Value}, nil
+	case storage.FieldBefore:
+		return "created_at < ?", []interface{}{term.Time}, nil
+	case storage.FieldAfter:
+		return "created_at >= ?", []interface{}{term.Time}, nil
+	}
+
+	condition := "((type LIKE 'text%' AND (" +
+		"  LOWER(CAST(content AS TEXT)) LIKE ? OR " +
+		"  LOWER(content_hash) LIKE ?" +
+		")) OR " +
+		"LOWER(plain_text) LIKE ? OR " +
+		"LOWER(source_app) LIKE ? OR " +
+		"LOWER(category) LIKE ? OR " +
+		"LOWER(tags) LIKE ?"
+	args := []interface{}{like, like, like, like, like, like}
+
+	var ids []uint
+	for i := range external {
+		content, err := loadExternalContent(ctx, fsPath, &external[i])
+		if ctx.Err() != nil {
+			return "", nil, ctx.Err()
+		}
+		if err == nil {
+			if strings.Contains(strings.ToLower(string(content)), term.Value) {
+				ids = append(ids, external[i].ID)
+			}
+		}
+	}
+	if len(ids) > 0 {
+		condition += " OR id IN (?" + strings.Repeat(", ?", len(ids)-1) + ")"
+		for _, id := range ids {
+			args = append(args, id)
+		}
+	}
+	return condition + ")", args, nil
+}
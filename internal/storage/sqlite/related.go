@@ -0,0 +1,30 @@
+package sqlite
+
+import (
+	"clipboard-manager/internal/storage"
+	"clipboard-manager/pkg/types"
+	"context"
+	"fmt"
+)
+
+// Related implements storage.RelatedFinder interface
+func (s *SQLiteStorage) Related(ctx context.Context, id string) ([]*types.Clip, error) {
+	var model storage.ClipModel
+	if err := s.reads.WithContext(ctx).First(&model, id).Error; err != nil {
+		return nil, fmt.Errorf("failed to get clip: %w", notFound(err))
+	}
+	clips := []*types.Clip{}
+	if model.Session == "" {
+		return clips, nil
+	}
+
+	var models []storage.ClipModel
+	if err := s.reads.WithContext(ctx).Where("session = ? AND id <> ?", model.Session, model.ID).
+		Order("last_used ASC").Find(&models).Error; err != nil {
+		return nil, fmt.Errorf("failed to find related clips: %w", err)
+	}
+	for i := range models {
+		clips = append(clips, models[i].ToClip())
+	}
+	return clips, nil
+}
@@ -2,6 +2,8 @@ package sqlite
 
 import (
 	"clipboard-manager/internal/storage"
+	"clipboard-manager/pkg/types"
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -9,51 +11,47 @@ import (
 )
 
 // Search implements storage.SearchService interface
-func (s *SQLiteStorage) Search(opts storage.SearchOptions) ([]storage.SearchResult, error) {
-	query := s.db.Model(&storage.ClipModel{})
-
-	// Apply text search if query provided
-	if opts.Query != "" {
-		// Case-insensitive search in content, source app, and metadata
-		searchTerm := strings.ToLower(opts.Query)
-		
-		// First, get all text clips that match the search term
-		query = query.Where(
-			"(type LIKE 'text%' AND ("+
-			"  (is_external = 0 AND LOWER(CAST(content AS TEXT)) LIKE ?) OR "+
-			"  LOWER(content_hash) LIKE ?"+
-			")) OR "+
-			"LOWER(source_app) LIKE ? OR "+
-			"LOWER(category) LIKE ? OR "+
-			"LOWER(tags) LIKE ?",
-			"%"+searchTerm+"%",
-			"%"+searchTerm+"%",
-			"%"+searchTerm+"%",
-			"%"+searchTerm+"%",
-			"%"+searchTerm+"%",
-		)
-
-		// Also get external text clips
-		var externalClips []storage.ClipModel
-		s.db.Where("type LIKE 'text%' AND is_external = 1").Find(&externalClips)
-
-		// Search through external content
-		for _, clip := range externalClips {
-			if content, err := s.loadExternalContent(&clip); err == nil {
-				if strings.Contains(strings.ToLower(string(content)), searchTerm) {
-					query = query.Or("id = ?", clip.ID)
-				}
-			}
+func (s *SQLiteStorage) Search(ctx context.Context, opts storage.SearchOptions) ([]storage.SearchResult, error) {
+	// Apply the query language, see storage.ParseQuery
+	parsed, err := storage.ParseQuery(opts.Query)
+	if err != nil {
+		return nil, err
+	}
+
+	// Searches without the query language come in a fixed set of shapes,
+	// which are worth preparing
+	db := s.reads
+	if parsed.Empty() {
+		db = s.prepared
+	}
+	query := db.WithContext(ctx).Model(&storage.ClipModel{})
+	if !parsed.Empty() {
+		condition, args, err := s.queryCondition(ctx, parsed)
+		if err != nil {
+			return nil, err
 		}
+		query = query.Where(condition, args...)
 	}
 
 	// Apply filters
 	if opts.Type != "" {
-		query = query.Where("type = ?", opts.Type)
+		query = query.Where("type = ?", types.NormalizeType(opts.Type))
+	}
+	if opts.Format != "" {
+		query = query.Where("(type = ? OR formats LIKE ?)", opts.Format, "%\""+opts.Format+"\":%")
 	}
 	if opts.SourceApp != "" {
 		query = query.Where("source_app = ?", opts.SourceApp)
 	}
+	if opts.SourceBundleID != "" {
+		query = query.Where("source_bundle_id = ?", opts.SourceBundleID)
+	}
+	if opts.SourceURL != "" {
+		query = query.Where("LOWER(source_url) LIKE ?", "%"+strings.ToLower(opts.SourceURL)+"%")
+	}
+	if opts.Device != "" {
+		query = query.Where("LOWER(device) LIKE ?", "%"+strings.ToLower(opts.Device)+"%")
+	}
 	if opts.Category != "" {
 		query = query.Where("category = ?", opts.Category)
 	}
@@ -63,6 +61,19 @@ func (s *SQLiteStorage) Search(opts storage.SearchOptions) ([]storage.SearchResu
 		}
 	}
 
+	// Apply the cursor, see storage.Cursor
+	usesCursor, err := opts.UsesCursor()
+	if err != nil {
+		return nil, err
+	}
+	if usesCursor {
+		cursor, err := storage.ParseCursor(opts.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("(last_used, id) < (?, ?)", cursor.LastUsed, cursor.ID)
+	}
+
 	// Apply time range
 	if !opts.From.IsZero() {
 		query = query.Where("created_at >= ?", opts.From)
@@ -82,11 +93,13 @@ func (s *SQLiteStorage) Search(opts storage.SearchOptions) ([]storage.SearchResu
 		case "created_at":
 			query = query.Order(fmt.Sprintf("created_at %s", direction))
 		case "last_used":
-			query = query.Order(fmt.Sprintf("last_used %s", direction))
+			query = query.Order(fmt.Sprintf("last_used %s, id %s", direction, direction))
+		case "use_count":
+			query = query.Order(fmt.Sprintf("use_count %s, last_used DESC", direction))
 		}
 	} else {
 		// Default sort by last used time
-		query = query.Order("last_used DESC")
+		query = query.Order("last_used DESC, id DESC")
 	}
 
 	// Apply pagination
@@ -107,9 +120,13 @@ func (s *SQLiteStorage) Search(opts storage.SearchOptions) ([]storage.SearchResu
 	for i, model := range models {
 		clip := model.ToClip()
 
-		// Load external content if needed
-		if model.IsExternal {
-			if content, err := s.loadExternalContent(&model); err == nil {
+		// Load external content if needed; large text only shows its head
+		if model.IsExternal && !model.HasHead() {
+			content, err := s.loadExternalContent(ctx, &model)
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			if err == nil {
 				clip.Content = content
 			}
 		}
@@ -117,56 +134,57 @@ func (s *SQLiteStorage) Search(opts storage.SearchOptions) ([]storage.SearchResu
 		results[i] = storage.SearchResult{
 			Clip:     clip,
 			LastUsed: model.LastUsed,
+			UseCount: int(model.Uses()),
 			// For now, we'll use a simple relevance score based on recency
 			Score: float64(model.LastUsed.Unix()),
 		}
 	}
 
+	storage.Highlight(results, parsed)
 	return results, nil
 }
 
 // GetRecent implements storage.SearchService interface
-func (s *SQLiteStorage) GetRecent(limit int) ([]storage.SearchResult, error) {
-	return s.Search(storage.SearchOptions{
-		Limit:     limit,
-		SortBy:    "last_used",
-		SortOrder: "desc",
-	})
+func (s *SQLiteStorage) GetRecent(ctx context.Context, opts storage.SearchOptions) ([]storage.SearchResult, error) {
+	return s.Search(ctx, opts.Recent())
 }
 
 // GetMostUsed implements storage.SearchService interface
-func (s *SQLiteStorage) GetMostUsed(limit int) ([]storage.SearchResult, error) {
-	// For now, we'll use last_used as a proxy for usage frequency
-	// In the future, we could add a use_count field to track this properly
-	return s.Search(storage.SearchOptions{
-		Limit:     limit,
-		SortBy:    "last_used",
-		SortOrder: "desc",
-	})
+func (s *SQLiteStorage) GetMostUsed(ctx context.Context, opts storage.SearchOptions) ([]storage.SearchResult, error) {
+	return s.Search(ctx, opts.MostUsed())
 }
 
 // GetByType implements storage.SearchService interface
-func (s *SQLiteStorage) GetByType(clipType string, limit int) ([]storage.SearchResult, error) {
-	return s.Search(storage.SearchOptions{
-		Type:      clipType,
-		Limit:     limit,
-		SortBy:    "last_used",
-		SortOrder: "desc",
-	})
+func (s *SQLiteStorage) GetByType(ctx context.Context, clipType string, opts storage.SearchOptions) ([]storage.SearchResult, error) {
+	return s.Search(ctx, opts.OfType(clipType))
 }
 
 // loadExternalContent loads content from filesystem for external storage
-func (s *SQLiteStorage) loadExternalContent(model *storage.ClipModel) ([]byte, error) {
+func (s *SQLiteStorage) loadExternalContent(ctx context.Context, model *storage.ClipModel) ([]byte, error) {
+	return loadExternalContent(ctx, s.fsPath, model)
+}
+
+// readExternalFile reads a file from the external storage directory, unless
+// ctx is done
+func (s *SQLiteStorage) readExternalFile(ctx context.Context, filename string) ([]byte, error) {
+	return readExternalFile(ctx, s.fsPath, filename)
+}
+
+// loadExternalContent loads the content of an external clip from fsPath
+func loadExternalContent(ctx context.Context, fsPath string, model *storage.ClipModel) ([]byte, error) {
 	if !model.IsExternal || model.StoragePath == "" {
 		return nil, fmt.Errorf("not an external clip")
 	}
 
-	return s.readExternalFile(model.StoragePath)
+	return readExternalFile(ctx, fsPath, model.StoragePath)
 }
 
-// readExternalFile reads a file from the external storage directory
-func (s *SQLiteStorage) readExternalFile(filename string) ([]byte, error) {
-	path := filepath.Join(s.fsPath, filename)
+// readExternalFile reads a file from fsPath, unless ctx is done
+func readExternalFile(ctx context.Context, fsPath, filename string) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(fsPath, filename)
 	content, err := os.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read file %s: %w", path, err)
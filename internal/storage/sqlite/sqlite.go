@@ -1,27 +1,74 @@
 package sqlite
 
 import (
+	"bytes"
 	"clipboard-manager/internal/storage"
 	"clipboard-manager/pkg/types"
 	"context"
 	"crypto/sha256"
+	"database/sql"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
 	"time"
 
+	"github.com/mattn/go-sqlite3"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
+func init() {
+	storage.Register("sqlite", func(config storage.Config) (storage.Storage, error) {
+		return New(config)
+	})
+
+	// Pragmas are per connection, so the read pool sets them on every
+	// connection it opens
+	sql.Register(readDriver, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			_, err := conn.Exec(`
+				PRAGMA query_only = ON;
+				PRAGMA cache_size = -4000;
+				PRAGMA mmap_size = 268435456;
+				PRAGMA busy_timeout = 5000;
+			`, nil)
+			return err
+		},
+	})
+}
+
+// readDriver is the database/sql driver of the read pool
+const readDriver = "sqlite3-read"
+
+// SQLiteStorage writes through a single connection, SQLite allowing one
+// writer at a time, and reads through a pool of read-only connections. In
+// WAL mode readers see the last committed write and are never blocked by
+// the writer, so searching stays fast while clips are being captured.
+// Queries that only read go through reads; anything that writes, and reads
+// that decide a write, go through db.
 type SQLiteStorage struct {
-	db     *gorm.DB
-	fsPath string // Base path for file system storage
+	db       *gorm.DB
+	sqlDB    *sql.DB  // The connection db uses, for queries written by hand
+	reads    *gorm.DB // Read-only connections
+	readSQL  *sql.DB  // The connections reads uses
+	prepared *gorm.DB // reads, caching prepared statements
+	fsPath   string   // Base path for file system storage
 }
 
+// readConns is the size of the read pool
+var readConns = max(4, runtime.NumCPU())
+
 // New creates a new SQLite storage instance with optimized configuration
 func New(config storage.Config) (*SQLiteStorage, error) {
+	if config.DBPath == "" {
+		config.DBPath = config.DSN
+	}
+
 	// Open database with WAL mode enabled
 	db, err := gorm.Open(sqlite.Open(config.DBPath), &gorm.Config{})
 	if err != nil {
@@ -39,13 +86,12 @@ func New(config storage.Config) (*SQLiteStorage, error) {
 	sqlDB.SetMaxIdleConns(1)
 	sqlDB.SetConnMaxLifetime(time.Hour)
 
-	// Auto-migrate the schema first
-	if err := db.AutoMigrate(&storage.ClipModel{}); err != nil {
-		return nil, fmt.Errorf("failed to migrate schema: %w", err)
-	}
-
 	// Apply performance optimizations
 	if err := db.Exec(`
+		-- Let maintenance return free pages to the file system. This only
+		-- takes effect in a new database; Maintain converts older ones.
+		PRAGMA auto_vacuum = INCREMENTAL;
+
 		-- Enable WAL mode for better concurrency and performance
 		PRAGMA journal_mode = WAL;
 		
@@ -68,12 +114,19 @@ func New(config storage.Config) (*SQLiteStorage, error) {
 		return nil, fmt.Errorf("failed to set PRAGMA options: %w", err)
 	}
 
-	// Create indexes after table creation
-	if err := db.Exec(`
-		CREATE INDEX IF NOT EXISTS idx_clips_content_hash ON clip_models(content_hash);
-		CREATE INDEX IF NOT EXISTS idx_clips_last_used ON clip_models(last_used);
-	`).Error; err != nil {
-		return nil, fmt.Errorf("failed to create indexes: %w", err)
+	// Bring the schema up to date, see migrate.go
+	if !config.NoMigrate {
+		if _, err := migrate(context.Background(), sqlDB); err != nil {
+			return nil, fmt.Errorf("failed to migrate schema: %w", err)
+		}
+	}
+
+	reads, readSQL, err := openReads(config.DBPath)
+	if err != nil {
+		return nil, err
+	}
+	if reads == nil {
+		reads, readSQL = db, sqlDB
 	}
 
 	// Create storage directory if it doesn't exist
@@ -82,11 +135,37 @@ func New(config storage.Config) (*SQLiteStorage, error) {
 	}
 
 	return &SQLiteStorage{
-		db:     db,
-		fsPath: config.FSPath,
+		db:       db,
+		sqlDB:    sqlDB,
+		reads:    reads,
+		readSQL:  readSQL,
+		prepared: reads.Session(&gorm.Session{PrepareStmt: true}),
+		fsPath:   config.FSPath,
 	}, nil
 }
 
+// openReads opens the read pool for the database at dsn. An in-memory
+// database exists only on the writer's connection, so it has no pool and
+// openReads returns nil.
+func openReads(dsn string) (*gorm.DB, *sql.DB, error) {
+	if dsn == ":memory:" || strings.Contains(dsn, "mode=memory") {
+		return nil, nil, nil
+	}
+
+	reads, err := gorm.Open(sqlite.New(sqlite.Config{DriverName: readDriver, DSN: dsn}), &gorm.Config{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open database for reading: %w", err)
+	}
+	readSQL, err := reads.DB()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get underlying *sql.DB: %w", err)
+	}
+	readSQL.SetMaxOpenConns(readConns)
+	readSQL.SetMaxIdleConns(readConns)
+	readSQL.SetConnMaxLifetime(time.Hour)
+	return reads, readSQL, nil
+}
+
 // calculateHash generates SHA-256 hash of content
 func calculateHash(content []byte) string {
 	hash := sha256.Sum256(content)
@@ -105,7 +184,12 @@ func (s *SQLiteStorage) Close() error {
 		return fmt.Errorf("failed to checkpoint WAL: %w", err)
 	}
 
-	// Close database connection
+	// Close database connections
+	if s.readSQL != sqlDB {
+		if err := s.readSQL.Close(); err != nil {
+			return fmt.Errorf("failed to close database: %w", err)
+		}
+	}
 	if err := sqlDB.Close(); err != nil {
 		return fmt.Errorf("failed to close database: %w", err)
 	}
@@ -120,20 +204,50 @@ func (s *SQLiteStorage) Store(ctx context.Context, content []byte, clipType stri
 		return nil, storage.ErrFileTooLarge
 	}
 
-	// Calculate content hash
-	contentHash := calculateHash(content)
+	return s.storeContent(ctx, &storage.SpooledContent{
+		Hash: calculateHash(content),
+		Size: size,
+		Data: content,
+	}, clipType, metadata)
+}
+
+// StoreStream implements storage.Storage interface
+func (s *SQLiteStorage) StoreStream(ctx context.Context, r io.Reader, clipType string, metadata types.Metadata) (*types.Clip, error) {
+	spooled, err := storage.Spool(r, s.fsPath)
+	if err != nil {
+		return nil, err
+	}
+	defer spooled.Discard()
+
+	return s.storeContent(ctx, spooled, clipType, metadata)
+}
+
+// storeContent saves content that has already been hashed and size checked
+func (s *SQLiteStorage) storeContent(ctx context.Context, content *storage.SpooledContent, clipType string, metadata types.Metadata) (*types.Clip, error) {
+	clipType = types.NormalizeType(clipType)
+	size := content.Size
+	contentHash := content.Hash
+
+	// Cache source app details
+	if err := s.saveApp(ctx, metadata); err != nil {
+		return nil, err
+	}
 
 	// Check for existing content with same hash
+	// Content hashes are unique, so copying content that is in the trash restores it
 	var existing storage.ClipModel
-	if err := s.db.Where("content_hash = ?", contentHash).First(&existing).Error; err == nil {
-		// Content exists, update LastUsed timestamp
-		existing.LastUsed = time.Now()
-		if err := s.db.Save(&existing).Error; err != nil {
-			return nil, fmt.Errorf("failed to update existing clip: %w", err)
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("content_hash = ?", contentHash).First(&existing).Error; err != nil {
+			return err
 		}
+		// Only the columns a copy changes are written, so a file copy, pin or
+		// edit made since the clip was read isn't undone
+		return tx.Unscoped().Model(&existing).Select(existing.Recopied(metadata, content.Data)).Updates(&existing).Error
+	})
+	if err == nil {
 		return existing.ToClip(), nil
 	} else if err != gorm.ErrRecordNotFound {
-		return nil, fmt.Errorf("failed to check for existing content: %w", err)
+		return nil, fmt.Errorf("failed to update existing clip: %w", err)
 	}
 
 	// Create new clip model
@@ -142,29 +256,56 @@ func (s *SQLiteStorage) Store(ctx context.Context, content []byte, clipType stri
 		Type:       clipType,
 		Size:       size,
 		SourceApp:  metadata.SourceApp,
+		SourceBundleID: metadata.SourceBundleID,
+		SourceURL:  metadata.SourceURL,
+		SourceTitle: metadata.SourceTitle,
+		SourceWindow: metadata.SourceWindow,
+		Device:      metadata.Device,
 		Category:   metadata.Category,
 		Tags:       metadata.Tags,
+		Formats:    metadata.Formats,
+		PlainText:  string(metadata.Formats[storage.FormatPlainText]),
+		ExpiresAt:  metadata.ExpiresAt,
+		Screenshot: metadata.Screenshot,
+		Link:       metadata.Link,
+		Language:   metadata.Language,
+		Media:      metadata.Media,
+		MediaType:  storage.MediaType(metadata.Media),
+		File:       metadata.File,
 		LastUsed:   time.Now(),
-	}
-
-	if size > storage.MaxInlineStorageSize {
-		// Store in filesystem
-		filename := contentHash
-		path := filepath.Join(s.fsPath, filename)
-
-		if err := os.WriteFile(path, content, 0644); err != nil {
-			return nil, fmt.Errorf("failed to write file: %w", err)
+		UseCount:   1,
+		NormalizedHash: storage.NormalizedHash(content.Data, clipType),
+		Session: metadata.Session,
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if storage.StoredExternally(clipType, size) {
+			// Large text keeps its head in the database for searches and lists
+			if storage.IsText(clipType) {
+				head, err := content.Head(storage.TextHeadSize)
+				if err != nil {
+					return err
+				}
+				model.Content = head
+			}
+			// Store in filesystem, sharing the file with any clip that has the same content
+			if err := s.acquireBlob(tx, content); err != nil {
+				return err
+			}
+			model.StoragePath = contentHash
+			model.IsExternal = true
+		} else {
+			// Store in database
+			model.Content = content.Data
 		}
 
-		model.StoragePath = filename
-		model.IsExternal = true
-	} else {
-		// Store in database
-		model.Content = content
-	}
-
-	if err := s.db.Create(model).Error; err != nil {
-		return nil, fmt.Errorf("failed to create clip: %w", err)
+		if err := tx.Create(model).Error; err != nil {
+			return fmt.Errorf("failed to create clip: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return model.ToClip(), nil
@@ -173,45 +314,65 @@ func (s *SQLiteStorage) Store(ctx context.Context, content []byte, clipType stri
 // Get implements storage.Storage interface
 func (s *SQLiteStorage) Get(ctx context.Context, id string) (*types.Clip, error) {
 	var model storage.ClipModel
-	if err := s.db.First(&model, id).Error; err != nil {
-		return nil, fmt.Errorf("failed to get clip: %w", err)
+	if err := s.reads.WithContext(ctx).First(&model, id).Error; err != nil {
+		return nil, fmt.Errorf("failed to get clip: %w", notFound(err))
 	}
 
 	// Load external content if needed
 	if model.IsExternal {
-		path := filepath.Join(s.fsPath, model.StoragePath)
-		content, err := os.ReadFile(path)
+		content, err := s.readExternalFile(ctx, model.StoragePath)
 		if err != nil {
 			return nil, fmt.Errorf("failed to read external content: %w", err)
 		}
 		model.Content = content
 	}
 
-	// Update LastUsed timestamp
+	// Update LastUsed timestamp, and nothing else: saving the whole model
+	// would undo changes made since it was read, such as a file copy
 	model.LastUsed = time.Now()
-	if err := s.db.Save(&model).Error; err != nil {
+	if err := s.db.WithContext(ctx).Model(&model).Update("last_used", model.LastUsed).Error; err != nil {
 		return nil, fmt.Errorf("failed to update last used time: %w", err)
 	}
 
 	return model.ToClip(), nil
 }
 
-// Delete implements storage.Storage interface
-func (s *SQLiteStorage) Delete(ctx context.Context, id string) error {
+// GetStream implements storage.Storage interface
+func (s *SQLiteStorage) GetStream(ctx context.Context, id string) (io.ReadCloser, *types.Clip, error) {
 	var model storage.ClipModel
-	if err := s.db.First(&model, id).Error; err != nil {
-		return fmt.Errorf("failed to get clip: %w", err)
+	if err := s.reads.WithContext(ctx).First(&model, id).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to get clip: %w", notFound(err))
 	}
 
-	// Delete external file if exists
-	if model.IsExternal {
-		path := filepath.Join(s.fsPath, model.StoragePath)
-		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
-			return fmt.Errorf("failed to delete external file: %w", err)
-		}
+	// Update LastUsed timestamp, and nothing else: saving the whole model
+	// would undo changes made since it was read, such as a file copy
+	model.LastUsed = time.Now()
+	if err := s.db.WithContext(ctx).Model(&model).Update("last_used", model.LastUsed).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to update last used time: %w", err)
 	}
 
-	if err := s.db.Delete(&model).Error; err != nil {
+	if !model.IsExternal {
+		content := model.Content
+		model.Content = nil
+		return io.NopCloser(bytes.NewReader(content)), model.ToClip(), nil
+	}
+
+	file, err := os.Open(filepath.Join(s.fsPath, model.StoragePath))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open external content: %w", err)
+	}
+	return file, model.ToClip(), nil
+}
+
+// Delete implements storage.Storage interface
+func (s *SQLiteStorage) Delete(ctx context.Context, id string) error {
+	var model storage.ClipModel
+	if err := s.db.WithContext(ctx).First(&model, id).Error; err != nil {
+		return fmt.Errorf("failed to get clip: %w", notFound(err))
+	}
+
+	// Soft delete moves the clip to the trash; its content is kept until purged
+	if err := s.db.WithContext(ctx).Delete(&model).Error; err != nil {
 		return fmt.Errorf("failed to delete clip: %w", err)
 	}
 
@@ -220,10 +381,10 @@ func (s *SQLiteStorage) Delete(ctx context.Context, id string) error {
 
 // List implements storage.Storage interface
 func (s *SQLiteStorage) List(ctx context.Context, filter storage.ListFilter) ([]*types.Clip, error) {
-	query := s.db.Model(&storage.ClipModel{})
+	query := s.reads.WithContext(ctx).Model(&storage.ClipModel{})
 
 	if filter.Type != "" {
-		query = query.Where("type = ?", filter.Type)
+		query = query.Where("type = ?", types.NormalizeType(filter.Type))
 	}
 	if filter.Category != "" {
 		query = query.Where("category = ?", filter.Category)
@@ -250,10 +411,9 @@ func (s *SQLiteStorage) List(ctx context.Context, filter storage.ListFilter) ([]
 
 	clips := make([]*types.Clip, len(models))
 	for i, model := range models {
-		// Load external content if needed
-		if model.IsExternal {
-			path := filepath.Join(s.fsPath, model.StoragePath)
-			content, err := os.ReadFile(path)
+		// Load external content if needed; large text only shows its head
+		if model.IsExternal && !model.HasHead() {
+			content, err := s.readExternalFile(ctx, model.StoragePath)
 			if err != nil {
 				return nil, fmt.Errorf("failed to read external content for clip %d: %w", model.ID, err)
 			}
@@ -267,7 +427,7 @@ func (s *SQLiteStorage) List(ctx context.Context, filter storage.ListFilter) ([]
 
 // MarkAsSynced implements storage.Storage interface
 func (s *SQLiteStorage) MarkAsSynced(ctx context.Context, id string) error {
-	result := s.db.Model(&storage.ClipModel{}).
+	result := s.db.WithContext(ctx).Model(&storage.ClipModel{}).
 		Where("id = ?", id).
 		Update("synced_to_obsidian", true)
 	
@@ -284,10 +444,16 @@ func (s *SQLiteStorage) MarkAsSynced(ctx context.Context, id string) error {
 
 // ListUnsynced implements storage.Storage interface
 func (s *SQLiteStorage) ListUnsynced(ctx context.Context, limit int) ([]*types.Clip, error) {
+	return s.findUnsynced(ctx, s.reads.WithContext(ctx).Model(&storage.ClipModel{}), limit)
+}
+
+// findUnsynced returns the clips query finds that are yet to be synced
+func (s *SQLiteStorage) findUnsynced(ctx context.Context, query *gorm.DB, limit int) ([]*types.Clip, error) {
 	var models []storage.ClipModel
 	
-	query := s.db.Model(&storage.ClipModel{}).
+	query = query.
 		Where("synced_to_obsidian = ?", false).
+		Where("expires_at IS NULL"). // Sensitive clips never leave the machine
 		Order("created_at DESC")
 	
 	if limit > 0 {
@@ -300,10 +466,9 @@ func (s *SQLiteStorage) ListUnsynced(ctx context.Context, limit int) ([]*types.C
 
 	clips := make([]*types.Clip, len(models))
 	for i, model := range models {
-		// Load external content if needed
-		if model.IsExternal {
-			path := filepath.Join(s.fsPath, model.StoragePath)
-			content, err := os.ReadFile(path)
+		// Load external content if needed; large text only shows its head
+		if model.IsExternal && !model.HasHead() {
+			content, err := s.readExternalFile(ctx, model.StoragePath)
 			if err != nil {
 				return nil, fmt.Errorf("failed to read external content for clip %d: %w", model.ID, err)
 			}
@@ -314,3 +479,11 @@ func (s *SQLiteStorage) ListUnsynced(ctx context.Context, limit int) ([]*types.C
 
 	return clips, nil
 }
+
+// notFound reports a missing row as storage.ErrNotFound
+func notFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return storage.ErrNotFound
+	}
+	return err
+}
@@ -7,6 +7,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"sort"
 	"testing"
 	"time"
 
@@ -187,3 +188,238 @@ func BenchmarkBulkStore(b *testing.B) {
 		}
 	}
 }
+
+// benchmarkDrivers runs bench against the GORM and direct drivers, each with
+// a database of its own
+func benchmarkDrivers(b *testing.B, bench func(b *testing.B, store storage.Storage)) {
+	for _, name := range []string{"gorm", "direct"} {
+		b.Run(name, func(b *testing.B) {
+			s, cleanup := setupBenchmarkDB(b)
+			defer cleanup()
+
+			store := storage.Storage(s)
+			if name == "direct" {
+				store = &DirectStorage{SQLiteStorage: s}
+			}
+			bench(b, store)
+		})
+	}
+}
+
+// storeBenchmarkClips stores n distinct clips
+func storeBenchmarkClips(b *testing.B, store storage.Storage, n int) []*types.Clip {
+	ctx := context.Background()
+	data := generateTestData(1024)
+	var clips []*types.Clip
+	for i := 0; i < n; i++ {
+		metadata := types.Metadata{
+			SourceApp: fmt.Sprintf("benchmark-%d", i%5),
+			Category:  "test",
+			Tags:      []string{"benchmark", "test"},
+		}
+		clip, err := store.Store(ctx, append([]byte(fmt.Sprintf("clip %d ", i)), data...), "text/plain", metadata)
+		if err != nil {
+			b.Fatal(err)
+		}
+		clips = append(clips, clip)
+	}
+	return clips
+}
+
+func BenchmarkDriversStore(b *testing.B) {
+	benchmarkDrivers(b, func(b *testing.B, store storage.Storage) {
+		ctx := context.Background()
+		data := generateTestData(1024)
+		metadata := types.Metadata{SourceApp: "benchmark", Category: "test", Tags: []string{"benchmark", "test"}}
+
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, err := store.Store(ctx, append([]byte(fmt.Sprint(i)), data...), "text/plain", metadata); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkDriversGet(b *testing.B) {
+	benchmarkDrivers(b, func(b *testing.B, store storage.Storage) {
+		ctx := context.Background()
+		clips := storeBenchmarkClips(b, store, 100)
+
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, err := store.Get(ctx, clips[i%len(clips)].ID); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkDriversList(b *testing.B) {
+	benchmarkDrivers(b, func(b *testing.B, store storage.Storage) {
+		ctx := context.Background()
+		storeBenchmarkClips(b, store, 1000)
+
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, err := store.List(ctx, storage.ListFilter{Limit: 50}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkDriversSearch(b *testing.B) {
+	benchmarkDrivers(b, func(b *testing.B, store storage.Storage) {
+		ctx := context.Background()
+		storeBenchmarkClips(b, store, 1000)
+		search := store.(storage.SearchService)
+
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, err := search.Search(ctx, storage.SearchOptions{Query: "clip app:benchmark-3", Limit: 20}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// BenchmarkSearchWhileCapturing measures search latency while the writer is
+// busy, with reads sharing the writer's connection as they used to and with
+// the read pool. The writer imports batches of clips in transactions, as
+// merging a profile or a bulk import does, and rolls them back so the table
+// searched is the same size in both cases.
+func BenchmarkSearchWhileCapturing(b *testing.B) {
+	for _, name := range []string{"shared", "pool"} {
+		b.Run(name, func(b *testing.B) {
+			s, cleanup := setupBenchmarkDB(b)
+			defer cleanup()
+			if name == "shared" {
+				s.reads, s.readSQL = s.db, s.sqlDB
+			}
+			storeBenchmarkClips(b, s, 1000)
+
+			ctx, stop := context.WithCancel(context.Background())
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				data := generateTestData(1024)
+				for ctx.Err() == nil {
+					tx := s.db.Begin()
+					for i := 0; i < 200; i++ {
+						content := append([]byte(fmt.Sprintf("capture %d ", i)), data...)
+						tx.Create(&storage.ClipModel{ContentHash: calculateHash(content), Content: content, Type: "text/plain"})
+					}
+					tx.Rollback()
+					time.Sleep(10 * time.Millisecond)
+				}
+			}()
+			defer func() {
+				stop()
+				<-done
+			}()
+
+			// A search that waits for the writer shows in the tail
+			latencies := make([]time.Duration, b.N)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				start := time.Now()
+				if _, err := s.Search(ctx, storage.SearchOptions{Query: "clip app:benchmark-3", Limit: 20}); err != nil {
+					b.Fatal(err)
+				}
+				latencies[i] = time.Since(start)
+			}
+			b.StopTimer()
+			sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+			b.ReportMetric(float64(latencies[b.N*99/100].Microseconds()), "p99-us")
+			b.ReportMetric(float64(latencies[b.N-1].Microseconds()), "max-us")
+		})
+	}
+}
+
+// seedClips inserts n clips of assorted types and apps, used over the last
+// n minutes, without going through Store so large databases build quickly
+func seedClips(b *testing.B, s *SQLiteStorage, n int) {
+	types := []string{"text/plain", "text/html", "screenshot", "file"}
+	tx, err := s.sqlDB.Begin()
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer tx.Rollback()
+	insert, err := tx.Prepare("INSERT INTO clip_models (created_at, updated_at, content_hash, content, size, type, " +
+		"source_app, source_bundle_id, tags, last_used, use_count, uuid) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer insert.Close()
+
+	start := time.Now().Add(-time.Duration(n) * time.Minute)
+	for i := 0; i < n; i++ {
+		at := start.Add(time.Duration(i) * time.Minute)
+		content := []byte(fmt.Sprintf("clip %d lorem ipsum dolor sit amet %x", i, generateTestData(32)))
+		app := i % 20
+		tags := `["benchmark"]`
+		if i%10 == 0 {
+			tags = `["benchmark","work"]`
+		}
+		if _, err := insert.Exec(at, at, calculateHash(content), content, len(content), types[i%len(types)],
+			fmt.Sprintf("App %d", app), fmt.Sprintf("com.example.app%d", app), tags, at, 1+i%7, storage.NewUUID()); err != nil {
+			b.Fatal(err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		b.Fatal(err)
+	}
+}
+
+// BenchmarkSearch100k runs the common searches against a database of 100,000
+// clips with both drivers, to catch a query that stops using its index
+func BenchmarkSearch100k(b *testing.B) {
+	if testing.Short() {
+		b.Skip("builds a database of 100,000 clips")
+	}
+	s, cleanup := setupBenchmarkDB(b)
+	defer cleanup()
+	seedClips(b, s, 100000)
+
+	// A cursor halfway down the history
+	middle, err := s.Search(context.Background(), storage.SearchOptions{Limit: 50, Offset: 50000})
+	if err != nil || len(middle) == 0 {
+		b.Fatalf("failed to find the middle of the history: %v", err)
+	}
+	cursor := storage.NextCursor(middle[len(middle)-1:], 1)
+
+	searches := []struct {
+		name string
+		opts storage.SearchOptions
+	}{
+		{"recent", storage.SearchOptions{Limit: 50}},
+		{"type", storage.SearchOptions{Type: "screenshot", Limit: 50}},
+		{"app", storage.SearchOptions{SourceApp: "App 7", Limit: 50}},
+		{"bundle", storage.SearchOptions{SourceBundleID: "com.example.app7", Limit: 50}},
+		{"cursor", storage.SearchOptions{Limit: 50, Cursor: cursor}},
+		{"type-cursor", storage.SearchOptions{Type: "screenshot", Limit: 50, Cursor: cursor}},
+		{"most-used", storage.SearchOptions{SortBy: "use_count", SortOrder: "desc", Limit: 50}},
+		{"text", storage.SearchOptions{Query: "lorem 4242", Limit: 50}},
+		{"tag", storage.SearchOptions{Query: "tag:work", Limit: 50}},
+	}
+	drivers := []struct {
+		name   string
+		search storage.SearchService
+	}{
+		{"gorm", s},
+		{"direct", &DirectStorage{SQLiteStorage: s, statements: newStatements(s.readSQL)}},
+	}
+	for _, driver := range drivers {
+		for _, search := range searches {
+			b.Run(driver.name+"/"+search.name, func(b *testing.B) {
+				ctx := context.Background()
+				for i := 0; i < b.N; i++ {
+					if _, err := driver.search.Search(ctx, search.opts); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
@@ -1,13 +1,22 @@
 package sqlite
 
 import (
+	"bytes"
 	"clipboard-manager/internal/storage"
 	"clipboard-manager/pkg/types"
 	"context"
+	"errors"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sort"
 	"testing"
 	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
 )
 
 func setupTestDB(t *testing.T) (*SQLiteStorage, func()) {
@@ -131,6 +140,33 @@ func TestStore_Deduplication(t *testing.T) {
 	if !model.LastUsed.After(clip1.CreatedAt) {
 		t.Error("LastUsed timestamp was not updated")
 	}
+
+	// A later copy adds what it knows without writing back the rest
+	if err := store.db.Model(&model).Update("pinned", true).Error; err != nil {
+		t.Fatalf("failed to pin clip: %v", err)
+	}
+	rtf := []byte(`{\rtf1 duplicate content}`)
+	ref := &types.FileRef{Path: "/tmp/duplicate.txt", Size: int64(len(content)), Hash: calculateHash(content)}
+	if _, err := store.Store(ctx, content, storage.TypeText, types.Metadata{
+		Formats: map[string][]byte{storage.FormatRTF: rtf},
+		File:    ref,
+		Session: "s1",
+	}); err != nil {
+		t.Fatalf("failed to store third clip: %v", err)
+	}
+	model = storage.ClipModel{}
+	if err := store.db.First(&model, clip1.ID).Error; err != nil {
+		t.Fatalf("failed to get clip model: %v", err)
+	}
+	if model.UseCount != 3 || !model.Pinned || model.Session != "s1" {
+		t.Errorf("expected 3 uses, pinned, session s1, got %d, %v, %q", model.UseCount, model.Pinned, model.Session)
+	}
+	if !bytes.Equal(model.Formats[storage.FormatRTF], rtf) {
+		t.Errorf("rtf = %q, want %q", model.Formats[storage.FormatRTF], rtf)
+	}
+	if model.File == nil || model.File.Path != ref.Path {
+		t.Errorf("expected file %s, got %+v", ref.Path, model.File)
+	}
 }
 
 func TestStore_SizeLimits(t *testing.T) {
@@ -175,3 +211,1364 @@ func TestStore_SizeLimits(t *testing.T) {
 		t.Errorf("content length mismatch: got %d, want %d", len(retrieved.Content), len(mediumContent))
 	}
 }
+
+func TestStore_LargeText(t *testing.T) {
+	store, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	// A log dump, with a multi-byte character across the head's end
+	ctx := context.Background()
+	content := append([]byte("first line "), bytes.Repeat([]byte("é"), storage.MaxInlineTextSize)...)
+	content = append(content, " last line"...)
+	clip, err := store.StoreStream(ctx, bytes.NewReader(content), "text/plain", types.Metadata{})
+	if err != nil {
+		t.Fatalf("failed to store clip: %v", err)
+	}
+
+	var model storage.ClipModel
+	if err := store.db.First(&model, clip.ID).Error; err != nil {
+		t.Fatalf("failed to get clip model: %v", err)
+	}
+	if !model.IsExternal || len(model.Content) != storage.TextHeadSize-1 || !utf8.Valid(model.Content) {
+		t.Fatalf("expected a file and a %d byte head, got external %v and %d bytes", storage.TextHeadSize-1, model.IsExternal, len(model.Content))
+	}
+
+	// Lists and searches show the head, matching only what it holds
+	clips, err := store.List(ctx, storage.ListFilter{Limit: 10})
+	if err != nil || len(clips) != 1 {
+		t.Fatalf("expected one clip, got %d, %v", len(clips), err)
+	}
+	if !clips[0].Truncated || !bytes.Equal(clips[0].Content, model.Content) {
+		t.Errorf("expected the truncated head in the list, got %d bytes, truncated %v", len(clips[0].Content), clips[0].Truncated)
+	}
+	for query, want := range map[string]int{"first line": 1, "last line": 0} {
+		results, err := store.Search(ctx, storage.SearchOptions{Query: query})
+		if err != nil {
+			t.Fatalf("failed to search: %v", err)
+		}
+		if len(results) != want {
+			t.Errorf("Search(%q) found %d clips, want %d", query, len(results), want)
+		} else if want > 0 && !results[0].Clip.Truncated {
+			t.Errorf("Search(%q) returned the whole clip", query)
+		}
+	}
+
+	// Getting the clip returns all of it
+	got, err := store.Get(ctx, clip.ID)
+	if err != nil {
+		t.Fatalf("failed to get clip: %v", err)
+	}
+	if got.Truncated || !bytes.Equal(got.Content, content) {
+		t.Errorf("expected the whole clip, got %d bytes, truncated %v", len(got.Content), got.Truncated)
+	}
+	reader, _, err := store.GetStream(ctx, clip.ID)
+	if err != nil {
+		t.Fatalf("failed to stream clip: %v", err)
+	}
+	streamed, _ := io.ReadAll(reader)
+	reader.Close()
+	if !bytes.Equal(streamed, content) {
+		t.Errorf("expected the whole clip streamed, got %d bytes", len(streamed))
+	}
+}
+
+func TestStore_Formats(t *testing.T) {
+	store, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	rtf := []byte(`{\rtf1\ansi {\b bold} text}`)
+	metadata := types.Metadata{
+		SourceApp: "test",
+		Formats:   map[string][]byte{storage.FormatRTF: rtf},
+	}
+
+	clip, err := store.Store(ctx, []byte("bold text"), "text/plain", metadata)
+	if err != nil {
+		t.Fatalf("failed to store clip: %v", err)
+	}
+	if _, err := store.Store(ctx, []byte("plain only"), "text/plain", types.Metadata{}); err != nil {
+		t.Fatalf("failed to store clip: %v", err)
+	}
+
+	// Verify both representations round-trip
+	retrieved, err := store.Get(ctx, clip.ID)
+	if err != nil {
+		t.Fatalf("failed to get clip: %v", err)
+	}
+	if string(retrieved.Metadata.Formats[storage.FormatRTF]) != string(rtf) {
+		t.Errorf("rtf mismatch: got %q, want %q", retrieved.Metadata.Formats[storage.FormatRTF], rtf)
+	}
+
+	// Verify format filter only matches the rich text clip
+	results, err := store.Search(ctx, storage.SearchOptions{Format: storage.FormatRTF})
+	if err != nil {
+		t.Fatalf("failed to search: %v", err)
+	}
+	if len(results) != 1 || results[0].Clip.ID != clip.ID {
+		t.Errorf("expected only clip %s for rtf filter, got %d results", clip.ID, len(results))
+	}
+}
+
+func TestStore_Types(t *testing.T) {
+	store, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	// Other names for a type are stored, and filtered by, as the type
+	ctx := context.Background()
+	clip, err := store.Store(ctx, []byte("old style"), "text", types.Metadata{})
+	if err != nil {
+		t.Fatalf("failed to store clip: %v", err)
+	}
+	if clip.Type != types.TypeText {
+		t.Errorf("type = %q, want %q", clip.Type, types.TypeText)
+	}
+	if _, err := store.Store(ctx, []byte("jpeg"), "image/jpg", types.Metadata{}); err != nil {
+		t.Fatalf("failed to store clip: %v", err)
+	}
+
+	for _, filter := range []string{"text", "public.utf8-plain-text", types.TypeText} {
+		clips, err := store.List(ctx, storage.ListFilter{Type: filter})
+		if err != nil {
+			t.Fatalf("failed to list clips: %v", err)
+		}
+		if len(clips) != 1 || clips[0].ID != clip.ID {
+			t.Errorf("type %q: expected clip %s, got %d clips", filter, clip.ID, len(clips))
+		}
+	}
+	results, err := store.GetByType(ctx, types.TypeJPEG, storage.SearchOptions{})
+	if err != nil {
+		t.Fatalf("failed to search: %v", err)
+	}
+	if len(results) != 1 {
+		t.Errorf("expected 1 jpeg, got %d", len(results))
+	}
+}
+
+func TestStore_Apps(t *testing.T) {
+	store, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	icon := []byte{0x89, 'P', 'N', 'G'}
+	safari := types.Metadata{SourceApp: "Safari", SourceBundleID: "com.apple.Safari", SourceIcon: icon}
+	notes := types.Metadata{SourceApp: "Notes", SourceBundleID: "com.apple.Notes"}
+
+	for _, c := range []struct {
+		content  string
+		metadata types.Metadata
+	}{
+		{"one", safari},
+		{"two", safari},
+		{"three", notes},
+	} {
+		if _, err := store.Store(ctx, []byte(c.content), storage.TypeText, c.metadata); err != nil {
+			t.Fatalf("failed to store clip: %v", err)
+		}
+	}
+
+	apps, err := store.ListApps(ctx)
+	if err != nil {
+		t.Fatalf("failed to list apps: %v", err)
+	}
+	if len(apps) != 2 {
+		t.Fatalf("expected 2 apps, got %d", len(apps))
+	}
+	if apps[0].BundleID != "com.apple.Safari" || apps[0].Count != 2 || !apps[0].HasIcon {
+		t.Errorf("unexpected first app: %+v", apps[0])
+	}
+	if apps[1].Name != "Notes" || apps[1].HasIcon {
+		t.Errorf("unexpected second app: %+v", apps[1])
+	}
+
+	got, err := store.GetAppIcon(ctx, "com.apple.Safari")
+	if err != nil {
+		t.Fatalf("failed to get icon: %v", err)
+	}
+	if string(got) != string(icon) {
+		t.Errorf("icon mismatch: got %v, want %v", got, icon)
+	}
+	if _, err := store.GetAppIcon(ctx, "com.apple.Notes"); err == nil {
+		t.Error("expected error for app without icon")
+	}
+}
+
+func TestSearch_SourceURL(t *testing.T) {
+	store, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	clip, err := store.Store(ctx, []byte("quoted text"), storage.TypeText, types.Metadata{
+		SourceURL:   "https://Example.com/docs/page",
+		SourceTitle: "Docs",
+	})
+	if err != nil {
+		t.Fatalf("failed to store clip: %v", err)
+	}
+	if _, err := store.Store(ctx, []byte("other text"), storage.TypeText, types.Metadata{}); err != nil {
+		t.Fatalf("failed to store clip: %v", err)
+	}
+
+	results, err := store.Search(ctx, storage.SearchOptions{SourceURL: "example.com/docs"})
+	if err != nil {
+		t.Fatalf("failed to search: %v", err)
+	}
+	if len(results) != 1 || results[0].Clip.ID != clip.ID {
+		t.Fatalf("expected only clip %s, got %d results", clip.ID, len(results))
+	}
+	if results[0].Clip.Metadata.SourceTitle != "Docs" {
+		t.Errorf("source title not stored: %q", results[0].Clip.Metadata.SourceTitle)
+	}
+}
+
+func TestStore_Stream(t *testing.T) {
+	store, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	content := bytes.Repeat([]byte("x"), storage.MaxInlineStorageSize+1)
+
+	clip, err := store.StoreStream(ctx, bytes.NewReader(content), storage.TypeFile, types.Metadata{})
+	if err != nil {
+		t.Fatalf("failed to store stream: %v", err)
+	}
+
+	// Spooled content should end up in external storage with no temp files left
+	entries, err := os.ReadDir(store.fsPath)
+	if err != nil {
+		t.Fatalf("failed to read storage dir: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Name() != calculateHash(content) {
+		t.Errorf("expected a single file named by content hash, got %d entries", len(entries))
+	}
+
+	reader, streamed, err := store.GetStream(ctx, clip.ID)
+	if err != nil {
+		t.Fatalf("failed to get stream: %v", err)
+	}
+	defer reader.Close()
+
+	got, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("failed to read stream: %v", err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Errorf("streamed content mismatch: got %d bytes, want %d", len(got), len(content))
+	}
+	if streamed.Content != nil {
+		t.Error("streamed clip should not carry content")
+	}
+
+	// Streaming identical content deduplicates like Store
+	again, err := store.StoreStream(ctx, bytes.NewReader(content), storage.TypeFile, types.Metadata{})
+	if err != nil {
+		t.Fatalf("failed to store stream: %v", err)
+	}
+	if again.ID != clip.ID {
+		t.Errorf("deduplication failed: got %s, want %s", again.ID, clip.ID)
+	}
+}
+
+func TestGC(t *testing.T) {
+	store, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	old := time.Now().Add(-2 * storage.GCGracePeriod)
+
+	kept, err := store.Store(ctx, bytes.Repeat([]byte("a"), storage.MaxInlineStorageSize+1), storage.TypeFile, types.Metadata{})
+	if err != nil {
+		t.Fatalf("failed to store clip: %v", err)
+	}
+	danglingContent := bytes.Repeat([]byte("b"), storage.MaxInlineStorageSize+1)
+	dangling, err := store.Store(ctx, danglingContent, storage.TypeFile, types.Metadata{})
+	if err != nil {
+		t.Fatalf("failed to store clip: %v", err)
+	}
+
+	// Simulate a failed delete and a crash between file write and insert
+	if err := os.Remove(filepath.Join(store.fsPath, calculateHash(danglingContent))); err != nil {
+		t.Fatalf("failed to remove file: %v", err)
+	}
+	orphan := filepath.Join(store.fsPath, "orphan")
+	recent := filepath.Join(store.fsPath, "recent")
+	for _, path := range []string{orphan, recent} {
+		if err := os.WriteFile(path, []byte("orphan"), 0644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+	if err := os.Chtimes(orphan, old, old); err != nil {
+		t.Fatalf("failed to age file: %v", err)
+	}
+
+	report, err := store.GC(ctx, true)
+	if err != nil {
+		t.Fatalf("dry run failed: %v", err)
+	}
+	if len(report.OrphanedFiles) != 1 || len(report.DanglingClips) != 1 {
+		t.Errorf("dry run found %d orphans and %d dangling clips, want 1 and 1", len(report.OrphanedFiles), len(report.DanglingClips))
+	}
+	if _, err := os.Stat(orphan); err != nil {
+		t.Error("dry run removed orphaned file")
+	}
+
+	if _, err := store.GC(ctx, false); err != nil {
+		t.Fatalf("gc failed: %v", err)
+	}
+	if _, err := os.Stat(orphan); !os.IsNotExist(err) {
+		t.Error("orphaned file was not removed")
+	}
+	if _, err := os.Stat(recent); err != nil {
+		t.Error("file within the grace period was removed")
+	}
+	if _, err := store.Get(ctx, dangling.ID); err == nil {
+		t.Error("dangling clip was not removed")
+	}
+	if _, err := store.Get(ctx, kept.ID); err != nil {
+		t.Errorf("referenced clip was removed: %v", err)
+	}
+}
+
+func TestStore_SharedBlobs(t *testing.T) {
+	store, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	content := bytes.Repeat([]byte("s"), storage.MaxInlineStorageSize+1)
+	hash := calculateHash(content)
+
+	clip, err := store.Store(ctx, content, storage.TypeFile, types.Metadata{})
+	if err != nil {
+		t.Fatalf("failed to store clip: %v", err)
+	}
+
+	// A second clip referencing the same file, as written before reference
+	// counting existed, is adopted by GC
+	shared := storage.ClipModel{ContentHash: "other", Type: storage.TypeFile, Size: int64(len(content)), StoragePath: hash, IsExternal: true}
+	if err := store.db.Create(&shared).Error; err != nil {
+		t.Fatalf("failed to create clip: %v", err)
+	}
+	report, err := store.GC(ctx, false)
+	if err != nil {
+		t.Fatalf("gc failed: %v", err)
+	}
+	if report.RepairedBlobs != 1 {
+		t.Errorf("expected 1 repaired blob, got %d", report.RepairedBlobs)
+	}
+
+	// Purging one clip keeps the file for the other
+	if err := store.Delete(ctx, clip.ID); err != nil {
+		t.Fatalf("failed to delete clip: %v", err)
+	}
+	if _, err := store.PurgeTrash(ctx, time.Now().Add(time.Second)); err != nil {
+		t.Fatalf("failed to purge trash: %v", err)
+	}
+	path := filepath.Join(store.fsPath, hash)
+	if _, err := os.Stat(path); err != nil {
+		t.Fatal("shared file was removed while still referenced")
+	}
+
+	if err := store.Delete(ctx, fmt.Sprint(shared.ID)); err != nil {
+		t.Fatalf("failed to delete clip: %v", err)
+	}
+	if _, err := store.PurgeTrash(ctx, time.Now().Add(time.Second)); err != nil {
+		t.Fatalf("failed to purge trash: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("file was not removed after its last reference was deleted")
+	}
+}
+
+func TestReleaseBlob_Rollback(t *testing.T) {
+	store, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	content := bytes.Repeat([]byte("r"), storage.MaxInlineStorageSize+1)
+	if _, err := store.Store(ctx, content, storage.TypeFile, types.Metadata{}); err != nil {
+		t.Fatalf("failed to store clip: %v", err)
+	}
+	hash := calculateHash(content)
+
+	// A transaction that rolls back after releasing the last reference keeps
+	// the file, since the blob and its clip come back
+	errRollback := errors.New("rollback")
+	err := store.db.Transaction(func(tx *gorm.DB) error {
+		if released, err := store.releaseBlob(tx, hash); err != nil || !released {
+			t.Fatalf("releaseBlob() = %v, %v", released, err)
+		}
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		t.Fatalf("expected the transaction to roll back, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(store.fsPath, hash)); err != nil {
+		t.Errorf("file was removed by a transaction that rolled back: %v", err)
+	}
+
+	// Files of blobs taken again before removal are kept
+	store.removeBlobFiles([]string{hash})
+	if _, err := os.Stat(filepath.Join(store.fsPath, hash)); err != nil {
+		t.Errorf("file of a held blob was removed: %v", err)
+	}
+}
+
+func TestTrash(t *testing.T) {
+	store, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	clip, err := store.Store(ctx, []byte("trashed"), storage.TypeText, types.Metadata{})
+	if err != nil {
+		t.Fatalf("failed to store clip: %v", err)
+	}
+
+	if err := store.Delete(ctx, clip.ID); err != nil {
+		t.Fatalf("failed to delete clip: %v", err)
+	}
+	if _, err := store.Get(ctx, clip.ID); err == nil {
+		t.Error("deleted clip is still visible")
+	}
+
+	trash, err := store.ListTrash(ctx, 10, 0)
+	if err != nil {
+		t.Fatalf("failed to list trash: %v", err)
+	}
+	if len(trash) != 1 || trash[0].ID != clip.ID || trash[0].DeletedAt == nil {
+		t.Fatalf("expected deleted clip in trash, got %+v", trash)
+	}
+
+	restored, err := store.Restore(ctx, clip.ID)
+	if err != nil {
+		t.Fatalf("failed to restore clip: %v", err)
+	}
+	if restored.DeletedAt != nil {
+		t.Error("restored clip is still marked deleted")
+	}
+	if _, err := store.Get(ctx, clip.ID); err != nil {
+		t.Errorf("restored clip is not visible: %v", err)
+	}
+
+	// Copying the same content again restores it from the trash
+	if err := store.Delete(ctx, clip.ID); err != nil {
+		t.Fatalf("failed to delete clip: %v", err)
+	}
+	again, err := store.Store(ctx, []byte("trashed"), storage.TypeText, types.Metadata{})
+	if err != nil {
+		t.Fatalf("failed to store clip: %v", err)
+	}
+	if again.ID != clip.ID {
+		t.Errorf("expected clip %s to be restored, got new clip %s", clip.ID, again.ID)
+	}
+
+	// Purging only removes clips deleted before the cutoff
+	if err := store.Delete(ctx, clip.ID); err != nil {
+		t.Fatalf("failed to delete clip: %v", err)
+	}
+	if purged, err := store.PurgeTrash(ctx, time.Now().Add(-time.Hour)); err != nil || purged != 0 {
+		t.Errorf("PurgeTrash() = %d, %v; want 0, nil", purged, err)
+	}
+	if purged, err := store.PurgeTrash(ctx, time.Now().Add(time.Second)); err != nil || purged != 1 {
+		t.Errorf("PurgeTrash() = %d, %v; want 1, nil", purged, err)
+	}
+	if _, err := store.Restore(ctx, clip.ID); err == nil {
+		t.Error("purged clip could still be restored")
+	}
+	if _, err := store.Store(ctx, []byte("trashed"), storage.TypeText, types.Metadata{}); err != nil {
+		t.Errorf("failed to store content again after purging: %v", err)
+	}
+}
+
+func TestChanges(t *testing.T) {
+	store, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	kept, err := store.Store(ctx, []byte("kept"), storage.TypeText, types.Metadata{})
+	if err != nil {
+		t.Fatalf("failed to store clip: %v", err)
+	}
+	deleted, err := store.Store(ctx, []byte("deleted"), storage.TypeText, types.Metadata{})
+	if err != nil {
+		t.Fatalf("failed to store clip: %v", err)
+	}
+	seen, err := store.LatestChange(ctx)
+	if err != nil || seen == 0 {
+		t.Fatalf("LatestChange() = %d, %v; want a change", seen, err)
+	}
+
+	// Syncing to Obsidian doesn't change the clip
+	if err := store.MarkAsSynced(ctx, kept.ID); err != nil {
+		t.Fatalf("failed to mark clip synced: %v", err)
+	}
+	if latest, err := store.LatestChange(ctx); err != nil || latest != seen {
+		t.Errorf("LatestChange() = %d, %v; want %d", latest, err, seen)
+	}
+
+	// Each clip is listed once, with its latest change
+	if _, err := store.Store(ctx, []byte("kept"), storage.TypeText, types.Metadata{}); err != nil {
+		t.Fatalf("failed to store clip: %v", err)
+	}
+	if err := store.Delete(ctx, deleted.ID); err != nil {
+		t.Fatalf("failed to delete clip: %v", err)
+	}
+	added, err := store.Store(ctx, []byte("added"), storage.TypeText, types.Metadata{})
+	if err != nil {
+		t.Fatalf("failed to store clip: %v", err)
+	}
+
+	summary := func(changes []storage.Change) []string {
+		var got []string
+		for _, change := range changes {
+			entry := change.Op + " " + change.ClipID
+			if change.Clip != nil {
+				entry += " " + string(change.Clip.Content)
+			}
+			got = append(got, entry)
+		}
+		return got
+	}
+	want := []string{"update " + kept.ID + " kept", "delete " + deleted.ID, "insert " + added.ID + " added"}
+	changes, err := store.Changes(ctx, seen, 0)
+	if err != nil {
+		t.Fatalf("failed to list changes: %v", err)
+	}
+	if got := summary(changes); !reflect.DeepEqual(got, want) {
+		t.Errorf("Changes() = %v, want %v", got, want)
+	}
+	if changes[0].Seq <= seen || changes[2].Seq <= changes[1].Seq {
+		t.Errorf("expected sequence numbers after %d in order, got %+v", seen, changes)
+	}
+
+	// Pages pick up where the last one ended
+	first, err := store.Changes(ctx, seen, 2)
+	if err != nil || len(first) != 2 {
+		t.Fatalf("Changes() = %v, %v; want 2 changes", first, err)
+	}
+	rest, err := store.Changes(ctx, first[1].Seq, 2)
+	if err != nil {
+		t.Fatalf("failed to list changes: %v", err)
+	}
+	if got := summary(rest); !reflect.DeepEqual(got, want[2:]) {
+		t.Errorf("Changes() after %d = %v, want %v", first[1].Seq, got, want[2:])
+	}
+
+	// Compacting leaves the feed as it was
+	all, err := store.Changes(ctx, 0, 0)
+	if err != nil {
+		t.Fatalf("failed to list changes: %v", err)
+	}
+	if _, err := store.Maintain(ctx, storage.MaintenanceOptions{Full: true}); err != nil {
+		t.Fatalf("failed to maintain database: %v", err)
+	}
+	var rows int64
+	store.db.Model(&storage.ChangeModel{}).Count(&rows)
+	if rows != int64(len(all)) {
+		t.Errorf("expected %d changes left after compacting, got %d", len(all), rows)
+	}
+	if compacted, err := store.Changes(ctx, 0, 0); err != nil || !reflect.DeepEqual(summary(compacted), summary(all)) {
+		t.Errorf("Changes() after compacting = %v, %v; want %v", summary(compacted), err, summary(all))
+	}
+}
+
+func TestSyncState(t *testing.T) {
+	store, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	failing, err := store.Store(ctx, []byte("failing"), storage.TypeText, types.Metadata{})
+	if err != nil {
+		t.Fatalf("failed to store clip: %v", err)
+	}
+	other, err := store.Store(ctx, []byte("other"), storage.TypeText, types.Metadata{})
+	if err != nil {
+		t.Fatalf("failed to store clip: %v", err)
+	}
+	due := func() []string {
+		t.Helper()
+		clips, err := store.ListSyncDue(ctx, 0)
+		if err != nil {
+			t.Fatalf("failed to list clips due: %v", err)
+		}
+		var ids []string
+		for _, clip := range clips {
+			ids = append(ids, clip.ID)
+		}
+		return ids
+	}
+
+	// A failed clip waits to be retried, without holding back the others
+	state, err := store.SyncFailed(ctx, failing.ID, errors.New("disk full"))
+	if err != nil {
+		t.Fatalf("failed to record failure: %v", err)
+	}
+	if state.Attempts != 1 || state.LastError != "disk full" || !state.NextRetry.After(time.Now()) || state.GivenUp {
+		t.Errorf("unexpected state %+v", state)
+	}
+	if got := due(); !reflect.DeepEqual(got, []string{other.ID}) {
+		t.Errorf("ListSyncDue() = %v, want %v", got, []string{other.ID})
+	}
+	store.db.Model(&storage.SyncStateModel{}).Where("1 = 1").Update("next_retry", time.Now().Add(-time.Second))
+	if got := due(); len(got) != 2 {
+		t.Errorf("expected the failed clip to be due once its retry time passed, got %v", got)
+	}
+
+	// It is given up on after too many failures, until failures are reset
+	for i := 1; i < storage.MaxSyncAttempts; i++ {
+		if state, err = store.SyncFailed(ctx, failing.ID, errors.New("disk full")); err != nil {
+			t.Fatalf("failed to record failure: %v", err)
+		}
+	}
+	store.db.Model(&storage.SyncStateModel{}).Where("1 = 1").Update("next_retry", time.Now().Add(-time.Second))
+	if !state.GivenUp || len(due()) != 1 {
+		t.Errorf("expected the clip to be given up on, got %+v", state)
+	}
+	failures, err := store.ListSyncFailures(ctx)
+	if err != nil || len(failures) != 1 || failures[0].ClipID != failing.ID || !failures[0].GivenUp {
+		t.Errorf("ListSyncFailures() = %+v, %v", failures, err)
+	}
+	if reset, err := store.ResetSyncFailures(ctx); err != nil || reset != 1 {
+		t.Errorf("ResetSyncFailures() = %d, %v; want 1, nil", reset, err)
+	}
+	if got := due(); len(got) != 2 {
+		t.Errorf("expected both clips due after a reset, got %v", got)
+	}
+
+	// Syncing the clip forgets its failures
+	if _, err := store.SyncFailed(ctx, failing.ID, errors.New("disk full")); err != nil {
+		t.Fatalf("failed to record failure: %v", err)
+	}
+	if err := store.MarkAsSynced(ctx, failing.ID); err != nil {
+		t.Fatalf("failed to mark clip synced: %v", err)
+	}
+	var states int64
+	store.db.Model(&storage.SyncStateModel{}).Count(&states)
+	if states != 0 {
+		t.Errorf("expected no sync state left, got %d", states)
+	}
+	if _, err := store.SyncFailed(ctx, "999", errors.New("disk full")); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("SyncFailed() of a missing clip = %v, want ErrNotFound", err)
+	}
+}
+
+func TestExpiry(t *testing.T) {
+	store, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	expiresAt := time.Now().Add(time.Minute)
+
+	secret, err := store.Store(ctx, []byte("hunter2"), storage.TypeText, types.Metadata{ExpiresAt: &expiresAt})
+	if err != nil {
+		t.Fatalf("failed to store clip: %v", err)
+	}
+	kept, err := store.Store(ctx, []byte("keep me"), storage.TypeText, types.Metadata{})
+	if err != nil {
+		t.Fatalf("failed to store clip: %v", err)
+	}
+
+	// Expiring clips are never synced
+	unsynced, err := store.ListUnsynced(ctx, 10)
+	if err != nil {
+		t.Fatalf("failed to list unsynced clips: %v", err)
+	}
+	if len(unsynced) != 1 || unsynced[0].ID != kept.ID {
+		t.Errorf("expected only the non-expiring clip to be unsynced, got %d clips", len(unsynced))
+	}
+
+	// Expiry can be set after capture too
+	if _, err := store.SetExpiry(ctx, kept.ID, &expiresAt); err != nil {
+		t.Fatalf("failed to set expiry: %v", err)
+	}
+	if err := store.Delete(ctx, kept.ID); err != nil {
+		t.Fatalf("failed to delete clip: %v", err)
+	}
+
+	if deleted, err := store.DeleteExpired(ctx, time.Now()); err != nil || deleted != 0 {
+		t.Errorf("DeleteExpired() = %d, %v; want 0, nil", deleted, err)
+	}
+	// Expired clips are removed even from the trash
+	if deleted, err := store.DeleteExpired(ctx, expiresAt); err != nil || deleted != 2 {
+		t.Errorf("DeleteExpired() = %d, %v; want 2, nil", deleted, err)
+	}
+	if _, err := store.Get(ctx, secret.ID); err == nil {
+		t.Error("expired clip is still stored")
+	}
+	if trash, _ := store.ListTrash(ctx, 10, 0); len(trash) != 0 {
+		t.Error("expired clip is still in the trash")
+	}
+}
+
+func TestEvict(t *testing.T) {
+	store, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	var ids []string
+	for i, tags := range [][]string{{"pinned"}, nil, nil, nil} {
+		content := bytes.Repeat([]byte{byte('a' + i)}, 1000)
+		clip, err := store.Store(ctx, content, storage.TypeText, types.Metadata{Tags: tags})
+		if err != nil {
+			t.Fatalf("failed to store clip: %v", err)
+		}
+		ids = append(ids, clip.ID)
+	}
+	// The most recent clip goes first from the trash
+	if err := store.Delete(ctx, ids[3]); err != nil {
+		t.Fatalf("failed to delete clip: %v", err)
+	}
+
+	eviction, err := store.Evict(ctx, 2500)
+	if err != nil {
+		t.Fatalf("failed to evict clips: %v", err)
+	}
+	if !reflect.DeepEqual(eviction.IDs, []string{ids[3], ids[1]}) || eviction.FreedBytes != 2000 || eviction.UsedBytes != 2000 {
+		t.Errorf("Evict(2500) = %+v, want clips %s and %s evicted, 2000 bytes freed and left", eviction, ids[3], ids[1])
+	}
+
+	// The pinned clip stays even over the limit
+	eviction, err = store.Evict(ctx, 0)
+	if err != nil {
+		t.Fatalf("failed to evict clips: %v", err)
+	}
+	if !reflect.DeepEqual(eviction.IDs, []string{ids[2]}) || eviction.UsedBytes != 1000 {
+		t.Errorf("Evict(0) = %+v, want clip %s evicted and 1000 bytes left", eviction, ids[2])
+	}
+	if _, err := store.Get(ctx, ids[0]); err != nil {
+		t.Errorf("pinned clip was evicted: %v", err)
+	}
+	if eviction, err := store.Evict(ctx, 0); err != nil || len(eviction.IDs) != 0 {
+		t.Errorf("Evict(0) = %+v, %v; want nothing left to evict", eviction, err)
+	}
+}
+
+func TestFileCopy(t *testing.T) {
+	store, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	content := []byte("the copied file")
+	ref := &types.FileRef{Path: "/tmp/report.txt", Size: int64(len(content)), ModTime: time.Now(), Hash: calculateHash(content)}
+	clip, err := store.Store(ctx, []byte("file:///tmp/report.txt"), storage.TypeFile, types.Metadata{File: ref})
+	if err != nil {
+		t.Fatalf("failed to store clip: %v", err)
+	}
+	if clip.Metadata.File == nil || clip.Metadata.File.Hash != ref.Hash || clip.Metadata.File.Copied {
+		t.Fatalf("expected the file reference without a copy, got %+v", clip.Metadata.File)
+	}
+
+	if _, err := store.CopyFile(ctx, clip.ID, bytes.NewReader([]byte("changed"))); !errors.Is(err, storage.ErrFileChanged) {
+		t.Errorf("expected ErrFileChanged, got %v", err)
+	}
+	if _, err := store.OpenFileCopy(ctx, clip.ID); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("expected no copy yet, got %v", err)
+	}
+
+	copied, err := store.CopyFile(ctx, clip.ID, bytes.NewReader(content))
+	if err != nil {
+		t.Fatalf("failed to copy file: %v", err)
+	}
+	if !copied.Metadata.File.Copied {
+		t.Error("expected the clip to have a copy")
+	}
+	reader, err := store.OpenFileCopy(ctx, clip.ID)
+	if err != nil {
+		t.Fatalf("failed to open copy: %v", err)
+	}
+	got, _ := io.ReadAll(reader)
+	reader.Close()
+	if !bytes.Equal(got, content) {
+		t.Errorf("copy = %q, want %q", got, content)
+	}
+
+	// A lost copy is forgotten rather than the clip removed
+	path := filepath.Join(store.fsPath, ref.Hash)
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("failed to remove copy: %v", err)
+	}
+	report, err := store.GC(ctx, false)
+	if err != nil {
+		t.Fatalf("failed to collect garbage: %v", err)
+	}
+	if !reflect.DeepEqual(report.LostCopies, []string{clip.ID}) || len(report.DanglingClips) != 0 {
+		t.Errorf("expected the copy of clip %s to be lost, got %+v", clip.ID, report)
+	}
+	if got, err := store.Get(ctx, clip.ID); err != nil || got.Metadata.File.Copied {
+		t.Errorf("expected the clip without its copy, got %+v, %v", got, err)
+	}
+
+	// Purging the clip releases its copy
+	if _, err := store.CopyFile(ctx, clip.ID, bytes.NewReader(content)); err != nil {
+		t.Fatalf("failed to copy file: %v", err)
+	}
+	if err := store.Delete(ctx, clip.ID); err != nil {
+		t.Fatalf("failed to delete clip: %v", err)
+	}
+	if _, err := store.PurgeTrash(ctx, time.Now().Add(time.Second)); err != nil {
+		t.Fatalf("failed to purge trash: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected the copy to be removed, got %v", err)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	store, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	keep, err := store.Store(ctx, []byte("https://example.com/page"), storage.TypeText, types.Metadata{})
+	if err != nil {
+		t.Fatalf("failed to store clip: %v", err)
+	}
+	// Copied twice, so it counts two uses
+	for i := 0; i < 2; i++ {
+		if _, err := store.Store(ctx, []byte("https://example.com/page#section"), storage.TypeText, types.Metadata{}); err != nil {
+			t.Fatalf("failed to store clip: %v", err)
+		}
+	}
+	dup, err := store.Store(ctx, []byte("https://example.com/page#section"), storage.TypeText, types.Metadata{})
+	if err != nil {
+		t.Fatalf("failed to store clip: %v", err)
+	}
+
+	grouped := storage.GroupSimilar(mustSearch(t, store, storage.SearchOptions{}))
+	if len(grouped) != 1 || len(grouped[0].Duplicates) != 1 || grouped[0].UseCount != 4 {
+		t.Fatalf("expected one group of 4 uses, got %+v", grouped)
+	}
+
+	merged, err := store.Merge(ctx, keep.ID, []string{keep.ID, dup.ID})
+	if err != nil {
+		t.Fatalf("failed to merge clips: %v", err)
+	}
+	if merged.ID != keep.ID || string(merged.Content) != "https://example.com/page" {
+		t.Errorf("merge kept the wrong clip: %+v", merged)
+	}
+	if _, err := store.Get(ctx, dup.ID); err == nil {
+		t.Error("merged duplicate is still visible")
+	}
+
+	results := mustSearch(t, store, storage.SearchOptions{})
+	if len(results) != 1 || results[0].UseCount != 4 {
+		t.Errorf("expected merged clip with 4 uses, got %+v", results)
+	}
+}
+
+func mustSearch(t *testing.T, store storage.SearchService, opts storage.SearchOptions) []storage.SearchResult {
+	t.Helper()
+	results, err := store.Search(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("failed to search: %v", err)
+	}
+	return results
+}
+
+func TestVersions(t *testing.T) {
+	store, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	clip, err := store.Store(ctx, []byte("teh quick fox"), storage.TypeText, types.Metadata{})
+	if err != nil {
+		t.Fatalf("failed to store clip: %v", err)
+	}
+	other, err := store.Store(ctx, []byte("other"), storage.TypeText, types.Metadata{})
+	if err != nil {
+		t.Fatalf("failed to store clip: %v", err)
+	}
+
+	edited, err := store.Edit(ctx, clip.ID, []byte("the quick fox"), nil)
+	if err != nil {
+		t.Fatalf("failed to edit clip: %v", err)
+	}
+	if edited.ID != clip.ID || string(edited.Content) != "the quick fox" {
+		t.Errorf("unexpected edited clip: %+v", edited)
+	}
+	if _, err := store.Edit(ctx, other.ID, []byte("the quick fox"), nil); !errors.Is(err, storage.ErrDuplicate) {
+		t.Errorf("expected duplicate content error, got %v", err)
+	}
+
+	versions, err := store.ListVersions(ctx, clip.ID)
+	if err != nil {
+		t.Fatalf("failed to list versions: %v", err)
+	}
+	if len(versions) != 1 || versions[0].Version != 1 || string(versions[0].Content) != "teh quick fox" {
+		t.Fatalf("expected the original as version 1, got %+v", versions)
+	}
+
+	reverted, err := store.Revert(ctx, clip.ID, 1)
+	if err != nil {
+		t.Fatalf("failed to revert clip: %v", err)
+	}
+	if string(reverted.Content) != "teh quick fox" {
+		t.Errorf("expected original content, got %q", reverted.Content)
+	}
+	versions, err = store.ListVersions(ctx, clip.ID)
+	if err != nil {
+		t.Fatalf("failed to list versions: %v", err)
+	}
+	if len(versions) != 2 || string(versions[1].Content) != "the quick fox" {
+		t.Errorf("expected the edit as version 2, got %+v", versions)
+	}
+
+	// The edited content is what a new copy deduplicates against
+	again, err := store.Store(ctx, []byte("teh quick fox"), storage.TypeText, types.Metadata{})
+	if err != nil {
+		t.Fatalf("failed to store clip: %v", err)
+	}
+	if again.ID != clip.ID {
+		t.Errorf("expected copy to match reverted clip %s, got %s", clip.ID, again.ID)
+	}
+}
+
+func TestSearch_QueryLanguage(t *testing.T) {
+	store, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	clips := []struct {
+		content  string
+		clipType string
+		metadata types.Metadata
+	}{
+		{"meeting notes for work", "text/plain", types.Metadata{SourceApp: "Google Chrome", Tags: []string{"work"}}},
+		{"grocery list", "text/plain", types.Metadata{SourceApp: "Notes", Tags: []string{"home"}}},
+		{"png bytes", "image/png", types.Metadata{SourceApp: "Google Chrome"}},
+		{"copied on my phone", "text/plain", types.Metadata{SourceApp: "iOS Device", Device: "iOS Device"}},
+	}
+	for _, c := range clips {
+		if _, err := store.Store(ctx, []byte(c.content), c.clipType, c.metadata); err != nil {
+			t.Fatalf("failed to store clip: %v", err)
+		}
+	}
+
+	tomorrow := time.Now().AddDate(0, 0, 1).Format("2006-01-02")
+	tests := map[string][]string{
+		"type:image app:chrome":        {"png bytes"},
+		"tag:work":                     {"meeting notes for work"},
+		"notes work":                   {"meeting notes for work"},
+		`"notes for"`:                  {"meeting notes for work"},
+		"grocery OR tag:work":          {"grocery list", "meeting notes for work"},
+		"app:notes AND list":           {"grocery list"},
+		"before:2000-01-01":            nil,
+		"type:text after:" + tomorrow:  nil,
+		"type:text before:" + tomorrow: {"copied on my phone", "grocery list", "meeting notes for work"},
+		"device:ios":                   {"copied on my phone"},
+	}
+	for query, want := range tests {
+		var got []string
+		for _, result := range mustSearch(t, store, storage.SearchOptions{Query: query}) {
+			got = append(got, string(result.Clip.Content))
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("search %q: got %q, want %q", query, got, want)
+		}
+	}
+
+	if _, err := store.Search(ctx, storage.SearchOptions{Query: "before:soon"}); err == nil {
+		t.Error("expected an error for a malformed date")
+	}
+
+	results := mustSearch(t, store, storage.SearchOptions{Query: "NOTES tag:work"})
+	if len(results) != 1 || !reflect.DeepEqual(results[0].Matches, []string{"notes"}) ||
+		results[0].Snippet != "meeting notes for work" {
+		t.Errorf("expected highlighted match, got %+v", results)
+	}
+}
+
+func TestSearch_Cursor(t *testing.T) {
+	store, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	for i := 0; i < 5; i++ {
+		if _, err := store.Store(ctx, []byte(fmt.Sprintf("clip %d", i)), storage.TypeText, types.Metadata{}); err != nil {
+			t.Fatalf("failed to store clip: %v", err)
+		}
+	}
+
+	var seen []string
+	cursor := ""
+	for page := 0; ; page++ {
+		results := mustSearch(t, store, storage.SearchOptions{Limit: 2, Cursor: cursor})
+		for _, result := range results {
+			seen = append(seen, string(result.Clip.Content))
+		}
+		if page == 0 {
+			// A new clip doesn't shift the pages after the cursor
+			if _, err := store.Store(ctx, []byte("new clip"), storage.TypeText, types.Metadata{}); err != nil {
+				t.Fatalf("failed to store clip: %v", err)
+			}
+		}
+		if cursor = storage.NextCursor(results, 2); cursor == "" {
+			break
+		}
+	}
+
+	want := []string{"clip 4", "clip 3", "clip 2", "clip 1", "clip 0"}
+	if !reflect.DeepEqual(seen, want) {
+		t.Errorf("pages = %q, want %q", seen, want)
+	}
+
+	if _, err := store.Search(ctx, storage.SearchOptions{Cursor: "1-1", SortBy: "created_at"}); !errors.Is(err, storage.ErrCursorOrder) {
+		t.Errorf("expected cursor order error, got %v", err)
+	}
+}
+
+func TestSearch_Shortcuts(t *testing.T) {
+	store, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	for _, c := range []struct {
+		content string
+		uses    int
+	}{{"work often", 3}, {"work once", 1}, {"home often", 2}} {
+		for i := 0; i < c.uses; i++ {
+			if _, err := store.Store(ctx, []byte(c.content), "text/plain", types.Metadata{}); err != nil {
+				t.Fatalf("failed to store clip: %v", err)
+			}
+		}
+	}
+	if _, err := store.Store(ctx, []byte("png"), "image/png", types.Metadata{}); err != nil {
+		t.Fatalf("failed to store clip: %v", err)
+	}
+
+	contents := func(results []storage.SearchResult, err error) []string {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("failed to search: %v", err)
+		}
+		var got []string
+		for _, result := range results {
+			got = append(got, string(result.Clip.Content))
+		}
+		return got
+	}
+
+	// The shortcuts take the same filters as Search, but keep their own order
+	opts := storage.SearchOptions{Query: "work", SortBy: "created_at", SortOrder: "asc"}
+	if got := contents(store.GetMostUsed(ctx, opts)); !reflect.DeepEqual(got, []string{"work often", "work once"}) {
+		t.Errorf("GetMostUsed = %v", got)
+	}
+	if got := contents(store.GetRecent(ctx, storage.SearchOptions{Query: "often", Limit: 1})); !reflect.DeepEqual(got, []string{"home often"}) {
+		t.Errorf("GetRecent = %v", got)
+	}
+	if got := contents(store.GetByType(ctx, "image/png", storage.SearchOptions{})); !reflect.DeepEqual(got, []string{"png"}) {
+		t.Errorf("GetByType = %v", got)
+	}
+}
+
+func TestSearch_Cancelled(t *testing.T) {
+	store, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	large := append([]byte("needle "), bytes.Repeat([]byte("x"), storage.MaxInlineStorageSize)...)
+	if _, err := store.Store(ctx, large, storage.TypeText, types.Metadata{}); err != nil {
+		t.Fatalf("failed to store clip: %v", err)
+	}
+	if results := mustSearch(t, store, storage.SearchOptions{Query: "needle"}); len(results) != 1 {
+		t.Fatalf("expected the external clip to match, got %d results", len(results))
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	for _, opts := range []storage.SearchOptions{{}, {Query: "needle"}} {
+		if _, err := store.Search(cancelled, opts); !errors.Is(err, context.Canceled) {
+			t.Errorf("Search(%q) with a cancelled context: expected context.Canceled, got %v", opts.Query, err)
+		}
+	}
+
+	// The scan of external files stops even once the clips are loaded
+	var external []storage.ClipModel
+	if err := store.db.Where("is_external = 1").Find(&external).Error; err != nil {
+		t.Fatalf("failed to load external clips: %v", err)
+	}
+	term := storage.Term{Field: storage.FieldText, Value: "needle"}
+	if _, _, err := termCondition(cancelled, term, store.fsPath, external); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the external content scan to stop, got %v", err)
+	}
+
+	if _, err := store.DeleteWhere(cancelled, storage.Query{Groups: [][]storage.Term{{term}}}); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected DeleteWhere to stop, got %v", err)
+	}
+	if results := mustSearch(t, store, storage.SearchOptions{}); len(results) != 1 {
+		t.Errorf("expected the clip to be kept, got %d results", len(results))
+	}
+}
+
+func TestBulk(t *testing.T) {
+	store, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	var ids []string
+	for _, content := range []string{"keep me", "delete me", "https://example.com", "tag me"} {
+		clip, err := store.Store(ctx, []byte(content), storage.TypeText, types.Metadata{})
+		if err != nil {
+			t.Fatalf("failed to store clip: %v", err)
+		}
+		ids = append(ids, clip.ID)
+	}
+
+	tagged, err := store.UpdateTagsMany(ctx, []string{ids[0], ids[3]}, []string{"work"})
+	if err != nil || tagged != 2 {
+		t.Fatalf("UpdateTagsMany = %d, %v, want 2", tagged, err)
+	}
+	clip, err := store.Get(ctx, ids[3])
+	if err != nil || !reflect.DeepEqual(clip.Metadata.Tags, []string{"work"}) {
+		t.Errorf("expected tags [work], got %+v, %v", clip, err)
+	}
+
+	deleted, err := store.DeleteMany(ctx, []string{ids[1], ids[1]})
+	if err != nil || deleted != 1 {
+		t.Fatalf("DeleteMany = %d, %v, want 1", deleted, err)
+	}
+
+	query, err := storage.ParseQuery("example.com")
+	if err != nil {
+		t.Fatalf("failed to parse query: %v", err)
+	}
+	deleted, err = store.DeleteWhere(ctx, query)
+	if err != nil || deleted != 1 {
+		t.Fatalf("DeleteWhere = %d, %v, want 1", deleted, err)
+	}
+
+	results := mustSearch(t, store, storage.SearchOptions{})
+	if len(results) != 2 || string(results[0].Clip.Content) != "tag me" || string(results[1].Clip.Content) != "keep me" {
+		t.Errorf("expected the tagged clips to remain, got %+v", results)
+	}
+
+	deleted, err = store.DeleteWhere(ctx, storage.Query{})
+	if err != nil || deleted != 2 {
+		t.Fatalf("DeleteWhere with an empty query = %d, %v, want 2", deleted, err)
+	}
+	trash, err := store.ListTrash(ctx, 10, 0)
+	if err != nil || len(trash) != 4 {
+		t.Errorf("expected 4 clips in the trash, got %d, %v", len(trash), err)
+	}
+}
+
+func TestReads_NotBlockedByWrites(t *testing.T) {
+	store, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	if _, err := store.Store(ctx, []byte("committed clip"), storage.TypeText, types.Metadata{}); err != nil {
+		t.Fatalf("failed to store clip: %v", err)
+	}
+
+	// Hold the writer in an open transaction, as a long capture would
+	tx := store.db.Begin()
+	defer tx.Rollback()
+	if err := tx.Create(&storage.ClipModel{ContentHash: "pending", Content: []byte("pending clip"), Type: storage.TypeText}).Error; err != nil {
+		t.Fatalf("failed to write in transaction: %v", err)
+	}
+
+	readCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	results, err := store.Search(readCtx, storage.SearchOptions{Query: "clip"})
+	if err != nil {
+		t.Fatalf("search waited for the writer: %v", err)
+	}
+	if len(results) != 1 || string(results[0].Clip.Content) != "committed clip" {
+		t.Errorf("expected only the committed clip, got %d results", len(results))
+	}
+
+	// The read pool can't write
+	if err := store.reads.Exec("DELETE FROM clip_models").Error; err == nil {
+		t.Error("expected a write through the read pool to fail")
+	}
+}
+
+func TestMaintain(t *testing.T) {
+	store, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	// Clips deleted for good leave free pages behind
+	ctx := context.Background()
+	for i := 0; i < 200; i++ {
+		content := append([]byte(fmt.Sprintf("clip %d ", i)), bytes.Repeat([]byte("x"), 8000)...)
+		clip, err := store.Store(ctx, content, storage.TypeText, types.Metadata{})
+		if err != nil {
+			t.Fatalf("failed to store clip: %v", err)
+		}
+		if err := store.Delete(ctx, clip.ID); err != nil {
+			t.Fatalf("failed to delete clip: %v", err)
+		}
+	}
+	if _, err := store.PurgeTrash(ctx, time.Now().Add(time.Second)); err != nil {
+		t.Fatalf("failed to purge trash: %v", err)
+	}
+
+	state, err := store.DatabaseState(ctx)
+	if err != nil {
+		t.Fatalf("failed to get database state: %v", err)
+	}
+	if state.FreeBytes == 0 || state.WALBytes == 0 {
+		t.Fatalf("expected free pages and a WAL, got %+v", state)
+	}
+
+	// A checkpoint alone frees nothing
+	report, err := store.Maintain(ctx, storage.MaintenanceOptions{WALLimit: state.WALBytes + 1})
+	if err != nil {
+		t.Fatalf("failed to checkpoint: %v", err)
+	}
+	if report.FreedBytes != 0 || report.WALTruncated {
+		t.Errorf("expected only a checkpoint, got %+v", report)
+	}
+
+	report, err = store.Maintain(ctx, storage.MaintenanceOptions{Full: true, WALLimit: 1})
+	if err != nil {
+		t.Fatalf("failed to maintain database: %v", err)
+	}
+	if report.FreedBytes < state.FreeBytes/2 || !report.WALTruncated {
+		t.Errorf("expected free pages to be released and the WAL truncated, got %+v", report)
+	}
+	if after, err := store.DatabaseState(ctx); err != nil || after.WALBytes != 0 || after.FreeBytes >= state.FreeBytes {
+		t.Errorf("expected an empty WAL and fewer free pages, got %+v, %v", after, err)
+	}
+	var analyzed int64
+	store.db.Raw("SELECT COUNT(*) FROM sqlite_stat1").Scan(&analyzed)
+	if analyzed == 0 {
+		t.Error("expected the database to be analyzed")
+	}
+}
+
+func TestFindNormalized(t *testing.T) {
+	store, cleanup := setupTestDB(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	clip, err := store.Store(ctx, []byte("ls -la"), storage.TypeText, types.Metadata{})
+	if err != nil {
+		t.Fatalf("failed to store clip: %v", err)
+	}
+
+	found, err := store.FindNormalized(ctx, storage.NormalizedHash([]byte("  ls -la\n"), storage.TypeText))
+	if err != nil || found.ID != clip.ID {
+		t.Fatalf("FindNormalized = %v, %v; want clip %s", found, err, clip.ID)
+	}
+	if _, err := store.FindNormalized(ctx, storage.NormalizedHash([]byte("pwd"), storage.TypeText)); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("FindNormalized of other text = %v, want ErrNotFound", err)
+	}
+
+	// Clips from before normalized hashes get one when copied again
+	if err := store.db.Model(&storage.ClipModel{}).Where("id = ?", clip.ID).Update("normalized_hash", "").Error; err != nil {
+		t.Fatalf("failed to clear hash: %v", err)
+	}
+	if _, err := store.Store(ctx, []byte("ls -la"), storage.TypeText, types.Metadata{}); err != nil {
+		t.Fatalf("failed to store clip again: %v", err)
+	}
+	if found, err := store.FindNormalized(ctx, storage.NormalizedHash([]byte("ls -la"), storage.TypeText)); err != nil || found.ID != clip.ID {
+		t.Errorf("FindNormalized after copying again = %v, %v; want clip %s", found, err, clip.ID)
+	}
+}
+
+func TestRelated(t *testing.T) {
+	store, cleanup := setupTestDB(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	var ids []string
+	for _, text := range []string{"alpha", "beta", "gamma"} {
+		clip, err := store.Store(ctx, []byte(text), storage.TypeText, types.Metadata{Session: "s1"})
+		if err != nil {
+			t.Fatalf("failed to store clip: %v", err)
+		}
+		ids = append(ids, clip.ID)
+		time.Sleep(time.Millisecond)
+	}
+	other, err := store.Store(ctx, []byte("other"), storage.TypeText, types.Metadata{Session: "s2"})
+	if err != nil {
+		t.Fatalf("failed to store clip: %v", err)
+	}
+
+	related, err := store.Related(ctx, ids[1])
+	if err != nil {
+		t.Fatalf("Related failed: %v", err)
+	}
+	if len(related) != 2 || related[0].ID != ids[0] || related[1].ID != ids[2] || related[0].Metadata.Session != "s1" {
+		t.Errorf("Related = %v, want clips %s and %s", related, ids[0], ids[2])
+	}
+	if related, err := store.Related(ctx, other.ID); err != nil || len(related) != 0 {
+		t.Errorf("Related of a clip alone in its session = %v, %v", related, err)
+	}
+
+	// Copying a clip again moves it to the new session
+	if _, err := store.Store(ctx, []byte("alpha"), storage.TypeText, types.Metadata{Session: "s2"}); err != nil {
+		t.Fatalf("failed to store clip again: %v", err)
+	}
+	if related, err := store.Related(ctx, other.ID); err != nil || len(related) != 1 || related[0].ID != ids[0] {
+		t.Errorf("Related after copying again = %v, %v; want clip %s", related, err, ids[0])
+	}
+	if _, err := store.Related(ctx, "999"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("Related of a missing clip = %v, want ErrNotFound", err)
+	}
+}
+
+func TestSearch_Window(t *testing.T) {
+	store, cleanup := setupTestDB(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	if _, err := store.Store(ctx, []byte("42.50"), storage.TypeText, types.Metadata{SourceApp: "Numbers", SourceWindow: "Budget 2024.numbers"}); err != nil {
+		t.Fatalf("failed to store clip: %v", err)
+	}
+	if _, err := store.Store(ctx, []byte("17.00"), storage.TypeText, types.Metadata{SourceApp: "Numbers", SourceWindow: "Expenses.numbers"}); err != nil {
+		t.Fatalf("failed to store clip: %v", err)
+	}
+
+	results := mustSearch(t, store, storage.SearchOptions{Query: "window:budget"})
+	if len(results) != 1 || string(results[0].Clip.Content) != "42.50" || results[0].Clip.Metadata.SourceWindow != "Budget 2024.numbers" {
+		t.Errorf("window:budget found %d clips, want the one copied from the Budget window", len(results))
+	}
+}
+
+func TestTimeline(t *testing.T) {
+	store, cleanup := setupTestDB(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	tuesday := time.Date(2024, 6, 4, 14, 0, 0, 0, time.Local)
+	for i, createdAt := range []time.Time{
+		tuesday.Add(10 * time.Minute),
+		tuesday.Add(40 * time.Minute),
+		tuesday.Add(2 * time.Hour),
+		tuesday.AddDate(0, 0, 1),
+	} {
+		clip, err := store.Store(ctx, []byte(fmt.Sprintf("clip %d", i)), storage.TypeText, types.Metadata{})
+		if err != nil {
+			t.Fatalf("failed to store clip: %v", err)
+		}
+		if err := store.db.Model(&storage.ClipModel{}).Where("id = ?", clip.ID).Update("created_at", createdAt).Error; err != nil {
+			t.Fatalf("failed to backdate clip: %v", err)
+		}
+	}
+
+	days, err := store.Timeline(ctx, storage.TimelineOptions{Granularity: storage.GranularityDay})
+	if err != nil {
+		t.Fatalf("Timeline failed: %v", err)
+	}
+	if len(days) != 2 || days[0].Clips != 3 || days[1].Clips != 1 {
+		t.Fatalf("days = %+v, want 3 clips on Tuesday and 1 on Wednesday", days)
+	}
+	if !days[0].Start.Equal(time.Date(2024, 6, 4, 0, 0, 0, 0, time.Local)) || !days[0].End.Equal(days[1].Start) {
+		t.Errorf("Tuesday runs from %v to %v", days[0].Start, days[0].End)
+	}
+
+	// Tuesday afternoon, by the hour
+	hours, err := store.Timeline(ctx, storage.TimelineOptions{
+		Granularity: storage.GranularityHour,
+		From:        tuesday,
+		To:          tuesday.Add(6 * time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("Timeline failed: %v", err)
+	}
+	if len(hours) != 2 || !hours[0].Start.Equal(tuesday) || hours[0].Clips != 2 || hours[1].Clips != 1 {
+		t.Errorf("hours = %+v, want 2 clips at 14:00 and 1 at 16:00", hours)
+	}
+}
@@ -0,0 +1,92 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+)
+
+// maxStatements bounds the statements a cache keeps. The shapes callers
+// cache are bounded by the filters a search can combine, well under this.
+const maxStatements = 256
+
+// statements caches prepared statements by query, so SQLite parses and plans
+// each query shape once rather than on every call. Only queries whose text
+// comes from a fixed set of shapes should go through it; a query that embeds
+// a variable number of terms would fill it with shapes never seen again.
+type statements struct {
+	db    *sql.DB
+	mu    sync.Mutex
+	stmts map[string]*sql.Stmt
+}
+
+func newStatements(db *sql.DB) *statements {
+	return &statements{db: db, stmts: make(map[string]*sql.Stmt)}
+}
+
+// prepare returns the statement for query, preparing it the first time.
+// Once the cache is full, queries not yet in it return nil.
+func (c *statements) prepare(ctx context.Context, query string) (*sql.Stmt, error) {
+	c.mu.Lock()
+	stmt, ok := c.stmts[query]
+	full := len(c.stmts) >= maxStatements
+	c.mu.Unlock()
+	if ok || full {
+		return stmt, nil
+	}
+
+	stmt, err := c.db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if existing, ok := c.stmts[query]; ok {
+		// Prepared concurrently
+		stmt.Close()
+		return existing, nil
+	}
+	c.stmts[query] = stmt
+	return stmt, nil
+}
+
+// query runs query through its prepared statement
+func (c *statements) query(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	stmt, err := c.prepare(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	if stmt == nil {
+		return c.db.QueryContext(ctx, query, args...)
+	}
+	return stmt.QueryContext(ctx, args...)
+}
+
+// queryRow runs query, expected to return at most one row, through its
+// prepared statement
+func (c *statements) queryRow(ctx context.Context, query string, args ...interface{}) (*sql.Row, error) {
+	stmt, err := c.prepare(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	if stmt == nil {
+		return c.db.QueryRowContext(ctx, query, args...), nil
+	}
+	return stmt.QueryRowContext(ctx, args...), nil
+}
+
+// close closes the prepared statements
+func (c *statements) close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var firstErr error
+	for query, stmt := range c.stmts {
+		if err := stmt.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		delete(c.stmts, query)
+	}
+	return firstErr
+}
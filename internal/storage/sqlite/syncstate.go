@@ -0,0 +1,76 @@
+package sqlite
+
+import (
+	"clipboard-manager/internal/storage"
+	"clipboard-manager/pkg/types"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Sync states are removed by the triggers in migration 0009_sync_state.sql
+
+// ListSyncDue implements storage.SyncStateService interface
+func (s *SQLiteStorage) ListSyncDue(ctx context.Context, limit int) ([]*types.Clip, error) {
+	query := s.reads.WithContext(ctx).Model(&storage.ClipModel{}).
+		Select("clip_models.*").
+		Joins("LEFT JOIN sync_state_models ON sync_state_models.clip_id = clip_models.id").
+		Where("sync_state_models.clip_id IS NULL OR (sync_state_models.attempts < ? AND sync_state_models.next_retry <= ?)",
+			storage.MaxSyncAttempts, time.Now())
+	return s.findUnsynced(ctx, query, limit)
+}
+
+// SyncFailed implements storage.SyncStateService interface
+func (s *SQLiteStorage) SyncFailed(ctx context.Context, id string, syncErr error) (*storage.SyncState, error) {
+	var state storage.SyncStateModel
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var clip storage.ClipModel
+		if err := tx.Select("id").First(&clip, id).Error; err != nil {
+			return notFound(err)
+		}
+		if err := tx.First(&state, clip.ID).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		now := time.Now()
+		state.ClipID = clip.ID
+		state.Attempts++
+		state.LastError = syncErr.Error()
+		state.LastAttempt = now
+		state.NextRetry = storage.SyncRetryAt(state.Attempts, now)
+		return tx.Save(&state).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to record sync failure: %w", err)
+	}
+	result := state.ToSyncState()
+	return &result, nil
+}
+
+// ListSyncFailures implements storage.SyncStateService interface
+func (s *SQLiteStorage) ListSyncFailures(ctx context.Context) ([]storage.SyncState, error) {
+	var models []storage.SyncStateModel
+	if err := s.reads.WithContext(ctx).
+		Joins("JOIN clip_models ON clip_models.id = sync_state_models.clip_id AND clip_models.deleted_at IS NULL").
+		Order("last_attempt DESC").
+		Find(&models).Error; err != nil {
+		return nil, fmt.Errorf("failed to list sync failures: %w", err)
+	}
+
+	states := make([]storage.SyncState, len(models))
+	for i, model := range models {
+		states[i] = model.ToSyncState()
+	}
+	return states, nil
+}
+
+// ResetSyncFailures implements storage.SyncStateService interface
+func (s *SQLiteStorage) ResetSyncFailures(ctx context.Context) (int64, error) {
+	result := s.db.WithContext(ctx).Where("1 = 1").Delete(&storage.SyncStateModel{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to reset sync failures: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
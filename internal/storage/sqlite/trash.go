@@ -0,0 +1,127 @@
+package sqlite
+
+import (
+	"clipboard-manager/internal/storage"
+	"clipboard-manager/pkg/types"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ListTrash implements storage.TrashService interface
+func (s *SQLiteStorage) ListTrash(ctx context.Context, limit, offset int) ([]*types.Clip, error) {
+	query := s.reads.WithContext(ctx).Unscoped().
+		Where("deleted_at IS NOT NULL").
+		Order("deleted_at DESC")
+
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	if offset > 0 {
+		query = query.Offset(offset)
+	}
+
+	var models []storage.ClipModel
+	if err := query.Find(&models).Error; err != nil {
+		return nil, fmt.Errorf("failed to list trash: %w", err)
+	}
+
+	clips := make([]*types.Clip, len(models))
+	for i, model := range models {
+		// Load external content if needed; large text only shows its head
+		if model.IsExternal && !model.HasHead() {
+			path := filepath.Join(s.fsPath, model.StoragePath)
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read external content for clip %d: %w", model.ID, err)
+			}
+			model.Content = content
+		}
+		clips[i] = model.ToClip()
+	}
+
+	return clips, nil
+}
+
+// Restore implements storage.TrashService interface
+func (s *SQLiteStorage) Restore(ctx context.Context, id string) (*types.Clip, error) {
+	var model storage.ClipModel
+	if err := s.db.WithContext(ctx).Unscoped().
+		Where("deleted_at IS NOT NULL").
+		First(&model, id).Error; err != nil {
+		return nil, fmt.Errorf("failed to get deleted clip: %w", notFound(err))
+	}
+
+	// Restored clips come back at the top of the history
+	model.DeletedAt = gorm.DeletedAt{}
+	model.LastUsed = time.Now()
+	if err := s.db.WithContext(ctx).Unscoped().Save(&model).Error; err != nil {
+		return nil, fmt.Errorf("failed to restore clip: %w", err)
+	}
+
+	return model.ToClip(), nil
+}
+
+// PurgeTrash implements storage.TrashService interface
+func (s *SQLiteStorage) PurgeTrash(ctx context.Context, before time.Time) (int64, error) {
+	var models []storage.ClipModel
+	if err := s.db.WithContext(ctx).Unscoped().
+		Select("id", "storage_path", "is_external", "file_copy").
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", before).
+		Find(&models).Error; err != nil {
+		return 0, fmt.Errorf("failed to list trash: %w", err)
+	}
+
+	return s.purge(ctx, models)
+}
+
+// purge permanently deletes clips, removing external files no other clip uses
+func (s *SQLiteStorage) purge(ctx context.Context, models []storage.ClipModel) (int64, error) {
+	var purged int64
+	for _, model := range models {
+		var orphaned []string
+		err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			orphaned = nil
+			if err := tx.Unscoped().Delete(&model).Error; err != nil {
+				return fmt.Errorf("failed to purge clip: %w", err)
+			}
+			if err := tx.Where("clip_id = ?", model.ID).Delete(&storage.VersionModel{}).Error; err != nil {
+				return fmt.Errorf("failed to purge versions: %w", err)
+			}
+
+			// Delete external files once no other clip shares them
+			for _, hash := range []string{model.FileCopy, externalPath(model)} {
+				if hash == "" {
+					continue
+				}
+				released, err := s.releaseBlob(tx, hash)
+				if err != nil {
+					return err
+				}
+				if released {
+					orphaned = append(orphaned, hash)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return purged, err
+		}
+		s.removeBlobFiles(orphaned)
+		purged++
+	}
+
+	return purged, nil
+}
+
+// externalPath is the blob holding the content of model, if it is external
+func externalPath(model storage.ClipModel) string {
+	if !model.IsExternal {
+		return ""
+	}
+	return model.StoragePath
+}
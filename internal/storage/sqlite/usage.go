@@ -0,0 +1,26 @@
+package sqlite
+
+import (
+	"clipboard-manager/internal/storage"
+	"context"
+	"fmt"
+)
+
+// Usage implements storage.UsageReporter interface
+func (s *SQLiteStorage) Usage(ctx context.Context) (storage.Usage, error) {
+	var usage storage.Usage
+
+	if err := s.reads.WithContext(ctx).
+		Raw("SELECT page_count * page_size FROM pragma_page_count(), pragma_page_size()").
+		Scan(&usage.DatabaseBytes).Error; err != nil {
+		return usage, fmt.Errorf("failed to get database size: %w", err)
+	}
+
+	if err := s.reads.WithContext(ctx).Model(&storage.BlobModel{}).
+		Select("COALESCE(SUM(size), 0)").
+		Scan(&usage.ExternalBytes).Error; err != nil {
+		return usage, fmt.Errorf("failed to get external storage size: %w", err)
+	}
+
+	return usage, nil
+}
@@ -0,0 +1,121 @@
+package sqlite
+
+import (
+	"clipboard-manager/internal/storage"
+	"clipboard-manager/pkg/types"
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// Edit implements storage.VersionService interface
+func (s *SQLiteStorage) Edit(ctx context.Context, id string, content []byte, formats map[string][]byte) (*types.Clip, error) {
+	if len(content) > storage.MaxInlineStorageSize {
+		return nil, storage.ErrNotEditable
+	}
+
+	var model storage.ClipModel
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&model, "id = ?", id).Error; err != nil {
+			return fmt.Errorf("failed to get clip: %w", notFound(err))
+		}
+		return replaceContent(tx, &model, content, model.Type, formats)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return model.ToClip(), nil
+}
+
+// ListVersions implements storage.VersionService interface
+func (s *SQLiteStorage) ListVersions(ctx context.Context, id string) ([]storage.ClipVersion, error) {
+	var model storage.ClipModel
+	if err := s.reads.WithContext(ctx).First(&model, "id = ?", id).Error; err != nil {
+		return nil, fmt.Errorf("failed to get clip: %w", notFound(err))
+	}
+
+	var models []storage.VersionModel
+	if err := s.reads.WithContext(ctx).Where("clip_id = ?", model.ID).Order("version").Find(&models).Error; err != nil {
+		return nil, fmt.Errorf("failed to list versions: %w", err)
+	}
+
+	versions := make([]storage.ClipVersion, len(models))
+	for i := range models {
+		versions[i] = models[i].ToVersion()
+	}
+	return versions, nil
+}
+
+// Revert implements storage.VersionService interface
+func (s *SQLiteStorage) Revert(ctx context.Context, id string, version int) (*types.Clip, error) {
+	var model storage.ClipModel
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&model, "id = ?", id).Error; err != nil {
+			return fmt.Errorf("failed to get clip: %w", notFound(err))
+		}
+		var old storage.VersionModel
+		if err := tx.First(&old, "clip_id = ? AND version = ?", model.ID, version).Error; err != nil {
+			return fmt.Errorf("failed to get version %d: %w", version, notFound(err))
+		}
+		return replaceContent(tx, &model, old.Content, old.Type, old.Formats)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return model.ToClip(), nil
+}
+
+// replaceContent saves a clip's current content as its next version and
+// replaces it. Content shared with another clip, even one in the trash, is
+// refused since hashes are unique.
+func replaceContent(tx *gorm.DB, model *storage.ClipModel, content []byte, clipType string, formats storage.FormatMap) error {
+	if model.IsExternal {
+		return storage.ErrNotEditable
+	}
+
+	hash := calculateHash(content)
+	if hash == model.ContentHash && clipType == model.Type {
+		return nil
+	}
+	var count int64
+	if err := tx.Unscoped().Model(&storage.ClipModel{}).
+		Where("content_hash = ? AND id <> ?", hash, model.ID).Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to check content: %w", err)
+	}
+	if count > 0 {
+		return storage.ErrDuplicate
+	}
+
+	var latest int
+	if err := tx.Model(&storage.VersionModel{}).Where("clip_id = ?", model.ID).
+		Select("COALESCE(MAX(version), 0)").Scan(&latest).Error; err != nil {
+		return fmt.Errorf("failed to number version: %w", err)
+	}
+	if err := tx.Create(&storage.VersionModel{
+		ClipID:  model.ID,
+		Version: latest + 1,
+		Content: model.Content,
+		Type:    model.Type,
+		Formats: model.Formats,
+	}).Error; err != nil {
+		return fmt.Errorf("failed to save version: %w", err)
+	}
+
+	// UpdateColumns skips the hook that would set last_used to now
+	if err := tx.Model(model).UpdateColumns(map[string]interface{}{
+		"content":         content,
+		"content_hash":    hash,
+		"size":            int64(len(content)),
+		"type":            clipType,
+		"formats":         formats,
+		"plain_text":      string(formats[storage.FormatPlainText]),
+		"normalized_hash": storage.NormalizedHash(content, clipType),
+	}).Error; err != nil {
+		return fmt.Errorf("failed to update clip: %w", err)
+	}
+	model.Content, model.ContentHash, model.Size = content, hash, int64(len(content))
+	model.Type, model.Formats, model.PlainText = clipType, formats, string(formats[storage.FormatPlainText])
+	model.NormalizedHash = storage.NormalizedHash(content, clipType)
+	return nil
+}
@@ -0,0 +1,120 @@
+package storage
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// StatsDays is how many days the daily histogram of Stats covers
+const StatsDays = 30
+
+// Count is the number and total size of clips in one bucket of a breakdown
+type Count struct {
+	Key   string
+	Clips int64
+	Bytes int64
+}
+
+// Stats summarizes the clipboard history. Clips in the trash are not counted.
+type Stats struct {
+	Clips        int64
+	Bytes        int64 // Total content size
+	AverageBytes int64
+
+	ByType []Count   // Most clips first
+	ByApp  []Count   // Most clips first; Key is empty for clips with no source app
+	ByDay  []Count   // The last StatsDays days in local time, oldest first, keyed YYYY-MM-DD
+	ByHour [24]int64 // Clips by local hour of day copied
+
+	// Space taken on disk, when the storage reports it
+	Usage Usage
+
+	// Database upkeep, when the storage needs it, see Maintainer
+	Maintenance *MaintenanceStatus
+}
+
+// StatsService defines the interface for summarizing the clipboard history
+type StatsService interface {
+	// Stats counts clips by type, source app and time copied
+	Stats(ctx context.Context) (*Stats, error)
+}
+
+// StatsBuilder accumulates Stats one clip at a time, for backends that
+// scan their clips
+type StatsBuilder struct {
+	stats  Stats
+	byType map[string]*Count
+	byApp  map[string]*Count
+	byDay  map[string]*Count
+}
+
+// NewStatsBuilder starts Stats whose daily histogram ends on the day of now
+func NewStatsBuilder(now time.Time) *StatsBuilder {
+	b := &StatsBuilder{
+		byType: make(map[string]*Count),
+		byApp:  make(map[string]*Count),
+		byDay:  make(map[string]*Count),
+	}
+	today := now.Local()
+	for i := StatsDays - 1; i >= 0; i-- {
+		key := today.AddDate(0, 0, -i).Format("2006-01-02")
+		b.stats.ByDay = append(b.stats.ByDay, Count{Key: key})
+	}
+	for i := range b.stats.ByDay {
+		b.byDay[b.stats.ByDay[i].Key] = &b.stats.ByDay[i]
+	}
+	return b
+}
+
+// Add counts a clip
+func (b *StatsBuilder) Add(clipType, sourceApp string, size int64, createdAt time.Time) {
+	b.stats.Clips++
+	b.stats.Bytes += size
+	addCount(b.byType, clipType, size)
+	addCount(b.byApp, sourceApp, size)
+
+	local := createdAt.Local()
+	b.stats.ByHour[local.Hour()]++
+	if day, ok := b.byDay[local.Format("2006-01-02")]; ok {
+		day.Clips++
+		day.Bytes += size
+	}
+}
+
+// Stats returns the counts so far
+func (b *StatsBuilder) Stats() *Stats {
+	stats := b.stats
+	stats.ByDay = append([]Count(nil), b.stats.ByDay...)
+	if stats.Clips > 0 {
+		stats.AverageBytes = stats.Bytes / stats.Clips
+	}
+	stats.ByType = sortedCounts(b.byType)
+	stats.ByApp = sortedCounts(b.byApp)
+	return &stats
+}
+
+func addCount(counts map[string]*Count, key string, size int64) {
+	count, ok := counts[key]
+	if !ok {
+		count = &Count{Key: key}
+		counts[key] = count
+	}
+	count.Clips++
+	count.Bytes += size
+}
+
+// sortedCounts lists counts with the most clips first, then by key
+func sortedCounts(counts map[string]*Count) []Count {
+	list := make([]Count, 0, len(counts))
+	for _, count := range counts {
+		list = append(list, *count)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Clips != list[j].Clips {
+			return list[i].Clips > list[j].Clips
+		}
+		return list[i].Key < list[j].Key
+	})
+	return list
+}
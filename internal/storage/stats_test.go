@@ -0,0 +1,37 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStatsBuilder(t *testing.T) {
+	now := time.Date(2024, 6, 30, 15, 0, 0, 0, time.Local)
+	b := NewStatsBuilder(now)
+	b.Add("text/plain", "Safari", 10, now)
+	b.Add("text/plain", "Notes", 20, now.Add(-time.Hour))
+	b.Add("image/png", "Safari", 90, now.AddDate(0, 0, -1))
+	b.Add("text/plain", "", 0, now.AddDate(-1, 0, 0)) // Outside the daily histogram
+	stats := b.Stats()
+
+	if stats.Clips != 4 || stats.Bytes != 120 || stats.AverageBytes != 30 {
+		t.Errorf("unexpected totals: %+v", stats)
+	}
+	if len(stats.ByType) != 2 || stats.ByType[0] != (Count{Key: "text/plain", Clips: 3, Bytes: 30}) {
+		t.Errorf("unexpected types: %+v", stats.ByType)
+	}
+	if len(stats.ByApp) != 3 || stats.ByApp[0] != (Count{Key: "Safari", Clips: 2, Bytes: 100}) {
+		t.Errorf("unexpected apps: %+v", stats.ByApp)
+	}
+
+	if len(stats.ByDay) != StatsDays {
+		t.Fatalf("expected %d days, got %d", StatsDays, len(stats.ByDay))
+	}
+	today, yesterday := stats.ByDay[StatsDays-1], stats.ByDay[StatsDays-2]
+	if today != (Count{Key: "2024-06-30", Clips: 2, Bytes: 30}) || yesterday.Clips != 1 {
+		t.Errorf("unexpected days: %+v %+v", yesterday, today)
+	}
+	if stats.ByHour[15] != 3 || stats.ByHour[14] != 1 {
+		t.Errorf("unexpected hours: %v", stats.ByHour)
+	}
+}
@@ -3,6 +3,7 @@ package storage
 import (
 	"clipboard-manager/pkg/types"
 	"context"
+	"io"
 )
 
 // Storage defines the interface for clipboard data persistence
@@ -10,8 +11,15 @@ type Storage interface {
 	// Store saves clipboard content and returns a clip ID
 	Store(ctx context.Context, content []byte, clipType string, metadata types.Metadata) (*types.Clip, error)
 	
+	// StoreStream saves clipboard content read from r without loading large content into memory
+	StoreStream(ctx context.Context, r io.Reader, clipType string, metadata types.Metadata) (*types.Clip, error)
+
 	// Get retrieves clipboard content by ID
 	Get(ctx context.Context, id string) (*types.Clip, error)
+
+	// GetStream returns a reader for the content of a clip along with the clip
+	// without its content. The caller must close the reader.
+	GetStream(ctx context.Context, id string) (io.ReadCloser, *types.Clip, error)
 	
 	// Delete removes clipboard content
 	Delete(ctx context.Context, id string) error
@@ -38,6 +46,8 @@ type ListFilter struct {
 
 // Config holds storage configuration
 type Config struct {
-	DBPath  string // Path to SQLite database
-	FSPath  string // Path to filesystem storage for large files
+	DBPath    string // Path to SQLite database
+	FSPath    string // Path to filesystem storage for large files
+	DSN       string // Backend specific connection string, set by Open
+	NoMigrate bool   // Leave pending schema migrations for MigrationService.Migrate
 }
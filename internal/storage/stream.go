@@ -0,0 +1,105 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+)
+
+// SpooledContent is clip content read from a stream. Content small enough to
+// be stored inline is kept in memory; larger content is spooled to a
+// temporary file so it never has to be held in memory as a whole.
+type SpooledContent struct {
+	Hash     string // SHA-256 of the content
+	Size     int64
+	Data     []byte // Set when the content fits inline
+	TempPath string // Set when the content was spooled to disk
+}
+
+// Spool reads a stream, hashing it as it goes. Temporary files are created in
+// dir so they can later be renamed into place without copying.
+func Spool(r io.Reader, dir string) (*SpooledContent, error) {
+	hash := sha256.New()
+	r = io.TeeReader(r, hash)
+
+	// Read up to the inline limit into memory
+	var buf bytes.Buffer
+	n, err := io.CopyN(&buf, r, MaxInlineStorageSize+1)
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to read content: %w", err)
+	}
+	if n <= MaxInlineStorageSize {
+		return &SpooledContent{
+			Hash: hex.EncodeToString(hash.Sum(nil)),
+			Size: n,
+			Data: buf.Bytes(),
+		}, nil
+	}
+
+	// Spool the rest to disk, enforcing the maximum size
+	tmp, err := os.CreateTemp(dir, ".spool-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	spooled := &SpooledContent{TempPath: tmp.Name()}
+
+	size, err := io.Copy(tmp, io.MultiReader(&buf, io.LimitReader(r, MaxStorageSize-n+1)))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		spooled.Discard()
+		return nil, fmt.Errorf("failed to spool content: %w", err)
+	}
+	if size > MaxStorageSize {
+		spooled.Discard()
+		return nil, ErrFileTooLarge
+	}
+
+	spooled.Hash = hex.EncodeToString(hash.Sum(nil))
+	spooled.Size = size
+	return spooled, nil
+}
+
+// Head returns up to n bytes from the start of the content, cut short so a
+// UTF-8 character isn't split
+func (c *SpooledContent) Head(n int) ([]byte, error) {
+	head := c.Data
+	if c.TempPath != "" {
+		file, err := os.Open(c.TempPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open spooled content: %w", err)
+		}
+		defer file.Close()
+		head = make([]byte, n)
+		read, err := io.ReadFull(file, head)
+		if err != nil && err != io.ErrUnexpectedEOF {
+			return nil, fmt.Errorf("failed to read spooled content: %w", err)
+		}
+		head = head[:read]
+	}
+	return TextHead(head, n), nil
+}
+
+// MoveTo places spooled content at path, writing inline data if it was never spooled
+func (c *SpooledContent) MoveTo(path string) error {
+	if c.TempPath == "" {
+		return os.WriteFile(path, c.Data, 0644)
+	}
+	if err := os.Rename(c.TempPath, path); err != nil {
+		return err
+	}
+	c.TempPath = ""
+	return nil
+}
+
+// Discard removes the temporary file, if any
+func (c *SpooledContent) Discard() {
+	if c.TempPath != "" {
+		os.Remove(c.TempPath)
+		c.TempPath = ""
+	}
+}
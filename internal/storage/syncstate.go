@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"clipboard-manager/pkg/types"
+	"context"
+	"time"
+)
+
+// MaxSyncAttempts is how many times syncing a clip may fail before it is
+// given up on
+const MaxSyncAttempts = 5
+
+// Wait before syncing a clip again, doubling after each failure
+const (
+	SyncRetryMin = time.Minute
+	SyncRetryMax = 6 * time.Hour
+)
+
+// SyncRetryAt returns when to try syncing a clip again after it failed
+// attempts times, the last time at now
+func SyncRetryAt(attempts int, now time.Time) time.Time {
+	wait := SyncRetryMin
+	for i := 1; i < attempts && wait < SyncRetryMax; i++ {
+		wait *= 2
+	}
+	if wait > SyncRetryMax {
+		wait = SyncRetryMax
+	}
+	return now.Add(wait)
+}
+
+// SyncState is how syncing a clip that failed to sync has gone
+type SyncState struct {
+	ClipID      string    `json:"clip_id"`
+	Attempts    int       `json:"attempts"`
+	LastError   string    `json:"last_error"`
+	LastAttempt time.Time `json:"last_attempt"`
+	NextRetry   time.Time `json:"next_retry"`
+	GivenUp     bool      `json:"given_up"` // Failed MaxSyncAttempts times
+}
+
+// SyncStateService defines the interface for tracking clips that failed to
+// sync, so one clip that can't be synced doesn't hold back the others.
+// Marking a clip synced forgets its failures.
+type SyncStateService interface {
+	// ListSyncDue returns unsynced clips like ListUnsynced, leaving out
+	// clips waiting to be retried after a failed sync and clips given up on
+	ListSyncDue(ctx context.Context, limit int) ([]*types.Clip, error)
+
+	// SyncFailed records a failed attempt to sync a clip
+	SyncFailed(ctx context.Context, id string, syncErr error) (*SyncState, error)
+
+	// ListSyncFailures returns the state of unsynced clips that failed to
+	// sync, most recently attempted first
+	ListSyncFailures(ctx context.Context) ([]SyncState, error)
+
+	// ResetSyncFailures forgets the failures of every clip, so clips given
+	// up on are tried again, and returns how many clips it reset
+	ResetSyncFailures(ctx context.Context) (int64, error)
+}
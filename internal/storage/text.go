@@ -0,0 +1,42 @@
+package storage
+
+import (
+	"bytes"
+	"strings"
+	"unicode/utf8"
+)
+
+// Large text clips, such as log dumps, are stored in files like other large
+// content. The database keeps only their head, which is what searches match
+// and lists show.
+const (
+	MaxInlineTextSize = 256 * 1024 // Text clips past this are stored in files
+	TextHeadSize      = 4 * 1024   // Bytes of large text kept in the database
+)
+
+// IsText reports whether clipType is a text type, matching what searches
+// treat as text
+func IsText(clipType string) bool {
+	return strings.HasPrefix(clipType, "text")
+}
+
+// StoredExternally reports whether content of clipType and size belongs in
+// a file rather than the database
+func StoredExternally(clipType string, size int64) bool {
+	if IsText(clipType) {
+		return size > MaxInlineTextSize
+	}
+	return size > MaxInlineStorageSize
+}
+
+// TextHead returns up to n bytes from the start of data, cut short so a
+// UTF-8 character isn't split. The result doesn't share data's memory.
+func TextHead(data []byte, n int) []byte {
+	if len(data) > n {
+		for n > 0 && !utf8.RuneStart(data[n]) {
+			n--
+		}
+		data = data[:n]
+	}
+	return bytes.Clone(data)
+}
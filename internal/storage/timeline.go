@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Granularities of a timeline
+const (
+	GranularityDay  = "day"
+	GranularityHour = "hour"
+)
+
+// ParseGranularity checks name is a timeline granularity
+func ParseGranularity(name string) (string, error) {
+	switch name {
+	case GranularityDay, GranularityHour:
+		return name, nil
+	}
+	return "", fmt.Errorf("unknown timeline granularity %q: expected day or hour", name)
+}
+
+// TimelineBucket is the number of clips copied in one day or hour of local
+// time, from Start up to but not including End
+type TimelineBucket struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	Clips int64     `json:"clips"`
+}
+
+// TimelineOptions chooses the clips counted by a timeline
+type TimelineOptions struct {
+	Granularity string // GranularityDay or GranularityHour
+
+	// Only clips created in this range, inclusive, as with SearchOptions.
+	// Zero times leave the range open.
+	From time.Time
+	To   time.Time
+}
+
+// TimelineService is implemented by backends that can count clips by when
+// they were copied, for browsing the history by time
+type TimelineService interface {
+	// Timeline counts the clips copied in each day or hour, oldest first.
+	// Days and hours without clips are left out, as are clips in the trash.
+	Timeline(ctx context.Context, opts TimelineOptions) ([]TimelineBucket, error)
+}
+
+// TimelineBuilder accumulates timeline buckets one clip at a time, for
+// backends that scan their clips
+type TimelineBuilder struct {
+	granularity string
+	buckets     map[int64]*TimelineBucket // By the Unix time of Start
+}
+
+// NewTimelineBuilder starts a timeline with buckets of the granularity,
+// days unless it is GranularityHour
+func NewTimelineBuilder(granularity string) *TimelineBuilder {
+	return &TimelineBuilder{granularity: granularity, buckets: make(map[int64]*TimelineBucket)}
+}
+
+// Add counts a clip created at createdAt
+func (b *TimelineBuilder) Add(createdAt time.Time) {
+	t := createdAt.Local()
+	var start, end time.Time
+	if b.granularity == GranularityHour {
+		start = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, time.Local)
+		end = start.Add(time.Hour)
+	} else {
+		start = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+		end = start.AddDate(0, 0, 1)
+	}
+
+	bucket, ok := b.buckets[start.Unix()]
+	if !ok {
+		bucket = &TimelineBucket{Start: start, End: end}
+		b.buckets[start.Unix()] = bucket
+	}
+	bucket.Clips++
+}
+
+// Buckets returns the buckets counted so far, oldest first
+func (b *TimelineBuilder) Buckets() []TimelineBucket {
+	buckets := make([]TimelineBucket, 0, len(b.buckets))
+	for _, bucket := range b.buckets {
+		buckets = append(buckets, *bucket)
+	}
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i].Start.Before(buckets[j].Start) })
+	return buckets
+}
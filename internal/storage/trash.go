@@ -0,0 +1,25 @@
+package storage
+
+import (
+	"clipboard-manager/pkg/types"
+	"context"
+	"time"
+)
+
+// DefaultTrashRetention is how long deleted clips stay in the trash before
+// they are purged
+const DefaultTrashRetention = 30 * 24 * time.Hour
+
+// TrashService defines the interface for recovering deleted clips. Backends
+// implementing it move clips to the trash on Delete instead of removing them.
+type TrashService interface {
+	// ListTrash returns deleted clips, most recently deleted first
+	ListTrash(ctx context.Context, limit, offset int) ([]*types.Clip, error)
+
+	// Restore moves a clip out of the trash
+	Restore(ctx context.Context, id string) (*types.Clip, error)
+
+	// PurgeTrash permanently removes clips deleted before the given time and
+	// returns how many were removed
+	PurgeTrash(ctx context.Context, before time.Time) (int64, error)
+}
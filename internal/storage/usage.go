@@ -0,0 +1,15 @@
+package storage
+
+import "context"
+
+// Usage describes how much space the stored clips take up
+type Usage struct {
+	DatabaseBytes int64 // Size of the database itself
+	ExternalBytes int64 // Size of large clips kept as files
+}
+
+// UsageReporter defines the interface for reporting storage size
+type UsageReporter interface {
+	// Usage returns the current storage size
+	Usage(ctx context.Context) (Usage, error)
+}
@@ -0,0 +1,31 @@
+package storage
+
+import (
+	"clipboard-manager/pkg/types"
+	"context"
+	"time"
+)
+
+// ClipVersion is content a clip had before it was edited
+type ClipVersion struct {
+	Version   int               `json:"version"`
+	Content   []byte            `json:"content"`
+	Type      string            `json:"type"`
+	Formats   map[string][]byte `json:"formats,omitempty"`
+	CreatedAt time.Time         `json:"created_at"` // When the content was replaced
+}
+
+// VersionService defines the interface for editing clips while keeping
+// their earlier content
+type VersionService interface {
+	// Edit replaces a clip's content and alternate formats, saving the
+	// current ones as a new version
+	Edit(ctx context.Context, id string, content []byte, formats map[string][]byte) (*types.Clip, error)
+
+	// ListVersions returns the earlier contents of a clip, oldest first
+	ListVersions(ctx context.Context, id string) ([]ClipVersion, error)
+
+	// Revert restores the content of a version. The current content is saved
+	// as a new version first, so a revert can itself be undone.
+	Revert(ctx context.Context, id string, version int) (*types.Clip, error)
+}
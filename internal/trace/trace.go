@@ -0,0 +1,79 @@
+// Package trace follows API requests through the service and storage. The
+// request's ID travels in its context, log lines written on its behalf carry
+// the ID, and spans time operations so slow ones can be matched with the
+// request that ran them.
+package trace
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultSlowThreshold is how long an operation may take before it is logged
+const DefaultSlowThreshold = 500 * time.Millisecond
+
+var slowThreshold atomic.Int64
+
+func init() {
+	slowThreshold.Store(int64(DefaultSlowThreshold))
+}
+
+// SetSlowThreshold sets how long an operation may take before it is logged.
+// 0 turns slow operation logging off.
+func SetSlowThreshold(d time.Duration) {
+	slowThreshold.Store(int64(d))
+}
+
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of ctx carrying the ID of the request it serves
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the ID of the request ctx serves, or "" for work the
+// daemon does on its own, such as storing captured clips
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// Logf logs like log.Printf, prefixed with the request ID the way the
+// request log shows it
+func Logf(ctx context.Context, format string, args ...interface{}) {
+	logf(ctx, 3, format, args...)
+}
+
+// logf logs for the caller depth frames up, so the log shows its file and line
+func logf(ctx context.Context, depth int, format string, args ...interface{}) {
+	if id := RequestID(ctx); id != "" {
+		format = "[%s] " + format
+		args = append([]interface{}{id}, args...)
+	}
+	log.Output(depth, fmt.Sprintf(format, args...))
+}
+
+// Span times one operation, such as a search
+type Span struct {
+	ctx   context.Context
+	name  string
+	start time.Time
+}
+
+// Start starts timing the operation name done for the request in ctx
+func Start(ctx context.Context, name string) *Span {
+	return &Span{ctx: ctx, name: name, start: time.Now()}
+}
+
+// End stops timing the operation, logging it if it was slow, and returns how
+// long it took
+func (s *Span) End() time.Duration {
+	elapsed := time.Since(s.start)
+	if threshold := time.Duration(slowThreshold.Load()); threshold > 0 && elapsed >= threshold {
+		logf(s.ctx, 3, "[SLOW] %s took %s", s.name, elapsed.Round(time.Millisecond))
+	}
+	return elapsed
+}
@@ -0,0 +1,68 @@
+package trace
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"strings"
+	"testing"
+	"time"
+)
+
+func captureLog(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	out, flags := log.Writer(), log.Flags()
+	log.SetOutput(&buf)
+	log.SetFlags(0)
+	t.Cleanup(func() {
+		log.SetOutput(out)
+		log.SetFlags(flags)
+	})
+	return &buf
+}
+
+func TestLogf(t *testing.T) {
+	buf := captureLog(t)
+
+	Logf(context.Background(), "no request")
+	ctx := WithRequestID(context.Background(), "host/abc-000001")
+	Logf(ctx, "clip %d", 7)
+
+	want := "no request\n[host/abc-000001] clip 7\n"
+	if buf.String() != want {
+		t.Errorf("log = %q, want %q", buf.String(), want)
+	}
+	if got := RequestID(ctx); got != "host/abc-000001" {
+		t.Errorf("RequestID = %q", got)
+	}
+}
+
+func TestSpan_LogsSlowOperations(t *testing.T) {
+	buf := captureLog(t)
+	t.Cleanup(func() { SetSlowThreshold(DefaultSlowThreshold) })
+	ctx := WithRequestID(context.Background(), "req-1")
+
+	SetSlowThreshold(time.Hour)
+	if elapsed := Start(ctx, "search").End(); elapsed <= 0 {
+		t.Errorf("End = %v, want a positive duration", elapsed)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("fast operation was logged: %q", buf.String())
+	}
+
+	SetSlowThreshold(time.Nanosecond)
+	span := Start(ctx, "search")
+	time.Sleep(time.Millisecond)
+	span.End()
+	if !strings.HasPrefix(buf.String(), "[req-1] [SLOW] search took ") {
+		t.Errorf("slow operation log = %q", buf.String())
+	}
+
+	buf.Reset()
+	SetSlowThreshold(0)
+	Start(ctx, "search").End()
+	if buf.Len() != 0 {
+		t.Errorf("operation was logged with the threshold off: %q", buf.String())
+	}
+}
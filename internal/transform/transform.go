@@ -0,0 +1,250 @@
+// Package transform reformats and extracts from structured text clips, such
+// as pretty-printing JSON or converting YAML to JSON
+package transform
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Operations
+const (
+	JSONPretty = "json-pretty" // Indent JSON
+	JSONMinify = "json-minify" // Remove insignificant whitespace from JSON
+	YAMLToJSON = "yaml-to-json"
+	JSONToYAML = "json-to-yaml"
+	JSONPath   = "json-path" // Extract the value at a jq-style path such as .items[0].name
+)
+
+// Ops lists every operation
+var Ops = []string{JSONPretty, JSONMinify, YAMLToJSON, JSONToYAML, JSONPath}
+
+var (
+	ErrUnknownOp   = errors.New("unknown transform") // The operation is not in Ops
+	ErrInvalidPath = errors.New("invalid path")      // The JSONPath path can't be parsed
+)
+
+// InputError reports input the operation can't read, such as invalid JSON
+type InputError struct {
+	Line, Column int // Zero when unknown
+	Err          error
+}
+
+func (e *InputError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("line %d, column %d: %v", e.Line, e.Column, e.Err)
+	}
+	return e.Err.Error()
+}
+
+func (e *InputError) Unwrap() error {
+	return e.Err
+}
+
+// Apply runs op on input. Path is the path JSONPath extracts and is ignored
+// by the other operations.
+func Apply(op, path string, input []byte) ([]byte, error) {
+	input = bytes.TrimSpace(input)
+	switch op {
+	case JSONPretty:
+		var out bytes.Buffer
+		if err := json.Indent(&out, input, "", "  "); err != nil {
+			return nil, jsonError(input, err)
+		}
+		return out.Bytes(), nil
+	case JSONMinify:
+		var out bytes.Buffer
+		if err := json.Compact(&out, input); err != nil {
+			return nil, jsonError(input, err)
+		}
+		return out.Bytes(), nil
+	case YAMLToJSON:
+		var value interface{}
+		if err := yaml.Unmarshal(input, &value); err != nil {
+			return nil, &InputError{Err: err}
+		}
+		value, err := jsonValue(value)
+		if err != nil {
+			return nil, &InputError{Err: err}
+		}
+		return json.MarshalIndent(value, "", "  ")
+	case JSONToYAML:
+		value, err := decodeJSON(input)
+		if err != nil {
+			return nil, err
+		}
+		return yaml.Marshal(yamlValue(value))
+	case JSONPath:
+		value, err := decodeJSON(input)
+		if err != nil {
+			return nil, err
+		}
+		found, err := extract(value, path)
+		if err != nil {
+			return nil, err
+		}
+		// Strings come out raw, like jq -r, ready to paste
+		if s, ok := found.(string); ok {
+			return []byte(s), nil
+		}
+		return json.MarshalIndent(found, "", "  ")
+	}
+	return nil, fmt.Errorf("%w %q, expected one of %s", ErrUnknownOp, op, strings.Join(Ops, ", "))
+}
+
+// decodeJSON decodes input keeping numbers as they were written
+func decodeJSON(input []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(input))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, jsonError(input, err)
+	}
+	if decoder.More() {
+		return nil, &InputError{Err: errors.New("unexpected content after the JSON value")}
+	}
+	return value, nil
+}
+
+// jsonError locates a JSON syntax error in input
+func jsonError(input []byte, err error) error {
+	var syntaxErr *json.SyntaxError
+	if !errors.As(err, &syntaxErr) {
+		return &InputError{Err: err}
+	}
+	// Offset counts the byte the error was found at
+	before := input[:min(max(int(syntaxErr.Offset)-1, 0), len(input))]
+	line := bytes.Count(before, []byte("\n")) + 1
+	column := len(before) - bytes.LastIndexByte(before, '\n')
+	return &InputError{Line: line, Column: column, Err: err}
+}
+
+// jsonValue converts decoded YAML to values encoding/json can marshal,
+// turning the keys of mappings into strings
+func jsonValue(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			converted, err := jsonValue(item)
+			if err != nil {
+				return nil, err
+			}
+			v[key] = converted
+		}
+		return v, nil
+	case map[interface{}]interface{}:
+		converted := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			item, err := jsonValue(item)
+			if err != nil {
+				return nil, err
+			}
+			converted[fmt.Sprint(key)] = item
+		}
+		return converted, nil
+	case []interface{}:
+		for i, item := range v {
+			converted, err := jsonValue(item)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = converted
+		}
+		return v, nil
+	}
+	return value, nil
+}
+
+// yamlValue converts decoded JSON for YAML, writing numbers as they were
+// written rather than as strings
+func yamlValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		tag := "!!int"
+		if strings.ContainsAny(v.String(), ".eE") {
+			tag = "!!float"
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: v.String()}
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = yamlValue(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = yamlValue(item)
+		}
+	}
+	return value
+}
+
+// extract returns the value at path, made of .key, ["key"] and [index]
+// steps. An empty path or "." is the whole value.
+func extract(value interface{}, path string) (interface{}, error) {
+	rest := strings.TrimSpace(path)
+	walked := ""
+	for rest != "" && rest != "." {
+		var key string
+		index := -1
+		switch {
+		case strings.HasPrefix(rest, `["`):
+			end := strings.Index(rest, `"]`)
+			if end < 0 {
+				return nil, fmt.Errorf("%w %q: unterminated [\"", ErrInvalidPath, path)
+			}
+			key, rest = rest[2:end], rest[end+2:]
+		case strings.HasPrefix(rest, "["):
+			end := strings.Index(rest, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("%w %q: unterminated [", ErrInvalidPath, path)
+			}
+			n, err := strconv.Atoi(rest[1:end])
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("%w %q: bad index %q", ErrInvalidPath, path, rest[1:end])
+			}
+			index, rest = n, rest[end+1:]
+		case strings.HasPrefix(rest, "."):
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			key, rest = rest[:end], rest[end:]
+			if key == "" {
+				if strings.HasPrefix(rest, "[") {
+					continue
+				}
+				return nil, fmt.Errorf("%w %q: empty key", ErrInvalidPath, path)
+			}
+		default:
+			return nil, fmt.Errorf("%w %q: expected . or [ at %q", ErrInvalidPath, path, rest)
+		}
+
+		if index >= 0 {
+			walked += fmt.Sprintf("[%d]", index)
+			list, ok := value.([]interface{})
+			if !ok {
+				return nil, &InputError{Err: fmt.Errorf("%s is not an array", walked)}
+			}
+			if index >= len(list) {
+				return nil, &InputError{Err: fmt.Errorf("%s is past the end of the array", walked)}
+			}
+			value = list[index]
+			continue
+		}
+		walked += "." + key
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, &InputError{Err: fmt.Errorf("%s: parent is not an object", walked)}
+		}
+		if value, ok = object[key]; !ok {
+			return nil, &InputError{Err: fmt.Errorf("%s not found", walked)}
+		}
+	}
+	return value, nil
+}
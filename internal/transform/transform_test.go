@@ -0,0 +1,57 @@
+package transform
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		op, path, input, want string
+	}{
+		{JSONPretty, "", `{"a":[1,2],"b":{}}`, "{\n  \"a\": [\n    1,\n    2\n  ],\n  \"b\": {}\n}"},
+		{JSONMinify, "", "{\n  \"a\": [1, 2],\n  \"b\": \"x y\"\n}\n", `{"a":[1,2],"b":"x y"}`},
+		{YAMLToJSON, "", "name: rockstar\ntags:\n  - go\n  - cli\n1: one\n", "{\n  \"1\": \"one\",\n  \"name\": \"rockstar\",\n  \"tags\": [\n    \"go\",\n    \"cli\"\n  ]\n}"},
+		{JSONToYAML, "", `{"name":"rockstar","port":54321,"ratio":0.5}`, "name: rockstar\nport: 54321\nratio: 0.5\n"},
+		{JSONPath, ".items[1].name", `{"items":[{"name":"a"},{"name":"b"}]}`, "b"},
+		{JSONPath, `.["a key"].n`, `{"a key":{"n":12345678901234567890}}`, "12345678901234567890"},
+		{JSONPath, ".items[0]", `{"items":[{"id":1}]}`, "{\n  \"id\": 1\n}"},
+		{JSONPath, ".", `[true]`, "[\n  true\n]"},
+		{JSONPath, "[0][1]", `[[1,2]]`, "2"},
+	}
+	for _, tt := range tests {
+		got, err := Apply(tt.op, tt.path, []byte(tt.input))
+		if err != nil {
+			t.Errorf("Apply(%s, %q) error: %v", tt.op, tt.path, err)
+			continue
+		}
+		if string(got) != tt.want {
+			t.Errorf("Apply(%s, %q) = %q, want %q", tt.op, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestApply_Errors(t *testing.T) {
+	if _, err := Apply("xml-pretty", "", []byte("<a/>")); !errors.Is(err, ErrUnknownOp) {
+		t.Errorf("unknown op error = %v, want ErrUnknownOp", err)
+	}
+	if _, err := Apply(JSONPath, "items", []byte("{}")); !errors.Is(err, ErrInvalidPath) {
+		t.Errorf("bad path error = %v, want ErrInvalidPath", err)
+	}
+
+	_, err := Apply(JSONPretty, "", []byte("{\n  \"a\": 1,\n  \"b\": \n}"))
+	var inputErr *InputError
+	if !errors.As(err, &inputErr) {
+		t.Fatalf("invalid JSON error = %v, want an InputError", err)
+	}
+	if inputErr.Line != 4 || inputErr.Column != 1 {
+		t.Errorf("invalid JSON located at %d:%d, want 4:1", inputErr.Line, inputErr.Column)
+	}
+
+	for _, path := range []string{".missing", ".a[0]", ".a.b", ".list[3]"} {
+		_, err := Apply(JSONPath, path, []byte(`{"a":1,"list":[]}`))
+		if !errors.As(err, &inputErr) {
+			t.Errorf("path %s error = %v, want an InputError", path, err)
+		}
+	}
+}
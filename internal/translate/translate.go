@@ -0,0 +1,206 @@
+// Package translate translates text through LibreTranslate, such as a
+// server running locally, or DeepL
+package translate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// Backends
+const (
+	LibreTranslate = "libretranslate"
+	DeepL          = "deepl"
+)
+
+const (
+	// DefaultLibreTranslateURL is where a local LibreTranslate listens
+	DefaultLibreTranslateURL = "http://localhost:5000"
+
+	// DeepL's APIs, free keys end in :fx and use DeepLFreeAPI
+	DeepLAPI     = "https://api.deepl.com"
+	DeepLFreeAPI = "https://api-free.deepl.com"
+
+	// DefaultTarget is the language text is translated into by default
+	DefaultTarget = "en"
+
+	// MaxTextSize is the most text translated at once, DeepL's request limit
+	MaxTextSize = 128 * 1024
+
+	maxResponseSize = 4 << 20
+)
+
+var (
+	ErrUnknownBackend  = errors.New("unknown translation backend, expected libretranslate or deepl")
+	ErrNotConfigured   = errors.New("translation is not configured")
+	ErrInvalidLanguage = errors.New("invalid language, expected a code such as de or pt-BR")
+	ErrTooLong         = fmt.Errorf("text is over the %d KB translation limit", MaxTextSize/1024)
+	ErrSensitive       = errors.New("sensitive clips are never translated")
+	ErrTranslate       = errors.New("translation failed")
+)
+
+// language matches language codes such as en, pt-BR or zh-Hans
+var language = regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+
+// ValidLanguage reports whether code looks like a language code
+func ValidLanguage(code string) bool {
+	return language.MatchString(code)
+}
+
+// SameLanguage reports whether a and b are the same language, ignoring
+// case and regional variants, so en and EN-US match
+func SameLanguage(a, b string) bool {
+	primary := func(code string) string {
+		code, _, _ = strings.Cut(strings.ToLower(code), "-")
+		return code
+	}
+	return a != "" && b != "" && primary(a) == primary(b)
+}
+
+// Result is translated text
+type Result struct {
+	Text   string
+	Source string // Language of the original in lower case, when detected
+}
+
+// Translator translates text through a backend
+type Translator struct {
+	Client  *http.Client
+	Backend string // LibreTranslate or DeepL; "" is LibreTranslate
+	URL     string // "" uses DefaultLibreTranslateURL, or DeepL's API for the key
+	APIKey  string // Optional for LibreTranslate, required for DeepL
+}
+
+// Translate translates text into target, detecting its language
+func (t Translator) Translate(ctx context.Context, text, target string) (Result, error) {
+	if !ValidLanguage(target) {
+		return Result{}, fmt.Errorf("%w: %q", ErrInvalidLanguage, target)
+	}
+	if len(text) > MaxTextSize {
+		return Result{}, ErrTooLong
+	}
+	switch t.Backend {
+	case LibreTranslate, "":
+		return t.libreTranslate(ctx, text, target)
+	case DeepL:
+		return t.deepL(ctx, text, target)
+	}
+	return Result{}, fmt.Errorf("%w: %q", ErrUnknownBackend, t.Backend)
+}
+
+func (t Translator) libreTranslate(ctx context.Context, text, target string) (Result, error) {
+	endpoint := t.URL
+	if endpoint == "" {
+		endpoint = DefaultLibreTranslateURL
+	}
+	body := map[string]string{"q": text, "source": "auto", "target": strings.ToLower(target), "format": "text"}
+	if t.APIKey != "" {
+		body["api_key"] = t.APIKey
+	}
+	var response struct {
+		TranslatedText   string `json:"translatedText"`
+		DetectedLanguage struct {
+			Language string `json:"language"`
+		} `json:"detectedLanguage"`
+	}
+	if err := t.post(ctx, strings.TrimRight(endpoint, "/")+"/translate", nil, body, &response); err != nil {
+		return Result{}, err
+	}
+	return Result{Text: response.TranslatedText, Source: strings.ToLower(response.DetectedLanguage.Language)}, nil
+}
+
+func (t Translator) deepL(ctx context.Context, text, target string) (Result, error) {
+	if t.APIKey == "" {
+		return Result{}, fmt.Errorf("%w: set translate.api_key to a DeepL authentication key", ErrNotConfigured)
+	}
+	endpoint := t.URL
+	switch {
+	case endpoint != "":
+	case strings.HasSuffix(t.APIKey, ":fx"):
+		endpoint = DeepLFreeAPI
+	default:
+		endpoint = DeepLAPI
+	}
+	body := map[string]any{"text": []string{text}, "target_lang": strings.ToUpper(target)}
+	header := http.Header{"Authorization": {"DeepL-Auth-Key " + t.APIKey}}
+	var response struct {
+		Translations []struct {
+			Text             string `json:"text"`
+			DetectedLanguage string `json:"detected_source_language"`
+		} `json:"translations"`
+	}
+	if err := t.post(ctx, strings.TrimRight(endpoint, "/")+"/v2/translate", header, body, &response); err != nil {
+		return Result{}, err
+	}
+	if len(response.Translations) == 0 {
+		return Result{}, fmt.Errorf("%w: DeepL returned no translation", ErrTranslate)
+	}
+	translation := response.Translations[0]
+	return Result{Text: translation.Text, Source: strings.ToLower(translation.DetectedLanguage)}, nil
+}
+
+// post posts body as JSON and decodes the response into result
+func (t Translator) post(ctx context.Context, endpoint string, header http.Header, body, result any) error {
+	encoded, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(encoded))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrTranslate, err)
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := t.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrTranslate, err)
+	}
+	defer resp.Body.Close()
+	response, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrTranslate, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%w: %s returned %s: %s", ErrTranslate, req.URL.Host, resp.Status, errorMessage(response))
+	}
+	if err := json.Unmarshal(response, result); err != nil {
+		return fmt.Errorf("%w: invalid response from %s", ErrTranslate, req.URL.Host)
+	}
+	return nil
+}
+
+// errorMessage is the message in an error response: LibreTranslate's error
+// field, DeepL's message field, or the start of the body
+func errorMessage(body []byte) string {
+	var failure struct {
+		Error   string `json:"error"`
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(body, &failure) == nil {
+		if failure.Error != "" {
+			return failure.Error
+		}
+		if failure.Message != "" {
+			return failure.Message
+		}
+	}
+	message := strings.TrimSpace(string(body))
+	if len(message) > 200 {
+		message = message[:200]
+	}
+	return message
+}
@@ -0,0 +1,106 @@
+package translate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTranslate_LibreTranslate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]string
+		json.NewDecoder(r.Body).Decode(&req)
+		if r.URL.Path != "/translate" || req["source"] != "auto" || req["target"] != "de" || req["api_key"] != "secret" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error": "bad request"}`))
+			return
+		}
+		w.Write([]byte(`{"translatedText": "Hallo", "detectedLanguage": {"confidence": 90, "language": "en"}}`))
+	}))
+	defer server.Close()
+
+	translator := Translator{Client: server.Client(), URL: server.URL + "/", APIKey: "secret"}
+	result, err := translator.Translate(context.Background(), "Hello", "DE")
+	if err != nil {
+		t.Fatalf("Translate() error = %v", err)
+	}
+	if result.Text != "Hallo" || result.Source != "en" {
+		t.Errorf("Translate() = %+v", result)
+	}
+
+	translator.APIKey = ""
+	_, err = translator.Translate(context.Background(), "Hello", "de")
+	if !errors.Is(err, ErrTranslate) || !strings.Contains(err.Error(), "bad request") {
+		t.Errorf("expected the server's error, got %v", err)
+	}
+}
+
+func TestTranslate_DeepL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Text       []string `json:"text"`
+			TargetLang string   `json:"target_lang"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if r.Header.Get("Authorization") != "DeepL-Auth-Key key:fx" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"message": "Wrong key"}`))
+			return
+		}
+		if r.URL.Path != "/v2/translate" || len(req.Text) != 1 || req.TargetLang != "EN-US" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"translations": [{"detected_source_language": "DE", "text": "Hello"}]}`))
+	}))
+	defer server.Close()
+
+	translator := Translator{Client: server.Client(), Backend: DeepL, URL: server.URL, APIKey: "key:fx"}
+	result, err := translator.Translate(context.Background(), "Hallo", "en-us")
+	if err != nil || result.Text != "Hello" || result.Source != "de" {
+		t.Errorf("Translate() = %+v, %v", result, err)
+	}
+
+	translator.APIKey = "wrong"
+	if _, err := translator.Translate(context.Background(), "Hallo", "en"); !errors.Is(err, ErrTranslate) || !strings.Contains(err.Error(), "Wrong key") {
+		t.Errorf("expected DeepL's error, got %v", err)
+	}
+	translator.APIKey = ""
+	if _, err := translator.Translate(context.Background(), "Hallo", "en"); !errors.Is(err, ErrNotConfigured) {
+		t.Errorf("expected ErrNotConfigured without a key, got %v", err)
+	}
+}
+
+func TestTranslate_Errors(t *testing.T) {
+	ctx := context.Background()
+	if _, err := (Translator{}).Translate(ctx, "text", "not a language"); !errors.Is(err, ErrInvalidLanguage) {
+		t.Errorf("expected ErrInvalidLanguage, got %v", err)
+	}
+	if _, err := (Translator{}).Translate(ctx, strings.Repeat("a", MaxTextSize+1), "de"); !errors.Is(err, ErrTooLong) {
+		t.Errorf("expected ErrTooLong, got %v", err)
+	}
+	if _, err := (Translator{Backend: "babelfish"}).Translate(ctx, "text", "de"); !errors.Is(err, ErrUnknownBackend) {
+		t.Errorf("expected ErrUnknownBackend, got %v", err)
+	}
+}
+
+func TestSameLanguage(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"en", "EN-US", true},
+		{"pt-br", "pt-PT", true},
+		{"de", "en", false},
+		{"", "en", false},
+	}
+	for _, tt := range tests {
+		if got := SameLanguage(tt.a, tt.b); got != tt.want {
+			t.Errorf("SameLanguage(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
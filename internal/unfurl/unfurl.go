@@ -0,0 +1,170 @@
+// Package unfurl fetches the title, description and icon of web pages that
+// were copied as links
+package unfurl
+
+import (
+	"clipboard-manager/pkg/types"
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// Timeout bounds fetching a page and its icon
+	Timeout = 10 * time.Second
+
+	maxPageSize    = 512 * 1024 // The head of a page is near its start
+	maxFaviconSize = 64 * 1024
+	maxTextLength  = 300
+)
+
+var (
+	headEndPattern = regexp.MustCompile(`(?i)</head\s*>`)
+	titlePattern   = regexp.MustCompile(`(?is)<title\b[^>]*>(.*?)</title\s*>`)
+	metaPattern    = regexp.MustCompile(`(?is)<meta\b[^>]*>`)
+	linkPattern    = regexp.MustCompile(`(?is)<link\b[^>]*>`)
+	attrPattern    = regexp.MustCompile(`(?is)([a-z:-]+)\s*=\s*("[^"]*"|'[^']*'|[^\s>]+)`)
+)
+
+// IsLink reports whether text is a single web link
+func IsLink(text string) bool {
+	text = strings.TrimSpace(text)
+	if text == "" || strings.ContainsAny(text, " \t\n") {
+		return false
+	}
+	u, err := url.Parse(text)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// Fetch downloads the page at rawURL and returns its preview. Open Graph
+// titles and descriptions are preferred over the page's own.
+func Fetch(ctx context.Context, client *http.Client, rawURL string) (*types.LinkPreview, error) {
+	page, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return nil, fmt.Errorf("invalid link: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	body, contentType, err := get(ctx, client, page.String(), maxPageSize)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.Contains(contentType, "html") {
+		return nil, fmt.Errorf("%s is %s, not a web page", page, contentType)
+	}
+
+	preview := parse(string(body))
+	if preview.Title == "" && preview.Description == "" {
+		return nil, fmt.Errorf("%s has no title", page)
+	}
+
+	// Fall back to the conventional location when the page names no icon
+	iconURL := &url.URL{Scheme: page.Scheme, Host: page.Host, Path: "/favicon.ico"}
+	if preview.FaviconURL != "" {
+		if ref, err := url.Parse(preview.FaviconURL); err == nil {
+			iconURL = page.ResolveReference(ref)
+		}
+	}
+	preview.FaviconURL = iconURL.String()
+	if icon, iconType, err := get(ctx, client, preview.FaviconURL, maxFaviconSize); err == nil && strings.HasPrefix(iconType, "image/") {
+		preview.Favicon = icon
+		preview.FaviconType = iconType
+	}
+	return preview, nil
+}
+
+// get fetches url, failing if the response is larger than limit
+func get(ctx context.Context, client *http.Client, url string, limit int64) ([]byte, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("User-Agent", "clipboard-manager link preview")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	// Pages are cut off rather than rejected, the head is all that's needed
+	body, err := io.ReadAll(io.LimitReader(resp.Body, limit))
+	if err != nil {
+		return nil, "", err
+	}
+	return body, resp.Header.Get("Content-Type"), nil
+}
+
+// parse reads the preview from the head of a page
+func parse(page string) *types.LinkPreview {
+	if loc := headEndPattern.FindStringIndex(page); loc != nil {
+		page = page[:loc[0]]
+	}
+
+	preview := &types.LinkPreview{}
+	if match := titlePattern.FindStringSubmatch(page); match != nil {
+		preview.Title = clean(match[1])
+	}
+
+	for _, tag := range metaPattern.FindAllString(page, -1) {
+		attrs := attributes(tag)
+		name := strings.ToLower(attrs["property"] + attrs["name"])
+		content := clean(attrs["content"])
+		if content == "" {
+			continue
+		}
+		switch name {
+		case "og:title":
+			preview.Title = content
+		case "og:description":
+			preview.Description = content
+		case "description":
+			if preview.Description == "" {
+				preview.Description = content
+			}
+		}
+	}
+
+	for _, tag := range linkPattern.FindAllString(page, -1) {
+		attrs := attributes(tag)
+		rel := strings.Fields(strings.ToLower(attrs["rel"]))
+		for _, r := range rel {
+			if r == "icon" && attrs["href"] != "" {
+				preview.FaviconURL = html.UnescapeString(attrs["href"])
+				break
+			}
+		}
+		if preview.FaviconURL != "" {
+			break
+		}
+	}
+	return preview
+}
+
+// attributes returns the attributes of an HTML tag by lower case name
+func attributes(tag string) map[string]string {
+	attrs := make(map[string]string)
+	for _, match := range attrPattern.FindAllStringSubmatch(tag, -1) {
+		attrs[strings.ToLower(match[1])] = strings.Trim(match[2], `"'`)
+	}
+	return attrs
+}
+
+// clean unescapes text, collapses its whitespace and shortens it
+func clean(text string) string {
+	text = strings.Join(strings.Fields(html.UnescapeString(text)), " ")
+	if runes := []rune(text); len(runes) > maxTextLength {
+		text = string(runes[:maxTextLength-1]) + "…"
+	}
+	return text
+}
@@ -0,0 +1,64 @@
+package unfurl
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFetch(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/article", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(`<!doctype html><html><head>
+<title>Fallback title</title>
+<meta property="og:title" content="Tom &amp; Jerry">
+<meta name="description" content="  A   classic
+  cartoon ">
+<link rel="shortcut icon" href="/static/icon.png">
+</head><body><title>Not this</title></body></html>`))
+	})
+	mux.HandleFunc("/static/icon.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte("png"))
+	})
+	mux.HandleFunc("/plain", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("<title>text</title>"))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	preview, err := Fetch(context.Background(), server.Client(), server.URL+"/article")
+	if err != nil {
+		t.Fatalf("failed to fetch preview: %v", err)
+	}
+	if preview.Title != "Tom & Jerry" || preview.Description != "A classic cartoon" {
+		t.Errorf("unexpected preview %q, %q", preview.Title, preview.Description)
+	}
+	if preview.FaviconURL != server.URL+"/static/icon.png" || string(preview.Favicon) != "png" || preview.FaviconType != "image/png" {
+		t.Errorf("unexpected favicon %s %q %s", preview.FaviconURL, preview.Favicon, preview.FaviconType)
+	}
+
+	for _, path := range []string{"/plain", "/missing"} {
+		if _, err := Fetch(context.Background(), server.Client(), server.URL+path); err == nil {
+			t.Errorf("expected no preview for %s", path)
+		}
+	}
+}
+
+func TestIsLink(t *testing.T) {
+	for text, want := range map[string]bool{
+		"https://example.com/a?b=c": true,
+		" http://example.com \n":    true,
+		"example.com":               false,
+		"ftp://example.com":         false,
+		"see https://example.com":   false,
+		"https://":                  false,
+	} {
+		if got := IsLink(text); got != want {
+			t.Errorf("IsLink(%q) = %v, want %v", text, got, want)
+		}
+	}
+}
@@ -0,0 +1,338 @@
+// Package update finds newer releases of the clipboard manager on GitHub and
+// replaces the running binary with one, once its checksum and signature
+// check out
+package update
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DefaultRepo is the GitHub repository releases are published to
+	DefaultRepo = "hp77-creator/rockstar"
+	// DefaultAPI is the GitHub API releases are found through
+	DefaultAPI = "https://api.github.com"
+
+	// ChecksumsAsset lists the SHA-256 of every binary in a release, in the
+	// format of sha256sum. ChecksumsAsset + ".sig" is its Ed25519 signature.
+	ChecksumsAsset = "checksums.txt"
+
+	userAgent        = "clipboard-manager"
+	maxMetadataSize  = 1024 * 1024
+	maxSignatureSize = 1024
+)
+
+// PublicKey is the base64 Ed25519 key releases are signed with. Release
+// builds set it with -ldflags "-X clipboard-manager/internal/update.PublicKey=...".
+var PublicKey = ""
+
+var (
+	ErrNoKey       = errors.New("this build has no release signing key, so updates can't be verified; download releases by hand")
+	ErrNoAsset     = errors.New("the release has no binary for this platform")
+	ErrChecksum    = errors.New("checksum mismatch")
+	ErrSignature   = errors.New("invalid release signature")
+	ErrUnavailable = errors.New("failed to reach GitHub")
+)
+
+// Release is a published release
+type Release struct {
+	Version string  `json:"tag_name"`
+	URL     string  `json:"html_url"`
+	Assets  []Asset `json:"assets"`
+}
+
+// Asset is a file attached to a release
+type Asset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+// asset returns the asset called name
+func (r *Release) asset(name string) (Asset, bool) {
+	for _, asset := range r.Assets {
+		if asset.Name == name {
+			return asset, true
+		}
+	}
+	return Asset{}, false
+}
+
+// AssetName is the name of the binary released for goos and goarch, such as
+// clipboard-manager-darwin-arm64
+func AssetName(goos, goarch string) string {
+	name := "clipboard-manager-" + goos + "-" + goarch
+	if goos == "windows" {
+		name += ".exe"
+	}
+	return name
+}
+
+// Updater finds and installs releases
+type Updater struct {
+	Client    *http.Client // nil uses http.DefaultClient
+	API       string       // "" uses DefaultAPI
+	Repo      string       // "" uses DefaultRepo
+	PublicKey string       // "" uses PublicKey
+}
+
+// Latest returns the latest release
+func (u Updater) Latest(ctx context.Context) (*Release, error) {
+	api, repo := u.API, u.Repo
+	if api == "" {
+		api = DefaultAPI
+	}
+	if repo == "" {
+		repo = DefaultRepo
+	}
+
+	body, err := u.get(ctx, strings.TrimRight(api, "/")+"/repos/"+repo+"/releases/latest", "application/vnd.github+json", maxMetadataSize)
+	if err != nil {
+		return nil, err
+	}
+	var release Release
+	if err := json.Unmarshal(body, &release); err != nil || release.Version == "" {
+		return nil, fmt.Errorf("%w: GitHub returned no release", ErrUnavailable)
+	}
+	return &release, nil
+}
+
+// Install downloads the binary of release for this platform, checks it
+// against the signed checksums and swaps it in for the binary at exe. The
+// running program carries on as it was; it has to be restarted to update.
+func (u Updater) Install(ctx context.Context, release *Release, exe string) error {
+	key, err := u.publicKey()
+	if err != nil {
+		return err
+	}
+
+	name := AssetName(runtime.GOOS, runtime.GOARCH)
+	binary, ok := release.asset(name)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNoAsset, name)
+	}
+	checksums, ok := release.asset(ChecksumsAsset)
+	if !ok {
+		return fmt.Errorf("%w: %s is missing", ErrSignature, ChecksumsAsset)
+	}
+	signature, ok := release.asset(ChecksumsAsset + ".sig")
+	if !ok {
+		return fmt.Errorf("%w: %s.sig is missing", ErrSignature, ChecksumsAsset)
+	}
+
+	sums, err := u.get(ctx, checksums.URL, "", maxMetadataSize)
+	if err != nil {
+		return err
+	}
+	sig, err := u.get(ctx, signature.URL, "", maxSignatureSize)
+	if err != nil {
+		return err
+	}
+	if err := Verify(key, sums, sig); err != nil {
+		return err
+	}
+	want, err := checksum(sums, name)
+	if err != nil {
+		return err
+	}
+
+	return u.replace(ctx, binary.URL, want, exe)
+}
+
+// replace downloads url next to exe, checks it hashes to want and renames it
+// over exe, which is atomic within a directory
+func (u Updater) replace(ctx context.Context, url string, want []byte, exe string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(exe), "."+filepath.Base(exe)+".update-*")
+	if err != nil {
+		return fmt.Errorf("failed to create the new binary next to %s: %w", exe, err)
+	}
+	defer os.Remove(tmp.Name()) // Gone already once renamed
+
+	resp, err := u.request(ctx, url, "")
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	defer resp.Body.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tmp, hash), resp.Body); err != nil {
+		tmp.Close()
+		return fmt.Errorf("%w: download failed: %v", ErrUnavailable, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write the new binary: %w", err)
+	}
+	if got := hash.Sum(nil); !bytes.Equal(got, want) {
+		return fmt.Errorf("%w: downloaded %x, release lists %x", ErrChecksum, got, want)
+	}
+
+	mode := os.FileMode(0755)
+	if info, err := os.Stat(exe); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return fmt.Errorf("failed to make the new binary executable: %w", err)
+	}
+	if runtime.GOOS == "windows" {
+		// Windows won't replace a running binary, but will rename it
+		old := exe + ".old"
+		os.Remove(old)
+		if err := os.Rename(exe, old); err != nil {
+			return fmt.Errorf("failed to move %s aside: %w", exe, err)
+		}
+	}
+	if err := os.Rename(tmp.Name(), exe); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", exe, err)
+	}
+	return nil
+}
+
+// Verify checks that sig, raw or base64, is key's signature of checksums
+func Verify(key ed25519.PublicKey, checksums, sig []byte) error {
+	if len(sig) != ed25519.SignatureSize {
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sig)))
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrSignature, err)
+		}
+		sig = decoded
+	}
+	if !ed25519.Verify(key, checksums, sig) {
+		return ErrSignature
+	}
+	return nil
+}
+
+// checksum finds the SHA-256 of name in sha256sum output
+func checksum(sums []byte, name string) ([]byte, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(sums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 || strings.TrimPrefix(fields[1], "*") != name {
+			continue
+		}
+		sum, err := hex.DecodeString(fields[0])
+		if err != nil || len(sum) != sha256.Size {
+			return nil, fmt.Errorf("%w: %s has an invalid checksum for %s", ErrChecksum, ChecksumsAsset, name)
+		}
+		return sum, nil
+	}
+	return nil, fmt.Errorf("%w: %s has no checksum for %s", ErrChecksum, ChecksumsAsset, name)
+}
+
+// publicKey decodes the key releases are checked against
+func (u Updater) publicKey() (ed25519.PublicKey, error) {
+	encoded := u.PublicKey
+	if encoded == "" {
+		encoded = PublicKey
+	}
+	if encoded == "" {
+		return nil, ErrNoKey
+	}
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid release signing key %q", encoded)
+	}
+	return ed25519.PublicKey(key), nil
+}
+
+// get fetches url, refusing responses over limit bytes
+func (u Updater) get(ctx context.Context, url, accept string, limit int64) ([]byte, error) {
+	resp, err := u.request(ctx, url, accept)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	if int64(len(body)) > limit {
+		return nil, fmt.Errorf("%w: %s is over %d bytes", ErrUnavailable, url, limit)
+	}
+	return body, nil
+}
+
+// request sends a GET to url, failing unless it's answered with 200
+func (u Updater) request(ctx context.Context, url, accept string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	req.Header.Set("User-Agent", userAgent)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+
+	client := u.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%w: %s answered %s", ErrUnavailable, url, resp.Status)
+	}
+	return resp, nil
+}
+
+// Newer reports whether version latest is newer than current. Versions are
+// compared as vMAJOR.MINOR.PATCH; a development build, whose version isn't
+// one, is older than any release.
+func Newer(latest, current string) bool {
+	l, ok := parseVersion(latest)
+	if !ok {
+		return false
+	}
+	c, ok := parseVersion(current)
+	if !ok {
+		return true
+	}
+	for i := range l {
+		if l[i] != c[i] {
+			return l[i] > c[i]
+		}
+	}
+	return false
+}
+
+// parseVersion splits a version such as v1.2.3 into its numbers, ignoring
+// any pre-release or build suffix
+func parseVersion(version string) ([3]int, bool) {
+	var parts [3]int
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+	if i := strings.IndexAny(version, "-+"); i >= 0 {
+		version = version[:i]
+	}
+	fields := strings.Split(version, ".")
+	if len(fields) == 0 || len(fields) > 3 {
+		return parts, false
+	}
+	for i, field := range fields {
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 0 {
+			return parts, false
+		}
+		parts[i] = n
+	}
+	return parts, true
+}
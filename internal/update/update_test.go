@@ -0,0 +1,137 @@
+package update
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// newRelease serves a release of binary whose checksums are signed with
+// signer, and returns an updater that checks them against key
+func newRelease(t *testing.T, binary []byte, signer ed25519.PrivateKey, key ed25519.PublicKey) Updater {
+	t.Helper()
+	name := AssetName(runtime.GOOS, runtime.GOARCH)
+	sums := []byte(fmt.Sprintf("%x  %s\n%x  other\n", sha256.Sum256(binary), name, sha256.Sum256(nil)))
+	sig := base64.StdEncoding.EncodeToString(ed25519.Sign(signer, sums))
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/" + DefaultRepo + "/releases/latest":
+			json.NewEncoder(w).Encode(Release{Version: "v1.2.0", Assets: []Asset{
+				{Name: name, URL: server.URL + "/binary"},
+				{Name: ChecksumsAsset, URL: server.URL + "/sums"},
+				{Name: ChecksumsAsset + ".sig", URL: server.URL + "/sig"},
+			}})
+		case "/binary":
+			w.Write([]byte("new binary"))
+		case "/sums":
+			w.Write(sums)
+		case "/sig":
+			w.Write([]byte(sig))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return Updater{Client: server.Client(), API: server.URL, PublicKey: base64.StdEncoding.EncodeToString(key)}
+}
+
+func TestInstall(t *testing.T) {
+	key, signer, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, otherSigner, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		served  []byte // What the checksums are taken from; the server sends "new binary"
+		signer  ed25519.PrivateKey
+		key     ed25519.PublicKey
+		wantErr error
+	}{
+		{name: "valid", served: []byte("new binary"), signer: signer, key: key},
+		{name: "checksum mismatch", served: []byte("another binary"), signer: signer, key: key, wantErr: ErrChecksum},
+		{name: "signed by another key", served: []byte("new binary"), signer: otherSigner, key: key, wantErr: ErrSignature},
+		{name: "checked against another key", served: []byte("new binary"), signer: signer, key: otherKey, wantErr: ErrSignature},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updater := newRelease(t, tt.served, tt.signer, tt.key)
+			exe := filepath.Join(t.TempDir(), "clipboard-manager")
+			if err := os.WriteFile(exe, []byte("old binary"), 0755); err != nil {
+				t.Fatal(err)
+			}
+
+			release, err := updater.Latest(context.Background())
+			if err != nil {
+				t.Fatalf("Latest() error = %v", err)
+			}
+			if release.Version != "v1.2.0" {
+				t.Errorf("Latest() version = %q, want v1.2.0", release.Version)
+			}
+
+			err = updater.Install(context.Background(), release, exe)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Install() error = %v, want %v", err, tt.wantErr)
+			}
+
+			content, _ := os.ReadFile(exe)
+			want := "new binary"
+			if tt.wantErr != nil {
+				want = "old binary"
+			}
+			if string(content) != want {
+				t.Errorf("binary = %q, want %q", content, want)
+			}
+			entries, _ := os.ReadDir(filepath.Dir(exe))
+			if len(entries) != 1 {
+				t.Errorf("left %d files next to the binary, want only the binary", len(entries))
+			}
+		})
+	}
+}
+
+func TestInstall_NoKey(t *testing.T) {
+	err := Updater{}.Install(context.Background(), &Release{}, "unused")
+	if !errors.Is(err, ErrNoKey) {
+		t.Errorf("Install() error = %v, want ErrNoKey", err)
+	}
+}
+
+func TestNewer(t *testing.T) {
+	tests := []struct {
+		latest, current string
+		want            bool
+	}{
+		{"v1.2.0", "v1.1.9", true},
+		{"v1.10.0", "v1.9.0", true},
+		{"v2", "v1.9.9", true},
+		{"v1.2.0", "v1.2.0", false},
+		{"v1.2.0", "v1.3.0", false},
+		{"v1.2.0", "dev", true},
+		{"v1.2.1-rc.1", "v1.2.0", true},
+		{"nightly", "v1.2.0", false},
+	}
+	for _, tt := range tests {
+		if got := Newer(tt.latest, tt.current); got != tt.want {
+			t.Errorf("Newer(%q, %q) = %v, want %v", tt.latest, tt.current, got, tt.want)
+		}
+	}
+}
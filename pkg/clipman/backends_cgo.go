@@ -0,0 +1,6 @@
+//go:build cgo
+
+package clipman
+
+// The sqlite backends require CGO
+import _ "clipboard-manager/internal/storage/sqlite"
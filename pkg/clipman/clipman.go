@@ -0,0 +1,103 @@
+// Package clipman embeds the clipboard manager in other programs. It is
+// the stable API to the packages under internal, which Go doesn't let
+// other modules import: the interfaces a monitor and a storage backend
+// implement, and constructors for the service and the built-in ones.
+//
+// A program records the clipboard history in a SQLite database with:
+//
+//	store, err := clipman.OpenStorage("sqlite", dbPath, clipman.StorageConfig{FSPath: filesDir})
+//	if err != nil {
+//		return err
+//	}
+//	service := clipman.New(clipman.NewMonitor(), store)
+//	if err := service.Start(); err != nil {
+//		return err
+//	}
+//	defer service.Stop()
+//
+// Clip types are the constants and helpers of package types.
+package clipman
+
+import (
+	"clipboard-manager/internal/clipboard"
+	"clipboard-manager/internal/service"
+	"clipboard-manager/internal/storage"
+	"clipboard-manager/pkg/types"
+)
+
+// Clips and what is recorded about them
+type (
+	Clip     = types.Clip
+	Metadata = types.Metadata
+)
+
+// Monitor watches the system clipboard, reporting each copy to the
+// handler given to OnChange, and writes clips back to it
+type Monitor = clipboard.Monitor
+
+// MemoryMonitor is a Monitor that never touches the system clipboard.
+// Clips are copied with InjectClip, and what the service writes back is
+// kept for Current.
+type MemoryMonitor = clipboard.MemoryMonitor
+
+// Storage keeps clips. Backends may also implement SearchService, which
+// the service needs for searching, and the other optional interfaces of
+// the built-in backends; the service reports operations a backend doesn't
+// support as errors.
+type Storage = storage.Storage
+
+// SearchService is implemented by backends that can search clips
+type SearchService = storage.SearchService
+
+// Arguments and results of Storage and SearchService
+type (
+	StorageConfig = storage.Config
+	ListFilter    = storage.ListFilter
+	SearchOptions = storage.SearchOptions
+	SearchResult  = storage.SearchResult
+)
+
+// Service records what the monitor reports in the store and pastes clips
+// back, as the daemon does
+type Service = service.ClipboardService
+
+// Error is the error the service's methods return, naming the operation
+// that failed
+type Error = service.ClipboardError
+
+// New creates a service recording clips from monitor in store. It starts
+// watching the clipboard when Start is called.
+func New(monitor Monitor, store Storage) *Service {
+	return service.New(monitor, store)
+}
+
+// NewMonitor returns a monitor of the system clipboard. It watches the
+// pasteboard on macOS; elsewhere it is a MemoryMonitor.
+func NewMonitor() Monitor {
+	return clipboard.NewMonitor()
+}
+
+// NewMemoryMonitor returns a monitor for running without a system
+// clipboard, such as on a server or in tests
+func NewMemoryMonitor() *MemoryMonitor {
+	return clipboard.NewMemoryMonitor()
+}
+
+// OpenStorage opens one of the built-in storage backends, named as
+// Drivers lists them. The DSN is backend specific: a database file path for
+// sqlite, sqlite-direct and bolt, a connection string for postgres.
+func OpenStorage(driver, dsn string, config StorageConfig) (Storage, error) {
+	return storage.Open(driver, dsn, config)
+}
+
+// Drivers returns the names of the storage backends OpenStorage opens. The
+// sqlite ones are only built with CGO.
+func Drivers() []string {
+	return storage.Drivers()
+}
+
+// NextCursor returns the SearchOptions.Cursor of the page after results, a
+// page of at most limit clips, or "" if results is the last page
+func NextCursor(results []SearchResult, limit int) string {
+	return storage.NextCursor(results, limit)
+}
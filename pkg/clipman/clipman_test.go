@@ -0,0 +1,39 @@
+package clipman
+
+import (
+	"clipboard-manager/pkg/types"
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestService(t *testing.T) {
+	dir := t.TempDir()
+	store, err := OpenStorage("bolt", filepath.Join(dir, "clipboard.db"), StorageConfig{FSPath: filepath.Join(dir, "files")})
+	if err != nil {
+		t.Fatalf("failed to open storage: %v", err)
+	}
+	monitor := NewMemoryMonitor()
+	service := New(monitor, store)
+	if err := service.Start(); err != nil {
+		t.Fatalf("failed to start service: %v", err)
+	}
+	defer service.Stop()
+
+	monitor.InjectClip(Clip{Content: []byte("embedded copy"), Type: types.TypeText})
+	ctx := context.Background()
+	var results []SearchResult
+	for deadline := time.Now().Add(5 * time.Second); len(results) == 0 && time.Now().Before(deadline); time.Sleep(50 * time.Millisecond) {
+		if results, err = service.Search(ctx, SearchOptions{Query: "embedded"}); err != nil {
+			t.Fatalf("failed to search: %v", err)
+		}
+	}
+	if len(results) != 1 {
+		t.Fatalf("expected the copied clip, got %d results", len(results))
+	}
+
+	if _, err := OpenStorage("missing", "", StorageConfig{}); err == nil {
+		t.Error("expected an error opening an unknown driver")
+	}
+}
@@ -5,13 +5,100 @@ import "time"
 type Clip struct {
 	ID        string
 	Content   []byte
-	Type      string // supported types -> text, image, file(will have to check)
+	Type      string // A Type constant or another MIME type, as NormalizeType returns
 	Metadata  Metadata
 	CreatedAt time.Time
+	// DeletedAt is set for clips in the trash
+	DeletedAt *time.Time `json:",omitempty"`
+	// Truncated is set when Content holds only the head of a large text
+	// clip, as in lists and search results. Getting the clip by ID, or its
+	// content stream, returns all of it.
+	Truncated bool `json:",omitempty"`
 }
 
 type Metadata struct {
 	SourceApp string
-	Tags      []string
-	Category  string
+	// SourceBundleID is the bundle identifier of the source application
+	SourceBundleID string
+	// SourceIcon is the PNG icon of the source application. It is only
+	// passed along at capture time and cached per app by storage.
+	SourceIcon []byte `json:"-"`
+	// SourceURL is the page a clip was copied from, when copied in a browser
+	SourceURL string
+	// SourceTitle is the title of the source page, when available
+	SourceTitle string
+	// SourceWindow is the title of the source app's front window when the
+	// clip was copied, which tells browser tabs and documents apart
+	SourceWindow string `json:",omitempty"`
+	// Device is the device a clip was copied on, when it arrived over
+	// Universal Clipboard rather than being copied on this machine
+	Device   string `json:",omitempty"`
+	Tags     []string
+	Category string
+	// Formats holds alternate representations of the content keyed by
+	// MIME type (e.g. "text/rtf" alongside a plain text clip)
+	Formats map[string][]byte
+	// ExpiresAt is when a sensitive clip is deleted automatically. Clips
+	// that expire are never synced to external targets.
+	ExpiresAt *time.Time `json:",omitempty"`
+	// Screenshot describes where a screenshot clip was taken
+	Screenshot *Screenshot `json:",omitempty"`
+	// Language is the programming language of a code snippet, such as "go"
+	Language string `json:",omitempty"`
+	// Link previews the page a link clip points to, once it has been fetched
+	Link *LinkPreview `json:",omitempty"`
+	// Media describes the audio or video file a file clip points to
+	Media *Media `json:",omitempty"`
+	// File is the file a file clip points to as it was when copied
+	File *FileRef `json:",omitempty"`
+	// Session identifies the copy session a clip was last copied in. Clips
+	// copied from the same app in quick succession share one.
+	Session string `json:",omitempty"`
+}
+
+// FileRef identifies the file a file clip points to, so a paste can tell
+// whether it changed after it was copied
+type FileRef struct {
+	Path    string
+	Size    int64
+	ModTime time.Time
+	Hash    string // SHA-256 of the content
+	// Copied is set when storage keeps a copy of this content, pasted in
+	// place of the file once it changes
+	Copied bool `json:",omitempty"`
+}
+
+// Media is what is known about a copied audio or video file. Duration, size
+// and codecs are only found when ffprobe is installed.
+type Media struct {
+	Kind       string // "audio" or "video"
+	MIMEType   string
+	Duration   float64 `json:",omitempty"` // In seconds
+	Width      int     `json:",omitempty"`
+	Height     int     `json:",omitempty"`
+	VideoCodec string  `json:",omitempty"`
+	AudioCodec string  `json:",omitempty"`
+	Poster     []byte  `json:",omitempty"` // PNG frame of a video
+}
+
+// LinkPreview is what was found on the page a link points to
+type LinkPreview struct {
+	Title       string `json:",omitempty"`
+	Description string `json:",omitempty"`
+	FaviconURL  string `json:",omitempty"`
+	Favicon     []byte `json:",omitempty"`
+	FaviconType string `json:",omitempty"` // MIME type of Favicon
+}
+
+// Screenshot is what is known about how a screenshot was captured
+type Screenshot struct {
+	WindowID    int    `json:",omitempty"` // Window captured, for window screenshots
+	WindowTitle string `json:",omitempty"`
+	App         string `json:",omitempty"` // App in front when the screenshot was taken
+	// DisplayWidth and DisplayHeight are the pixel size of the main display
+	DisplayWidth  int `json:",omitempty"`
+	DisplayHeight int `json:",omitempty"`
+	// Width and Height are the pixel size of the captured area
+	Width  int `json:",omitempty"`
+	Height int `json:",omitempty"`
 }
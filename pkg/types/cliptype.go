@@ -0,0 +1,80 @@
+package types
+
+import "strings"
+
+// Clip types. Clip.Type holds one of these or another MIME type, such as
+// image/jpeg, always in the form NormalizeType returns.
+const (
+	TypeText       = "text/plain"
+	TypeHTML       = "text/html"
+	TypeRTF        = "text/rtf"
+	TypeRTFD       = "text/rtfd" // Flat RTFD: rich text with images, as copied from Pages or TextEdit
+	TypePNG        = "image/png"
+	TypeTIFF       = "image/tiff"
+	TypeJPEG       = "image/jpeg"
+	TypePDF        = "application/pdf" // Such as a selection copied in Preview
+	TypeScreenshot = "screenshot"      // A PNG taken with the screenshot shortcuts
+	TypeFile       = "file"            // The URL of a copied file
+	TypeFileList   = "file-list"       // A JSON array of the URLs of several copied files
+)
+
+// typeAliases maps other names for clip types to the type: the "text" older
+// versions stored, macOS pasteboard types and variant MIME types
+var typeAliases = map[string]string{
+	"text":                   TypeText,
+	"public.utf8-plain-text": TypeText,
+	"public.plain-text":      TypeText,
+	"nsstringpboardtype":     TypeText,
+	"public.html":            TypeHTML,
+	"public.rtf":             TypeRTF,
+	"nsrtfpboardtype":        TypeRTF,
+	"com.apple.flat-rtfd":    TypeRTFD,
+	"public.png":             TypePNG,
+	"public.tiff":            TypeTIFF,
+	"nstiffpboardtype":       TypeTIFF,
+	"image/tif":              TypeTIFF,
+	"public.jpeg":            TypeJPEG,
+	"image/jpg":              TypeJPEG,
+	"com.adobe.pdf":          TypePDF,
+	"public.file-url":        TypeFile,
+	"text/uri-list":          TypeFile,
+	"nsfilenamespboardtype":  TypeFile,
+}
+
+// NormalizeType returns the clip type for t, which may be spelled as older
+// versions stored it, as a pasteboard type or as a MIME type with
+// parameters, such as "text/plain; charset=utf-8". Types it doesn't know
+// are returned in lower case without parameters.
+func NormalizeType(t string) string {
+	t, _, _ = strings.Cut(t, ";")
+	t = strings.ToLower(strings.TrimSpace(t))
+	if canonical, ok := typeAliases[t]; ok {
+		return canonical
+	}
+	return t
+}
+
+// MajorType returns the kind of content of a clip type: the part of a MIME
+// type before the slash, "image" for screenshots and "file" for file lists
+func MajorType(t string) string {
+	t = NormalizeType(t)
+	switch t {
+	case TypeScreenshot:
+		return "image"
+	case TypeFileList:
+		return "file"
+	}
+	major, _, _ := strings.Cut(t, "/")
+	return major
+}
+
+// IsText reports whether clips of type t hold text, plain or rich
+func IsText(t string) bool {
+	return MajorType(t) == "text"
+}
+
+// IsImage reports whether clips of type t hold an image, screenshots
+// included
+func IsImage(t string) bool {
+	return MajorType(t) == "image"
+}
@@ -0,0 +1,47 @@
+package types
+
+import "testing"
+
+func TestNormalizeType(t *testing.T) {
+	tests := map[string]string{
+		"text":                      TypeText,
+		"text/plain":                TypeText,
+		"Text/Plain; charset=utf-8": TypeText,
+		"public.utf8-plain-text":    TypeText,
+		"NSStringPboardType":        TypeText,
+		"public.html":               TypeHTML,
+		"image/jpg":                 TypeJPEG,
+		"public.file-url":           TypeFile,
+		"screenshot":                TypeScreenshot,
+		"file-list":                 TypeFileList,
+		"image/webp":                "image/webp",
+		"":                          "",
+	}
+	for input, want := range tests {
+		if got := NormalizeType(input); got != want {
+			t.Errorf("NormalizeType(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestMajorType(t *testing.T) {
+	tests := map[string]string{
+		"text":              "text",
+		TypeRTFD:            "text",
+		TypeScreenshot:      "image",
+		TypePNG:             "image",
+		TypeFile:            "file",
+		TypeFileList:        "file",
+		TypePDF:             "application",
+		"public.file-url":   "file",
+		"application/x-foo": "application",
+	}
+	for input, want := range tests {
+		if got := MajorType(input); got != want {
+			t.Errorf("MajorType(%q) = %q, want %q", input, got, want)
+		}
+	}
+	if !IsText("text") || IsText(TypeFile) || !IsImage(TypeScreenshot) || IsImage(TypePDF) {
+		t.Error("IsText or IsImage misclassified a type")
+	}
+}